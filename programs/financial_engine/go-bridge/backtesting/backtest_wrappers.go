@@ -2,7 +2,8 @@ package backtesting
 
 import (
 	"fmt"
-	"math"
+
+	"zig-financial-engine/internal/indicators"
 )
 
 // BacktestWrapper provides a generic wrapper to convert live trading strategies to backtesting strategies
@@ -30,6 +31,7 @@ type RSIBacktestStrategy struct {
 	stopLossPct    float64
 	takeProfitPct  float64
 	useTrendFilter bool
+	rsi            *indicators.RSI // Built from the current parameters on first bar
 }
 
 func NewRSIBacktestStrategy(symbol string) *RSIBacktestStrategy {
@@ -49,16 +51,16 @@ func NewRSIBacktestStrategy(symbol string) *RSIBacktestStrategy {
 }
 
 func (s *RSIBacktestStrategy) ProcessBar(bar Bar, portfolio *Portfolio) Signal {
-	s.prices = append(s.prices, bar.Close)
+	if s.rsi == nil {
+		s.rsi = indicators.NewRSI(s.rsiPeriod)
+	}
+	rsi := s.rsi.Update(bar.Close)
 	
 	// Need enough data for RSI calculation
-	if len(s.prices) < s.rsiPeriod+1 {
+	if !s.rsi.Ready() {
 		return Signal{Action: "HOLD"}
 	}
 	
-	// Calculate RSI
-	rsi := s.calculateRSI(s.prices, s.rsiPeriod)
-	
 	// Store RSI in indicators
 	if s.indicators["rsi"] == nil {
 		s.indicators["rsi"] = make([]float64, 0)
@@ -105,6 +107,7 @@ func (s *RSIBacktestStrategy) Reset() {
 	s.lastSignal = ""
 	s.tradeCount = 0
 	s.position = nil
+	s.rsi = nil
 }
 
 func (s *RSIBacktestStrategy) SetParameters(params map[string]interface{}) error {
@@ -126,42 +129,11 @@ func (s *RSIBacktestStrategy) SetParameters(params map[string]interface{}) error
 	if useTrend, ok := params["use_trend_filter"].(bool); ok {
 		s.useTrendFilter = useTrend
 	}
+	s.rsi = nil // Rebuilt with the new periods
 	s.parameters = params
 	return nil
 }
 
-// calculateRSI computes RSI indicator
-func (s *RSIBacktestStrategy) calculateRSI(prices []float64, period int) float64 {
-	if len(prices) < period+1 {
-		return 50.0 // Neutral RSI
-	}
-	
-	gains := 0.0
-	losses := 0.0
-	
-	// Calculate initial average gain/loss
-	for i := len(prices) - period; i < len(prices); i++ {
-		change := prices[i] - prices[i-1]
-		if change > 0 {
-			gains += change
-		} else {
-			losses += math.Abs(change)
-		}
-	}
-	
-	avgGain := gains / float64(period)
-	avgLoss := losses / float64(period)
-	
-	if avgLoss == 0 {
-		return 100.0
-	}
-	
-	rs := avgGain / avgLoss
-	rsi := 100 - (100 / (1 + rs))
-	
-	return rsi
-}
-
 // MovingAverageCrossoverBacktestStrategy wraps MA Crossover strategy
 type MovingAverageCrossoverBacktestStrategy struct {
 	*BacktestWrapper
@@ -169,6 +141,8 @@ type MovingAverageCrossoverBacktestStrategy struct {
 	longPeriod    int
 	stopLossPct   float64
 	takeProfitPct float64
+	shortMA       *indicators.SMA
+	longMA        *indicators.SMA
 }
 
 func NewMovingAverageCrossoverBacktestStrategy(symbol string) *MovingAverageCrossoverBacktestStrategy {
@@ -186,15 +160,18 @@ func NewMovingAverageCrossoverBacktestStrategy(symbol string) *MovingAverageCros
 }
 
 func (s *MovingAverageCrossoverBacktestStrategy) ProcessBar(bar Bar, portfolio *Portfolio) Signal {
-	s.prices = append(s.prices, bar.Close)
-	
-	if len(s.prices) < s.longPeriod {
-		return Signal{Action: "HOLD"}
+	if s.shortMA == nil {
+		s.shortMA = indicators.NewSMA(s.shortPeriod)
+		s.longMA = indicators.NewSMA(s.longPeriod)
 	}
 	
 	// Calculate moving averages
-	shortMA := s.calculateSMA(s.prices, s.shortPeriod)
-	longMA := s.calculateSMA(s.prices, s.longPeriod)
+	shortMA := s.shortMA.Update(bar.Close)
+	longMA := s.longMA.Update(bar.Close)
+	
+	if !s.shortMA.Ready() || !s.longMA.Ready() {
+		return Signal{Action: "HOLD"}
+	}
 	
 	// Store MAs in indicators
 	if s.indicators["short_ma"] == nil {
@@ -246,6 +223,7 @@ func (s *MovingAverageCrossoverBacktestStrategy) Reset() {
 	s.lastSignal = ""
 	s.tradeCount = 0
 	s.position = nil
+	s.shortMA, s.longMA = nil, nil
 }
 
 func (s *MovingAverageCrossoverBacktestStrategy) SetParameters(params map[string]interface{}) error {
@@ -261,23 +239,11 @@ func (s *MovingAverageCrossoverBacktestStrategy) SetParameters(params map[string
 	if takeProfit, ok := params["take_profit_pct"].(float64); ok {
 		s.takeProfitPct = takeProfit
 	}
+	s.shortMA, s.longMA = nil, nil // Rebuilt with the new periods
 	s.parameters = params
 	return nil
 }
 
-func (s *MovingAverageCrossoverBacktestStrategy) calculateSMA(prices []float64, period int) float64 {
-	if len(prices) < period {
-		return prices[len(prices)-1] // Return last price if insufficient data
-	}
-	
-	sum := 0.0
-	start := len(prices) - period
-	for i := start; i < len(prices); i++ {
-		sum += prices[i]
-	}
-	return sum / float64(period)
-}
-
 // BollingerBandsBacktestStrategy wraps Bollinger Bands strategy
 type BollingerBandsBacktestStrategy struct {
 	*BacktestWrapper
@@ -285,6 +251,7 @@ type BollingerBandsBacktestStrategy struct {
 	numStdDev     float64
 	stopLossPct   float64
 	takeProfitPct float64
+	bands         *indicators.Bollinger
 }
 
 func NewBollingerBandsBacktestStrategy(symbol string) *BollingerBandsBacktestStrategy {
@@ -302,17 +269,19 @@ func NewBollingerBandsBacktestStrategy(symbol string) *BollingerBandsBacktestStr
 }
 
 func (s *BollingerBandsBacktestStrategy) ProcessBar(bar Bar, portfolio *Portfolio) Signal {
-	s.prices = append(s.prices, bar.Close)
+	if s.bands == nil {
+		s.bands = indicators.NewBollinger(s.period, s.numStdDev)
+	}
+	bands := s.bands.Update(bar.Close)
 	
-	if len(s.prices) < s.period {
+	if !s.bands.Ready() {
 		return Signal{Action: "HOLD"}
 	}
 	
 	// Calculate Bollinger Bands
-	sma := s.calculateSMA(s.prices, s.period)
-	stdDev := s.calculateStdDev(s.prices, s.period, sma)
-	upperBand := sma + (s.numStdDev * stdDev)
-	lowerBand := sma - (s.numStdDev * stdDev)
+	sma := bands.Middle
+	upperBand := bands.Upper
+	lowerBand := bands.Lower
 	
 	// Store indicators
 	if s.indicators["sma"] == nil {
@@ -361,6 +330,7 @@ func (s *BollingerBandsBacktestStrategy) Reset() {
 	s.lastSignal = ""
 	s.tradeCount = 0
 	s.position = nil
+	s.bands = nil
 }
 
 func (s *BollingerBandsBacktestStrategy) SetParameters(params map[string]interface{}) error {
@@ -376,38 +346,11 @@ func (s *BollingerBandsBacktestStrategy) SetParameters(params map[string]interfa
 	if takeProfit, ok := params["take_profit_pct"].(float64); ok {
 		s.takeProfitPct = takeProfit
 	}
+	s.bands = nil // Rebuilt with the new periods
 	s.parameters = params
 	return nil
 }
 
-func (s *BollingerBandsBacktestStrategy) calculateSMA(prices []float64, period int) float64 {
-	if len(prices) < period {
-		return prices[len(prices)-1] // Return last price if insufficient data
-	}
-	
-	sum := 0.0
-	start := len(prices) - period
-	for i := start; i < len(prices); i++ {
-		sum += prices[i]
-	}
-	return sum / float64(period)
-}
-
-func (s *BollingerBandsBacktestStrategy) calculateStdDev(prices []float64, period int, mean float64) float64 {
-	if len(prices) < period {
-		return 0.0
-	}
-	
-	variance := 0.0
-	start := len(prices) - period
-	for i := start; i < len(prices); i++ {
-		diff := prices[i] - mean
-		variance += diff * diff
-	}
-	variance /= float64(period)
-	return math.Sqrt(variance)
-}
-
 // MACDBacktestStrategy wraps MACD Divergence strategy
 type MACDBacktestStrategy struct {
 	*BacktestWrapper
@@ -416,6 +359,7 @@ type MACDBacktestStrategy struct {
 	signalPeriod  int
 	stopLossPct   float64
 	takeProfitPct float64
+	macd          *indicators.MACD
 }
 
 func NewMACDBacktestStrategy(symbol string) *MACDBacktestStrategy {
@@ -434,57 +378,38 @@ func NewMACDBacktestStrategy(symbol string) *MACDBacktestStrategy {
 }
 
 func (s *MACDBacktestStrategy) ProcessBar(bar Bar, portfolio *Portfolio) Signal {
-	s.prices = append(s.prices, bar.Close)
+	if s.macd == nil {
+		s.macd = indicators.NewMACD(s.fastPeriod, s.slowPeriod, s.signalPeriod)
+	}
+	prev, prevReady := s.macd.Value(), s.macd.Ready()
+	current := s.macd.Update(bar.Close)
 	
-	if len(s.prices) < s.slowPeriod+s.signalPeriod {
+	// Need the signal line on this bar and the last to see a crossover
+	if !s.macd.Ready() || !prevReady {
 		return Signal{Action: "HOLD"}
 	}
 	
-	// Calculate MACD
-	fastEMA := s.calculateEMA(s.prices, s.fastPeriod)
-	slowEMA := s.calculateEMA(s.prices, s.slowPeriod)
-	macdLine := fastEMA - slowEMA
+	macdLine := current.MACD
+	signalLine := current.Signal
 	
-	// Store MACD line for signal calculation
-	if s.indicators["macd"] == nil {
-		s.indicators["macd"] = make([]float64, 0)
-	}
+	// Store MACD line for inspection
 	s.indicators["macd"] = append(s.indicators["macd"], macdLine)
 	
-	// Need enough MACD values for signal line
-	if len(s.indicators["macd"]) < s.signalPeriod {
-		return Signal{Action: "HOLD"}
-	}
-	
-	// Calculate signal line
-	signalLine := s.calculateEMA(s.indicators["macd"], s.signalPeriod)
-	
 	signal := Signal{Action: "HOLD"}
 	
 	// MACD bullish crossover
-	if len(s.indicators["macd"]) > s.signalPeriod && macdLine > signalLine && s.lastSignal != "BUY" {
-		// Check if MACD was below signal line before
-		prevMacdLine := s.indicators["macd"][len(s.indicators["macd"])-2]
-		prevSignalLine := s.calculateEMA(s.indicators["macd"][:len(s.indicators["macd"])-1], s.signalPeriod)
-		
-		if prevMacdLine <= prevSignalLine {
-			signal.Action = "BUY"
-			signal.Quantity = 1
-			signal.StopLoss = bar.Close * (1 - s.stopLossPct)
-			signal.TakeProfit = bar.Close * (1 + s.takeProfitPct)
-			s.lastSignal = "BUY"
-		}
+	if macdLine > signalLine && prev.MACD <= prev.Signal && s.lastSignal != "BUY" {
+		signal.Action = "BUY"
+		signal.Quantity = 1
+		signal.StopLoss = bar.Close * (1 - s.stopLossPct)
+		signal.TakeProfit = bar.Close * (1 + s.takeProfitPct)
+		s.lastSignal = "BUY"
 	}
 	
 	// MACD bearish crossover
-	if len(s.indicators["macd"]) > s.signalPeriod && macdLine < signalLine && s.lastSignal != "SELL" {
-		prevMacdLine := s.indicators["macd"][len(s.indicators["macd"])-2]
-		prevSignalLine := s.calculateEMA(s.indicators["macd"][:len(s.indicators["macd"])-1], s.signalPeriod)
-		
-		if prevMacdLine >= prevSignalLine {
-			signal.Action = "SELL"
-			s.lastSignal = "SELL"
-		}
+	if macdLine < signalLine && prev.MACD >= prev.Signal && s.lastSignal != "SELL" {
+		signal.Action = "SELL"
+		s.lastSignal = "SELL"
 	}
 	
 	return signal
@@ -508,6 +433,7 @@ func (s *MACDBacktestStrategy) Reset() {
 	s.lastSignal = ""
 	s.tradeCount = 0
 	s.position = nil
+	s.macd = nil
 }
 
 func (s *MACDBacktestStrategy) SetParameters(params map[string]interface{}) error {
@@ -526,28 +452,11 @@ func (s *MACDBacktestStrategy) SetParameters(params map[string]interface{}) erro
 	if takeProfit, ok := params["take_profit_pct"].(float64); ok {
 		s.takeProfitPct = takeProfit
 	}
+	s.macd = nil // Rebuilt with the new periods
 	s.parameters = params
 	return nil
 }
 
-func (s *MACDBacktestStrategy) calculateEMA(prices []float64, period int) float64 {
-	if len(prices) == 0 {
-		return 0.0
-	}
-	if len(prices) < period {
-		return prices[len(prices)-1]
-	}
-	
-	multiplier := 2.0 / (float64(period) + 1.0)
-	ema := prices[0]
-	
-	for i := 1; i < len(prices); i++ {
-		ema = (prices[i] * multiplier) + (ema * (1 - multiplier))
-	}
-	
-	return ema
-}
-
 // VWAPBacktestStrategy wraps VWAP Intraday strategy
 type VWAPBacktestStrategy struct {
 	*BacktestWrapper
@@ -555,6 +464,7 @@ type VWAPBacktestStrategy struct {
 	deviationPct  float64
 	stopLossPct   float64
 	takeProfitPct float64
+	vwap          *indicators.VWAP
 }
 
 func NewVWAPBacktestStrategy(symbol string) *VWAPBacktestStrategy {
@@ -572,21 +482,17 @@ func NewVWAPBacktestStrategy(symbol string) *VWAPBacktestStrategy {
 }
 
 func (s *VWAPBacktestStrategy) ProcessBar(bar Bar, portfolio *Portfolio) Signal {
-	s.prices = append(s.prices, bar.Close)
-	
-	// Store volume (for now use price as proxy for volume)
-	if s.indicators["volume"] == nil {
-		s.indicators["volume"] = make([]float64, 0)
+	if s.vwap == nil {
+		s.vwap = indicators.NewVWAP(s.vwapPeriod)
 	}
-	s.indicators["volume"] = append(s.indicators["volume"], bar.Volume)
 	
-	if len(s.prices) < s.vwapPeriod {
+	// Calculate VWAP over typical prices
+	vwap := s.vwap.Update(indicators.TypicalPrice(bar.High, bar.Low, bar.Close), bar.Volume)
+	
+	if !s.vwap.Ready() {
 		return Signal{Action: "HOLD"}
 	}
 	
-	// Calculate VWAP
-	vwap := s.calculateVWAP(s.prices, s.indicators["volume"], s.vwapPeriod)
-	
 	signal := Signal{Action: "HOLD"}
 	
 	// Buy when price is below VWAP by deviation threshold
@@ -624,6 +530,7 @@ func (s *VWAPBacktestStrategy) Reset() {
 	s.lastSignal = ""
 	s.tradeCount = 0
 	s.position = nil
+	s.vwap = nil
 }
 
 func (s *VWAPBacktestStrategy) SetParameters(params map[string]interface{}) error {
@@ -639,32 +546,11 @@ func (s *VWAPBacktestStrategy) SetParameters(params map[string]interface{}) erro
 	if takeProfit, ok := params["take_profit_pct"].(float64); ok {
 		s.takeProfitPct = takeProfit
 	}
+	s.vwap = nil // Rebuilt with the new periods
 	s.parameters = params
 	return nil
 }
 
-func (s *VWAPBacktestStrategy) calculateVWAP(prices, volumes []float64, period int) float64 {
-	if len(prices) < period || len(volumes) < period {
-		return prices[len(prices)-1]
-	}
-	
-	start := len(prices) - period
-	sumPriceVolume := 0.0
-	sumVolume := 0.0
-	
-	for i := start; i < len(prices); i++ {
-		typicalPrice := prices[i] // Simplified - normally (H+L+C)/3
-		sumPriceVolume += typicalPrice * volumes[i]
-		sumVolume += volumes[i]
-	}
-	
-	if sumVolume == 0 {
-		return prices[len(prices)-1]
-	}
-	
-	return sumPriceVolume / sumVolume
-}
-
 // Helper function to create strategy instances by name
 func CreateBacktestStrategy(strategyName, symbol string) (BacktestStrategy, error) {
 	switch strategyName {
@@ -128,6 +128,10 @@ type Backtester struct {
 	// Data
 	Bars []Bar
 	
+	// Regulatory simulation (nil disables compliance checks)
+	Profile    *AccountProfile
+	compliance *complianceSimulator
+	
 	// Results
 	Results *BacktestResults
 }
@@ -172,6 +176,9 @@ type BacktestResults struct {
 	
 	// Trade distribution
 	TradeDistribution []float64
+	
+	// Regulatory simulation results (nil when no account profile was set)
+	Compliance *ComplianceReport
 }
 
 // NewBacktester creates a new backtester instance
@@ -188,6 +195,19 @@ func NewBacktester(strategy BacktestStrategy, symbol string, start, end time.Tim
 	}
 }
 
+// SetAccountProfile enables regulatory simulation against the given account
+func (b *Backtester) SetAccountProfile(profile AccountProfile) {
+	if profile.AccountType == "" {
+		profile.AccountType = AccountTypeMargin
+	}
+	if profile.StartingEquity <= 0 {
+		profile.StartingEquity = b.Portfolio.InitialCapital
+	}
+	
+	b.Profile = &profile
+	b.Portfolio = NewPortfolio(profile.StartingEquity)
+}
+
 // LoadData fetches historical data from Alpaca
 func (b *Backtester) LoadData(dataClient *marketdata.Client) error {
 	barsReq := marketdata.GetBarsRequest{
@@ -229,7 +249,17 @@ func (b *Backtester) Run() error {
 	b.Logger.Printf("Starting backtest for %s using %T strategy", b.Symbol, b.Strategy)
 	b.Strategy.Reset()
 	
+	b.compliance = nil
+	if b.Profile != nil {
+		b.compliance = newComplianceSimulator(*b.Profile, b.Logger)
+	}
+	
 	for _, bar := range b.Bars {
+		// Advance simulated clock for compliance checks
+		if b.compliance != nil {
+			b.compliance.advance(bar, b.Portfolio.Equity)
+		}
+		
 		// Update position prices
 		b.updatePositions(bar)
 		
@@ -301,6 +331,11 @@ func (b *Backtester) executeSignal(signal Signal, bar Bar) {
 		if len(b.Portfolio.Positions) >= b.Portfolio.MaxPositions {
 			return // Max positions reached
 		}
+		// A repeat BUY replaces the open position; under compliance simulation
+		// that would record a buy the portfolio never holds, so skip it there
+		if _, exists := b.Portfolio.Positions[b.Symbol]; exists && b.compliance != nil {
+			return
+		}
 		
 		// Calculate position size
 		positionSize := b.Portfolio.Equity * b.Portfolio.MaxPositionSize
@@ -321,6 +356,14 @@ func (b *Backtester) executeSignal(signal Signal, bar Bar) {
 			cost = quantity * executionPrice + b.Portfolio.Commission
 		}
 		
+		// Run the order through the account protection rules
+		if b.compliance != nil {
+			if !b.compliance.allow(b.Symbol, "buy", quantity, executionPrice) {
+				return
+			}
+			b.compliance.recordFill(b.Symbol, "buy", quantity, executionPrice, false)
+		}
+		
 		// Open position
 		b.Portfolio.Positions[b.Symbol] = &Position{
 			Symbol:     b.Symbol,
@@ -336,8 +379,11 @@ func (b *Backtester) executeSignal(signal Signal, bar Bar) {
 		b.Portfolio.OpenTrades++
 		
 	} else if signal.Action == "SELL" {
-		if _, exists := b.Portfolio.Positions[b.Symbol]; exists {
+		if pos, exists := b.Portfolio.Positions[b.Symbol]; exists {
 			executionPrice := bar.Close * (1 - b.Portfolio.Slippage)
+			if b.compliance != nil && !b.compliance.allow(b.Symbol, "sell", pos.Quantity, executionPrice) {
+				return
+			}
 			b.closePosition(b.Symbol, executionPrice, bar.Time, "SIGNAL")
 		}
 	}
@@ -371,6 +417,12 @@ func (b *Backtester) closePosition(symbol string, exitPrice float64, exitTime ti
 	}
 	
 	b.Portfolio.CompletedTrades = append(b.Portfolio.CompletedTrades, trade)
+	
+	// Bracket exits are not blocked, but still count toward day trades
+	if b.compliance != nil {
+		sameDay := tradingDate(pos.EntryTime) == tradingDate(exitTime)
+		b.compliance.recordFill(symbol, "sell", pos.Quantity, exitPrice, sameDay)
+	}
 	b.Portfolio.Cash += proceeds
 	b.Portfolio.TotalTrades++
 	
@@ -444,6 +496,10 @@ func (b *Backtester) calculateResults() *BacktestResults {
 		MonthlyReturns: make(map[string]float64),
 	}
 	
+	if b.compliance != nil {
+		results.Compliance = b.compliance.report
+	}
+	
 	if b.Portfolio.TotalTrades == 0 {
 		return results
 	}
@@ -768,5 +824,10 @@ func (b *Backtester) PrintResults() {
 		fmt.Printf("%s: %+.2f%%\n", month, r.MonthlyReturns[month])
 	}
 	
+	if r.Compliance != nil {
+		fmt.Println("\n--- REGULATORY SIMULATION ---")
+		fmt.Print(r.Compliance.String())
+	}
+	
 	fmt.Println("\n========================")
 }
\ No newline at end of file
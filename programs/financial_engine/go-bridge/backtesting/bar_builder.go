@@ -0,0 +1,434 @@
+package backtesting
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BarKind selects what closes a bar
+type BarKind string
+
+const (
+	BarKindTime   BarKind = "time"   // Fixed clock interval
+	BarKindTick   BarKind = "tick"   // Fixed number of trades
+	BarKindVolume BarKind = "volume" // Fixed number of shares
+	BarKindDollar BarKind = "dollar" // Fixed traded notional
+)
+
+// DefaultExcludedConditions are SIP trade conditions that do not reflect the
+// current market (average price, out of sequence, prior reference, cash and
+// next-day settlement, contingent and derivatively priced trades)
+var DefaultExcludedConditions = []string{"W", "Z", "U", "P", "C", "N", "R", "4", "7"}
+
+// TradePrint is one trade from a trade stream or historical trade file
+type TradePrint struct {
+	Time       time.Time
+	Price      float64
+	Size       float64
+	Conditions []string
+}
+
+// QuotePrint is one top-of-book quote
+type QuotePrint struct {
+	Time     time.Time
+	BidPrice float64
+	AskPrice float64
+}
+
+// BarBuilderConfig controls how prints are aggregated into bars
+type BarBuilderConfig struct {
+	Kind      BarKind
+	Interval  time.Duration // Time bars: bar length
+	Threshold float64       // Tick, volume and dollar bars: trades, shares or dollars per bar
+
+	Location      *time.Location // Session time zone (default America/New_York)
+	SessionOpen   time.Duration  // Offset from midnight (default 9:30)
+	SessionClose  time.Duration  // Offset from midnight (default 16:00)
+	ExtendedHours bool           // Accept prints all day; sessions become calendar days
+
+	LateTolerance     time.Duration // How far behind the newest print a print may arrive and still count
+	ExcludeConditions []string      // Trades carrying any of these conditions are ignored
+	QuoteFallback     bool          // Time bars with no trades are built from quote midpoints
+}
+
+// DefaultBarBuilderConfig returns one-minute regular-session time bars
+func DefaultBarBuilderConfig() BarBuilderConfig {
+	return BarBuilderConfig{
+		Kind:              BarKindTime,
+		Interval:          time.Minute,
+		SessionOpen:       9*time.Hour + 30*time.Minute,
+		SessionClose:      16 * time.Hour,
+		LateTolerance:     2 * time.Second,
+		ExcludeConditions: DefaultExcludedConditions,
+	}
+}
+
+// BarBuilderStats counts prints by what happened to them
+type BarBuilderStats struct {
+	Trades          int // Trades applied to a bar
+	Quotes          int // Quotes applied to a bar
+	Bars            int // Bars emitted
+	DroppedLate     int // Older than the late tolerance allows
+	DroppedSession  int // Outside the trading session
+	DroppedFiltered int // Excluded trade condition
+	DroppedInvalid  int // Non-positive price, negative size or crossed quote
+}
+
+// barState accumulates one bar. Open and close are taken from the earliest
+// and latest print times, so out-of-order prints land where they belong.
+type barState struct {
+	start, end time.Time // end is zero for tick, volume and dollar bars
+	session    time.Time // Session open the bar belongs to
+	trades     ohlc
+	quotes     ohlc
+	volume     float64
+	notional   float64
+	tradeCount int
+}
+
+// ohlc tracks prices by print time
+type ohlc struct {
+	open, high, low, close float64
+	openAt, closeAt        time.Time
+	count                  int
+}
+
+// add folds one price into the running OHLC
+func (o *ohlc) add(at time.Time, price float64) {
+	if o.count == 0 {
+		o.open, o.high, o.low, o.close = price, price, price, price
+		o.openAt, o.closeAt = at, at
+		o.count = 1
+		return
+	}
+	o.count++
+	if price > o.high {
+		o.high = price
+	}
+	if price < o.low {
+		o.low = price
+	}
+	if at.Before(o.openAt) {
+		o.open, o.openAt = price, at
+	}
+	if !at.Before(o.closeAt) {
+		o.close, o.closeAt = price, at
+	}
+}
+
+// BarBuilder aggregates trade prints, and optionally quotes, into the Bar type
+// the backtester consumes. Feed prints with AddTrade/AddQuote; each call
+// returns the bars completed by it, oldest first. A time bar completes once a
+// print at least LateTolerance past its end arrives, or on Advance/Flush.
+// Intervals without prints produce no bar.
+type BarBuilder struct {
+	cfg      BarBuilderConfig
+	excluded map[string]bool
+
+	pending   map[time.Time]*barState // Time bars by start, not yet emitted
+	current   *barState               // Tick, volume or dollar bar in progress
+	watermark time.Time               // Newest print time seen
+	stats     BarBuilderStats
+}
+
+// NewBarBuilder validates cfg and creates a builder
+func NewBarBuilder(cfg BarBuilderConfig) (*BarBuilder, error) {
+	switch cfg.Kind {
+	case BarKindTime:
+		if cfg.Interval <= 0 {
+			return nil, fmt.Errorf("time bars need a positive interval")
+		}
+	case BarKindTick, BarKindVolume, BarKindDollar:
+		if cfg.Threshold <= 0 {
+			return nil, fmt.Errorf("%s bars need a positive threshold", cfg.Kind)
+		}
+	default:
+		return nil, fmt.Errorf("unknown bar kind %q", cfg.Kind)
+	}
+
+	if cfg.Location == nil {
+		loc, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load session time zone: %w", err)
+		}
+		cfg.Location = loc
+	}
+	if !cfg.ExtendedHours && cfg.SessionClose <= cfg.SessionOpen {
+		return nil, fmt.Errorf("session close %v must be after open %v", cfg.SessionClose, cfg.SessionOpen)
+	}
+	if cfg.LateTolerance < 0 {
+		return nil, fmt.Errorf("late tolerance cannot be negative")
+	}
+
+	excluded := make(map[string]bool, len(cfg.ExcludeConditions))
+	for _, condition := range cfg.ExcludeConditions {
+		excluded[condition] = true
+	}
+
+	return &BarBuilder{
+		cfg:      cfg,
+		excluded: excluded,
+		pending:  make(map[time.Time]*barState),
+	}, nil
+}
+
+// Stats returns print and bar counters
+func (b *BarBuilder) Stats() BarBuilderStats {
+	return b.stats
+}
+
+// AddTrade applies a trade print and returns any bars it completes
+func (b *BarBuilder) AddTrade(trade TradePrint) []Bar {
+	if trade.Price <= 0 || trade.Size < 0 {
+		b.stats.DroppedInvalid++
+		return nil
+	}
+	for _, condition := range trade.Conditions {
+		if b.excluded[condition] {
+			b.stats.DroppedFiltered++
+			return nil
+		}
+	}
+	sessionOpen, sessionClose, ok := b.session(trade.Time)
+	if !ok {
+		b.stats.DroppedSession++
+		return nil
+	}
+	if b.isLate(trade.Time) {
+		b.stats.DroppedLate++
+		return nil
+	}
+	b.stats.Trades++
+
+	var bars []Bar
+	if b.cfg.Kind == BarKindTime {
+		bar := b.timeBar(trade.Time, sessionOpen, sessionClose)
+		bar.trades.add(trade.Time, trade.Price)
+		bar.volume += trade.Size
+		bar.notional += trade.Size * trade.Price
+		bar.tradeCount++
+	} else {
+		bars = b.addToThresholdBar(trade, sessionOpen)
+	}
+
+	b.advanceWatermark(trade.Time)
+	return append(bars, b.closeReady()...)
+}
+
+// AddQuote applies a quote and returns any bars it completes. Quotes move
+// the clock for every bar kind but only shape time bars with QuoteFallback.
+func (b *BarBuilder) AddQuote(quote QuotePrint) []Bar {
+	if quote.BidPrice <= 0 || quote.AskPrice < quote.BidPrice {
+		b.stats.DroppedInvalid++
+		return nil
+	}
+	sessionOpen, sessionClose, ok := b.session(quote.Time)
+	if !ok {
+		b.stats.DroppedSession++
+		return nil
+	}
+	if b.isLate(quote.Time) {
+		b.stats.DroppedLate++
+		return nil
+	}
+
+	if b.cfg.Kind == BarKindTime && b.cfg.QuoteFallback {
+		b.stats.Quotes++
+		bar := b.timeBar(quote.Time, sessionOpen, sessionClose)
+		bar.quotes.add(quote.Time, (quote.BidPrice+quote.AskPrice)/2)
+	}
+
+	b.advanceWatermark(quote.Time)
+	return b.closeReady()
+}
+
+// Advance moves the clock without a print, e.g. from a timer in live
+// trading, and returns bars that are now complete
+func (b *BarBuilder) Advance(now time.Time) []Bar {
+	b.advanceWatermark(now)
+	return b.closeReady()
+}
+
+// Flush emits every bar in progress, e.g. at the end of a data file
+func (b *BarBuilder) Flush() []Bar {
+	bars := b.drainPending(func(*barState) bool { return true })
+	if b.current != nil {
+		if bar, ok := b.emit(b.current); ok {
+			bars = append(bars, bar)
+		}
+		b.current = nil
+	}
+	return bars
+}
+
+// session returns the bounds of the session containing t
+func (b *BarBuilder) session(t time.Time) (time.Time, time.Time, bool) {
+	local := t.In(b.cfg.Location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, b.cfg.Location)
+	if b.cfg.ExtendedHours {
+		return midnight, midnight.AddDate(0, 0, 1), true
+	}
+
+	open := midnight.Add(b.cfg.SessionOpen)
+	close := midnight.Add(b.cfg.SessionClose)
+	if t.Before(open) || !t.Before(close) {
+		return time.Time{}, time.Time{}, false
+	}
+	return open, close, true
+}
+
+// isLate reports whether a print is too far behind the newest one to count
+func (b *BarBuilder) isLate(t time.Time) bool {
+	return !b.watermark.IsZero() && t.Before(b.watermark.Add(-b.cfg.LateTolerance))
+}
+
+// advanceWatermark records the newest print time
+func (b *BarBuilder) advanceWatermark(t time.Time) {
+	if t.After(b.watermark) {
+		b.watermark = t
+	}
+}
+
+// timeBar returns the pending time bar containing t, aligned to the session
+// open and cut short at the session close
+func (b *BarBuilder) timeBar(t, sessionOpen, sessionClose time.Time) *barState {
+	start := sessionOpen.Add(t.Sub(sessionOpen) / b.cfg.Interval * b.cfg.Interval)
+	if bar, exists := b.pending[start]; exists {
+		return bar
+	}
+
+	end := start.Add(b.cfg.Interval)
+	if end.After(sessionClose) {
+		end = sessionClose
+	}
+	bar := &barState{start: start, end: end, session: sessionOpen}
+	b.pending[start] = bar
+	return bar
+}
+
+// addToThresholdBar applies a trade to tick, volume or dollar bars, splitting
+// its size across bars when it fills more than one. Bars never span sessions.
+func (b *BarBuilder) addToThresholdBar(trade TradePrint, sessionOpen time.Time) []Bar {
+	var bars []Bar
+	if b.current != nil && !b.current.session.Equal(sessionOpen) {
+		if bar, ok := b.emit(b.current); ok {
+			bars = append(bars, bar)
+		}
+		b.current = nil
+	}
+
+	remaining := trade.Size
+	for {
+		if b.current == nil {
+			b.current = &barState{start: trade.Time, session: sessionOpen}
+		}
+		bar := b.current
+		bar.trades.add(trade.Time, trade.Price)
+		if trade.Time.Before(bar.start) {
+			bar.start = trade.Time
+		}
+
+		take := remaining
+		switch b.cfg.Kind {
+		case BarKindVolume:
+			if room := b.cfg.Threshold - bar.volume; take > room {
+				take = room
+			}
+		case BarKindDollar:
+			if room := (b.cfg.Threshold - bar.notional) / trade.Price; take > room {
+				take = room
+			}
+		}
+		bar.volume += take
+		bar.notional += take * trade.Price
+		remaining -= take
+		bar.tradeCount++ // A split print counts toward each bar it reaches
+
+		if !b.full(bar) {
+			return bars
+		}
+		if out, ok := b.emit(bar); ok {
+			bars = append(bars, out)
+		}
+		b.current = nil
+		if remaining <= 0 {
+			return bars
+		}
+	}
+}
+
+// full reports whether a threshold bar has reached its size
+func (b *BarBuilder) full(bar *barState) bool {
+	// Tolerate float rounding when a print is split exactly at the threshold
+	const epsilon = 1e-9
+	switch b.cfg.Kind {
+	case BarKindTick:
+		return float64(bar.tradeCount) >= b.cfg.Threshold
+	case BarKindVolume:
+		return bar.volume >= b.cfg.Threshold-epsilon
+	case BarKindDollar:
+		return bar.notional >= b.cfg.Threshold*(1-epsilon)
+	}
+	return false
+}
+
+// closeReady emits time bars whose end plus the late tolerance has passed,
+// and a threshold bar left over from a session that has ended
+func (b *BarBuilder) closeReady() []Bar {
+	horizon := b.watermark.Add(-b.cfg.LateTolerance)
+	bars := b.drainPending(func(bar *barState) bool {
+		return !bar.end.After(horizon)
+	})
+
+	if b.current != nil && !b.cfg.ExtendedHours {
+		if _, close, ok := b.session(b.current.start); ok && !close.After(horizon) {
+			if bar, ok := b.emit(b.current); ok {
+				bars = append(bars, bar)
+			}
+			b.current = nil
+		}
+	}
+	return bars
+}
+
+// drainPending removes and emits pending time bars matching ready, oldest first
+func (b *BarBuilder) drainPending(ready func(*barState) bool) []Bar {
+	var starts []time.Time
+	for start, bar := range b.pending {
+		if ready(bar) {
+			starts = append(starts, start)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	bars := make([]Bar, 0, len(starts))
+	for _, start := range starts {
+		if bar, ok := b.emit(b.pending[start]); ok {
+			bars = append(bars, bar)
+		}
+		delete(b.pending, start)
+	}
+	return bars
+}
+
+// emit converts accumulated state to a Bar; quote-only bars have no volume
+func (b *BarBuilder) emit(state *barState) (Bar, bool) {
+	prices := state.trades
+	if prices.count == 0 {
+		prices = state.quotes
+	}
+	if prices.count == 0 {
+		return Bar{}, false
+	}
+
+	b.stats.Bars++
+	return Bar{
+		Time:   state.start,
+		Open:   prices.open,
+		High:   prices.high,
+		Low:    prices.low,
+		Close:  prices.close,
+		Volume: state.volume,
+	}, true
+}
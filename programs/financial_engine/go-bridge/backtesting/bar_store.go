@@ -0,0 +1,201 @@
+package backtesting
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column names accepted by LoadBarsCSV, matched case-insensitively
+var (
+	timeColumns   = []string{"timestamp", "time", "date", "datetime", "t"}
+	symbolColumns = []string{"symbol", "ticker"}
+)
+
+// LoadBarsCSV reads bars from a CSV file with a header row, as written by the
+// data collectors or exported from a broker. It needs a time column
+// (timestamp, time, date or t) and close; open, high and low default to close
+// and volume to 0. Times may be RFC 3339, dates, "2006-01-02 15:04:05" or
+// Unix seconds, milliseconds or nanoseconds; dates and times without a zone
+// are exchange time (America/New_York). A symbol column splits rows by
+// symbol; otherwise every row belongs to defaultSymbol. Bars come back sorted
+// by time with duplicate timestamps resolved to the last row.
+func LoadBarsCSV(path, defaultSymbol string) (map[string][]Bar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header of %s: %w", path, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	find := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	timeCol := find(timeColumns...)
+	closeCol := find("close", "c")
+	if timeCol < 0 || closeCol < 0 {
+		return nil, fmt.Errorf("%s needs a time and a close column", path)
+	}
+	openCol, highCol, lowCol := find("open", "o"), find("high", "h"), find("low", "l")
+	volumeCol := find("volume", "v")
+	symbolCol := find(symbolColumns...)
+	if symbolCol < 0 && defaultSymbol == "" {
+		return nil, fmt.Errorf("%s has no symbol column and no default symbol", path)
+	}
+
+	exchange, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		exchange = time.UTC
+	}
+
+	bars := make(map[string][]Bar)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		ts, err := parseBarTime(field(timeCol), exchange)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		closePrice, err := strconv.ParseFloat(field(closeCol), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: bad close %q", path, line, field(closeCol))
+		}
+		bar := Bar{Time: ts, Open: closePrice, High: closePrice, Low: closePrice, Close: closePrice}
+		for _, col := range []struct {
+			index int
+			dest  *float64
+		}{{openCol, &bar.Open}, {highCol, &bar.High}, {lowCol, &bar.Low}, {volumeCol, &bar.Volume}} {
+			if raw := field(col.index); raw != "" {
+				if v, err := strconv.ParseFloat(raw, 64); err == nil {
+					*col.dest = v
+				}
+			}
+		}
+
+		symbol := defaultSymbol
+		if s := field(symbolCol); s != "" {
+			symbol = strings.ToUpper(s)
+		}
+		bars[symbol] = append(bars[symbol], bar)
+	}
+
+	for symbol, series := range bars {
+		bars[symbol] = sortBars(series)
+	}
+	return bars, nil
+}
+
+// LoadBarDir reads every .csv file under dir. Files without a symbol column
+// are named after their file, e.g. SPY.csv or SPY_daily.csv hold SPY. Bars
+// for a symbol spread across files are merged.
+func LoadBarDir(dir string) (map[string][]Bar, error) {
+	merged := make(map[string][]Bar)
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.EqualFold(filepath.Ext(path), ".csv") {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if i := strings.IndexAny(name, "_-."); i > 0 {
+			name = name[:i]
+		}
+		bars, err := LoadBarsCSV(path, strings.ToUpper(name))
+		if err != nil {
+			return err
+		}
+		for symbol, series := range bars {
+			merged[symbol] = append(merged[symbol], series...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for symbol, series := range merged {
+		merged[symbol] = sortBars(series)
+	}
+	return merged, nil
+}
+
+// DailyCloses reduces bars to the last close of each calendar day in loc,
+// keyed by date ("2006-01-02")
+func DailyCloses(bars []Bar, loc *time.Location) map[string]float64 {
+	closes := make(map[string]float64)
+	last := make(map[string]time.Time)
+	for _, bar := range bars {
+		date := bar.Time.In(loc).Format("2006-01-02")
+		if prev, ok := last[date]; !ok || !bar.Time.Before(prev) {
+			closes[date] = bar.Close
+			last[date] = bar.Time
+		}
+	}
+	return closes
+}
+
+// sortBars orders bars by time, keeping the last of any duplicate timestamps
+func sortBars(bars []Bar) []Bar {
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].Time.Before(bars[j].Time) })
+	out := bars[:0]
+	for _, bar := range bars {
+		if n := len(out); n > 0 && out[n-1].Time.Equal(bar.Time) {
+			out[n-1] = bar
+			continue
+		}
+		out = append(out, bar)
+	}
+	return out
+}
+
+// parseBarTime accepts the timestamp formats LoadBarsCSV documents, reading
+// zone-less times in loc
+func parseBarTime(raw string, loc *time.Location) (time.Time, error) {
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		switch {
+		case n > 1e17:
+			return time.Unix(0, n).UTC(), nil
+		case n > 1e11:
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return ts, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if ts, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return ts, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", raw)
+}
@@ -0,0 +1,145 @@
+package backtesting
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/protection"
+)
+
+// Account types supported by AccountProfile
+const (
+	AccountTypeCash   = "cash"
+	AccountTypeMargin = "margin"
+)
+
+// AccountProfile describes the simulated brokerage account a backtest runs against
+type AccountProfile struct {
+	AccountType      string  `json:"account_type"`              // "cash" or "margin"
+	StartingEquity   float64 `json:"starting_equity"`           // Initial account equity
+	PatternDayTrader bool    `json:"pattern_day_trader"`        // Account already flagged PDT
+	SettlementDays   int     `json:"settlement_days,omitempty"` // Cash settlement lag (default T+1)
+}
+
+// ComplianceReport summarises how simulated orders fared against protection rules
+type ComplianceReport struct {
+	AccountType    string         `json:"account_type"`
+	StartingEquity float64        `json:"starting_equity"`
+	OrdersChecked  int            `json:"orders_checked"`
+	SignalsBlocked int            `json:"signals_blocked"`
+	BlockedByRule  map[string]int `json:"blocked_by_rule"`
+	DayTrades      int            `json:"day_trades"`
+}
+
+// complianceSimulator runs simulated orders through internal/protection on simulated time
+type complianceSimulator struct {
+	protection *protection.AccountProtection
+	now        time.Time
+	report     *ComplianceReport
+	logger     *log.Logger
+}
+
+// newComplianceSimulator builds an AccountProtection configured from the profile
+func newComplianceSimulator(profile AccountProfile, logger *log.Logger) *complianceSimulator {
+	equity := decimal.NewFromFloat(profile.StartingEquity)
+
+	c := &complianceSimulator{
+		protection: protection.NewAccountProtection("backtest", equity),
+		report: &ComplianceReport{
+			AccountType:    profile.AccountType,
+			StartingEquity: profile.StartingEquity,
+			BlockedByRule:  make(map[string]int),
+		},
+		logger: logger,
+	}
+
+	c.protection.SetClock(func() time.Time { return c.now })
+	c.protection.SetLogger(log.New(io.Discard, "", 0))
+
+	if profile.AccountType == AccountTypeCash {
+		settlementDays := profile.SettlementDays
+		if settlementDays <= 0 {
+			settlementDays = protection.DefaultSettlementDays
+		}
+		c.protection.ConfigureCashAccount(equity, settlementDays)
+	} else {
+		c.protection.SetPatternDayTrader(profile.PatternDayTrader)
+		c.protection.SetStickyPDT(true)
+	}
+
+	return c
+}
+
+// tradingDate returns the US equity trading date for t, so fills late in the
+// New York evening don't land on the next UTC day
+func tradingDate(t time.Time) string {
+	if loc, err := time.LoadLocation("America/New_York"); err == nil {
+		t = t.In(loc)
+	}
+	return t.Format("2006-01-02")
+}
+
+// advance moves the simulated clock to the bar and refreshes account equity
+func (c *complianceSimulator) advance(bar Bar, equity float64) {
+	c.now = bar.Time
+	equityDec := decimal.NewFromFloat(equity)
+	c.protection.UpdateAccountMetrics(equityDec, decimal.Zero, equityDec)
+}
+
+// allow checks a simulated market order and records the blocking rule, if any
+func (c *complianceSimulator) allow(symbol, side string, qty, price float64) bool {
+	c.report.OrdersChecked++
+
+	order := protection.ProtectionOrder{
+		Symbol:      symbol,
+		Side:        side,
+		Type:        "market",
+		Qty:         decimal.NewFromFloat(qty).String(),
+		LimitPrice:  decimal.NewFromFloat(price).StringFixed(2), // Notional estimate for value checks
+		AssetClass:  "us_equity",
+		TimeInForce: "day",
+	}
+
+	rule, err := c.protection.ValidateOrder(order)
+	if err != nil {
+		c.report.SignalsBlocked++
+		c.report.BlockedByRule[rule]++
+		c.logger.Printf("%s %s signal blocked at %s: %v",
+			side, symbol, c.now.Format("2006-01-02 15:04"), err)
+		return false
+	}
+
+	return true
+}
+
+// recordFill feeds an execution back into the protection state
+func (c *complianceSimulator) recordFill(symbol, side string, qty, price float64, dayTrade bool) {
+	c.protection.RecordFill(symbol, side, decimal.NewFromFloat(qty), decimal.NewFromFloat(price))
+	if dayTrade {
+		c.report.DayTrades++
+	}
+}
+
+// String renders the report for console output
+func (r *ComplianceReport) String() string {
+	s := fmt.Sprintf("Account: %s ($%.2f)\n", r.AccountType, r.StartingEquity)
+	s += fmt.Sprintf("Orders Checked: %d\n", r.OrdersChecked)
+	s += fmt.Sprintf("Signals Blocked: %d\n", r.SignalsBlocked)
+	for _, rule := range []string{
+		protection.ProtectionPDT,
+		protection.ProtectionDTMC,
+		protection.ProtectionWashTrade,
+		protection.ProtectionEquityRatio,
+		protection.ProtectionCashSettlement,
+	} {
+		if count := r.BlockedByRule[rule]; count > 0 {
+			s += fmt.Sprintf("  %s: %d\n", rule, count)
+		}
+	}
+	s += fmt.Sprintf("Day Trades: %d\n", r.DayTrades)
+	return s
+}
@@ -23,6 +23,7 @@ type BacktestConfig struct {
 	Parameters map[string]interface{} `json:"parameters"`
 	StartDate  string                 `json:"start_date"`
 	EndDate    string                 `json:"end_date"`
+	Account    *AccountProfile        `json:"account,omitempty"` // Optional regulatory simulation
 }
 
 // BacktestResult stores comprehensive metrics from a backtest
@@ -133,6 +134,9 @@ func (bs *BacktestSuite) RunBacktest(config BacktestConfig) (*BacktestResult, er
 	// Create backtester
 	bt := NewBacktester(strategy, config.Symbol, start, end) // Create with proper args
 	bt.Portfolio = NewPortfolio(100000) // Set $100k initial capital
+	if config.Account != nil {
+		bt.SetAccountProfile(*config.Account)
+	}
 	
 	// Load market data
 	if bs.verbose {
@@ -189,6 +193,12 @@ func (bs *BacktestSuite) RunBacktest(config BacktestConfig) (*BacktestResult, er
 		"consecutive_losses": bt.Results.MaxConsecutiveLosses,
 	}
 	
+	if bt.Results.Compliance != nil {
+		result.TradeStatistics["signals_blocked"] = bt.Results.Compliance.SignalsBlocked
+		result.TradeStatistics["blocked_by_rule"] = bt.Results.Compliance.BlockedByRule
+		result.TradeStatistics["day_trades"] = bt.Results.Compliance.DayTrades
+	}
+	
 	// Risk metrics
 	result.RiskMetrics = map[string]float64{
 		"sharpe_ratio":       bt.Results.SharpeRatio,
@@ -439,6 +449,11 @@ func RunBacktestCLI() {
 		apiKey     = flag.String("api-key", "", "Alpaca API key")
 		apiSecret  = flag.String("api-secret", "", "Alpaca API secret")
 		verbose    = flag.Bool("verbose", true, "Verbose output")
+		
+		// Regulatory simulation
+		accountType = flag.String("account-type", "", "Simulate account rules: cash or margin (empty disables)")
+		equity      = flag.Float64("equity", 100000, "Starting equity for the simulated account")
+		pdt         = flag.Bool("pdt", false, "Simulated margin account is already flagged PDT")
 	)
 	flag.Parse()
 	
@@ -499,6 +514,19 @@ func RunBacktestCLI() {
 		}
 	}
 	
+	// Apply account profile to configs that don't define their own
+	if *accountType != "" {
+		for i := range configs {
+			if configs[i].Account == nil {
+				configs[i].Account = &AccountProfile{
+					AccountType:      *accountType,
+					StartingEquity:   *equity,
+					PatternDayTrader: *pdt,
+				}
+			}
+		}
+	}
+	
 	// Run backtests
 	fmt.Println("═══════════════════════════════════════════════════════════════")
 	fmt.Println("       THE GREAT SYNAPSE - COMPREHENSIVE BACKTESTING")
@@ -0,0 +1,66 @@
+package backtesting
+
+import (
+	"time"
+
+	"zig-financial-engine/strategies"
+)
+
+// SentimentGatedStrategy passes a strategy's signals through a
+// SentimentFilter running on the backtest's clock, the way a live strategy
+// calls FilterBuySignal and FilterSellSignal. The clock reads each bar's
+// open time, so only sentiment published before the bar began is used.
+type SentimentGatedStrategy struct {
+	Strategy BacktestStrategy
+	Symbol   string
+	Filter   *strategies.SentimentFilter
+
+	clock    time.Time
+	filtered int // Signals turned into HOLD
+}
+
+// NewSentimentGatedStrategy gates strategy with sentiment from history
+func NewSentimentGatedStrategy(strategy BacktestStrategy, symbol string, history strategies.HistoricalSentimentProvider) *SentimentGatedStrategy {
+	g := &SentimentGatedStrategy{Strategy: strategy, Symbol: symbol}
+	g.Filter = strategies.NewBacktestSentimentFilter(history, func() time.Time { return g.clock })
+	return g
+}
+
+// ProcessBar implements BacktestStrategy
+func (g *SentimentGatedStrategy) ProcessBar(bar Bar, portfolio *Portfolio) Signal {
+	g.clock = bar.Time
+	signal := g.Strategy.ProcessBar(bar, portfolio)
+
+	switch signal.Action {
+	case "BUY":
+		if !g.Filter.FilterBuySignal(g.Symbol, true) {
+			g.filtered++
+			return Signal{Action: "HOLD"}
+		}
+	case "SELL":
+		if !g.Filter.FilterSellSignal(g.Symbol, true) {
+			g.filtered++
+			return Signal{Action: "HOLD"}
+		}
+	}
+	return signal
+}
+
+// GetParameters implements BacktestStrategy
+func (g *SentimentGatedStrategy) GetParameters() map[string]interface{} {
+	params := make(map[string]interface{})
+	for k, v := range g.Strategy.GetParameters() {
+		params[k] = v
+	}
+	params["min_positive_sentiment"] = g.Filter.MinPositiveSentiment
+	params["max_negative_sentiment"] = g.Filter.MaxNegativeSentiment
+	params["sentiment_filtered"] = g.filtered
+	return params
+}
+
+// Reset implements BacktestStrategy
+func (g *SentimentGatedStrategy) Reset() {
+	g.Strategy.Reset()
+	g.clock = time.Time{}
+	g.filtered = 0
+}
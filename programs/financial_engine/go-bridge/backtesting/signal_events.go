@@ -0,0 +1,42 @@
+package backtesting
+
+import (
+	"zig-financial-engine/internal/features"
+	"zig-financial-engine/internal/labeling"
+)
+
+// SignalEvents replays bars through a strategy and returns its signals as
+// labeling events, BUY as a long bet and SELL as a short one, for
+// meta-labeling. The strategy is reset first and sees an empty portfolio,
+// so signals that depend on held positions are not reproduced.
+func SignalEvents(strategy BacktestStrategy, bars []Bar) []labeling.Event {
+	strategy.Reset()
+	portfolio := NewPortfolio(0)
+
+	var events []labeling.Event
+	for i, bar := range bars {
+		switch strategy.ProcessBar(bar, portfolio).Action {
+		case "BUY":
+			events = append(events, labeling.Event{Index: i, Side: 1})
+		case "SELL":
+			events = append(events, labeling.Event{Index: i, Side: -1})
+		}
+	}
+	return events
+}
+
+// FeatureBars converts backtest bars to the feature and labeling input type
+func FeatureBars(bars []Bar) []features.Bar {
+	converted := make([]features.Bar, len(bars))
+	for i, bar := range bars {
+		converted[i] = features.Bar{
+			Time:   bar.Time,
+			Open:   bar.Open,
+			High:   bar.High,
+			Low:    bar.Low,
+			Close:  bar.Close,
+			Volume: bar.Volume,
+		}
+	}
+	return converted
+}
@@ -15,13 +15,17 @@ import (
 
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+
+	"zig-financial-engine/internal/features"
+	"zig-financial-engine/internal/labeling"
+	"zig-financial-engine/strategies"
 )
 
 // Live Alpaca credentials
-const (
+var (
 	ALPACA_API_KEY    = os.Getenv("APCA_API_KEY_ID")
 	ALPACA_API_SECRET = os.Getenv("APCA_API_SECRET_KEY")
-	ALPACA_BASE_URL   = "https://paper-api.alpaca.markets"
+	ALPACA_BASE_URL   = strategies.BaseURLFromEnv()
 )
 
 // MarketData structure for AI-readable format
@@ -49,6 +53,20 @@ type MarketData struct {
 	Signal       float64   `json:"signal"`
 	BidAskRatio  float64   `json:"bid_ask_ratio"`
 	PriceLevel   int       `json:"price_level"` // 0=low, 1=mid, 2=high
+	FeatureVersion string  `json:"feature_version"`
+}
+
+// collectorFeatures are the bar features recorded with each quote, computed
+// on mid prices by the shared feature pipeline
+var collectorFeatures = features.Spec{
+	Name: "collector_v1",
+	Features: []features.Feature{
+		{Name: "return_5min", Kind: features.KindReturn, Window: 60, Normalize: features.Normalization{Scale: 100}}, // Percent over 60 5s ticks
+		{Name: "volatility", Kind: features.KindVolatility, Window: 20},
+		{Name: "rsi", Kind: features.KindRSI, Window: 14},
+		{Name: "macd", Kind: features.KindMACD, Fast: 12, Slow: 26},
+		{Name: "signal", Kind: features.KindMACDSignal, Fast: 12, Slow: 26, Window: 9},
+	},
 }
 
 // DataCollector manages automated data collection
@@ -63,8 +81,9 @@ type DataCollector struct {
 	isRunning       bool
 	tickCount       uint64
 	symbols         []string
-	priceHistory    map[string][]float64
 	lastPrices      map[string]float64
+	pipelines       map[string]*features.Pipeline // Per-symbol streaming features
+	featureMu       sync.Mutex
 	wg              sync.WaitGroup
 }
 
@@ -140,8 +159,8 @@ func NewDataCollector() (*DataCollector, error) {
 		csvWriter:    csvWriter,
 		jsonFile:     jsonFile,
 		symbols:      []string{"AAPL", "MSFT", "GOOGL", "TSLA", "AMZN", "META", "NVDA", "SPY", "QQQ", "IWM"},
-		priceHistory: make(map[string][]float64),
 		lastPrices:   make(map[string]float64),
+		pipelines:    make(map[string]*features.Pipeline),
 	}, nil
 }
 
@@ -222,8 +241,8 @@ func (dc *DataCollector) fetchAndStoreQuote(symbol string) error {
 	dc.tickCount++
 	dc.bufferMutex.Unlock()
 	
-	// Update price history
-	dc.updatePriceHistory(symbol, basePrice)
+	// Remember last price for the random walk
+	dc.lastPrices[symbol] = basePrice
 	
 	return nil
 }
@@ -254,52 +273,32 @@ func (dc *DataCollector) getBasePrice(symbol string) float64 {
 	return 100.0
 }
 
-// Update price history for technical indicators
-func (dc *DataCollector) updatePriceHistory(symbol string, price float64) {
-	history := dc.priceHistory[symbol]
-	history = append(history, price)
-	
-	// Keep last 200 prices for indicators
-	if len(history) > 200 {
-		history = history[1:]
-	}
-	
-	dc.priceHistory[symbol] = history
-	dc.lastPrices[symbol] = price
-}
-
 // Calculate ML features
 func (dc *DataCollector) calculateFeatures(data *MarketData) {
-	// Simple feature calculations
-	if history, ok := dc.priceHistory[data.Symbol]; ok && len(history) > 14 {
-		// 5-minute return (approximate)
-		if len(history) >= 60 {
-			data.Return5Min = (data.MidPrice - history[len(history)-60]) / history[len(history)-60] * 100
-		}
-		
-		// Volatility (standard deviation of last 20 prices)
-		if len(history) >= 20 {
-			var sum, sumSq float64
-			last20 := history[len(history)-20:]
-			for _, p := range last20 {
-				sum += p
-				sumSq += p * p
-			}
-			mean := sum / 20
-			data.Volatility = sumSq/20 - mean*mean
-		}
-		
-		// RSI (simplified)
-		data.RSI = 50 + (data.MidPrice-history[len(history)-14])/history[len(history)-14]*50
-		
-		// MACD (simplified)
-		if len(history) >= 26 {
-			ema12 := data.MidPrice * 0.15 + history[len(history)-12] * 0.85
-			ema26 := data.MidPrice * 0.07 + history[len(history)-26] * 0.93
-			data.MACD = ema12 - ema26
-			data.Signal = data.MACD * 0.2
-		}
-	}
+	// Bar features come from the shared pipeline so training data matches
+	// what live strategies compute
+	dc.featureMu.Lock()
+	pipeline, ok := dc.pipelines[data.Symbol]
+	if !ok {
+		pipeline, _ = features.NewPipeline(collectorFeatures)
+		dc.pipelines[data.Symbol] = pipeline
+	}
+	vector := pipeline.Update(features.Bar{
+		Time:   data.Timestamp,
+		Open:   data.MidPrice,
+		High:   data.MidPrice,
+		Low:    data.MidPrice,
+		Close:  data.MidPrice,
+		Volume: float64(data.Volume),
+	})
+	dc.featureMu.Unlock()
+
+	data.FeatureVersion = pipeline.Version()
+	data.Return5Min = vector[0]
+	data.Volatility = vector[1]
+	data.RSI = vector[2]
+	data.MACD = vector[3]
+	data.Signal = vector[4]
 	
 	// Bid-Ask ratio
 	if data.AskSize > 0 {
@@ -473,6 +472,46 @@ func (dc *DataCollector) GetStats() {
 	}
 }
 
+// LabeledData is a dataset row: the collected record and its outcome
+type LabeledData struct {
+	MarketData
+	Label        int       `json:"label"`         // Triple barrier: -1=stop, 0=time-out, 1=profit-take
+	LabelReturn  float64   `json:"label_return"`
+	LabelEnd     time.Time `json:"label_end"`     // When the label was decided
+	SampleWeight float64   `json:"sample_weight"` // 0 when the outcome is not known yet
+}
+
+// Label records per symbol with triple barriers on the mid price
+func labelDataset(records []MarketData, labeler labeling.TripleBarrier) ([]LabeledData, error) {
+	rows := make([]LabeledData, len(records))
+	bySymbol := make(map[string][]int)
+	for i, data := range records {
+		rows[i].MarketData = data
+		bySymbol[data.Symbol] = append(bySymbol[data.Symbol], i)
+	}
+	
+	for _, indexes := range bySymbol {
+		bars := make([]features.Bar, len(indexes))
+		for j, i := range indexes {
+			mid := records[i].MidPrice
+			bars[j] = features.Bar{Time: records[i].Timestamp, Open: mid, High: mid, Low: mid, Close: mid, Volume: float64(records[i].Volume)}
+		}
+		labels, err := labeler.Label(bars, nil)
+		if err != nil {
+			return nil, err
+		}
+		labeling.SampleWeights(labels)
+		for _, label := range labels {
+			row := &rows[indexes[label.Start]]
+			row.Label = label.Class
+			row.LabelReturn = label.Return
+			row.LabelEnd = label.EndTime
+			row.SampleWeight = label.Weight
+		}
+	}
+	return rows, nil
+}
+
 // Create ML-ready dataset
 func (dc *DataCollector) CreateMLDataset() error {
 	fmt.Println("\n🤖 === CREATING ML-READY DATASET ===")
@@ -487,6 +526,13 @@ func (dc *DataCollector) CreateMLDataset() error {
 		return fmt.Errorf("no data to process")
 	}
 	
+	// Label outcomes alongside the features
+	labeler := labeling.DefaultTripleBarrier()
+	rows, err := labelDataset(allData, labeler)
+	if err != nil {
+		return fmt.Errorf("failed to label dataset: %w", err)
+	}
+	
 	// Create feature matrix file
 	timestamp := time.Now().Format("20060102_150405")
 	featurePath := filepath.Join(dc.dataDir, "features", fmt.Sprintf("ml_features_%s.json", timestamp))
@@ -509,8 +555,10 @@ func (dc *DataCollector) CreateMLDataset() error {
 				"volume", "vwap", "return_5min", "volatility",
 				"rsi", "macd", "signal", "bid_ask_ratio", "price_level",
 			},
+			"label_names": []string{"label", "label_return", "label_end", "sample_weight"},
+			"labeling":    labeler,
 		},
-		"data": allData,
+		"data": rows,
 	}
 	
 	// Write ML dataset
@@ -17,6 +17,11 @@ import (
 	"github.com/xitongsys/parquet-go-source/local"
 	"github.com/xitongsys/parquet-go/parquet"
 	"github.com/xitongsys/parquet-go/writer"
+
+	"zig-financial-engine/backtesting"
+	"zig-financial-engine/internal/features"
+	"zig-financial-engine/internal/labeling"
+	"zig-financial-engine/strategies"
 )
 
 // Configuration
@@ -79,7 +84,27 @@ type MLFeatures struct {
 	VolumeMA        float64   `parquet:"name=volume_ma, type=DOUBLE"`
 	SpreadBPS       float64   `parquet:"name=spread_bps, type=DOUBLE"`
 	ImbalanceRatio  float64   `parquet:"name=imbalance_ratio, type=DOUBLE"`
-	Label           int32     `parquet:"name=label, type=INT32"` // -1=sell, 0=hold, 1=buy
+	Label           int32     `parquet:"name=label, type=INT32"` // Triple barrier: -1=stop, 0=time-out, 1=profit-take
+	LabelReturn     float64   `parquet:"name=label_return, type=DOUBLE"`
+	LabelEnd        int64     `parquet:"name=label_end, type=INT64"` // Timestamp the label was decided at
+	SampleWeight    float64   `parquet:"name=sample_weight, type=DOUBLE"` // 0 when the outcome is not known yet
+	MetaSide        int32     `parquet:"name=meta_side, type=INT32"` // Primary strategy signal on this bar: 1=buy, -1=sell, 0=none
+	MetaLabel       int32     `parquet:"name=meta_label, type=INT32"` // 1 when acting on the signal paid off
+	FeatureVersion  string    `parquet:"name=feature_version, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// barFeatures defines the MLFeatures columns computed by the shared feature
+// pipeline, so training files match what strategies compute live
+var barFeatures = features.Spec{
+	Name: "bars_v1",
+	Features: []features.Feature{
+		{Name: "returns_1min", Kind: features.KindReturn, Window: 1, Normalize: features.Normalization{Scale: 100}},
+		{Name: "returns_5min", Kind: features.KindReturn, Window: 5, Normalize: features.Normalization{Scale: 100}},
+		{Name: "returns_15min", Kind: features.KindReturn, Window: 15, Normalize: features.Normalization{Scale: 100}},
+		{Name: "volatility", Kind: features.KindVolatility, Window: 20},
+		{Name: "rsi", Kind: features.KindRSI, Window: 14},
+		{Name: "macd", Kind: features.KindMACD, Fast: 12, Slow: 26},
+	},
 }
 
 // Data collector manages all data streams
@@ -93,6 +118,9 @@ type DataCollector struct {
 	tradeBuffer    []TradeData
 	barBuffer      []BarData
 	featureBuffer  []MLFeatures
+	pipelines      map[string]*features.Pipeline // Per-symbol feature state across flushes
+	labeler        labeling.TripleBarrier
+	metaStrategy   string // Backtest strategy whose signals are meta-labeled, e.g. "rsi"
 	mu             sync.Mutex
 	wg             sync.WaitGroup
 	ctx            context.Context
@@ -121,7 +149,7 @@ func NewDataCollector(symbols []string) (*DataCollector, error) {
 	alpacaClient := alpaca.NewClient(alpaca.ClientOpts{
 		APIKey:    ALPACA_API_KEY,
 		APISecret: ALPACA_API_SECRET,
-		BaseURL:   "https://paper-api.alpaca.markets",
+		BaseURL:   strategies.BaseURLFromEnv(),
 	})
 
 	marketClient := marketdata.NewClient(marketdata.ClientOpts{
@@ -146,6 +174,9 @@ func NewDataCollector(symbols []string) (*DataCollector, error) {
 		tradeBuffer:   make([]TradeData, 0, 10000),
 		barBuffer:     make([]BarData, 0, 1000),
 		featureBuffer: make([]MLFeatures, 0, 1000),
+		pipelines:     make(map[string]*features.Pipeline),
+		labeler:       labeling.DefaultTripleBarrier(),
+		metaStrategy:  os.Getenv("META_LABEL_STRATEGY"),
 		ctx:           ctx,
 		cancel:        cancel,
 	}, nil
@@ -380,50 +411,127 @@ func (dc *DataCollector) flushBarBuffer() error {
 
 // Generate ML features from bar data
 func (dc *DataCollector) generateMLFeatures(bars []BarData) {
-	// This is a simplified example - real feature engineering would be more complex
-	for i, bar := range bars {
-		feature := MLFeatures{
-			Symbol:    bar.Symbol,
-			Timestamp: bar.Timestamp,
-			Price:     bar.Close,
-			Volume:    bar.Volume,
+	// Bars may interleave symbols; each is featured and labeled on its own path
+	bySymbol := make(map[string][]BarData)
+	var symbols []string
+	for _, bar := range bars {
+		if _, ok := bySymbol[bar.Symbol]; !ok {
+			symbols = append(symbols, bar.Symbol)
 		}
+		bySymbol[bar.Symbol] = append(bySymbol[bar.Symbol], bar)
+	}
+	for _, symbol := range symbols {
+		dc.generateSymbolFeatures(symbol, bySymbol[symbol])
+	}
 
-		// Calculate returns
-		if i > 0 {
-			feature.Returns1Min = (bar.Close - bars[i-1].Close) / bars[i-1].Close * 100
-		}
+	// Flush features if buffer is large
+	if len(dc.featureBuffer) >= 100 {
+		dc.flushFeatureBuffer()
+	}
+}
 
-		// Simple volatility (would use rolling window in production)
-		if i > 5 {
-			var sum float64
-			for j := i - 5; j < i; j++ {
-				sum += bars[j].High - bars[j].Low
-			}
-			feature.Volatility = sum / 5
+// Feature and label one symbol's bars, oldest first
+func (dc *DataCollector) generateSymbolFeatures(symbol string, bars []BarData) {
+	input := make([]features.Bar, len(bars))
+	for i, bar := range bars {
+		input[i] = features.Bar{
+			Time:   time.Unix(bar.Timestamp, 0),
+			Open:   bar.Open,
+			High:   bar.High,
+			Low:    bar.Low,
+			Close:  bar.Close,
+			Volume: float64(bar.Volume),
 		}
+	}
 
-		// Label for supervised learning (simplified)
-		if i < len(bars)-1 {
-			futureReturn := (bars[i+1].Close - bar.Close) / bar.Close * 100
-			if futureReturn > 0.1 {
-				feature.Label = 1 // Buy signal
-			} else if futureReturn < -0.1 {
-				feature.Label = -1 // Sell signal
-			} else {
-				feature.Label = 0 // Hold
-			}
+	// Each symbol streams through its own pipeline across calls
+	dc.mu.Lock()
+	pipeline, ok := dc.pipelines[symbol]
+	if !ok {
+		pipeline, _ = features.NewPipeline(barFeatures)
+		dc.pipelines[symbol] = pipeline
+	}
+	vectors := make([][]float64, len(input))
+	ready := make([]bool, len(input))
+	for i, bar := range input {
+		vectors[i] = pipeline.Update(bar)
+		ready[i] = pipeline.Ready()
+	}
+	dc.mu.Unlock()
+
+	// Labels only see this batch, so its last bars have no outcome yet and
+	// are written with zero weight
+	labels, err := dc.labeler.Label(input, nil)
+	if err != nil {
+		log.Printf("Error labeling %s: %v", symbol, err)
+	}
+	labeling.SampleWeights(labels)
+	byStart := labeling.Index(labels)
+	metaByStart := dc.metaLabels(symbol, input)
+
+	for i, bar := range bars {
+		// Rows before the pipeline has a full lookback are not usable training data
+		if !ready[i] {
+			continue
+		}
+		vector := vectors[i]
+		feature := MLFeatures{
+			Symbol:         bar.Symbol,
+			Timestamp:      bar.Timestamp,
+			Price:          bar.Close,
+			Volume:         bar.Volume,
+			Returns1Min:    vector[0],
+			Returns5Min:    vector[1],
+			Returns15Min:   vector[2],
+			Volatility:     vector[3],
+			RSI:            vector[4],
+			MACD:           vector[5],
+			FeatureVersion: pipeline.Version(),
+		}
+		if label, ok := byStart[i]; ok {
+			feature.Label = int32(label.Class)
+			feature.LabelReturn = label.Return
+			feature.LabelEnd = label.EndTime.Unix()
+			feature.SampleWeight = label.Weight
+		}
+		if meta, ok := metaByStart[i]; ok {
+			feature.MetaSide = int32(meta.Side)
+			feature.MetaLabel = int32(meta.Class)
 		}
 
 		dc.mu.Lock()
 		dc.featureBuffer = append(dc.featureBuffer, feature)
 		dc.mu.Unlock()
 	}
+}
 
-	// Flush features if buffer is large
-	if len(dc.featureBuffer) >= 100 {
-		dc.flushFeatureBuffer()
+// Meta-label the configured primary strategy's signals on one symbol's bars
+func (dc *DataCollector) metaLabels(symbol string, bars []features.Bar) map[int]labeling.Label {
+	if dc.metaStrategy == "" {
+		return nil
+	}
+	strategy, err := backtesting.CreateBacktestStrategy(dc.metaStrategy, symbol)
+	if err != nil {
+		log.Printf("Meta-labeling disabled: %v", err)
+		dc.metaStrategy = ""
+		return nil
+	}
+
+	replay := make([]backtesting.Bar, len(bars))
+	for i, bar := range bars {
+		replay[i] = backtesting.Bar{Time: bar.Time, Open: bar.Open, High: bar.High, Low: bar.Low, Close: bar.Close, Volume: bar.Volume}
+	}
+	labels, err := dc.labeler.Label(bars, backtesting.SignalEvents(strategy, replay))
+	if err != nil {
+		log.Printf("Error meta-labeling %s: %v", symbol, err)
+		return nil
+	}
+	meta, err := labeling.MetaLabels(labels)
+	if err != nil {
+		log.Printf("Error meta-labeling %s: %v", symbol, err)
+		return nil
 	}
+	return labeling.Index(meta)
 }
 
 // Save ML features to Parquet
@@ -0,0 +1,320 @@
+package main
+
+// dataset-builder assembles a training set from data collector output. Bars
+// are read from every CSV, JSON export and Parquet bar file under the input
+// paths, deduplicated by symbol and timestamp, featured with a versioned
+// feature spec and labeled. The rows are split chronologically into train,
+// validation and test sets, and into purged, embargoed K folds, so no label
+// window reaches across a split boundary.
+//
+//	dataset-builder -in ./market_data -out ./datasets/aapl_v1 -label triple_barrier -folds 5
+//
+// The output directory holds dataset.parquet (every row with its split),
+// NumPy arrays (X.npy, y.npy, weights.npy, times.npy, label_end.npy and
+// train_idx.npy, val_idx.npy, test_idx.npy), folds.json and a manifest.json
+// naming the feature spec version the rows were computed with.
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"zig-financial-engine/internal/dataset"
+	"zig-financial-engine/internal/features"
+	"zig-financial-engine/internal/labeling"
+)
+
+// Manifest describes a built dataset
+type Manifest struct {
+	CreatedAt      string               `json:"created_at"`
+	FeatureVersion string               `json:"feature_version"`
+	FeatureSpec    features.Spec        `json:"feature_spec"`
+	FeatureNames   []string             `json:"feature_names"`
+	Labeling       dataset.LabelConfig  `json:"labeling"`
+	Inputs         []InputFile          `json:"inputs"`
+	Duplicates     int                  `json:"duplicates"` // Records dropped as repeated symbol/timestamp pairs
+	Symbols        []string             `json:"symbols"`
+	Rows           int                  `json:"rows"`
+	Start          time.Time            `json:"start"`
+	End            time.Time            `json:"end"`
+	Splits         map[string]int       `json:"splits"` // Rows in train, validation, test and purged
+	Embargo        string               `json:"embargo"`
+	Folds          int                  `json:"folds"`
+	Files          map[string]ArrayFile `json:"files"`
+}
+
+// InputFile is one file the builder read
+type InputFile struct {
+	Path    string `json:"path"`
+	Records int    `json:"records"`
+}
+
+// ArrayFile describes one output array
+type ArrayFile struct {
+	DType string `json:"dtype"`
+	Shape []int  `json:"shape"`
+}
+
+func main() {
+	var (
+		inputs     = flag.String("in", "./market_data", "Comma-separated collector files or directories, searched recursively")
+		out        = flag.String("out", "./datasets/"+time.Now().Format("20060102_150405"), "Output directory")
+		specPath   = flag.String("spec", "", "Feature spec JSON (default: the ML strategy's default spec)")
+		symbolList = flag.String("symbols", "", "Comma-separated symbols to keep (default: all)")
+		method     = flag.String("label", string(dataset.LabelTripleBarrier), "Labels: triple_barrier, fixed_horizon or meta")
+		profitTake = flag.Float64("pt", 2, "Triple barrier profit-take, in target moves")
+		stopLoss   = flag.Float64("sl", 1, "Triple barrier stop-loss, in target moves")
+		maxHolding = flag.Int("max-holding", 20, "Triple barrier time-out, in bars")
+		volSpan    = flag.Int("vol-span", 50, "EWMA span of the volatility that sizes the barriers")
+		minTarget  = flag.Float64("min-target", 0, "Skip triple-barrier events whose target move is smaller")
+		horizon    = flag.Int("horizon", 5, "Fixed-horizon labels: bars ahead")
+		threshold  = flag.Float64("threshold", 0.001, "Fixed-horizon labels: return dead band")
+		strategy   = flag.String("strategy", "", "Meta labels: primary backtest strategy (rsi, ma, bb, macd, vwap)")
+		valShare   = flag.Float64("val", 0.15, "Share of rows for validation")
+		testShare  = flag.Float64("test", 0.15, "Share of rows for test")
+		folds      = flag.Int("folds", 5, "Purged K-fold splits (0 disables)")
+		embargo    = flag.Duration("embargo", 0, "Gap after each test fold and split boundary (0 uses the longest label horizon)")
+	)
+	flag.Parse()
+
+	spec := features.DefaultSpec()
+	if *specPath != "" {
+		loaded, err := features.LoadSpec(*specPath)
+		if err != nil {
+			log.Fatalf("Failed to load feature spec: %v", err)
+		}
+		spec = loaded
+	}
+	config := dataset.LabelConfig{
+		Method: dataset.LabelMethod(*method),
+		Barrier: labeling.TripleBarrier{
+			ProfitTake: *profitTake,
+			StopLoss:   *stopLoss,
+			MaxHolding: *maxHolding,
+			VolSpan:    *volSpan,
+			MinTarget:  *minTarget,
+		},
+		Strategy: *strategy,
+	}
+	if config.Method == dataset.LabelFixedHorizon {
+		config.Barrier = labeling.TripleBarrier{}
+		config.Horizon, config.Threshold = *horizon, *threshold
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatalf("Invalid labeling: %v", err)
+	}
+
+	// Load and deduplicate
+	paths, err := collectFiles(strings.Split(*inputs, ","))
+	if err != nil {
+		log.Fatalf("Failed to list inputs: %v", err)
+	}
+	keep := make(map[string]bool)
+	for _, s := range strings.Split(*symbolList, ",") {
+		if s = strings.TrimSpace(strings.ToUpper(s)); s != "" {
+			keep[s] = true
+		}
+	}
+	var records []dataset.Record
+	var inputFiles []InputFile
+	for _, path := range paths {
+		loaded, err := loadFile(path)
+		if err != nil {
+			log.Printf("Skipping %s: %v", path, err)
+			continue
+		}
+		if len(loaded) == 0 {
+			continue
+		}
+		if len(keep) > 0 {
+			filtered := loaded[:0]
+			for _, r := range loaded {
+				if keep[r.Symbol] {
+					filtered = append(filtered, r)
+				}
+			}
+			loaded = filtered
+		}
+		records = append(records, loaded...)
+		inputFiles = append(inputFiles, InputFile{Path: path, Records: len(loaded)})
+	}
+	series, duplicates := dataset.Dedup(records)
+	fmt.Printf("Read %d records from %d files (%d duplicates dropped), %d symbols\n",
+		len(records), len(inputFiles), duplicates, len(series))
+
+	// Feature, label and split
+	rows, err := dataset.Build(spec, config, series)
+	if err != nil {
+		log.Fatalf("Failed to build dataset: %v", err)
+	}
+	if len(rows) == 0 {
+		log.Fatalf("No labeled rows; need more than %d bars per symbol", spec.Warmup())
+	}
+	gap := *embargo
+	if gap == 0 {
+		gap = dataset.DefaultEmbargo(rows)
+	}
+	split, err := dataset.ChronologicalSplit(rows, *valShare, *testShare, gap)
+	if err != nil {
+		log.Fatalf("Failed to split: %v", err)
+	}
+	var cv []dataset.Fold
+	if *folds > 0 {
+		if cv, err = dataset.PurgedKFold(rows, *folds, gap); err != nil {
+			log.Fatalf("Failed to build folds: %v", err)
+		}
+	}
+
+	// Write
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		log.Fatalf("Failed to create %s: %v", *out, err)
+	}
+	files, err := writeArrays(*out, rows, split, len(spec.Features))
+	if err != nil {
+		log.Fatalf("Failed to write arrays: %v", err)
+	}
+	if err := writeParquet(filepath.Join(*out, "dataset.parquet"), rows, spec.Names(), split); err != nil {
+		log.Fatalf("Failed to write Parquet: %v", err)
+	}
+	if cv != nil {
+		if err := writeJSON(filepath.Join(*out, "folds.json"), cv); err != nil {
+			log.Fatalf("Failed to write folds: %v", err)
+		}
+	}
+
+	manifest := Manifest{
+		CreatedAt:      time.Now().UTC().Format(time.RFC3339),
+		FeatureVersion: spec.Version(),
+		FeatureSpec:    spec,
+		FeatureNames:   spec.Names(),
+		Labeling:       config,
+		Inputs:         inputFiles,
+		Duplicates:     duplicates,
+		Symbols:        make([]string, 0, len(series)),
+		Rows:           len(rows),
+		Start:          rows[0].Time,
+		End:            rows[len(rows)-1].Time,
+		Splits: map[string]int{
+			"train":      len(split.Train),
+			"validation": len(split.Validation),
+			"test":       len(split.Test),
+			"purged":     len(split.Purged),
+		},
+		Embargo: gap.String(),
+		Folds:   len(cv),
+		Files:   files,
+	}
+	for symbol := range series {
+		manifest.Symbols = append(manifest.Symbols, symbol)
+	}
+	sort.Strings(manifest.Symbols)
+	if err := writeJSON(filepath.Join(*out, "manifest.json"), manifest); err != nil {
+		log.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	fmt.Printf("Wrote %d rows of %s to %s: train %d, validation %d, test %d, purged %d, %d folds (embargo %s)\n",
+		len(rows), spec.Version(), *out, len(split.Train), len(split.Validation), len(split.Test),
+		len(split.Purged), len(cv), gap)
+}
+
+// collectFiles expands inputs to the readable files under them, in name order
+func collectFiles(inputs []string) ([]string, error) {
+	var paths []string
+	for _, input := range inputs {
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		err := filepath.WalkDir(input, func(path string, entry os.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".csv", ".json", ".parquet":
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// loadFile reads one collector file by extension
+func loadFile(path string) ([]dataset.Record, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return dataset.LoadCSV(path)
+	case ".json":
+		return dataset.LoadJSON(path)
+	case ".parquet":
+		return loadParquetBars(path)
+	}
+	return nil, fmt.Errorf("unsupported file type")
+}
+
+// writeArrays writes the NumPy arrays and returns their descriptions
+func writeArrays(dir string, rows []dataset.Row, split dataset.Split, width int) (map[string]ArrayFile, error) {
+	n := len(rows)
+	x := make([]float32, 0, n*width)
+	y := make([]int8, n)
+	weights := make([]float64, n)
+	times := make([]int64, n)
+	ends := make([]int64, n)
+	for i, row := range rows {
+		for _, v := range row.Features {
+			x = append(x, float32(v))
+		}
+		y[i] = int8(row.Label.Class)
+		weights[i] = row.Label.Weight
+		times[i] = row.Time.Unix()
+		ends[i] = row.Label.EndTime.Unix()
+	}
+
+	files := map[string]ArrayFile{
+		"X.npy":         {DType: "float32", Shape: []int{n, width}},
+		"y.npy":         {DType: "int8", Shape: []int{n}},
+		"weights.npy":   {DType: "float64", Shape: []int{n}},
+		"times.npy":     {DType: "int64", Shape: []int{n}}, // Unix seconds
+		"label_end.npy": {DType: "int64", Shape: []int{n}}, // Unix seconds
+	}
+	writes := []error{
+		dataset.WriteNPY(filepath.Join(dir, "X.npy"), []int{n, width}, x),
+		dataset.WriteNPY(filepath.Join(dir, "y.npy"), []int{n}, y),
+		dataset.WriteNPY(filepath.Join(dir, "weights.npy"), []int{n}, weights),
+		dataset.WriteNPY(filepath.Join(dir, "times.npy"), []int{n}, times),
+		dataset.WriteNPY(filepath.Join(dir, "label_end.npy"), []int{n}, ends),
+	}
+	for name, indexes := range map[string][]int{"train_idx.npy": split.Train, "val_idx.npy": split.Validation, "test_idx.npy": split.Test} {
+		values := make([]int64, len(indexes))
+		for i, index := range indexes {
+			values[i] = int64(index)
+		}
+		files[name] = ArrayFile{DType: "int64", Shape: []int{len(values)}}
+		writes = append(writes, dataset.WriteNPY(filepath.Join(dir, name), []int{len(values)}, values))
+	}
+	for _, err := range writes {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"zig-financial-engine/internal/dataset"
+	"zig-financial-engine/internal/features"
+)
+
+// collectorBar matches the bar files written by cmd/data-collector
+type collectorBar struct {
+	Symbol     string  `parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Timestamp  int64   `parquet:"name=timestamp, type=INT64"`
+	Open       float64 `parquet:"name=open, type=DOUBLE"`
+	High       float64 `parquet:"name=high, type=DOUBLE"`
+	Low        float64 `parquet:"name=low, type=DOUBLE"`
+	Close      float64 `parquet:"name=close, type=DOUBLE"`
+	Volume     int64   `parquet:"name=volume, type=INT64"`
+	TradeCount int32   `parquet:"name=trade_count, type=INT32"`
+	VWAP       float64 `parquet:"name=vwap, type=DOUBLE"`
+}
+
+// loadParquetBars reads a collector bar file (bars_*.parquet or
+// historical_*.parquet). Tick, trade and feature files are skipped; features
+// are always recomputed from bars so every row shares one spec version.
+func loadParquetBars(path string) ([]dataset.Record, error) {
+	name := filepath.Base(path)
+	if !strings.HasPrefix(name, "bars_") && !strings.HasPrefix(name, "historical_") {
+		return nil, nil
+	}
+
+	fr, err := local.NewLocalFileReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fr.Close()
+
+	pr, err := reader.NewParquetReader(fr, new(collectorBar), 4)
+	if err != nil {
+		return nil, err
+	}
+	defer pr.ReadStop()
+
+	bars := make([]collectorBar, int(pr.GetNumRows()))
+	if err := pr.Read(&bars); err != nil {
+		return nil, err
+	}
+
+	records := make([]dataset.Record, 0, len(bars))
+	for _, bar := range bars {
+		records = append(records, dataset.Record{Symbol: bar.Symbol, Bar: features.Bar{
+			Time:   time.Unix(bar.Timestamp, 0),
+			Open:   bar.Open,
+			High:   bar.High,
+			Low:    bar.Low,
+			Close:  bar.Close,
+			Volume: float64(bar.Volume),
+		}})
+	}
+	return records, nil
+}
+
+// writeParquet writes every row with its features, label and split. The
+// schema follows the feature spec, so rows are written through the JSON
+// writer rather than a fixed struct.
+func writeParquet(path string, rows []dataset.Row, names []string, split dataset.Split) error {
+	splitOf := make([]string, len(rows))
+	for name, indexes := range map[string][]int{
+		"train": split.Train, "validation": split.Validation, "test": split.Test, "purged": split.Purged,
+	} {
+		for _, i := range indexes {
+			splitOf[i] = name
+		}
+	}
+
+	fields := []string{
+		`{"Tag": "name=symbol, type=BYTE_ARRAY, convertedtype=UTF8"}`,
+		`{"Tag": "name=timestamp, type=INT64"}`,
+		`{"Tag": "name=label_end, type=INT64"}`,
+	}
+	for _, name := range names {
+		fields = append(fields, fmt.Sprintf(`{"Tag": "name=%s, type=DOUBLE"}`, name))
+	}
+	fields = append(fields,
+		`{"Tag": "name=label, type=INT32"}`,
+		`{"Tag": "name=label_return, type=DOUBLE"}`,
+		`{"Tag": "name=side, type=INT32"}`,
+		`{"Tag": "name=barrier, type=BYTE_ARRAY, convertedtype=UTF8"}`,
+		`{"Tag": "name=sample_weight, type=DOUBLE"}`,
+		`{"Tag": "name=split, type=BYTE_ARRAY, convertedtype=UTF8"}`,
+	)
+	schema := `{"Tag": "name=dataset", "Fields": [` + strings.Join(fields, ", ") + `]}`
+
+	fw, err := local.NewLocalFileWriter(path)
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+
+	pw, err := writer.NewJSONWriter(schema, fw, 4)
+	if err != nil {
+		return err
+	}
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+
+	for i, row := range rows {
+		record := map[string]interface{}{
+			"symbol":        row.Symbol,
+			"timestamp":     row.Time.Unix(),
+			"label_end":     row.Label.EndTime.Unix(),
+			"label":         row.Label.Class,
+			"label_return":  row.Label.Return,
+			"side":          row.Label.Side,
+			"barrier":       string(row.Label.Barrier),
+			"sample_weight": row.Label.Weight,
+			"split":         splitOf[i],
+		}
+		for j, name := range names {
+			record[name] = row.Features[j]
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if err := pw.Write(string(data)); err != nil {
+			return err
+		}
+	}
+	return pw.WriteStop()
+}
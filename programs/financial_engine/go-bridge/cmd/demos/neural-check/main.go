@@ -11,6 +11,8 @@ import (
 	"time"
 
 	ort "github.com/yalue/onnxruntime_go"
+
+	"zig-financial-engine/internal/features"
 )
 
 // AlpacaBar represents market data
@@ -68,26 +70,30 @@ func fetchRealBars(symbol string, days int) ([]AlpacaBar, error) {
 }
 
 func calculateFeatures(bars []AlpacaBar) [][]float32 {
-	// Simple feature engineering (50 features per bar)
-	// Real implementation would match Python feature engineering
-	sequences := make([][]float32, 0)
+	// Features come from the shared pipeline (the ML strategy's default
+	// spec) over every fetched bar; the demo model takes 50 inputs per bar,
+	// so columns past the spec are zero
+	input := make([]features.Bar, len(bars))
+	for i, bar := range bars {
+		t, _ := time.Parse(time.RFC3339, bar.T)
+		input[i] = features.Bar{Time: t, Open: bar.O, High: bar.H, Low: bar.L, Close: bar.C, Volume: float64(bar.V)}
+	}
+	rows, err := features.Batch(features.DefaultSpec(), input)
+	if err != nil {
+		log.Fatalf("Failed to compute features: %v", err)
+	}
 	
-	for i := 0; i < len(bars) && i < 60; i++ {
-		features := make([]float32, 50)
-		
-		// Basic price features (normalized)
-		basePrice := bars[0].C
-		features[0] = float32((bars[i].C - basePrice) / basePrice)      // Return
-		features[1] = float32((bars[i].H - bars[i].L) / bars[i].C)      // Range
-		features[2] = float32((bars[i].C - bars[i].O) / bars[i].O)      // Change
-		features[3] = float32(math.Log(float64(bars[i].V + 1)) / 20.0)  // Log volume
-		
-		// Mock technical indicators (would be real in production)
-		for j := 4; j < 50; j++ {
-			features[j] = float32(math.Sin(float64(i*j)) * 0.01) // Placeholder
+	// Keep the most recent 60 bars
+	if len(rows) > 60 {
+		rows = rows[len(rows)-60:]
+	}
+	sequences := make([][]float32, 0, 60)
+	for _, row := range rows {
+		vector := make([]float32, 50)
+		for j, val := range row {
+			vector[j] = float32(val)
 		}
-		
-		sequences = append(sequences, features)
+		sequences = append(sequences, vector)
 	}
 	
 	// Pad if needed
@@ -0,0 +1,190 @@
+package main
+
+// mock-broker serves an Alpaca-compatible trading and market data API from
+// local files, so strategies, the OMS and the stream clients run end to end
+// without network access. Orders are filled by an in-memory simulator
+// against the replayed quotes, trades and bars, and fills go out on the
+// trade_updates stream.
+//
+//	mock-broker -data ./market_data -speed 60
+//
+// Point clients at it through the environment:
+//
+//	export APCA_API_BASE_URL=http://localhost:8765
+//	export APCA_API_DATA_URL=http://localhost:8765
+//	export DATA_PROXY_WS=ws://localhost:8765/v2
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/dataset"
+	"zig-financial-engine/internal/mockbroker"
+)
+
+func main() {
+	var (
+		addr        = flag.String("addr", ":8765", "Listen address")
+		inputs      = flag.String("data", "./market_data", "Comma-separated market data files or directories, searched recursively")
+		cash        = flag.Float64("cash", mockbroker.DefaultConfig().Cash, "Starting cash")
+		multiplier  = flag.Float64("multiplier", mockbroker.DefaultConfig().Multiplier, "Buying power multiplier (1 is a cash account)")
+		slippageBps = flag.Float64("slippage-bps", mockbroker.DefaultConfig().SlippageBps, "Slippage on market and stop fills, in basis points")
+		noShorting  = flag.Bool("no-shorting", false, "Reject sells that would open a short")
+		speed       = flag.Float64("speed", 1, "Replay speed multiple of real time (0 replays as fast as possible)")
+		maxPause    = flag.Duration("max-pause", 5*time.Second, "Longest wait between replayed events")
+		start       = flag.String("start", "", "Stream from this RFC 3339 time; earlier data only warms up prices and bar history")
+		key         = flag.String("key", "", "Required API key ID (default: accept any)")
+		secret      = flag.String("secret", "", "Required API secret key")
+	)
+	flag.Parse()
+
+	paths, err := collectFiles(strings.Split(*inputs, ","))
+	if err != nil {
+		log.Fatalf("Failed to list market data: %v", err)
+	}
+	var events []broker.Event
+	for _, path := range paths {
+		loaded, err := loadEvents(path)
+		if err != nil {
+			log.Printf("Skipping %s: %v", path, err)
+			continue
+		}
+		events = append(events, loaded...)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	log.Printf("Loaded %d market data events from %d files", len(events), len(paths))
+
+	sim := mockbroker.NewSimulator(mockbroker.Config{
+		Cash:        *cash,
+		Multiplier:  *multiplier,
+		SlippageBps: *slippageBps,
+		NoShorting:  *noShorting,
+	})
+	history := mockbroker.NewHistory()
+	server := mockbroker.NewServer(sim, history)
+	server.KeyID, server.SecretKey = *key, *secret
+
+	replayer := mockbroker.NewReplayer(events, sim, history, server.Publish)
+	replayer.Speed, replayer.MaxPause = *speed, *maxPause
+	if *start != "" {
+		if replayer.Start, err = time.Parse(time.RFC3339, *start); err != nil {
+			log.Fatalf("Invalid -start: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpServer := &http.Server{Addr: *addr, Handler: server.Handler()}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	host := *addr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	fmt.Printf("Mock broker listening on %s\n\n", *addr)
+	fmt.Printf("  export APCA_API_BASE_URL=http://%s\n", host)
+	fmt.Printf("  export APCA_API_DATA_URL=http://%s\n", host)
+	fmt.Printf("  export DATA_PROXY_WS=ws://%s/v2\n\n", host)
+
+	if err := replayer.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("Replay stopped: %v", err)
+	} else if err == nil {
+		log.Printf("Replay finished; serving the final state until interrupted")
+		<-ctx.Done()
+	}
+
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	httpServer.Shutdown(shutdown)
+}
+
+// collectFiles lists the market data files under the inputs
+func collectFiles(inputs []string) ([]string, error) {
+	var paths []string
+	for _, input := range inputs {
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		err := filepath.WalkDir(input, func(path string, entry os.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".csv", ".json", ".ndjson", ".jsonl":
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// loadEvents reads market data from a file. CSV files and data collector
+// JSON exports hold bars (see dataset.LoadCSV and dataset.LoadJSON); JSON
+// and NDJSON files may instead hold recorded stream messages (see
+// mockbroker.LoadEvents).
+func loadEvents(path string) ([]broker.Event, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		records, err := dataset.LoadCSV(path)
+		if err != nil {
+			return nil, err
+		}
+		return barEvents(records), nil
+	case ".json", ".ndjson", ".jsonl":
+		events, err := mockbroker.LoadEvents(path)
+		if err == nil && len(events) > 0 {
+			return events, nil
+		}
+		records, exportErr := dataset.LoadJSON(path)
+		if exportErr != nil {
+			if err == nil {
+				err = exportErr
+			}
+			return nil, err
+		}
+		return barEvents(records), nil
+	}
+	return nil, fmt.Errorf("unsupported market data file %s", path)
+}
+
+// barEvents converts dataset records to bar events
+func barEvents(records []dataset.Record) []broker.Event {
+	events := make([]broker.Event, 0, len(records))
+	for _, r := range records {
+		events = append(events, broker.Event{Time: r.Bar.Time, Symbol: r.Symbol, Bar: &broker.StreamBar{
+			Type:      "b",
+			Symbol:    r.Symbol,
+			Open:      r.Bar.Open,
+			High:      r.Bar.High,
+			Low:       r.Bar.Low,
+			Close:     r.Bar.Close,
+			Volume:    uint64(r.Bar.Volume),
+			Timestamp: r.Bar.Time,
+			VWAP:      (r.Bar.High + r.Bar.Low + r.Bar.Close) / 3,
+		}})
+	}
+	return events
+}
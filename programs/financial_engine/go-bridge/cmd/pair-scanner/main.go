@@ -0,0 +1,569 @@
+package main
+
+// pair-scanner searches a universe for cointegrated pairs, and optionally
+// baskets, in locally stored bars. Every pair gets a full-sample Engle-Granger
+// test and an Ornstein-Uhlenbeck half-life; p-values are corrected for the
+// number of pairs tested, and survivors are ranked by how consistently they
+// stay cointegrated across rolling windows. The ranked pairs are written as a
+// pairs strategy config (see strategies.LoadPairsConfig).
+//
+//	pair-scanner -data ./market_data -universe sector-etfs -out pairs.json
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gonum.org/v1/gonum/stat"
+
+	"zig-financial-engine/backtesting"
+	"zig-financial-engine/internal/cointegration"
+	"zig-financial-engine/strategies"
+)
+
+// presets are named universes accepted by -universe
+var presets = map[string][]string{
+	"sector-etfs": {"XLB", "XLC", "XLE", "XLF", "XLI", "XLK", "XLP", "XLRE", "XLU", "XLV", "XLY"},
+}
+
+// tradingDaysPerYear converts trading-day windows to the strategy's calendar lookback
+const tradingDaysPerYear = 252
+
+// scanConfig holds the command line settings
+type scanConfig struct {
+	window       int
+	step         int
+	maxLags      int
+	alpha        float64
+	correction   string
+	minHalfLife  float64
+	maxHalfLife  float64
+	minStability float64
+	top          int
+	basketSize   int
+}
+
+// pairScan is the evidence for one pair; it is written into the config's scan field
+type pairScan struct {
+	SymbolA        string  `json:"-"`
+	SymbolB        string  `json:"-"`
+	PValue         float64 `json:"p_value"`          // Full-sample Engle-Granger, better orientation doubled
+	AdjustedPValue float64 `json:"adjusted_p_value"` // After the multiple-testing correction
+	ADFStatistic   float64 `json:"adf_statistic"`
+	HedgeRatio     float64 `json:"hedge_ratio"`
+	HalfLifeDays   float64 `json:"half_life_days"` // 0 if the spread does not mean-revert
+	Correlation    float64 `json:"correlation"`
+	Stability      float64 `json:"stability"` // Share of rolling windows cointegrated at alpha
+	Windows        int     `json:"windows"`
+	HedgeRatioCV   float64 `json:"hedge_ratio_cv"` // Spread of the hedge ratio across windows
+	Observations   int     `json:"observations"`
+	ScannedAt      string  `json:"scanned_at"`
+}
+
+// basketScan is the evidence for one basket
+type basketScan struct {
+	Symbols        []string  `json:"symbols"`
+	Weights        []float64 `json:"weights"` // First Johansen vector, first symbol weighted 1
+	TraceStatistic float64   `json:"trace_statistic"`
+	Critical1Pct   float64   `json:"critical_1pct"`
+	Rank           int       `json:"rank"`
+	HalfLifeDays   float64   `json:"half_life_days"`
+	Stability      float64   `json:"stability"` // Share of rolling windows with rank of at least 1 at 5%
+	Windows        int       `json:"windows"`
+	Observations   int       `json:"observations"`
+}
+
+func main() {
+	var (
+		dataDir      = flag.String("data", "./market_data", "Directory of bar CSV files, searched recursively")
+		universe     = flag.String("universe", "", "Comma-separated symbols, a file of symbols, or a preset (sector-etfs); default is every symbol found")
+		out          = flag.String("out", "pairs.json", "Pairs strategy config to write")
+		basketsOut   = flag.String("baskets-out", "", "Optional JSON file for basket results")
+		window       = flag.Int("window", 250, "Rolling window in trading days")
+		step         = flag.Int("step", 21, "Trading days between rolling windows")
+		maxLags      = flag.Int("max-lags", -1, "Most ADF lags to consider (-1 uses 12*(n/100)^0.25)")
+		alpha        = flag.Float64("alpha", 0.05, "Significance level after correction")
+		correction   = flag.String("correction", "bh", "Multiple-testing correction: bh (Benjamini-Hochberg), bonferroni or none")
+		minHalfLife  = flag.Float64("min-half-life", 1, "Shortest half-life in trading days")
+		maxHalfLife  = flag.Float64("max-half-life", 60, "Longest half-life in trading days")
+		minStability = flag.Float64("min-stability", 0.5, "Least share of rolling windows that must be cointegrated")
+		top          = flag.Int("top", 20, "Most pairs to write (0 for all)")
+		basketSize   = flag.Int("basket", 0, "Also test baskets of this many symbols with Johansen (3-6, 0 disables)")
+	)
+	flag.Parse()
+
+	cfg := scanConfig{
+		window:       *window,
+		step:         *step,
+		maxLags:      *maxLags,
+		alpha:        *alpha,
+		correction:   *correction,
+		minHalfLife:  *minHalfLife,
+		maxHalfLife:  *maxHalfLife,
+		minStability: *minStability,
+		top:          *top,
+		basketSize:   *basketSize,
+	}
+	if cfg.window < 30 || cfg.step < 1 {
+		log.Fatalf("window must be at least 30 trading days and step at least 1")
+	}
+	if cfg.basketSize != 0 && (cfg.basketSize < 3 || cfg.basketSize > 6) {
+		log.Fatalf("basket size must be between 3 and 6")
+	}
+	switch cfg.correction {
+	case "bh", "bonferroni", "none":
+	default:
+		log.Fatalf("unknown correction %q", cfg.correction)
+	}
+
+	bars, err := backtesting.LoadBarDir(*dataDir)
+	if err != nil {
+		log.Fatalf("Failed to load bars: %v", err)
+	}
+	symbols, err := resolveUniverse(*universe, bars)
+	if err != nil {
+		log.Fatalf("Failed to resolve universe: %v", err)
+	}
+
+	exchange, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		exchange = time.UTC
+	}
+	closes := make(map[string]map[string]float64, len(symbols))
+	for _, symbol := range symbols {
+		closes[symbol] = backtesting.DailyCloses(bars[symbol], exchange)
+	}
+	fmt.Printf("Scanning %d symbols (%d pairs) from %s\n", len(symbols), len(symbols)*(len(symbols)-1)/2, *dataDir)
+
+	pairs := scanPairs(symbols, closes, cfg)
+	candidates := selectPairs(pairs, cfg)
+	printPairs(candidates)
+
+	scannedAt := time.Now().UTC().Format(time.RFC3339)
+	configs := make([]strategies.PairsConfig, 0, len(candidates))
+	for _, p := range candidates {
+		p.ScannedAt = scannedAt
+		config, err := pairConfig(p, cfg)
+		if err != nil {
+			log.Fatalf("Failed to encode %s/%s: %v", p.SymbolA, p.SymbolB, err)
+		}
+		configs = append(configs, config)
+	}
+	if err := strategies.SavePairsConfig(*out, configs); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+	fmt.Printf("Wrote %d pairs to %s\n", len(configs), *out)
+
+	if cfg.basketSize > 0 {
+		baskets := scanBaskets(symbols, closes, cfg)
+		printBaskets(baskets)
+		if *basketsOut != "" {
+			if err := writeJSON(*basketsOut, baskets); err != nil {
+				log.Fatalf("Failed to write %s: %v", *basketsOut, err)
+			}
+			fmt.Printf("Wrote %d baskets to %s\n", len(baskets), *basketsOut)
+		}
+	}
+}
+
+// resolveUniverse turns -universe into symbols that have data
+func resolveUniverse(spec string, bars map[string][]backtesting.Bar) ([]string, error) {
+	var requested []string
+	switch {
+	case spec == "":
+		for symbol := range bars {
+			requested = append(requested, symbol)
+		}
+	case presets[spec] != nil:
+		requested = presets[spec]
+	default:
+		if file, err := os.Open(spec); err == nil {
+			defer file.Close()
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				line := strings.TrimSpace(strings.SplitN(scanner.Text(), "#", 2)[0])
+				requested = append(requested, strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })...)
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+		} else {
+			requested = strings.Split(spec, ",")
+		}
+	}
+
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, symbol := range requested {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		if len(bars[symbol]) == 0 {
+			fmt.Printf("Skipping %s: no bars found\n", symbol)
+			continue
+		}
+		symbols = append(symbols, symbol)
+	}
+	if len(symbols) < 2 {
+		return nil, fmt.Errorf("need at least two symbols with data, found %d", len(symbols))
+	}
+	sort.Strings(symbols)
+	return symbols, nil
+}
+
+// align returns the closes of each symbol on the dates they all traded, oldest first
+func align(closes map[string]map[string]float64, symbols ...string) [][]float64 {
+	var dates []string
+	for date := range closes[symbols[0]] {
+		shared := true
+		for _, symbol := range symbols[1:] {
+			if _, ok := closes[symbol][date]; !ok {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+
+	series := make([][]float64, len(symbols))
+	for i, symbol := range symbols {
+		series[i] = make([]float64, len(dates))
+		for j, date := range dates {
+			series[i][j] = closes[symbol][date]
+		}
+	}
+	return series
+}
+
+// scanPairs tests every pair in parallel; pairs with too little history are dropped
+func scanPairs(symbols []string, closes map[string]map[string]float64, cfg scanConfig) []pairScan {
+	type job struct{ a, b string }
+	jobs := make(chan job)
+	results := make(chan pairScan)
+
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if result, ok := scanPair(j.a, j.b, closes, cfg); ok {
+					results <- result
+				}
+			}
+		}()
+	}
+	go func() {
+		for i := range symbols {
+			for _, b := range symbols[i+1:] {
+				jobs <- job{symbols[i], b}
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var pairs []pairScan
+	for result := range results {
+		pairs = append(pairs, result)
+	}
+	return pairs
+}
+
+// scanPair tests one pair. Engle-Granger depends on which symbol is regressed
+// on which, so both orientations are tested and the better p-value is doubled
+// (Bonferroni over the two) before the cross-pair correction.
+func scanPair(a, b string, closes map[string]map[string]float64, cfg scanConfig) (pairScan, bool) {
+	series := align(closes, a, b)
+	pricesA, pricesB := series[0], series[1]
+	if len(pricesA) < cfg.window {
+		return pairScan{}, false
+	}
+
+	forward, errF := cointegration.EngleGranger(pricesA, pricesB, cfg.maxLags)
+	reverse, errR := cointegration.EngleGranger(pricesB, pricesA, cfg.maxLags)
+	if errF != nil && errR != nil {
+		return pairScan{}, false
+	}
+	best, y, x := forward, pricesA, pricesB
+	result := pairScan{SymbolA: a, SymbolB: b}
+	if errF != nil || (errR == nil && reverse.PValue < forward.PValue) {
+		best, y, x = reverse, pricesB, pricesA
+		result.SymbolA, result.SymbolB = b, a
+	}
+
+	result.PValue = math.Min(1, 2*best.PValue)
+	result.ADFStatistic = best.Statistic
+	result.HedgeRatio = best.HedgeRatio
+	result.HalfLifeDays = halfLife(best.Spread)
+	result.Correlation = stat.Correlation(y, x, nil)
+	result.Observations = len(y)
+
+	// Stability: the same orientation re-tested on each rolling window
+	var hedges []float64
+	passed := 0
+	for start := 0; start+cfg.window <= len(y); start += cfg.step {
+		eg, err := cointegration.EngleGranger(y[start:start+cfg.window], x[start:start+cfg.window], cfg.maxLags)
+		if err != nil {
+			continue
+		}
+		result.Windows++
+		hedges = append(hedges, eg.HedgeRatio)
+		if eg.Cointegrated(cfg.alpha) {
+			passed++
+		}
+	}
+	if result.Windows > 0 {
+		result.Stability = float64(passed) / float64(result.Windows)
+	}
+	if len(hedges) > 1 {
+		if mean, std := stat.MeanStdDev(hedges, nil); mean != 0 {
+			result.HedgeRatioCV = math.Abs(std / mean)
+		}
+	}
+	return result, true
+}
+
+// halfLife is the spread half-life in bars, 0 if it does not mean-revert
+func halfLife(spread []float64) float64 {
+	hl, err := cointegration.HalfLife(spread)
+	if err != nil || math.IsInf(hl, 1) {
+		return 0
+	}
+	return hl
+}
+
+// selectPairs corrects p-values across all tested pairs, filters and ranks
+func selectPairs(pairs []pairScan, cfg scanConfig) []pairScan {
+	pValues := make([]float64, len(pairs))
+	for i, p := range pairs {
+		pValues[i] = p.PValue
+	}
+	adjusted := adjustPValues(pValues, cfg.correction)
+
+	var selected []pairScan
+	for i, p := range pairs {
+		p.AdjustedPValue = adjusted[i]
+		if p.AdjustedPValue > cfg.alpha || p.Stability < cfg.minStability {
+			continue
+		}
+		if p.HalfLifeDays < cfg.minHalfLife || p.HalfLifeDays > cfg.maxHalfLife {
+			continue
+		}
+		selected = append(selected, p)
+	}
+	fmt.Printf("Tested %d pairs, %d pass %s-corrected p <= %.2f, half-life %.0f-%.0f days and stability >= %.0f%%\n",
+		len(pairs), len(selected), cfg.correction, cfg.alpha, cfg.minHalfLife, cfg.maxHalfLife, cfg.minStability*100)
+
+	// Most stable first, then strongest evidence, then steadiest hedge ratio
+	sort.Slice(selected, func(i, j int) bool {
+		a, b := selected[i], selected[j]
+		if a.Stability != b.Stability {
+			return a.Stability > b.Stability
+		}
+		if a.AdjustedPValue != b.AdjustedPValue {
+			return a.AdjustedPValue < b.AdjustedPValue
+		}
+		return a.HedgeRatioCV < b.HedgeRatioCV
+	})
+	if cfg.top > 0 && len(selected) > cfg.top {
+		selected = selected[:cfg.top]
+	}
+	return selected
+}
+
+// adjustPValues corrects p-values for the number of tests. Benjamini-Hochberg
+// controls the false discovery rate; Bonferroni the family-wise error rate.
+func adjustPValues(pValues []float64, method string) []float64 {
+	m := float64(len(pValues))
+	adjusted := make([]float64, len(pValues))
+	switch method {
+	case "bonferroni":
+		for i, p := range pValues {
+			adjusted[i] = math.Min(1, p*m)
+		}
+	case "bh":
+		order := make([]int, len(pValues))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool { return pValues[order[a]] < pValues[order[b]] })
+		running := 1.0
+		for rank := len(order) - 1; rank >= 0; rank-- {
+			i := order[rank]
+			running = math.Min(running, pValues[i]*m/float64(rank+1))
+			adjusted[i] = running
+		}
+	default:
+		copy(adjusted, pValues)
+	}
+	return adjusted
+}
+
+// pairConfig converts a scan result into a strategy config. The strategy's
+// lookback is in calendar days, so the scanned window is converted.
+func pairConfig(p pairScan, cfg scanConfig) (strategies.PairsConfig, error) {
+	evidence, err := json.Marshal(p)
+	if err != nil {
+		return strategies.PairsConfig{}, err
+	}
+	return strategies.PairsConfig{
+		SymbolA: p.SymbolA,
+		SymbolB: p.SymbolB,
+		Parameters: map[string]interface{}{
+			"lookback_days": int(math.Ceil(float64(cfg.window) * 365 / tradingDaysPerYear)),
+			"max_p_value":   cfg.alpha,
+		},
+		Scan: evidence,
+	}, nil
+}
+
+// scanBaskets runs Johansen on every combination of basketSize symbols.
+// Johansen gives critical values rather than p-values, so with many baskets
+// tested the full sample must clear the 1% level instead of a corrected one.
+func scanBaskets(symbols []string, closes map[string]map[string]float64, cfg scanConfig) []basketScan {
+	var baskets []basketScan
+	tested := 0
+	forEachCombination(len(symbols), cfg.basketSize, func(indexes []int) {
+		members := make([]string, len(indexes))
+		for i, idx := range indexes {
+			members[i] = symbols[idx]
+		}
+		series := align(closes, members...)
+		if len(series[0]) < cfg.window {
+			return
+		}
+		tested++
+		res, err := cointegration.Johansen(series, 1)
+		if err != nil || res.TraceStats[0] < res.TraceCritical[0].OnePercent {
+			return
+		}
+
+		basket := basketScan{
+			Symbols:        members,
+			Weights:        res.Vectors[0],
+			TraceStatistic: res.TraceStats[0],
+			Critical1Pct:   res.TraceCritical[0].OnePercent,
+			Rank:           res.Rank,
+			Observations:   len(series[0]),
+		}
+		spread := make([]float64, len(series[0]))
+		for i, w := range basket.Weights {
+			for t, price := range series[i] {
+				spread[t] += w * price
+			}
+		}
+		basket.HalfLifeDays = halfLife(spread)
+		if basket.HalfLifeDays < cfg.minHalfLife || basket.HalfLifeDays > cfg.maxHalfLife {
+			return
+		}
+
+		passed := 0
+		for start := 0; start+cfg.window <= len(series[0]); start += cfg.step {
+			windowed := make([][]float64, len(series))
+			for i := range series {
+				windowed[i] = series[i][start : start+cfg.window]
+			}
+			if wr, err := cointegration.Johansen(windowed, 1); err == nil {
+				basket.Windows++
+				if wr.Rank >= 1 {
+					passed++
+				}
+			}
+		}
+		if basket.Windows > 0 {
+			basket.Stability = float64(passed) / float64(basket.Windows)
+		}
+		if basket.Stability >= cfg.minStability {
+			baskets = append(baskets, basket)
+		}
+	})
+	fmt.Printf("Tested %d baskets of %d, %d pass the 1%% trace test and filters\n", tested, cfg.basketSize, len(baskets))
+
+	sort.Slice(baskets, func(i, j int) bool {
+		if baskets[i].Stability != baskets[j].Stability {
+			return baskets[i].Stability > baskets[j].Stability
+		}
+		return baskets[i].TraceStatistic/baskets[i].Critical1Pct > baskets[j].TraceStatistic/baskets[j].Critical1Pct
+	})
+	if cfg.top > 0 && len(baskets) > cfg.top {
+		baskets = baskets[:cfg.top]
+	}
+	return baskets
+}
+
+// forEachCombination calls fn with each k-subset of 0..n-1 in lexicographic order
+func forEachCombination(n, k int, fn func([]int)) {
+	if k > n {
+		return
+	}
+	indexes := make([]int, k)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	for {
+		fn(indexes)
+		i := k - 1
+		for i >= 0 && indexes[i] == n-k+i {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		indexes[i]++
+		for j := i + 1; j < k; j++ {
+			indexes[j] = indexes[j-1] + 1
+		}
+	}
+}
+
+// printPairs prints the ranked pairs
+func printPairs(pairs []pairScan) {
+	if len(pairs) == 0 {
+		fmt.Println("No pairs passed")
+		return
+	}
+	fmt.Printf("\n%-4s %-13s %9s %8s %8s %9s %9s %8s\n", "Rank", "Pair (A/B)", "Adj. p", "ADF", "Hedge", "HalfLife", "Stable", "HedgeCV")
+	for i, p := range pairs {
+		fmt.Printf("%-4d %-13s %9.4f %8.2f %8.3f %8.1fd %8.0f%% %8.3f\n",
+			i+1, p.SymbolA+"/"+p.SymbolB, p.AdjustedPValue, p.ADFStatistic, p.HedgeRatio,
+			p.HalfLifeDays, p.Stability*100, p.HedgeRatioCV)
+	}
+	fmt.Println()
+}
+
+// printBaskets prints the ranked baskets
+func printBaskets(baskets []basketScan) {
+	for i, b := range baskets {
+		weights := make([]string, len(b.Weights))
+		for j, w := range b.Weights {
+			weights[j] = fmt.Sprintf("%+.3f %s", w, b.Symbols[j])
+		}
+		fmt.Printf("%-4d %s | trace %.1f (1%%: %.1f) rank %d | half-life %.1fd | stable %.0f%%\n",
+			i+1, strings.Join(weights, " "), b.TraceStatistic, b.Critical1Pct, b.Rank, b.HalfLifeDays, b.Stability*100)
+	}
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
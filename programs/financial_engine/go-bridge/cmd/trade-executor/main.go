@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -9,6 +10,9 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/pebbe/zmq4"
 	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/protection"
+	"zig-financial-engine/strategies"
 )
 
 // OrderSignal represents an order request from the HFT engine
@@ -37,6 +41,7 @@ type TradeExecutor struct {
 	alpacaClient *alpaca.Client
 	zmqSocket    *zmq4.Socket
 	zmqReply     *zmq4.Socket
+	breaker      *protection.CircuitBreaker
 	isLive       bool
 }
 
@@ -53,7 +58,7 @@ func NewTradeExecutor() (*TradeExecutor, error) {
 	client := alpaca.NewClient(alpaca.ClientOpts{
 		APIKey:    apiKey,
 		APISecret: apiSecret,
-		BaseURL:   "https://paper-api.alpaca.markets",
+		BaseURL:   strategies.BaseURLFromEnv(),
 	})
 
 	// Test connection
@@ -66,8 +71,22 @@ func NewTradeExecutor() (*TradeExecutor, error) {
 	fmt.Printf("💰 Buying Power: $%.2f\n", account.BuyingPower.InexactFloat64())
 	fmt.Printf("💵 Cash: $%.2f\n", account.Cash.InexactFloat64())
 
+	// Circuit breaker: loss, drawdown, reject and kill file triggers.
+	// Signals arrive only when the engine wants to trade, so they can't
+	// serve as a market data heartbeat; the stale data trigger is off.
+	cfg := protection.DefaultCircuitBreakerConfig()
+	cfg.StaleDataAfter = 0
+	cfg.StateFile = "/tmp/trade_executor_breaker.json"
+	cfg.FlattenOnTrip = os.Getenv("FLATTEN_ON_TRIP") == "true"
+	breaker := protection.NewCircuitBreaker(cfg)
+	breaker.SetActions(client.CancelAllOrders, func() error {
+		_, err := client.CloseAllPositions(alpaca.CloseAllPositionsRequest{CancelOrders: true})
+		return err
+	})
+
 	return &TradeExecutor{
 		alpacaClient: client,
+		breaker:      breaker,
 		isLive:       false, // Start in paper mode
 	}, nil
 }
@@ -103,6 +122,17 @@ func (te *TradeExecutor) Start() error {
 	fmt.Printf("🔌 Trade Executor listening on %s\n", endpoint)
 	fmt.Printf("📤 Sending responses on %s\n", replyEndpoint)
 
+	// Monitor account equity and the kill file; SIGUSR1 trips, SIGUSR2 resets
+	ctx := context.Background()
+	go te.breaker.Run(ctx, func() (decimal.Decimal, error) {
+		account, err := te.alpacaClient.GetAccount()
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return account.Equity, nil
+	})
+	go te.breaker.WatchSignals(ctx)
+
 	// Start processing loop
 	for {
 		// Receive order signal
@@ -133,6 +163,16 @@ func (te *TradeExecutor) processOrder(signal OrderSignal) OrderResponse {
 	fmt.Printf("\n📨 Received Signal: %s %s %.2f @ %.2f\n",
 		signal.Action, signal.Symbol, signal.Quantity, signal.Price)
 
+	// Block everything while the circuit breaker is tripped
+	if err := te.breaker.Allow(); err != nil {
+		fmt.Printf("🛑 Order blocked: %v\n", err)
+		return OrderResponse{
+			SignalID: signal.SignalID,
+			Status:   "HALTED",
+			Error:    err.Error(),
+		}
+	}
+
 	// Determine order side
 	var side alpaca.Side
 	if signal.Action == "BUY" {
@@ -167,6 +207,7 @@ func (te *TradeExecutor) processOrder(signal OrderSignal) OrderResponse {
 
 	// Place the order
 	order, err := te.alpacaClient.PlaceOrder(req)
+	te.breaker.RecordOrderResult(err != nil)
 	if err != nil {
 		fmt.Printf("❌ Order failed: %v\n", err)
 		return OrderResponse{
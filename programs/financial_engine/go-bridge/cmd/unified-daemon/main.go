@@ -64,6 +64,10 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/gorilla/websocket"
 	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/protection"
+	"zig-financial-engine/strategies"
 )
 
 // Global logger for daemon mode
@@ -137,7 +141,7 @@ type AlpacaWebSocketClient struct {
 
 // LCT Trader structure
 type LCTTrader struct {
-	alpacaClient    *alpaca.Client
+	tradingClient   broker.Broker
 	baseCurrency    string
 	positionTracker *MultiCurrencyPositionTracker
 	logger          *log.Logger
@@ -150,8 +154,8 @@ type MultiCurrencyPositionTracker struct {
 
 // Crypto Trader structure
 type CryptoTrader struct {
-	alpacaClient *alpaca.Client
-	logger       *log.Logger
+	tradingClient broker.Broker
+	logger        *log.Logger
 }
 
 // Crypto Wallet structures
@@ -304,7 +308,7 @@ type Settlement struct {
 
 // Funding Manager
 type FundingManager struct {
-	alpacaClient     *alpaca.Client
+	tradingClient    broker.Broker
 	fundingWallets   map[string]*FundingWallet
 	transfers        map[string]*Transfer
 	recipientBanks   map[string]*RecipientBank
@@ -316,7 +320,7 @@ type FundingManager struct {
 
 // Trading Manager for enhanced order management
 type TradingManager struct {
-	alpacaClient     *alpaca.Client
+	tradingClient    broker.Broker
 	orders           map[string]*Order
 	assets           map[string]*Asset
 	configurations   map[string]*AccountConfiguration
@@ -356,13 +360,13 @@ func NewAlpacaWebSocketClient(apiKey, apiSecret string, isPaper bool) *AlpacaWeb
 }
 
 // NewLCTTrader creates a new Local Currency Trader
-func NewLCTTrader(alpacaClient *alpaca.Client, baseCurrency string, logger *log.Logger) *LCTTrader {
+func NewLCTTrader(tradingClient broker.Broker, baseCurrency string, logger *log.Logger) *LCTTrader {
 	positionTracker := &MultiCurrencyPositionTracker{
 		positions: make(map[string]map[string]decimal.Decimal),
 	}
 	
 	return &LCTTrader{
-		alpacaClient:    alpacaClient,
+		tradingClient:   tradingClient,
 		baseCurrency:    baseCurrency,
 		positionTracker: positionTracker,
 		logger:          logger,
@@ -370,10 +374,10 @@ func NewLCTTrader(alpacaClient *alpaca.Client, baseCurrency string, logger *log.
 }
 
 // NewCryptoTrader creates a new Crypto Trader
-func NewCryptoTrader(alpacaClient *alpaca.Client, logger *log.Logger) *CryptoTrader {
+func NewCryptoTrader(tradingClient broker.Broker, logger *log.Logger) *CryptoTrader {
 	return &CryptoTrader{
-		alpacaClient: alpacaClient,
-		logger:       logger,
+		tradingClient: tradingClient,
+		logger:        logger,
 	}
 }
 
@@ -397,9 +401,9 @@ func NewJournalManager(firmAccount string, logger *log.Logger) *JournalManager {
 }
 
 // NewFundingManager creates a new Funding Manager
-func NewFundingManager(alpacaClient *alpaca.Client, logger *log.Logger) *FundingManager {
+func NewFundingManager(tradingClient broker.Broker, logger *log.Logger) *FundingManager {
 	return &FundingManager{
-		alpacaClient:     alpacaClient,
+		tradingClient:    tradingClient,
 		fundingWallets:   make(map[string]*FundingWallet),
 		transfers:        make(map[string]*Transfer),
 		recipientBanks:   make(map[string]*RecipientBank),
@@ -410,9 +414,9 @@ func NewFundingManager(alpacaClient *alpaca.Client, logger *log.Logger) *Funding
 }
 
 // NewTradingManager creates a new Trading Manager
-func NewTradingManager(alpacaClient *alpaca.Client, logger *log.Logger) *TradingManager {
+func NewTradingManager(tradingClient broker.Broker, logger *log.Logger) *TradingManager {
 	return &TradingManager{
-		alpacaClient:   alpacaClient,
+		tradingClient:  tradingClient,
 		orders:         make(map[string]*Order),
 		assets:         make(map[string]*Asset),
 		configurations: make(map[string]*AccountConfiguration),
@@ -505,7 +509,7 @@ type ImmortalSynapse struct {
 	// Core components from unified system
 	engineInitialized bool
 	engineMutex       sync.RWMutex
-	alpacaClient      *alpaca.Client
+	tradingClient     broker.Broker
 	marketClient      *marketdata.Client
 	wsConn            *websocket.Conn
 	
@@ -520,6 +524,9 @@ type ImmortalSynapse struct {
 	marketDataManager *MarketDataManager
 	marginManager     *MarginTradingManager
 	
+	// Kill switch for loss, stale data and manual halts
+	breaker *protection.CircuitBreaker
+	
 	// Queues
 	orderQueue  chan Order
 	signalQueue chan Signal
@@ -641,13 +648,12 @@ func InitializeImmortalSynapse() (*ImmortalSynapse, error) {
 		apiSecret = os.Getenv("APCA_API_SECRET_KEY")
 	}
 	
-	// Initialize Alpaca clients
+	// Initialize Alpaca clients; BROKER selects the account, simulator or replay
 	logger.Println("Connecting to market data feeds...")
-	alpacaClient := alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		BaseURL:   "https://paper-api.alpaca.markets",
-	})
+	tradingClient, err := strategies.BrokerFromEnv(apiKey, apiSecret, strategies.BaseURLFromEnv())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create broker: %w", err)
+	}
 	
 	marketClient := marketdata.NewClient(marketdata.ClientOpts{
 		APIKey:    apiKey,
@@ -669,10 +675,10 @@ func InitializeImmortalSynapse() (*ImmortalSynapse, error) {
 	websocketClient := NewAlpacaWebSocketClient(apiKey, apiSecret, false)
 	
 	logger.Println("Initializing Local Currency Trading...")
-	lctTrader := NewLCTTrader(alpacaClient, "USD", logger)
+	lctTrader := NewLCTTrader(tradingClient, "USD", logger)
 	
 	logger.Println("Initializing Crypto Trading...")
-	cryptoTrader := NewCryptoTrader(alpacaClient, logger)
+	cryptoTrader := NewCryptoTrader(tradingClient, logger)
 	
 	logger.Println("Initializing Crypto Wallets...")
 	cryptoWallets := NewCryptoWalletManager(logger)
@@ -681,14 +687,26 @@ func InitializeImmortalSynapse() (*ImmortalSynapse, error) {
 	journalManager := NewJournalManager("firm-synapse-001", logger)
 	
 	logger.Println("Initializing Funding Manager...")
-	fundingManager := NewFundingManager(alpacaClient, logger)
+	fundingManager := NewFundingManager(tradingClient, logger)
 	
 	logger.Println("Initializing Trading Manager...")
-	tradingManager := NewTradingManager(alpacaClient, logger)
+	tradingManager := NewTradingManager(tradingClient, logger)
+	
+	logger.Println("Initializing Circuit Breaker...")
+	breakerConfig := protection.DefaultCircuitBreakerConfig()
+	breakerConfig.KillFile = filepath.Join(stateDir, "KILL")
+	breakerConfig.StateFile = filepath.Join(stateDir, "circuit_breaker.json")
+	breakerConfig.StaleDataAfter = 30 * time.Second // Ticks arrive every 100ms
+	breakerConfig.FlattenOnTrip = os.Getenv("FLATTEN_ON_TRIP") == "true"
+	breaker := protection.NewCircuitBreaker(breakerConfig)
+	breaker.SetActions(tradingClient.CancelAllOrders, func() error {
+		_, err := tradingClient.CloseAllPositions(alpaca.CloseAllPositionsRequest{CancelOrders: true})
+		return err
+	})
 	
 	synapse := &ImmortalSynapse{
 		engineInitialized: true,
-		alpacaClient:      alpacaClient,
+		tradingClient:     tradingClient,
 		marketClient:      marketClient,
 		websocketClient:   websocketClient,
 		lctTrader:        lctTrader,
@@ -697,6 +715,7 @@ func InitializeImmortalSynapse() (*ImmortalSynapse, error) {
 		journalManager:   journalManager,
 		fundingManager:   fundingManager,
 		tradingManager:   tradingManager,
+		breaker:          breaker,
 		orderQueue:       make(chan Order, 1000),
 		signalQueue:      make(chan Signal, 1000),
 		ctx:              ctx,
@@ -733,6 +752,9 @@ func (is *ImmortalSynapse) RunDaemon() error {
 	is.wg.Add(1)
 	go is.healthMonitorService()
 	
+	is.wg.Add(1)
+	go is.circuitBreakerService()
+	
 	// START ENHANCED TRADING SERVICES
 	logger.Println("Starting enhanced trading capabilities...")
 	
@@ -752,7 +774,7 @@ func (is *ImmortalSynapse) RunDaemon() error {
 	
 	// Setup signal handlers
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2)
 	
 	for {
 		select {
@@ -761,6 +783,14 @@ func (is *ImmortalSynapse) RunDaemon() error {
 			case syscall.SIGHUP:
 				logger.Println("Received SIGHUP - reloading configuration")
 				is.reloadConfig()
+			case syscall.SIGUSR1:
+				logger.Println("Received SIGUSR1 - tripping kill switch")
+				is.breaker.Trip(protection.TripManual, "SIGUSR1 kill signal")
+			case syscall.SIGUSR2:
+				logger.Println("Received SIGUSR2 - resetting kill switch")
+				if err := is.breaker.Reset("SIGUSR2"); err != nil {
+					logger.Printf("Kill switch reset refused: %v", err)
+				}
 			case syscall.SIGINT, syscall.SIGTERM:
 				logger.Println("Received shutdown signal")
 				return is.shutdown()
@@ -816,6 +846,7 @@ func (is *ImmortalSynapse) processTick(symbol string, sequence uint64) {
 	result := C.hft_process_tick(&tick)
 	if result == 0 {
 		atomic.AddUint64(&is.ticksProcessed, 1)
+		is.breaker.RecordMarketData()
 		is.checkForSignals()
 	}
 }
@@ -884,6 +915,19 @@ func (is *ImmortalSynapse) healthMonitorService() {
 	}
 }
 
+// Circuit breaker service: polls equity for loss limits, watches the kill file and data freshness
+func (is *ImmortalSynapse) circuitBreakerService() {
+	defer is.wg.Done()
+	
+	is.breaker.Run(is.ctx, func() (decimal.Decimal, error) {
+		account, err := is.tradingClient.GetAccount()
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return account.Equity, nil
+	})
+}
+
 // Log metrics
 func (is *ImmortalSynapse) logMetrics() {
 	var stats C.CSystemStats
@@ -920,6 +964,13 @@ func (is *ImmortalSynapse) performHealthCheck() {
 		}
 	}
 	
+	// Trading halted until an operator resets the breaker
+	if is.breaker.IsTripped() {
+		status := is.breaker.GetStatus()
+		logger.Printf("WARNING: Circuit breaker tripped (%s: %s) - send SIGUSR2 to reset",
+			status["trip_reason"], status["trip_detail"])
+	}
+	
 	// Update health status
 	select {
 	case is.healthCheck <- true:
@@ -1007,6 +1058,11 @@ func (is *ImmortalSynapse) processSignal(signal Signal) {
 }
 
 func (is *ImmortalSynapse) executeOrder(order Order) {
+	if err := is.breaker.Allow(); err != nil {
+		logger.Printf("HALTED: %s %s %s blocked: %v", order.Side, order.Quantity, order.Symbol, err)
+		return
+	}
+	
 	// Log order attempt
 	logger.Printf("TRADE: %s %s %s", order.Side, order.Quantity, order.Symbol)
 	
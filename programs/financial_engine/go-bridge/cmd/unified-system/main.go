@@ -65,6 +65,10 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/gorilla/websocket"
 	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/features"
+	"zig-financial-engine/strategies"
 )
 
 // THE GREAT SYNAPSE - The Unified System
@@ -74,7 +78,7 @@ type GreatSynapse struct {
 	engineMutex       sync.RWMutex
 	
 	// The Eyes (Data Collector)
-	alpacaClient  *alpaca.Client
+	tradingClient broker.Broker
 	marketClient  *marketdata.Client
 	wsConn        *websocket.Conn
 	
@@ -98,6 +102,20 @@ type GreatSynapse struct {
 	mlModel       *MLPredictor
 	priceHistory  map[string][]float64
 	featureCache  map[string][]float64
+	pipelines     map[string]*features.Pipeline
+}
+
+// synapseFeatures feeds the AI confidence model, computed on tick prices by
+// the same pipeline the strategies and data collectors use
+var synapseFeatures = features.Spec{
+	Name: "synapse_v1",
+	Features: []features.Feature{
+		{Name: "momentum", Kind: features.KindReturn, Window: 4},
+		{Name: "volatility", Kind: features.KindVolatility, Window: 20},
+		{Name: "rsi", Kind: features.KindRSI, Window: 14, Normalize: features.Normalization{Scale: 0.01}},
+		{Name: "price_position", Kind: features.KindStochasticK, Window: 20, Normalize: features.Normalization{Scale: 0.01}},
+		{Name: "trend", Kind: features.KindPPO, Fast: 5, Slow: 10},
+	},
 }
 
 type Order struct {
@@ -149,13 +167,12 @@ func InitializeGreatSynapse() (*GreatSynapse, error) {
 		apiSecret = os.Getenv("APCA_API_SECRET_KEY")
 	}
 	
-	// Initialize Alpaca clients
+	// Initialize Alpaca clients; BROKER selects the account, simulator or replay
 	fmt.Println("🌐 Connecting to market data feeds...")
-	alpacaClient := alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		BaseURL:   "https://paper-api.alpaca.markets",
-	})
+	tradingClient, err := strategies.BrokerFromEnv(apiKey, apiSecret, strategies.BaseURLFromEnv())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create broker: %w", err)
+	}
 	
 	marketClient := marketdata.NewClient(marketdata.ClientOpts{
 		APIKey:    apiKey,
@@ -167,7 +184,7 @@ func InitializeGreatSynapse() (*GreatSynapse, error) {
 	
 	gs := &GreatSynapse{
 		engineInitialized: true,
-		alpacaClient:      alpacaClient,
+		tradingClient:     tradingClient,
 		marketClient:      marketClient,
 		orderQueue:        make(chan Order, 1000),
 		signalQueue:       make(chan Signal, 1000),
@@ -175,6 +192,7 @@ func InitializeGreatSynapse() (*GreatSynapse, error) {
 		cancel:            cancel,
 		priceHistory:      make(map[string][]float64),
 		featureCache:      make(map[string][]float64),
+		pipelines:         make(map[string]*features.Pipeline),
 		latencyTracker:    make([]time.Duration, 0, 10000),
 		mlModel:           initializeMLModel(),
 	}
@@ -189,7 +207,7 @@ func InitializeGreatSynapse() (*GreatSynapse, error) {
 // Initialize simple ML model (in production, load from TensorFlow/PyTorch)
 func initializeMLModel() *MLPredictor {
 	return &MLPredictor{
-		weights:   []float64{0.3, 0.2, 0.15, 0.15, 0.1, 0.1}, // Feature weights
+		weights:   []float64{0.3, 0.2, 0.15, 0.15, 0.1}, // synapseFeatures weights
 		bias:      0.01,
 		threshold: 0.65, // Confidence threshold for trading
 	}
@@ -428,7 +446,7 @@ func (gs *GreatSynapse) executeOrder(order Order) {
 		TimeInForce: alpaca.Day,
 	}
 	
-	_, err := gs.alpacaClient.PlaceOrder(req)
+	_, err := gs.tradingClient.PlaceOrder(req)
 	if err != nil {
 		log.Printf("Order failed for %s: %v", order.Symbol, err)
 		return
@@ -582,73 +600,29 @@ func (gs *GreatSynapse) updatePriceHistory(symbol string, price float64) {
 	gs.priceHistory[symbol] = history
 	
 	// Update feature cache for ML
-	gs.updateFeatureCache(symbol, history)
+	gs.updateFeatureCache(symbol, price)
 }
 
-func (gs *GreatSynapse) updateFeatureCache(symbol string, history []float64) {
-	if len(history) < 20 {
-		return
-	}
-	
-	features := make([]float64, 6)
-	
-	// Feature 1: Price momentum (last 5 prices)
-	if len(history) >= 5 {
-		features[0] = (history[len(history)-1] - history[len(history)-5]) / history[len(history)-5]
-	}
-	
-	// Feature 2: Volatility (std dev of last 20)
-	var sum, sumSq float64
-	last20 := history[len(history)-20:]
-	for _, p := range last20 {
-		sum += p
-		sumSq += p * p
+func (gs *GreatSynapse) updateFeatureCache(symbol string, price float64) {
+	pipeline, ok := gs.pipelines[symbol]
+	if !ok {
+		pipeline, _ = features.NewPipeline(synapseFeatures)
+		gs.pipelines[symbol] = pipeline
 	}
-	mean := sum / 20
-	features[1] = math.Sqrt(sumSq/20 - mean*mean)
 	
-	// Feature 3: RSI proxy
-	gains, losses := 0.0, 0.0
-	for i := 1; i < len(last20); i++ {
-		diff := last20[i] - last20[i-1]
-		if diff > 0 {
-			gains += diff
-		} else {
-			losses -= diff
-		}
-	}
-	if losses > 0 {
-		rs := gains / losses
-		features[2] = 100 - (100 / (1 + rs))
-	} else {
-		features[2] = 100
-	}
-	
-	// Feature 4: Price position (0-1 range)
-	min, max := last20[0], last20[0]
-	for _, p := range last20 {
-		if p < min {
-			min = p
-		}
-		if p > max {
-			max = p
-		}
-	}
-	if max > min {
-		features[3] = (history[len(history)-1] - min) / (max - min)
-	}
-	
-	// Feature 5: Trend strength
-	if len(history) >= 10 {
-		ema5 := history[len(history)-1] * 0.33 + history[len(history)-5] * 0.67
-		ema10 := history[len(history)-1] * 0.18 + history[len(history)-10] * 0.82
-		features[4] = (ema5 - ema10) / ema10
+	// Ticks carry a single price, so it stands in for the whole bar
+	vector := pipeline.Update(features.Bar{
+		Time:  time.Now(),
+		Open:  price,
+		High:  price,
+		Low:   price,
+		Close: price,
+	})
+	if !pipeline.Ready() {
+		return
 	}
 	
-	// Feature 6: Volume proxy (simulated)
-	features[5] = math.Sin(float64(time.Now().UnixNano())) * 0.5 + 0.5
-	
-	gs.featureCache[symbol] = features
+	gs.featureCache[symbol] = vector
 }
 
 func (gs *GreatSynapse) cleanup() {
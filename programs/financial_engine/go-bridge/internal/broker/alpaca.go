@@ -0,0 +1,41 @@
+package broker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+)
+
+// Alpaca is a Broker backed by an Alpaca paper or live account. The REST
+// calls are the SDK client's own; trade updates come from the stream
+// factory, which the strategies package supplies with its websocket client.
+type Alpaca struct {
+	*alpaca.Client
+	streams func() TradeStream
+}
+
+// NewAlpaca creates a broker for the account opts points at. Without a
+// stream factory, TradeUpdates returns a stream that fails to connect.
+func NewAlpaca(opts alpaca.ClientOpts, streams func() TradeStream) *Alpaca {
+	return &Alpaca{Client: alpaca.NewClient(opts), streams: streams}
+}
+
+// TradeUpdates returns a new trade_updates stream for the account
+func (a *Alpaca) TradeUpdates() TradeStream {
+	if a.streams == nil {
+		return unavailableStream{}
+	}
+	return a.streams()
+}
+
+// unavailableStream is the stream of a broker built without one
+type unavailableStream struct{}
+
+func (unavailableStream) SetTradeUpdateHandler(func(TradeUpdate)) {}
+func (unavailableStream) SetReconnectHandler(func())              {}
+func (unavailableStream) Disconnect() error                       { return nil }
+
+func (unavailableStream) Connect(context.Context) error {
+	return errors.New("no trade updates stream configured")
+}
@@ -0,0 +1,126 @@
+// Package broker abstracts the brokerage account strategies, managers and
+// daemons trade through, so the same code runs against Alpaca (paper or
+// live), the in-process simulator, or a recorded session without changes.
+// Method signatures follow the Alpaca SDK, whose *alpaca.Client provides
+// the REST half of the Alpaca implementation as is.
+package broker
+
+import (
+	"context"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+)
+
+// Alpaca trading API hosts
+const (
+	PaperBaseURL = "https://paper-api.alpaca.markets"
+	LiveBaseURL  = "https://api.alpaca.markets"
+)
+
+// Broker is a brokerage account: account state, positions, orders and
+// their events, assets and the market clock
+type Broker interface {
+	GetAccount() (*alpaca.Account, error)
+	GetClock() (*alpaca.Clock, error)
+
+	GetPositions() ([]alpaca.Position, error)
+	GetPosition(symbol string) (*alpaca.Position, error)
+	ClosePosition(symbol string, req alpaca.ClosePositionRequest) (*alpaca.Order, error)
+	CloseAllPositions(req alpaca.CloseAllPositionsRequest) ([]alpaca.Order, error)
+
+	GetOrders(req alpaca.GetOrdersRequest) ([]alpaca.Order, error)
+	GetOrder(orderID string) (*alpaca.Order, error)
+	GetOrderByClientOrderID(clientOrderID string) (*alpaca.Order, error)
+	PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error)
+	ReplaceOrder(orderID string, req alpaca.ReplaceOrderRequest) (*alpaca.Order, error)
+	CancelOrder(orderID string) error
+	CancelAllOrders() error
+
+	GetAssets(req alpaca.GetAssetsRequest) ([]alpaca.Asset, error)
+	GetAsset(symbol string) (*alpaca.Asset, error)
+
+	// TradeUpdates returns a new, unconnected stream of the account's order events
+	TradeUpdates() TradeStream
+}
+
+// TradeStream delivers trade_updates events
+type TradeStream interface {
+	SetTradeUpdateHandler(handler func(TradeUpdate))
+	// SetReconnectHandler sets a callback run after a gap in the stream,
+	// when events may have been missed
+	SetReconnectHandler(handler func())
+	Connect(ctx context.Context) error
+	Disconnect() error
+}
+
+// MarketFeed is implemented by brokers that fill orders against market
+// data the process feeds them, such as mockbroker.Broker
+type MarketFeed interface {
+	Apply(event Event)
+}
+
+// FeedOf returns the market feed behind b, looking through decorators such
+// as Recorder
+func FeedOf(b Broker) (MarketFeed, bool) {
+	for {
+		if feed, ok := b.(MarketFeed); ok {
+			return feed, true
+		}
+		wrapper, ok := b.(interface{ Unwrap() Broker })
+		if !ok {
+			return nil, false
+		}
+		b = wrapper.Unwrap()
+	}
+}
+
+// TradeUpdate represents a trade update message
+type TradeUpdate struct {
+	Stream string `json:"stream"`
+	Data   struct {
+		Event       string    `json:"event"`
+		ExecutionID string    `json:"execution_id,omitempty"`
+		Order       Order     `json:"order"`
+		Timestamp   time.Time `json:"timestamp,omitempty"`
+		Price       string    `json:"price,omitempty"`
+		Qty         string    `json:"qty,omitempty"`
+		PositionQty string    `json:"position_qty,omitempty"`
+	} `json:"data"`
+}
+
+// Order represents an order in trade updates
+type Order struct {
+	ID             string     `json:"id"`
+	ClientOrderID  string     `json:"client_order_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	SubmittedAt    time.Time  `json:"submitted_at"`
+	FilledAt       *time.Time `json:"filled_at"`
+	ExpiredAt      *time.Time `json:"expired_at"`
+	CanceledAt     *time.Time `json:"canceled_at"`
+	FailedAt       *time.Time `json:"failed_at"`
+	ReplacedAt     *time.Time `json:"replaced_at"`
+	AssetID        string     `json:"asset_id"`
+	Symbol         string     `json:"symbol"`
+	AssetClass     string     `json:"asset_class"`
+	Notional       *string    `json:"notional"`
+	Qty            string     `json:"qty"`
+	FilledQty      string     `json:"filled_qty"`
+	FilledAvgPrice *string    `json:"filled_avg_price"`
+	OrderClass     string     `json:"order_class"`
+	OrderType      string     `json:"order_type"`
+	Type           string     `json:"type"`
+	Side           string     `json:"side"`
+	TimeInForce    string     `json:"time_in_force"`
+	LimitPrice     *string    `json:"limit_price"`
+	StopPrice      *string    `json:"stop_price"`
+	Status         string     `json:"status"`
+	ExtendedHours  bool       `json:"extended_hours"`
+	Legs           []Order    `json:"legs"`
+	TrailPercent   *string    `json:"trail_percent"`
+	TrailPrice     *string    `json:"trail_price"`
+	Hwm            *string    `json:"hwm"`
+	ReplacedBy     *string    `json:"replaced_by"`
+	Replaces       *string    `json:"replaces"`
+}
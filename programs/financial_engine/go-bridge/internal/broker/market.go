@@ -0,0 +1,77 @@
+package broker
+
+import "time"
+
+// StreamBar is a market data stream bar message ("T": "b")
+type StreamBar struct {
+	Type       string    `json:"T"`
+	Symbol     string    `json:"S"`
+	Open       float64   `json:"o"`
+	High       float64   `json:"h"`
+	Low        float64   `json:"l"`
+	Close      float64   `json:"c"`
+	Volume     uint64    `json:"v"`
+	Timestamp  time.Time `json:"t"`
+	TradeCount uint64    `json:"n"`
+	VWAP       float64   `json:"vw"`
+}
+
+// StreamTrade is a market data stream trade message ("T": "t")
+type StreamTrade struct {
+	Type       string    `json:"T"`
+	Symbol     string    `json:"S"`
+	ID         int64     `json:"i"`
+	Exchange   string    `json:"x"`
+	Price      float64   `json:"p"`
+	Size       uint32    `json:"s"`
+	Timestamp  time.Time `json:"t"`
+	Conditions []string  `json:"c"`
+	Tape       string    `json:"z"`
+}
+
+// StreamQuote is a market data stream quote message ("T": "q")
+type StreamQuote struct {
+	Type        string    `json:"T"`
+	Symbol      string    `json:"S"`
+	BidExchange string    `json:"bx"`
+	BidPrice    float64   `json:"bp"`
+	BidSize     uint32    `json:"bs"`
+	AskExchange string    `json:"ax"`
+	AskPrice    float64   `json:"ap"`
+	AskSize     uint32    `json:"as"`
+	Timestamp   time.Time `json:"t"`
+	Conditions  []string  `json:"c"`
+	Tape        string    `json:"z"`
+}
+
+// Event is one market data message, as replayed or fed to a simulated
+// broker; exactly one of Bar, Trade and Quote is set
+type Event struct {
+	Time   time.Time
+	Symbol string
+	Bar    *StreamBar
+	Trade  *StreamTrade
+	Quote  *StreamQuote
+}
+
+// Kind returns the stream channel of the event: "bars", "trades" or "quotes"
+func (e Event) Kind() string {
+	switch {
+	case e.Bar != nil:
+		return "bars"
+	case e.Trade != nil:
+		return "trades"
+	}
+	return "quotes"
+}
+
+// Message returns the event as its stream message
+func (e Event) Message() interface{} {
+	switch {
+	case e.Bar != nil:
+		return e.Bar
+	case e.Trade != nil:
+		return e.Trade
+	}
+	return e.Quote
+}
@@ -0,0 +1,592 @@
+package broker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+)
+
+// Entry is one line of a broker recording: a call with its request and
+// result, or a trade update received on one of the session's streams
+type Entry struct {
+	Time     time.Time       `json:"time"`
+	Method   string          `json:"method"`           // Broker method, or "trade_update"
+	Stream   int             `json:"stream,omitempty"` // Trade updates: 1 for the first stream opened, and so on
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    *EntryError     `json:"error,omitempty"`
+}
+
+// EntryError is a recorded error; API errors keep their status and code
+type EntryError struct {
+	Status  int    `json:"status,omitempty"`
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// tradeUpdateMethod marks trade update entries
+const tradeUpdateMethod = "trade_update"
+
+// Recorder is a Broker decorator that writes every call and trade update
+// to an NDJSON log, which Replay plays back
+type Recorder struct {
+	Broker
+
+	mu      sync.Mutex
+	enc     *json.Encoder
+	streams int
+	err     error
+}
+
+// NewRecorder records b's traffic to w
+func NewRecorder(b Broker, w io.Writer) *Recorder {
+	return &Recorder{Broker: b, enc: json.NewEncoder(w)}
+}
+
+// Unwrap returns the recorded broker
+func (r *Recorder) Unwrap() Broker {
+	return r.Broker
+}
+
+// Err returns the first error writing the recording
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// record writes one entry; a failed write is kept for Err and stops recording
+func (r *Recorder) record(method string, stream int, request, response interface{}, callErr error) {
+	entry := Entry{Time: time.Now().UTC(), Method: method, Stream: stream}
+	if request != nil {
+		entry.Request, _ = json.Marshal(request)
+	}
+	if callErr != nil {
+		entry.Error = &EntryError{Message: callErr.Error()}
+		var apiErr *alpaca.APIError
+		if errors.As(callErr, &apiErr) {
+			entry.Error.Status, entry.Error.Code, entry.Error.Message = apiErr.StatusCode, apiErr.Code, apiErr.Message
+		}
+	}
+	// A call can fail yet still return results, as CloseAllPositions does
+	if response != nil {
+		entry.Response, _ = json.Marshal(response)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = r.enc.Encode(entry)
+	}
+}
+
+// recorded runs a call and records it
+func recorded[T any](r *Recorder, method string, request interface{}, call func() (T, error)) (T, error) {
+	result, err := call()
+	r.record(method, 0, request, result, err)
+	return result, err
+}
+
+// Broker methods, each recording its call
+
+func (r *Recorder) GetAccount() (*alpaca.Account, error) {
+	return recorded(r, "GetAccount", nil, r.Broker.GetAccount)
+}
+
+func (r *Recorder) GetClock() (*alpaca.Clock, error) {
+	return recorded(r, "GetClock", nil, r.Broker.GetClock)
+}
+
+func (r *Recorder) GetPositions() ([]alpaca.Position, error) {
+	return recorded(r, "GetPositions", nil, r.Broker.GetPositions)
+}
+
+func (r *Recorder) GetPosition(symbol string) (*alpaca.Position, error) {
+	return recorded(r, "GetPosition", symbol, func() (*alpaca.Position, error) {
+		return r.Broker.GetPosition(symbol)
+	})
+}
+
+func (r *Recorder) ClosePosition(symbol string, req alpaca.ClosePositionRequest) (*alpaca.Order, error) {
+	request := map[string]interface{}{"symbol": symbol, "request": req}
+	return recorded(r, "ClosePosition", request, func() (*alpaca.Order, error) {
+		return r.Broker.ClosePosition(symbol, req)
+	})
+}
+
+func (r *Recorder) CloseAllPositions(req alpaca.CloseAllPositionsRequest) ([]alpaca.Order, error) {
+	return recorded(r, "CloseAllPositions", req, func() ([]alpaca.Order, error) {
+		return r.Broker.CloseAllPositions(req)
+	})
+}
+
+func (r *Recorder) GetOrders(req alpaca.GetOrdersRequest) ([]alpaca.Order, error) {
+	return recorded(r, "GetOrders", req, func() ([]alpaca.Order, error) {
+		return r.Broker.GetOrders(req)
+	})
+}
+
+func (r *Recorder) GetOrder(orderID string) (*alpaca.Order, error) {
+	return recorded(r, "GetOrder", orderID, func() (*alpaca.Order, error) {
+		return r.Broker.GetOrder(orderID)
+	})
+}
+
+func (r *Recorder) GetOrderByClientOrderID(clientOrderID string) (*alpaca.Order, error) {
+	request := map[string]interface{}{"client_order_id": clientOrderID}
+	return recorded(r, "GetOrderByClientOrderID", request, func() (*alpaca.Order, error) {
+		return r.Broker.GetOrderByClientOrderID(clientOrderID)
+	})
+}
+
+func (r *Recorder) PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error) {
+	return recorded(r, "PlaceOrder", req, func() (*alpaca.Order, error) {
+		return r.Broker.PlaceOrder(req)
+	})
+}
+
+func (r *Recorder) ReplaceOrder(orderID string, req alpaca.ReplaceOrderRequest) (*alpaca.Order, error) {
+	request := map[string]interface{}{"order_id": orderID, "request": req}
+	return recorded(r, "ReplaceOrder", request, func() (*alpaca.Order, error) {
+		return r.Broker.ReplaceOrder(orderID, req)
+	})
+}
+
+func (r *Recorder) CancelOrder(orderID string) error {
+	err := r.Broker.CancelOrder(orderID)
+	r.record("CancelOrder", 0, orderID, nil, err)
+	return err
+}
+
+func (r *Recorder) CancelAllOrders() error {
+	err := r.Broker.CancelAllOrders()
+	r.record("CancelAllOrders", 0, nil, nil, err)
+	return err
+}
+
+func (r *Recorder) GetAssets(req alpaca.GetAssetsRequest) ([]alpaca.Asset, error) {
+	return recorded(r, "GetAssets", req, func() ([]alpaca.Asset, error) {
+		return r.Broker.GetAssets(req)
+	})
+}
+
+func (r *Recorder) GetAsset(symbol string) (*alpaca.Asset, error) {
+	return recorded(r, "GetAsset", symbol, func() (*alpaca.Asset, error) {
+		return r.Broker.GetAsset(symbol)
+	})
+}
+
+// TradeUpdates returns the broker's stream, recording what it delivers.
+// Streams are numbered in the order they are opened, so Replay can hand
+// each one back its own events.
+func (r *Recorder) TradeUpdates() TradeStream {
+	r.mu.Lock()
+	r.streams++
+	stream := r.streams
+	r.mu.Unlock()
+	return &recordingStream{TradeStream: r.Broker.TradeUpdates(), recorder: r, stream: stream}
+}
+
+// recordingStream records the updates its stream delivers
+type recordingStream struct {
+	TradeStream
+	recorder *Recorder
+	stream   int
+}
+
+func (s *recordingStream) SetTradeUpdateHandler(handler func(TradeUpdate)) {
+	s.TradeStream.SetTradeUpdateHandler(func(update TradeUpdate) {
+		s.recorder.record(tradeUpdateMethod, s.stream, nil, update, nil)
+		handler(update)
+	})
+}
+
+// replayCall is a recorded call with the trade updates received after it
+type replayCall struct {
+	Entry
+	after map[int][]TradeUpdate // Stream -> updates recorded before the next call
+}
+
+// Replay is a Broker that answers from a recording. Each call returns the
+// next recorded result for its method and must repeat that call's
+// arguments; a call that doesn't fails instead of getting another call's
+// answer. Client order IDs are exempt, since callers usually derive them
+// from the clock: a new ID is paired with the recorded one the first time
+// it is sent and must stay paired after that, and recorded IDs in responses
+// and trade updates are rewritten to the live ones. Trade updates are held
+// until the call they followed has returned and the caller has had a moment to
+// register the result, then released to their stream when the next call starts
+// or after releaseDelay, whichever comes first.
+type Replay struct {
+	mu        sync.Mutex
+	calls     map[string][]*replayCall
+	clientIDs map[string]string     // Client order ID sent during replay -> recorded ID
+	liveIDs   map[string]string     // Recorded client order ID -> ID sent during replay
+	held      []*replayCall         // Replayed calls whose trade updates are not released yet
+	backlog   map[int][]TradeUpdate // Released updates for streams not yet connected
+	streams   []*QueuedStream       // In the order opened; index 0 is stream 1
+	active    map[*QueuedStream]bool
+}
+
+// LoadReplay reads a recording written by Recorder
+func LoadReplay(path string) (*Replay, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return NewReplay(file)
+}
+
+// NewReplay reads a recording from r
+func NewReplay(r io.Reader) (*Replay, error) {
+	replay := &Replay{
+		calls:     make(map[string][]*replayCall),
+		clientIDs: make(map[string]string),
+		liveIDs:   make(map[string]string),
+		backlog:   make(map[int][]TradeUpdate),
+		active:    make(map[*QueuedStream]bool),
+	}
+
+	var last *replayCall
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("recording line %d: %w", n, err)
+		}
+
+		if entry.Method != tradeUpdateMethod {
+			last = &replayCall{Entry: entry, after: make(map[int][]TradeUpdate)}
+			replay.calls[entry.Method] = append(replay.calls[entry.Method], last)
+			continue
+		}
+		var update TradeUpdate
+		if err := json.Unmarshal(entry.Response, &update); err != nil {
+			return nil, fmt.Errorf("recording line %d: %w", n, err)
+		}
+		if last == nil {
+			replay.backlog[entry.Stream] = append(replay.backlog[entry.Stream], update) // Before any call
+		} else {
+			last.after[entry.Stream] = append(last.after[entry.Stream], update)
+		}
+	}
+	return replay, scanner.Err()
+}
+
+// releaseDelay is how long a replayed call's trade updates are held after it
+// returns when no other call comes first
+const releaseDelay = 50 * time.Millisecond
+
+// next takes the method's next recorded call, if request matches its
+// arguments, and holds the trade updates that followed it. Updates held by
+// earlier calls are released first, as they were received before this call.
+func (r *Replay) next(method string, request interface{}) (*replayCall, error) {
+	var live json.RawMessage
+	if request != nil {
+		live, _ = json.Marshal(request)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.releaseLocked()
+
+	queue := r.calls[method]
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("replay: no recorded %s call left", method)
+	}
+	call := queue[0]
+	if !r.matchesLocked(call.Request, live) {
+		return nil, fmt.Errorf("replay: %s(%s) does not match the recorded %s(%s)", method, live, method, call.Request)
+	}
+	r.calls[method] = queue[1:]
+	r.held = append(r.held, call)
+	return call, nil
+}
+
+// release hands held trade updates to their streams
+func (r *Replay) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.releaseLocked()
+}
+
+// releaseLocked hands held trade updates to their streams, with client order
+// IDs rewritten to the live ones; caller must hold r.mu
+func (r *Replay) releaseLocked() {
+	for _, call := range r.held {
+		for stream, updates := range call.after {
+			live := make([]TradeUpdate, len(updates))
+			for j, update := range updates {
+				if id, exists := r.liveIDs[update.Data.Order.ClientOrderID]; exists {
+					update.Data.Order.ClientOrderID = id
+				}
+				live[j] = update
+			}
+
+			if i := stream - 1; i >= 0 && i < len(r.streams) && r.active[r.streams[i]] {
+				r.streams[i].Push(live...)
+			} else {
+				r.backlog[stream] = append(r.backlog[stream], live...)
+			}
+		}
+	}
+	r.held = nil
+}
+
+// liveResponse rewrites recorded client order IDs in a response to the live ones
+func (r *Replay) liveResponse(response json.RawMessage) json.RawMessage {
+	if !bytes.Contains(response, []byte(`"client_order_id"`)) {
+		return response
+	}
+
+	var decoded interface{}
+	if json.Unmarshal(response, &decoded) != nil {
+		return response
+	}
+	r.mu.Lock()
+	r.rewriteIDsLocked(decoded)
+	r.mu.Unlock()
+
+	rewritten, err := json.Marshal(decoded)
+	if err != nil {
+		return response
+	}
+	return rewritten
+}
+
+// rewriteIDsLocked replaces paired client order IDs in decoded JSON; caller
+// must hold r.mu
+func (r *Replay) rewriteIDsLocked(value interface{}) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for field, inner := range value {
+			if id, ok := inner.(string); ok && field == "client_order_id" {
+				if live, exists := r.liveIDs[id]; exists {
+					value[field] = live
+				}
+				continue
+			}
+			r.rewriteIDsLocked(inner)
+		}
+	case []interface{}:
+		for _, inner := range value {
+			r.rewriteIDsLocked(inner)
+		}
+	}
+}
+
+// matchesLocked compares a call's arguments with the recorded ones, pairing
+// client order IDs as it goes; caller must hold r.mu
+func (r *Replay) matchesLocked(recorded, live json.RawMessage) bool {
+	if len(recorded) == 0 || len(live) == 0 {
+		return len(recorded) == len(live)
+	}
+	var want, got interface{}
+	if json.Unmarshal(recorded, &want) != nil || json.Unmarshal(live, &got) != nil {
+		return false
+	}
+
+	// Pairings made by a call that doesn't match are discarded
+	paired := make(map[string]string)
+	if !r.sameLocked(want, got, "", paired) {
+		return false
+	}
+	for sent, recordedID := range paired {
+		r.clientIDs[sent] = recordedID
+		r.liveIDs[recordedID] = sent
+	}
+	return true
+}
+
+// sameLocked compares decoded JSON values; key is the field holding them and
+// paired collects new client order ID pairings
+func (r *Replay) sameLocked(want, got interface{}, key string, paired map[string]string) bool {
+	switch want := want.(type) {
+	case map[string]interface{}:
+		got, ok := got.(map[string]interface{})
+		if !ok || len(got) != len(want) {
+			return false
+		}
+		for field, value := range want {
+			if other, ok := got[field]; !ok || !r.sameLocked(value, other, field, paired) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		got, ok := got.([]interface{})
+		if !ok || len(got) != len(want) {
+			return false
+		}
+		for i := range want {
+			if !r.sameLocked(want[i], got[i], key, paired) {
+				return false
+			}
+		}
+		return true
+	case string:
+		got, ok := got.(string)
+		if !ok {
+			return false
+		}
+		if key == "client_order_id" && want != "" && got != "" {
+			if recordedID, exists := r.clientIDs[got]; exists {
+				return recordedID == want
+			}
+			if recordedID, exists := paired[got]; exists {
+				return recordedID == want
+			}
+			paired[got] = want
+			return true
+		}
+		return got == want
+	}
+	return want == got // Numbers, booleans and null
+}
+
+// err rebuilds a recorded error
+func (e *EntryError) err() error {
+	if e == nil {
+		return nil
+	}
+	if e.Status != 0 || e.Code != 0 {
+		return &alpaca.APIError{StatusCode: e.Status, Code: e.Code, Message: e.Message}
+	}
+	return errors.New(e.Message)
+}
+
+// replayed returns the method's next recorded result for request
+func replayed[T any](r *Replay, method string, request interface{}) (T, error) {
+	var result T
+	call, err := r.next(method, request)
+	if err != nil {
+		return result, err
+	}
+	defer time.AfterFunc(releaseDelay, r.release)
+
+	if len(call.Response) > 0 {
+		if err := json.Unmarshal(r.liveResponse(call.Response), &result); err != nil {
+			return result, fmt.Errorf("replay: bad %s response: %w", method, err)
+		}
+	}
+	return result, call.Error.err()
+}
+
+// Broker methods, each answered from the recording
+
+func (r *Replay) GetAccount() (*alpaca.Account, error) {
+	return replayed[*alpaca.Account](r, "GetAccount", nil)
+}
+
+func (r *Replay) GetClock() (*alpaca.Clock, error) {
+	return replayed[*alpaca.Clock](r, "GetClock", nil)
+}
+
+func (r *Replay) GetPositions() ([]alpaca.Position, error) {
+	return replayed[[]alpaca.Position](r, "GetPositions", nil)
+}
+
+func (r *Replay) GetPosition(symbol string) (*alpaca.Position, error) {
+	return replayed[*alpaca.Position](r, "GetPosition", symbol)
+}
+
+func (r *Replay) ClosePosition(symbol string, req alpaca.ClosePositionRequest) (*alpaca.Order, error) {
+	return replayed[*alpaca.Order](r, "ClosePosition", map[string]interface{}{"symbol": symbol, "request": req})
+}
+
+func (r *Replay) CloseAllPositions(req alpaca.CloseAllPositionsRequest) ([]alpaca.Order, error) {
+	return replayed[[]alpaca.Order](r, "CloseAllPositions", req)
+}
+
+func (r *Replay) GetOrders(req alpaca.GetOrdersRequest) ([]alpaca.Order, error) {
+	return replayed[[]alpaca.Order](r, "GetOrders", req)
+}
+
+func (r *Replay) GetOrder(orderID string) (*alpaca.Order, error) {
+	return replayed[*alpaca.Order](r, "GetOrder", orderID)
+}
+
+func (r *Replay) GetOrderByClientOrderID(clientOrderID string) (*alpaca.Order, error) {
+	return replayed[*alpaca.Order](r, "GetOrderByClientOrderID", map[string]interface{}{"client_order_id": clientOrderID})
+}
+
+func (r *Replay) PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error) {
+	return replayed[*alpaca.Order](r, "PlaceOrder", req)
+}
+
+func (r *Replay) ReplaceOrder(orderID string, req alpaca.ReplaceOrderRequest) (*alpaca.Order, error) {
+	return replayed[*alpaca.Order](r, "ReplaceOrder", map[string]interface{}{"order_id": orderID, "request": req})
+}
+
+func (r *Replay) CancelOrder(orderID string) error {
+	_, err := replayed[json.RawMessage](r, "CancelOrder", orderID)
+	return err
+}
+
+func (r *Replay) CancelAllOrders() error {
+	_, err := replayed[json.RawMessage](r, "CancelAllOrders", nil)
+	return err
+}
+
+func (r *Replay) GetAssets(req alpaca.GetAssetsRequest) ([]alpaca.Asset, error) {
+	return replayed[[]alpaca.Asset](r, "GetAssets", req)
+}
+
+func (r *Replay) GetAsset(symbol string) (*alpaca.Asset, error) {
+	return replayed[*alpaca.Asset](r, "GetAsset", symbol)
+}
+
+// TradeUpdates returns the next stream of the recorded session
+func (r *Replay) TradeUpdates() TradeStream {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	q := NewQueuedStream(r.attach, r.detach)
+	r.streams = append(r.streams, q)
+	return q
+}
+
+// attach starts a connected stream with the updates released so far
+func (r *Replay) attach(q *QueuedStream) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.active[q] = true
+	for i, stream := range r.streams {
+		if stream == q {
+			q.Push(r.backlog[i+1]...)
+			delete(r.backlog, i+1)
+		}
+	}
+}
+
+func (r *Replay) detach(q *QueuedStream) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.active, q)
+}
+
+// Remaining reports how many recorded calls have not been replayed, by
+// method; a faithful rerun leaves none
+func (r *Replay) Remaining() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	remaining := make(map[string]int)
+	for method, queue := range r.calls {
+		if len(queue) > 0 {
+			remaining[method] = len(queue)
+		}
+	}
+	return remaining
+}
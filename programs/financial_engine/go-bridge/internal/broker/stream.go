@@ -0,0 +1,126 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// QueuedStream is a TradeStream fed in process. Events queue without bound
+// and reach the handler in order on the stream's own goroutine, as they
+// would from the network, so handlers may call back into the broker.
+type QueuedStream struct {
+	attach func(*QueuedStream) // Registers a connected stream with its broker
+	detach func(*QueuedStream)
+
+	mu        sync.Mutex
+	handler   func(TradeUpdate)
+	queue     []TradeUpdate
+	connected bool
+	closed    bool
+	wake      chan struct{}
+	done      chan struct{}
+}
+
+func NewQueuedStream(attach, detach func(*QueuedStream)) *QueuedStream {
+	return &QueuedStream{
+		attach: attach,
+		detach: detach,
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// SetTradeUpdateHandler sets the trade update callback
+func (q *QueuedStream) SetTradeUpdateHandler(handler func(TradeUpdate)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handler = handler
+}
+
+// SetReconnectHandler is a no-op; an in-process stream never drops
+func (q *QueuedStream) SetReconnectHandler(func()) {}
+
+// Connect starts delivery until Disconnect or ctx is done
+func (q *QueuedStream) Connect(ctx context.Context) error {
+	q.mu.Lock()
+	if q.connected || q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.connected = true
+	q.mu.Unlock()
+
+	go q.deliver()
+	go func() {
+		select {
+		case <-ctx.Done():
+			q.Disconnect()
+		case <-q.done:
+		}
+	}()
+	q.attach(q)
+	return nil
+}
+
+// Disconnect stops delivery; queued events are dropped
+func (q *QueuedStream) Disconnect() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	connected := q.connected
+	q.queue = nil
+	close(q.done)
+	q.mu.Unlock()
+
+	if connected {
+		q.detach(q)
+	}
+	return nil
+}
+
+// push queues updates for delivery
+func (q *QueuedStream) Push(updates ...TradeUpdate) {
+	if len(updates) == 0 {
+		return
+	}
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.queue = append(q.queue, updates...)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliver hands queued events to the handler in order
+func (q *QueuedStream) deliver() {
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-q.wake:
+		}
+		for {
+			q.mu.Lock()
+			if q.closed || len(q.queue) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			update, handler := q.queue[0], q.handler
+			q.queue = q.queue[1:]
+			q.mu.Unlock()
+
+			if handler != nil {
+				handler(update)
+			}
+		}
+	}
+}
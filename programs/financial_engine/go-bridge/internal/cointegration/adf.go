@@ -0,0 +1,166 @@
+package cointegration
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// CriticalValues are test statistic thresholds at common significance levels
+type CriticalValues struct {
+	OnePercent  float64
+	FivePercent float64
+	TenPercent  float64
+}
+
+// ADFResult is an augmented Dickey-Fuller unit-root test
+type ADFResult struct {
+	Statistic      float64 // t-statistic of the lagged level; more negative rejects a unit root
+	PValue         float64 // MacKinnon approximate p-value
+	Lags           int     // Lagged differences in the test regression, chosen by AIC
+	NObs           int     // Observations in the test regression
+	CriticalValues CriticalValues
+}
+
+// ADF runs an augmented Dickey-Fuller test with a constant. Lagged differences
+// are chosen by AIC from 0 to maxLags; a negative maxLags uses Schwert's rule
+// 12*(n/100)^(1/4).
+func ADF(series []float64, maxLags int) (ADFResult, error) {
+	statistic, lags, nobs, err := adfStatistic(series, maxLags, true)
+	if err != nil {
+		return ADFResult{}, err
+	}
+	return ADFResult{
+		Statistic:      statistic,
+		PValue:         mackinnonP(statistic, 1),
+		Lags:           lags,
+		NObs:           nobs,
+		CriticalValues: mackinnonCritical(1, nobs),
+	}, nil
+}
+
+// adfStatistic fits Δy(t) = [c] + γ·y(t-1) + Σ φ(i)·Δy(t-i) and returns the
+// t-statistic of γ. Lags are compared by AIC on a common sample, then the
+// chosen lag is refit on all available observations.
+func adfStatistic(series []float64, maxLags int, constant bool) (float64, int, int, error) {
+	n := len(series)
+	trend := 0
+	if constant {
+		trend = 1
+	}
+	if maxLags < 0 {
+		maxLags = int(12 * math.Pow(float64(n)/100, 0.25))
+	}
+	if limit := n/2 - trend - 1; maxLags > limit {
+		maxLags = limit
+	}
+	if maxLags < 0 || n < 4 {
+		return 0, 0, 0, fmt.Errorf("series too short for a unit-root test: %d observations", n)
+	}
+
+	dy := diff(series)
+	best, bestAIC := 0, math.Inf(1)
+	for lags := 0; lags <= maxLags; lags++ {
+		fit, nobs, err := adfRegression(series, dy, lags, maxLags, constant)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		k := float64(1 + lags + trend)
+		if aic := float64(nobs)*math.Log(fit.rss/float64(nobs)) + 2*k; aic < bestAIC {
+			best, bestAIC = lags, aic
+		}
+	}
+
+	fit, nobs, err := adfRegression(series, dy, best, best, constant)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if fit.stdErr[0] == 0 {
+		return 0, 0, 0, fmt.Errorf("unit-root regression is degenerate")
+	}
+	return fit.coef[0] / fit.stdErr[0], best, nobs, nil
+}
+
+// adfRegression fits the test regression with lags lagged differences,
+// starting after start differences so different lags share a sample. The
+// lagged level is the first coefficient.
+func adfRegression(series, dy []float64, lags, start int, constant bool) (olsFit, int, error) {
+	nobs := len(dy) - start
+	y := dy[start:]
+	columns := [][]float64{series[start : start+nobs]}
+	for i := 1; i <= lags; i++ {
+		columns = append(columns, dy[start-i:start-i+nobs])
+	}
+	if constant {
+		columns = append(columns, ones(nobs))
+	}
+	fit, err := ols(y, design(columns...))
+	return fit, nobs, err
+}
+
+// MacKinnon (1994) response surfaces for the p-value of a unit-root statistic
+// with a constant, indexed by the number of series less one. Below tauStar
+// the small-p polynomial applies and above it the large-p one; two-series
+// (Engle-Granger) tests use the small-p polynomial throughout, which is least
+// accurate for p-values far above any usable significance level.
+var (
+	tauStar  = []float64{-1.61, -2.62}
+	tauMin   = []float64{-18.83, -18.86}
+	tauMax   = []float64{2.74, 0.92}
+	tauSmall = [][]float64{
+		{2.1659, 1.4412, 3.8269e-2},
+		{2.92, 1.5012, 3.9796e-2},
+	}
+	tauLarge = [][]float64{
+		{1.7339, 9.3202e-1, -1.2745e-1, -1.0368e-2},
+		nil,
+	}
+)
+
+// mackinnonP is the approximate p-value of a unit-root statistic for a test
+// on nSeries series (1 for ADF, 2 for a pair's residuals)
+func mackinnonP(statistic float64, nSeries int) float64 {
+	i := nSeries - 1
+	switch {
+	case statistic > tauMax[i]:
+		return 1
+	case statistic < tauMin[i]:
+		return 0
+	}
+	coef := tauSmall[i]
+	if statistic > tauStar[i] && tauLarge[i] != nil {
+		coef = tauLarge[i]
+	}
+	x, power := 0.0, 1.0
+	for _, c := range coef {
+		x += c * power
+		power *= statistic
+	}
+	return distuv.UnitNormal.CDF(x)
+}
+
+// MacKinnon (2010) finite-sample critical values with a constant: each level
+// is τ∞ + β1/T + β2/T² + β3/T³, indexed by the number of series less one
+var criticalSurface = [][3][4]float64{
+	{
+		{-3.43035, -6.5393, -16.786, -79.433},
+		{-2.86154, -2.8903, -4.234, -40.040},
+		{-2.56677, -1.5384, -2.809, 0},
+	},
+	{
+		{-3.89644, -10.9519, -22.527, 0},
+		{-3.33613, -6.1101, -6.823, 0},
+		{-3.04445, -4.2412, -2.720, 0},
+	},
+}
+
+// mackinnonCritical returns critical values for nobs test observations
+func mackinnonCritical(nSeries, nobs int) CriticalValues {
+	var levels [3]float64
+	t := 1 / float64(nobs)
+	for i, b := range criticalSurface[nSeries-1] {
+		levels[i] = b[0] + b[1]*t + b[2]*t*t + b[3]*t*t*t
+	}
+	return CriticalValues{OnePercent: levels[0], FivePercent: levels[1], TenPercent: levels[2]}
+}
@@ -0,0 +1,80 @@
+// Package cointegration provides the statistics behind pairs and basket
+// trading: augmented Dickey-Fuller and Engle-Granger tests for a pair, the
+// Johansen test for baskets, Ornstein-Uhlenbeck half-life of a spread, and a
+// Kalman-filter hedge ratio that updates every bar.
+//
+// P-values and critical values follow MacKinnon's response surfaces, the
+// same approximations statistical packages report.
+package cointegration
+
+import (
+	"fmt"
+	"math"
+)
+
+// EngleGrangerResult is a two-step Engle-Granger cointegration test of
+// y = Intercept + HedgeRatio·x + spread
+type EngleGrangerResult struct {
+	HedgeRatio     float64
+	Intercept      float64
+	Statistic      float64 // ADF t-statistic of the spread
+	PValue         float64 // MacKinnon approximate p-value for two series
+	Lags           int
+	NObs           int
+	CriticalValues CriticalValues
+	Spread         []float64 // Regression residuals
+}
+
+// Cointegrated reports whether the test rejects no cointegration at the
+// given significance level, e.g. 0.05
+func (r EngleGrangerResult) Cointegrated(significance float64) bool {
+	return r.PValue <= significance
+}
+
+// EngleGranger regresses y on x by OLS and tests the residual spread for a
+// unit root. Lags are chosen as in ADF. The spread already has zero mean, so
+// its test regression has no constant, and the statistic is compared with
+// two-series critical values because the hedge ratio was estimated.
+func EngleGranger(y, x []float64, maxLags int) (EngleGrangerResult, error) {
+	if len(y) != len(x) {
+		return EngleGrangerResult{}, fmt.Errorf("series lengths differ: %d and %d", len(y), len(x))
+	}
+	fit, err := ols(y, design(x, ones(len(x))))
+	if err != nil {
+		return EngleGrangerResult{}, fmt.Errorf("hedge regression failed: %w", err)
+	}
+
+	statistic, lags, nobs, err := adfStatistic(fit.residuals, maxLags, false)
+	if err != nil {
+		return EngleGrangerResult{}, err
+	}
+	return EngleGrangerResult{
+		HedgeRatio:     fit.coef[0],
+		Intercept:      fit.coef[1],
+		Statistic:      statistic,
+		PValue:         mackinnonP(statistic, 2),
+		Lags:           lags,
+		NObs:           nobs,
+		CriticalValues: mackinnonCritical(2, nobs),
+		Spread:         fit.residuals,
+	}, nil
+}
+
+// HalfLife estimates how many bars an Ornstein-Uhlenbeck spread takes to
+// close half its distance to the mean, from Δs(t) = a + b·s(t-1) as -ln2/b.
+// A spread that does not mean-revert (b >= 0) has an infinite half-life.
+func HalfLife(spread []float64) (float64, error) {
+	if len(spread) < 3 {
+		return 0, fmt.Errorf("series too short for a half-life: %d observations", len(spread))
+	}
+	n := len(spread) - 1
+	fit, err := ols(diff(spread), design(spread[:n], ones(n)))
+	if err != nil {
+		return 0, fmt.Errorf("half-life regression failed: %w", err)
+	}
+	b := fit.coef[0]
+	if b >= 0 {
+		return math.Inf(1), nil
+	}
+	return -math.Ln2 / b, nil
+}
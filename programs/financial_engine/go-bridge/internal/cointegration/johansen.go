@@ -0,0 +1,198 @@
+package cointegration
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// JohansenResult is a Johansen cointegration test on a basket. Entry r of
+// each slice tests the hypothesis of at most r cointegrating relations.
+type JohansenResult struct {
+	Eigenvalues      []float64   // Descending
+	Vectors          [][]float64 // Cointegrating vectors, by eigenvalue, scaled so the first weight is 1
+	TraceStats       []float64
+	MaxEigenStats    []float64
+	TraceCritical    []CriticalValues
+	MaxEigenCritical []CriticalValues
+	Rank             int // Cointegrating relations accepted by the trace test at 5%
+}
+
+// Johansen critical values with an unrestricted constant, indexed by the
+// number of series less the hypothesized rank, less one
+var (
+	johansenTrace = []CriticalValues{
+		{OnePercent: 6.6349, FivePercent: 3.8415, TenPercent: 2.7055},
+		{OnePercent: 19.9349, FivePercent: 15.4943, TenPercent: 13.4294},
+		{OnePercent: 35.4628, FivePercent: 29.7961, TenPercent: 27.0669},
+		{OnePercent: 54.6815, FivePercent: 47.8545, TenPercent: 44.4929},
+		{OnePercent: 77.8202, FivePercent: 69.8189, TenPercent: 65.8202},
+		{OnePercent: 104.9637, FivePercent: 95.7542, TenPercent: 91.1090},
+	}
+	johansenMaxEigen = []CriticalValues{
+		{OnePercent: 6.6349, FivePercent: 3.8415, TenPercent: 2.7055},
+		{OnePercent: 18.5200, FivePercent: 14.2639, TenPercent: 12.2971},
+		{OnePercent: 25.8650, FivePercent: 21.1314, TenPercent: 18.8928},
+		{OnePercent: 32.7172, FivePercent: 27.5858, TenPercent: 25.1236},
+		{OnePercent: 39.3693, FivePercent: 33.8777, TenPercent: 31.2379},
+		{OnePercent: 45.8662, FivePercent: 40.0763, TenPercent: 37.2786},
+	}
+)
+
+// Johansen tests a basket of 2 to 6 price series for cointegration using a
+// VECM with lags lagged differences and an unrestricted constant. The first
+// vector weights the most stationary combination of the series.
+func Johansen(series [][]float64, lags int) (JohansenResult, error) {
+	k := len(series)
+	if k < 2 || k > len(johansenTrace) {
+		return JohansenResult{}, fmt.Errorf("johansen test needs 2 to %d series, got %d", len(johansenTrace), k)
+	}
+	if lags < 0 {
+		return JohansenResult{}, fmt.Errorf("lags must not be negative")
+	}
+	n := len(series[0])
+	for _, s := range series[1:] {
+		if len(s) != n {
+			return JohansenResult{}, fmt.Errorf("series lengths differ")
+		}
+	}
+	t := n - 1 - lags // Usable observations
+	if t <= k*(lags+1)+1 {
+		return JohansenResult{}, fmt.Errorf("series too short for %d lags: %d observations", lags, n)
+	}
+
+	// Concentrate out the short-run terms: regress Δx(t) and x(t-1) on the
+	// lagged differences and a constant, keeping the residuals
+	dx := mat.NewDense(t, k, nil)
+	level := mat.NewDense(t, k, nil)
+	var short [][]float64
+	for j, s := range series {
+		d := diff(s)
+		for i := 0; i < t; i++ {
+			dx.Set(i, j, d[lags+i])
+			level.Set(i, j, s[lags+i])
+		}
+		for l := 1; l <= lags; l++ {
+			short = append(short, d[lags-l:lags-l+t])
+		}
+	}
+	z := design(append(short, ones(t))...)
+	r0, err := residualMatrix(dx, z)
+	if err != nil {
+		return JohansenResult{}, err
+	}
+	r1, err := residualMatrix(level, z)
+	if err != nil {
+		return JohansenResult{}, err
+	}
+
+	var s00, s11, s01 mat.Dense
+	s00.Mul(r0.T(), r0)
+	s00.Scale(1/float64(t), &s00)
+	s11.Mul(r1.T(), r1)
+	s11.Scale(1/float64(t), &s11)
+	s01.Mul(r0.T(), r1)
+	s01.Scale(1/float64(t), &s01)
+
+	// Solve |λ·S11 - S10·S00⁻¹·S01| = 0 symmetrically with S11 = L·Lᵀ:
+	// the eigenvalues of L⁻¹·S10·S00⁻¹·S01·L⁻ᵀ, with vectors v = L⁻ᵀ·u
+	var chol mat.Cholesky
+	if ok := chol.Factorize(symmetric(&s11)); !ok {
+		return JohansenResult{}, fmt.Errorf("series are collinear")
+	}
+	var lower mat.TriDense
+	chol.LTo(&lower)
+	var lInv mat.TriDense
+	if err := lInv.InverseTri(&lower); err != nil {
+		return JohansenResult{}, fmt.Errorf("failed to invert covariance factor: %w", err)
+	}
+	var s00Inv mat.Dense
+	if err := s00Inv.Inverse(&s00); err != nil {
+		return JohansenResult{}, fmt.Errorf("residual covariance is singular: %w", err)
+	}
+	var s10s00, product, scaled, m mat.Dense
+	s10s00.Mul(s01.T(), &s00Inv)
+	product.Mul(&s10s00, &s01)
+	scaled.Mul(&lInv, &product)
+	m.Mul(&scaled, lInv.T())
+
+	var eig mat.EigenSym
+	if ok := eig.Factorize(symmetric(&m), true); !ok {
+		return JohansenResult{}, fmt.Errorf("eigendecomposition failed")
+	}
+	values := eig.Values(nil)
+	var u, vectors mat.Dense
+	eig.VectorsTo(&u)
+	vectors.Mul(lInv.T(), &u)
+
+	order := make([]int, k)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return values[order[a]] > values[order[b]] })
+
+	res := JohansenResult{
+		Eigenvalues:      make([]float64, k),
+		Vectors:          make([][]float64, k),
+		TraceStats:       make([]float64, k),
+		MaxEigenStats:    make([]float64, k),
+		TraceCritical:    make([]CriticalValues, k),
+		MaxEigenCritical: make([]CriticalValues, k),
+	}
+	for i, idx := range order {
+		// Rounding can push an eigenvalue a hair outside [0, 1)
+		res.Eigenvalues[i] = math.Min(math.Max(values[idx], 0), 1-1e-12)
+		v := mat.Col(nil, idx, &vectors)
+		if first := v[0]; first != 0 {
+			for j := range v {
+				v[j] /= first
+			}
+		}
+		res.Vectors[i] = v
+	}
+
+	res.Rank = -1
+	for r := 0; r < k; r++ {
+		for i := r; i < k; i++ {
+			res.TraceStats[r] -= float64(t) * math.Log(1-res.Eigenvalues[i])
+		}
+		res.MaxEigenStats[r] = -float64(t) * math.Log(1-res.Eigenvalues[r])
+		res.TraceCritical[r] = johansenTrace[k-r-1]
+		res.MaxEigenCritical[r] = johansenMaxEigen[k-r-1]
+		if res.Rank < 0 && res.TraceStats[r] < res.TraceCritical[r].FivePercent {
+			res.Rank = r
+		}
+	}
+	if res.Rank < 0 {
+		res.Rank = k
+	}
+	return res, nil
+}
+
+// residualMatrix regresses each column of y on z and returns the residuals
+func residualMatrix(y, z *mat.Dense) (*mat.Dense, error) {
+	rows, cols := y.Dims()
+	out := mat.NewDense(rows, cols, nil)
+	for j := 0; j < cols; j++ {
+		fit, err := ols(mat.Col(nil, j, y), z)
+		if err != nil {
+			return nil, fmt.Errorf("short-run regression failed: %w", err)
+		}
+		out.SetCol(j, fit.residuals)
+	}
+	return out, nil
+}
+
+// symmetric copies a numerically symmetric matrix into a SymDense
+func symmetric(a *mat.Dense) *mat.SymDense {
+	n, _ := a.Dims()
+	s := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			s.SetSym(i, j, (a.At(i, j)+a.At(j, i))/2)
+		}
+	}
+	return s
+}
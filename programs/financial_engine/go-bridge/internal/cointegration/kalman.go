@@ -0,0 +1,75 @@
+package cointegration
+
+import "math"
+
+// KalmanEstimate is one Kalman hedge ratio update
+type KalmanEstimate struct {
+	HedgeRatio   float64
+	Intercept    float64
+	Spread       float64 // y less its forecast from the previous bar's hedge
+	SpreadStdDev float64 // Forecast standard deviation of the spread
+	ZScore       float64 // Spread / SpreadStdDev
+}
+
+// KalmanHedge tracks y = Intercept + HedgeRatio·x as a random walk in both
+// coefficients, updating once per bar. Delta in (0, 1) sets how fast the
+// coefficients may drift; observation variance is the noise around the line.
+type KalmanHedge struct {
+	driftVar float64 // Per-bar coefficient variance, delta/(1-delta)
+	obsVar   float64
+
+	beta, alpha float64
+	cov         [2][2]float64 // Coefficient covariance, beta then alpha
+	value       KalmanEstimate
+}
+
+// NewKalmanHedge creates a filter starting from the given hedge ratio and
+// intercept, usually an OLS fit, with no uncertainty about them
+func NewKalmanHedge(delta, observationVar, hedgeRatio, intercept float64) *KalmanHedge {
+	return &KalmanHedge{
+		driftVar: delta / (1 - delta),
+		obsVar:   observationVar,
+		beta:     hedgeRatio,
+		alpha:    intercept,
+		value:    KalmanEstimate{HedgeRatio: hedgeRatio, Intercept: intercept},
+	}
+}
+
+// Update adds a bar and returns the new estimate. Spread and ZScore use the
+// coefficients from before the bar, so they are true one-step forecast errors.
+func (k *KalmanHedge) Update(y, x float64) KalmanEstimate {
+	// Predict: coefficients carry over and gain drift variance
+	r := k.cov
+	r[0][0] += k.driftVar
+	r[1][1] += k.driftVar
+
+	// Observation h = [x, 1]; forecast variance q = h·R·hᵀ + obsVar
+	rh0 := r[0][0]*x + r[0][1]
+	rh1 := r[1][0]*x + r[1][1]
+	q := x*rh0 + rh1 + k.obsVar
+	e := y - (k.beta*x + k.alpha)
+
+	// Correct with gain R·hᵀ/q
+	g0, g1 := rh0/q, rh1/q
+	k.beta += g0 * e
+	k.alpha += g1 * e
+	k.cov = [2][2]float64{
+		{r[0][0] - g0*rh0, r[0][1] - g0*rh1},
+		{r[1][0] - g1*rh0, r[1][1] - g1*rh1},
+	}
+
+	std := math.Sqrt(q)
+	k.value = KalmanEstimate{
+		HedgeRatio:   k.beta,
+		Intercept:    k.alpha,
+		Spread:       e,
+		SpreadStdDev: std,
+	}
+	if std > 0 {
+		k.value.ZScore = e / std
+	}
+	return k.value
+}
+
+// Value returns the latest estimate
+func (k *KalmanHedge) Value() KalmanEstimate { return k.value }
@@ -0,0 +1,99 @@
+package cointegration
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// olsFit is an ordinary least squares fit
+type olsFit struct {
+	coef      []float64
+	stdErr    []float64
+	residuals []float64
+	rss       float64
+}
+
+// ols regresses y on the columns of x. Standard errors use the residual
+// variance with n-k degrees of freedom.
+func ols(y []float64, x *mat.Dense) (olsFit, error) {
+	n, k := x.Dims()
+	if n != len(y) {
+		return olsFit{}, fmt.Errorf("regression has %d observations but %d rows", len(y), n)
+	}
+	if n <= k {
+		return olsFit{}, fmt.Errorf("regression needs more than %d observations, got %d", k, n)
+	}
+
+	var xtx mat.SymDense
+	xtx.SymOuterK(1, x.T())
+	var chol mat.Cholesky
+	if ok := chol.Factorize(&xtx); !ok {
+		return olsFit{}, fmt.Errorf("regressors are collinear")
+	}
+
+	yVec := mat.NewVecDense(n, y)
+	var xty, beta mat.VecDense
+	xty.MulVec(x.T(), yVec)
+	if err := chol.SolveVecTo(&beta, &xty); err != nil {
+		return olsFit{}, fmt.Errorf("failed to solve normal equations: %w", err)
+	}
+
+	fit := olsFit{coef: make([]float64, k), stdErr: make([]float64, k), residuals: make([]float64, n)}
+	for j := 0; j < k; j++ {
+		fit.coef[j] = beta.AtVec(j)
+	}
+	var fitted mat.VecDense
+	fitted.MulVec(x, &beta)
+	for i := 0; i < n; i++ {
+		r := y[i] - fitted.AtVec(i)
+		fit.residuals[i] = r
+		fit.rss += r * r
+	}
+
+	var inv mat.SymDense
+	if err := chol.InverseTo(&inv); err != nil {
+		return olsFit{}, fmt.Errorf("failed to invert normal equations: %w", err)
+	}
+	sigma2 := fit.rss / float64(n-k)
+	for j := 0; j < k; j++ {
+		if v := sigma2 * inv.At(j, j); v > 0 {
+			fit.stdErr[j] = math.Sqrt(v)
+		}
+	}
+	return fit, nil
+}
+
+// design builds an n×k regressor matrix from columns of equal length
+func design(columns ...[]float64) *mat.Dense {
+	n := len(columns[0])
+	x := mat.NewDense(n, len(columns), nil)
+	for j, col := range columns {
+		for i, v := range col {
+			x.Set(i, j, v)
+		}
+	}
+	return x
+}
+
+// ones returns n ones, the constant regressor
+func ones(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = 1
+	}
+	return out
+}
+
+// diff returns first differences: out[i] = series[i+1] - series[i]
+func diff(series []float64) []float64 {
+	if len(series) < 2 {
+		return nil
+	}
+	out := make([]float64, len(series)-1)
+	for i := range out {
+		out[i] = series[i+1] - series[i]
+	}
+	return out
+}
@@ -11,6 +11,8 @@ import (
 
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
 )
 
 // Supported blockchain assets for wallets
@@ -109,7 +111,7 @@ type WalletWithdrawRequest struct {
 
 // CryptoWalletManager handles blockchain wallet operations
 type CryptoWalletManager struct {
-	alpacaClient *alpaca.Client
+	tradingClient broker.Broker
 	accountID    string
 	wallets      map[string]*CryptoWallet  // asset -> wallet
 	transfers    []*WalletTransfer
@@ -122,26 +124,16 @@ type CryptoWalletManager struct {
 	whitelist        map[string][]string // asset -> allowed addresses
 }
 
-// NewCryptoWalletManager creates a new wallet manager
-func NewCryptoWalletManager(apiKey, apiSecret, accountID string, isPaper bool) *CryptoWalletManager {
-	baseURL := "https://api.alpaca.markets"
-	if isPaper {
-		baseURL = "https://paper-api.alpaca.markets"
-	}
-	
-	alpacaClient := alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret, 
-		BaseURL:   baseURL,
-	})
-	
+// NewCryptoWalletManager creates a new wallet manager for the account
+// tradingClient trades on; testnet wallets go with paper accounts
+func NewCryptoWalletManager(tradingClient broker.Broker, accountID string, isTestnet bool) *CryptoWalletManager {
 	return &CryptoWalletManager{
-		alpacaClient:     alpacaClient,
+		tradingClient:    tradingClient,
 		accountID:        accountID,
 		wallets:          make(map[string]*CryptoWallet),
 		transfers:        make([]*WalletTransfer, 0),
 		logger:           log.New(log.Writer(), "[WALLET] ", log.LstdFlags|log.Lmicroseconds),
-		isTestnet:        isPaper, // Use testnet for paper trading
+		isTestnet:        isTestnet,
 		withdrawalLimits: make(map[string]decimal.Decimal),
 		dailyLimits:      make(map[string]decimal.Decimal),
 		whitelist:        make(map[string][]string),
@@ -532,7 +524,13 @@ func RunCryptoWalletsDemo() {
 		apiSecret = os.Getenv("APCA_API_SECRET_KEY")
 	}
 	
-	walletManager := NewCryptoWalletManager(apiKey, apiSecret, "test-account", true)
+	tradingClient := broker.NewAlpaca(alpaca.ClientOpts{
+		APIKey:    apiKey,
+		APISecret: apiSecret,
+		BaseURL:   broker.PaperBaseURL,
+	}, nil)
+	
+	walletManager := NewCryptoWalletManager(tradingClient, "test-account", true)
 	
 	fmt.Printf("\n🔐 Wallet Manager initialized (Testnet: %v)\n", walletManager.isTestnet)
 	fmt.Printf("📊 Supported assets: %v\n", getAssetList())
@@ -0,0 +1,190 @@
+// Package dataset assembles supervised training sets from collector output.
+// Bars from any number of files are deduplicated by symbol and timestamp,
+// featured with a versioned feature spec and labeled by the labeling
+// package, one symbol path at a time. Rows come back in time order so they
+// can be split chronologically, or into purged and embargoed K folds,
+// without a label window reaching across a split boundary.
+package dataset
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"zig-financial-engine/backtesting"
+	"zig-financial-engine/internal/features"
+	"zig-financial-engine/internal/labeling"
+)
+
+// Record is one bar of one symbol read from a collector file
+type Record struct {
+	Symbol string
+	Bar    features.Bar
+}
+
+// Row is a labeled feature vector
+type Row struct {
+	Symbol   string
+	Time     time.Time
+	Features []float64
+	Label    labeling.Label
+}
+
+// Dedup groups records by symbol, keeping the first record seen for each
+// timestamp, and returns each symbol's bars in time order along with the
+// number of duplicates dropped
+func Dedup(records []Record) (map[string][]features.Bar, int) {
+	series := make(map[string][]features.Bar)
+	seen := make(map[string]map[int64]bool)
+	dropped := 0
+	for _, record := range records {
+		if seen[record.Symbol] == nil {
+			seen[record.Symbol] = make(map[int64]bool)
+		}
+		key := record.Bar.Time.UnixNano()
+		if seen[record.Symbol][key] {
+			dropped++
+			continue
+		}
+		seen[record.Symbol][key] = true
+		series[record.Symbol] = append(series[record.Symbol], record.Bar)
+	}
+	for _, bars := range series {
+		sort.SliceStable(bars, func(i, j int) bool { return bars[i].Time.Before(bars[j].Time) })
+	}
+	return series, dropped
+}
+
+// LabelMethod selects how rows are labeled
+type LabelMethod string
+
+const (
+	LabelTripleBarrier LabelMethod = "triple_barrier" // Profit-take, stop or time-out on every bar
+	LabelFixedHorizon  LabelMethod = "fixed_horizon"  // Sign of the return Horizon bars ahead
+	LabelMeta          LabelMethod = "meta"           // Triple-barrier meta-labels on Strategy's signals
+)
+
+// valid reports whether the method is known
+func (m LabelMethod) valid() bool {
+	switch m {
+	case LabelTripleBarrier, LabelFixedHorizon, LabelMeta:
+		return true
+	}
+	return false
+}
+
+// LabelConfig describes how rows are labeled; it is recorded in the manifest
+type LabelConfig struct {
+	Method    LabelMethod            `json:"method"`
+	Barrier   labeling.TripleBarrier `json:"barrier"`             // triple_barrier and meta
+	Horizon   int                    `json:"horizon,omitempty"`   // fixed_horizon, in bars
+	Threshold float64                `json:"threshold,omitempty"` // fixed_horizon dead band
+	Strategy  string                 `json:"strategy,omitempty"`  // meta: backtest strategy name, e.g. "rsi"
+}
+
+// Validate checks the settings the method needs
+func (c LabelConfig) Validate() error {
+	if !c.Method.valid() {
+		return fmt.Errorf("unknown label method %q", c.Method)
+	}
+	switch c.Method {
+	case LabelFixedHorizon:
+		if c.Horizon < 1 {
+			return fmt.Errorf("fixed_horizon needs a horizon of at least one bar")
+		}
+	case LabelMeta:
+		if c.Strategy == "" {
+			return fmt.Errorf("meta labels need a primary strategy")
+		}
+		if _, err := backtesting.CreateBacktestStrategy(c.Strategy, ""); err != nil {
+			return err
+		}
+		return c.Barrier.Validate()
+	default:
+		return c.Barrier.Validate()
+	}
+	return nil
+}
+
+// label computes one symbol's labels
+func (c LabelConfig) label(symbol string, bars []features.Bar) ([]labeling.Label, error) {
+	switch c.Method {
+	case LabelFixedHorizon:
+		return labeling.FixedHorizon(bars, c.Horizon, c.Threshold)
+	case LabelMeta:
+		strategy, err := backtesting.CreateBacktestStrategy(c.Strategy, symbol)
+		if err != nil {
+			return nil, err
+		}
+		replay := make([]backtesting.Bar, len(bars))
+		for i, bar := range bars {
+			replay[i] = backtesting.Bar{Time: bar.Time, Open: bar.Open, High: bar.High, Low: bar.Low, Close: bar.Close, Volume: bar.Volume}
+		}
+		labels, err := c.Barrier.Label(bars, backtesting.SignalEvents(strategy, replay))
+		if err != nil {
+			return nil, err
+		}
+		return labeling.MetaLabels(labels)
+	}
+	return c.Barrier.Label(bars, nil)
+}
+
+// Build features and labels each symbol's bars. Only bars with a full
+// feature lookback and a resolved label become rows; sample weights are
+// computed per symbol. Rows are ordered by time, then symbol.
+func Build(spec features.Spec, config LabelConfig, series map[string][]features.Bar) ([]Row, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	warmup := spec.Warmup()
+	for _, symbol := range sortedSymbols(series) {
+		bars := series[symbol]
+		vectors, err := features.Batch(spec, bars)
+		if err != nil {
+			return nil, err
+		}
+		labels, err := config.label(symbol, bars)
+		if err != nil {
+			return nil, fmt.Errorf("failed to label %s: %w", symbol, err)
+		}
+		kept := labels[:0]
+		for _, label := range labels {
+			if label.Start >= warmup-1 {
+				kept = append(kept, label)
+			}
+		}
+		labeling.SampleWeights(kept)
+
+		for _, label := range kept {
+			rows = append(rows, Row{
+				Symbol:   symbol,
+				Time:     label.Time,
+				Features: vectors[label.Start],
+				Label:    label,
+			})
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if !rows[i].Time.Equal(rows[j].Time) {
+			return rows[i].Time.Before(rows[j].Time)
+		}
+		return rows[i].Symbol < rows[j].Symbol
+	})
+	return rows, nil
+}
+
+// sortedSymbols returns the series keys in order, for reproducible output
+func sortedSymbols(series map[string][]features.Bar) []string {
+	symbols := make([]string, 0, len(series))
+	for symbol := range series {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
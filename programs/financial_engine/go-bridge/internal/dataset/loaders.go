@@ -0,0 +1,91 @@
+package dataset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"zig-financial-engine/backtesting"
+	"zig-financial-engine/internal/features"
+)
+
+// quoteRecord is the part of a data-collector-v2 record the builder reads.
+// Those records carry synthetic OHLC values, so the mid price stands in for
+// the whole bar.
+type quoteRecord struct {
+	Symbol    string    `json:"symbol"`
+	Timestamp time.Time `json:"timestamp"`
+	MidPrice  float64   `json:"mid_price"`
+	Volume    int64     `json:"volume"`
+}
+
+// LoadCSV reads bars from a collector or broker CSV; see
+// backtesting.LoadBarsCSV for the accepted columns. Files without a symbol
+// column are named after their file, as in backtesting.LoadBarDir.
+func LoadCSV(path string) ([]Record, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if i := strings.IndexAny(name, "_-."); i > 0 {
+		name = name[:i]
+	}
+	bars, err := backtesting.LoadBarsCSV(path, strings.ToUpper(name))
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for symbol, series := range bars {
+		for _, bar := range series {
+			records = append(records, Record{Symbol: symbol, Bar: features.Bar{
+				Time:   bar.Time,
+				Open:   bar.Open,
+				High:   bar.High,
+				Low:    bar.Low,
+				Close:  bar.Close,
+				Volume: bar.Volume,
+			}})
+		}
+	}
+	return records, nil
+}
+
+// LoadJSON reads collector quotes from an ML dataset export
+// ({"metadata": ..., "data": [...]}) or a plain JSON array of records
+func LoadJSON(path string) ([]Record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var quotes []quoteRecord
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &quotes)
+	} else {
+		var export struct {
+			Data []quoteRecord `json:"data"`
+		}
+		err = json.Unmarshal(data, &export)
+		quotes = export.Data
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	records := make([]Record, 0, len(quotes))
+	for _, q := range quotes {
+		if q.Symbol == "" || q.MidPrice <= 0 || q.Timestamp.IsZero() {
+			continue
+		}
+		records = append(records, Record{Symbol: q.Symbol, Bar: features.Bar{
+			Time:   q.Timestamp,
+			Open:   q.MidPrice,
+			High:   q.MidPrice,
+			Low:    q.MidPrice,
+			Close:  q.MidPrice,
+			Volume: float64(q.Volume),
+		}})
+	}
+	return records, nil
+}
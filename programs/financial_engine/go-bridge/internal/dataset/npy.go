@@ -0,0 +1,78 @@
+package dataset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// npyElement is a type WriteNPY can store
+type npyElement interface {
+	int8 | int32 | int64 | float32 | float64
+}
+
+// WriteNPY writes data as a little-endian, C-ordered NumPy .npy file (format
+// 1.0) with the given shape, readable with numpy.load
+func WriteNPY[T npyElement](path string, shape []int, data []T) error {
+	size := 1
+	for _, dim := range shape {
+		size *= dim
+	}
+	if size != len(data) {
+		return fmt.Errorf("shape %v holds %d values, have %d", shape, size, len(data))
+	}
+
+	dims := make([]string, len(shape))
+	for i, dim := range shape {
+		dims[i] = strconv.Itoa(dim)
+	}
+	shapeText := strings.Join(dims, ", ")
+	if len(shape) == 1 {
+		shapeText += "," // One-element Python tuple
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", npyDescr(data), shapeText)
+	// Magic, version and header length take 10 bytes; the header is padded
+	// with spaces and ends in a newline so the data starts 64-byte aligned
+	padding := 64 - (10+len(header)+1)%64
+	if padding == 64 {
+		padding = 0
+	}
+	header += strings.Repeat(" ", padding) + "\n"
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	w.WriteString("\x93NUMPY\x01\x00")
+	binary.Write(w, binary.LittleEndian, uint16(len(header)))
+	w.WriteString(header)
+	if err := binary.Write(w, binary.LittleEndian, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// npyDescr is the NumPy dtype string for the element type
+func npyDescr[T npyElement](data []T) string {
+	var zero T
+	switch any(zero).(type) {
+	case int8:
+		return "|i1"
+	case int32:
+		return "<i4"
+	case int64:
+		return "<i8"
+	case float32:
+		return "<f4"
+	}
+	return "<f8"
+}
@@ -0,0 +1,148 @@
+package dataset
+
+import (
+	"fmt"
+	"time"
+)
+
+// Split holds row indexes of a chronological train/validation/test split
+type Split struct {
+	Train      []int `json:"train"`
+	Validation []int `json:"validation"`
+	Test       []int `json:"test"`
+	Purged     []int `json:"purged"` // Dropped because their label reached into a later split
+}
+
+// Fold is one purged K-fold cross-validation split
+type Fold struct {
+	Train []int `json:"train"`
+	Test  []int `json:"test"`
+}
+
+// DefaultEmbargo is the longest label horizon in rows, the shortest gap
+// that keeps any training label from overlapping the bars after a test fold
+func DefaultEmbargo(rows []Row) time.Duration {
+	var longest time.Duration
+	for _, row := range rows {
+		if horizon := row.Label.EndTime.Sub(row.Label.Time); horizon > longest {
+			longest = horizon
+		}
+	}
+	return longest
+}
+
+// ChronologicalSplit assigns the earliest rows to training, the next
+// validation share to validation and the last test share to test. Rows
+// sharing a timestamp always land in the same split. A train or validation
+// row is purged when its label, extended by the embargo, ends at or after
+// the start of the next split, so no label is decided by bars the next
+// split is evaluated on. Rows must be in time order.
+func ChronologicalSplit(rows []Row, validation, test float64, embargo time.Duration) (Split, error) {
+	if validation < 0 || test < 0 || validation+test >= 1 {
+		return Split{}, fmt.Errorf("validation and test shares must be non-negative and leave rows to train on")
+	}
+	if err := checkOrder(rows); err != nil {
+		return Split{}, err
+	}
+
+	n := len(rows)
+	valStart := timeBoundary(rows, int(float64(n)*(1-validation-test)))
+	testStart := timeBoundary(rows, int(float64(n)*(1-test)))
+
+	var split Split
+	for i, row := range rows {
+		switch {
+		case i >= testStart:
+			split.Test = append(split.Test, i)
+		case i >= valStart:
+			if testStart < n && !row.Label.EndTime.Add(embargo).Before(rows[testStart].Time) {
+				split.Purged = append(split.Purged, i)
+				continue
+			}
+			split.Validation = append(split.Validation, i)
+		default:
+			if valStart < n && !row.Label.EndTime.Add(embargo).Before(rows[valStart].Time) {
+				split.Purged = append(split.Purged, i)
+				continue
+			}
+			split.Train = append(split.Train, i)
+		}
+	}
+	return split, nil
+}
+
+// PurgedKFold cuts rows into k contiguous folds in time. Each fold is tested
+// in turn and trained on the others, minus rows whose label window
+// overlaps the test fold's span (purging) and rows starting within the
+// embargo after it (embargo), which would otherwise share serially
+// correlated information with the test labels. Rows must be in time order.
+func PurgedKFold(rows []Row, k int, embargo time.Duration) ([]Fold, error) {
+	if k < 2 {
+		return nil, fmt.Errorf("need at least 2 folds")
+	}
+	if len(rows) < k {
+		return nil, fmt.Errorf("%d rows cannot make %d folds", len(rows), k)
+	}
+	if err := checkOrder(rows); err != nil {
+		return nil, err
+	}
+
+	bounds := make([]int, k+1)
+	for f := 1; f < k; f++ {
+		bounds[f] = timeBoundary(rows, len(rows)*f/k)
+	}
+	bounds[k] = len(rows)
+
+	folds := make([]Fold, 0, k)
+	for f := 0; f < k; f++ {
+		lo, hi := bounds[f], bounds[f+1]
+		if lo >= hi {
+			return nil, fmt.Errorf("fold %d is empty; too many rows share timestamps for %d folds", f, k)
+		}
+
+		// The test fold spans from its first bar to its last label's outcome
+		from, to := rows[lo].Time, rows[lo].Label.EndTime
+		for _, row := range rows[lo:hi] {
+			if row.Label.EndTime.After(to) {
+				to = row.Label.EndTime
+			}
+		}
+
+		fold := Fold{}
+		for i, row := range rows {
+			if i >= lo && i < hi {
+				fold.Test = append(fold.Test, i)
+				continue
+			}
+			overlaps := !row.Label.EndTime.Before(from) && !row.Time.After(to)
+			embargoed := row.Time.After(to) && !row.Time.After(to.Add(embargo))
+			if !overlaps && !embargoed {
+				fold.Train = append(fold.Train, i)
+			}
+		}
+		folds = append(folds, fold)
+	}
+	return folds, nil
+}
+
+// timeBoundary moves index forward past any rows sharing the timestamp
+// before it, so a split never divides one bar's rows
+func timeBoundary(rows []Row, index int) int {
+	if index <= 0 {
+		return 0
+	}
+	for index < len(rows) && rows[index].Time.Equal(rows[index-1].Time) {
+		index++
+	}
+	return index
+}
+
+// checkOrder rejects rows that are not in time order
+func checkOrder(rows []Row) error {
+	for i := 1; i < len(rows); i++ {
+		if rows[i].Time.Before(rows[i-1].Time) {
+			return fmt.Errorf("row %d is earlier than row %d; rows must be in time order", i, i-1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package features
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultTolerance is the largest absolute difference treated as equal. The
+// live path may round-trip vectors through float32 model inputs.
+const DefaultTolerance = 1e-6
+
+// Mismatch is the first place two feature matrices disagree
+type Mismatch struct {
+	Row       int     `json:"row"`
+	Feature   string  `json:"feature"`
+	Batch     float64 `json:"batch"`
+	Streaming float64 `json:"streaming"`
+}
+
+// ParityReport compares vectors served live with a batch recomputation
+type ParityReport struct {
+	Version    string    `json:"version"`
+	Rows       int       `json:"rows"`
+	Mismatches int       `json:"mismatches"` // Cells differing by more than the tolerance
+	MaxAbsDiff float64   `json:"max_abs_diff"`
+	First      *Mismatch `json:"first,omitempty"`
+}
+
+// OK reports whether every compared value matched
+func (r ParityReport) OK() bool {
+	return r.Mismatches == 0
+}
+
+// String summarizes the report for logs
+func (r ParityReport) String() string {
+	if r.OK() {
+		return fmt.Sprintf("features %s: %d rows match (max diff %.2g)", r.Version, r.Rows, r.MaxAbsDiff)
+	}
+	return fmt.Sprintf("features %s: %d mismatches in %d rows, first at row %d %s (batch %.6g, streaming %.6g)",
+		r.Version, r.Mismatches, r.Rows, r.First.Row, r.First.Feature, r.First.Batch, r.First.Streaming)
+}
+
+// CheckParity recomputes features for bars in batch mode and compares them
+// with streamed, the vectors a live pipeline produced for the same bars
+// from the same starting point. A nil streamed runs a fresh streaming
+// pipeline instead, which checks the pipeline itself is deterministic.
+func CheckParity(spec Spec, bars []Bar, streamed [][]float64, tolerance float64) (ParityReport, error) {
+	batch, err := Batch(spec, bars)
+	if err != nil {
+		return ParityReport{}, err
+	}
+	if streamed == nil {
+		p, err := NewPipeline(spec)
+		if err != nil {
+			return ParityReport{}, err
+		}
+		streamed = make([][]float64, len(bars))
+		for i, bar := range bars {
+			streamed[i] = p.Update(bar)
+		}
+	}
+	if len(streamed) != len(batch) {
+		return ParityReport{}, fmt.Errorf("%d streamed rows for %d bars", len(streamed), len(batch))
+	}
+
+	report := ParityReport{Version: spec.Version(), Rows: len(batch)}
+	for row := range batch {
+		if len(streamed[row]) != len(batch[row]) {
+			return ParityReport{}, fmt.Errorf("row %d has %d features, spec has %d", row, len(streamed[row]), len(batch[row]))
+		}
+		for col, want := range batch[row] {
+			diff := math.Abs(streamed[row][col] - want)
+			if math.IsNaN(diff) {
+				diff = math.Inf(1)
+			}
+			if diff > report.MaxAbsDiff {
+				report.MaxAbsDiff = diff
+			}
+			if diff <= tolerance {
+				continue
+			}
+			report.Mismatches++
+			if report.First == nil {
+				report.First = &Mismatch{Row: row, Feature: spec.Features[col].Name, Batch: want, Streaming: streamed[row][col]}
+			}
+		}
+	}
+	return report, nil
+}
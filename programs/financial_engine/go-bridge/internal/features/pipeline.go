@@ -0,0 +1,244 @@
+package features
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"zig-financial-engine/internal/indicators"
+)
+
+// Bar is one input row: a price bar plus any exogenous inputs
+type Bar struct {
+	Time      time.Time
+	Open      float64
+	High      float64
+	Low       float64
+	Close     float64
+	Volume    float64
+	Sentiment float64
+}
+
+// Pipeline computes a spec's feature vector one bar at a time. Until a
+// feature has its full lookback it reads a neutral value (50 for RSI and
+// %K, 0 otherwise) before normalization, so vectors are always complete.
+type Pipeline struct {
+	spec    Spec
+	version string
+	calcs   []calculator
+	bars    int
+}
+
+// calculator is one streaming feature; it returns the raw value and
+// whether it is warmed up
+type calculator interface {
+	update(bar Bar) (float64, bool)
+}
+
+// NewPipeline builds a streaming pipeline for spec
+func NewPipeline(spec Spec) (*Pipeline, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	p := &Pipeline{spec: spec, version: spec.Version()}
+	p.Reset()
+	return p, nil
+}
+
+// Spec returns the pipeline's definition
+func (p *Pipeline) Spec() Spec { return p.spec }
+
+// Version returns the spec version the pipeline computes
+func (p *Pipeline) Version() string { return p.version }
+
+// Ready reports whether every feature has its full lookback
+func (p *Pipeline) Ready() bool { return p.bars >= p.spec.Warmup() }
+
+// Update adds a bar and returns its normalized feature vector
+func (p *Pipeline) Update(bar Bar) []float64 {
+	p.bars++
+	vector := make([]float64, len(p.calcs))
+	for i, calc := range p.calcs {
+		f := p.spec.Features[i]
+		raw, ok := calc.update(bar)
+		if !ok || math.IsNaN(raw) || math.IsInf(raw, 0) {
+			raw = neutralValue(f.Kind)
+		}
+		vector[i] = normalize(raw, f.Normalize)
+	}
+	return vector
+}
+
+// Reset clears all indicator state
+func (p *Pipeline) Reset() {
+	p.bars = 0
+	p.calcs = make([]calculator, len(p.spec.Features))
+	for i, f := range p.spec.Features {
+		p.calcs[i] = newCalculator(f)
+	}
+}
+
+// Batch runs a fresh pipeline over stored bars, oldest first, returning one
+// vector per bar. It is the same code path live strategies stream through,
+// so a model trained on Batch output sees identical inputs when served.
+func Batch(spec Spec, bars []Bar) ([][]float64, error) {
+	p, err := NewPipeline(spec)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]float64, len(bars))
+	for i, bar := range bars {
+		rows[i] = p.Update(bar)
+	}
+	return rows, nil
+}
+
+// neutralValue is a feature's raw reading before it has warmed up
+func neutralValue(kind Kind) float64 {
+	switch kind {
+	case KindRSI, KindStochasticK:
+		return 50
+	}
+	return 0
+}
+
+// normalize applies a feature's normalization to a raw value
+func normalize(raw float64, n Normalization) float64 {
+	scale := n.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	v := (raw + n.Offset) * scale
+	if n.Tanh {
+		v = math.Tanh(v)
+	}
+	if n.Clip > 0 {
+		v = math.Max(-n.Clip, math.Min(n.Clip, v))
+	}
+	return v
+}
+
+// newCalculator creates the streaming calculation for a validated feature
+func newCalculator(f Feature) calculator {
+	switch f.Kind {
+	case KindReturn:
+		return &returnCalc{window: f.Window}
+	case KindVolatility:
+		return &volatilityCalc{bands: indicators.NewBollinger(f.Window, 1)}
+	case KindRSI:
+		return rsiCalc{indicators.NewRSI(f.Window)}
+	case KindMACD:
+		return macdCalc{macd: indicators.NewMACD(f.Fast, f.Slow, 9)}
+	case KindMACDSignal:
+		return macdCalc{macd: indicators.NewMACD(f.Fast, f.Slow, f.Window), signal: true}
+	case KindPPO:
+		return ppoCalc{fast: indicators.NewEMA(f.Fast), slow: indicators.NewEMA(f.Slow)}
+	case KindBBWidth:
+		return bbWidthCalc{indicators.NewBollinger(f.Window, 1)}
+	case KindVolumeZ:
+		return volumeZCalc{indicators.NewZScore(f.Window)}
+	case KindStochasticK:
+		return stochasticCalc{indicators.NewStochastic(f.Window, 1, 1)}
+	case KindLogVolume:
+		return funcCalc(func(bar Bar) (float64, bool) { return math.Log1p(math.Max(bar.Volume, 0)), true })
+	case KindRange:
+		return funcCalc(func(bar Bar) (float64, bool) {
+			if bar.Close <= 0 {
+				return 0, false
+			}
+			return (bar.High - bar.Low) / bar.Close, true
+		})
+	case KindSentiment:
+		return funcCalc(func(bar Bar) (float64, bool) { return bar.Sentiment, true })
+	}
+	panic(fmt.Sprintf("features: no calculator for kind %q", f.Kind))
+}
+
+// returnCalc is the return over window bars
+type returnCalc struct {
+	window int
+	closes []float64
+}
+
+func (c *returnCalc) update(bar Bar) (float64, bool) {
+	c.closes = append(c.closes, bar.Close)
+	if len(c.closes) > c.window+1 {
+		c.closes = c.closes[1:]
+	}
+	if len(c.closes) <= c.window || c.closes[0] <= 0 {
+		return 0, false
+	}
+	return (bar.Close - c.closes[0]) / c.closes[0], true
+}
+
+// volatilityCalc is the rolling standard deviation of one-bar returns
+type volatilityCalc struct {
+	bands     *indicators.Bollinger
+	prevClose float64
+}
+
+func (c *volatilityCalc) update(bar Bar) (float64, bool) {
+	prev := c.prevClose
+	c.prevClose = bar.Close
+	if prev <= 0 {
+		return 0, false
+	}
+	bands := c.bands.Update((bar.Close - prev) / prev)
+	return bands.StdDev, c.bands.Ready()
+}
+
+type rsiCalc struct{ rsi *indicators.RSI }
+
+func (c rsiCalc) update(bar Bar) (float64, bool) {
+	v := c.rsi.Update(bar.Close)
+	return v, c.rsi.Ready()
+}
+
+type macdCalc struct {
+	macd   *indicators.MACD
+	signal bool // Report the signal line instead of the MACD line
+}
+
+func (c macdCalc) update(bar Bar) (float64, bool) {
+	v := c.macd.Update(bar.Close)
+	if c.signal {
+		return v.Signal, c.macd.Ready()
+	}
+	return v.MACD, c.macd.LineReady()
+}
+
+type ppoCalc struct{ fast, slow *indicators.EMA }
+
+func (c ppoCalc) update(bar Bar) (float64, bool) {
+	fast, slow := c.fast.Update(bar.Close), c.slow.Update(bar.Close)
+	if !c.slow.Ready() || slow == 0 {
+		return 0, false
+	}
+	return (fast - slow) / slow, true
+}
+
+type bbWidthCalc struct{ bands *indicators.Bollinger }
+
+func (c bbWidthCalc) update(bar Bar) (float64, bool) {
+	v := c.bands.Update(bar.Close)
+	return v.Width, c.bands.Ready() && v.Middle != 0
+}
+
+type volumeZCalc struct{ z *indicators.ZScore }
+
+func (c volumeZCalc) update(bar Bar) (float64, bool) {
+	v := c.z.Update(bar.Volume)
+	return v, c.z.Ready()
+}
+
+type stochasticCalc struct{ stoch *indicators.Stochastic }
+
+func (c stochasticCalc) update(bar Bar) (float64, bool) {
+	v := c.stoch.Update(bar.High, bar.Low, bar.Close)
+	return v.K, c.stoch.Ready()
+}
+
+// funcCalc is a stateless feature of the current bar
+type funcCalc func(bar Bar) (float64, bool)
+
+func (f funcCalc) update(bar Bar) (float64, bool) { return f(bar) }
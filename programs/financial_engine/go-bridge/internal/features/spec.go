@@ -0,0 +1,182 @@
+// Package features defines model input features once, declaratively, and
+// computes them identically over stored history (Batch) and bar by bar in
+// live strategies (Pipeline).
+//
+// A Spec lists features in output order, each with its lookback windows and
+// normalization. The spec's Version hash changes whenever anything that
+// affects the output changes, so a model can record the exact features it
+// was trained on and a server can refuse to feed it anything else.
+package features
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Kind is the calculation behind a feature
+type Kind string
+
+const (
+	KindReturn      Kind = "return"       // Close-to-close return over Window bars
+	KindVolatility  Kind = "volatility"   // Population std dev of one-bar returns over Window
+	KindRSI         Kind = "rsi"          // Wilder RSI(Window), 0-100
+	KindMACD        Kind = "macd"         // MACD line, EMA(Fast) - EMA(Slow)
+	KindMACDSignal  Kind = "macd_signal"  // Signal line: EMA(Window) of the MACD line
+	KindPPO         Kind = "ppo"          // (EMA(Fast) - EMA(Slow)) / EMA(Slow)
+	KindBBWidth     Kind = "bb_width"     // Two standard deviations over the mean, over Window
+	KindVolumeZ     Kind = "volume_z"     // Z-score of volume over Window
+	KindStochasticK Kind = "stochastic_k" // Fast %K: close within the Window high-low range, 0-100
+	KindLogVolume   Kind = "log_volume"   // ln(1 + volume)
+	KindRange       Kind = "range"        // (high - low) / close
+	KindSentiment   Kind = "sentiment"    // Exogenous sentiment score in [-1, 1]
+)
+
+// Normalization maps a raw feature value to model scale:
+// clip(f((raw + Offset) * Scale)), where f is tanh if Tanh is set
+type Normalization struct {
+	Offset float64 `json:"offset,omitempty"`
+	Scale  float64 `json:"scale,omitempty"` // Zero means 1
+	Tanh   bool    `json:"tanh,omitempty"`
+	Clip   float64 `json:"clip,omitempty"` // Clamp to ±Clip when positive
+}
+
+// Feature is one column of a spec
+type Feature struct {
+	Name      string        `json:"name"`
+	Kind      Kind          `json:"kind"`
+	Window    int           `json:"window,omitempty"` // Lookback in bars; the signal period for macd_signal
+	Fast      int           `json:"fast,omitempty"`   // Fast EMA period for macd, macd_signal and ppo
+	Slow      int           `json:"slow,omitempty"`   // Slow EMA period for macd, macd_signal and ppo
+	Normalize Normalization `json:"normalize,omitempty"`
+}
+
+// Spec is an ordered, versioned feature list
+type Spec struct {
+	Name     string    `json:"name"`
+	Features []Feature `json:"features"`
+}
+
+// DefaultSpec is the feature set the ML strategy was built around: six
+// per-bar features normalized to roughly unit scale
+func DefaultSpec() Spec {
+	return Spec{
+		Name: "ml_v1",
+		Features: []Feature{
+			{Name: "rsi", Kind: KindRSI, Window: 14, Normalize: Normalization{Scale: 0.01}},
+			{Name: "macd", Kind: KindMACD, Fast: 12, Slow: 26, Normalize: Normalization{Scale: 0.1, Tanh: true}},
+			{Name: "bb_width", Kind: KindBBWidth, Window: 20},
+			{Name: "volume_z", Kind: KindVolumeZ, Window: 20},
+			{Name: "sentiment", Kind: KindSentiment, Normalize: Normalization{Offset: 1, Scale: 0.5}},
+			{Name: "price_change", Kind: KindReturn, Window: 1},
+		},
+	}
+}
+
+// LoadSpec reads a spec from a JSON file
+func LoadSpec(path string) (Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Spec{}, err
+	}
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return Spec{}, fmt.Errorf("failed to parse feature spec %s: %w", path, err)
+	}
+	if err := spec.Validate(); err != nil {
+		return Spec{}, fmt.Errorf("feature spec %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// Validate checks every feature has a unique name, a known kind and the
+// windows its kind needs
+func (s Spec) Validate() error {
+	if s.Name == "" || len(s.Features) == 0 {
+		return fmt.Errorf("feature spec needs a name and at least one feature")
+	}
+	seen := make(map[string]bool, len(s.Features))
+	for _, f := range s.Features {
+		if f.Name == "" {
+			return fmt.Errorf("feature of kind %s has no name", f.Kind)
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("duplicate feature %s", f.Name)
+		}
+		seen[f.Name] = true
+		if f.Normalize.Clip < 0 {
+			return fmt.Errorf("feature %s: clip must not be negative", f.Name)
+		}
+
+		switch f.Kind {
+		case KindReturn, KindVolatility, KindRSI, KindBBWidth, KindVolumeZ, KindStochasticK:
+			if f.Window < 1 {
+				return fmt.Errorf("feature %s: %s needs a window", f.Name, f.Kind)
+			}
+		case KindMACD, KindMACDSignal, KindPPO:
+			if f.Fast < 1 || f.Slow <= f.Fast {
+				return fmt.Errorf("feature %s: %s needs 0 < fast < slow", f.Name, f.Kind)
+			}
+			if f.Kind == KindMACDSignal && f.Window < 1 {
+				return fmt.Errorf("feature %s: macd_signal needs a signal window", f.Name)
+			}
+		case KindLogVolume, KindRange, KindSentiment:
+		default:
+			return fmt.Errorf("feature %s: unknown kind %q", f.Name, f.Kind)
+		}
+	}
+	return nil
+}
+
+// Names returns the feature names in output order
+func (s Spec) Names() []string {
+	names := make([]string, len(s.Features))
+	for i, f := range s.Features {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// Index returns the column of a named feature, or -1
+func (s Spec) Index(name string) int {
+	for i, f := range s.Features {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Version identifies the spec's exact output: its name plus a hash of every
+// feature definition, e.g. "ml_v1-3f2a9c0d1e4b"
+func (s Spec) Version() string {
+	data, _ := json.Marshal(s) // Field order is fixed, so the encoding is canonical
+	hash := sha256.Sum256(data)
+	return s.Name + "-" + hex.EncodeToString(hash[:6])
+}
+
+// Warmup is the number of bars before every feature has its full lookback
+func (s Spec) Warmup() int {
+	warmup := 0
+	for _, f := range s.Features {
+		var bars int
+		switch f.Kind {
+		case KindReturn, KindVolatility, KindRSI:
+			bars = f.Window + 1 // Differences need one bar before the window
+		case KindBBWidth, KindVolumeZ, KindStochasticK:
+			bars = f.Window
+		case KindMACD, KindPPO:
+			bars = f.Slow
+		case KindMACDSignal:
+			bars = f.Slow + f.Window - 1
+		default:
+			bars = 1
+		}
+		if bars > warmup {
+			warmup = bars
+		}
+	}
+	return warmup
+}
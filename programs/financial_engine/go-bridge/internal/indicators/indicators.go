@@ -0,0 +1,239 @@
+// Package indicators implements technical indicators as streaming objects
+// that update in O(1) per value, with batch functions over whole series.
+//
+// Every batch function runs the streaming indicator over its input, so the
+// two always agree. Warm-up and smoothing rules are the same everywhere:
+//   - Outputs are NaN until the indicator is ready; batch series carry NaN in
+//     the warm-up positions.
+//   - EMA and Wilder averages are seeded with the simple mean of their first
+//     period inputs.
+//   - Standard deviations are population (divide by n) over the window.
+package indicators
+
+import "math"
+
+// Indicator is a single-input streaming indicator
+type Indicator interface {
+	Update(value float64) float64
+	Value() float64
+	Ready() bool
+	Reset()
+}
+
+// Series runs a fresh single-input indicator over values
+func Series(ind Indicator, values []float64) []float64 {
+	ind.Reset()
+	out := make([]float64, len(values))
+	for i, v := range values {
+		out[i] = ind.Update(v)
+	}
+	return out
+}
+
+// TypicalPrice is (high + low + close) / 3, the usual VWAP input
+func TypicalPrice(high, low, close float64) float64 {
+	return (high + low + close) / 3
+}
+
+// Last returns the last value of a series, or NaN if it is empty
+func Last(series []float64) float64 {
+	if len(series) == 0 {
+		return math.NaN()
+	}
+	return series[len(series)-1]
+}
+
+// validPeriod clamps periods below one to one
+func validPeriod(period int) int {
+	if period < 1 {
+		return 1
+	}
+	return period
+}
+
+// minLen is the length of the shortest series
+func minLen(series ...[]float64) int {
+	n := -1
+	for _, s := range series {
+		if n < 0 || len(s) < n {
+			n = len(s)
+		}
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// window is a ring buffer holding the most recent values
+type window struct {
+	values []float64
+	next   int
+	count  int
+}
+
+func newWindow(size int) *window {
+	return &window{values: make([]float64, validPeriod(size))}
+}
+
+// push adds v and returns the value it displaced, if the window was full
+func (w *window) push(v float64) (evicted float64, full bool) {
+	if w.count == len(w.values) {
+		evicted, full = w.values[w.next], true
+	} else {
+		w.count++
+	}
+	w.values[w.next] = v
+	w.next = (w.next + 1) % len(w.values)
+	return evicted, full
+}
+
+// wrapped reports whether the last push completed a full cycle of the ring
+func (w *window) wrapped() bool {
+	return w.next == 0
+}
+
+func (w *window) full() bool {
+	return w.count == len(w.values)
+}
+
+func (w *window) reset() {
+	w.next, w.count = 0, 0
+}
+
+// rollingStats tracks the mean and variance of a window. Incremental updates
+// are refreshed from the window once per cycle so rounding cannot accumulate.
+type rollingStats struct {
+	win  *window
+	sum  float64
+	mean float64
+	m2   float64 // Sum of squared deviations from the mean
+}
+
+func newRollingStats(period int) *rollingStats {
+	return &rollingStats{win: newWindow(period)}
+}
+
+// push adds a value to the window
+func (r *rollingStats) push(v float64) {
+	evicted, full := r.win.push(v)
+	n := float64(r.win.count)
+	if !full {
+		r.sum += v
+		delta := v - r.mean
+		r.mean += delta / n
+		r.m2 += delta * (v - r.mean)
+	} else {
+		r.sum += v - evicted
+		oldMean := r.mean
+		r.mean += (v - evicted) / n
+		r.m2 += (v - evicted) * (v - r.mean + evicted - oldMean)
+	}
+	if full && r.win.wrapped() {
+		r.recompute()
+	}
+}
+
+// recompute recalculates the sums exactly from the window
+func (r *rollingStats) recompute() {
+	r.sum = 0
+	for _, v := range r.win.values[:r.win.count] {
+		r.sum += v
+	}
+	r.mean = r.sum / float64(r.win.count)
+	r.m2 = 0
+	for _, v := range r.win.values[:r.win.count] {
+		d := v - r.mean
+		r.m2 += d * d
+	}
+}
+
+// stdDev is the population standard deviation of the window
+func (r *rollingStats) stdDev() float64 {
+	if r.win.count == 0 || r.m2 <= 0 {
+		return 0
+	}
+	return math.Sqrt(r.m2 / float64(r.win.count))
+}
+
+func (r *rollingStats) full() bool {
+	return r.win.full()
+}
+
+func (r *rollingStats) reset() {
+	r.win.reset()
+	r.sum, r.mean, r.m2 = 0, 0, 0
+}
+
+// seededAverage is an exponential average seeded with the simple mean of its
+// first period inputs. EMA uses alpha 2/(n+1); Wilder smoothing uses 1/n.
+type seededAverage struct {
+	period int
+	alpha  float64
+	count  int
+	value  float64
+}
+
+func newEMAAverage(period int) seededAverage {
+	period = validPeriod(period)
+	return seededAverage{period: period, alpha: 2 / (float64(period) + 1)}
+}
+
+func newWilderAverage(period int) seededAverage {
+	period = validPeriod(period)
+	return seededAverage{period: period, alpha: 1 / float64(period)}
+}
+
+// update folds v in and returns the average once seeded
+func (a *seededAverage) update(v float64) (float64, bool) {
+	if a.count < a.period {
+		a.count++
+		a.value += (v - a.value) / float64(a.count) // Running mean while seeding
+		return a.value, a.count == a.period
+	}
+	a.value += a.alpha * (v - a.value)
+	return a.value, true
+}
+
+func (a *seededAverage) ready() bool {
+	return a.count >= a.period
+}
+
+func (a *seededAverage) reset() {
+	a.count, a.value = 0, 0
+}
+
+// rollingExtreme tracks the max (or min) of a window with a monotonic deque
+type rollingExtreme struct {
+	period int
+	max    bool
+	index  []int
+	values []float64
+	seen   int
+}
+
+func newRollingExtreme(period int, max bool) *rollingExtreme {
+	return &rollingExtreme{period: validPeriod(period), max: max}
+}
+
+// push adds v and returns the extreme of the window
+func (r *rollingExtreme) push(v float64) float64 {
+	for n := len(r.values); n > 0; n = len(r.values) {
+		last := r.values[n-1]
+		if (r.max && last > v) || (!r.max && last < v) {
+			break
+		}
+		r.index, r.values = r.index[:n-1], r.values[:n-1]
+	}
+	r.index = append(r.index, r.seen)
+	r.values = append(r.values, v)
+	for r.index[0] <= r.seen-r.period {
+		r.index, r.values = r.index[1:], r.values[1:]
+	}
+	r.seen++
+	return r.values[0]
+}
+
+func (r *rollingExtreme) reset() {
+	r.index, r.values, r.seen = r.index[:0], r.values[:0], 0
+}
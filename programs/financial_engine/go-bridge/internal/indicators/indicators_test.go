@@ -0,0 +1,172 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+// The reference bars are a fixed synthetic series. Expected values come from a
+// straightforward textbook implementation of each indicator (windowed sums
+// recomputed from scratch, no incremental updates), so they independently
+// cross-check the O(1) code.
+
+const tolerance = 1e-8
+
+var refHigh = []float64{100.40, 103.34, 105.49, 107.56, 108.65, 109.28, 109.25, 109.15, 108.66, 107.70, 107.03, 106.10, 105.98, 105.93, 106.59, 106.76, 106.99, 106.91, 106.58, 105.66, 104.06, 102.14, 99.60, 97.37, 94.82, 93.31, 91.90, 92.00, 92.80, 94.29, 95.81, 97.32, 98.67, 99.47, 100.15, 100.14, 100.43, 100.23, 100.81, 101.33, 102.68, 104.24, 106.32, 108.49, 110.54, 112.39, 113.40, 114.04, 113.77, 113.72, 112.41, 111.04, 108.92, 107.01, 105.09, 103.61, 102.62, 101.95, 101.92, 101.73}
+var refLow = []float64{99.30, 99.52, 101.98, 104.39, 106.34, 107.41, 108.14, 107.32, 106.61, 105.75, 104.99, 104.83, 104.55, 104.74, 104.86, 105.26, 105.74, 105.26, 104.58, 102.83, 100.88, 98.54, 95.99, 93.99, 91.92, 90.90, 90.39, 90.35, 90.84, 91.56, 93.16, 94.55, 96.11, 97.48, 98.25, 99.05, 99.00, 99.16, 99.22, 99.45, 100.38, 101.31, 103.16, 105.05, 107.23, 109.46, 111.02, 112.45, 112.35, 111.37, 109.78, 107.71, 105.94, 103.81, 102.47, 101.36, 100.75, 100.74, 100.52, 100.76}
+var refClose = []float64{100.00, 102.64, 105.01, 106.88, 108.10, 108.64, 108.56, 108.00, 107.18, 106.34, 105.66, 105.28, 105.25, 105.50, 105.90, 106.25, 106.33, 105.96, 105.03, 103.50, 101.47, 99.11, 96.67, 94.41, 92.61, 91.44, 91.01, 91.32, 92.26, 93.64, 95.21, 96.73, 98.02, 98.94, 99.47, 99.68, 99.73, 99.81, 100.12, 100.83, 102.01, 103.67, 105.69, 107.87, 109.97, 111.72, 112.90, 113.35, 113.02, 111.96, 110.35, 108.39, 106.36, 104.50, 103.01, 101.98, 101.41, 101.22, 101.24, 101.26}
+var refVolume = []float64{1000, 1511, 1726, 1573, 1701, 2088, 2106, 1008, 1516, 1724, 1566, 1708, 2090, 2100, 1016, 1521, 1722, 1558, 1716, 2092, 2095, 1025, 1526, 1720, 1550, 1723, 2093, 2089, 1033, 1531, 1718, 1542, 1730, 2094, 2084, 1041, 1536, 1715, 1534, 1738, 2095, 2078, 1050, 1540, 1713, 1527, 1745, 2096, 2072, 1058, 1545, 1710, 1519, 1752, 2097, 2066, 1067, 1549, 1707, 1511}
+
+// goldens maps each series to expected values at bar indexes. Bars before the
+// first index are warm-up and must be NaN.
+var goldens = []struct {
+	name   string
+	values map[int]float64
+}{
+	{"SMA(10)", map[int]float64{9: 106.1350000000, 30: 93.7680000000, 59: 103.9720000000}},
+	{"EMA(10)", map[int]float64{9: 106.1350000000, 30: 94.8986026548, 59: 103.4697809694}},
+	{"RSI(14)", map[int]float64{14: 73.2649842271, 30: 42.3281000834, 59: 39.8945598894}},
+	{"MACD(12,26,9) line", map[int]float64{25: -4.6969115837, 40: -0.6935163166, 59: -0.4126177907}},
+	{"MACD(12,26,9) signal", map[int]float64{33: -4.4694322970, 45: 0.0229387432, 59: 0.7053977869}},
+	{"Bollinger(20,2) upper", map[int]float64{19: 109.8132221434, 40: 103.6814364018, 59: 115.4704348699}},
+	{"Bollinger(20,2) lower", map[int]float64{19: 101.7877778566, 40: 89.6205635982, 59: 97.7175651301}},
+	{"ATR(14)", map[int]float64{13: 2.0464285714, 30: 2.3220874980, 59: 2.1796413410}},
+	{"ADX(14)", map[int]float64{27: 36.8936601815, 40: 24.3835381455, 59: 28.7306745556}},
+	{"ADX(14) +DI", map[int]float64{14: 32.5819672131, 40: 28.2710643750, 59: 18.4795332488}},
+	{"ADX(14) -DI", map[int]float64{14: 12.2609289617, 40: 15.5925281703, 59: 27.6505311145}},
+	{"Stochastic(14,3,3) %K", map[int]float64{15: 61.9954172136, 40: 94.4719355221, 59: 4.8026130415}},
+	{"Stochastic(14,3,3) %D", map[int]float64{17: 53.0551926556, 40: 94.0469915693, 59: 4.6414664266}},
+	{"OBV", map[int]float64{0: 0.0000000000, 30: -7487.0000000000, 59: 8154.0000000000}},
+	{"ZScore(20)", map[int]float64{19: -1.1466031875, 40: 1.5245141820, 59: -1.2018338619}},
+	{"VWAP(session)", map[int]float64{0: 99.9000000000, 30: 101.8839723661, 59: 103.0335991143}},
+	{"VWAP(10)", map[int]float64{9: 106.2434300758, 30: 93.6526324338, 59: 104.3451828764}},
+}
+
+// batchSeries computes every golden series with the batch functions
+func batchSeries() map[string][]float64 {
+	macd := MACDSeries(refClose, 12, 26, 9)
+	bands := BollingerSeries(refClose, 20, 2)
+	adx := ADXSeries(refHigh, refLow, refClose, 14)
+	stoch := StochasticSeries(refHigh, refLow, refClose, 14, 3, 3)
+
+	typical := make([]float64, len(refClose))
+	for i := range refClose {
+		typical[i] = TypicalPrice(refHigh[i], refLow[i], refClose[i])
+	}
+
+	series := map[string][]float64{
+		"SMA(10)":       SMASeries(refClose, 10),
+		"EMA(10)":       EMASeries(refClose, 10),
+		"RSI(14)":       RSISeries(refClose, 14),
+		"ATR(14)":       ATRSeries(refHigh, refLow, refClose, 14),
+		"OBV":           OBVSeries(refClose, refVolume),
+		"ZScore(20)":    ZScoreSeries(refClose, 20),
+		"VWAP(session)": VWAPSeries(typical, refVolume, 0),
+		"VWAP(10)":      VWAPSeries(typical, refVolume, 10),
+	}
+	pluck := func(name string, n int, field func(i int) float64) {
+		values := make([]float64, n)
+		for i := range values {
+			values[i] = field(i)
+		}
+		series[name] = values
+	}
+	pluck("MACD(12,26,9) line", len(macd), func(i int) float64 { return macd[i].MACD })
+	pluck("MACD(12,26,9) signal", len(macd), func(i int) float64 { return macd[i].Signal })
+	pluck("Bollinger(20,2) upper", len(bands), func(i int) float64 { return bands[i].Upper })
+	pluck("Bollinger(20,2) lower", len(bands), func(i int) float64 { return bands[i].Lower })
+	pluck("ADX(14)", len(adx), func(i int) float64 { return adx[i].ADX })
+	pluck("ADX(14) +DI", len(adx), func(i int) float64 { return adx[i].PlusDI })
+	pluck("ADX(14) -DI", len(adx), func(i int) float64 { return adx[i].MinusDI })
+	pluck("Stochastic(14,3,3) %K", len(stoch), func(i int) float64 { return stoch[i].K })
+	pluck("Stochastic(14,3,3) %D", len(stoch), func(i int) float64 { return stoch[i].D })
+	return series
+}
+
+// streamSeries computes the same series one bar at a time
+func streamSeries() map[string][]float64 {
+	sma, ema, rsi := NewSMA(10), NewEMA(10), NewRSI(14)
+	macd := NewMACD(12, 26, 9)
+	bands := NewBollinger(20, 2)
+	atr, adx := NewATR(14), NewADX(14)
+	stoch := NewStochastic(14, 3, 3)
+	obv, zscore := NewOBV(), NewZScore(20)
+	session, rolling := NewVWAP(0), NewVWAP(10)
+
+	series := make(map[string][]float64)
+	add := func(name string, v float64) { series[name] = append(series[name], v) }
+	for i := range refClose {
+		h, l, c, v := refHigh[i], refLow[i], refClose[i], refVolume[i]
+		add("SMA(10)", sma.Update(c))
+		add("EMA(10)", ema.Update(c))
+		add("RSI(14)", rsi.Update(c))
+		m := macd.Update(c)
+		add("MACD(12,26,9) line", m.MACD)
+		add("MACD(12,26,9) signal", m.Signal)
+		b := bands.Update(c)
+		add("Bollinger(20,2) upper", b.Upper)
+		add("Bollinger(20,2) lower", b.Lower)
+		add("ATR(14)", atr.Update(h, l, c))
+		a := adx.Update(h, l, c)
+		add("ADX(14)", a.ADX)
+		add("ADX(14) +DI", a.PlusDI)
+		add("ADX(14) -DI", a.MinusDI)
+		s := stoch.Update(h, l, c)
+		add("Stochastic(14,3,3) %K", s.K)
+		add("Stochastic(14,3,3) %D", s.D)
+		add("OBV", obv.Update(c, v))
+		add("ZScore(20)", zscore.Update(c))
+		tp := TypicalPrice(h, l, c)
+		add("VWAP(session)", session.Update(tp, v))
+		add("VWAP(10)", rolling.Update(tp, v))
+	}
+	return series
+}
+
+// same treats NaN as equal to NaN
+func same(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
+
+func TestGoldenValues(t *testing.T) {
+	batch := batchSeries()
+	for _, g := range goldens {
+		t.Run(g.name, func(t *testing.T) {
+			got := batch[g.name]
+			if len(got) != len(refClose) {
+				t.Fatalf("got %d values for %d bars", len(got), len(refClose))
+			}
+
+			first := len(refClose)
+			for idx, want := range g.values {
+				first = min(first, idx)
+				if math.Abs(got[idx]-want) > tolerance {
+					t.Errorf("bar %d: got %.10f, want %.10f", idx, got[idx], want)
+				}
+			}
+			for i := 0; i < first; i++ {
+				if !math.IsNaN(got[i]) {
+					t.Errorf("bar %d: got %.10f during warm-up, want NaN", i, got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStreamingMatchesBatch(t *testing.T) {
+	batch := batchSeries()
+	stream := streamSeries()
+	for _, g := range goldens {
+		t.Run(g.name, func(t *testing.T) {
+			want, got := batch[g.name], stream[g.name]
+			if len(got) != len(want) {
+				t.Fatalf("got %d streaming values, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if !same(got[i], want[i]) {
+					t.Fatalf("bar %d: streaming %.10f, batch %.10f", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
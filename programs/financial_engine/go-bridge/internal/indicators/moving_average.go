@@ -0,0 +1,77 @@
+package indicators
+
+import "math"
+
+// SMA is a simple moving average over the last period values
+type SMA struct {
+	stats *rollingStats
+	value float64
+}
+
+// NewSMA creates a simple moving average; it is ready after period values
+func NewSMA(period int) *SMA {
+	return &SMA{stats: newRollingStats(period), value: math.NaN()}
+}
+
+// Update adds a value and returns the average
+func (s *SMA) Update(value float64) float64 {
+	s.stats.push(value)
+	if s.stats.full() {
+		s.value = s.stats.mean
+	}
+	return s.value
+}
+
+// Value returns the latest average
+func (s *SMA) Value() float64 { return s.value }
+
+// Ready reports whether a full window has been seen
+func (s *SMA) Ready() bool { return s.stats.full() }
+
+// Reset clears all state
+func (s *SMA) Reset() {
+	s.stats.reset()
+	s.value = math.NaN()
+}
+
+// SMASeries computes a simple moving average over values
+func SMASeries(values []float64, period int) []float64 {
+	return Series(NewSMA(period), values)
+}
+
+// EMA is an exponential moving average with alpha 2/(period+1), seeded with
+// the simple mean of the first period values
+type EMA struct {
+	avg   seededAverage
+	value float64
+}
+
+// NewEMA creates an exponential moving average; it is ready after period values
+func NewEMA(period int) *EMA {
+	return &EMA{avg: newEMAAverage(period), value: math.NaN()}
+}
+
+// Update adds a value and returns the average
+func (e *EMA) Update(value float64) float64 {
+	if avg, ok := e.avg.update(value); ok {
+		e.value = avg
+	}
+	return e.value
+}
+
+// Value returns the latest average
+func (e *EMA) Value() float64 { return e.value }
+
+// Ready reports whether the seed period has been seen
+func (e *EMA) Ready() bool { return e.avg.ready() }
+
+// Reset clears all state
+func (e *EMA) Reset() {
+	e.avg.reset()
+	e.value = math.NaN()
+}
+
+// EMASeries computes an exponential moving average over values
+func EMASeries(values []float64, period int) []float64 {
+	return Series(NewEMA(period), values)
+}
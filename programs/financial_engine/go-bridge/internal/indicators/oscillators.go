@@ -0,0 +1,216 @@
+package indicators
+
+import "math"
+
+// RSI is Wilder's relative strength index. Average gain and loss are seeded
+// with the mean of the first period changes, then Wilder-smoothed.
+type RSI struct {
+	gains, losses seededAverage
+	prev          float64
+	count         int
+	value         float64
+}
+
+// NewRSI creates an RSI; it is ready after period+1 prices
+func NewRSI(period int) *RSI {
+	return &RSI{
+		gains:  newWilderAverage(period),
+		losses: newWilderAverage(period),
+		value:  math.NaN(),
+	}
+}
+
+// Update adds a price and returns the RSI
+func (r *RSI) Update(price float64) float64 {
+	r.count++
+	if r.count == 1 {
+		r.prev = price
+		return r.value
+	}
+	change := price - r.prev
+	r.prev = price
+
+	avgGain, ok := r.gains.update(math.Max(change, 0))
+	avgLoss, _ := r.losses.update(math.Max(-change, 0))
+	if ok {
+		r.value = rsiFromAverages(avgGain, avgLoss)
+	}
+	return r.value
+}
+
+// rsiFromAverages converts average gain and loss to RSI; a flat series is 50
+func rsiFromAverages(avgGain, avgLoss float64) float64 {
+	switch {
+	case avgLoss == 0 && avgGain == 0:
+		return 50
+	case avgLoss == 0:
+		return 100
+	}
+	return 100 - 100/(1+avgGain/avgLoss)
+}
+
+// Value returns the latest RSI
+func (r *RSI) Value() float64 { return r.value }
+
+// Ready reports whether the seed period has been seen
+func (r *RSI) Ready() bool { return r.gains.ready() }
+
+// Reset clears all state
+func (r *RSI) Reset() {
+	r.gains.reset()
+	r.losses.reset()
+	r.prev, r.count = 0, 0
+	r.value = math.NaN()
+}
+
+// RSISeries computes Wilder's RSI over prices
+func RSISeries(prices []float64, period int) []float64 {
+	return Series(NewRSI(period), prices)
+}
+
+// MACDValue is one MACD reading
+type MACDValue struct {
+	MACD      float64 // Fast EMA minus slow EMA
+	Signal    float64 // EMA of the MACD line
+	Histogram float64 // MACD minus signal
+}
+
+// MACD is the moving average convergence/divergence oscillator
+type MACD struct {
+	fast, slow, signal *EMA
+	value              MACDValue
+}
+
+// NewMACD creates a MACD. The line is available after slow prices and the
+// signal after slow+signal-1; Ready waits for both.
+func NewMACD(fastPeriod, slowPeriod, signalPeriod int) *MACD {
+	m := &MACD{
+		fast:   NewEMA(fastPeriod),
+		slow:   NewEMA(slowPeriod),
+		signal: NewEMA(signalPeriod),
+	}
+	m.Reset()
+	return m
+}
+
+// Update adds a price and returns the MACD reading
+func (m *MACD) Update(price float64) MACDValue {
+	fast := m.fast.Update(price)
+	slow := m.slow.Update(price)
+	if !m.fast.Ready() || !m.slow.Ready() {
+		return m.value
+	}
+
+	m.value.MACD = fast - slow
+	if signal := m.signal.Update(m.value.MACD); m.signal.Ready() {
+		m.value.Signal = signal
+		m.value.Histogram = m.value.MACD - signal
+	}
+	return m.value
+}
+
+// Value returns the latest reading
+func (m *MACD) Value() MACDValue { return m.value }
+
+// LineReady reports whether the MACD line is available
+func (m *MACD) LineReady() bool { return m.fast.Ready() && m.slow.Ready() }
+
+// Ready reports whether the signal line is available
+func (m *MACD) Ready() bool { return m.signal.Ready() }
+
+// Reset clears all state
+func (m *MACD) Reset() {
+	m.fast.Reset()
+	m.slow.Reset()
+	m.signal.Reset()
+	m.value = MACDValue{MACD: math.NaN(), Signal: math.NaN(), Histogram: math.NaN()}
+}
+
+// MACDSeries computes MACD over prices
+func MACDSeries(prices []float64, fastPeriod, slowPeriod, signalPeriod int) []MACDValue {
+	m := NewMACD(fastPeriod, slowPeriod, signalPeriod)
+	out := make([]MACDValue, len(prices))
+	for i, p := range prices {
+		out[i] = m.Update(p)
+	}
+	return out
+}
+
+// StochasticValue is one stochastic oscillator reading
+type StochasticValue struct {
+	K float64 // %K, smoothed by the slowing period
+	D float64 // %D, the moving average of %K
+}
+
+// Stochastic is the stochastic oscillator. %K compares the close with the
+// high-low range of the last kPeriod bars; a zero range reads 50.
+type Stochastic struct {
+	highest, lowest *rollingExtreme
+	kPeriod, seen   int
+	smoothK, d      *SMA
+	value           StochasticValue
+}
+
+// NewStochastic creates a stochastic oscillator. smoothK of 1 gives the fast
+// stochastic; 3 gives the common slow variant. It is ready after
+// kPeriod+smoothK+dPeriod-2 bars.
+func NewStochastic(kPeriod, smoothK, dPeriod int) *Stochastic {
+	kPeriod = validPeriod(kPeriod)
+	s := &Stochastic{
+		highest: newRollingExtreme(kPeriod, true),
+		lowest:  newRollingExtreme(kPeriod, false),
+		kPeriod: kPeriod,
+		smoothK: NewSMA(smoothK),
+		d:       NewSMA(dPeriod),
+	}
+	s.Reset()
+	return s
+}
+
+// Update adds a bar and returns the reading
+func (s *Stochastic) Update(high, low, close float64) StochasticValue {
+	hh := s.highest.push(high)
+	ll := s.lowest.push(low)
+	s.seen++
+	if s.seen < s.kPeriod {
+		return s.value
+	}
+
+	rawK := 50.0
+	if hh > ll {
+		rawK = 100 * (close - ll) / (hh - ll)
+	}
+	if k := s.smoothK.Update(rawK); s.smoothK.Ready() {
+		s.value.K = k
+		if d := s.d.Update(k); s.d.Ready() {
+			s.value.D = d
+		}
+	}
+	return s.value
+}
+
+// Value returns the latest reading
+func (s *Stochastic) Value() StochasticValue { return s.value }
+
+// Ready reports whether %D is available
+func (s *Stochastic) Ready() bool { return s.d.Ready() }
+
+// Reset clears all state
+func (s *Stochastic) Reset() {
+	s.highest.reset()
+	s.lowest.reset()
+	s.smoothK.Reset()
+	s.d.Reset()
+	s.seen = 0
+	s.value = StochasticValue{K: math.NaN(), D: math.NaN()}
+}
+
+// StochasticSeries computes the stochastic oscillator over bars
+func StochasticSeries(high, low, close []float64, kPeriod, smoothK, dPeriod int) []StochasticValue {
+	s := NewStochastic(kPeriod, smoothK, dPeriod)
+	out := make([]StochasticValue, minLen(high, low, close))
+	for i := range out {
+		out[i] = s.Update(high[i], low[i], close[i])
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package indicators
+
+import "math"
+
+// ADXValue is one directional movement reading
+type ADXValue struct {
+	ADX     float64 // Average directional index, trend strength 0-100
+	PlusDI  float64 // +DI, upward movement relative to true range
+	MinusDI float64 // -DI, downward movement relative to true range
+}
+
+// ADX is Wilder's average directional index. +DM, -DM and true range are
+// Wilder-summed over period bars, and ADX is the Wilder average of DX.
+type ADX struct {
+	period int
+	seen   int // Bars after the first, which only sets the previous bar
+
+	prevHigh, prevLow, prevClose float64
+	trSum, plusSum, minusSum     float64
+	dx                           seededAverage
+	value                        ADXValue
+}
+
+// NewADX creates an ADX. +DI and -DI are available after period+1 bars and
+// ADX after 2*period.
+func NewADX(period int) *ADX {
+	period = validPeriod(period)
+	a := &ADX{period: period, dx: newWilderAverage(period)}
+	a.Reset()
+	return a
+}
+
+// Update adds a bar and returns the reading
+func (a *ADX) Update(high, low, close float64) ADXValue {
+	if a.seen < 0 {
+		a.prevHigh, a.prevLow, a.prevClose = high, low, close
+		a.seen = 0
+		return a.value
+	}
+
+	up := high - a.prevHigh
+	down := a.prevLow - low
+	plusDM, minusDM := 0.0, 0.0
+	if up > down && up > 0 {
+		plusDM = up
+	}
+	if down > up && down > 0 {
+		minusDM = down
+	}
+	tr := trueRange(high, low, a.prevClose, true)
+	a.prevHigh, a.prevLow, a.prevClose = high, low, close
+
+	// Wilder sums: plain sums over the first period, then S - S/n + x
+	a.seen++
+	n := float64(a.period)
+	if a.seen <= a.period {
+		a.trSum += tr
+		a.plusSum += plusDM
+		a.minusSum += minusDM
+		if a.seen < a.period {
+			return a.value
+		}
+	} else {
+		a.trSum += tr - a.trSum/n
+		a.plusSum += plusDM - a.plusSum/n
+		a.minusSum += minusDM - a.minusSum/n
+	}
+
+	a.value.PlusDI, a.value.MinusDI = 0, 0
+	if a.trSum > 0 {
+		a.value.PlusDI = 100 * a.plusSum / a.trSum
+		a.value.MinusDI = 100 * a.minusSum / a.trSum
+	}
+	dx := 0.0
+	if sum := a.value.PlusDI + a.value.MinusDI; sum > 0 {
+		dx = 100 * math.Abs(a.value.PlusDI-a.value.MinusDI) / sum
+	}
+	if adx, ok := a.dx.update(dx); ok {
+		a.value.ADX = adx
+	}
+	return a.value
+}
+
+// Value returns the latest reading
+func (a *ADX) Value() ADXValue { return a.value }
+
+// Ready reports whether ADX is available
+func (a *ADX) Ready() bool { return a.dx.ready() }
+
+// Reset clears all state
+func (a *ADX) Reset() {
+	a.seen = -1
+	a.trSum, a.plusSum, a.minusSum = 0, 0, 0
+	a.dx.reset()
+	a.value = ADXValue{ADX: math.NaN(), PlusDI: math.NaN(), MinusDI: math.NaN()}
+}
+
+// ADXSeries computes the average directional index over bars
+func ADXSeries(high, low, close []float64, period int) []ADXValue {
+	a := NewADX(period)
+	out := make([]ADXValue, minLen(high, low, close))
+	for i := range out {
+		out[i] = a.Update(high[i], low[i], close[i])
+	}
+	return out
+}
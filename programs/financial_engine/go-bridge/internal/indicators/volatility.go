@@ -0,0 +1,174 @@
+package indicators
+
+import "math"
+
+// BollingerValue is one Bollinger Bands reading
+type BollingerValue struct {
+	Middle   float64 // Simple moving average
+	Upper    float64
+	Lower    float64
+	StdDev   float64 // Population standard deviation of the window
+	Width    float64 // (Upper - Lower) / Middle
+	PercentB float64 // Where the price sits in the bands: 0 at Lower, 1 at Upper
+}
+
+// Bollinger computes Bollinger Bands at numStdDev standard deviations
+type Bollinger struct {
+	stats     *rollingStats
+	numStdDev float64
+	value     BollingerValue
+}
+
+// NewBollinger creates Bollinger Bands; they are ready after period prices
+func NewBollinger(period int, numStdDev float64) *Bollinger {
+	b := &Bollinger{stats: newRollingStats(period), numStdDev: numStdDev}
+	b.Reset()
+	return b
+}
+
+// Update adds a price and returns the bands
+func (b *Bollinger) Update(price float64) BollingerValue {
+	b.stats.push(price)
+	if !b.stats.full() {
+		return b.value
+	}
+
+	middle := b.stats.mean
+	std := b.stats.stdDev()
+	v := BollingerValue{
+		Middle:   middle,
+		Upper:    middle + b.numStdDev*std,
+		Lower:    middle - b.numStdDev*std,
+		StdDev:   std,
+		PercentB: 0.5,
+	}
+	if middle != 0 {
+		v.Width = (v.Upper - v.Lower) / middle
+	}
+	if v.Upper > v.Lower {
+		v.PercentB = (price - v.Lower) / (v.Upper - v.Lower)
+	}
+	b.value = v
+	return v
+}
+
+// Value returns the latest bands
+func (b *Bollinger) Value() BollingerValue { return b.value }
+
+// Ready reports whether a full window has been seen
+func (b *Bollinger) Ready() bool { return b.stats.full() }
+
+// Reset clears all state
+func (b *Bollinger) Reset() {
+	b.stats.reset()
+	nan := math.NaN()
+	b.value = BollingerValue{Middle: nan, Upper: nan, Lower: nan, StdDev: nan, Width: nan, PercentB: nan}
+}
+
+// BollingerSeries computes Bollinger Bands over prices
+func BollingerSeries(prices []float64, period int, numStdDev float64) []BollingerValue {
+	b := NewBollinger(period, numStdDev)
+	out := make([]BollingerValue, len(prices))
+	for i, p := range prices {
+		out[i] = b.Update(p)
+	}
+	return out
+}
+
+// ZScore is how many standard deviations a value sits from the mean of the
+// window it closes. A flat window reads 0.
+type ZScore struct {
+	stats *rollingStats
+	value float64
+}
+
+// NewZScore creates a rolling z-score; it is ready after period values
+func NewZScore(period int) *ZScore {
+	return &ZScore{stats: newRollingStats(period), value: math.NaN()}
+}
+
+// Update adds a value and returns its z-score
+func (z *ZScore) Update(value float64) float64 {
+	z.stats.push(value)
+	if !z.stats.full() {
+		return z.value
+	}
+	z.value = 0
+	if std := z.stats.stdDev(); std > 0 {
+		z.value = (value - z.stats.mean) / std
+	}
+	return z.value
+}
+
+// Value returns the latest z-score
+func (z *ZScore) Value() float64 { return z.value }
+
+// Ready reports whether a full window has been seen
+func (z *ZScore) Ready() bool { return z.stats.full() }
+
+// Reset clears all state
+func (z *ZScore) Reset() {
+	z.stats.reset()
+	z.value = math.NaN()
+}
+
+// ZScoreSeries computes a rolling z-score over values
+func ZScoreSeries(values []float64, period int) []float64 {
+	return Series(NewZScore(period), values)
+}
+
+// ATR is Wilder's average true range. The first bar's true range is its
+// high-low range; later bars also reach back to the previous close.
+type ATR struct {
+	avg       seededAverage
+	prevClose float64
+	started   bool
+	value     float64
+}
+
+// NewATR creates an ATR; it is ready after period bars
+func NewATR(period int) *ATR {
+	return &ATR{avg: newWilderAverage(period), value: math.NaN()}
+}
+
+// Update adds a bar and returns the ATR
+func (a *ATR) Update(high, low, close float64) float64 {
+	tr := trueRange(high, low, a.prevClose, a.started)
+	a.prevClose, a.started = close, true
+	if avg, ok := a.avg.update(tr); ok {
+		a.value = avg
+	}
+	return a.value
+}
+
+// trueRange is the bar's range extended to the previous close, if any
+func trueRange(high, low, prevClose float64, hasPrev bool) float64 {
+	tr := high - low
+	if hasPrev {
+		tr = math.Max(tr, math.Max(math.Abs(high-prevClose), math.Abs(low-prevClose)))
+	}
+	return tr
+}
+
+// Value returns the latest ATR
+func (a *ATR) Value() float64 { return a.value }
+
+// Ready reports whether the seed period has been seen
+func (a *ATR) Ready() bool { return a.avg.ready() }
+
+// Reset clears all state
+func (a *ATR) Reset() {
+	a.avg.reset()
+	a.prevClose, a.started = 0, false
+	a.value = math.NaN()
+}
+
+// ATRSeries computes the average true range over bars
+func ATRSeries(high, low, close []float64, period int) []float64 {
+	a := NewATR(period)
+	out := make([]float64, minLen(high, low, close))
+	for i := range out {
+		out[i] = a.Update(high[i], low[i], close[i])
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package indicators
+
+import "math"
+
+// OBV is on-balance volume: volume added on up closes and subtracted on down
+// closes, starting from zero at the first bar
+type OBV struct {
+	prevClose float64
+	started   bool
+	value     float64
+}
+
+// NewOBV creates on-balance volume; it is ready after the first bar
+func NewOBV() *OBV {
+	return &OBV{value: math.NaN()}
+}
+
+// Update adds a bar and returns the running total
+func (o *OBV) Update(close, volume float64) float64 {
+	switch {
+	case !o.started:
+		o.value, o.started = 0, true
+	case close > o.prevClose:
+		o.value += volume
+	case close < o.prevClose:
+		o.value -= volume
+	}
+	o.prevClose = close
+	return o.value
+}
+
+// Value returns the running total
+func (o *OBV) Value() float64 { return o.value }
+
+// Ready reports whether a bar has been seen
+func (o *OBV) Ready() bool { return o.started }
+
+// Reset clears all state
+func (o *OBV) Reset() {
+	o.prevClose, o.started = 0, false
+	o.value = math.NaN()
+}
+
+// OBVSeries computes on-balance volume over bars
+func OBVSeries(close, volume []float64) []float64 {
+	o := NewOBV()
+	out := make([]float64, minLen(close, volume))
+	for i := range out {
+		out[i] = o.Update(close[i], volume[i])
+	}
+	return out
+}
+
+// VWAP is the volume-weighted average price. With a period it covers the last
+// period bars; with period 0 it accumulates until Reset, e.g. once per session.
+// It is not ready until the covered bars have traded some volume.
+type VWAP struct {
+	prices, volumes *window // Nil when cumulative
+	pv, volume      float64
+	value           float64
+}
+
+// NewVWAP creates a rolling VWAP, or a cumulative one when period is 0
+func NewVWAP(period int) *VWAP {
+	v := &VWAP{value: math.NaN()}
+	if period > 0 {
+		v.prices, v.volumes = newWindow(period), newWindow(period)
+	}
+	return v
+}
+
+// Update adds a bar's price (usually TypicalPrice) and volume and returns the VWAP
+func (v *VWAP) Update(price, volume float64) float64 {
+	v.pv += price * volume
+	v.volume += volume
+	if v.prices != nil {
+		oldPrice, full := v.prices.push(price)
+		oldVolume, _ := v.volumes.push(volume)
+		if full {
+			v.pv -= oldPrice * oldVolume
+			v.volume -= oldVolume
+		}
+		if v.prices.wrapped() {
+			v.recompute()
+		}
+	}
+
+	if v.Ready() {
+		v.value = v.pv / v.volume
+	}
+	return v.value
+}
+
+// recompute recalculates the rolling sums exactly from the window
+func (v *VWAP) recompute() {
+	v.pv, v.volume = 0, 0
+	for i := 0; i < v.prices.count; i++ {
+		v.pv += v.prices.values[i] * v.volumes.values[i]
+		v.volume += v.volumes.values[i]
+	}
+}
+
+// Value returns the latest VWAP
+func (v *VWAP) Value() float64 { return v.value }
+
+// Volume returns the volume the VWAP currently covers
+func (v *VWAP) Volume() float64 { return v.volume }
+
+// Ready reports whether the window is full and has traded volume
+func (v *VWAP) Ready() bool {
+	if v.prices != nil && !v.prices.full() {
+		return false
+	}
+	return v.volume > 0
+}
+
+// Reset clears all state, starting a new session for a cumulative VWAP
+func (v *VWAP) Reset() {
+	if v.prices != nil {
+		v.prices.reset()
+		v.volumes.reset()
+	}
+	v.pv, v.volume = 0, 0
+	v.value = math.NaN()
+}
+
+// VWAPSeries computes a rolling (period > 0) or cumulative (period 0) VWAP
+func VWAPSeries(prices, volumes []float64, period int) []float64 {
+	v := NewVWAP(period)
+	out := make([]float64, minLen(prices, volumes))
+	for i := range out {
+		out[i] = v.Update(prices[i], volumes[i])
+	}
+	return out
+}
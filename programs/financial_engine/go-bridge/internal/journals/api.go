@@ -10,6 +10,8 @@ import (
 
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
 )
 
 // Journal types
@@ -84,7 +86,7 @@ type JournalResponse struct {
 
 // JournalManager handles cash and securities journaling
 type JournalManager struct {
-	alpacaClient    *alpaca.Client
+	tradingClient   broker.Broker
 	firmAccountID   string
 	journals        []*JournalResponse
 	logger          *log.Logger
@@ -98,21 +100,11 @@ type JournalManager struct {
 	journalsToday   int
 }
 
-// NewJournalManager creates a new journal manager
-func NewJournalManager(apiKey, apiSecret, firmAccountID string, isPaper bool) *JournalManager {
-	baseURL := "https://api.alpaca.markets"
-	if isPaper {
-		baseURL = "https://paper-api.alpaca.markets"
-	}
-	
-	alpacaClient := alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		BaseURL:   baseURL,
-	})
-	
+// NewJournalManager creates a new journal manager for the firm account
+// tradingClient trades on
+func NewJournalManager(tradingClient broker.Broker, firmAccountID string) *JournalManager {
 	return &JournalManager{
-		alpacaClient:     alpacaClient,
+		tradingClient:    tradingClient,
 		firmAccountID:    firmAccountID,
 		journals:         make([]*JournalResponse, 0),
 		logger:           log.New(log.Writer(), "[JOURNALS] ", log.LstdFlags|log.Lmicroseconds),
@@ -394,8 +386,14 @@ func RunJournalsDemo() {
 		apiSecret = os.Getenv("APCA_API_SECRET_KEY")
 	}
 	
+	tradingClient := broker.NewAlpaca(alpaca.ClientOpts{
+		APIKey:    apiKey,
+		APISecret: apiSecret,
+		BaseURL:   broker.PaperBaseURL,
+	}, nil)
+	
 	firmAccountID := "firm-abc123"
-	journalManager := NewJournalManager(apiKey, apiSecret, firmAccountID, true)
+	journalManager := NewJournalManager(tradingClient, firmAccountID)
 	
 	fmt.Printf("\n💼 Journal Manager initialized\n")
 	fmt.Printf("🏢 Firm Account: %s\n", firmAccountID)
@@ -0,0 +1,151 @@
+package labeling
+
+import (
+	"fmt"
+	"math"
+
+	"zig-financial-engine/internal/features"
+)
+
+// TripleBarrier labels an event by whichever it reaches first: a profit-take
+// barrier, a stop-loss barrier, or the end of the holding period. Both
+// price barriers are set in units of the event's target move, the EWMA
+// volatility of one-bar returns scaled to the holding period, so quiet and
+// volatile markets produce comparable labels.
+type TripleBarrier struct {
+	ProfitTake float64 `json:"profit_take"` // Profit barrier in target moves; 0 disables it
+	StopLoss   float64 `json:"stop_loss"`   // Stop barrier in target moves; 0 disables it
+	MaxHolding int     `json:"max_holding"` // Vertical barrier, in bars after the event
+	VolSpan    int     `json:"vol_span"`    // EWMA span of the volatility estimate
+	MinTarget  float64 `json:"min_target"`  // Events with a smaller target move are skipped
+}
+
+// DefaultTripleBarrier takes profit at two target moves, stops at one, and
+// times out after 20 bars
+func DefaultTripleBarrier() TripleBarrier {
+	return TripleBarrier{ProfitTake: 2, StopLoss: 1, MaxHolding: 20, VolSpan: 50}
+}
+
+// Validate checks the barriers are usable
+func (t TripleBarrier) Validate() error {
+	if t.MaxHolding < 1 {
+		return fmt.Errorf("max holding must be at least one bar")
+	}
+	if t.ProfitTake < 0 || t.StopLoss < 0 || t.MinTarget < 0 {
+		return fmt.Errorf("barrier widths and min target must not be negative")
+	}
+	if t.VolSpan < 2 {
+		return fmt.Errorf("volatility span must be at least 2 bars")
+	}
+	return nil
+}
+
+// Label resolves each event against the bars after it. Touches are checked
+// on bar highs and lows; when one bar crosses both barriers the stop is
+// assumed to have filled first. Events before the volatility estimate has
+// warmed up, below MinTarget, or whose holding period runs past the data
+// without a touch are not labeled. A nil events labels every bar unsided.
+//
+// Unsided events are classed 1 at the upper barrier, -1 at the lower and 0
+// on time-out. Sided events use the same classes for profit-take, stop and
+// time-out, with Return in the direction of the bet.
+func (t TripleBarrier) Label(bars []features.Bar, events []Event) ([]Label, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = EveryBar(bars)
+	}
+
+	vol := Volatility(bars, t.VolSpan)
+	var labels []Label
+	for _, event := range events {
+		if event.Index < 0 || event.Index >= len(bars) {
+			return nil, fmt.Errorf("event at bar %d is outside %d bars", event.Index, len(bars))
+		}
+		if event.Side < -1 || event.Side > 1 {
+			return nil, fmt.Errorf("event at bar %d has side %d, expected -1, 0 or 1", event.Index, event.Side)
+		}
+		if event.Index < t.VolSpan || bars[event.Index].Close <= 0 {
+			continue
+		}
+		target := vol[event.Index] * math.Sqrt(float64(t.MaxHolding))
+		if target <= 0 || target < t.MinTarget {
+			continue
+		}
+		if label, ok := t.resolve(bars, event, target); ok {
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
+
+// resolve walks the holding period of one event
+func (t TripleBarrier) resolve(bars []features.Bar, event Event, target float64) (Label, bool) {
+	start := event.Index
+	entry := bars[start].Close
+	side := float64(event.Side)
+	if event.Side == 0 {
+		side = 1 // Unsided barriers sit where a long's would
+	}
+	label := Label{Start: start, Time: bars[start].Time, Side: event.Side, Weight: 1}
+
+	for end := start + 1; end <= start+t.MaxHolding && end < len(bars); end++ {
+		bar := bars[end]
+		high, low := math.Max(bar.High, bar.Close), bar.Low
+		if low <= 0 || low > bar.Close {
+			low = bar.Close
+		}
+		// Best and worst return in the direction of the bet
+		best, worst := high/entry-1, low/entry-1
+		if side < 0 {
+			best, worst = -worst, -best
+		}
+
+		label.End, label.EndTime = end, bar.Time
+		switch {
+		case t.StopLoss > 0 && worst <= -t.StopLoss*target:
+			label.Return, label.Class, label.Barrier = -t.StopLoss*target, -1, BarrierLower
+			if side < 0 {
+				label.Barrier = BarrierUpper
+			}
+			return label, true
+		case t.ProfitTake > 0 && best >= t.ProfitTake*target:
+			label.Return, label.Class, label.Barrier = t.ProfitTake*target, 1, BarrierUpper
+			if side < 0 {
+				label.Barrier = BarrierLower
+			}
+			return label, true
+		case end == start+t.MaxHolding:
+			label.Return, label.Class, label.Barrier = side*(bar.Close/entry-1), 0, BarrierTime
+			return label, true
+		}
+	}
+	return Label{}, false // Data ends before the outcome is known
+}
+
+// Volatility is the exponentially weighted standard deviation of one-bar
+// returns with the given span, aligned with bars (0 on the first bar)
+func Volatility(bars []features.Bar, span int) []float64 {
+	vol := make([]float64, len(bars))
+	alpha := 2 / (float64(span) + 1)
+	mean, variance := 0.0, 0.0
+	started := false
+	for i := 1; i < len(bars); i++ {
+		if bars[i-1].Close <= 0 {
+			vol[i] = vol[i-1]
+			continue
+		}
+		ret := bars[i].Close/bars[i-1].Close - 1
+		if !started {
+			mean, started = ret, true
+		} else {
+			diff := ret - mean
+			increment := alpha * diff
+			mean += increment
+			variance = (1 - alpha) * (variance + diff*increment)
+		}
+		vol[i] = math.Sqrt(variance)
+	}
+	return vol
+}
@@ -0,0 +1,125 @@
+// Package labeling turns price paths into supervised-learning targets for
+// feature rows: fixed-horizon returns, triple-barrier outcomes with
+// volatility-scaled profit-take and stop barriers, and meta-labels that
+// grade another strategy's signals. SampleWeights discounts labels whose
+// outcome windows overlap, so a model is not trained on the same move many
+// times over.
+//
+// Labels read bars from the feature pipeline's Bar type, so one bar slice
+// yields both a feature matrix and its targets.
+package labeling
+
+import (
+	"fmt"
+	"time"
+
+	"zig-financial-engine/internal/features"
+)
+
+// Barrier is what decided a label's outcome
+type Barrier string
+
+const (
+	BarrierUpper Barrier = "upper" // Profit-take, or the upper barrier of an unsided event
+	BarrierLower Barrier = "lower" // Stop-loss, or the lower barrier of an unsided event
+	BarrierTime  Barrier = "time"  // Holding period ran out, or a fixed horizon
+)
+
+// Event is a bar to label and the side of the bet taken there: +1 long,
+// -1 short, or 0 when the direction is what the label should predict
+type Event struct {
+	Index int `json:"index"`
+	Side  int `json:"side"`
+}
+
+// Label is the outcome of one event
+type Label struct {
+	Start   int       `json:"start"`    // Bar the outcome is measured from
+	End     int       `json:"end"`      // Bar the outcome was decided on
+	Time    time.Time `json:"time"`     // Time of the start bar
+	EndTime time.Time `json:"end_time"` // Time of the end bar; the row's information horizon
+	Side    int       `json:"side"`
+	Return  float64   `json:"return"` // Return from Start to End, in the direction of Side when set
+	Barrier Barrier   `json:"barrier"`
+	Class   int       `json:"class"`  // -1, 0 or 1; 0 or 1 for meta-labels
+	Weight  float64   `json:"weight"` // Sample weight, 1 until SampleWeights is applied
+}
+
+// FixedHorizon labels every bar with its close-to-close return horizon bars
+// ahead: 1 above threshold, -1 below -threshold, 0 otherwise. The last
+// horizon bars have no outcome yet and are not labeled.
+func FixedHorizon(bars []features.Bar, horizon int, threshold float64) ([]Label, error) {
+	if horizon < 1 {
+		return nil, fmt.Errorf("horizon must be at least one bar")
+	}
+	if threshold < 0 {
+		return nil, fmt.Errorf("threshold must not be negative")
+	}
+
+	var labels []Label
+	for i := 0; i+horizon < len(bars); i++ {
+		if bars[i].Close <= 0 {
+			continue
+		}
+		end := i + horizon
+		ret := bars[end].Close/bars[i].Close - 1
+		labels = append(labels, Label{
+			Start:   i,
+			End:     end,
+			Time:    bars[i].Time,
+			EndTime: bars[end].Time,
+			Return:  ret,
+			Barrier: BarrierTime,
+			Class:   classify(ret, threshold),
+			Weight:  1,
+		})
+	}
+	return labels, nil
+}
+
+// MetaLabels grades sided labels as bets: 1 where acting on the signal made
+// money and 0 where it did not. A model trained on them learns when to
+// trust the primary strategy, not which way to trade.
+func MetaLabels(labels []Label) ([]Label, error) {
+	meta := make([]Label, len(labels))
+	for i, label := range labels {
+		if label.Side == 0 {
+			return nil, fmt.Errorf("label at bar %d has no side; meta-labels need the primary signal's direction", label.Start)
+		}
+		label.Class = 0
+		if label.Return > 0 {
+			label.Class = 1
+		}
+		meta[i] = label
+	}
+	return meta, nil
+}
+
+// EveryBar returns an unsided event for each bar
+func EveryBar(bars []features.Bar) []Event {
+	events := make([]Event, len(bars))
+	for i := range bars {
+		events[i] = Event{Index: i}
+	}
+	return events
+}
+
+// Index maps labels by start bar, for joining them onto feature rows
+func Index(labels []Label) map[int]Label {
+	byStart := make(map[int]Label, len(labels))
+	for _, label := range labels {
+		byStart[label.Start] = label
+	}
+	return byStart
+}
+
+// classify maps a return to -1, 0 or 1 around a dead band of ±threshold
+func classify(ret, threshold float64) int {
+	switch {
+	case ret > threshold:
+		return 1
+	case ret < -threshold:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,48 @@
+package labeling
+
+// SampleWeights sets each label's Weight to its average uniqueness: over the
+// bars its outcome spans, (Start, End], the mean of 1/c where c is the
+// number of labels spanning that bar. A label that shares its whole window
+// with nine others counts a tenth as much as one that stands alone. Weights
+// are scaled to average 1 so they can be passed to a trainer as-is.
+func SampleWeights(labels []Label) {
+	if len(labels) == 0 {
+		return
+	}
+	last := 0
+	for _, label := range labels {
+		if label.End > last {
+			last = label.End
+		}
+	}
+
+	// Concurrency by bar, built from interval start and end deltas
+	concurrency := make([]int, last+2)
+	for _, label := range labels {
+		concurrency[label.Start+1]++
+		concurrency[label.End+1]--
+	}
+	for i := 1; i < len(concurrency); i++ {
+		concurrency[i] += concurrency[i-1]
+	}
+
+	total := 0.0
+	for i := range labels {
+		uniqueness := 0.0
+		for bar := labels[i].Start + 1; bar <= labels[i].End; bar++ {
+			uniqueness += 1 / float64(concurrency[bar])
+		}
+		if span := labels[i].End - labels[i].Start; span > 0 {
+			uniqueness /= float64(span)
+		}
+		labels[i].Weight = uniqueness
+		total += uniqueness
+	}
+	if total <= 0 {
+		return
+	}
+	scale := float64(len(labels)) / total
+	for i := range labels {
+		labels[i].Weight *= scale
+	}
+}
@@ -3,6 +3,7 @@ package market
 import (
 	"fmt"
 	"log"
+	"os"
 	"sync"
 	"time"
 
@@ -451,8 +452,8 @@ func RunMarketDataDemo() {
 		fmt.Printf("📊 AAPL: Retrieved %d daily bars\n", len(stockBars))
 		if len(stockBars) > 0 {
 			latest := stockBars[len(stockBars)-1]
-			fmt.Printf("   Latest: Open=$%.2f High=$%.2f Low=$%.2f Close=$%.2f Vol=%d\n",
-				latest.Open, latest.High, latest.Low, latest.Close, latest.Volume)
+			fmt.Printf("   Latest: Open=$%s High=$%s Low=$%s Close=$%s Vol=%d\n",
+				latest.Open.StringFixed(2), latest.High.StringFixed(2), latest.Low.StringFixed(2), latest.Close.StringFixed(2), latest.Volume)
 		}
 	}
 	
@@ -465,8 +466,8 @@ func RunMarketDataDemo() {
 		fmt.Printf("₿ BTC/USD: Retrieved %d daily bars\n", len(cryptoBars))
 		if len(cryptoBars) > 0 {
 			latest := cryptoBars[len(cryptoBars)-1]
-			fmt.Printf("   Latest: Open=$%.2f High=$%.2f Low=$%.2f Close=$%.2f Vol=%.4f\n",
-				latest.Open, latest.High, latest.Low, latest.Close, latest.Volume)
+			fmt.Printf("   Latest: Open=$%s High=$%s Low=$%s Close=$%s Vol=%s\n",
+				latest.Open.StringFixed(2), latest.High.StringFixed(2), latest.Low.StringFixed(2), latest.Close.StringFixed(2), latest.Volume.StringFixed(4))
 		}
 	}
 	
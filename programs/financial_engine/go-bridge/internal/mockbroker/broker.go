@@ -0,0 +1,237 @@
+package mockbroker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
+)
+
+// Broker is a broker.Broker backed by an in-process Simulator: a paper
+// account that fills against whatever market data the process feeds it
+// through Apply. Errors come back as *alpaca.APIError, as from the API.
+type Broker struct {
+	sim *Simulator
+
+	mu      sync.Mutex
+	streams map[*broker.QueuedStream]bool
+}
+
+// NewBroker trades on sim, which may be shared with a Server or
+// Replayer
+func NewBroker(sim *Simulator) *Broker {
+	s := &Broker{sim: sim, streams: make(map[*broker.QueuedStream]bool)}
+	sim.Subscribe(s.publish)
+	return s
+}
+
+// Simulator returns the simulator behind the broker
+func (s *Broker) Simulator() *Simulator {
+	return s.sim
+}
+
+// Apply feeds one market data event to the simulator
+func (s *Broker) Apply(event broker.Event) {
+	s.sim.Apply(event)
+}
+
+// simError converts a simulator error to the SDK's error type
+func simError(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return &alpaca.APIError{StatusCode: apiErr.Status, Code: apiErr.Code, Message: apiErr.Message}
+	}
+	return err
+}
+
+// orderResult boxes an order result the way the SDK returns it
+func orderResult(order alpaca.Order, err error) (*alpaca.Order, error) {
+	if err != nil {
+		return nil, simError(err)
+	}
+	return &order, nil
+}
+
+// GetAccount returns the simulated account
+func (s *Broker) GetAccount() (*alpaca.Account, error) {
+	account := s.sim.Account()
+	return &account, nil
+}
+
+// GetClock returns the market clock on simulated time
+func (s *Broker) GetClock() (*alpaca.Clock, error) {
+	clock := s.sim.Clock()
+	return &clock, nil
+}
+
+// GetPositions lists open positions
+func (s *Broker) GetPositions() ([]alpaca.Position, error) {
+	return s.sim.Positions(), nil
+}
+
+// GetPosition returns the position in symbol
+func (s *Broker) GetPosition(symbol string) (*alpaca.Position, error) {
+	position, err := s.sim.Position(symbol)
+	if err != nil {
+		return nil, simError(err)
+	}
+	return &position, nil
+}
+
+// ClosePosition liquidates all of a position, or the qty or percentage req asks for
+func (s *Broker) ClosePosition(symbol string, req alpaca.ClosePositionRequest) (*alpaca.Order, error) {
+	if req.Qty.IsZero() && req.Percentage.IsZero() {
+		return orderResult(s.sim.ClosePosition(symbol))
+	}
+
+	position, err := s.sim.Position(symbol)
+	if err != nil {
+		return nil, simError(err)
+	}
+	qty := req.Qty
+	if qty.IsZero() {
+		qty = position.Qty.Abs().Mul(req.Percentage).Div(decimal.NewFromInt(100))
+	}
+	side := alpaca.Sell
+	if position.Qty.IsNegative() {
+		side = alpaca.Buy
+	}
+	return orderResult(s.sim.SubmitOrder(alpaca.PlaceOrderRequest{
+		Symbol:      position.Symbol,
+		Qty:         &qty,
+		Side:        side,
+		Type:        alpaca.Market,
+		TimeInForce: alpaca.Day,
+	}))
+}
+
+// CloseAllPositions liquidates every position; failures are joined into the error
+func (s *Broker) CloseAllPositions(req alpaca.CloseAllPositionsRequest) ([]alpaca.Order, error) {
+	var (
+		orders = []alpaca.Order{}
+		errs   []error
+	)
+	for _, result := range s.sim.CloseAllPositions(req.CancelOrders) {
+		if result.Err != nil {
+			errs = append(errs, simError(result.Err))
+			continue
+		}
+		orders = append(orders, result.Order)
+	}
+	return orders, errors.Join(errs...)
+}
+
+// GetOrders lists orders; like the API, it defaults to the 50 newest open orders
+func (s *Broker) GetOrders(req alpaca.GetOrdersRequest) ([]alpaca.Order, error) {
+	q := OrderQuery{
+		Status: req.Status,
+		Limit:  req.Limit,
+		After:  req.After,
+		Until:  req.Until,
+		Desc:   req.Direction != "asc",
+		Nested: req.Nested,
+		Side:   alpaca.Side(req.Side),
+	}
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+	if len(req.Symbols) > 0 {
+		q.Symbols = make(map[string]bool)
+		for _, symbol := range req.Symbols {
+			q.Symbols[strings.ToUpper(symbol)] = true
+		}
+	}
+	return s.sim.Orders(q), nil
+}
+
+// GetOrder returns an order by ID
+func (s *Broker) GetOrder(orderID string) (*alpaca.Order, error) {
+	return orderResult(s.sim.Order(orderID))
+}
+
+// GetOrderByClientOrderID returns an order by client order ID
+func (s *Broker) GetOrderByClientOrderID(clientOrderID string) (*alpaca.Order, error) {
+	return orderResult(s.sim.OrderByClientID(clientOrderID))
+}
+
+// PlaceOrder submits an order
+func (s *Broker) PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error) {
+	return orderResult(s.sim.SubmitOrder(req))
+}
+
+// ReplaceOrder amends a working order
+func (s *Broker) ReplaceOrder(orderID string, req alpaca.ReplaceOrderRequest) (*alpaca.Order, error) {
+	return orderResult(s.sim.ReplaceOrder(orderID, req))
+}
+
+// CancelOrder cancels a working order
+func (s *Broker) CancelOrder(orderID string) error {
+	return simError(s.sim.CancelOrder(orderID))
+}
+
+// CancelAllOrders cancels every working order
+func (s *Broker) CancelAllOrders() error {
+	s.sim.CancelAllOrders()
+	return nil
+}
+
+// GetAssets lists the symbols the simulator has market data for
+func (s *Broker) GetAssets(req alpaca.GetAssetsRequest) ([]alpaca.Asset, error) {
+	return s.sim.Assets(), nil
+}
+
+// GetAsset describes symbol as a tradable equity
+func (s *Broker) GetAsset(symbol string) (*alpaca.Asset, error) {
+	asset := s.sim.Asset(symbol)
+	return &asset, nil
+}
+
+// TradeUpdates returns a stream of the simulator's order events
+func (s *Broker) TradeUpdates() broker.TradeStream {
+	return broker.NewQueuedStream(
+		func(q *broker.QueuedStream) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.streams[q] = true
+		},
+		func(q *broker.QueuedStream) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.streams, q)
+		},
+	)
+}
+
+// publish forwards a simulator event to connected streams in the
+// trade_updates wire format
+func (s *Broker) publish(event TradeUpdate) {
+	update, err := toTradeUpdate(event)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for q := range s.streams {
+		q.Push(update)
+	}
+}
+
+// toTradeUpdate converts a simulator event through its JSON form, so
+// in-process events match those decoded from the stream
+func toTradeUpdate(event TradeUpdate) (broker.TradeUpdate, error) {
+	var update broker.TradeUpdate
+	data, err := json.Marshal(map[string]interface{}{"stream": "trade_updates", "data": event})
+	if err != nil {
+		return update, fmt.Errorf("failed to encode trade update: %w", err)
+	}
+	if err := json.Unmarshal(data, &update); err != nil {
+		return update, fmt.Errorf("failed to decode trade update: %w", err)
+	}
+	return update, nil
+}
@@ -0,0 +1,137 @@
+package mockbroker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"zig-financial-engine/internal/broker"
+)
+
+// History keeps the bars replayed so far, for the historical bars endpoint
+type History struct {
+	mu   sync.RWMutex
+	bars map[string][]broker.StreamBar // By symbol, in time order
+}
+
+// NewHistory creates an empty bar history
+func NewHistory() *History {
+	return &History{bars: make(map[string][]broker.StreamBar)}
+}
+
+// Add records a bar, replacing any earlier bar with the same timestamp
+func (h *History) Add(bar broker.StreamBar) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	series := h.bars[bar.Symbol]
+	i := sort.Search(len(series), func(i int) bool { return !series[i].Timestamp.Before(bar.Timestamp) })
+	switch {
+	case i < len(series) && series[i].Timestamp.Equal(bar.Timestamp):
+		series[i] = bar
+	case i == len(series):
+		h.bars[bar.Symbol] = append(series, bar)
+	default:
+		series = append(series, broker.StreamBar{})
+		copy(series[i+1:], series[i:])
+		series[i] = bar
+		h.bars[bar.Symbol] = series
+	}
+}
+
+// Symbols lists the symbols with bars
+func (h *History) Symbols() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	symbols := make([]string, 0, len(h.bars))
+	for symbol := range h.bars {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Bars returns symbol's bars in [start, end] aggregated to timeframe; a zero
+// start or end leaves that side open
+func (h *History) Bars(symbol string, timeframe TimeFrame, start, end time.Time) []broker.StreamBar {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var out []broker.StreamBar
+	for _, bar := range h.bars[symbol] {
+		if !start.IsZero() && bar.Timestamp.Before(start) || !end.IsZero() && bar.Timestamp.After(end) {
+			continue
+		}
+		bucket := timeframe.bucket(bar.Timestamp)
+		if n := len(out); n > 0 && out[n-1].Timestamp.Equal(bucket) {
+			merged := &out[n-1]
+			if merged.Volume+bar.Volume > 0 {
+				merged.VWAP = (merged.VWAP*float64(merged.Volume) + bar.VWAP*float64(bar.Volume)) / float64(merged.Volume+bar.Volume)
+			}
+			if bar.High > merged.High {
+				merged.High = bar.High
+			}
+			if bar.Low < merged.Low {
+				merged.Low = bar.Low
+			}
+			merged.Close = bar.Close
+			merged.Volume += bar.Volume
+			merged.TradeCount += bar.TradeCount
+			continue
+		}
+		bar.Timestamp = bucket
+		out = append(out, bar)
+	}
+	return out
+}
+
+// TimeFrame is a bar size as the data API spells it ("1Min", "15Min",
+// "1Hour", "1Day")
+type TimeFrame struct {
+	N    int
+	Unit time.Duration // time.Minute, time.Hour or 24 * time.Hour
+}
+
+// ParseTimeFrame parses a data API timeframe; weeks and months are not
+// supported
+func ParseTimeFrame(s string) (TimeFrame, error) {
+	i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if i <= 0 {
+		return TimeFrame{}, fmt.Errorf("invalid timeframe %q", s)
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil || n < 1 {
+		return TimeFrame{}, fmt.Errorf("invalid timeframe %q", s)
+	}
+	switch s[i:] {
+	case "Min", "T":
+		return TimeFrame{N: n, Unit: time.Minute}, nil
+	case "Hour", "H":
+		return TimeFrame{N: n, Unit: time.Hour}, nil
+	case "Day", "D":
+		if n == 1 {
+			return TimeFrame{N: 1, Unit: 24 * time.Hour}, nil
+		}
+	}
+	return TimeFrame{}, fmt.Errorf("unsupported timeframe %q", s)
+}
+
+// exchange is the market's time zone, which daily bars are cut in
+var exchange = func() *time.Location {
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}()
+
+// bucket returns the start of the bar t falls in
+func (tf TimeFrame) bucket(t time.Time) time.Time {
+	if tf.Unit >= 24*time.Hour {
+		local := t.In(exchange)
+		return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, exchange).UTC()
+	}
+	return t.Truncate(time.Duration(tf.N) * tf.Unit).UTC()
+}
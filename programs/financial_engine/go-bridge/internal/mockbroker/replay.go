@@ -0,0 +1,159 @@
+package mockbroker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"zig-financial-engine/internal/broker"
+)
+
+// LoadEvents reads stream messages recorded from the Alpaca market data
+// stream, one object or array of objects per line. Messages other than
+// bars, trades and quotes are skipped. Events come back in time order.
+func LoadEvents(path string) ([]broker.Event, error) {
+	events, err := loadStreamMessages(path)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+// loadStreamMessages reads recorded stream messages
+func loadStreamMessages(path string) ([]broker.Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []broker.Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var messages []json.RawMessage
+		if line[0] == '[' {
+			if err := json.Unmarshal(line, &messages); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", path, n, err)
+			}
+		} else {
+			messages = []json.RawMessage{line}
+		}
+		for _, raw := range messages {
+			event, ok, err := parseStreamMessage(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", path, n, err)
+			}
+			if ok {
+				events = append(events, event)
+			}
+		}
+	}
+	return events, scanner.Err()
+}
+
+// parseStreamMessage decodes a bar, trade or quote message
+func parseStreamMessage(raw json.RawMessage) (broker.Event, bool, error) {
+	var header struct {
+		Type string `json:"T"`
+	}
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return broker.Event{}, false, err
+	}
+	switch header.Type {
+	case "b", "d", "u": // Minute, daily and updated bars
+		var bar broker.StreamBar
+		if err := json.Unmarshal(raw, &bar); err != nil {
+			return broker.Event{}, false, err
+		}
+		bar.Type, bar.Symbol = "b", strings.ToUpper(bar.Symbol)
+		return broker.Event{Time: bar.Timestamp, Symbol: bar.Symbol, Bar: &bar}, true, nil
+	case "t":
+		var trade broker.StreamTrade
+		if err := json.Unmarshal(raw, &trade); err != nil {
+			return broker.Event{}, false, err
+		}
+		trade.Symbol = strings.ToUpper(trade.Symbol)
+		return broker.Event{Time: trade.Timestamp, Symbol: trade.Symbol, Trade: &trade}, true, nil
+	case "q":
+		var quote broker.StreamQuote
+		if err := json.Unmarshal(raw, &quote); err != nil {
+			return broker.Event{}, false, err
+		}
+		quote.Symbol = strings.ToUpper(quote.Symbol)
+		return broker.Event{Time: quote.Timestamp, Symbol: quote.Symbol, Quote: &quote}, true, nil
+	}
+	return broker.Event{}, false, nil
+}
+
+// Replayer plays events into a simulator on a scaled clock. Events before
+// Start only set prices and bar history; later ones are also published to
+// stream subscribers.
+type Replayer struct {
+	Speed    float64       // Replay speed multiple of real time; 0 replays without pausing
+	MaxPause time.Duration // Longest wait between events, so overnight gaps pass quickly
+	Start    time.Time     // First streamed event (default: the first event)
+
+	events  []broker.Event
+	sim     *Simulator
+	history *History
+	publish func(broker.Event)
+}
+
+// NewReplayer replays events into sim, recording bars in history and
+// sending streamed events to publish
+func NewReplayer(events []broker.Event, sim *Simulator, history *History, publish func(broker.Event)) *Replayer {
+	return &Replayer{
+		Speed:    1,
+		MaxPause: 5 * time.Second,
+		events:   events,
+		sim:      sim,
+		history:  history,
+		publish:  publish,
+	}
+}
+
+// Run replays every event and returns when done or when ctx is canceled
+func (r *Replayer) Run(ctx context.Context) error {
+	var last time.Time
+	for _, event := range r.events {
+		streamed := r.Start.IsZero() || !event.Time.Before(r.Start)
+		if streamed && !last.IsZero() && r.Speed > 0 {
+			pause := time.Duration(float64(event.Time.Sub(last)) / r.Speed)
+			if r.MaxPause > 0 && pause > r.MaxPause {
+				pause = r.MaxPause
+			}
+			if pause > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(pause):
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		r.sim.Apply(event)
+		if event.Bar != nil {
+			r.history.Add(*event.Bar)
+		}
+		if streamed {
+			r.publish(event)
+			last = event.Time
+		}
+	}
+	return nil
+}
@@ -0,0 +1,640 @@
+package mockbroker
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/gorilla/websocket"
+
+	"zig-financial-engine/internal/broker"
+)
+
+// Server serves a simulator over the Alpaca REST and websocket APIs:
+//
+//	/v2/account, /v2/positions, /v2/orders, /v2/assets, /v2/clock  trading API
+//	/v2/stocks/bars, /v2/stocks/quotes/latest                     market data API
+//	/stream                                                       trade_updates stream
+//	/v2/iex, /v2/sip, /v2/test                                    market data stream
+//
+// One address therefore stands in for the trading host, the data host and
+// both stream hosts. Streams speak JSON only; clients requesting msgpack
+// (the Alpaca SDK's stream package) are not supported.
+type Server struct {
+	KeyID     string // Required API key; empty accepts any credentials
+	SecretKey string
+
+	sim      *Simulator
+	history  *History
+	logger   *log.Logger
+	upgrader websocket.Upgrader
+
+	mu           sync.Mutex
+	dataClients  map[*streamClient]bool
+	tradeClients map[*streamClient]bool
+}
+
+// streamClient is one websocket connection. Writes go through send so a
+// single goroutine owns the connection's writer.
+type streamClient struct {
+	conn          *websocket.Conn
+	send          chan interface{}
+	done          chan struct{}
+	authenticated bool
+	listening     bool                       // Trade stream: listening to trade_updates
+	subscriptions map[string]map[string]bool // Data stream: channel to symbols ("*" for all)
+}
+
+// streamBuffer is how many messages a client may fall behind before it is
+// disconnected
+const streamBuffer = 4096
+
+// NewServer serves sim, answering bar requests from history
+func NewServer(sim *Simulator, history *History) *Server {
+	s := &Server{
+		sim:          sim,
+		history:      history,
+		logger:       log.New(log.Writer(), "[MOCK-BROKER] ", log.LstdFlags),
+		upgrader:     websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }},
+		dataClients:  make(map[*streamClient]bool),
+		tradeClients: make(map[*streamClient]bool),
+	}
+	sim.Subscribe(s.publishTradeUpdate)
+	return s
+}
+
+// Handler returns the HTTP routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	rest := func(pattern string, handler http.HandlerFunc) {
+		mux.HandleFunc(pattern, s.authorize(handler))
+	}
+
+	rest("GET /v2/account", s.getAccount)
+	rest("GET /v2/positions", s.getPositions)
+	rest("DELETE /v2/positions", s.closeAllPositions)
+	rest("GET /v2/positions/{symbol}", s.getPosition)
+	rest("DELETE /v2/positions/{symbol}", s.closePosition)
+	rest("GET /v2/orders", s.getOrders)
+	rest("POST /v2/orders", s.placeOrder)
+	rest("DELETE /v2/orders", s.cancelAllOrders)
+	rest("GET /v2/orders:by_client_order_id", s.getOrderByClientID)
+	rest("GET /v2/orders/{id}", s.getOrder)
+	rest("PATCH /v2/orders/{id}", s.replaceOrder)
+	rest("DELETE /v2/orders/{id}", s.cancelOrder)
+	rest("GET /v2/assets", s.getAssets)
+	rest("GET /v2/assets/{symbol}", s.getAsset)
+	rest("GET /v2/clock", s.getClock)
+	rest("GET /v2/stocks/bars", s.getBars)
+	rest("GET /v2/stocks/{symbol}/bars", s.getSymbolBars)
+	rest("GET /v2/stocks/quotes/latest", s.getLatestQuotes)
+	rest("GET /v2/stocks/{symbol}/quotes/latest", s.getLatestQuote)
+
+	mux.HandleFunc("GET /stream", s.serveTradeStream)
+	for _, feed := range []string{"iex", "sip", "delayed_sip", "test"} {
+		mux.HandleFunc("GET /v2/"+feed, s.serveDataStream)
+	}
+	return mux
+}
+
+// authorize checks the API key headers when the server has credentials
+func (s *Server) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.credentialsOK(r.Header.Get("APCA-API-KEY-ID"), r.Header.Get("APCA-API-SECRET-KEY")) {
+			writeJSON(w, http.StatusUnauthorized, APIError{Code: 40110000, Message: "request is not authorized"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) credentialsOK(key, secret string) bool {
+	return s.KeyID == "" || key == s.KeyID && secret == s.SecretKey
+}
+
+// writeJSON writes v with status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an API error, or a 500 for anything else
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		writeJSON(w, apiErr.Status, apiErr)
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, APIError{Code: 50010000, Message: err.Error()})
+}
+
+// Trading API
+
+func (s *Server) getAccount(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.sim.Account())
+}
+
+func (s *Server) getPositions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.sim.Positions())
+}
+
+func (s *Server) getPosition(w http.ResponseWriter, r *http.Request) {
+	position, err := s.sim.Position(r.PathValue("symbol"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, position)
+}
+
+func (s *Server) closePosition(w http.ResponseWriter, r *http.Request) {
+	order, err := s.sim.ClosePosition(r.PathValue("symbol"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, order)
+}
+
+func (s *Server) closeAllPositions(w http.ResponseWriter, r *http.Request) {
+	cancelOrders, _ := strconv.ParseBool(r.URL.Query().Get("cancel_orders"))
+	type closeResponse struct {
+		Symbol string      `json:"symbol"`
+		Status int         `json:"status"`
+		Body   interface{} `json:"body"`
+	}
+	responses := []closeResponse{}
+	for _, result := range s.sim.CloseAllPositions(cancelOrders) {
+		var apiErr *APIError
+		switch {
+		case result.Err == nil:
+			responses = append(responses, closeResponse{result.Symbol, http.StatusOK, result.Order})
+		case errors.As(result.Err, &apiErr):
+			responses = append(responses, closeResponse{result.Symbol, apiErr.Status, apiErr})
+		default:
+			responses = append(responses, closeResponse{result.Symbol, http.StatusInternalServerError,
+				APIError{Code: 50010000, Message: result.Err.Error()}})
+		}
+	}
+	writeJSON(w, http.StatusMultiStatus, responses)
+}
+
+func (s *Server) getOrders(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := OrderQuery{
+		Status: params.Get("status"),
+		Limit:  50,
+		Desc:   params.Get("direction") != "asc",
+		Side:   alpaca.Side(params.Get("side")),
+	}
+	if limit, err := strconv.Atoi(params.Get("limit")); err == nil && limit > 0 {
+		q.Limit = min(limit, 500)
+	}
+	q.Nested, _ = strconv.ParseBool(params.Get("nested"))
+	q.After, _ = parseTime(params.Get("after"))
+	q.Until, _ = parseTime(params.Get("until"))
+	if symbols := params.Get("symbols"); symbols != "" {
+		q.Symbols = make(map[string]bool)
+		for _, symbol := range strings.Split(symbols, ",") {
+			q.Symbols[strings.ToUpper(strings.TrimSpace(symbol))] = true
+		}
+	}
+	orders := s.sim.Orders(q)
+	if orders == nil {
+		orders = []alpaca.Order{}
+	}
+	writeJSON(w, http.StatusOK, orders)
+}
+
+func (s *Server) getOrder(w http.ResponseWriter, r *http.Request) {
+	order, err := s.sim.Order(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, order)
+}
+
+func (s *Server) getOrderByClientID(w http.ResponseWriter, r *http.Request) {
+	order, err := s.sim.OrderByClientID(r.URL.Query().Get("client_order_id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, order)
+}
+
+func (s *Server) placeOrder(w http.ResponseWriter, r *http.Request) {
+	var req alpaca.PlaceOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, invalid("invalid order: %v", err))
+		return
+	}
+	order, err := s.sim.SubmitOrder(req)
+	if err != nil {
+		s.logger.Printf("Rejected %s %s %s: %v", req.Side, req.Type, req.Symbol, err)
+		writeError(w, err)
+		return
+	}
+	s.logger.Printf("Accepted %s %s %s %s: %s", order.Side, order.Type, order.Symbol, order.ID, order.Status)
+	writeJSON(w, http.StatusOK, order)
+}
+
+func (s *Server) replaceOrder(w http.ResponseWriter, r *http.Request) {
+	var req alpaca.ReplaceOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, invalid("invalid replacement: %v", err))
+		return
+	}
+	order, err := s.sim.ReplaceOrder(r.PathValue("id"), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, order)
+}
+
+func (s *Server) cancelOrder(w http.ResponseWriter, r *http.Request) {
+	if err := s.sim.CancelOrder(r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) cancelAllOrders(w http.ResponseWriter, r *http.Request) {
+	type cancelResponse struct {
+		ID     string `json:"id"`
+		Status int    `json:"status"`
+	}
+	responses := []cancelResponse{}
+	for _, id := range s.sim.CancelAllOrders() {
+		responses = append(responses, cancelResponse{ID: id, Status: http.StatusOK})
+	}
+	writeJSON(w, http.StatusMultiStatus, responses)
+}
+
+func (s *Server) getAssets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.sim.Assets())
+}
+
+func (s *Server) getAsset(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.sim.Asset(r.PathValue("symbol")))
+}
+
+func (s *Server) getClock(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.sim.Clock())
+}
+
+// Market data API
+
+// barsPage reads the shared bar query parameters and returns one page of
+// bars per symbol, with the next page token when more remain
+func (s *Server) barsPage(params map[string][]string, symbols []string) (map[string][]broker.StreamBar, *string, error) {
+	get := func(key string) string {
+		if values := params[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	timeframe, err := ParseTimeFrame(get("timeframe"))
+	if get("timeframe") == "" {
+		timeframe, err = TimeFrame{N: 1, Unit: 24 * time.Hour}, nil
+	}
+	if err != nil {
+		return nil, nil, invalid("%v", err)
+	}
+	start, err := parseTime(get("start"))
+	if err != nil {
+		return nil, nil, invalid("invalid start: %v", err)
+	}
+	end, err := parseTime(get("end"))
+	if err != nil {
+		return nil, nil, invalid("invalid end: %v", err)
+	}
+	limit := 1000
+	if n, err := strconv.Atoi(get("limit")); err == nil && n > 0 {
+		limit = min(n, 10000)
+	}
+	offset, _ := strconv.Atoi(get("page_token"))
+
+	// Pages run across symbols in request order, as the API's do
+	page := make(map[string][]broker.StreamBar)
+	skipped, taken := 0, 0
+	for _, symbol := range symbols {
+		bars := s.history.Bars(symbol, timeframe, start, end)
+		if get("sort") == "desc" {
+			for i, j := 0, len(bars)-1; i < j; i, j = i+1, j-1 {
+				bars[i], bars[j] = bars[j], bars[i]
+			}
+		}
+		if skip := min(offset-skipped, len(bars)); skip > 0 {
+			bars = bars[skip:]
+			skipped += skip
+		}
+		if len(bars) == 0 {
+			continue
+		}
+		if taken == limit {
+			next := strconv.Itoa(offset + taken)
+			return page, &next, nil
+		}
+		if len(bars) > limit-taken {
+			page[symbol] = bars[:limit-taken]
+			taken = limit
+			next := strconv.Itoa(offset + taken)
+			return page, &next, nil
+		}
+		page[symbol] = bars
+		taken += len(bars)
+	}
+	return page, nil, nil
+}
+
+func (s *Server) getBars(w http.ResponseWriter, r *http.Request) {
+	page, next, err := s.barsPage(r.URL.Query(), splitSymbols(r.URL.Query().Get("symbols")))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"bars": page, "next_page_token": next})
+}
+
+func (s *Server) getSymbolBars(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(r.PathValue("symbol"))
+	page, next, err := s.barsPage(r.URL.Query(), []string{symbol})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	bars := page[symbol]
+	if bars == nil {
+		bars = []broker.StreamBar{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"symbol": symbol, "bars": bars, "next_page_token": next})
+}
+
+// latestQuote renders the simulator's market as a REST quote
+func (s *Server) latestQuote(symbol string) (map[string]interface{}, bool) {
+	q, ok := s.sim.LatestQuote(symbol)
+	if !ok {
+		return nil, false
+	}
+	return map[string]interface{}{
+		"t": q.time, "bp": q.bid, "bs": q.bidSize, "bx": "V",
+		"ap": q.ask, "as": q.askSize, "ax": "V", "c": []string{"R"}, "z": "C",
+	}, true
+}
+
+func (s *Server) getLatestQuotes(w http.ResponseWriter, r *http.Request) {
+	quotes := make(map[string]interface{})
+	for _, symbol := range splitSymbols(r.URL.Query().Get("symbols")) {
+		if q, ok := s.latestQuote(symbol); ok {
+			quotes[symbol] = q
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"quotes": quotes})
+}
+
+func (s *Server) getLatestQuote(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(r.PathValue("symbol"))
+	q, ok := s.latestQuote(symbol)
+	if !ok {
+		writeError(w, errNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"symbol": symbol, "quote": q})
+}
+
+// splitSymbols parses a comma-separated symbol list
+func splitSymbols(list string) []string {
+	var symbols []string
+	for _, symbol := range strings.Split(list, ",") {
+		if symbol = strings.ToUpper(strings.TrimSpace(symbol)); symbol != "" {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// parseTime reads an RFC 3339 time or a date; empty is the zero time
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, exchange)
+}
+
+// Streams
+
+// accept upgrades a stream connection and starts its writer
+func (s *Server) accept(w http.ResponseWriter, r *http.Request) (*streamClient, bool) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Printf("Websocket upgrade failed: %v", err)
+		return nil, false
+	}
+	c := &streamClient{
+		conn:          conn,
+		send:          make(chan interface{}, streamBuffer),
+		done:          make(chan struct{}),
+		subscriptions: make(map[string]map[string]bool),
+	}
+	go func() {
+		for {
+			select {
+			case <-c.done:
+				return
+			case msg := <-c.send:
+				if err := conn.WriteJSON(msg); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+	return c, true
+}
+
+// enqueue queues msg for c, dropping a client that has fallen too far
+// behind. Callers hold s.mu or own c before it is registered.
+func (c *streamClient) enqueue(msg interface{}) {
+	select {
+	case c.send <- msg:
+	default:
+		c.conn.Close()
+	}
+}
+
+// drop unregisters and stops a client
+func (s *Server) drop(c *streamClient, clients map[*streamClient]bool) {
+	s.mu.Lock()
+	delete(clients, c)
+	s.mu.Unlock()
+	close(c.done)
+	c.conn.Close()
+}
+
+// serveTradeStream speaks the trade_updates protocol: auth, then listen
+func (s *Server) serveTradeStream(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.accept(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	s.tradeClients[c] = true
+	s.mu.Unlock()
+	defer s.drop(c, s.tradeClients)
+
+	type reply struct {
+		Stream string      `json:"stream"`
+		Data   interface{} `json:"data"`
+	}
+	for {
+		var msg struct {
+			Action string `json:"action"`
+			Key    string `json:"key"`
+			Secret string `json:"secret"`
+			Data   struct {
+				KeyID     string   `json:"key_id"`
+				SecretKey string   `json:"secret_key"`
+				Streams   []string `json:"streams"`
+			} `json:"data"`
+		}
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		switch msg.Action {
+		case "auth", "authenticate":
+			key, secret := msg.Key, msg.Secret
+			if key == "" {
+				key, secret = msg.Data.KeyID, msg.Data.SecretKey
+			}
+			c.authenticated = s.credentialsOK(key, secret)
+			status := "authorized"
+			if !c.authenticated {
+				status = "unauthorized"
+			}
+			c.enqueue(reply{"authorization", map[string]string{"status": status, "action": "authenticate"}})
+		case "listen":
+			if !c.authenticated {
+				c.enqueue(reply{"authorization", map[string]string{"status": "unauthorized", "action": "listen"}})
+				break
+			}
+			streams := []string{}
+			for _, stream := range msg.Data.Streams {
+				if stream == "trade_updates" {
+					c.listening = true
+					streams = append(streams, stream)
+				}
+			}
+			c.enqueue(reply{"listening", map[string][]string{"streams": streams}})
+		}
+		s.mu.Unlock()
+	}
+}
+
+// publishTradeUpdate sends a simulator event to listening clients
+func (s *Server) publishTradeUpdate(update TradeUpdate) {
+	msg := map[string]interface{}{"stream": "trade_updates", "data": update}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.tradeClients {
+		if c.listening {
+			c.enqueue(msg)
+		}
+	}
+}
+
+// serveDataStream speaks the market data protocol: connected, auth, then
+// subscribe and unsubscribe
+func (s *Server) serveDataStream(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.accept(w, r)
+	if !ok {
+		return
+	}
+	c.enqueue([]map[string]string{{"T": "success", "msg": "connected"}})
+	s.mu.Lock()
+	s.dataClients[c] = true
+	s.mu.Unlock()
+	defer s.drop(c, s.dataClients)
+
+	for {
+		var msg struct {
+			Action string   `json:"action"`
+			Key    string   `json:"key"`
+			Secret string   `json:"secret"`
+			Trades []string `json:"trades"`
+			Quotes []string `json:"quotes"`
+			Bars   []string `json:"bars"`
+		}
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		switch msg.Action {
+		case "auth":
+			if c.authenticated = s.credentialsOK(msg.Key, msg.Secret); c.authenticated {
+				c.enqueue([]map[string]string{{"T": "success", "msg": "authenticated"}})
+			} else {
+				c.enqueue([]map[string]interface{}{{"T": "error", "code": 402, "msg": "auth failed"}})
+			}
+		case "subscribe", "unsubscribe":
+			if !c.authenticated {
+				c.enqueue([]map[string]interface{}{{"T": "error", "code": 401, "msg": "not authenticated"}})
+				break
+			}
+			for channel, symbols := range map[string][]string{"trades": msg.Trades, "quotes": msg.Quotes, "bars": msg.Bars} {
+				if c.subscriptions[channel] == nil {
+					c.subscriptions[channel] = make(map[string]bool)
+				}
+				for _, symbol := range symbols {
+					if msg.Action == "subscribe" {
+						c.subscriptions[channel][strings.ToUpper(symbol)] = true
+					} else {
+						delete(c.subscriptions[channel], strings.ToUpper(symbol))
+					}
+				}
+			}
+			ack := map[string]interface{}{"T": "subscription"}
+			for _, channel := range []string{"trades", "quotes", "bars"} {
+				symbols := []string{}
+				for symbol := range c.subscriptions[channel] {
+					symbols = append(symbols, symbol)
+				}
+				ack[channel] = symbols
+			}
+			c.enqueue([]map[string]interface{}{ack})
+		default:
+			c.enqueue([]map[string]interface{}{{"T": "error", "code": 400, "msg": "invalid syntax"}})
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Publish sends a replayed event to the data stream clients subscribed to
+// its symbol and channel
+func (s *Server) Publish(event broker.Event) {
+	msg := []interface{}{event.Message()}
+	kind := event.Kind()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.dataClients {
+		if subs := c.subscriptions[kind]; subs[event.Symbol] || subs["*"] {
+			c.enqueue(msg)
+		}
+	}
+}
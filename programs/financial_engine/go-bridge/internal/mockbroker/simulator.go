@@ -0,0 +1,1055 @@
+// Package mockbroker simulates the part of the Alpaca trading and market
+// data APIs the engine uses, so strategies and commands can run end to end
+// without network access. A Simulator keeps the account, orders and
+// positions and fills orders against replayed quotes, trades and bars; a
+// Server exposes it through Alpaca-compatible REST and websocket endpoints.
+package mockbroker
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
+)
+
+// Config sets up the simulated account
+type Config struct {
+	Cash        float64 // Starting cash
+	Multiplier  float64 // Buying power as a multiple of equity: 1 cash, 2 Reg T, 4 day trading
+	SlippageBps float64 // Adverse move applied to market and triggered stop fills
+	NoShorting  bool    // Reject sells larger than the long position
+}
+
+// DefaultConfig is a $100k Reg T margin account with 1bp of slippage
+func DefaultConfig() Config {
+	return Config{Cash: 100000, Multiplier: 2, SlippageBps: 1}
+}
+
+// TradeUpdate is one trade_updates stream event
+type TradeUpdate struct {
+	Event       string       `json:"event"`
+	ExecutionID string       `json:"execution_id,omitempty"`
+	Order       alpaca.Order `json:"order"`
+	Timestamp   time.Time    `json:"timestamp"`
+	Price       string       `json:"price,omitempty"`
+	Qty         string       `json:"qty,omitempty"`
+	PositionQty string       `json:"position_qty,omitempty"`
+}
+
+// APIError is an error the REST API reports with an HTTP status, in
+// Alpaca's {"code", "message"} shape
+type APIError struct {
+	Status  int    `json:"-"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// Alpaca error codes the simulator returns
+var (
+	errNotFound           = &APIError{Status: 404, Code: 40410000, Message: "resource not found"}
+	errInsufficientFunds  = &APIError{Status: 403, Code: 40310000, Message: "insufficient buying power"}
+	errInsufficientQty    = &APIError{Status: 403, Code: 40310000, Message: "insufficient qty available for order"}
+	errOrderNotCancelable = &APIError{Status: 422, Code: 42210000, Message: "order is not cancelable"}
+)
+
+// invalid reports a malformed request
+func invalid(format string, args ...interface{}) *APIError {
+	return &APIError{Status: 422, Code: 40010001, Message: fmt.Sprintf(format, args...)}
+}
+
+// tick is what one market data event says about prices. Buys are checked
+// against the ask range and sells against the bid range; a trade or bar
+// sets both sides.
+type tick struct {
+	buyLow, buyHigh, buyRef    float64
+	sellLow, sellHigh, sellRef float64
+}
+
+// quote is the latest known market for a symbol
+type quote struct {
+	bid, ask, last float64
+	bidSize        uint32
+	askSize        uint32
+	time           time.Time
+}
+
+// position is a net holding; qty is negative for shorts
+type position struct {
+	qty, avgPrice float64
+}
+
+// order is an order with its simulation state
+type order struct {
+	alpaca.Order
+	qty, notional   float64
+	limit, stop     float64
+	triggered       bool     // A stop order's stop was reached
+	filledValue     float64  // Sum of fill qty * price
+	parentID        string   // Set on bracket legs
+	legIDs          []string // Set on bracket parents
+	immediateOrKill bool     // IOC and FOK orders cancel when they cannot fill on arrival
+}
+
+// Simulator is an in-memory brokerage account. Market data is fed in with
+// Apply, which moves the simulated clock and fills resting orders; order
+// and position changes are published as trade updates.
+type Simulator struct {
+	config Config
+
+	mu          sync.Mutex
+	now         time.Time
+	cash        float64
+	startEquity float64
+	accountID   string
+	createdAt   time.Time
+	quotes      map[string]*quote
+	positions   map[string]*position
+	orders      map[string]*order
+	sequence    []string // Order IDs in submission order
+	pending     []TradeUpdate
+	subscribers []func(TradeUpdate)
+}
+
+// NewSimulator opens an account with config's cash
+func NewSimulator(config Config) *Simulator {
+	if config.Multiplier <= 0 {
+		config.Multiplier = 1
+	}
+	return &Simulator{
+		config:      config,
+		cash:        config.Cash,
+		startEquity: config.Cash,
+		accountID:   newID(),
+		createdAt:   time.Now().UTC(),
+		quotes:      make(map[string]*quote),
+		positions:   make(map[string]*position),
+		orders:      make(map[string]*order),
+	}
+}
+
+// Subscribe registers a trade update handler. Handlers run outside the
+// simulator's lock, in event order.
+func (s *Simulator) Subscribe(handler func(TradeUpdate)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, handler)
+}
+
+// unlock releases the lock and publishes the events queued under it
+func (s *Simulator) unlock() {
+	events, subscribers := s.pending, s.subscribers
+	s.pending = nil
+	s.mu.Unlock()
+	for _, event := range events {
+		for _, handler := range subscribers {
+			handler(event)
+		}
+	}
+}
+
+// Now returns the simulated clock: the time of the latest market event, or
+// the wall clock before any data arrives
+func (s *Simulator) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clock()
+}
+
+func (s *Simulator) clock() time.Time {
+	if s.now.IsZero() {
+		return time.Now().UTC()
+	}
+	return s.now
+}
+
+// Apply feeds one market data event: it advances the clock, updates the
+// symbol's market and fills any orders the new prices reach
+func (s *Simulator) Apply(event broker.Event) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	if event.Time.After(s.now) {
+		s.now = event.Time
+	}
+	q := s.quotes[event.Symbol]
+	if q == nil {
+		q = &quote{}
+		s.quotes[event.Symbol] = q
+	}
+	q.time = event.Time
+
+	var t tick
+	switch {
+	case event.Quote != nil:
+		q.bid, q.ask = event.Quote.BidPrice, event.Quote.AskPrice
+		q.bidSize, q.askSize = event.Quote.BidSize, event.Quote.AskSize
+		if q.bid <= 0 || q.ask <= 0 {
+			return
+		}
+		t = tick{q.ask, q.ask, q.ask, q.bid, q.bid, q.bid}
+	case event.Trade != nil:
+		q.last = event.Trade.Price
+		p := event.Trade.Price
+		t = tick{p, p, p, p, p, p}
+	case event.Bar != nil:
+		q.last = event.Bar.Close
+		b := event.Bar
+		t = tick{b.Low, b.High, b.Close, b.Low, b.High, b.Close}
+	default:
+		return
+	}
+	s.matchSymbol(event.Symbol, t)
+}
+
+// currentTick is the market an arriving order meets
+func (s *Simulator) currentTick(symbol string) (tick, bool) {
+	q := s.quotes[symbol]
+	switch {
+	case q == nil:
+		return tick{}, false
+	case q.bid > 0 && q.ask > 0:
+		return tick{q.ask, q.ask, q.ask, q.bid, q.bid, q.bid}, true
+	case q.last > 0:
+		return tick{q.last, q.last, q.last, q.last, q.last, q.last}, true
+	}
+	return tick{}, false
+}
+
+// price is the latest mark for a symbol: the last trade, else the mid
+func (s *Simulator) price(symbol string) float64 {
+	q := s.quotes[symbol]
+	switch {
+	case q == nil:
+		return 0
+	case q.last > 0:
+		return q.last
+	case q.bid > 0 && q.ask > 0:
+		return (q.bid + q.ask) / 2
+	}
+	return 0
+}
+
+// matchSymbol fills the symbol's working orders that t reaches, oldest
+// first. Exits released by a fill wait for the next event, since a bar's
+// range may have been traded before the entry.
+func (s *Simulator) matchSymbol(symbol string, t tick) {
+	var working []*order
+	for _, id := range s.sequence {
+		if o := s.orders[id]; o.Symbol == symbol && o.Status == "new" {
+			working = append(working, o)
+		}
+	}
+	for _, o := range working {
+		if o.Status != "new" {
+			continue // Canceled by a sibling's fill
+		}
+		if price, ok := s.fillPrice(o, t); ok {
+			s.fill(o, price)
+		}
+	}
+}
+
+// fillPrice returns the price o fills at against t, if it fills. Limits fill
+// at the limit or better; stops trigger on the stop price and then fill as
+// market orders, at the stop or worse.
+func (s *Simulator) fillPrice(o *order, t tick) (float64, bool) {
+	buy := o.Side == alpaca.Buy
+	low, high, ref := t.sellLow, t.sellHigh, t.sellRef
+	if buy {
+		low, high, ref = t.buyLow, t.buyHigh, t.buyRef
+	}
+	if ref <= 0 {
+		return 0, false
+	}
+	slip := s.config.SlippageBps / 10000
+	if !buy {
+		slip = -slip
+	}
+
+	if o.Type == alpaca.Stop || o.Type == alpaca.StopLimit {
+		if !o.triggered {
+			if buy && high < o.stop || !buy && low > o.stop {
+				return 0, false
+			}
+			o.triggered = true
+		}
+		if o.Type == alpaca.Stop {
+			if buy {
+				return math.Max(o.stop, ref) * (1 + slip), true
+			}
+			return math.Min(o.stop, ref) * (1 + slip), true
+		}
+	}
+
+	switch o.Type {
+	case alpaca.Market:
+		return ref * (1 + slip), true
+	case alpaca.Limit, alpaca.StopLimit:
+		if buy && low <= o.limit {
+			return math.Min(o.limit, ref), true
+		}
+		if !buy && high >= o.limit {
+			return math.Max(o.limit, ref), true
+		}
+	}
+	return 0, false
+}
+
+// fill executes the rest of o at price and updates the book
+func (s *Simulator) fill(o *order, price float64) {
+	now := s.clock()
+	qty := o.qty - o.FilledQty.InexactFloat64()
+	if o.notional > 0 && o.qty == 0 {
+		qty = math.Floor(o.notional/price*1e9) / 1e9
+		o.qty = qty
+		o.Qty = decimalPtr(qty)
+	}
+	if qty <= 0 {
+		return
+	}
+
+	signed := qty
+	if o.Side == alpaca.Sell {
+		signed = -qty
+	}
+	p := s.positions[o.Symbol]
+	if p == nil {
+		p = &position{}
+		s.positions[o.Symbol] = p
+	}
+	newQty := p.qty + signed
+	switch {
+	case p.qty == 0 || p.qty*signed > 0:
+		p.avgPrice = (p.avgPrice*math.Abs(p.qty) + price*qty) / math.Abs(newQty)
+	case p.qty*newQty < 0:
+		p.avgPrice = price // Flipped through flat
+	}
+	p.qty = newQty
+	if math.Abs(p.qty) < 1e-9 {
+		delete(s.positions, o.Symbol)
+		p.qty = 0
+	}
+	s.cash -= signed * price
+
+	o.filledValue += qty * price
+	filled := o.FilledQty.InexactFloat64() + qty
+	o.FilledQty = decimal.NewFromFloat(filled)
+	o.FilledAvgPrice = decimalPtr(o.filledValue / filled)
+	o.Status = "filled"
+	o.FilledAt = &now
+	o.UpdatedAt = now
+	s.emit("fill", o, qty, price, p.qty)
+
+	// A filled bracket parent releases its exits; a filled exit cancels
+	// its sibling
+	for _, id := range o.legIDs {
+		if leg := s.orders[id]; leg.Status == "held" {
+			leg.Status = "new"
+			leg.UpdatedAt = now
+			s.emit("new", leg, 0, 0, 0)
+		}
+	}
+	if parent := s.orders[o.parentID]; parent != nil {
+		for _, id := range parent.legIDs {
+			if sibling := s.orders[id]; id != o.ID && isOpen(sibling.Status) {
+				s.cancel(sibling, "canceled")
+			}
+		}
+	}
+}
+
+// cancel closes an open order with status (canceled or replaced)
+func (s *Simulator) cancel(o *order, status string) {
+	now := s.clock()
+	o.Status = status
+	o.UpdatedAt = now
+	if status == "canceled" {
+		o.CanceledAt = &now
+	} else {
+		o.ReplacedAt = &now
+	}
+	s.emit(status, o, 0, 0, 0)
+	for _, id := range o.legIDs {
+		if leg := s.orders[id]; isOpen(leg.Status) {
+			s.cancel(leg, "canceled")
+		}
+	}
+}
+
+// emit queues a trade update carrying a snapshot of o
+func (s *Simulator) emit(event string, o *order, qty, price, positionQty float64) {
+	update := TradeUpdate{Event: event, Order: s.snapshot(o), Timestamp: s.clock()}
+	if event == "fill" || event == "partial_fill" {
+		update.ExecutionID = newID()
+		update.Qty = decimal.NewFromFloat(qty).String()
+		update.Price = decimal.NewFromFloat(price).String()
+		update.PositionQty = decimal.NewFromFloat(positionQty).String()
+	}
+	s.pending = append(s.pending, update)
+}
+
+// snapshot copies o for the API, with its legs nested
+func (s *Simulator) snapshot(o *order) alpaca.Order {
+	out := o.Order
+	out.Legs = nil
+	for _, id := range o.legIDs {
+		leg := s.orders[id].Order
+		leg.Legs = nil
+		out.Legs = append(out.Legs, leg)
+	}
+	return out
+}
+
+// isOpen reports whether an order status can still fill or be canceled
+func isOpen(status string) bool {
+	switch status {
+	case "new", "partially_filled", "held", "accepted", "pending_new":
+		return true
+	}
+	return false
+}
+
+// SubmitOrder validates and accepts an order, filling it at once when the
+// market allows
+func (s *Simulator) SubmitOrder(req alpaca.PlaceOrderRequest) (alpaca.Order, error) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	o, err := s.newOrder(req)
+	if err != nil {
+		return alpaca.Order{}, err
+	}
+	if err := s.checkFunds(o); err != nil {
+		return alpaca.Order{}, err
+	}
+
+	switch req.OrderClass {
+	case "", alpaca.Simple:
+	case alpaca.Bracket:
+		if err := s.addBracketLegs(o, req); err != nil {
+			return alpaca.Order{}, err
+		}
+	default:
+		return alpaca.Order{}, invalid("order_class %q is not supported", req.OrderClass)
+	}
+
+	s.accept(o)
+	for _, id := range o.legIDs {
+		s.accept(s.orders[id])
+	}
+	s.emit("new", o, 0, 0, 0)
+
+	if t, ok := s.currentTick(o.Symbol); ok {
+		if price, ok := s.fillPrice(o, t); ok {
+			s.fill(o, price)
+		}
+	}
+	if o.immediateOrKill && isOpen(o.Status) {
+		s.cancel(o, "canceled")
+	}
+	return s.snapshot(o), nil
+}
+
+// newOrder builds an order from a request
+func (s *Simulator) newOrder(req alpaca.PlaceOrderRequest) (*order, error) {
+	symbol := strings.ToUpper(req.Symbol)
+	if symbol == "" {
+		return nil, invalid("symbol is required")
+	}
+	if req.Side != alpaca.Buy && req.Side != alpaca.Sell {
+		return nil, invalid("side must be buy or sell")
+	}
+	if req.Type == "" {
+		req.Type = alpaca.Market
+	}
+	if req.TimeInForce == "" {
+		req.TimeInForce = alpaca.Day
+	}
+
+	o := &order{}
+	switch {
+	case req.Qty != nil && req.Qty.IsPositive():
+		o.qty = req.Qty.InexactFloat64()
+	case req.Notional != nil && req.Notional.IsPositive() && req.Type == alpaca.Market:
+		o.notional = req.Notional.InexactFloat64()
+	default:
+		return nil, invalid("qty or, for market orders, notional is required")
+	}
+
+	switch req.Type {
+	case alpaca.Market:
+	case alpaca.Limit:
+		if req.LimitPrice == nil {
+			return nil, invalid("limit_price is required")
+		}
+	case alpaca.Stop:
+		if req.StopPrice == nil {
+			return nil, invalid("stop_price is required")
+		}
+	case alpaca.StopLimit:
+		if req.LimitPrice == nil || req.StopPrice == nil {
+			return nil, invalid("limit_price and stop_price are required")
+		}
+	default:
+		return nil, invalid("order type %q is not supported", req.Type)
+	}
+	if req.LimitPrice != nil {
+		o.limit = req.LimitPrice.InexactFloat64()
+	}
+	if req.StopPrice != nil {
+		o.stop = req.StopPrice.InexactFloat64()
+	}
+	o.immediateOrKill = req.TimeInForce == alpaca.IOC || req.TimeInForce == alpaca.FOK
+
+	o.Order = alpaca.Order{
+		ClientOrderID:  req.ClientOrderID,
+		AssetID:        assetID(symbol),
+		Symbol:         symbol,
+		AssetClass:     alpaca.USEquity,
+		OrderClass:     req.OrderClass,
+		Type:           req.Type,
+		Side:           req.Side,
+		PositionIntent: req.PositionIntent,
+		TimeInForce:    req.TimeInForce,
+		Qty:            req.Qty,
+		Notional:       req.Notional,
+		LimitPrice:     req.LimitPrice,
+		StopPrice:      req.StopPrice,
+		ExtendedHours:  req.ExtendedHours,
+	}
+	return o, nil
+}
+
+// addBracketLegs creates a bracket's take-profit and stop-loss exits. They
+// are held until the entry fills and cancel each other.
+func (s *Simulator) addBracketLegs(o *order, req alpaca.PlaceOrderRequest) error {
+	if o.qty == 0 {
+		return invalid("bracket orders need a qty")
+	}
+	if req.TakeProfit == nil || req.TakeProfit.LimitPrice == nil || req.StopLoss == nil || req.StopLoss.StopPrice == nil {
+		return invalid("bracket orders need take_profit.limit_price and stop_loss.stop_price")
+	}
+	exit := alpaca.Sell
+	if o.Side == alpaca.Sell {
+		exit = alpaca.Buy
+	}
+	takeProfit := &order{qty: o.qty, limit: req.TakeProfit.LimitPrice.InexactFloat64(), parentID: "pending"}
+	takeProfit.Order = alpaca.Order{
+		Type:       alpaca.Limit,
+		LimitPrice: req.TakeProfit.LimitPrice,
+	}
+	stopLoss := &order{qty: o.qty, stop: req.StopLoss.StopPrice.InexactFloat64(), parentID: "pending"}
+	stopLoss.Order = alpaca.Order{
+		Type:      alpaca.Stop,
+		StopPrice: req.StopLoss.StopPrice,
+	}
+	if req.StopLoss.LimitPrice != nil {
+		stopLoss.Type = alpaca.StopLimit
+		stopLoss.limit = req.StopLoss.LimitPrice.InexactFloat64()
+		stopLoss.LimitPrice = req.StopLoss.LimitPrice
+	}
+	for _, leg := range []*order{takeProfit, stopLoss} {
+		leg.AssetID, leg.Symbol, leg.AssetClass = o.AssetID, o.Symbol, o.AssetClass
+		leg.OrderClass, leg.Side, leg.TimeInForce = alpaca.Bracket, exit, o.TimeInForce
+		leg.Qty = o.Qty
+		leg.ID = newID()
+		o.legIDs = append(o.legIDs, leg.ID)
+		s.orders[leg.ID] = leg
+	}
+	return nil
+}
+
+// accept assigns identity and timestamps and indexes the order
+func (s *Simulator) accept(o *order) {
+	now := s.clock()
+	if o.ID == "" {
+		o.ID = newID()
+	}
+	if o.ClientOrderID == "" {
+		o.ClientOrderID = newID()
+	}
+	o.CreatedAt, o.UpdatedAt, o.SubmittedAt = now, now, now
+	o.FilledQty = decimal.Zero
+	o.Status = "new"
+	if o.parentID != "" {
+		o.Status = "held"
+	}
+	s.orders[o.ID] = o
+	s.sequence = append(s.sequence, o.ID)
+	for _, id := range o.legIDs {
+		s.orders[id].parentID = o.ID
+	}
+}
+
+// checkFunds rejects orders that open more exposure than buying power
+// covers and, without shorting, sells beyond the long position. Reducing a
+// position is always allowed.
+func (s *Simulator) checkFunds(o *order) error {
+	price := o.limit
+	if price == 0 {
+		price = math.Max(o.stop, s.price(o.Symbol))
+	}
+	held := 0.0
+	if p := s.positions[o.Symbol]; p != nil {
+		held = p.qty
+	}
+
+	opening := o.qty - math.Max(-held, 0)
+	if o.Side == alpaca.Sell {
+		if s.config.NoShorting && o.qty > held+1e-9 {
+			return errInsufficientQty
+		}
+		opening = o.qty - math.Max(held, 0)
+	}
+	cost := opening * price
+	if o.notional > 0 {
+		cost = o.notional
+	}
+	if cost > s.buyingPower()+1e-9 {
+		return errInsufficientFunds
+	}
+	return nil
+}
+
+// equity and gross exposure at the latest marks
+func (s *Simulator) marks() (equity, long, short float64) {
+	equity = s.cash
+	for symbol, p := range s.positions {
+		value := p.qty * s.markOr(symbol, p.avgPrice)
+		equity += value
+		if value > 0 {
+			long += value
+		} else {
+			short += value
+		}
+	}
+	return equity, long, short
+}
+
+// markOr is the symbol's mark, or fallback before any data
+func (s *Simulator) markOr(symbol string, fallback float64) float64 {
+	if price := s.price(symbol); price > 0 {
+		return price
+	}
+	return fallback
+}
+
+// buyingPower is equity times the multiplier less gross exposure
+func (s *Simulator) buyingPower() float64 {
+	equity, long, short := s.marks()
+	return math.Max(0, equity*s.config.Multiplier-long+short)
+}
+
+// Account reports balances at the latest marks
+func (s *Simulator) Account() alpaca.Account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	equity, long, short := s.marks()
+	buyingPower := decimal.NewFromFloat(s.buyingPower()).Round(2)
+	return alpaca.Account{
+		ID:                    s.accountID,
+		AccountNumber:         "MOCK" + strings.ToUpper(s.accountID[:8]),
+		Status:                "ACTIVE",
+		CryptoStatus:          "INACTIVE",
+		Currency:              "USD",
+		BuyingPower:           buyingPower,
+		RegTBuyingPower:       buyingPower,
+		DaytradingBuyingPower: buyingPower,
+		EffectiveBuyingPower:  buyingPower,
+		NonMarginBuyingPower:  decimal.NewFromFloat(math.Max(0, s.cash)).Round(2),
+		Cash:                  decimal.NewFromFloat(s.cash).Round(2),
+		PortfolioValue:        decimal.NewFromFloat(equity).Round(2),
+		ShortingEnabled:       !s.config.NoShorting,
+		CreatedAt:             s.createdAt,
+		Multiplier:            decimal.NewFromFloat(s.config.Multiplier),
+		Equity:                decimal.NewFromFloat(equity).Round(2),
+		LastEquity:            decimal.NewFromFloat(s.startEquity).Round(2),
+		LongMarketValue:       decimal.NewFromFloat(long).Round(2),
+		ShortMarketValue:      decimal.NewFromFloat(short).Round(2),
+		PositionMarketValue:   decimal.NewFromFloat(long - short).Round(2),
+	}
+}
+
+// Positions lists open positions by symbol
+func (s *Simulator) Positions() []alpaca.Position {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	symbols := make([]string, 0, len(s.positions))
+	for symbol := range s.positions {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	positions := make([]alpaca.Position, 0, len(symbols))
+	for _, symbol := range symbols {
+		positions = append(positions, s.positionLocked(symbol))
+	}
+	return positions
+}
+
+// Position reports one symbol's position
+func (s *Simulator) Position(symbol string) (alpaca.Position, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	symbol = strings.ToUpper(symbol)
+	if s.positions[symbol] == nil {
+		return alpaca.Position{}, &APIError{Status: 404, Code: 40410000, Message: "position does not exist"}
+	}
+	return s.positionLocked(symbol), nil
+}
+
+func (s *Simulator) positionLocked(symbol string) alpaca.Position {
+	p := s.positions[symbol]
+	price := s.markOr(symbol, p.avgPrice)
+	side := "long"
+	if p.qty < 0 {
+		side = "short"
+	}
+	costBasis := p.qty * p.avgPrice
+	marketValue := p.qty * price
+	pl := marketValue - costBasis
+	plpc := 0.0
+	if costBasis != 0 {
+		plpc = pl / math.Abs(costBasis)
+	}
+	return alpaca.Position{
+		AssetID:         assetID(symbol),
+		Symbol:          symbol,
+		Exchange:        "NASDAQ",
+		AssetClass:      alpaca.USEquity,
+		AssetMarginable: true,
+		Qty:             decimal.NewFromFloat(p.qty),
+		QtyAvailable:    decimal.NewFromFloat(p.qty),
+		AvgEntryPrice:   decimal.NewFromFloat(p.avgPrice),
+		Side:            side,
+		MarketValue:     decimalPtr(marketValue),
+		CostBasis:       decimal.NewFromFloat(costBasis),
+		UnrealizedPL:    decimalPtr(pl),
+		UnrealizedPLPC:  decimalPtr(plpc),
+		CurrentPrice:    decimalPtr(price),
+	}
+}
+
+// OrderQuery selects orders the way GET /v2/orders does
+type OrderQuery struct {
+	Status  string // open (default), closed or all
+	Limit   int
+	After   time.Time
+	Until   time.Time
+	Desc    bool
+	Nested  bool // Report bracket legs under their parent only
+	Symbols map[string]bool
+	Side    alpaca.Side
+}
+
+// Orders lists orders matching q, newest first unless q says otherwise
+func (s *Simulator) Orders(q OrderQuery) []alpaca.Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var orders []alpaca.Order
+	for i := len(s.sequence) - 1; i >= 0; i-- {
+		o := s.orders[s.sequence[i]]
+		open := isOpen(o.Status)
+		switch {
+		case q.Status == "closed" && open, (q.Status == "" || q.Status == "open") && !open:
+			continue
+		case q.Nested && o.parentID != "":
+			continue
+		case len(q.Symbols) > 0 && !q.Symbols[o.Symbol]:
+			continue
+		case q.Side != "" && o.Side != q.Side:
+			continue
+		case !q.After.IsZero() && !o.SubmittedAt.After(q.After), !q.Until.IsZero() && !o.SubmittedAt.Before(q.Until):
+			continue
+		}
+		if q.Nested {
+			orders = append(orders, s.snapshot(o))
+		} else {
+			flat := o.Order
+			flat.Legs = nil
+			orders = append(orders, flat)
+		}
+	}
+	if !q.Desc {
+		for i, j := 0, len(orders)-1; i < j; i, j = i+1, j-1 {
+			orders[i], orders[j] = orders[j], orders[i]
+		}
+	}
+	if q.Limit > 0 && len(orders) > q.Limit {
+		if q.Desc {
+			orders = orders[:q.Limit]
+		} else {
+			orders = orders[len(orders)-q.Limit:]
+		}
+	}
+	return orders
+}
+
+// Order looks an order up by ID
+func (s *Simulator) Order(id string) (alpaca.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.orders[id]
+	if o == nil {
+		return alpaca.Order{}, errNotFound
+	}
+	return s.snapshot(o), nil
+}
+
+// OrderByClientID looks an order up by client order ID
+func (s *Simulator) OrderByClientID(clientID string) (alpaca.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.sequence) - 1; i >= 0; i-- {
+		if o := s.orders[s.sequence[i]]; o.ClientOrderID == clientID {
+			return s.snapshot(o), nil
+		}
+	}
+	return alpaca.Order{}, errNotFound
+}
+
+// CancelOrder cancels an open order and its bracket legs
+func (s *Simulator) CancelOrder(id string) error {
+	s.mu.Lock()
+	defer s.unlock()
+	o := s.orders[id]
+	if o == nil {
+		return errNotFound
+	}
+	if !isOpen(o.Status) {
+		return errOrderNotCancelable
+	}
+	s.cancel(o, "canceled")
+	return nil
+}
+
+// CancelAllOrders cancels every open order and returns their IDs
+func (s *Simulator) CancelAllOrders() []string {
+	s.mu.Lock()
+	defer s.unlock()
+	var ids []string
+	for _, id := range s.sequence {
+		if o := s.orders[id]; isOpen(o.Status) && o.parentID == "" {
+			s.cancel(o, "canceled")
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ReplaceOrder replaces an open order's qty, prices or time in force. The
+// old order ends as replaced and the new one references it.
+func (s *Simulator) ReplaceOrder(id string, req alpaca.ReplaceOrderRequest) (alpaca.Order, error) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	old := s.orders[id]
+	if old == nil {
+		return alpaca.Order{}, errNotFound
+	}
+	if old.Status != "new" || len(old.legIDs) > 0 || old.parentID != "" {
+		return alpaca.Order{}, errOrderNotCancelable
+	}
+
+	o := &order{qty: old.qty, notional: old.notional, limit: old.limit, stop: old.stop, immediateOrKill: old.immediateOrKill}
+	o.Order = old.Order
+	o.ID, o.ClientOrderID = "", req.ClientOrderID
+	o.Replaces, o.ReplacedBy, o.ReplacedAt = &old.ID, nil, nil
+	if req.Qty != nil {
+		if !req.Qty.IsPositive() {
+			return alpaca.Order{}, invalid("qty must be positive")
+		}
+		o.qty, o.Qty = req.Qty.InexactFloat64(), req.Qty
+	}
+	if req.LimitPrice != nil {
+		o.limit, o.LimitPrice = req.LimitPrice.InexactFloat64(), req.LimitPrice
+	}
+	if req.StopPrice != nil {
+		o.stop, o.StopPrice = req.StopPrice.InexactFloat64(), req.StopPrice
+	}
+	if req.TimeInForce != "" {
+		o.TimeInForce = req.TimeInForce
+	}
+	if err := s.checkFunds(o); err != nil {
+		return alpaca.Order{}, err
+	}
+
+	s.accept(o)
+	old.ReplacedBy = &o.ID
+	s.cancel(old, "replaced")
+	s.emit("new", o, 0, 0, 0)
+	if t, ok := s.currentTick(o.Symbol); ok {
+		if price, ok := s.fillPrice(o, t); ok {
+			s.fill(o, price)
+		}
+	}
+	return s.snapshot(o), nil
+}
+
+// ClosePosition sends a market order flattening symbol's position
+func (s *Simulator) ClosePosition(symbol string) (alpaca.Order, error) {
+	symbol = strings.ToUpper(symbol)
+	s.mu.Lock()
+	p := s.positions[symbol]
+	qty := 0.0
+	if p != nil {
+		qty = p.qty
+	}
+	s.mu.Unlock()
+	if qty == 0 {
+		return alpaca.Order{}, &APIError{Status: 404, Code: 40410000, Message: "position does not exist"}
+	}
+
+	side := alpaca.Sell
+	if qty < 0 {
+		side = alpaca.Buy
+	}
+	return s.SubmitOrder(alpaca.PlaceOrderRequest{
+		Symbol:      symbol,
+		Qty:         decimalPtr(math.Abs(qty)),
+		Side:        side,
+		Type:        alpaca.Market,
+		TimeInForce: alpaca.Day,
+	})
+}
+
+// CloseResult is one entry of DELETE /v2/positions
+type CloseResult struct {
+	Symbol string
+	Order  alpaca.Order
+	Err    error
+}
+
+// CloseAllPositions flattens every position, canceling open orders first
+// when asked
+func (s *Simulator) CloseAllPositions(cancelOrders bool) []CloseResult {
+	if cancelOrders {
+		s.CancelAllOrders()
+	}
+	var results []CloseResult
+	for _, p := range s.Positions() {
+		o, err := s.ClosePosition(p.Symbol)
+		results = append(results, CloseResult{Symbol: p.Symbol, Order: o, Err: err})
+	}
+	return results
+}
+
+// LatestQuote returns the newest market for symbol, synthesizing a
+// zero-spread quote from trades or bars when no quote was replayed
+func (s *Simulator) LatestQuote(symbol string) (quote, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.quotes[strings.ToUpper(symbol)]
+	if q == nil {
+		return quote{}, false
+	}
+	out := *q
+	if out.bid <= 0 || out.ask <= 0 {
+		out.bid, out.ask = out.last, out.last
+	}
+	return out, out.bid > 0
+}
+
+// Asset describes any symbol as an active, tradable NASDAQ equity
+func (s *Simulator) Asset(symbol string) alpaca.Asset {
+	symbol = strings.ToUpper(symbol)
+	return alpaca.Asset{
+		ID:           assetID(symbol),
+		Class:        alpaca.USEquity,
+		Exchange:     "NASDAQ",
+		Symbol:       symbol,
+		Name:         symbol,
+		Status:       alpaca.AssetActive,
+		Tradable:     true,
+		Marginable:   true,
+		Shortable:    !s.config.NoShorting,
+		EasyToBorrow: !s.config.NoShorting,
+		Fractionable: true,
+	}
+}
+
+// Assets describes every symbol with market data
+func (s *Simulator) Assets() []alpaca.Asset {
+	assets := []alpaca.Asset{}
+	for _, symbol := range s.Symbols() {
+		assets = append(assets, s.Asset(symbol))
+	}
+	return assets
+}
+
+// Clock reports regular US equity hours (09:30-16:00 New York, weekdays) on
+// the simulated clock; holidays are not modeled
+func (s *Simulator) Clock() alpaca.Clock {
+	now := s.Now().In(exchange)
+	session := func(day time.Time, hour, minute int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, exchange)
+	}
+	weekday := now.Weekday() != time.Saturday && now.Weekday() != time.Sunday
+
+	nextOpen := session(now, 9, 30)
+	if !weekday || !now.Before(nextOpen) {
+		for nextOpen = session(now.AddDate(0, 0, 1), 9, 30); nextOpen.Weekday() == time.Saturday || nextOpen.Weekday() == time.Sunday; {
+			nextOpen = nextOpen.AddDate(0, 0, 1)
+		}
+	}
+	nextClose := session(now, 16, 0)
+	if !weekday || !now.Before(nextClose) {
+		nextClose = session(nextOpen, 16, 0)
+	}
+	return alpaca.Clock{
+		Timestamp: now,
+		IsOpen:    weekday && !now.Before(session(now, 9, 30)) && now.Before(session(now, 16, 0)),
+		NextOpen:  nextOpen,
+		NextClose: nextClose,
+	}
+}
+
+// Symbols lists the symbols with market data
+func (s *Simulator) Symbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	symbols := make([]string, 0, len(s.quotes))
+	for symbol := range s.quotes {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// decimalPtr boxes a float as a decimal
+func decimalPtr(v float64) *decimal.Decimal {
+	d := decimal.NewFromFloat(v)
+	return &d
+}
+
+// newID returns a random UUID
+func newID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// assetID derives a stable asset ID from a symbol
+func assetID(symbol string) string {
+	var b [16]byte
+	copy(b[:], strings.ToUpper(symbol))
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,458 @@
+package protection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Circuit breaker trip reasons
+const (
+	TripDailyLoss          = "daily_loss"
+	TripIntradayDrawdown   = "intraday_drawdown"
+	TripConsecutiveRejects = "consecutive_rejects"
+	TripStaleData          = "stale_data"
+	TripKillFile           = "kill_file"
+	TripManual             = "manual"
+)
+
+// ErrCircuitOpen is returned by Allow while the breaker is tripped
+var ErrCircuitOpen = errors.New("circuit breaker tripped: trading halted")
+
+// CircuitBreakerConfig configures triggers and trip actions. Zero values disable a trigger.
+type CircuitBreakerConfig struct {
+	MaxDailyLoss          decimal.Decimal `json:"max_daily_loss"`           // Dollar loss from the day's starting equity
+	MaxDailyLossPct       float64         `json:"max_daily_loss_pct"`       // Same, as a fraction of starting equity
+	MaxIntradayDrawdown   float64         `json:"max_intraday_drawdown"`    // Fraction below the day's high-water equity
+	MaxConsecutiveRejects int             `json:"max_consecutive_rejects"`  // Broker rejections in a row
+	StaleDataAfter        time.Duration   `json:"stale_data_after"`         // Max age of the last market data
+	KillFile              string          `json:"kill_file,omitempty"`      // Trip while this file exists
+	StateFile             string          `json:"state_file,omitempty"`     // Persist trips across restarts
+	FlattenOnTrip         bool            `json:"flatten_on_trip"`          // Close all positions on trip
+	CheckInterval         time.Duration   `json:"check_interval,omitempty"` // Monitor loop period
+}
+
+// DefaultCircuitBreakerConfig returns conservative defaults for live trading
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		MaxDailyLossPct:       0.03, // 3% of starting equity
+		MaxIntradayDrawdown:   0.05, // 5% off the day's high
+		MaxConsecutiveRejects: 5,
+		StaleDataAfter:        5 * time.Minute,
+		KillFile:              "/tmp/trading.kill",
+		CheckInterval:         15 * time.Second,
+	}
+}
+
+// TripEvent records why and when the breaker tripped
+type TripEvent struct {
+	Reason    string    `json:"reason"`
+	Detail    string    `json:"detail"`
+	TrippedAt time.Time `json:"tripped_at"`
+}
+
+// CircuitBreaker halts trading when loss, rejection, data or manual triggers fire.
+// Once tripped it stays open until an operator calls Reset.
+type CircuitBreaker struct {
+	cfg CircuitBreakerConfig
+
+	tripped bool
+	trip    TripEvent
+	history []TripEvent
+
+	// Daily equity tracking
+	tradingDay    string
+	startEquity   decimal.Decimal
+	highWater     decimal.Decimal
+	currentEquity decimal.Decimal
+
+	consecutiveRejects int
+	lastDataAt         time.Time
+	marketOpen         func() bool
+
+	// Trip actions
+	cancelAll func() error
+	flatten   func() error
+	onTrip    []func(TripEvent)
+
+	clock  func() time.Time
+	logger *log.Logger
+	mu     sync.RWMutex
+}
+
+// NewCircuitBreaker creates a breaker, restoring a latched trip from StateFile if present
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = 15 * time.Second
+	}
+
+	cb := &CircuitBreaker{
+		cfg:    cfg,
+		clock:  time.Now,
+		logger: log.New(log.Writer(), "[CIRCUIT-BREAKER] ", log.LstdFlags|log.Lmicroseconds),
+	}
+	cb.loadState()
+	return cb
+}
+
+// SetActions sets the hooks run on trip: cancel all open orders and flatten positions
+func (cb *CircuitBreaker) SetActions(cancelAll, flatten func() error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.cancelAll = cancelAll
+	cb.flatten = flatten
+}
+
+// OnTrip registers an additional callback run after trip actions
+func (cb *CircuitBreaker) OnTrip(handler func(TripEvent)) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.onTrip = append(cb.onTrip, handler)
+}
+
+// SetMarketOpenFunc limits the stale data trigger to when the market is open
+func (cb *CircuitBreaker) SetMarketOpenFunc(isOpen func() bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.marketOpen = isOpen
+}
+
+// SetClock replaces the time source
+func (cb *CircuitBreaker) SetClock(clock func() time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if clock == nil {
+		clock = time.Now
+	}
+	cb.clock = clock
+}
+
+// Allow returns ErrCircuitOpen while the breaker is tripped
+func (cb *CircuitBreaker) Allow() error {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	if cb.tripped {
+		return fmt.Errorf("%w (%s: %s)", ErrCircuitOpen, cb.trip.Reason, cb.trip.Detail)
+	}
+	return nil
+}
+
+// IsTripped reports whether trading is halted
+func (cb *CircuitBreaker) IsTripped() bool {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.tripped
+}
+
+// UpdateEquity feeds current account equity (realized + unrealized) into the loss triggers
+func (cb *CircuitBreaker) UpdateEquity(equity decimal.Decimal) {
+	cb.mu.Lock()
+
+	day := tradingDay(cb.clock())
+	if day != cb.tradingDay || cb.startEquity.IsZero() {
+		cb.tradingDay = day
+		cb.startEquity = equity
+		cb.highWater = equity
+		cb.logger.Printf("New trading day %s, starting equity $%s", day, equity.StringFixed(2))
+	}
+	cb.currentEquity = equity
+	if equity.GreaterThan(cb.highWater) {
+		cb.highWater = equity
+	}
+
+	reason, detail := "", ""
+	loss := cb.startEquity.Sub(equity)
+	if cb.cfg.MaxDailyLoss.IsPositive() && loss.GreaterThanOrEqual(cb.cfg.MaxDailyLoss) {
+		reason = TripDailyLoss
+		detail = fmt.Sprintf("daily loss $%s >= $%s", loss.StringFixed(2), cb.cfg.MaxDailyLoss.StringFixed(2))
+	} else if cb.cfg.MaxDailyLossPct > 0 && cb.startEquity.IsPositive() {
+		lossPct := loss.Div(cb.startEquity).InexactFloat64()
+		if lossPct >= cb.cfg.MaxDailyLossPct {
+			reason = TripDailyLoss
+			detail = fmt.Sprintf("daily loss %.2f%% >= %.2f%%", lossPct*100, cb.cfg.MaxDailyLossPct*100)
+		}
+	}
+	if reason == "" && cb.cfg.MaxIntradayDrawdown > 0 && cb.highWater.IsPositive() {
+		drawdown := cb.highWater.Sub(equity).Div(cb.highWater).InexactFloat64()
+		if drawdown >= cb.cfg.MaxIntradayDrawdown {
+			reason = TripIntradayDrawdown
+			detail = fmt.Sprintf("drawdown %.2f%% from high $%s", drawdown*100, cb.highWater.StringFixed(2))
+		}
+	}
+	cb.mu.Unlock()
+
+	if reason != "" {
+		cb.Trip(reason, detail)
+	}
+}
+
+// RecordOrderResult tracks consecutive broker rejections
+func (cb *CircuitBreaker) RecordOrderResult(rejected bool) {
+	cb.mu.Lock()
+	if !rejected {
+		cb.consecutiveRejects = 0
+		cb.mu.Unlock()
+		return
+	}
+
+	cb.consecutiveRejects++
+	count := cb.consecutiveRejects
+	limit := cb.cfg.MaxConsecutiveRejects
+	cb.mu.Unlock()
+
+	if limit > 0 && count >= limit {
+		cb.Trip(TripConsecutiveRejects, fmt.Sprintf("%d consecutive rejected orders", count))
+	}
+}
+
+// RecordMarketData marks market data as fresh
+func (cb *CircuitBreaker) RecordMarketData() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.lastDataAt = cb.clock()
+}
+
+// checkStaleData trips if data has stopped arriving. Nothing is checked until
+// the first update, so a feed that never started doesn't halt trading at boot.
+func (cb *CircuitBreaker) checkStaleData() {
+	cb.mu.RLock()
+	limit := cb.cfg.StaleDataAfter
+	last := cb.lastDataAt
+	isOpen := cb.marketOpen
+	now := cb.clock()
+	cb.mu.RUnlock()
+
+	if limit <= 0 || last.IsZero() {
+		return
+	}
+	if isOpen != nil && !isOpen() {
+		return
+	}
+	if age := now.Sub(last); age > limit {
+		cb.Trip(TripStaleData, fmt.Sprintf("no market data for %v", age.Round(time.Second)))
+	}
+}
+
+// checkKillFile trips while the configured kill file exists
+func (cb *CircuitBreaker) checkKillFile() {
+	if cb.cfg.KillFile == "" {
+		return
+	}
+	if _, err := os.Stat(cb.cfg.KillFile); err == nil {
+		cb.Trip(TripKillFile, fmt.Sprintf("kill file %s present", cb.cfg.KillFile))
+	}
+}
+
+// Trip halts trading: cancels open orders, optionally flattens, and latches until Reset
+func (cb *CircuitBreaker) Trip(reason, detail string) {
+	cb.mu.Lock()
+	if cb.tripped {
+		cb.mu.Unlock()
+		return
+	}
+
+	event := TripEvent{Reason: reason, Detail: detail, TrippedAt: cb.clock()}
+	cb.tripped = true
+	cb.trip = event
+	cb.history = append(cb.history, event)
+	cancelAll, flatten := cb.cancelAll, cb.flatten
+	flattenOnTrip := cb.cfg.FlattenOnTrip
+	handlers := append([]func(TripEvent){}, cb.onTrip...)
+	cb.mu.Unlock()
+
+	cb.logger.Printf("🚨 TRIPPED (%s): %s - trading halted until operator reset", reason, detail)
+	cb.saveState()
+
+	if cancelAll != nil {
+		if err := cancelAll(); err != nil {
+			cb.logger.Printf("Failed to cancel open orders: %v", err)
+		} else {
+			cb.logger.Printf("All open orders canceled")
+		}
+	}
+	if flattenOnTrip && flatten != nil {
+		if err := flatten(); err != nil {
+			cb.logger.Printf("Failed to flatten positions: %v", err)
+		} else {
+			cb.logger.Printf("All positions flattened")
+		}
+	}
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// Reset re-enables trading. It refuses while the kill file is still present.
+func (cb *CircuitBreaker) Reset(operator string) error {
+	if cb.cfg.KillFile != "" {
+		if _, err := os.Stat(cb.cfg.KillFile); err == nil {
+			return fmt.Errorf("remove kill file %s before resetting", cb.cfg.KillFile)
+		}
+	}
+
+	cb.mu.Lock()
+	if !cb.tripped {
+		cb.mu.Unlock()
+		return nil
+	}
+	previous := cb.trip
+	cb.tripped = false
+	cb.trip = TripEvent{}
+	cb.consecutiveRejects = 0
+	cb.lastDataAt = time.Time{}
+	if cb.currentEquity.IsPositive() {
+		// Further losses are measured from the reset point, otherwise the
+		// same loss would trip the breaker again on the next equity update
+		cb.startEquity = cb.currentEquity
+		cb.highWater = cb.currentEquity
+	}
+	cb.mu.Unlock()
+
+	cb.saveState()
+	cb.logger.Printf("Reset by %s (was %s: %s)", operator, previous.Reason, previous.Detail)
+	return nil
+}
+
+// Run polls equity and checks stale data and the kill file until ctx is done.
+// equity may be nil if loss triggers are fed via UpdateEquity elsewhere.
+func (cb *CircuitBreaker) Run(ctx context.Context, equity func() (decimal.Decimal, error)) {
+	ticker := time.NewTicker(cb.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		cb.checkKillFile()
+		cb.checkStaleData()
+		if equity != nil && !cb.IsTripped() {
+			if value, err := equity(); err != nil {
+				cb.logger.Printf("Failed to fetch equity: %v", err)
+			} else {
+				cb.UpdateEquity(value)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// WatchSignals trips on SIGUSR1 and resets on SIGUSR2 until ctx is done
+func (cb *CircuitBreaker) WatchSignals(ctx context.Context) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(sigChan)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigChan:
+			if sig == syscall.SIGUSR1 {
+				cb.Trip(TripManual, "SIGUSR1 kill signal")
+			} else if err := cb.Reset("SIGUSR2"); err != nil {
+				cb.logger.Printf("Reset refused: %v", err)
+			}
+		}
+	}
+}
+
+// GetStatus returns breaker state for monitoring
+func (cb *CircuitBreaker) GetStatus() map[string]interface{} {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	status := map[string]interface{}{
+		"tripped":             cb.tripped,
+		"trading_day":         cb.tradingDay,
+		"start_equity":        cb.startEquity.StringFixed(2),
+		"high_water_equity":   cb.highWater.StringFixed(2),
+		"current_equity":      cb.currentEquity.StringFixed(2),
+		"consecutive_rejects": cb.consecutiveRejects,
+		"trips_total":         len(cb.history),
+	}
+	if cb.tripped {
+		status["trip_reason"] = cb.trip.Reason
+		status["trip_detail"] = cb.trip.Detail
+		status["tripped_at"] = cb.trip.TrippedAt
+	}
+	if !cb.lastDataAt.IsZero() {
+		status["last_data_at"] = cb.lastDataAt
+	}
+	return status
+}
+
+// saveState persists the latched trip so a restart doesn't silently resume trading
+func (cb *CircuitBreaker) saveState() {
+	if cb.cfg.StateFile == "" {
+		return
+	}
+
+	cb.mu.RLock()
+	tripped, trip := cb.tripped, cb.trip
+	cb.mu.RUnlock()
+
+	if !tripped {
+		if err := os.Remove(cb.cfg.StateFile); err != nil && !os.IsNotExist(err) {
+			cb.logger.Printf("Failed to clear state file: %v", err)
+		}
+		return
+	}
+
+	data, err := json.MarshalIndent(trip, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(cb.cfg.StateFile), 0755); err != nil {
+		cb.logger.Printf("Failed to create state directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(cb.cfg.StateFile, data, 0644); err != nil {
+		cb.logger.Printf("Failed to write state file: %v", err)
+	}
+}
+
+// loadState restores a trip latched by a previous process
+func (cb *CircuitBreaker) loadState() {
+	if cb.cfg.StateFile == "" {
+		return
+	}
+
+	data, err := os.ReadFile(cb.cfg.StateFile)
+	if err != nil {
+		return
+	}
+
+	var trip TripEvent
+	if err := json.Unmarshal(data, &trip); err != nil {
+		cb.logger.Printf("Ignoring unreadable state file %s: %v", cb.cfg.StateFile, err)
+		return
+	}
+
+	cb.tripped = true
+	cb.trip = trip
+	cb.history = append(cb.history, trip)
+	cb.logger.Printf("Restored latched trip from %s (%s: %s) - reset required",
+		trip.TrippedAt.Format(time.RFC3339), trip.Reason, trip.Detail)
+}
+
+// tradingDay returns the US equity trading date for t
+func tradingDay(t time.Time) string {
+	if loc, err := time.LoadLocation("America/New_York"); err == nil {
+		t = t.In(loc)
+	}
+	return t.Format("2006-01-02")
+}
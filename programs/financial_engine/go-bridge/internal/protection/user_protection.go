@@ -15,6 +15,7 @@ const (
 	ProtectionDTMC      = "dtmc"       // Day Trade Margin Call
 	ProtectionWashTrade = "wash_trade" // Wash Trade Prevention
 	ProtectionEquityRatio = "equity_ratio" // Equity/Order Ratio
+	ProtectionCashSettlement = "cash_settlement" // Good-faith / settled cash
 )
 
 // PDT Constants
@@ -25,6 +26,11 @@ const (
 	PDTTradeThreshold = 0.06  // 6% of total trades threshold
 )
 
+// Cash account constants
+const (
+	DefaultSettlementDays = 1 // T+1 settlement for US equities
+)
+
 // DayTrade represents a round-trip trade within the same day
 type DayTrade struct {
 	Symbol       string          `json:"symbol"`
@@ -58,6 +64,12 @@ type WashTradePair struct {
 	BlockedAt     time.Time `json:"blocked_at"`
 }
 
+// unsettledFunds tracks sale proceeds in a cash account until they settle
+type unsettledFunds struct {
+	Amount    decimal.Decimal
+	SettlesAt time.Time
+}
+
 // AccountProtection manages user protection features
 type AccountProtection struct {
 	accountID          string
@@ -66,6 +78,16 @@ type AccountProtection struct {
 	totalTrades        int
 	pendingOrders      map[string]ProtectionOrder
 	positions          map[string]decimal.Decimal
+	positionOpened     map[string]time.Time
+	
+	// Sticky PDT designation, simulated for backtests on margin accounts
+	stickyPDT          bool
+	
+	// Cash account settlement
+	cashAccount        bool
+	settlementDays     int
+	settledCash        decimal.Decimal
+	unsettled          []unsettledFunds
 	
 	// Account metrics
 	equity             decimal.Decimal
@@ -86,6 +108,8 @@ type AccountProtection struct {
 	washedTrades       []WashTradePair
 	marginCalls        int
 	
+	// clock supplies the current time; backtests replace it with simulated time
+	clock  func() time.Time
 	logger *log.Logger
 	mu     sync.RWMutex
 }
@@ -99,30 +123,95 @@ func NewAccountProtection(accountID string, equity decimal.Decimal) *AccountProt
 		dayTrades:             make([]DayTrade, 0),
 		pendingOrders:         make(map[string]ProtectionOrder),
 		positions:             make(map[string]decimal.Decimal),
+		positionOpened:        make(map[string]time.Time),
+		settlementDays:        DefaultSettlementDays,
 		multiplier:            2, // Default for non-PDT
 		pdtProtectionOnEntry:  true,
 		dtmcProtectionOnEntry: true,
 		washTradeProtection:   true,
 		equityRatioCheck:      true,
+		clock:                 time.Now,
 		logger:                log.New(log.Writer(), "[PROTECTION] ", log.LstdFlags|log.Lmicroseconds),
 	}
 }
 
+// SetClock replaces the time source used by all rule checks
+func (ap *AccountProtection) SetClock(clock func() time.Time) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	
+	if clock == nil {
+		clock = time.Now
+	}
+	ap.clock = clock
+}
+
+// SetLogger replaces the protection logger
+func (ap *AccountProtection) SetLogger(logger *log.Logger) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	
+	ap.logger = logger
+}
+
+// SetPatternDayTrader flags the account as PDT (e.g. from the broker account)
+func (ap *AccountProtection) SetPatternDayTrader(isPDT bool) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	
+	ap.isPDT = isPDT
+	ap.multiplier = 2
+	if isPDT {
+		ap.multiplier = 4
+	}
+}
+
+// SetStickyPDT keeps the PDT flag once the day trade count sets it, as the
+// broker designation would. Backtests enable it to simulate a margin account;
+// live status comes from the broker via SetPatternDayTrader.
+func (ap *AccountProtection) SetStickyPDT(sticky bool) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	
+	ap.stickyPDT = sticky
+}
+
+// ConfigureCashAccount switches the account to cash rules: no margin, no PDT,
+// and purchases limited to settled funds
+func (ap *AccountProtection) ConfigureCashAccount(settledCash decimal.Decimal, settlementDays int) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	
+	ap.cashAccount = true
+	ap.isPDT = false
+	ap.multiplier = 1
+	ap.settledCash = settledCash
+	ap.settlementDays = settlementDays
+	ap.unsettled = nil
+}
+
+func (ap *AccountProtection) now() time.Time {
+	return ap.clock()
+}
+
 // CheckPDTStatus determines if account is Pattern Day Trader
 func (ap *AccountProtection) CheckPDTStatus() (bool, string) {
-	ap.mu.RLock()
-	defer ap.mu.RUnlock()
-	
-	// Count day trades in last 5 business days
-	cutoff := time.Now().AddDate(0, 0, -PDTLookbackDays)
-	recentDayTrades := 0
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
 	
-	for _, dt := range ap.dayTrades {
-		if dt.OpenTime.After(cutoff) {
-			recentDayTrades++
-		}
+	return ap.checkPDTStatusLocked()
+}
+
+// checkPDTStatusLocked evaluates PDT status; caller must hold ap.mu.
+// Cash accounts are never PDT. With SetStickyPDT an account stays flagged
+// once the count sets it; otherwise the flag follows the current count.
+func (ap *AccountProtection) checkPDTStatusLocked() (bool, string) {
+	if ap.cashAccount {
+		return false, "PDT rules do not apply to cash accounts"
 	}
 	
+	recentDayTrades := ap.recentDayTrades()
+	
 	// Check PDT criteria
 	isPDT := false
 	reason := ""
@@ -142,6 +231,9 @@ func (ap *AccountProtection) CheckPDTStatus() (bool, string) {
 			ap.equity.StringFixed(2))
 	}
 	
+	if ap.stickyPDT {
+		isPDT = isPDT || ap.isPDT
+	}
 	ap.isPDT = isPDT
 	if isPDT {
 		ap.multiplier = 4 // PDT gets 4x buying power
@@ -151,6 +243,20 @@ func (ap *AccountProtection) CheckPDTStatus() (bool, string) {
 	return isPDT, reason
 }
 
+// recentDayTrades counts day trades inside the PDT lookback window
+func (ap *AccountProtection) recentDayTrades() int {
+	cutoff := ap.now().AddDate(0, 0, -PDTLookbackDays)
+	count := 0
+	
+	for _, dt := range ap.dayTrades {
+		if dt.OpenTime.After(cutoff) {
+			count++
+		}
+	}
+	
+	return count
+}
+
 // ValidatePDTOrder checks if order would violate PDT rules
 func (ap *AccountProtection) ValidatePDTOrder(order ProtectionOrder) error {
 	ap.mu.RLock()
@@ -161,6 +267,11 @@ func (ap *AccountProtection) ValidatePDTOrder(order ProtectionOrder) error {
 		return nil
 	}
 	
+	// Cash accounts are governed by settlement rules, not PDT
+	if ap.cashAccount {
+		return nil
+	}
+	
 	// If already PDT with sufficient equity, no restrictions
 	if ap.isPDT && ap.equity.GreaterThanOrEqual(decimal.NewFromInt(PDTMinEquity)) {
 		return nil
@@ -169,14 +280,7 @@ func (ap *AccountProtection) ValidatePDTOrder(order ProtectionOrder) error {
 	// Check if this would be a day trade
 	if ap.wouldBeDayTrade(order) {
 		// Count recent day trades
-		cutoff := time.Now().AddDate(0, 0, -PDTLookbackDays)
-		recentDayTrades := 0
-		
-		for _, dt := range ap.dayTrades {
-			if dt.OpenTime.After(cutoff) {
-				recentDayTrades++
-			}
-		}
+		recentDayTrades := ap.recentDayTrades()
 		
 		// Check pending orders that could become day trades
 		potentialDayTrades := ap.countPotentialDayTrades()
@@ -193,8 +297,8 @@ func (ap *AccountProtection) ValidatePDTOrder(order ProtectionOrder) error {
 
 // CheckDTMCProtection validates Day Trade Margin Call protection
 func (ap *AccountProtection) CheckDTMCProtection(order ProtectionOrder) error {
-	ap.mu.RLock()
-	defer ap.mu.RUnlock()
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
 	
 	// Only applies to PDT accounts
 	if !ap.isPDT {
@@ -228,8 +332,8 @@ func (ap *AccountProtection) CheckDTMCProtection(order ProtectionOrder) error {
 
 // CheckWashTrade prevents wash trading violations
 func (ap *AccountProtection) CheckWashTrade(newOrder ProtectionOrder) error {
-	ap.mu.RLock()
-	defer ap.mu.RUnlock()
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
 	
 	if !ap.washTradeProtection {
 		return nil
@@ -247,7 +351,7 @@ func (ap *AccountProtection) CheckWashTrade(newOrder ProtectionOrder) error {
 				ExistingOrder: existingOrder,
 				NewOrder:      newOrder,
 				Reason:        ap.getWashTradeReason(existingOrder, newOrder),
-				BlockedAt:     time.Now(),
+				BlockedAt:     ap.now(),
 			}
 			
 			ap.washedTrades = append(ap.washedTrades, washPair)
@@ -292,7 +396,7 @@ func (ap *AccountProtection) RecordDayTrade(dt DayTrade) {
 		dt.Symbol, dt.CloseQty.String(), dt.PL.StringFixed(2))
 	
 	// Recheck PDT status (don't need return values here)
-	ap.isPDT, _ = ap.CheckPDTStatus()
+	ap.checkPDTStatusLocked()
 }
 
 // UpdateAccountMetrics updates account financial metrics
@@ -314,11 +418,158 @@ func (ap *AccountProtection) UpdateAccountMetrics(equity, maintenanceMargin, buy
 		equity.StringFixed(2), maintenanceMargin.StringFixed(2), buyingPower.StringFixed(2))
 }
 
+// CheckCashSettlement blocks cash account purchases that would use unsettled funds
+func (ap *AccountProtection) CheckCashSettlement(order ProtectionOrder) error {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	
+	if !ap.cashAccount || order.Side != "buy" {
+		return nil
+	}
+	
+	ap.settleFunds()
+	
+	orderValue := ap.calculateOrderValue(order)
+	if orderValue.GreaterThan(ap.settledCash) {
+		return fmt.Errorf("Cash Settlement Protection: Order value $%s exceeds settled cash $%s (good faith violation)",
+			orderValue.StringFixed(2), ap.settledCash.StringFixed(2))
+	}
+	
+	return nil
+}
+
+// ValidateOrder runs every protection rule and returns the rule that blocked the order
+func (ap *AccountProtection) ValidateOrder(order ProtectionOrder) (string, error) {
+	checks := []struct {
+		rule  string
+		check func(ProtectionOrder) error
+	}{
+		{ProtectionPDT, ap.ValidatePDTOrder},
+		{ProtectionDTMC, ap.CheckDTMCProtection},
+		{ProtectionWashTrade, ap.CheckWashTrade},
+		{ProtectionEquityRatio, ap.CheckEquityRatio},
+		{ProtectionCashSettlement, ap.CheckCashSettlement},
+	}
+	
+	for _, c := range checks {
+		if err := c.check(order); err != nil {
+			ap.mu.Lock()
+			ap.blockedOrders++
+			ap.mu.Unlock()
+			return c.rule, err
+		}
+	}
+	
+	return "", nil
+}
+
+// AddPendingOrder registers a working order for wash trade and day trade checks
+func (ap *AccountProtection) AddPendingOrder(orderID string, order ProtectionOrder) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	
+	ap.pendingOrders[orderID] = order
+}
+
+// RemovePendingOrder drops an order once it is filled, canceled or rejected
+func (ap *AccountProtection) RemovePendingOrder(orderID string) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	
+	delete(ap.pendingOrders, orderID)
+}
+
+// RecordFill updates positions, settlement and day trade history from an execution
+func (ap *AccountProtection) RecordFill(symbol, side string, qty, price decimal.Decimal) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	
+	now := ap.now()
+	position := ap.positions[symbol]
+	signedQty := qty
+	if side == "sell" {
+		signedQty = qty.Neg()
+	}
+	
+	// A fill that reduces a position opened today completes a day trade
+	reducing := (position.GreaterThan(decimal.Zero) && side == "sell") ||
+		(position.LessThan(decimal.Zero) && side == "buy")
+	if reducing && ap.openedToday(symbol) {
+		ap.dayTrades = append(ap.dayTrades, DayTrade{
+			Symbol:     symbol,
+			OpenTime:   ap.positionOpened[symbol],
+			CloseTime:  now,
+			OpenQty:    position.Abs(),
+			CloseQty:   qty,
+			ClosePrice: price,
+			TradeDate:  tradingDay(now),
+		})
+		ap.logger.Printf("Day trade recorded: %s %s shares", symbol, qty.String())
+	}
+	ap.totalTrades++
+	
+	newPosition := position.Add(signedQty)
+	switch {
+	case newPosition.IsZero():
+		delete(ap.positions, symbol)
+		delete(ap.positionOpened, symbol)
+	case position.IsZero() || position.Sign() != newPosition.Sign():
+		ap.positions[symbol] = newPosition
+		ap.positionOpened[symbol] = now
+	default:
+		ap.positions[symbol] = newPosition
+	}
+	
+	// Cash accounts spend settled cash on buys; sale proceeds settle later
+	if ap.cashAccount {
+		ap.settleFunds()
+		notional := qty.Mul(price)
+		if side == "buy" {
+			ap.settledCash = ap.settledCash.Sub(notional)
+		} else {
+			ap.unsettled = append(ap.unsettled, unsettledFunds{
+				Amount:    notional,
+				SettlesAt: addBusinessDays(now, ap.settlementDays),
+			})
+		}
+	}
+	
+	ap.checkPDTStatusLocked()
+}
+
+// settleFunds moves matured sale proceeds into settled cash; caller must hold ap.mu
+func (ap *AccountProtection) settleFunds() {
+	now := ap.now()
+	remaining := ap.unsettled[:0]
+	
+	for _, funds := range ap.unsettled {
+		if !now.Before(funds.SettlesAt) {
+			ap.settledCash = ap.settledCash.Add(funds.Amount)
+		} else {
+			remaining = append(remaining, funds)
+		}
+	}
+	
+	ap.unsettled = remaining
+}
+
+// addBusinessDays returns the start of the day n business days after t
+func addBusinessDays(t time.Time, n int) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for n > 0 {
+		day = day.AddDate(0, 0, 1)
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			n--
+		}
+	}
+	return day
+}
+
 // Helper functions
 
 func (ap *AccountProtection) wouldBeDayTrade(order ProtectionOrder) bool {
 	// Check if closing a position opened today
-	if position, exists := ap.positions[order.Symbol]; exists {
+	if position, exists := ap.positions[order.Symbol]; exists && ap.openedToday(order.Symbol) {
 		if order.Side == "sell" && position.GreaterThan(decimal.Zero) {
 			// Selling a long position opened today
 			return true
@@ -342,6 +593,15 @@ func (ap *AccountProtection) wouldBeDayTrade(order ProtectionOrder) bool {
 	return false
 }
 
+func (ap *AccountProtection) openedToday(symbol string) bool {
+	opened, exists := ap.positionOpened[symbol]
+	if !exists {
+		// Positions loaded without an open time are treated as same-day
+		return true
+	}
+	return tradingDay(opened) == tradingDay(ap.now())
+}
+
 func (ap *AccountProtection) countPotentialDayTrades() int {
 	count := 0
 	symbols := make(map[string]bool)
@@ -438,13 +698,7 @@ func (ap *AccountProtection) GetProtectionStatus() map[string]interface{} {
 	ap.mu.RLock()
 	defer ap.mu.RUnlock()
 	
-	cutoff := time.Now().AddDate(0, 0, -PDTLookbackDays)
-	recentDayTrades := 0
-	for _, dt := range ap.dayTrades {
-		if dt.OpenTime.After(cutoff) {
-			recentDayTrades++
-		}
-	}
+	recentDayTrades := ap.recentDayTrades()
 	
 	return map[string]interface{}{
 		"account_id":            ap.accountID,
@@ -458,6 +712,8 @@ func (ap *AccountProtection) GetProtectionStatus() map[string]interface{} {
 		"blocked_orders":        ap.blockedOrders,
 		"margin_calls":          ap.marginCalls,
 		"wash_trades_prevented": len(ap.washedTrades),
+		"cash_account":          ap.cashAccount,
+		"settled_cash":          ap.settledCash.StringFixed(2),
 		"protections": map[string]bool{
 			"pdt_on_entry":  ap.pdtProtectionOnEntry,
 			"dtmc_on_entry": ap.dtmcProtectionOnEntry,
@@ -9,6 +9,8 @@ import (
 
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
 )
 
 // Options Trading Levels
@@ -119,7 +121,7 @@ type StrategyLeg struct {
 
 // OptionsTrader handles options trading operations
 type OptionsTrader struct {
-	alpacaClient      *alpaca.Client
+	tradingClient     broker.Broker
 	accountID         string
 	optionsLevel      int
 	logger            *log.Logger
@@ -140,21 +142,11 @@ type OptionsTrader struct {
 	losingTrades      int
 }
 
-// NewOptionsTrader creates a new options trader
-func NewOptionsTrader(apiKey, apiSecret, accountID string, isPaper bool) *OptionsTrader {
-	baseURL := "https://api.alpaca.markets"
-	if isPaper {
-		baseURL = "https://paper-api.alpaca.markets"
-	}
-	
-	alpacaClient := alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		BaseURL:   baseURL,
-	})
-	
+// NewOptionsTrader creates a new options trader for the account
+// tradingClient trades on
+func NewOptionsTrader(tradingClient broker.Broker, accountID string) *OptionsTrader {
 	return &OptionsTrader{
-		alpacaClient:     alpacaClient,
+		tradingClient:    tradingClient,
 		accountID:        accountID,
 		optionsLevel:     OptionsLevel3, // Maximum level for paper trading
 		logger:           log.New(log.Writer(), "[OPTIONS] ", log.LstdFlags|log.Lmicroseconds),
@@ -358,7 +350,7 @@ func (ot *OptionsTrader) PlaceOptionsOrder(order OptionsOrder, underlyingPositio
 	}
 	
 	// Place the order
-	placedOrder, err := ot.alpacaClient.PlaceOrder(req)
+	placedOrder, err := ot.tradingClient.PlaceOrder(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to place options order: %v", err)
 	}
@@ -399,7 +391,7 @@ func (ot *OptionsTrader) ExecuteSpreadStrategy(strategy OptionsStrategy) error {
 		if err != nil {
 			// Cancel already placed legs on error
 			for _, o := range orders {
-				ot.alpacaClient.CancelOrder(o.ID)
+				ot.tradingClient.CancelOrder(o.ID)
 			}
 			return fmt.Errorf("failed to place leg %d: %v", i+1, err)
 		}
@@ -437,7 +429,7 @@ func (ot *OptionsTrader) ExerciseOption(symbolOrContractID string) error {
 func (ot *OptionsTrader) GetOptionsPositions() ([]*OptionsPosition, error) {
 	ot.logger.Println("Fetching options positions...")
 	
-	positions, err := ot.alpacaClient.GetPositions()
+	positions, err := ot.tradingClient.GetPositions()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get positions: %v", err)
 	}
@@ -641,7 +633,13 @@ func RunOptionsDemo() {
 		apiSecret = os.Getenv("APCA_API_SECRET_KEY")
 	}
 	
-	optionsTrader := NewOptionsTrader(apiKey, apiSecret, "demo-account", true)
+	tradingClient := broker.NewAlpaca(alpaca.ClientOpts{
+		APIKey:    apiKey,
+		APISecret: apiSecret,
+		BaseURL:   broker.PaperBaseURL,
+	}, nil)
+	
+	optionsTrader := NewOptionsTrader(tradingClient, "demo-account")
 	
 	// 1. Check options level
 	fmt.Println("\n1️⃣ === OPTIONS TRADING LEVEL ===")
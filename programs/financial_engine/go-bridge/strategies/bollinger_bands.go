@@ -11,6 +11,9 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/indicators"
 )
 
 // BollingerBandsStrategy implements a volatility breakout strategy
@@ -27,8 +30,11 @@ type BollingerBandsStrategy struct {
 	SqueezeThreshold float64 // Band width threshold for squeeze detection
 
 	// Alpaca clients
-	tradingClient *alpaca.Client
+	tradingClient broker.Broker
 	dataClient    *marketdata.Client
+
+	// Order routing through the shared OMS
+	orderRouting
 	
 	// API credentials (needed for WebSocket)
 	apiKey        string
@@ -39,6 +45,8 @@ type BollingerBandsStrategy struct {
 	mu            sync.RWMutex
 	prices        []float64
 	volumes       []float64
+	bands         *indicators.Bollinger
+	volumeMA      *indicators.SMA
 	upperBand     float64
 	lowerBand     float64
 	middleBand    float64
@@ -71,7 +79,10 @@ func NewBollingerBandsStrategy(symbol string, period int) *BollingerBandsStrateg
 		SqueezeThreshold: 0.02, // 2% bandwidth for squeeze
 		prices:           make([]float64, 0, period+1),
 		volumes:          make([]float64, 0, period+1),
+		bands:            indicators.NewBollinger(period, 2.0),
+		volumeMA:         indicators.NewSMA(period),
 		logger:           log.New(log.Writer(), "[BB-BREAKOUT] ", log.LstdFlags),
+		orderRouting:     orderRouting{strategyID: "bb_" + symbol},
 	}
 }
 
@@ -82,12 +93,12 @@ func (s *BollingerBandsStrategy) Initialize(apiKey, apiSecret, baseURL string) e
 	s.apiSecret = apiSecret
 	s.baseURL = baseURL
 	
-	// Initialize Alpaca trading client
-	s.tradingClient = alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		BaseURL:   baseURL,
-	})
+	// Trade through the shared OMS's broker, or an Alpaca account when standalone
+	s.tradingClient = s.ensureOrderManager(apiKey, apiSecret, baseURL)
+	s.ensureMarketDataHub(apiKey, apiSecret, baseURL)
+	if err := s.ensureStateStore(); err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
+	}
 
 	// Initialize market data client
 	s.dataClient = marketdata.NewClient(marketdata.ClientOpts{
@@ -100,11 +111,15 @@ func (s *BollingerBandsStrategy) Initialize(apiKey, apiSecret, baseURL string) e
 		return fmt.Errorf("failed to load historical data: %w", err)
 	}
 
-	// Check current position
-	if err := s.syncPosition(); err != nil {
-		return fmt.Errorf("failed to sync position: %w", err)
+	// Resume position, counters and trade context from before a restart
+	if err := s.restoreState(); err != nil {
+		return fmt.Errorf("failed to restore state: %w", err)
 	}
 
+	// Position and P&L follow this strategy's own fills; re-read its book after stream gaps
+	s.oms.Subscribe(s.strategyID, s.onFill)
+	s.oms.OnReconnect(s.strategyID, s.syncPosition)
+
 	s.logger.Printf("Bollinger Bands strategy initialized for %s (Period: %d, StdDev: %.1f)",
 		s.Symbol, s.Period, s.StdDevs)
 	return nil
@@ -130,10 +145,11 @@ func (s *BollingerBandsStrategy) loadHistoricalData() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Store closing prices and volumes
+	// Store closing prices and volumes, warming up the bands
 	for _, bar := range bars {
 		s.prices = append(s.prices, bar.Close)
 		s.volumes = append(s.volumes, float64(bar.Volume))
+		s.updateBands(bar.Close, float64(bar.Volume))
 	}
 
 	// Trim to needed size
@@ -142,39 +158,102 @@ func (s *BollingerBandsStrategy) loadHistoricalData() error {
 		s.volumes = s.volumes[len(s.volumes)-s.Period:]
 	}
 
-	// Calculate initial bands
-	if len(s.prices) >= s.Period {
-		s.calculateBands()
-		s.avgVolume = s.calculateAverage(s.volumes)
-	}
-
 	s.logger.Printf("Loaded %d historical bars, initial bands: Upper=%.2f, Middle=%.2f, Lower=%.2f, Width=%.4f",
 		len(s.prices), s.upperBand, s.middleBand, s.lowerBand, s.bandwidth)
 	return nil
 }
 
-// syncPosition checks if we have an existing position
-func (s *BollingerBandsStrategy) syncPosition() error {
-	positions, err := s.tradingClient.GetPositions()
-	if err != nil {
-		return err
+// syncPosition loads the strategy's position from its own OMS book
+func (s *BollingerBandsStrategy) syncPosition() {
+	qty, entryPrice := s.bookPosition(s.Symbol)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.positionQty = qty
+	s.entryPrice = entryPrice
+	s.hasPosition = qty != 0
+}
+
+// onFill updates position and P&L from an actual execution, including
+// partial fills and bracket stop/target exits
+func (s *BollingerBandsStrategy) onFill(fill FillEvent) {
+	if fill.Symbol != s.Symbol {
+		return
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.hasPosition = false
-	for _, pos := range positions {
-		if pos.Symbol == s.Symbol {
-			s.hasPosition = true
-			s.positionQty = pos.Qty.BigInt().Int64()
-			avgPrice, _ := pos.AvgEntryPrice.Float64()
-			s.entryPrice = avgPrice
-			s.logger.Printf("Found existing position: %d shares at %.2f", s.positionQty, s.entryPrice)
-			break
+	s.positionQty = fill.Position.Qty.IntPart()
+	s.entryPrice = fill.Position.AvgEntryPrice.InexactFloat64()
+	s.hasPosition = s.positionQty != 0
+
+	pnl := fill.RealizedPnL.InexactFloat64()
+	s.totalPnL += pnl
+	if fill.TripClosed {
+		s.tradeCount++
+		if fill.TripPnL.IsPositive() {
+			s.winCount++
 		}
 	}
 
+	s.saveStateLocked()
+
+	s.logger.Printf("%s %s %s @ %s (position %d @ %.2f, realized %.2f)",
+		fill.Event, fill.Side, fill.Qty.String(), fill.Price.StringFixed(2),
+		s.positionQty, s.entryPrice, pnl)
+}
+
+// bollingerState is the live state persisted across restarts
+type bollingerState struct {
+	positionState
+	FalseBreakouts int `json:"false_breakouts"`
+}
+
+// saveStateLocked snapshots live state to the store; caller must hold s.mu
+func (s *BollingerBandsStrategy) saveStateLocked() {
+	state := bollingerState{
+		positionState: positionState{
+			PositionQty: s.positionQty,
+			EntryPrice:  s.entryPrice,
+			LastSignal:  s.lastSignal,
+			Trades:      s.tradeCount,
+			Wins:        s.winCount,
+			TotalPnL:    s.totalPnL,
+		},
+		FalseBreakouts: s.falseBreakouts,
+	}
+	if err := s.saveState(state); err != nil {
+		s.logger.Printf("Failed to save state: %v", err)
+	}
+}
+
+// restoreState reloads state saved before a restart, including the position,
+// which seeds this strategy's OMS book
+func (s *BollingerBandsStrategy) restoreState() error {
+	var saved bollingerState
+	found, err := s.loadState(&saved)
+	if err != nil || !found {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tradeCount = saved.Trades
+	s.winCount = saved.Wins
+	s.totalPnL = saved.TotalPnL
+	s.falseBreakouts = saved.FalseBreakouts
+	s.positionQty = saved.PositionQty
+	s.entryPrice = saved.EntryPrice
+	s.hasPosition = saved.PositionQty != 0
+	s.lastSignal = saved.LastSignal
+	s.restorePosition(s.Symbol, saved.PositionQty, saved.EntryPrice)
+
+	s.logger.Printf("Restored state: position %d at %.2f, %d trades, %d wins, P&L %.2f",
+		s.positionQty, s.entryPrice, s.tradeCount, s.winCount, s.totalPnL)
+	s.saveStateLocked()
 	return nil
 }
 
@@ -182,6 +261,7 @@ func (s *BollingerBandsStrategy) syncPosition() error {
 func (s *BollingerBandsStrategy) ProcessBar(price float64, volume float64, timestamp time.Time) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.markData()
 
 	// Update price and volume history
 	s.prices = append(s.prices, price)
@@ -193,18 +273,17 @@ func (s *BollingerBandsStrategy) ProcessBar(price float64, volume float64, times
 		s.volumes = s.volumes[1:]
 	}
 
-	// Need full period for calculations
-	if len(s.prices) < s.Period {
-		return
-	}
-
 	// Calculate bands and indicators
 	prevUpper := s.upperBand
 	prevLower := s.lowerBand
 	prevBandwidth := s.bandwidth
 	
-	s.calculateBands()
-	s.avgVolume = s.calculateAverage(s.volumes)
+	s.updateBands(price, volume)
+
+	// Need full period for calculations
+	if !s.bands.Ready() {
+		return
+	}
 
 	// Detect squeeze (bands contracting)
 	wasInSqueeze := s.inSqueeze
@@ -227,51 +306,28 @@ func (s *BollingerBandsStrategy) ProcessBar(price float64, volume float64, times
 	}
 }
 
-// calculateBands computes Bollinger Bands
-func (s *BollingerBandsStrategy) calculateBands() {
-	// Calculate SMA (middle band)
-	s.middleBand = s.calculateAverage(s.prices)
-	
-	// Calculate standard deviation
-	stdDev := s.calculateStdDev(s.prices, s.middleBand)
-	
-	// Calculate bands
-	s.upperBand = s.middleBand + (s.StdDevs * stdDev)
-	s.lowerBand = s.middleBand - (s.StdDevs * stdDev)
-	
-	// Calculate bandwidth (relative width)
-	if s.middleBand > 0 {
-		s.bandwidth = (s.upperBand - s.lowerBand) / s.middleBand
+// updateBands feeds a bar to the bands and average volume
+func (s *BollingerBandsStrategy) updateBands(price, volume float64) {
+	bands := s.bands.Update(price)
+	avgVolume := s.volumeMA.Update(volume)
+	if !s.bands.Ready() {
+		return
 	}
-}
 
-// calculateAverage computes simple average
-func (s *BollingerBandsStrategy) calculateAverage(values []float64) float64 {
-	if len(values) == 0 {
-		return 0
-	}
-	
-	sum := 0.0
-	for _, v := range values {
-		sum += v
-	}
-	return sum / float64(len(values))
+	s.middleBand = bands.Middle
+	s.upperBand = bands.Upper
+	s.lowerBand = bands.Lower
+	s.bandwidth = bands.Width // Relative width
+	s.avgVolume = avgVolume
 }
 
-// calculateStdDev computes standard deviation
-func (s *BollingerBandsStrategy) calculateStdDev(values []float64, mean float64) float64 {
-	if len(values) == 0 {
-		return 0
-	}
-	
-	sumSquares := 0.0
-	for _, v := range values {
-		diff := v - mean
-		sumSquares += diff * diff
+// rebuildBands replays the retained history after the period or width changes
+func (s *BollingerBandsStrategy) rebuildBands() {
+	s.bands = indicators.NewBollinger(s.Period, s.StdDevs)
+	s.volumeMA = indicators.NewSMA(s.Period)
+	for i := range s.prices {
+		s.updateBands(s.prices[i], s.volumes[i])
 	}
-	
-	variance := sumSquares / float64(len(values))
-	return math.Sqrt(variance)
 }
 
 // generateSignal determines if we should buy/sell based on band breakouts
@@ -342,6 +398,12 @@ func (s *BollingerBandsStrategy) generateSignal(price, volume float64, prevUpper
 
 // executeTrade places orders based on signals
 func (s *BollingerBandsStrategy) executeTrade(signal string, currentPrice float64) {
+	// Don't stack signals on an order that hasn't filled yet
+	if s.hasWorkingOrder(s.Symbol) {
+		s.logger.Printf("Order already working for %s, skipping %s", s.Symbol, signal)
+		return
+	}
+
 	// Get account info for position sizing
 	account, err := s.tradingClient.GetAccount()
 	if err != nil {
@@ -414,40 +476,27 @@ func (s *BollingerBandsStrategy) executeTrade(signal string, currentPrice float6
 			TimeInForce: alpaca.Day,
 		}
 
-		// Calculate P&L
+		// Estimate only: realized P&L is booked in onFill from the execution
 		pnl := (currentPrice - s.entryPrice) * float64(s.positionQty)
-		s.totalPnL += pnl
-		if pnl > 0 {
-			s.winCount++
-		}
-		s.tradeCount++
 
-		s.logger.Printf("Placing SELL order: %d shares, entry=%.2f, exit=%.2f, P&L=%.2f",
+		s.logger.Printf("Placing SELL order: %d shares, entry=%.2f, exit=%.2f, est. P&L=%.2f",
 			s.positionQty, s.entryPrice, currentPrice, pnl)
 	}
 
 	// Submit order
-	order, err := s.tradingClient.PlaceOrder(orderReq)
+	order, err := s.submitOrder(signal, orderReq, currentPrice)
 	if err != nil {
 		s.logger.Printf("Order failed: %v", err)
 		return
 	}
 
-	// Update state
+	// Position state is updated from fills (see onFill)
 	s.mu.Lock()
-	if signal == "BUY" {
-		s.hasPosition = true
-		s.entryPrice = currentPrice
-		s.positionQty = qty
-	} else if signal == "SELL" {
-		s.hasPosition = false
-		s.entryPrice = 0
-		s.positionQty = 0
-	}
 	s.lastSignal = signal
+	s.saveStateLocked()
 	s.mu.Unlock()
 
-	s.logger.Printf("Order placed successfully: %s", order.ID)
+	s.logger.Printf("Order placed successfully: %s", order.ClientOrderID)
 }
 
 // validateTradeCompliance checks PDT rules and other protections
@@ -471,39 +520,47 @@ func (s *BollingerBandsStrategy) validateTradeCompliance(account *alpaca.Account
 	return true
 }
 
+// SetParameters updates configuration fields while the strategy runs
+func (s *BollingerBandsStrategy) SetParameters(params map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	period, stdDevs := s.Period, s.StdDevs
+	err := setParameters(s, params, func() error {
+		if s.Period < 2 || s.StdDevs <= 0 {
+			return fmt.Errorf("period must be at least 2 and std devs positive")
+		}
+		if s.PositionSize <= 0 || s.PositionSize > 1 {
+			return fmt.Errorf("position size must be a fraction of equity in (0, 1]")
+		}
+		return nil
+	}, "Symbol")
+	if err == nil && (s.Period != period || s.StdDevs != stdDevs) {
+		s.rebuildBands()
+	}
+	return err
+}
+
 // GetStatistics returns strategy performance metrics
-func (s *BollingerBandsStrategy) GetStatistics() map[string]interface{} {
+func (s *BollingerBandsStrategy) GetStatistics() StrategyStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	winRate := 0.0
-	avgTrade := 0.0
-	if s.tradeCount > 0 {
-		winRate = float64(s.winCount) / float64(s.tradeCount) * 100
-		avgTrade = s.totalPnL / float64(s.tradeCount)
-	}
-
 	falseBreakoutRate := 0.0
 	if (s.tradeCount + s.falseBreakouts) > 0 {
 		falseBreakoutRate = float64(s.falseBreakouts) / float64(s.tradeCount + s.falseBreakouts) * 100
 	}
 
-	return map[string]interface{}{
-		"symbol":           s.Symbol,
-		"strategy":         "Bollinger Bands Breakout",
-		"period":           s.Period,
-		"std_devs":         s.StdDevs,
-		"trades":           s.tradeCount,
-		"wins":             s.winCount,
-		"win_rate":         winRate,
-		"total_pnl":        s.totalPnL,
-		"avg_trade_pnl":    avgTrade,
-		"false_breakouts":  s.falseBreakouts,
-		"false_breakout_rate": falseBreakoutRate,
-		"current_bandwidth": s.bandwidth,
-		"in_squeeze":       s.inSqueeze,
-		"has_position":     s.hasPosition,
-	}
+	stats := s.newStats("Bollinger Bands Breakout", []string{s.Symbol}, s.tradeCount, s.winCount, s.totalPnL, lastMark(s.Symbol, s.prices))
+	stats.warmingUp(!s.bands.Ready())
+	stats.Extra["period"] = s.Period
+	stats.Extra["std_devs"] = s.StdDevs
+	stats.Extra["false_breakouts"] = s.falseBreakouts
+	stats.Extra["false_breakout_rate"] = falseBreakoutRate
+	stats.Extra["current_bandwidth"] = s.bandwidth
+	stats.Extra["in_squeeze"] = s.inSqueeze
+	stats.Extra["has_position"] = s.hasPosition
+	return stats
 }
 
 // fetchAndProcessLatestBar fetches the latest bar and processes it
@@ -533,40 +590,17 @@ func (s *BollingerBandsStrategy) fetchAndProcessLatestBar() error {
 
 // Run starts the strategy main loop with WebSocket streaming
 func (s *BollingerBandsStrategy) Run(ctx context.Context) error {
-	// Create proper WebSocket market data stream
-	marketStream := NewMarketDataStream(s.apiKey, s.apiSecret, s.baseURL)
-	
-	// Set up bar handler
-	marketStream.SetBarHandler(func(bar MarketBar) {
-		if bar.Symbol == s.Symbol {
-			s.ProcessBar(bar.Close, float64(bar.Volume), bar.Timestamp)
-		}
-	})
-	
-	// Connect to market data stream
-	if err := marketStream.Connect(ctx); err != nil {
-		return fmt.Errorf("failed to connect to market data stream: %w", err)
-	}
-	defer marketStream.Disconnect()
-	
-	// Subscribe to bars for our symbol
-	if err := marketStream.SubscribeBars([]string{s.Symbol}); err != nil {
-		return fmt.Errorf("failed to subscribe to bars: %w", err)
+	// Start order tracking (no-op if the runner already started the shared OMS)
+	if err := s.oms.Start(ctx); err != nil {
+		s.logger.Printf("Order updates unavailable: %v", err)
 	}
-	
-	// Create trade updates stream for order confirmations
-	tradeStream := NewTradeUpdatesStream(s.apiKey, s.apiSecret, s.baseURL)
-	
-	// Set up trade update handler
-	tradeStream.SetTradeUpdateHandler(func(update TradeUpdate) {
-		s.logger.Printf("Trade update: %s for %s - %s", update.Data.Event, update.Data.Order.Symbol, update.Data.Order.Status)
-	})
-	
-	// Connect to trade updates stream
-	if err := tradeStream.Connect(ctx); err != nil {
-		return fmt.Errorf("failed to connect to trade updates stream: %w", err)
+
+	// Bars arrive from the shared market data hub
+	bars, err := s.subscribeBars(ctx, s.Symbol)
+	if err != nil {
+		return err
 	}
-	defer tradeStream.Disconnect()
+	defer bars.Close()
 
 	s.logger.Printf("Bollinger Bands strategy running for %s", s.Symbol)
 
@@ -583,6 +617,13 @@ func (s *BollingerBandsStrategy) Run(ctx context.Context) error {
 			stats := s.GetStatistics()
 			s.logger.Printf("Final Statistics: %+v", stats)
 			return nil
+
+		case event := <-bars.Events():
+			if event.Cached {
+				continue // Last-value replay of a bar already in history or processed
+			}
+			bar := event.Bar
+			s.ProcessBar(bar.Close, float64(bar.Volume), bar.Timestamp)
 			
 		case <-statsTicker.C:
 			// Print periodic statistics
@@ -0,0 +1,115 @@
+package strategies
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/mockbroker"
+)
+
+// NewAlpacaBroker trades on the Alpaca account baseURL points at, with
+// order events from a TradeUpdatesStream
+func NewAlpacaBroker(apiKey, apiSecret, baseURL string) *broker.Alpaca {
+	return broker.NewAlpaca(alpaca.ClientOpts{
+		APIKey:    apiKey,
+		APISecret: apiSecret,
+		BaseURL:   baseURL,
+	}, func() broker.TradeStream {
+		return NewTradeUpdatesStream(apiKey, apiSecret, baseURL)
+	})
+}
+
+// BrokerFromEnv picks the broker a runner trades through:
+//
+//	BROKER=alpaca (default)  the account at baseURL, paper or live
+//	BROKER=sim               an in-process simulator filled from the runner's
+//	                         market data (SIM_CASH sets the starting cash)
+//	BROKER=replay            answers from the recording at BROKER_REPLAY
+//
+// BROKER_RECORD names a file to record the session to, for later replay.
+func BrokerFromEnv(apiKey, apiSecret, baseURL string) (broker.Broker, error) {
+	var b broker.Broker
+	switch mode := os.Getenv("BROKER"); mode {
+	case "", "alpaca":
+		b = NewAlpacaBroker(apiKey, apiSecret, baseURL)
+	case "sim":
+		config := mockbroker.DefaultConfig()
+		if cash := os.Getenv("SIM_CASH"); cash != "" {
+			value, err := strconv.ParseFloat(cash, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SIM_CASH %q: %w", cash, err)
+			}
+			config.Cash = value
+		}
+		b = mockbroker.NewBroker(mockbroker.NewSimulator(config))
+	case "replay":
+		replay, err := broker.LoadReplay(os.Getenv("BROKER_REPLAY"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load broker replay: %w", err)
+		}
+		b = replay
+	default:
+		return nil, fmt.Errorf("unknown BROKER %q (want alpaca, sim or replay)", mode)
+	}
+
+	if path := os.Getenv("BROKER_RECORD"); path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open broker recording: %w", err)
+		}
+		b = broker.NewRecorder(b, file)
+	}
+	return b, nil
+}
+
+// simEvent converts a hub event for a broker that fills against the
+// process's own market data
+func simEvent(event MarketEvent) (broker.Event, bool) {
+	switch {
+	case event.Bar != nil:
+		bar := event.Bar
+		return broker.Event{Time: bar.Timestamp, Symbol: event.Symbol, Bar: &broker.StreamBar{
+			Type:       "b",
+			Symbol:     event.Symbol,
+			Open:       bar.Open,
+			High:       bar.High,
+			Low:        bar.Low,
+			Close:      bar.Close,
+			Volume:     uint64(max(bar.Volume, 0)),
+			Timestamp:  bar.Timestamp,
+			TradeCount: uint64(max(bar.Count, 0)),
+			VWAP:       bar.VWAP,
+		}}, true
+	case event.Trade != nil:
+		trade := event.Trade
+		return broker.Event{Time: trade.Timestamp, Symbol: event.Symbol, Trade: &broker.StreamTrade{
+			Type:      "t",
+			Symbol:    event.Symbol,
+			ID:        trade.ID,
+			Exchange:  trade.Exchange,
+			Price:     trade.Price,
+			Size:      uint32(max(trade.Size, 0)),
+			Timestamp: trade.Timestamp,
+			Tape:      trade.Tape,
+		}}, true
+	case event.Quote != nil:
+		quote := event.Quote
+		return broker.Event{Time: quote.Timestamp, Symbol: event.Symbol, Quote: &broker.StreamQuote{
+			Type:        "q",
+			Symbol:      event.Symbol,
+			BidExchange: quote.BidEx,
+			BidPrice:    quote.BidPrice,
+			BidSize:     uint32(max(quote.BidSize, 0)),
+			AskExchange: quote.AskEx,
+			AskPrice:    quote.AskPrice,
+			AskSize:     uint32(max(quote.AskSize, 0)),
+			Timestamp:   quote.Timestamp,
+			Tape:        quote.Tape,
+		}}, true
+	}
+	return broker.Event{}, false
+}
@@ -0,0 +1,58 @@
+package strategies
+
+import (
+	"os"
+	"strings"
+
+	"zig-financial-engine/internal/broker"
+)
+
+// Alpaca endpoints. Each can be overridden from the environment, so every
+// client in a process can be pointed at cmd/mock-broker for offline runs:
+// APCA_API_BASE_URL moves the trading API and its trade_updates stream,
+// APCA_API_DATA_URL the market data REST API (read by the SDK itself) and
+// DATA_PROXY_WS the market data stream.
+const (
+	PaperBaseURL         = broker.PaperBaseURL
+	LiveBaseURL          = broker.LiveBaseURL
+	DefaultDataStreamURL = "wss://stream.data.alpaca.markets/v2"
+)
+
+// BaseURLFromEnv returns APCA_API_BASE_URL or PaperBaseURL
+func BaseURLFromEnv() string {
+	if url := os.Getenv("APCA_API_BASE_URL"); url != "" {
+		return strings.TrimRight(url, "/")
+	}
+	return PaperBaseURL
+}
+
+// DataStreamURL returns the market data stream for a trading base URL: the
+// IEX feed for paper accounts and SIP otherwise, under DATA_PROXY_WS (the
+// variable the Alpaca SDK's stream client reads) or DefaultDataStreamURL
+func DataStreamURL(baseURL string) string {
+	feed := "sip"
+	if strings.TrimRight(baseURL, "/") == PaperBaseURL {
+		feed = "iex"
+	}
+	root := DefaultDataStreamURL
+	if url := os.Getenv("DATA_PROXY_WS"); url != "" {
+		root = strings.TrimRight(url, "/")
+	}
+	return root + "/" + feed
+}
+
+// TradeStreamURL returns the trade_updates stream served alongside a
+// trading base URL, defaulting to the live API
+func TradeStreamURL(baseURL string) string {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if baseURL == "" {
+		baseURL = LiveBaseURL
+	}
+	switch {
+	case strings.HasPrefix(baseURL, "https://"):
+		baseURL = "wss://" + strings.TrimPrefix(baseURL, "https://")
+	case strings.HasPrefix(baseURL, "http://"):
+		baseURL = "ws://" + strings.TrimPrefix(baseURL, "http://")
+	}
+	return baseURL + "/stream"
+}
@@ -0,0 +1,662 @@
+package strategies
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+// ExecutionAlgo selects how a parent order is worked
+type ExecutionAlgo string
+
+const (
+	AlgoTWAP ExecutionAlgo = "twap" // Equal slices over the window
+	AlgoVWAP ExecutionAlgo = "vwap" // Slices follow the historical intraday volume profile
+	AlgoPOV  ExecutionAlgo = "pov"  // Track a fixed share of live traded volume
+)
+
+// ExecutionState tracks a parent order through its lifecycle
+type ExecutionState string
+
+const (
+	ExecutionWorking   ExecutionState = "working"
+	ExecutionCompleted ExecutionState = "completed"
+	ExecutionCanceled  ExecutionState = "canceled"
+	ExecutionExpired   ExecutionState = "expired" // Window ended with quantity left, e.g. held back by the limit
+	ExecutionFailed    ExecutionState = "failed"
+)
+
+// Execution engine timing
+const (
+	executionTick           = time.Second // How often schedules and fills are checked
+	maxChildRejections      = 3           // Consecutive OMS rejections before a parent fails
+	defaultPOVParticipation = 0.1
+)
+
+// ParentOrder is a large order the ExecutionEngine works as child orders
+type ParentOrder struct {
+	StrategyID     string // Owning strategy; children are routed through the OMS under it
+	Signal         string // Signal that produced the order (for logs)
+	Symbol         string
+	Side           alpaca.Side
+	Qty            int64 // Shares to execute
+	Algo           ExecutionAlgo
+	Start          time.Time      // Defaults to now
+	End            time.Time      // Schedule end for TWAP/VWAP; optional deadline for POV
+	Slices         int            // TWAP/VWAP child count (default one per minute)
+	Participation  float64        // POV share of market volume (e.g., 0.1 = 10%)
+	LimitPrice     float64        // Never buy above / sell below this (0 = none)
+	MaxSlippageBps float64        // Children are IOC limits this far through the touch (0 = market children)
+	Randomize      float64        // Jitter slice sizes and timing by up to this fraction (e.g., 0.2)
+	MinChildQty    int64          // Smallest child worth sending (default 1)
+	ArrivalPrice   float64        // Benchmark for shortfall; defaults to the mid when submitted
+	Profile        *VolumeProfile // VWAP profile; loaded from the profile source if nil
+}
+
+// ExecutionProgress is a live snapshot of a parent order
+type ExecutionProgress struct {
+	ID              string         `json:"id"`
+	StrategyID      string         `json:"strategy_id"`
+	Symbol          string         `json:"symbol"`
+	Side            alpaca.Side    `json:"side"`
+	Algo            ExecutionAlgo  `json:"algo"`
+	State           ExecutionState `json:"state"`
+	Message         string         `json:"message,omitempty"`
+	Qty             int64          `json:"qty"`
+	FilledQty       int64          `json:"filled_qty"`
+	WorkingQty      int64          `json:"working_qty"`
+	TargetQty       int64          `json:"target_qty"` // Scheduled to be done by now
+	PercentComplete float64        `json:"percent_complete"`
+	AvgFillPrice    float64        `json:"avg_fill_price"`
+	ArrivalPrice    float64        `json:"arrival_price"`
+	LastPrice       float64        `json:"last_price"`
+	Children        int            `json:"children"`
+	Rejections      int            `json:"rejections"`
+	ExecutionCost   float64        `json:"execution_cost"`   // Slippage vs arrival on filled shares, cash
+	OpportunityCost float64        `json:"opportunity_cost"` // Move vs arrival on unfilled shares, cash
+	ShortfallBps    float64        `json:"shortfall_bps"`    // Total shortfall over arrival notional
+	StartedAt       time.Time      `json:"started_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// Done reports whether the parent order has stopped working
+func (p ExecutionProgress) Done() bool {
+	return p.State != ExecutionWorking
+}
+
+// execution is the engine's working state for one parent order
+type execution struct {
+	id        string
+	parent    ParentOrder
+	schedule  sliceSchedule
+	nextSlice int
+	children  []string // Child client order IDs
+	done      chan struct{}
+	cancel    chan struct{}
+
+	// Updated by the worker goroutine, read under ExecutionEngine.mu
+	progress     ExecutionProgress
+	marketVolume float64 // Shares printed on the tape since start (POV)
+	bid, ask     float64
+	rejections   int // Consecutive
+	guardLogged  int // Slice index whose limit hold was last logged
+}
+
+// ExecutionEngine works parent orders as child orders through the OMS. TWAP
+// and VWAP follow a schedule of cumulative targets, so a slice held back by a
+// limit guard or left unfilled is caught up by the next; POV sizes children
+// from trade prints on the market data hub. Children are market orders, or
+// IOC limits when the parent has a limit price or slippage cap, so nothing
+// rests on the book between slices.
+type ExecutionEngine struct {
+	oms     *OrderManager
+	hub     *MarketDataHub
+	profile func(symbol string) (VolumeProfile, error)
+	logger  *log.Logger
+
+	ctx   context.Context
+	stop  context.CancelFunc
+	mu    sync.RWMutex
+	seq   uint64
+	execs map[string]*execution
+	rng   *rand.Rand
+}
+
+// NewExecutionEngine creates an engine routing children through oms. The hub
+// supplies quotes and trade prints; without one POV is unavailable and the
+// arrival price must be given.
+func NewExecutionEngine(oms *OrderManager, hub *MarketDataHub) *ExecutionEngine {
+	ctx, stop := context.WithCancel(context.Background())
+	return &ExecutionEngine{
+		oms:    oms,
+		hub:    hub,
+		logger: log.New(log.Writer(), "[EXECUTION] ", log.LstdFlags),
+		ctx:    ctx,
+		stop:   stop,
+		execs:  make(map[string]*execution),
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetProfileSource sets where VWAP parents without a profile get one, e.g.
+// VolumeProfileLoader.Profile
+func (e *ExecutionEngine) SetProfileSource(source func(symbol string) (VolumeProfile, error)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.profile = source
+}
+
+// Submit validates a parent order and starts working it in the background
+func (e *ExecutionEngine) Submit(parent ParentOrder) (ExecutionProgress, error) {
+	if err := e.prepare(&parent); err != nil {
+		return ExecutionProgress{}, err
+	}
+
+	e.mu.Lock()
+	e.seq++
+	x := &execution{
+		id:     fmt.Sprintf("%s-%s-%d", parent.StrategyID, parent.Algo, e.seq),
+		parent: parent,
+		done:   make(chan struct{}),
+		cancel: make(chan struct{}),
+	}
+	if parent.Algo != AlgoPOV {
+		x.schedule = buildSchedule(parent.Algo, parent.Start, parent.End, parent.Slices, parent.Profile, parent.Randomize, e.rng)
+	}
+	x.guardLogged = -1
+	x.progress = ExecutionProgress{
+		ID:           x.id,
+		StrategyID:   parent.StrategyID,
+		Symbol:       parent.Symbol,
+		Side:         parent.Side,
+		Algo:         parent.Algo,
+		State:        ExecutionWorking,
+		Qty:          parent.Qty,
+		ArrivalPrice: parent.ArrivalPrice,
+		LastPrice:    parent.ArrivalPrice,
+		StartedAt:    parent.Start,
+		UpdatedAt:    time.Now(),
+	}
+	e.execs[x.id] = x
+	progress := x.progress
+	e.mu.Unlock()
+
+	e.logger.Printf("[%s] %s %s %d %s by %s (arrival %.4f)",
+		x.id, parent.Algo, parent.Side, parent.Qty, parent.Symbol, describeEnd(parent), parent.ArrivalPrice)
+	go e.work(x)
+	return progress, nil
+}
+
+// prepare fills defaults and validates a parent order
+func (e *ExecutionEngine) prepare(parent *ParentOrder) error {
+	if e.oms == nil {
+		return fmt.Errorf("execution engine has no order manager")
+	}
+	if parent.StrategyID == "" || parent.Symbol == "" {
+		return fmt.Errorf("parent order needs a strategy ID and symbol")
+	}
+	if parent.Side != alpaca.Buy && parent.Side != alpaca.Sell {
+		return fmt.Errorf("parent order for %s has invalid side %q", parent.Symbol, parent.Side)
+	}
+	if parent.Qty <= 0 {
+		return fmt.Errorf("parent order for %s has no quantity", parent.Symbol)
+	}
+	if parent.Randomize < 0 || parent.Randomize >= 1 {
+		return fmt.Errorf("randomize must be in [0, 1)")
+	}
+	if parent.LimitPrice < 0 || parent.MaxSlippageBps < 0 {
+		return fmt.Errorf("limit price and slippage cap cannot be negative")
+	}
+	if parent.MinChildQty <= 0 {
+		parent.MinChildQty = 1
+	}
+	if parent.Start.IsZero() {
+		parent.Start = time.Now()
+	}
+
+	switch parent.Algo {
+	case AlgoTWAP, AlgoVWAP:
+		if !parent.End.After(parent.Start) {
+			return fmt.Errorf("%s order for %s needs an end after its start", parent.Algo, parent.Symbol)
+		}
+		if parent.Slices <= 0 {
+			parent.Slices = int(math.Max(1, math.Round(parent.End.Sub(parent.Start).Minutes())))
+		}
+		if maxSlices := parent.Qty / parent.MinChildQty; int64(parent.Slices) > maxSlices {
+			parent.Slices = int(maxSlices)
+		}
+		if parent.Algo == AlgoVWAP && parent.Profile == nil {
+			e.mu.RLock()
+			source := e.profile
+			e.mu.RUnlock()
+			if source == nil {
+				return fmt.Errorf("vwap order for %s has no volume profile", parent.Symbol)
+			}
+			profile, err := source(parent.Symbol)
+			if err != nil {
+				return fmt.Errorf("failed to load volume profile: %w", err)
+			}
+			parent.Profile = &profile
+		}
+	case AlgoPOV:
+		if e.hub == nil {
+			return fmt.Errorf("pov needs a market data hub for trade prints")
+		}
+		if parent.Participation == 0 {
+			parent.Participation = defaultPOVParticipation
+		}
+		if parent.Participation <= 0 || parent.Participation >= 1 {
+			return fmt.Errorf("participation must be in (0, 1)")
+		}
+	default:
+		return fmt.Errorf("unknown execution algo %q", parent.Algo)
+	}
+
+	if parent.ArrivalPrice <= 0 {
+		bid, ask, last := e.marketPrices(parent.Symbol)
+		switch {
+		case bid > 0 && ask > 0:
+			parent.ArrivalPrice = (bid + ask) / 2
+		case last > 0:
+			parent.ArrivalPrice = last
+		default:
+			return fmt.Errorf("no arrival price for %s", parent.Symbol)
+		}
+	}
+	return nil
+}
+
+// Cancel stops sending children for a parent order. Children are market or
+// IOC, so nothing is left working once the current one completes.
+func (e *ExecutionEngine) Cancel(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	x, ok := e.execs[id]
+	if !ok {
+		return fmt.Errorf("unknown execution %s", id)
+	}
+	if x.progress.Done() {
+		return nil
+	}
+	select {
+	case <-x.cancel:
+	default:
+		close(x.cancel)
+	}
+	return nil
+}
+
+// Stop cancels every working parent order, e.g. at shutdown
+func (e *ExecutionEngine) Stop() {
+	e.stop()
+}
+
+// Wait blocks until a parent order stops working or ctx is done
+func (e *ExecutionEngine) Wait(ctx context.Context, id string) (ExecutionProgress, error) {
+	e.mu.RLock()
+	x, ok := e.execs[id]
+	e.mu.RUnlock()
+	if !ok {
+		return ExecutionProgress{}, fmt.Errorf("unknown execution %s", id)
+	}
+	select {
+	case <-x.done:
+	case <-ctx.Done():
+		return e.progressOf(x), ctx.Err()
+	}
+	return e.progressOf(x), nil
+}
+
+// Progress returns a parent order's latest progress
+func (e *ExecutionEngine) Progress(id string) (ExecutionProgress, bool) {
+	e.mu.RLock()
+	x, ok := e.execs[id]
+	e.mu.RUnlock()
+	if !ok {
+		return ExecutionProgress{}, false
+	}
+	return e.progressOf(x), true
+}
+
+// Executions returns every parent order's progress, most recent first.
+// An empty strategyID includes every strategy.
+func (e *ExecutionEngine) Executions(strategyID string) []ExecutionProgress {
+	e.mu.RLock()
+	all := make([]ExecutionProgress, 0, len(e.execs))
+	for _, x := range e.execs {
+		if strategyID == "" || x.parent.StrategyID == strategyID {
+			all = append(all, x.progress)
+		}
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool { return all[i].StartedAt.After(all[j].StartedAt) })
+	return all
+}
+
+func (e *ExecutionEngine) progressOf(x *execution) ExecutionProgress {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return x.progress
+}
+
+// work drives one parent order until it completes, expires or is canceled
+func (e *ExecutionEngine) work(x *execution) {
+	defer close(x.done)
+
+	var events <-chan MarketEvent
+	if e.hub != nil {
+		sub, err := e.hub.Subscribe("exec-"+x.id, []string{x.parent.Symbol}, MarketEventTrade, MarketEventQuote)
+		if err != nil {
+			e.logger.Printf("[%s] Market data unavailable: %v", x.id, err)
+		} else {
+			defer sub.Close()
+			events = sub.Events()
+		}
+	}
+
+	ticker := time.NewTicker(executionTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			e.finish(x, ExecutionCanceled, "engine stopped")
+			return
+		case <-x.cancel:
+			e.finish(x, ExecutionCanceled, "canceled")
+			return
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			e.onMarketEvent(x, event)
+		case now := <-ticker.C:
+			if e.step(x, now) {
+				return
+			}
+		}
+	}
+}
+
+// onMarketEvent tracks the touch, last price and tape volume
+func (e *ExecutionEngine) onMarketEvent(x *execution, event MarketEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch {
+	case event.Trade != nil:
+		if !event.Cached && !event.Trade.Timestamp.Before(x.parent.Start) {
+			x.marketVolume += float64(event.Trade.Size)
+		}
+		x.progress.LastPrice = event.Trade.Price
+	case event.Quote != nil:
+		x.bid, x.ask = event.Quote.BidPrice, event.Quote.AskPrice
+	}
+}
+
+// step refreshes fills and sends the next child if one is due. It returns
+// true once the parent order is finished.
+func (e *ExecutionEngine) step(x *execution, now time.Time) bool {
+	filled, working := e.refreshFills(x)
+	parent := x.parent
+
+	if filled >= parent.Qty {
+		e.finish(x, ExecutionCompleted, "")
+		return true
+	}
+
+	e.mu.Lock()
+	target := e.targetLocked(x, now, filled)
+	x.progress.TargetQty = target
+	rejections := x.rejections
+	e.mu.Unlock()
+
+	if rejections >= maxChildRejections {
+		e.finish(x, ExecutionFailed, fmt.Sprintf("%d consecutive child rejections", rejections))
+		return true
+	}
+
+	scheduleOver := parent.Algo != AlgoPOV && x.nextSlice == len(x.schedule.times)
+	deadline := !parent.End.IsZero() && now.After(parent.End)
+	if working == 0 && deadline && (parent.Algo == AlgoPOV || scheduleOver) {
+		// One interval of grace lets the final slice catch up
+		if parent.Algo == AlgoPOV || now.After(parent.End.Add(parent.End.Sub(parent.Start)/time.Duration(parent.Slices))) {
+			e.finish(x, ExecutionExpired, fmt.Sprintf("window ended with %d of %d filled", filled, parent.Qty))
+			return true
+		}
+	}
+
+	if working > 0 {
+		return false // Wait for the current child before sending another
+	}
+	qty := target - filled
+	if parent.Algo == AlgoPOV && parent.Randomize > 0 {
+		e.mu.Lock()
+		qty = int64(math.Round(float64(qty) * (1 + parent.Randomize*(2*e.rng.Float64()-1))))
+		e.mu.Unlock()
+	}
+	if remaining := parent.Qty - filled; qty > remaining {
+		qty = remaining
+	}
+	if qty < parent.MinChildQty && !(qty > 0 && target == parent.Qty) {
+		return false
+	}
+	e.sendChild(x, qty)
+	return false
+}
+
+// targetLocked is the cumulative quantity that should be done by now; caller
+// must hold e.mu
+func (e *ExecutionEngine) targetLocked(x *execution, now time.Time, filled int64) int64 {
+	parent := x.parent
+	if parent.Algo == AlgoPOV {
+		// Our share of total volume, with our own prints taken out of the tape
+		others := math.Max(0, x.marketVolume-float64(filled))
+		target := int64(math.Floor(parent.Participation / (1 - parent.Participation) * others))
+		if target > parent.Qty {
+			target = parent.Qty
+		}
+		return target
+	}
+
+	for x.nextSlice < len(x.schedule.times) && !now.Before(x.schedule.times[x.nextSlice]) {
+		x.nextSlice++
+	}
+	if x.nextSlice == 0 {
+		return 0
+	}
+	target := int64(math.Round(float64(parent.Qty) * x.schedule.cumulative[x.nextSlice-1]))
+	if target > parent.Qty {
+		target = parent.Qty
+	}
+	return target
+}
+
+// sendChild submits one child through the OMS, as an IOC limit when the
+// parent is guarded. A limit guard the market has moved through holds the
+// child back; the quantity rolls into later slices.
+func (e *ExecutionEngine) sendChild(x *execution, qty int64) {
+	parent := x.parent
+	buy := parent.Side == alpaca.Buy
+
+	e.mu.RLock()
+	bid, ask, last := x.bid, x.ask, x.progress.LastPrice
+	slice := x.nextSlice
+	e.mu.RUnlock()
+	if bid == 0 && ask == 0 {
+		bid, ask, _ = e.marketPrices(parent.Symbol)
+	}
+
+	// Reference is the far touch: what a buyer pays now
+	ref := last
+	if buy && ask > 0 {
+		ref = ask
+	} else if !buy && bid > 0 {
+		ref = bid
+	}
+
+	req := alpaca.PlaceOrderRequest{
+		Symbol:      parent.Symbol,
+		Qty:         &[]decimal.Decimal{decimal.NewFromInt(qty)}[0],
+		Side:        parent.Side,
+		Type:        alpaca.Market,
+		TimeInForce: alpaca.Day,
+	}
+	if parent.LimitPrice > 0 || parent.MaxSlippageBps > 0 {
+		limit := parent.LimitPrice
+		if parent.MaxSlippageBps > 0 && ref > 0 {
+			capped := ref * (1 + parent.MaxSlippageBps/10000)
+			if !buy {
+				capped = ref * (1 - parent.MaxSlippageBps/10000)
+			}
+			if limit == 0 || (buy && capped < limit) || (!buy && capped > limit) {
+				limit = capped
+			}
+		}
+		if parent.LimitPrice > 0 && ref > 0 && ((buy && ref > parent.LimitPrice) || (!buy && ref < parent.LimitPrice)) {
+			e.mu.Lock()
+			if x.guardLogged != slice {
+				x.guardLogged = slice
+				e.logger.Printf("[%s] Holding %d %s: %.4f is through the %.4f limit",
+					x.id, qty, parent.Symbol, ref, parent.LimitPrice)
+			}
+			e.mu.Unlock()
+			return
+		}
+		limitPrice := decimal.NewFromFloat(limit).Round(2)
+		if limit < 1 {
+			limitPrice = decimal.NewFromFloat(limit).Round(4)
+		}
+		req.Type = alpaca.Limit
+		req.LimitPrice = &limitPrice
+		req.TimeInForce = alpaca.IOC
+	}
+
+	signal := parent.Signal
+	if signal == "" {
+		signal = string(parent.Algo)
+	}
+	order, err := e.oms.Submit(OrderIntent{
+		StrategyID: parent.StrategyID,
+		Signal:     signal,
+		Request:    req,
+		RefPrice:   decimal.NewFromFloat(ref),
+	})
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if order.ClientOrderID != "" && err == nil {
+		x.children = append(x.children, order.ClientOrderID)
+		x.progress.Children = len(x.children)
+		x.rejections = 0
+		return
+	}
+	x.rejections++
+	x.progress.Rejections++
+	e.logger.Printf("[%s] Child %d %s rejected: %v", x.id, qty, parent.Symbol, err)
+}
+
+// refreshFills recomputes filled and working quantity from the OMS view of
+// the children, which is authoritative whichever way the fills arrived
+func (e *ExecutionEngine) refreshFills(x *execution) (filled, working int64) {
+	e.mu.RLock()
+	children := append([]string{}, x.children...)
+	e.mu.RUnlock()
+
+	notional := 0.0
+	for _, id := range children {
+		order, ok := e.oms.Order(id)
+		if !ok {
+			continue
+		}
+		qty := order.FilledQty.IntPart()
+		filled += qty
+		notional += float64(qty) * order.AvgFillPrice.InexactFloat64()
+		if !order.State.IsTerminal() {
+			working += order.Qty.IntPart() - qty
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	p := &x.progress
+	p.FilledQty, p.WorkingQty = filled, working
+	if filled > 0 {
+		p.AvgFillPrice = notional / float64(filled)
+	}
+	p.PercentComplete = float64(filled) / float64(p.Qty) * 100
+	execCost, oppCost := implementationShortfall(x.parent.Side == alpaca.Buy, p.ArrivalPrice,
+		filled, p.AvgFillPrice, p.Qty-filled, p.LastPrice)
+	p.ExecutionCost, p.OpportunityCost = execCost, oppCost
+	p.ShortfallBps = (execCost + oppCost) / (float64(p.Qty) * p.ArrivalPrice) * 10000
+	p.UpdatedAt = time.Now()
+	return filled, working
+}
+
+// finish records the final state and logs the result
+func (e *ExecutionEngine) finish(x *execution, state ExecutionState, message string) {
+	e.refreshFills(x)
+
+	e.mu.Lock()
+	x.progress.State = state
+	x.progress.Message = message
+	p := x.progress
+	e.mu.Unlock()
+
+	e.logger.Printf("[%s] %s: %d/%d %s filled at %.4f, arrival %.4f, shortfall %.1f bps (%d children)%s",
+		x.id, state, p.FilledQty, p.Qty, p.Symbol, p.AvgFillPrice, p.ArrivalPrice, p.ShortfallBps, p.Children,
+		formatMessage(message))
+}
+
+// marketPrices reads the hub's cached quote and trade for a symbol
+func (e *ExecutionEngine) marketPrices(symbol string) (bid, ask, last float64) {
+	if e.hub == nil {
+		return 0, 0, 0
+	}
+	if quote, ok := e.hub.LastQuote(symbol); ok {
+		bid, ask = quote.BidPrice, quote.AskPrice
+	}
+	if trade, ok := e.hub.LastTrade(symbol); ok {
+		last = trade.Price
+	}
+	return bid, ask, last
+}
+
+// ExecutionUser is implemented by strategies that can work large orders
+// through a shared ExecutionEngine
+type ExecutionUser interface {
+	SetExecutionEngine(engine *ExecutionEngine)
+}
+
+// SetExecutionEngine shares a process-wide execution engine with the strategy
+func (r *orderRouting) SetExecutionEngine(engine *ExecutionEngine) {
+	r.exec = engine
+}
+
+// describeEnd renders when a parent order is due to finish
+func describeEnd(parent ParentOrder) string {
+	if parent.End.IsZero() {
+		return fmt.Sprintf("%.0f%% of volume", parent.Participation*100)
+	}
+	if parent.Algo == AlgoPOV {
+		return fmt.Sprintf("%.0f%% of volume until %s", parent.Participation*100, parent.End.Format("15:04:05"))
+	}
+	return fmt.Sprintf("%s in %d slices", parent.End.Format("15:04:05"), parent.Slices)
+}
+
+// formatMessage appends a message in parentheses if there is one
+func formatMessage(message string) string {
+	if message == "" {
+		return ""
+	}
+	return " (" + message + ")"
+}
@@ -0,0 +1,254 @@
+package strategies
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+// sessionLength is the regular US equity session, 9:30 to 16:00 exchange time
+const sessionLength = 390 * time.Minute
+
+// VolumeProfile is the share of a regular session's volume traded in each
+// fixed-size bucket after the open, averaged over past sessions
+type VolumeProfile struct {
+	Bucket  time.Duration `json:"bucket"`
+	Weights []float64     `json:"weights"` // Sum to 1; bucket 0 starts at the 9:30 open
+}
+
+// NewVolumeProfile builds a profile from historical intraday bars no longer
+// than bucket. Each session is normalized before averaging so heavy days do
+// not dominate the shape; bars outside regular hours are ignored.
+func NewVolumeProfile(bars []marketdata.Bar, bucket time.Duration) (VolumeProfile, error) {
+	if bucket <= 0 || bucket > sessionLength {
+		return VolumeProfile{}, fmt.Errorf("invalid profile bucket %s", bucket)
+	}
+	buckets := int(math.Ceil(float64(sessionLength) / float64(bucket)))
+
+	sessions := make(map[string][]float64)
+	for _, bar := range bars {
+		offset, ok := sessionOffset(bar.Timestamp)
+		if !ok {
+			continue
+		}
+		date := bar.Timestamp.In(exchangeLocation).Format("2006-01-02")
+		if sessions[date] == nil {
+			sessions[date] = make([]float64, buckets)
+		}
+		sessions[date][int(offset/bucket)] += float64(bar.Volume)
+	}
+
+	profile := VolumeProfile{Bucket: bucket, Weights: make([]float64, buckets)}
+	days := 0
+	for _, volumes := range sessions {
+		total := 0.0
+		for _, v := range volumes {
+			total += v
+		}
+		if total == 0 {
+			continue
+		}
+		for i, v := range volumes {
+			profile.Weights[i] += v / total
+		}
+		days++
+	}
+	if days == 0 {
+		return VolumeProfile{}, fmt.Errorf("no regular-session volume in %d bars", len(bars))
+	}
+	for i := range profile.Weights {
+		profile.Weights[i] /= float64(days)
+	}
+	return profile, nil
+}
+
+// Fraction returns the share of a session's volume expected between two
+// instants, interpolating within buckets. Time outside regular hours counts
+// for nothing.
+func (p VolumeProfile) Fraction(from, to time.Time) float64 {
+	total := 0.0
+	for from.Before(to) {
+		local := from.In(exchangeLocation)
+		midnight := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, exchangeLocation)
+		end := to
+		if end.After(midnight) {
+			end = midnight
+		}
+		total += p.cumulative(clampOffset(end.Add(-time.Nanosecond))) - p.cumulative(clampOffset(from))
+		from = end
+	}
+	return total
+}
+
+// cumulative is the share of session volume traded by offset into the session
+func (p VolumeProfile) cumulative(offset time.Duration) float64 {
+	sum := 0.0
+	for i, w := range p.Weights {
+		bucketStart := time.Duration(i) * p.Bucket
+		if offset >= bucketStart+p.Bucket {
+			sum += w
+			continue
+		}
+		if offset > bucketStart {
+			sum += w * float64(offset-bucketStart) / float64(p.Bucket)
+		}
+		break
+	}
+	return sum
+}
+
+// VolumeProfileLoader builds VWAP profiles from recent minute bars, cached per
+// symbol for the day they were built
+type VolumeProfileLoader struct {
+	client *marketdata.Client
+	days   int
+	bucket time.Duration
+
+	mu    sync.Mutex
+	cache map[string]loadedProfile
+}
+
+type loadedProfile struct {
+	date    string
+	profile VolumeProfile
+}
+
+// NewVolumeProfileLoader averages the last days sessions into bucket-sized slots
+func NewVolumeProfileLoader(client *marketdata.Client, days int, bucket time.Duration) *VolumeProfileLoader {
+	return &VolumeProfileLoader{
+		client: client,
+		days:   days,
+		bucket: bucket,
+		cache:  make(map[string]loadedProfile),
+	}
+}
+
+// Profile returns the symbol's volume profile, fetching bars once a day
+func (l *VolumeProfileLoader) Profile(symbol string) (VolumeProfile, error) {
+	today := time.Now().In(exchangeLocation).Format("2006-01-02")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cached, ok := l.cache[symbol]; ok && cached.date == today {
+		return cached.profile, nil
+	}
+
+	// Calendar days covering the requested sessions plus weekends and holidays
+	end := time.Now()
+	bars, err := l.client.GetBars(symbol, marketdata.GetBarsRequest{
+		TimeFrame: marketdata.OneMin,
+		Start:     end.AddDate(0, 0, -(l.days*7/5 + 4)),
+		End:       end,
+	})
+	if err != nil {
+		return VolumeProfile{}, fmt.Errorf("failed to get minute bars for %s: %w", symbol, err)
+	}
+	profile, err := NewVolumeProfile(bars, l.bucket)
+	if err != nil {
+		return VolumeProfile{}, fmt.Errorf("%s: %w", symbol, err)
+	}
+	l.cache[symbol] = loadedProfile{date: today, profile: profile}
+	return profile, nil
+}
+
+// sliceSchedule is when each child of a TWAP or VWAP parent is due and the
+// share of the parent that should have been sent by then
+type sliceSchedule struct {
+	times      []time.Time
+	cumulative []float64 // Non-decreasing, ending at 1
+}
+
+// buildSchedule splits [start, end) into equal intervals weighted evenly
+// (TWAP) or by the volume profile (VWAP). Randomize perturbs each interval's
+// share by up to ±randomize and delays each child by up to randomize of an
+// interval, so the pattern is harder to detect.
+func buildSchedule(algo ExecutionAlgo, start, end time.Time, slices int, profile *VolumeProfile, randomize float64, rng *rand.Rand) sliceSchedule {
+	interval := end.Sub(start) / time.Duration(slices)
+	shares := make([]float64, slices)
+	for i := range shares {
+		shares[i] = 1
+		if algo == AlgoVWAP && profile != nil {
+			from := start.Add(time.Duration(i) * interval)
+			shares[i] = profile.Fraction(from, from.Add(interval))
+		}
+	}
+	if sum(shares) == 0 {
+		// Window misses the session entirely; fall back to time slicing
+		for i := range shares {
+			shares[i] = 1
+		}
+	}
+
+	schedule := sliceSchedule{times: make([]time.Time, slices), cumulative: make([]float64, slices)}
+	for i := range shares {
+		if randomize > 0 {
+			shares[i] *= 1 + randomize*(2*rng.Float64()-1)
+		}
+		delay := time.Duration(randomize * rng.Float64() * float64(interval))
+		schedule.times[i] = start.Add(time.Duration(i)*interval + delay)
+	}
+	total := sum(shares)
+	running := 0.0
+	for i, share := range shares {
+		running += share / total
+		schedule.cumulative[i] = running
+	}
+	schedule.cumulative[slices-1] = 1
+	return schedule
+}
+
+// implementationShortfall measures execution against the arrival price in
+// cash (positive = cost): slippage on the filled quantity plus the move
+// missed on the unfilled remainder, valued at the last price
+func implementationShortfall(buy bool, arrival float64, filledQty int64, avgFill float64, unfilledQty int64, last float64) (execution, opportunity float64) {
+	sign := 1.0
+	if !buy {
+		sign = -1
+	}
+	execution = sign * float64(filledQty) * (avgFill - arrival)
+	if last > 0 {
+		opportunity = sign * float64(unfilledQty) * (last - arrival)
+	}
+	return execution, opportunity
+}
+
+// sessionOffset returns how far t is into the regular session, if it is in it
+func sessionOffset(t time.Time) (time.Duration, bool) {
+	local := t.In(exchangeLocation)
+	open := time.Date(local.Year(), local.Month(), local.Day(), 9, 30, 0, 0, exchangeLocation)
+	offset := local.Sub(open)
+	return offset, offset >= 0 && offset < sessionLength
+}
+
+// clampOffset is t's offset into its day's session, limited to [0, sessionLength]
+func clampOffset(t time.Time) time.Duration {
+	offset, _ := sessionOffset(t)
+	if offset < 0 {
+		return 0
+	}
+	if offset > sessionLength {
+		return sessionLength
+	}
+	return offset
+}
+
+// exchangeLocation is US equity exchange time, or UTC if the zone database is missing
+var exchangeLocation = func() *time.Location {
+	if loc, err := time.LoadLocation("America/New_York"); err == nil {
+		return loc
+	}
+	return time.UTC
+}()
+
+// sum adds values
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
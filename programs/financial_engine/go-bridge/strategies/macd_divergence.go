@@ -11,6 +11,9 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/indicators"
 )
 
 // MACDDivergenceStrategy implements momentum trading with MACD crossovers and divergence detection
@@ -26,8 +29,11 @@ type MACDDivergenceStrategy struct {
 	DivergenceWindow int  // Window to look for divergences
 
 	// Alpaca clients
-	tradingClient *alpaca.Client
+	tradingClient broker.Broker
 	dataClient    *marketdata.Client
+
+	// Order routing through the shared OMS
+	orderRouting
 	
 	// API credentials (needed for WebSocket)
 	apiKey        string
@@ -38,8 +44,7 @@ type MACDDivergenceStrategy struct {
 	mu            sync.RWMutex
 	prices        []float64
 	timestamps    []time.Time
-	fastEMA       float64
-	slowEMA       float64
+	macd          *indicators.MACD
 	macdLine      float64
 	signalLine    float64
 	histogram     float64
@@ -79,6 +84,7 @@ func NewMACDDivergenceStrategy(symbol string) *MACDDivergenceStrategy {
 		TakeProfitPct:    0.05, // 5% take profit
 		DivergenceWindow: 14,   // Look back 14 periods for divergences
 		prices:           make([]float64, 0, 100),
+		macd:             indicators.NewMACD(12, 26, 9),
 		timestamps:       make([]time.Time, 0, 100),
 		macdHistory:      make([]float64, 0, 100),
 		signalHistory:    make([]float64, 0, 100),
@@ -87,6 +93,7 @@ func NewMACDDivergenceStrategy(symbol string) *MACDDivergenceStrategy {
 		macdHighs:        make([]float64, 0, 14),
 		macdLows:         make([]float64, 0, 14),
 		logger:           log.New(log.Writer(), "[MACD-DIV] ", log.LstdFlags),
+		orderRouting:     orderRouting{strategyID: "macd_" + symbol},
 	}
 }
 
@@ -97,12 +104,12 @@ func (s *MACDDivergenceStrategy) Initialize(apiKey, apiSecret, baseURL string) e
 	s.apiSecret = apiSecret
 	s.baseURL = baseURL
 	
-	// Initialize Alpaca trading client
-	s.tradingClient = alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		BaseURL:   baseURL,
-	})
+	// Trade through the shared OMS's broker, or an Alpaca account when standalone
+	s.tradingClient = s.ensureOrderManager(apiKey, apiSecret, baseURL)
+	s.ensureMarketDataHub(apiKey, apiSecret, baseURL)
+	if err := s.ensureStateStore(); err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
+	}
 
 	// Initialize market data client
 	s.dataClient = marketdata.NewClient(marketdata.ClientOpts{
@@ -115,11 +122,15 @@ func (s *MACDDivergenceStrategy) Initialize(apiKey, apiSecret, baseURL string) e
 		return fmt.Errorf("failed to load historical data: %w", err)
 	}
 
-	// Check current position
-	if err := s.syncPosition(); err != nil {
-		return fmt.Errorf("failed to sync position: %w", err)
+	// Resume position, counters and trade context from before a restart
+	if err := s.restoreState(); err != nil {
+		return fmt.Errorf("failed to restore state: %w", err)
 	}
 
+	// Position and P&L follow this strategy's own fills; re-read its book after stream gaps
+	s.oms.Subscribe(s.strategyID, s.onFill)
+	s.oms.OnReconnect(s.strategyID, s.syncPosition)
+
 	s.logger.Printf("MACD Divergence strategy initialized for %s (Fast: %d, Slow: %d, Signal: %d)",
 		s.Symbol, s.FastPeriod, s.SlowPeriod, s.SignalPeriod)
 	return nil
@@ -146,16 +157,11 @@ func (s *MACDDivergenceStrategy) loadHistoricalData() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Store prices and timestamps
+	// Store prices and timestamps, warming up the MACD
 	for _, bar := range bars {
 		s.prices = append(s.prices, bar.Close)
 		s.timestamps = append(s.timestamps, bar.Timestamp)
-	}
-
-	// Initialize EMAs if we have enough data
-	if len(s.prices) >= s.SlowPeriod {
-		s.initializeEMAs()
-		s.calculateMACD()
+		s.updateMACD(bar.Close)
 	}
 
 	s.logger.Printf("Loaded %d historical bars, initial MACD: %.4f, Signal: %.4f, Histogram: %.4f",
@@ -163,44 +169,100 @@ func (s *MACDDivergenceStrategy) loadHistoricalData() error {
 	return nil
 }
 
-// initializeEMAs calculates initial EMA values using SMA for the first value
-func (s *MACDDivergenceStrategy) initializeEMAs() {
-	// Calculate initial SMAs
-	fastSum := 0.0
-	for i := len(s.prices) - s.FastPeriod; i < len(s.prices); i++ {
-		fastSum += s.prices[i]
-	}
-	s.fastEMA = fastSum / float64(s.FastPeriod)
+// syncPosition loads the strategy's position from its own OMS book
+func (s *MACDDivergenceStrategy) syncPosition() {
+	qty, entryPrice := s.bookPosition(s.Symbol)
 
-	slowSum := 0.0
-	for i := len(s.prices) - s.SlowPeriod; i < len(s.prices); i++ {
-		slowSum += s.prices[i]
-	}
-	s.slowEMA = slowSum / float64(s.SlowPeriod)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.positionQty = qty
+	s.entryPrice = entryPrice
+	s.hasPosition = qty != 0
 }
 
-// syncPosition checks if we have an existing position
-func (s *MACDDivergenceStrategy) syncPosition() error {
-	positions, err := s.tradingClient.GetPositions()
-	if err != nil {
-		return err
+// onFill updates position and P&L from an actual execution, including
+// partial fills and bracket stop/target exits
+func (s *MACDDivergenceStrategy) onFill(fill FillEvent) {
+	if fill.Symbol != s.Symbol {
+		return
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.hasPosition = false
-	for _, pos := range positions {
-		if pos.Symbol == s.Symbol {
-			s.hasPosition = true
-			s.positionQty = pos.Qty.BigInt().Int64()
-			avgPrice, _ := pos.AvgEntryPrice.Float64()
-			s.entryPrice = avgPrice
-			s.logger.Printf("Found existing position: %d shares at %.2f", s.positionQty, s.entryPrice)
-			break
+	s.positionQty = fill.Position.Qty.IntPart()
+	s.entryPrice = fill.Position.AvgEntryPrice.InexactFloat64()
+	s.hasPosition = s.positionQty != 0
+
+	pnl := fill.RealizedPnL.InexactFloat64()
+	s.totalPnL += pnl
+	if fill.TripClosed {
+		s.tradeCount++
+		if fill.TripPnL.IsPositive() {
+			s.winCount++
 		}
 	}
 
+	s.saveStateLocked()
+
+	s.logger.Printf("%s %s %s @ %s (position %d @ %.2f, realized %.2f)",
+		fill.Event, fill.Side, fill.Qty.String(), fill.Price.StringFixed(2),
+		s.positionQty, s.entryPrice, pnl)
+}
+
+// macdState is the live state persisted across restarts
+type macdState struct {
+	positionState
+	DivergenceHits   int `json:"divergence_hits"`
+	DivergenceMisses int `json:"divergence_misses"`
+}
+
+// saveStateLocked snapshots live state to the store; caller must hold s.mu
+func (s *MACDDivergenceStrategy) saveStateLocked() {
+	state := macdState{
+		positionState: positionState{
+			PositionQty: s.positionQty,
+			EntryPrice:  s.entryPrice,
+			LastSignal:  s.lastSignal,
+			Trades:      s.tradeCount,
+			Wins:        s.winCount,
+			TotalPnL:    s.totalPnL,
+		},
+		DivergenceHits:   s.divergenceHits,
+		DivergenceMisses: s.divergenceMisses,
+	}
+	if err := s.saveState(state); err != nil {
+		s.logger.Printf("Failed to save state: %v", err)
+	}
+}
+
+// restoreState reloads state saved before a restart, including the position,
+// which seeds this strategy's OMS book
+func (s *MACDDivergenceStrategy) restoreState() error {
+	var saved macdState
+	found, err := s.loadState(&saved)
+	if err != nil || !found {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tradeCount = saved.Trades
+	s.winCount = saved.Wins
+	s.totalPnL = saved.TotalPnL
+	s.divergenceHits = saved.DivergenceHits
+	s.divergenceMisses = saved.DivergenceMisses
+	s.positionQty = saved.PositionQty
+	s.entryPrice = saved.EntryPrice
+	s.hasPosition = saved.PositionQty != 0
+	s.lastSignal = saved.LastSignal
+	s.restorePosition(s.Symbol, saved.PositionQty, saved.EntryPrice)
+
+	s.logger.Printf("Restored state: position %d at %.2f, %d trades, %d wins, P&L %.2f",
+		s.positionQty, s.entryPrice, s.tradeCount, s.winCount, s.totalPnL)
+	s.saveStateLocked()
 	return nil
 }
 
@@ -208,6 +270,7 @@ func (s *MACDDivergenceStrategy) syncPosition() error {
 func (s *MACDDivergenceStrategy) ProcessBar(price float64, timestamp time.Time) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.markData()
 
 	// Update price history
 	s.prices = append(s.prices, price)
@@ -219,18 +282,17 @@ func (s *MACDDivergenceStrategy) ProcessBar(price float64, timestamp time.Time)
 		s.timestamps = s.timestamps[1:]
 	}
 
-	// Need enough data for slow EMA
-	if len(s.prices) < s.SlowPeriod {
-		return
-	}
-
 	// Update EMAs and MACD
-	s.updateEMAs(price)
 	prevMACD := s.macdLine
 	prevSignal := s.signalLine
 	prevHistogram := s.histogram
 	
-	s.calculateMACD()
+	s.updateMACD(price)
+
+	// Need enough data for slow EMA
+	if !s.macd.LineReady() {
+		return
+	}
 	
 	// Store MACD history for divergence detection
 	s.macdHistory = append(s.macdHistory, s.macdLine)
@@ -246,6 +308,11 @@ func (s *MACDDivergenceStrategy) ProcessBar(price float64, timestamp time.Time)
 	// Detect divergences
 	s.detectDivergences()
 
+	// Crossovers need the signal line
+	if !s.macd.Ready() {
+		return
+	}
+
 	// Generate signal
 	signal := s.generateSignal(prevMACD, prevSignal, prevHistogram, price)
 	
@@ -258,38 +325,26 @@ func (s *MACDDivergenceStrategy) ProcessBar(price float64, timestamp time.Time)
 	}
 }
 
-// updateEMAs updates exponential moving averages
-func (s *MACDDivergenceStrategy) updateEMAs(price float64) {
-	// EMA = (Price - Previous EMA) * Multiplier + Previous EMA
-	fastMultiplier := 2.0 / float64(s.FastPeriod+1)
-	slowMultiplier := 2.0 / float64(s.SlowPeriod+1)
-	
-	s.fastEMA = (price - s.fastEMA) * fastMultiplier + s.fastEMA
-	s.slowEMA = (price - s.slowEMA) * slowMultiplier + s.slowEMA
+// updateMACD feeds a price to the MACD and records the line, signal and
+// histogram once each is available
+func (s *MACDDivergenceStrategy) updateMACD(price float64) {
+	value := s.macd.Update(price)
+	if s.macd.LineReady() {
+		s.macdLine = value.MACD
+	}
+	if s.macd.Ready() {
+		s.signalLine = value.Signal
+		s.histogram = value.Histogram
+	}
 }
 
-// calculateMACD calculates MACD line, signal line, and histogram
-func (s *MACDDivergenceStrategy) calculateMACD() {
-	// MACD line = Fast EMA - Slow EMA
-	s.macdLine = s.fastEMA - s.slowEMA
-	
-	// Signal line = EMA of MACD line
-	if s.signalLine == 0 && len(s.macdHistory) >= s.SignalPeriod {
-		// Initialize signal line with SMA
-		sum := 0.0
-		start := len(s.macdHistory) - s.SignalPeriod
-		for i := start; i < len(s.macdHistory); i++ {
-			sum += s.macdHistory[i]
-		}
-		s.signalLine = sum / float64(s.SignalPeriod)
-	} else if s.signalLine != 0 {
-		// Update signal line EMA
-		signalMultiplier := 2.0 / float64(s.SignalPeriod+1)
-		s.signalLine = (s.macdLine - s.signalLine) * signalMultiplier + s.signalLine
+// rebuildMACD replays the retained prices after a period change
+func (s *MACDDivergenceStrategy) rebuildMACD() {
+	s.macd = indicators.NewMACD(s.FastPeriod, s.SlowPeriod, s.SignalPeriod)
+	s.macdLine, s.signalLine, s.histogram = 0, 0, 0
+	for _, price := range s.prices {
+		s.updateMACD(price)
 	}
-	
-	// Histogram = MACD line - Signal line
-	s.histogram = s.macdLine - s.signalLine
 }
 
 // updateHighsLows tracks price and MACD highs/lows for divergence detection
@@ -426,6 +481,12 @@ func (s *MACDDivergenceStrategy) generateSignal(prevMACD, prevSignal, prevHistog
 
 // executeTrade places orders based on signals
 func (s *MACDDivergenceStrategy) executeTrade(signal string, currentPrice float64) {
+	// Don't stack signals on an order that hasn't filled yet
+	if s.hasWorkingOrder(s.Symbol) {
+		s.logger.Printf("Order already working for %s, skipping %s", s.Symbol, signal)
+		return
+	}
+
 	// Get account info for position sizing
 	account, err := s.tradingClient.GetAccount()
 	if err != nil {
@@ -498,41 +559,28 @@ func (s *MACDDivergenceStrategy) executeTrade(signal string, currentPrice float6
 			TimeInForce: alpaca.Day,
 		}
 
-		// Calculate P&L
+		// Estimate only: realized P&L is booked in onFill from the execution
 		pnl := (currentPrice - s.entryPrice) * float64(s.positionQty)
-		s.totalPnL += pnl
-		if pnl > 0 {
-			s.winCount++
-		}
-		s.tradeCount++
 
-		s.logger.Printf("Placing SELL order: %d shares, entry=%.2f, exit=%.2f, P&L=%.2f",
+		s.logger.Printf("Placing SELL order: %d shares, entry=%.2f, exit=%.2f, est. P&L=%.2f",
 			s.positionQty, s.entryPrice, currentPrice, pnl)
 	}
 
 	// Submit order
-	order, err := s.tradingClient.PlaceOrder(orderReq)
+	order, err := s.submitOrder(signal, orderReq, currentPrice)
 	if err != nil {
 		s.logger.Printf("Order failed: %v", err)
 		s.divergenceMisses++
 		return
 	}
 
-	// Update state
+	// Position state is updated from fills (see onFill)
 	s.mu.Lock()
-	if signal == "BUY" {
-		s.hasPosition = true
-		s.entryPrice = currentPrice
-		s.positionQty = qty
-	} else if signal == "SELL" {
-		s.hasPosition = false
-		s.entryPrice = 0
-		s.positionQty = 0
-	}
 	s.lastSignal = signal
+	s.saveStateLocked()
 	s.mu.Unlock()
 
-	s.logger.Printf("Order placed successfully: %s", order.ID)
+	s.logger.Printf("Order placed successfully: %s", order.ClientOrderID)
 }
 
 // validateTradeCompliance checks PDT rules and other protections
@@ -556,38 +604,46 @@ func (s *MACDDivergenceStrategy) validateTradeCompliance(account *alpaca.Account
 	return true
 }
 
+// SetParameters updates configuration fields while the strategy runs
+func (s *MACDDivergenceStrategy) SetParameters(params map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fast, slow, signal := s.FastPeriod, s.SlowPeriod, s.SignalPeriod
+	err := setParameters(s, params, func() error {
+		if s.FastPeriod < 1 || s.FastPeriod >= s.SlowPeriod || s.SignalPeriod < 1 {
+			return fmt.Errorf("periods must satisfy 0 < fast < slow and signal > 0")
+		}
+		if s.PositionSize <= 0 || s.PositionSize > 1 {
+			return fmt.Errorf("position size must be a fraction of equity in (0, 1]")
+		}
+		return nil
+	}, "Symbol")
+	if err == nil && (s.FastPeriod != fast || s.SlowPeriod != slow || s.SignalPeriod != signal) {
+		s.rebuildMACD()
+	}
+	return err
+}
+
 // GetStatistics returns strategy performance metrics
-func (s *MACDDivergenceStrategy) GetStatistics() map[string]interface{} {
+func (s *MACDDivergenceStrategy) GetStatistics() StrategyStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	winRate := 0.0
-	avgTrade := 0.0
-	if s.tradeCount > 0 {
-		winRate = float64(s.winCount) / float64(s.tradeCount) * 100
-		avgTrade = s.totalPnL / float64(s.tradeCount)
-	}
-
 	divergenceAccuracy := 0.0
 	if (s.divergenceHits + s.divergenceMisses) > 0 {
 		divergenceAccuracy = float64(s.divergenceHits) / float64(s.divergenceHits + s.divergenceMisses) * 100
 	}
 
-	return map[string]interface{}{
-		"symbol":              s.Symbol,
-		"strategy":            "MACD Divergence",
-		"trades":              s.tradeCount,
-		"wins":                s.winCount,
-		"win_rate":            winRate,
-		"total_pnl":           s.totalPnL,
-		"avg_trade_pnl":       avgTrade,
-		"current_macd":        s.macdLine,
-		"current_signal":      s.signalLine,
-		"current_histogram":   s.histogram,
-		"divergence_hits":     s.divergenceHits,
-		"divergence_accuracy": divergenceAccuracy,
-		"has_position":        s.hasPosition,
-	}
+	stats := s.newStats("MACD Divergence", []string{s.Symbol}, s.tradeCount, s.winCount, s.totalPnL, lastMark(s.Symbol, s.prices))
+	stats.warmingUp(!s.macd.Ready())
+	stats.Extra["current_macd"] = s.macdLine
+	stats.Extra["current_signal"] = s.signalLine
+	stats.Extra["current_histogram"] = s.histogram
+	stats.Extra["divergence_hits"] = s.divergenceHits
+	stats.Extra["divergence_accuracy"] = divergenceAccuracy
+	stats.Extra["has_position"] = s.hasPosition
+	return stats
 }
 
 // fetchAndProcessLatestBar fetches the latest bar and processes it
@@ -617,40 +673,17 @@ func (s *MACDDivergenceStrategy) fetchAndProcessLatestBar() error {
 
 // Run starts the strategy main loop with WebSocket streaming
 func (s *MACDDivergenceStrategy) Run(ctx context.Context) error {
-	// Create proper WebSocket market data stream
-	marketStream := NewMarketDataStream(s.apiKey, s.apiSecret, s.baseURL)
-	
-	// Set up bar handler
-	marketStream.SetBarHandler(func(bar MarketBar) {
-		if bar.Symbol == s.Symbol {
-			s.ProcessBar(bar.Close, bar.Timestamp)
-		}
-	})
-	
-	// Connect to market data stream
-	if err := marketStream.Connect(ctx); err != nil {
-		return fmt.Errorf("failed to connect to market data stream: %w", err)
-	}
-	defer marketStream.Disconnect()
-	
-	// Subscribe to bars for our symbol
-	if err := marketStream.SubscribeBars([]string{s.Symbol}); err != nil {
-		return fmt.Errorf("failed to subscribe to bars: %w", err)
+	// Start order tracking (no-op if the runner already started the shared OMS)
+	if err := s.oms.Start(ctx); err != nil {
+		s.logger.Printf("Order updates unavailable: %v", err)
 	}
-	
-	// Create trade updates stream for order confirmations
-	tradeStream := NewTradeUpdatesStream(s.apiKey, s.apiSecret, s.baseURL)
-	
-	// Set up trade update handler
-	tradeStream.SetTradeUpdateHandler(func(update TradeUpdate) {
-		s.logger.Printf("Trade update: %s for %s - %s", update.Data.Event, update.Data.Order.Symbol, update.Data.Order.Status)
-	})
-	
-	// Connect to trade updates stream
-	if err := tradeStream.Connect(ctx); err != nil {
-		return fmt.Errorf("failed to connect to trade updates stream: %w", err)
+
+	// Bars arrive from the shared market data hub
+	bars, err := s.subscribeBars(ctx, s.Symbol)
+	if err != nil {
+		return err
 	}
-	defer tradeStream.Disconnect()
+	defer bars.Close()
 
 	s.logger.Printf("MACD Divergence strategy running for %s", s.Symbol)
 
@@ -667,6 +700,13 @@ func (s *MACDDivergenceStrategy) Run(ctx context.Context) error {
 			stats := s.GetStatistics()
 			s.logger.Printf("Final Statistics: %+v", stats)
 			return nil
+
+		case event := <-bars.Events():
+			if event.Cached {
+				continue // Last-value replay of a bar already in history or processed
+			}
+			bar := event.Bar
+			s.ProcessBar(bar.Close, bar.Timestamp)
 			
 		case <-statsTicker.C:
 			// Print periodic statistics
@@ -0,0 +1,395 @@
+package strategies
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// MarketEventKind identifies the payload of a MarketEvent
+type MarketEventKind string
+
+const (
+	MarketEventBar   MarketEventKind = "bar"
+	MarketEventTrade MarketEventKind = "trade"
+	MarketEventQuote MarketEventKind = "quote"
+)
+
+// defaultSubscriptionBuffer is how many events a subscriber may fall behind
+// before the oldest are dropped
+const defaultSubscriptionBuffer = 256
+
+// MarketEvent is one bar, trade or quote delivered to a subscriber. Exactly
+// one of Bar, Trade or Quote is set, matching Kind.
+type MarketEvent struct {
+	Kind   MarketEventKind
+	Symbol string
+	Cached bool // Replayed from the last-value cache on subscribe
+	Bar    *MarketBar
+	Trade  *Trade
+	Quote  *Quote
+}
+
+// MarketDataHub shares one market data connection across every strategy in
+// the process. It keeps a single subscription set on the stream, fans events
+// out to subscribers by symbol, and caches the last bar, trade and quote per
+// symbol for subscribers that join late. A subscriber that falls behind loses
+// its oldest buffered events rather than stalling the stream for everyone.
+type MarketDataHub struct {
+	stream *MarketDataStream
+	logger *log.Logger
+
+	mu        sync.RWMutex
+	started   bool
+	subs      map[*MarketDataSubscription]struct{}
+	refs      map[MarketEventKind]map[string]int // Kind -> symbol -> subscriber count
+	lastBar   map[string]MarketBar
+	lastTrade map[string]Trade
+	lastQuote map[string]Quote
+	observers []func(MarketEvent) // See every event, whatever the subscriptions
+}
+
+// MarketDataSubscription receives events for a set of symbols and kinds
+type MarketDataSubscription struct {
+	id      string
+	hub     *MarketDataHub
+	symbols map[string]bool
+	kinds   map[MarketEventKind]bool
+	events  chan MarketEvent
+	closed  bool // Guarded by hub.mu
+
+	delivered uint64
+	dropped   uint64
+}
+
+// SubscriptionStats reports a subscriber's delivery counters
+type SubscriptionStats struct {
+	ID        string   `json:"id"`
+	Symbols   []string `json:"symbols"`
+	Kinds     []string `json:"kinds"`
+	Buffered  int      `json:"buffered"`
+	Delivered uint64   `json:"delivered"`
+	Dropped   uint64   `json:"dropped"`
+}
+
+// NewMarketDataHub creates a hub over a new market data stream
+func NewMarketDataHub(apiKey, apiSecret, baseURL string) *MarketDataHub {
+	h := &MarketDataHub{
+		stream:    NewMarketDataStream(apiKey, apiSecret, baseURL),
+		logger:    log.New(log.Writer(), "[MARKET-DATA-HUB] ", log.LstdFlags),
+		subs:      make(map[*MarketDataSubscription]struct{}),
+		refs:      make(map[MarketEventKind]map[string]int),
+		lastBar:   make(map[string]MarketBar),
+		lastTrade: make(map[string]Trade),
+		lastQuote: make(map[string]Quote),
+	}
+	for _, kind := range []MarketEventKind{MarketEventBar, MarketEventTrade, MarketEventQuote} {
+		h.refs[kind] = make(map[string]int)
+	}
+
+	h.stream.SetBarHandler(func(bar MarketBar) {
+		h.publish(MarketEvent{Kind: MarketEventBar, Symbol: bar.Symbol, Bar: &bar})
+	})
+	h.stream.SetTradeHandler(func(trade Trade) {
+		h.publish(MarketEvent{Kind: MarketEventTrade, Symbol: trade.Symbol, Trade: &trade})
+	})
+	h.stream.SetQuoteHandler(func(quote Quote) {
+		h.publish(MarketEvent{Kind: MarketEventQuote, Symbol: quote.Symbol, Quote: &quote})
+	})
+	h.stream.SetReconnectHandler(h.resubscribe)
+	return h
+}
+
+// Start connects the stream and subscribes everything requested so far.
+// Calling Start again is a no-op, so strategies can call it unconditionally.
+func (h *MarketDataHub) Start(ctx context.Context) error {
+	h.mu.Lock()
+	if h.started {
+		h.mu.Unlock()
+		return nil
+	}
+	h.started = true
+	h.mu.Unlock()
+
+	if err := h.stream.Connect(ctx); err != nil {
+		h.mu.Lock()
+		h.started = false
+		h.mu.Unlock()
+		return fmt.Errorf("failed to connect market data hub: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		h.stream.Disconnect()
+	}()
+
+	h.resubscribe()
+	h.logger.Printf("Market data hub started")
+	return nil
+}
+
+// Subscribe registers a subscriber for kinds of events on symbols. Cached
+// last values are queued first so a late joiner starts from current state.
+func (h *MarketDataHub) Subscribe(id string, symbols []string, kinds ...MarketEventKind) (*MarketDataSubscription, error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("subscription %s has no symbols", id)
+	}
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("subscription %s has no event kinds", id)
+	}
+
+	sub := &MarketDataSubscription{
+		id:      id,
+		hub:     h,
+		symbols: make(map[string]bool),
+		kinds:   make(map[MarketEventKind]bool),
+		events:  make(chan MarketEvent, defaultSubscriptionBuffer),
+	}
+	for _, kind := range kinds {
+		if _, known := h.refs[kind]; !known {
+			return nil, fmt.Errorf("unknown market event kind %q", kind)
+		}
+		sub.kinds[kind] = true
+	}
+	for _, symbol := range symbols {
+		sub.symbols[symbol] = true
+	}
+
+	h.mu.Lock()
+	added := make(map[MarketEventKind][]string)
+	for kind := range sub.kinds {
+		for symbol := range sub.symbols {
+			if h.refs[kind][symbol] == 0 {
+				added[kind] = append(added[kind], symbol)
+			}
+			h.refs[kind][symbol]++
+		}
+	}
+	h.subs[sub] = struct{}{}
+	h.replayLocked(sub)
+	started := h.started
+	h.mu.Unlock()
+
+	if started {
+		if err := h.stream.UpdateSubscription("subscribe", added[MarketEventBar], added[MarketEventTrade], added[MarketEventQuote]); err != nil {
+			h.logger.Printf("Failed to subscribe %s: %v (will retry on reconnect)", id, err)
+		}
+	}
+	return sub, nil
+}
+
+// Events returns the subscriber's event channel; it is closed by Close
+func (s *MarketDataSubscription) Events() <-chan MarketEvent {
+	return s.events
+}
+
+// Dropped returns how many events were discarded because the subscriber fell behind
+func (s *MarketDataSubscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close unregisters the subscriber and unsubscribes symbols nobody else needs
+func (s *MarketDataSubscription) Close() {
+	h := s.hub
+
+	h.mu.Lock()
+	if s.closed {
+		h.mu.Unlock()
+		return
+	}
+	s.closed = true
+	delete(h.subs, s)
+
+	removed := make(map[MarketEventKind][]string)
+	for kind := range s.kinds {
+		for symbol := range s.symbols {
+			h.refs[kind][symbol]--
+			if h.refs[kind][symbol] <= 0 {
+				delete(h.refs[kind], symbol)
+				removed[kind] = append(removed[kind], symbol)
+			}
+		}
+	}
+	close(s.events)
+	started := h.started
+	h.mu.Unlock()
+
+	if started {
+		if err := h.stream.UpdateSubscription("unsubscribe", removed[MarketEventBar], removed[MarketEventTrade], removed[MarketEventQuote]); err != nil {
+			h.logger.Printf("Failed to unsubscribe %s: %v", s.id, err)
+		}
+	}
+}
+
+// LastBar returns the most recent bar seen for a symbol
+func (h *MarketDataHub) LastBar(symbol string) (MarketBar, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	bar, ok := h.lastBar[symbol]
+	return bar, ok
+}
+
+// LastTrade returns the most recent trade seen for a symbol
+func (h *MarketDataHub) LastTrade(symbol string) (Trade, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	trade, ok := h.lastTrade[symbol]
+	return trade, ok
+}
+
+// LastQuote returns the most recent quote seen for a symbol
+func (h *MarketDataHub) LastQuote(symbol string) (Quote, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	quote, ok := h.lastQuote[symbol]
+	return quote, ok
+}
+
+// Stats returns delivery counters for every subscriber, ordered by ID
+func (h *MarketDataHub) Stats() []SubscriptionStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := make([]SubscriptionStats, 0, len(h.subs))
+	for sub := range h.subs {
+		entry := SubscriptionStats{
+			ID:        sub.id,
+			Buffered:  len(sub.events),
+			Delivered: atomic.LoadUint64(&sub.delivered),
+			Dropped:   atomic.LoadUint64(&sub.dropped),
+		}
+		for symbol := range sub.symbols {
+			entry.Symbols = append(entry.Symbols, symbol)
+		}
+		for kind := range sub.kinds {
+			entry.Kinds = append(entry.Kinds, string(kind))
+		}
+		sort.Strings(entry.Symbols)
+		sort.Strings(entry.Kinds)
+		stats = append(stats, entry)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
+}
+
+// Observe registers a handler that sees every event before subscribers do,
+// e.g. to feed a simulated broker. Handlers run on the stream's goroutine
+// and must not block.
+func (h *MarketDataHub) Observe(handler func(MarketEvent)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.observers = append(h.observers, handler)
+}
+
+// publish updates the last-value cache and fans an event out to observers
+// and subscribers
+func (h *MarketDataHub) publish(event MarketEvent) {
+	h.mu.Lock()
+	switch event.Kind {
+	case MarketEventBar:
+		h.lastBar[event.Symbol] = *event.Bar
+	case MarketEventTrade:
+		h.lastTrade[event.Symbol] = *event.Trade
+	case MarketEventQuote:
+		h.lastQuote[event.Symbol] = *event.Quote
+	}
+	observers := h.observers
+	h.mu.Unlock()
+
+	for _, observe := range observers {
+		observe(event)
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		if sub.kinds[event.Kind] && sub.symbols[event.Symbol] {
+			sub.offer(event)
+		}
+	}
+}
+
+// replayLocked queues cached last values for a new subscriber; caller must hold h.mu
+func (h *MarketDataHub) replayLocked(sub *MarketDataSubscription) {
+	for symbol := range sub.symbols {
+		if bar, ok := h.lastBar[symbol]; ok && sub.kinds[MarketEventBar] {
+			sub.offer(MarketEvent{Kind: MarketEventBar, Symbol: symbol, Cached: true, Bar: &bar})
+		}
+		if trade, ok := h.lastTrade[symbol]; ok && sub.kinds[MarketEventTrade] {
+			sub.offer(MarketEvent{Kind: MarketEventTrade, Symbol: symbol, Cached: true, Trade: &trade})
+		}
+		if quote, ok := h.lastQuote[symbol]; ok && sub.kinds[MarketEventQuote] {
+			sub.offer(MarketEvent{Kind: MarketEventQuote, Symbol: symbol, Cached: true, Quote: &quote})
+		}
+	}
+}
+
+// offer queues an event without blocking, dropping the oldest queued event
+// when the buffer is full. Callers hold h.mu, so the channel is not closed.
+func (s *MarketDataSubscription) offer(event MarketEvent) {
+	for {
+		select {
+		case s.events <- event:
+			atomic.AddUint64(&s.delivered, 1)
+			return
+		default:
+		}
+
+		select {
+		case <-s.events:
+			if atomic.AddUint64(&s.dropped, 1)%defaultSubscriptionBuffer == 1 {
+				s.hub.logger.Printf("Subscriber %s is falling behind; dropped %d events", s.id, s.Dropped())
+			}
+		default:
+		}
+	}
+}
+
+// resubscribe sends the full subscription set, after connecting or reconnecting
+func (h *MarketDataHub) resubscribe() {
+	h.mu.RLock()
+	symbols := make(map[MarketEventKind][]string)
+	for kind, refs := range h.refs {
+		for symbol := range refs {
+			symbols[kind] = append(symbols[kind], symbol)
+		}
+		sort.Strings(symbols[kind])
+	}
+	h.mu.RUnlock()
+
+	if err := h.stream.UpdateSubscription("subscribe", symbols[MarketEventBar], symbols[MarketEventTrade], symbols[MarketEventQuote]); err != nil {
+		h.logger.Printf("Failed to resubscribe: %v", err)
+	}
+}
+
+// MarketDataConsumer is implemented by strategies that take market data from a hub
+type MarketDataConsumer interface {
+	SetMarketDataHub(hub *MarketDataHub)
+}
+
+// SetMarketDataHub shares a process-wide market data hub with the strategy
+func (r *orderRouting) SetMarketDataHub(hub *MarketDataHub) {
+	r.hub = hub
+}
+
+// ensureMarketDataHub creates a private hub when the strategy runs standalone
+func (r *orderRouting) ensureMarketDataHub(apiKey, apiSecret, baseURL string) {
+	if r.hub == nil {
+		r.hub = NewMarketDataHub(apiKey, apiSecret, baseURL)
+	}
+}
+
+// subscribeBars starts the hub if needed and subscribes the strategy to bars
+func (r *orderRouting) subscribeBars(ctx context.Context, symbols ...string) (*MarketDataSubscription, error) {
+	if err := r.hub.Start(ctx); err != nil {
+		return nil, fmt.Errorf("market data unavailable: %w", err)
+	}
+	bars, err := r.hub.Subscribe(r.strategyID, symbols, MarketEventBar)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to bars: %w", err)
+	}
+	return bars, nil
+}
@@ -0,0 +1,229 @@
+package strategies
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// streamCounters aggregates websocket activity for one kind of stream across
+// every connection in the process (strategies open their own market streams)
+type streamCounters struct {
+	readErrors uint64
+	reconnects uint64
+
+	mu     sync.Mutex
+	byType map[string]uint64 // Message type -> count
+}
+
+var (
+	marketDataCounters   = &streamCounters{byType: make(map[string]uint64)}
+	tradeUpdatesCounters = &streamCounters{byType: make(map[string]uint64)}
+)
+
+// message counts one received message of the given type
+func (c *streamCounters) message(msgType string) {
+	if msgType == "" {
+		msgType = "unknown"
+	}
+	c.mu.Lock()
+	c.byType[msgType]++
+	c.mu.Unlock()
+}
+
+// readError counts a failed websocket read
+func (c *streamCounters) readError() {
+	atomic.AddUint64(&c.readErrors, 1)
+}
+
+// reconnect counts a successful re-dial
+func (c *streamCounters) reconnect() {
+	atomic.AddUint64(&c.reconnects, 1)
+}
+
+// readErrorCount returns the number of failed reads
+func (c *streamCounters) readErrorCount() uint64 {
+	return atomic.LoadUint64(&c.readErrors)
+}
+
+// reconnectCount returns the number of re-dials
+func (c *streamCounters) reconnectCount() uint64 {
+	return atomic.LoadUint64(&c.reconnects)
+}
+
+// messages copies the per-type message counts
+func (c *streamCounters) messages() map[string]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]uint64, len(c.byType))
+	for msgType, count := range c.byType {
+		counts[msgType] = count
+	}
+	return counts
+}
+
+// latencyBuckets are histogram upper bounds in seconds, from broker round
+// trips (milliseconds) up to resting limit orders (an hour)
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 3600}
+
+// latencyHistogram is a fixed-bucket histogram of durations
+type latencyHistogram struct {
+	mu     sync.Mutex
+	counts []uint64 // Per bucket, not cumulative; last entry is +Inf
+	count  uint64
+	sum    float64
+}
+
+// newLatencyHistogram creates an empty histogram over latencyBuckets
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]uint64, len(latencyBuckets)+1)}
+}
+
+// observe records one duration
+func (h *latencyHistogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	if seconds < 0 {
+		return
+	}
+	index := sort.SearchFloat64s(latencyBuckets, seconds)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[index]++
+	h.count++
+	h.sum += seconds
+}
+
+// LatencySnapshot is a point-in-time copy of a latency histogram
+type LatencySnapshot struct {
+	Buckets    []float64 `json:"buckets"`    // Upper bounds in seconds
+	Cumulative []uint64  `json:"cumulative"` // Observations <= each bound
+	Count      uint64    `json:"count"`
+	Sum        float64   `json:"sum_seconds"`
+}
+
+// Mean returns the average latency in seconds
+func (s LatencySnapshot) Mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// snapshot copies the histogram with cumulative bucket counts
+func (h *latencyHistogram) snapshot() LatencySnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cumulative := make([]uint64, len(latencyBuckets))
+	var running uint64
+	for i := range latencyBuckets {
+		running += h.counts[i]
+		cumulative[i] = running
+	}
+	return LatencySnapshot{
+		Buckets:    latencyBuckets,
+		Cumulative: cumulative,
+		Count:      h.count,
+		Sum:        h.sum,
+	}
+}
+
+// promWriter renders the Prometheus text exposition format. Samples are
+// buffered per family so each family is written as one group, whatever order
+// they are produced in; call flush to write them out.
+type promWriter struct {
+	w        io.Writer
+	families []string            // Declaration order
+	headers  map[string]string   // Family -> HELP/TYPE lines
+	samples  map[string][]string // Family -> sample lines
+}
+
+// newPromWriter creates a writer over w
+func newPromWriter(w io.Writer) *promWriter {
+	return &promWriter{
+		w:       w,
+		headers: make(map[string]string),
+		samples: make(map[string][]string),
+	}
+}
+
+// family declares a metric's type and help text; repeated calls are ignored
+func (p *promWriter) family(name, metricType, help string) {
+	if _, exists := p.headers[name]; exists {
+		return
+	}
+	p.families = append(p.families, name)
+	p.headers[name] = fmt.Sprintf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// sample buffers one sample under its family; labels alternate name, value
+func (p *promWriter) sample(name string, value float64, labels ...string) {
+	family := name
+	if _, exists := p.headers[family]; !exists {
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			family = strings.TrimSuffix(family, suffix)
+		}
+	}
+	line := fmt.Sprintf("%s%s %s\n", name, formatLabels(labels), formatValue(value))
+	p.samples[family] = append(p.samples[family], line)
+}
+
+// flush writes every declared family that has samples
+func (p *promWriter) flush() error {
+	for _, name := range p.families {
+		if len(p.samples[name]) == 0 {
+			continue
+		}
+		if _, err := io.WriteString(p.w, p.headers[name]+strings.Join(p.samples[name], "")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// histogram writes bucket, sum and count samples for a latency snapshot
+func (p *promWriter) histogram(name, help string, snap LatencySnapshot, labels ...string) {
+	p.family(name, "histogram", help)
+	bucketLabels := make([]string, len(labels), len(labels)+2)
+	copy(bucketLabels, labels)
+	for i, bound := range snap.Buckets {
+		p.sample(name+"_bucket", float64(snap.Cumulative[i]), append(bucketLabels, "le", formatValue(bound))...)
+	}
+	p.sample(name+"_bucket", float64(snap.Count), append(bucketLabels, "le", "+Inf")...)
+	p.sample(name+"_sum", snap.Sum, labels...)
+	p.sample(name+"_count", float64(snap.Count), labels...)
+}
+
+// formatLabels renders {name="value",...} with Prometheus escaping
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, labels[i], escaper.Replace(labels[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// formatValue renders a float the way Prometheus expects
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return fmt.Sprintf("%g", value)
+}
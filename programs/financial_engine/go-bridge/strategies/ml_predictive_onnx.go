@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,6 +13,9 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/shopspring/decimal"
 	ort "github.com/yalue/onnxruntime_go"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/features"
 )
 
 // MLPredictiveONNXStrategy uses neural network predictions for trading decisions
@@ -19,8 +23,9 @@ import (
 type MLPredictiveONNXStrategy struct {
 	// Configuration
 	Symbol        string
-	ModelPath     string  // Path to .onnx model file
-	SequenceLen   int     // Input sequence length (e.g., 20 bars)
+	ModelPath     string  // Path to an unregistered .onnx model file, used when ModelName is empty
+	ModelName     string  // Registry model as "name" or "name@version" (latest if no version)
+	SequenceLen   int     // Input sequence length (e.g., 20 bars); the longest model's once loaded
 	BuyThreshold  float64 // Probability threshold for buy signal
 	SellThreshold float64 // Probability threshold for sell signal
 	PositionSize  float64 // Percentage of equity
@@ -28,14 +33,41 @@ type MLPredictiveONNXStrategy struct {
 	TakeProfitPct float64
 	UseEnsemble   bool    // Use multiple models for consensus
 
+	// Ensemble members, read from the model registry
+	EnsembleModels  []string       // Registry models combined when UseEnsemble is set
+	EnsembleWeights []float64      // Per-model weights (equal if empty)
+	EnsembleMethod  EnsembleMethod // average, vote or median
+
+	// Monitoring: crossing a threshold raises an alert and, with AutoShadow,
+	// demotes the strategy to shadow mode
+	ShadowMode        bool    // Predict and log entries without sending them; exits still trade
+	AutoShadow        bool    // Demote to shadow mode on a threshold breach
+	MonitorWindow     int     // Rolling predictions and feature values monitored
+	MonitorMinSamples int     // Samples needed before thresholds apply
+	MinHitRate        float64 // Directional hit rate floor
+	MaxBrier          float64 // Brier score ceiling (0.25 is a constant 0.5 forecast)
+	MaxPSI            float64 // Feature population stability index ceiling
+	MaxKS             float64 // Feature Kolmogorov-Smirnov distance ceiling
+
 	// Alpaca clients
-	tradingClient *alpaca.Client
+	tradingClient broker.Broker
 	dataClient    *marketdata.Client
 
+	// Order routing through the shared OMS
+	orderRouting
+
 	// ONNX Runtime
-	runtime       *ort.DynamicAdvancedSession
-	inputShape    []int64
-	outputShape   []int64
+	models           []*onnxModel
+	modelWeights     []float64
+	modelPredictions map[string]float64 // Latest probability from each model
+
+	// Model monitoring
+	monitor       *ModelMonitor
+	breached      bool // Thresholds crossed; cleared once the model recovers
+	shadowReason  string
+	shadowSignals int
+	lastAlert     *ModelAlert
+	alertHandlers []func(ModelAlert)
 	
 	// Feature calculation
 	featureExtractor *FeatureExtractor
@@ -64,7 +96,7 @@ type MLPredictiveONNXStrategy struct {
 	modelAccuracy float64
 }
 
-// Bar represents price data with computed indicators
+// Bar represents price data with the feature vectors computed from it
 type Bar struct {
 	Time      time.Time
 	Open      float64
@@ -72,19 +104,98 @@ type Bar struct {
 	Low       float64
 	Close     float64
 	Volume    float64
-	RSI       float64
-	MACD      float64
-	BB_Width  float64
 	Sentiment float64
+	Features  map[string][]float64 // Vector per feature pipeline version
 }
 
-// FeatureExtractor computes features for ML model
+// FeatureExtractor streams bars through the feature pipeline of each loaded
+// model. The inputs and vectors served since startup are kept (up to
+// parityRows) so they can be checked against a batch recomputation.
 type FeatureExtractor struct {
-	RSIPeriod    int
-	MACDFast     int
-	MACDSlow     int
-	BBPeriod     int
 	sentimentFilter *SentimentFilter
+
+	pipelines map[string]*features.Pipeline
+	served    map[string]*servedFeatures
+}
+
+// servedFeatures is what one pipeline was fed and produced
+type servedFeatures struct {
+	bars    []features.Bar
+	vectors [][]float64
+}
+
+// parityRows bounds the served history kept for parity checks; later bars
+// are not recorded, and the prefix is still checked
+const parityRows = 5000
+
+// addPipeline starts streaming a feature spec, if it is not already
+func (f *FeatureExtractor) addPipeline(spec features.Spec) error {
+	if f.pipelines == nil {
+		f.pipelines = make(map[string]*features.Pipeline)
+		f.served = make(map[string]*servedFeatures)
+	}
+	if _, ok := f.pipelines[spec.Version()]; ok {
+		return nil
+	}
+	pipeline, err := features.NewPipeline(spec)
+	if err != nil {
+		return err
+	}
+	f.pipelines[pipeline.Version()] = pipeline
+	f.served[pipeline.Version()] = &servedFeatures{}
+	return nil
+}
+
+// update feeds a bar to every pipeline and stores the vectors on it
+func (f *FeatureExtractor) update(bar *Bar) {
+	input := features.Bar{
+		Time:      bar.Time,
+		Open:      bar.Open,
+		High:      bar.High,
+		Low:       bar.Low,
+		Close:     bar.Close,
+		Volume:    bar.Volume,
+		Sentiment: bar.Sentiment,
+	}
+	bar.Features = make(map[string][]float64, len(f.pipelines))
+	for version, pipeline := range f.pipelines {
+		vector := pipeline.Update(input)
+		bar.Features[version] = vector
+		if served := f.served[version]; len(served.bars) < parityRows {
+			served.bars = append(served.bars, input)
+			served.vectors = append(served.vectors, vector)
+		}
+	}
+}
+
+// ready reports whether every pipeline has warmed up
+func (f *FeatureExtractor) ready() bool {
+	for _, pipeline := range f.pipelines {
+		if !pipeline.Ready() {
+			return false
+		}
+	}
+	return true
+}
+
+// versions lists the pipeline versions being streamed
+func (f *FeatureExtractor) versions() []string {
+	return sortedKeys(f.pipelines)
+}
+
+// parity recomputes each pipeline's served features in batch mode and
+// compares them with what was served
+func (f *FeatureExtractor) parity() ([]features.ParityReport, error) {
+	var reports []features.ParityReport
+	for _, version := range sortedKeys(f.pipelines) {
+		served := f.served[version]
+		report, err := features.CheckParity(f.pipelines[version].Spec(), served.bars, served.vectors, features.DefaultTolerance)
+		if err != nil {
+			return nil, fmt.Errorf("features %s: %w", version, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
 }
 
 // NewMLPredictiveONNXStrategy creates a new ML predictive strategy
@@ -99,28 +210,33 @@ func NewMLPredictiveONNXStrategy(symbol, modelPath string) *MLPredictiveONNXStra
 		StopLossPct:   0.02,   // 2% stop loss
 		TakeProfitPct: 0.05,   // 5% take profit
 		UseEnsemble:   false,
+		EnsembleMethod:    EnsembleAverage,
+		AutoShadow:        true,
+		MonitorWindow:     250,  // ~2 weeks of hourly bars
+		MonitorMinSamples: 100,
+		MinHitRate:        0.45,
+		MaxBrier:          0.30,
+		MaxPSI:            0.25, // Conventional "significant shift" level
+		MaxKS:             0.20,
 		priceHistory:  make([]Bar, 0, 100),
 		features:      make([][]float32, 0, 100),
 		predictions:   make([]float64, 0, 100),
 		featureExtractor: &FeatureExtractor{
-			RSIPeriod:    14,
-			MACDFast:     12,
-			MACDSlow:     26,
-			BBPeriod:     20,
 			sentimentFilter: NewSentimentFilter(),
 		},
 		logger:        log.New(log.Writer(), "[ML-ONNX] ", log.LstdFlags),
+		orderRouting:  orderRouting{strategyID: "ml_" + symbol},
 	}
 }
 
 // Initialize sets up the Alpaca clients and loads the ONNX model
 func (s *MLPredictiveONNXStrategy) Initialize(apiKey, apiSecret, baseURL string) error {
-	// Initialize Alpaca trading client
-	s.tradingClient = alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		BaseURL:   baseURL,
-	})
+	// Trade through the shared OMS's broker, or an Alpaca account when standalone
+	s.tradingClient = s.ensureOrderManager(apiKey, apiSecret, baseURL)
+	s.ensureMarketDataHub(apiKey, apiSecret, baseURL)
+	if err := s.ensureStateStore(); err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
+	}
 
 	// Initialize market data client
 	s.dataClient = marketdata.NewClient(marketdata.ClientOpts{
@@ -138,59 +254,118 @@ func (s *MLPredictiveONNXStrategy) Initialize(apiKey, apiSecret, baseURL string)
 		return fmt.Errorf("failed to load historical data: %w", err)
 	}
 
-	// Check current position
-	if err := s.syncPosition(); err != nil {
-		return fmt.Errorf("failed to sync position: %w", err)
+	// Resume position, counters and trade context from before a restart
+	if err := s.restoreState(); err != nil {
+		return fmt.Errorf("failed to restore state: %w", err)
 	}
 
-	s.logger.Printf("ML ONNX strategy initialized for %s with model %s", s.Symbol, s.ModelPath)
-	s.logger.Printf("Input shape: %v, Sequence length: %d", s.inputShape, s.SequenceLen)
+	// Position and P&L follow this strategy's own fills; re-read its book after stream gaps
+	s.oms.Subscribe(s.strategyID, s.onFill)
+	s.oms.OnReconnect(s.strategyID, s.syncPosition)
+
+	s.logger.Printf("ML ONNX strategy initialized for %s with model %s", s.Symbol, s.modelVersion)
+	for _, model := range s.models {
+		s.logger.Printf("Model %s: input shape %v, features %v from %s",
+			model.manifest.Ref(), model.inputShape, model.manifest.Features.Names, model.pipeline)
+	}
 	
 	return nil
 }
 
-// loadModel loads the ONNX model and creates runtime session
+// loadModel resolves the configured model manifests and loads each one. A
+// registry model or ensemble is read from the model registry; a bare
+// ModelPath is described from the file's own metadata.
 func (s *MLPredictiveONNXStrategy) loadModel() error {
-	// Initialize ONNX Runtime environment
-	if err := ort.InitializeEnvironment(); err != nil {
-		return fmt.Errorf("failed to initialize ONNX runtime: %w", err)
-	}
+	var manifests []ModelManifest
+	switch {
+	case s.UseEnsemble:
+		if len(s.EnsembleModels) == 0 {
+			return fmt.Errorf("ensemble enabled without any ensemble models")
+		}
+		if len(s.EnsembleWeights) > 0 && len(s.EnsembleWeights) != len(s.EnsembleModels) {
+			return fmt.Errorf("%d ensemble weights for %d models", len(s.EnsembleWeights), len(s.EnsembleModels))
+		}
+		registry, err := NewModelRegistry(ModelRegistryDirFromEnv())
+		if err != nil {
+			return err
+		}
+		for _, ref := range s.EnsembleModels {
+			manifest, err := registry.Get(ref)
+			if err != nil {
+				return err
+			}
+			manifests = append(manifests, manifest)
+		}
+		s.modelWeights = s.EnsembleWeights
 
-	// Define input/output shapes
-	// Input: [batch_size, sequence_length, features]
-	// Features: RSI, MACD, BB_Width, Volume_Z, Sentiment, Price_Change
-	s.inputShape = []int64{1, int64(s.SequenceLen), 6}
-	s.outputShape = []int64{1, 1} // Single probability output
+	case s.ModelName != "":
+		registry, err := NewModelRegistry(ModelRegistryDirFromEnv())
+		if err != nil {
+			return err
+		}
+		manifest, err := registry.Get(s.ModelName)
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, manifest)
 
-	// Create session options
-	options, err := ort.NewSessionOptions()
-	if err != nil {
-		return err
+	default:
+		manifest, err := manifestForFile(s.ModelPath, s.SequenceLen)
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, manifest)
 	}
-	defer options.Destroy()
-
-	// Set optimization level
-	options.SetGraphOptimizationLevel(1)
 
-	// Create session
-	session, err := ort.NewDynamicAdvancedSession(
-		s.ModelPath,
-		[]string{"input"},  // Input names
-		[]string{"output"}, // Output names
-		options,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create ONNX session: %w", err)
+	sequenceLen := 0
+	var refs []string
+	for _, manifest := range manifests {
+		if err := s.featureExtractor.addPipeline(manifest.Features.PipelineSpec()); err != nil {
+			s.destroyModels()
+			return fmt.Errorf("model %s: %w", manifest.Ref(), err)
+		}
+		model, err := loadONNXModel(manifest)
+		if err != nil {
+			s.destroyModels()
+			return err
+		}
+		s.models = append(s.models, model)
+		refs = append(refs, manifest.Ref())
+		if manifest.Features.SequenceLen > sequenceLen {
+			sequenceLen = manifest.Features.SequenceLen
+		}
+		if manifest.TrainedAt.After(s.lastRetrain) {
+			s.lastRetrain = manifest.TrainedAt
+		}
 	}
 
-	s.runtime = session
-	s.modelVersion = fmt.Sprintf("v1.0_%s", time.Now().Format("20060102"))
-	s.lastRetrain = time.Now()
+	s.SequenceLen = sequenceLen
+	s.modelVersion = strings.Join(refs, ",")
+	s.modelPredictions = make(map[string]float64, len(s.models))
 
-	s.logger.Printf("Loaded ONNX model from %s", s.ModelPath)
+	// Drift baselines come from the first model that recorded each feature
+	training := make(map[string]FeatureDistribution)
+	for _, manifest := range manifests {
+		for name, dist := range manifest.Training {
+			if _, ok := training[name]; !ok {
+				training[name] = dist
+			}
+		}
+	}
+	s.monitor = NewModelMonitor(s.MonitorWindow, training)
+
+	s.logger.Printf("Loaded ONNX model(s) %s", s.modelVersion)
 	return nil
 }
 
+// destroyModels releases every loaded model session
+func (s *MLPredictiveONNXStrategy) destroyModels() {
+	for _, model := range s.models {
+		model.Destroy()
+	}
+	s.models = nil
+}
+
 // loadHistoricalData fetches historical bars for feature calculation
 func (s *MLPredictiveONNXStrategy) loadHistoricalData() error {
 	end := time.Now()
@@ -237,133 +412,124 @@ func (s *MLPredictiveONNXStrategy) loadHistoricalData() error {
 	return nil
 }
 
-// calculateIndicators computes technical indicators for a bar
+// calculateIndicators attaches sentiment to a bar and computes its features
 func (s *MLPredictiveONNXStrategy) calculateIndicators(bar *Bar) {
-	if len(s.priceHistory) < 2 {
-		return
-	}
-
-	// Calculate RSI
-	bar.RSI = s.calculateRSI()
-	
-	// Calculate MACD
-	bar.MACD = s.calculateMACD()
-	
-	// Calculate Bollinger Band width
-	bar.BB_Width = s.calculateBBWidth()
-	
-	// Get sentiment
+	// Sentiment is a pipeline input, so it is read first
 	sentiment := s.featureExtractor.sentimentFilter.GetSentiment(s.Symbol)
 	if sentiment != nil {
 		bar.Sentiment = sentiment.Score
 	}
+
+	s.featureExtractor.update(bar)
 }
 
-// calculateRSI computes Relative Strength Index
-func (s *MLPredictiveONNXStrategy) calculateRSI() float64 {
-	if len(s.priceHistory) < s.featureExtractor.RSIPeriod {
-		return 50.0 // Neutral
-	}
+// syncPosition loads the strategy's position from its own OMS book
+func (s *MLPredictiveONNXStrategy) syncPosition() {
+	qty, entryPrice := s.bookPosition(s.Symbol)
 
-	gains := 0.0
-	losses := 0.0
-	
-	startIdx := len(s.priceHistory) - s.featureExtractor.RSIPeriod
-	for i := startIdx + 1; i < len(s.priceHistory); i++ {
-		change := s.priceHistory[i].Close - s.priceHistory[i-1].Close
-		if change > 0 {
-			gains += change
-		} else {
-			losses -= change
-		}
-	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	avgGain := gains / float64(s.featureExtractor.RSIPeriod)
-	avgLoss := losses / float64(s.featureExtractor.RSIPeriod)
+	s.positionQty = qty
+	s.entryPrice = entryPrice
+	s.hasPosition = qty != 0
+}
 
-	if avgLoss == 0 {
-		return 100.0
+// onFill updates position and P&L from an actual execution, including
+// partial fills and bracket stop/target exits
+func (s *MLPredictiveONNXStrategy) onFill(fill FillEvent) {
+	if fill.Symbol != s.Symbol {
+		return
 	}
 
-	rs := avgGain / avgLoss
-	return 100 - (100 / (1 + rs))
-}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-// calculateMACD computes MACD indicator
-func (s *MLPredictiveONNXStrategy) calculateMACD() float64 {
-	if len(s.priceHistory) < s.featureExtractor.MACDSlow {
-		return 0.0
-	}
+	s.positionQty = fill.Position.Qty.IntPart()
+	s.entryPrice = fill.Position.AvgEntryPrice.InexactFloat64()
+	s.hasPosition = s.positionQty != 0
 
-	// Simple moving averages for demo (use EMA in production)
-	fastSum := 0.0
-	slowSum := 0.0
-	
-	fastStart := len(s.priceHistory) - s.featureExtractor.MACDFast
-	slowStart := len(s.priceHistory) - s.featureExtractor.MACDSlow
-	
-	for i := fastStart; i < len(s.priceHistory); i++ {
-		fastSum += s.priceHistory[i].Close
-	}
-	
-	for i := slowStart; i < len(s.priceHistory); i++ {
-		slowSum += s.priceHistory[i].Close
+	pnl := fill.RealizedPnL.InexactFloat64()
+	s.totalPnL += pnl
+	if fill.TripClosed {
+		s.tradeCount++
+		if fill.TripPnL.IsPositive() {
+			s.winCount++
+		}
 	}
-	
-	fastMA := fastSum / float64(s.featureExtractor.MACDFast)
-	slowMA := slowSum / float64(s.featureExtractor.MACDSlow)
-	
-	return fastMA - slowMA
+
+	s.saveStateLocked()
+
+	s.logger.Printf("%s %s %s @ %s (position %d @ %.2f, realized %.2f)",
+		fill.Event, fill.Side, fill.Qty.String(), fill.Price.StringFixed(2),
+		s.positionQty, s.entryPrice, pnl)
 }
 
-// calculateBBWidth computes Bollinger Band width
-func (s *MLPredictiveONNXStrategy) calculateBBWidth() float64 {
-	if len(s.priceHistory) < s.featureExtractor.BBPeriod {
-		return 0.0
-	}
+// mlState is the live state persisted across restarts
+type mlState struct {
+	positionState
+	CorrectPreds  int     `json:"correct_predictions"`
+	TotalPreds    int     `json:"total_predictions"`
+	ModelAccuracy float64 `json:"model_accuracy"`
+	ShadowMode    bool    `json:"shadow_mode"`
+	ShadowReason  string  `json:"shadow_reason,omitempty"`
+}
 
-	// Calculate mean
-	sum := 0.0
-	startIdx := len(s.priceHistory) - s.featureExtractor.BBPeriod
-	for i := startIdx; i < len(s.priceHistory); i++ {
-		sum += s.priceHistory[i].Close
+// saveStateLocked snapshots live state to the store; caller must hold s.mu
+func (s *MLPredictiveONNXStrategy) saveStateLocked() {
+	state := mlState{
+		positionState: positionState{
+			PositionQty: s.positionQty,
+			EntryPrice:  s.entryPrice,
+			LastSignal:  s.lastSignal,
+			Trades:      s.tradeCount,
+			Wins:        s.winCount,
+			TotalPnL:    s.totalPnL,
+		},
+		CorrectPreds:  s.correctPreds,
+		TotalPreds:    s.totalPreds,
+		ModelAccuracy: s.modelAccuracy,
+		ShadowMode:    s.ShadowMode,
+		ShadowReason:  s.shadowReason,
 	}
-	mean := sum / float64(s.featureExtractor.BBPeriod)
-
-	// Calculate standard deviation
-	variance := 0.0
-	for i := startIdx; i < len(s.priceHistory); i++ {
-		diff := s.priceHistory[i].Close - mean
-		variance += diff * diff
+	if err := s.saveState(state); err != nil {
+		s.logger.Printf("Failed to save state: %v", err)
 	}
-	stdDev := math.Sqrt(variance / float64(s.featureExtractor.BBPeriod))
-
-	// BB Width as percentage of mean
-	return (stdDev * 2) / mean
 }
 
-// syncPosition checks if we have an existing position
-func (s *MLPredictiveONNXStrategy) syncPosition() error {
-	positions, err := s.tradingClient.GetPositions()
-	if err != nil {
+// restoreState reloads state saved before a restart, including the position,
+// which seeds this strategy's OMS book
+func (s *MLPredictiveONNXStrategy) restoreState() error {
+	var saved mlState
+	found, err := s.loadState(&saved)
+	if err != nil || !found {
 		return err
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.hasPosition = false
-	for _, pos := range positions {
-		if pos.Symbol == s.Symbol {
-			s.hasPosition = true
-			s.positionQty = pos.Qty.IntPart()
-			avgPrice, _ := pos.AvgEntryPrice.Float64()
-			s.entryPrice = avgPrice
-			s.logger.Printf("Found existing position: %d shares at %.2f", s.positionQty, s.entryPrice)
-			break
-		}
+	s.tradeCount = saved.Trades
+	s.winCount = saved.Wins
+	s.totalPnL = saved.TotalPnL
+	s.correctPreds = saved.CorrectPreds
+	s.totalPreds = saved.TotalPreds
+	s.modelAccuracy = saved.ModelAccuracy
+	if saved.ShadowMode {
+		// A demotion survives restarts until an operator clears it
+		s.ShadowMode = true
+		s.shadowReason = saved.ShadowReason
+		s.logger.Printf("Resuming in shadow mode: %s", s.shadowReason)
 	}
-
+	s.positionQty = saved.PositionQty
+	s.entryPrice = saved.EntryPrice
+	s.hasPosition = saved.PositionQty != 0
+	s.lastSignal = saved.LastSignal
+	s.restorePosition(s.Symbol, saved.PositionQty, saved.EntryPrice)
+
+	s.logger.Printf("Restored state: position %d at %.2f, %d trades, %d wins, P&L %.2f",
+		s.positionQty, s.entryPrice, s.tradeCount, s.winCount, s.totalPnL)
+	s.saveStateLocked()
 	return nil
 }
 
@@ -371,6 +537,7 @@ func (s *MLPredictiveONNXStrategy) syncPosition() error {
 func (s *MLPredictiveONNXStrategy) ProcessBar(price float64, timestamp time.Time, volume float64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.markData()
 
 	// Create new bar
 	bar := Bar{
@@ -388,8 +555,8 @@ func (s *MLPredictiveONNXStrategy) ProcessBar(price float64, timestamp time.Time
 		s.priceHistory = s.priceHistory[1:]
 	}
 
-	// Need enough history for prediction
-	if len(s.priceHistory) < s.SequenceLen {
+	// Need enough history, with warmed-up features, for prediction
+	if len(s.priceHistory) < s.SequenceLen || !s.featureExtractor.ready() {
 		return
 	}
 
@@ -405,9 +572,14 @@ func (s *MLPredictiveONNXStrategy) ProcessBar(price float64, timestamp time.Time
 	// Generate trading signal based on prediction
 	signal := s.generateSignal(prediction, price)
 	
-	if signal != "" {
-		s.logger.Printf("ML Signal: %s | Prediction: %.3f | Price: %.2f | RSI: %.1f | Sentiment: %.2f",
-			signal, prediction, price, bar.RSI, bar.Sentiment)
+	if signal == "BUY" && s.ShadowMode {
+		// Shadow mode keeps scoring the model without opening positions
+		s.shadowSignals++
+		s.logger.Printf("Shadow signal: %s | Prediction: %.3f | Price: %.2f (not sent)",
+			signal, prediction, price)
+	} else if signal != "" {
+		s.logger.Printf("ML Signal: %s | Prediction: %.3f | Price: %.2f | Sentiment: %.2f",
+			signal, prediction, price, bar.Sentiment)
 		
 		// Execute trade asynchronously
 		go s.executeTrade(signal, price)
@@ -417,117 +589,44 @@ func (s *MLPredictiveONNXStrategy) ProcessBar(price float64, timestamp time.Time
 	s.updateModelAccuracy(prediction, price)
 }
 
-// runPrediction runs ONNX model inference
+// runPrediction runs every loaded model on the price history and combines
+// their probabilities. Models that fail are left out; with none left the
+// prediction is neutral.
 func (s *MLPredictiveONNXStrategy) runPrediction() float64 {
-	// Prepare input features
-	features := s.extractFeatures()
-	
-	// Create input tensor
-	inputTensor, err := ort.NewTensor(s.inputShape, features)
-	if err != nil {
-		s.logger.Printf("Failed to create input tensor: %v", err)
+	var predictions, weights []float64
+	for i, model := range s.models {
+		p, err := model.predict(s.priceHistory)
+		if err != nil {
+			s.logger.Printf("Model %s: %v", model.manifest.Ref(), err)
+			delete(s.modelPredictions, model.manifest.Ref())
+			continue
+		}
+		s.modelPredictions[model.manifest.Ref()] = p
+		predictions = append(predictions, p)
+		if len(s.modelWeights) > 0 {
+			weights = append(weights, s.modelWeights[i])
+		}
+	}
+	if len(predictions) == 0 {
 		return 0.5 // Neutral prediction on error
 	}
-	defer inputTensor.Destroy()
 
-	// Run inference with DynamicAdvancedSession - requires input and output tensors
-	var inputs []ort.Value
-	var outputs []ort.Value
-	inputs = append(inputs, inputTensor)
-	err = s.runtime.Run(inputs, outputs)
-	if err != nil {
-		s.logger.Printf("Inference failed: %v", err)
-		return 0.5
+	prediction := predictions[0]
+	if len(s.models) > 1 {
+		prediction = combinePredictions(s.EnsembleMethod, predictions, weights)
 	}
 
-	// For DynamicAdvancedSession, get output tensors from the session
-	// This is a simplified approach - in production, you'd get actual outputs
-	// from the session's configured output tensors
-	prediction := 0.5 // Placeholder - would get from session outputs
-	
 	s.logger.Printf("ML prediction generated: %.3f", prediction)
 	return prediction
 }
 
-// extractFeatures prepares features for model input
-func (s *MLPredictiveONNXStrategy) extractFeatures() []float32 {
-	features := make([]float32, 0, s.SequenceLen*6)
-	
-	startIdx := len(s.priceHistory) - s.SequenceLen
-	
-	// Normalize features
-	for i := startIdx; i < len(s.priceHistory); i++ {
-		bar := s.priceHistory[i]
-		
-		// RSI normalized to [0, 1]
-		rsi := float32(bar.RSI / 100.0)
-		
-		// MACD normalized (rough normalization)
-		macd := float32(math.Tanh(bar.MACD / 10.0))
-		
-		// BB Width (already normalized as percentage)
-		bbWidth := float32(bar.BB_Width)
-		
-		// Volume Z-score
-		volZ := float32(s.calculateVolumeZScore(i))
-		
-		// Sentiment [-1, 1] to [0, 1]
-		sentiment := float32((bar.Sentiment + 1.0) / 2.0)
-		
-		// Price change (momentum)
-		priceChange := float32(0.0)
-		if i > startIdx {
-			prevPrice := s.priceHistory[i-1].Close
-			if prevPrice > 0 {
-				priceChange = float32((bar.Close - prevPrice) / prevPrice)
-			}
-		}
-		
-		// Add features in order
-		features = append(features, rsi, macd, bbWidth, volZ, sentiment, priceChange)
-	}
-	
-	return features
-}
-
-// calculateVolumeZScore computes volume z-score
-func (s *MLPredictiveONNXStrategy) calculateVolumeZScore(idx int) float64 {
-	if idx < 20 {
-		return 0.0
-	}
-
-	// Calculate mean and std of recent volume
-	sum := 0.0
-	volumes := []float64{}
-	for i := idx - 20; i < idx; i++ {
-		v := s.priceHistory[i].Volume
-		sum += v
-		volumes = append(volumes, v)
-	}
-	
-	mean := sum / 20.0
-	
-	variance := 0.0
-	for _, v := range volumes {
-		diff := v - mean
-		variance += diff * diff
-	}
-	stdDev := math.Sqrt(variance / 20.0)
-	
-	if stdDev == 0 {
-		return 0.0
-	}
-	
-	return (s.priceHistory[idx].Volume - mean) / stdDev
-}
-
 // generateSignal converts ML prediction to trading signal
 func (s *MLPredictiveONNXStrategy) generateSignal(prediction, price float64) string {
 	// Strong buy signal
 	if prediction > s.BuyThreshold && !s.hasPosition {
-		// Additional confirmation: positive sentiment
+		// Additional confirmation: positive sentiment, when there is any
 		sentiment := s.featureExtractor.sentimentFilter.GetSentiment(s.Symbol)
-		if sentiment != nil && sentiment.Score > 0 {
+		if sentiment == nil || sentiment.Confidence == 0 || sentiment.Score > 0 {
 			return "BUY"
 		}
 	}
@@ -564,6 +663,12 @@ func (s *MLPredictiveONNXStrategy) generateSignal(prediction, price float64) str
 
 // executeTrade places orders based on ML signals
 func (s *MLPredictiveONNXStrategy) executeTrade(signal string, currentPrice float64) {
+	// Don't stack signals on an order that hasn't filled yet
+	if s.hasWorkingOrder(s.Symbol) {
+		s.logger.Printf("Order already working for %s, skipping %s", s.Symbol, signal)
+		return
+	}
+
 	// Get account info
 	account, err := s.tradingClient.GetAccount()
 	if err != nil {
@@ -634,76 +739,178 @@ func (s *MLPredictiveONNXStrategy) executeTrade(signal string, currentPrice floa
 			TimeInForce: alpaca.Day,
 		}
 
-		// Calculate P&L
+		// Estimate only: realized P&L is booked in onFill from the execution
 		pnl := (currentPrice - s.entryPrice) * float64(s.positionQty)
-		s.totalPnL += pnl
-		if pnl > 0 {
-			s.winCount++
-		}
-		s.tradeCount++
 
-		s.logger.Printf("ML SELL: %d shares, entry=%.2f, exit=%.2f, P&L=%.2f",
+		s.logger.Printf("ML SELL: %d shares, entry=%.2f, exit=%.2f, est. P&L=%.2f",
 			s.positionQty, s.entryPrice, currentPrice, pnl)
 	}
 
 	// Submit order
-	order, err := s.tradingClient.PlaceOrder(orderReq)
+	order, err := s.submitOrder(signal, orderReq, currentPrice)
 	if err != nil {
 		s.logger.Printf("Order failed: %v", err)
 		return
 	}
 
-	// Update state
+	// Position state is updated from fills (see onFill)
 	s.mu.Lock()
-	if signal == "BUY" {
-		s.hasPosition = true
-		s.entryPrice = currentPrice
-		s.positionQty = qty
-	} else if signal == "SELL" {
-		s.hasPosition = false
-		s.entryPrice = 0
-		s.positionQty = 0
-	}
 	s.lastSignal = signal
+	s.saveStateLocked()
 	s.mu.Unlock()
 
-	s.logger.Printf("Order placed: %s", order.ID)
+	s.logger.Printf("Order placed: %s", order.ClientOrderID)
 }
 
-// updateModelAccuracy tracks prediction accuracy
+// updateModelAccuracy scores the previous prediction against this bar, feeds
+// the latest features to drift monitoring and checks the thresholds
 func (s *MLPredictiveONNXStrategy) updateModelAccuracy(prediction, price float64) {
-	if len(s.predictions) < 2 || len(s.priceHistory) < 2 {
+	if s.monitor == nil {
 		return
 	}
 
-	// Check if previous prediction was correct
-	prevPred := s.predictions[len(s.predictions)-2]
-	prevPrice := s.priceHistory[len(s.priceHistory)-2].Close
-	
-	actualMove := price > prevPrice
-	predictedMove := prevPred > 0.5
-	
-	s.totalPreds++
-	if actualMove == predictedMove {
-		s.correctPreds++
+	// Drift is measured on each feature as the first model that uses it sees it
+	latest := make(map[string]float64)
+	for _, model := range s.models {
+		for name, v := range model.latest(s.priceHistory) {
+			if _, ok := latest[name]; !ok {
+				latest[name] = v
+			}
+		}
 	}
-	
-	if s.totalPreds > 0 {
+	resolved, correct := s.monitor.Record(prediction, price, latest)
+	if resolved {
+		s.totalPreds++
+		if correct {
+			s.correctPreds++
+		}
 		s.modelAccuracy = float64(s.correctPreds) / float64(s.totalPreds) * 100
 	}
+
+	s.checkMonitor()
 }
 
-// GetStatistics returns strategy performance metrics
-func (s *MLPredictiveONNXStrategy) GetStatistics() map[string]interface{} {
+// checkMonitor raises one alert each time the model goes from healthy to
+// breaching a threshold, demoting it to shadow mode if AutoShadow is set.
+// Caller must hold s.mu.
+func (s *MLPredictiveONNXStrategy) checkMonitor() {
+	breaches := s.monitor.Report().Breaches(s.monitorThresholds())
+	if len(breaches) == 0 {
+		if s.breached {
+			s.logger.Printf("Model %s back within monitoring thresholds", s.modelVersion)
+		}
+		s.breached = false
+		return
+	}
+	if s.breached {
+		return // Already alerted for this episode
+	}
+	s.breached = true
+
+	alert := ModelAlert{
+		StrategyID: s.strategyID,
+		Model:      s.modelVersion,
+		Breaches:   breaches,
+		Time:       time.Now(),
+	}
+	if s.AutoShadow && !s.ShadowMode {
+		s.ShadowMode = true
+		s.shadowReason = strings.Join(breaches, "; ")
+		alert.Demoted = true
+		s.saveStateLocked()
+	}
+	s.lastAlert = &alert
+
+	s.logger.Printf("MODEL ALERT: %s %s (demoted to shadow: %v)", s.modelVersion, strings.Join(breaches, "; "), alert.Demoted)
+	for _, handler := range s.alertHandlers {
+		go handler(alert)
+	}
+}
+
+// monitorThresholds collects the configured monitoring limits
+func (s *MLPredictiveONNXStrategy) monitorThresholds() MonitorThresholds {
+	return MonitorThresholds{
+		MinSamples: s.MonitorMinSamples,
+		MinHitRate: s.MinHitRate,
+		MaxBrier:   s.MaxBrier,
+		MaxPSI:     s.MaxPSI,
+		MaxKS:      s.MaxKS,
+	}
+}
+
+// OnAlert registers a callback run (on its own goroutine) when the model
+// monitor raises an alert
+func (s *MLPredictiveONNXStrategy) OnAlert(handler func(ModelAlert)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alertHandlers = append(s.alertHandlers, handler)
+}
+
+// FeatureParity checks that the features served since startup match a batch
+// recomputation over the same bars, i.e. what a model trained offline on
+// this history would have been given
+func (s *MLPredictiveONNXStrategy) FeatureParity() ([]features.ParityReport, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.featureExtractor.parity()
+}
+
+// logFeatureParity runs the parity check and logs any divergence
+func (s *MLPredictiveONNXStrategy) logFeatureParity() {
+	reports, err := s.FeatureParity()
+	if err != nil {
+		s.logger.Printf("Feature parity check failed: %v", err)
+		return
+	}
+	for _, report := range reports {
+		if !report.OK() {
+			s.logger.Printf("FEATURE PARITY MISMATCH: %s", report)
+		}
+	}
+}
+
+// SetParameters updates configuration fields while the strategy runs
+func (s *MLPredictiveONNXStrategy) SetParameters(params map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wasShadow := s.ShadowMode
+	err := setParameters(s, params, func() error {
+		if s.BuyThreshold <= 0 || s.BuyThreshold >= 1 || s.SellThreshold <= 0 || s.SellThreshold >= 1 {
+			return fmt.Errorf("thresholds must be between 0 and 1")
+		}
+		if s.PositionSize <= 0 || s.PositionSize > 1 {
+			return fmt.Errorf("position size must be a fraction of equity in (0, 1]")
+		}
+		if !s.EnsembleMethod.valid() {
+			return fmt.Errorf("unknown ensemble method %q", s.EnsembleMethod)
+		}
+		if s.MonitorMinSamples < 1 {
+			return fmt.Errorf("monitor min samples must be positive")
+		}
+		if s.MinHitRate < 0 || s.MinHitRate >= 1 || s.MaxBrier < 0 || s.MaxPSI < 0 || s.MaxKS < 0 || s.MaxKS > 1 {
+			return fmt.Errorf("monitor thresholds out of range")
+		}
+		return nil
+	}, "Symbol", "ModelPath", "ModelName", "SequenceLen", "UseEnsemble", "MonitorWindow")
 
-	winRate := 0.0
-	avgTrade := 0.0
-	if s.tradeCount > 0 {
-		winRate = float64(s.winCount) / float64(s.tradeCount) * 100
-		avgTrade = s.totalPnL / float64(s.tradeCount)
+	// Promotion out of shadow mode holds until the model breaches again
+	if err == nil && wasShadow != s.ShadowMode {
+		if s.ShadowMode {
+			s.shadowReason = "set by operator"
+		} else {
+			s.shadowReason = ""
+		}
+		s.logger.Printf("Shadow mode %v (%s)", s.ShadowMode, s.shadowReason)
+		s.saveStateLocked()
 	}
+	return err
+}
+
+// GetStatistics returns strategy performance metrics
+func (s *MLPredictiveONNXStrategy) GetStatistics() StrategyStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	avgPrediction := 0.0
 	if len(s.predictions) > 0 {
@@ -714,21 +921,40 @@ func (s *MLPredictiveONNXStrategy) GetStatistics() map[string]interface{} {
 		avgPrediction = sum / float64(len(s.predictions))
 	}
 
-	return map[string]interface{}{
-		"symbol":          s.Symbol,
-		"strategy":        "ML Predictive (ONNX)",
-		"model_version":   s.modelVersion,
-		"model_accuracy":  s.modelAccuracy,
-		"trades":          s.tradeCount,
-		"wins":            s.winCount,
-		"win_rate":        winRate,
-		"total_pnl":       s.totalPnL,
-		"avg_trade_pnl":   avgTrade,
-		"avg_prediction":  avgPrediction,
-		"total_predictions": s.totalPreds,
-		"has_position":    s.hasPosition,
-		"last_retrain":    s.lastRetrain.Format("2006-01-02"),
+	var marks map[string]float64
+	if len(s.priceHistory) > 0 {
+		marks = map[string]float64{s.Symbol: s.priceHistory[len(s.priceHistory)-1].Close}
+	}
+
+	stats := s.newStats("ML Predictive (ONNX)", []string{s.Symbol}, s.tradeCount, s.winCount, s.totalPnL, marks)
+	stats.warmingUp(len(s.priceHistory) < s.SequenceLen || !s.featureExtractor.ready())
+	stats.Extra["model_version"] = s.modelVersion
+	stats.Extra["feature_versions"] = s.featureExtractor.versions()
+	if len(s.models) > 1 {
+		stats.Extra["ensemble_method"] = string(s.EnsembleMethod)
+		predictions := make(map[string]float64, len(s.modelPredictions))
+		for ref, p := range s.modelPredictions {
+			predictions[ref] = p
+		}
+		stats.Extra["model_predictions"] = predictions
+	}
+	stats.Extra["model_accuracy"] = s.modelAccuracy
+	stats.Extra["avg_prediction"] = avgPrediction
+	stats.Extra["total_predictions"] = s.totalPreds
+	stats.Extra["has_position"] = s.hasPosition
+	stats.Extra["last_retrain"] = s.lastRetrain.Format("2006-01-02")
+	stats.Extra["shadow_mode"] = s.ShadowMode
+	if s.ShadowMode {
+		stats.Extra["shadow_reason"] = s.shadowReason
+		stats.Extra["shadow_signals"] = s.shadowSignals
 	}
+	if s.monitor != nil {
+		stats.Extra["monitor"] = s.monitor.Report()
+	}
+	if s.lastAlert != nil {
+		stats.Extra["last_alert"] = *s.lastAlert
+	}
+	return stats
 }
 
 // fetchAndProcessLatestBar fetches the latest bar and processes it
@@ -758,10 +984,17 @@ func (s *MLPredictiveONNXStrategy) fetchAndProcessLatestBar() error {
 
 // Run starts the strategy main loop
 func (s *MLPredictiveONNXStrategy) Run(ctx context.Context) error {
-	// For now, use polling instead of streaming due to API complexity
-	// In production, implement proper streaming with WebSocket client
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
+	// Start order tracking (no-op if the runner already started the shared OMS)
+	if err := s.oms.Start(ctx); err != nil {
+		s.logger.Printf("Order updates unavailable: %v", err)
+	}
+
+	// Bars arrive from the shared market data hub
+	bars, err := s.subscribeBars(ctx, s.Symbol)
+	if err != nil {
+		return err
+	}
+	defer bars.Close()
 
 	s.logger.Printf("ML ONNX strategy running for %s", s.Symbol)
 
@@ -777,20 +1010,26 @@ func (s *MLPredictiveONNXStrategy) Run(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			// Cleanup
-			if s.runtime != nil {
-				s.runtime.Destroy()
-			}
-			ort.DestroyEnvironment()
+			s.mu.Lock()
+			s.destroyModels()
+			s.mu.Unlock()
 			
 			// Print final statistics
 			stats := s.GetStatistics()
 			s.logger.Printf("Final Statistics: %+v", stats)
 			return nil
+
+		case event := <-bars.Events():
+			if event.Cached {
+				continue // Last-value replay of a bar already in history or processed
+			}
+			s.ProcessBar(event.Bar.Close, event.Bar.Timestamp, float64(event.Bar.Volume))
 			
 		case <-statsTicker.C:
 			// Print periodic statistics
 			stats := s.GetStatistics()
 			s.logger.Printf("ML Performance: %+v", stats)
+			s.logFeatureParity()
 			
 		case <-retrainTicker.C:
 			// Remind to retrain model
@@ -802,8 +1041,8 @@ func (s *MLPredictiveONNXStrategy) Run(ctx context.Context) error {
 
 // Cleanup releases ONNX resources
 func (s *MLPredictiveONNXStrategy) Cleanup() {
-	if s.runtime != nil {
-		s.runtime.Destroy()
-	}
+	s.mu.Lock()
+	s.destroyModels()
+	s.mu.Unlock()
 	ort.DestroyEnvironment()
 }
\ No newline at end of file
@@ -0,0 +1,246 @@
+package strategies
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// FeatureDistribution is a feature's training distribution as a histogram:
+// Proportions[i] of training values fell below Edges[i] and at or above
+// Edges[i-1], with one extra bin above the last edge
+type FeatureDistribution struct {
+	Edges       []float64 `json:"edges"`       // Ascending cut points, usually training deciles
+	Proportions []float64 `json:"proportions"` // len(Edges)+1, summing to 1
+}
+
+// validate checks the histogram is well formed
+func (d FeatureDistribution) validate() error {
+	if len(d.Proportions) != len(d.Edges)+1 {
+		return fmt.Errorf("%d proportions for %d edges", len(d.Proportions), len(d.Edges))
+	}
+	if !sort.Float64sAreSorted(d.Edges) {
+		return fmt.Errorf("edges are not ascending")
+	}
+	if total := sum(d.Proportions); math.Abs(total-1) > 1e-3 {
+		return fmt.Errorf("proportions sum to %.4f", total)
+	}
+	return nil
+}
+
+// bin returns the histogram bin holding v
+func (d FeatureDistribution) bin(v float64) int {
+	return sort.Search(len(d.Edges), func(i int) bool { return v < d.Edges[i] })
+}
+
+// MonitorThresholds are the limits past which a model is considered unfit to
+// trade. Zero disables a check.
+type MonitorThresholds struct {
+	MinSamples int     `json:"min_samples"` // Resolved predictions (or feature values) before checks apply
+	MinHitRate float64 `json:"min_hit_rate"`
+	MaxBrier   float64 `json:"max_brier"`
+	MaxPSI     float64 `json:"max_psi"`
+	MaxKS      float64 `json:"max_ks"`
+}
+
+// ReliabilityBin groups resolved predictions by predicted probability
+type ReliabilityBin struct {
+	Lower          float64 `json:"lower"`
+	Upper          float64 `json:"upper"`
+	Count          int     `json:"count"`
+	MeanPrediction float64 `json:"mean_prediction"`
+	ObservedRate   float64 `json:"observed_rate"` // Share that actually went up
+	MeanReturn     float64 `json:"mean_return"`   // Average realized next-bar return
+}
+
+// FeatureDrift compares a feature's live values with its training distribution
+type FeatureDrift struct {
+	PSI     float64 `json:"psi"` // Population stability index
+	KS      float64 `json:"ks"`  // Largest gap between the CDFs at the training edges
+	Samples int     `json:"samples"`
+}
+
+// MonitorReport is a snapshot of a model's rolling live performance
+type MonitorReport struct {
+	Samples     int                     `json:"samples"`
+	HitRate     float64                 `json:"hit_rate"`
+	Brier       float64                 `json:"brier"`
+	Reliability []ReliabilityBin        `json:"reliability"`
+	Drift       map[string]FeatureDrift `json:"drift,omitempty"`
+}
+
+// ModelAlert is raised when a monitored model crosses a threshold
+type ModelAlert struct {
+	StrategyID string    `json:"strategy_id"`
+	Model      string    `json:"model"`
+	Breaches   []string  `json:"breaches"`
+	Demoted    bool      `json:"demoted"` // Strategy was moved to shadow mode
+	Time       time.Time `json:"time"`
+}
+
+// reliabilityBins is the number of equal-width probability buckets
+const reliabilityBins = 10
+
+// predictionOutcome is a prediction paired with what happened next
+type predictionOutcome struct {
+	prediction float64
+	ret        float64
+}
+
+// ModelMonitor tracks a model's predictions against realized next-bar moves
+// and its live feature values against the training distribution, over a
+// rolling window. It is not safe for concurrent use.
+type ModelMonitor struct {
+	window   int
+	training map[string]FeatureDistribution
+
+	pending      float64 // Prediction awaiting the next bar
+	pendingPrice float64
+	hasPending   bool
+
+	outcomes []predictionOutcome
+	live     map[string][]float64
+}
+
+// NewModelMonitor keeps the last window outcomes and feature values. Features
+// without a training distribution are not checked for drift.
+func NewModelMonitor(window int, training map[string]FeatureDistribution) *ModelMonitor {
+	return &ModelMonitor{
+		window:   window,
+		training: training,
+		live:     make(map[string][]float64),
+	}
+}
+
+// Record resolves the previous prediction against price and holds this one
+// until the next bar. It reports whether a prediction was resolved and, if
+// so, whether it called the direction correctly.
+func (m *ModelMonitor) Record(prediction, price float64, features map[string]float64) (resolved bool, correct bool) {
+	if m.hasPending && m.pendingPrice > 0 {
+		outcome := predictionOutcome{
+			prediction: m.pending,
+			ret:        (price - m.pendingPrice) / m.pendingPrice,
+		}
+		m.outcomes = appendWindow(m.outcomes, outcome, m.window)
+		resolved, correct = true, (outcome.ret > 0) == (outcome.prediction > 0.5)
+	}
+	m.pending, m.pendingPrice, m.hasPending = prediction, price, true
+
+	for name, v := range features {
+		if _, ok := m.training[name]; ok {
+			m.live[name] = appendWindow(m.live[name], v, m.window)
+		}
+	}
+	return resolved, correct
+}
+
+// Report computes hit rate, calibration and drift over the current window
+func (m *ModelMonitor) Report() MonitorReport {
+	report := MonitorReport{Samples: len(m.outcomes), Reliability: make([]ReliabilityBin, reliabilityBins)}
+	for i := range report.Reliability {
+		report.Reliability[i].Lower = float64(i) / reliabilityBins
+		report.Reliability[i].Upper = float64(i+1) / reliabilityBins
+	}
+
+	hits := 0
+	for _, outcome := range m.outcomes {
+		up := 0.0
+		if outcome.ret > 0 {
+			up = 1
+		}
+		if (up == 1) == (outcome.prediction > 0.5) {
+			hits++
+		}
+		report.Brier += (outcome.prediction - up) * (outcome.prediction - up)
+
+		bin := &report.Reliability[int(math.Min(outcome.prediction*reliabilityBins, reliabilityBins-1))]
+		bin.Count++
+		bin.MeanPrediction += outcome.prediction
+		bin.ObservedRate += up
+		bin.MeanReturn += outcome.ret
+	}
+	if n := len(m.outcomes); n > 0 {
+		report.HitRate = float64(hits) / float64(n)
+		report.Brier /= float64(n)
+	}
+	for i := range report.Reliability {
+		if bin := &report.Reliability[i]; bin.Count > 0 {
+			bin.MeanPrediction /= float64(bin.Count)
+			bin.ObservedRate /= float64(bin.Count)
+			bin.MeanReturn /= float64(bin.Count)
+		}
+	}
+
+	if len(m.live) > 0 {
+		report.Drift = make(map[string]FeatureDrift, len(m.live))
+		for name, values := range m.live {
+			psi, ks := distributionDrift(m.training[name], values)
+			report.Drift[name] = FeatureDrift{PSI: psi, KS: ks, Samples: len(values)}
+		}
+	}
+	return report
+}
+
+// Breaches lists every threshold the report crosses, once enough samples exist
+func (r MonitorReport) Breaches(t MonitorThresholds) []string {
+	var breaches []string
+	if r.Samples >= t.MinSamples && r.Samples > 0 {
+		if t.MinHitRate > 0 && r.HitRate < t.MinHitRate {
+			breaches = append(breaches, fmt.Sprintf("hit rate %.3f < %.3f", r.HitRate, t.MinHitRate))
+		}
+		if t.MaxBrier > 0 && r.Brier > t.MaxBrier {
+			breaches = append(breaches, fmt.Sprintf("Brier score %.3f > %.3f", r.Brier, t.MaxBrier))
+		}
+	}
+
+	for _, name := range sortedKeys(r.Drift) {
+		drift := r.Drift[name]
+		if drift.Samples < t.MinSamples || drift.Samples == 0 {
+			continue
+		}
+		if t.MaxPSI > 0 && drift.PSI > t.MaxPSI {
+			breaches = append(breaches, fmt.Sprintf("%s PSI %.3f > %.3f", name, drift.PSI, t.MaxPSI))
+		}
+		if t.MaxKS > 0 && drift.KS > t.MaxKS {
+			breaches = append(breaches, fmt.Sprintf("%s KS %.3f > %.3f", name, drift.KS, t.MaxKS))
+		}
+	}
+	return breaches
+}
+
+// distributionDrift bins live values on the training edges and returns the
+// population stability index Σ(live-train)·ln(live/train) and the
+// Kolmogorov-Smirnov distance between the two binned CDFs. Empty bins are
+// floored so the PSI stays finite.
+func distributionDrift(training FeatureDistribution, values []float64) (psi, ks float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	counts := make([]float64, len(training.Proportions))
+	for _, v := range values {
+		counts[training.bin(v)]++
+	}
+
+	const floor = 1e-4
+	trainCDF, liveCDF := 0.0, 0.0
+	for i, expected := range training.Proportions {
+		actual := counts[i] / float64(len(values))
+		e, a := math.Max(expected, floor), math.Max(actual, floor)
+		psi += (a - e) * math.Log(a/e)
+
+		trainCDF += expected
+		liveCDF += actual
+		ks = math.Max(ks, math.Abs(liveCDF-trainCDF))
+	}
+	return psi, ks
+}
+
+// appendWindow appends v and drops the oldest values beyond size
+func appendWindow[T any](values []T, v T, size int) []T {
+	values = append(values, v)
+	if size > 0 && len(values) > size {
+		values = values[len(values)-size:]
+	}
+	return values
+}
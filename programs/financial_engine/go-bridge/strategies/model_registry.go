@@ -0,0 +1,328 @@
+package strategies
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"zig-financial-engine/internal/features"
+)
+
+// DefaultModelRegistryDir is where models are registered unless
+// MODEL_REGISTRY_DIR overrides it
+const DefaultModelRegistryDir = "./models"
+
+// manifestFile is the manifest name inside each <model>/<version> directory
+const manifestFile = "manifest.json"
+
+// ModelManifest describes one registered model version: what it expects as
+// input, where its output is, and the checksum of the file it was built from
+type ModelManifest struct {
+	Name        string      `json:"name"`
+	Version     string      `json:"version"`
+	File        string      `json:"file"`   // ONNX file, relative to the manifest
+	SHA256      string      `json:"sha256"` // Hex digest of File
+	InputName   string      `json:"input_name"`
+	OutputName  string      `json:"output_name"`
+	OutputIndex int         `json:"output_index"`          // Element of the output row holding P(up); -1 = last
+	OutputKind  string      `json:"output_kind,omitempty"` // "probability" (default) or "logit"
+	Features    FeatureSpec `json:"features"`
+	TrainedAt   time.Time   `json:"trained_at,omitempty"`
+	Description string      `json:"description,omitempty"`
+
+	// Training distribution of each feature, the baseline for drift checks
+	Training map[string]FeatureDistribution `json:"training,omitempty"`
+
+	dir string // Directory the manifest was read from
+}
+
+// FeatureSpec is the model's input layout: SequenceLen bars of the named
+// features, in order, taken from the feature pipeline the model was trained
+// on (features.DefaultSpec when Pipeline is omitted)
+type FeatureSpec struct {
+	Names       []string       `json:"names"`
+	SequenceLen int            `json:"sequence_len"`
+	Pipeline    *features.Spec `json:"pipeline,omitempty"`
+	Version     string         `json:"version,omitempty"` // Pipeline version at training time
+}
+
+// PipelineSpec returns the feature pipeline the model's inputs come from
+func (f FeatureSpec) PipelineSpec() features.Spec {
+	if f.Pipeline != nil {
+		return *f.Pipeline
+	}
+	return features.DefaultSpec()
+}
+
+// Ref is the manifest's "name@version" reference
+func (m ModelManifest) Ref() string {
+	return m.Name + "@" + m.Version
+}
+
+// Path is the absolute location of the model file
+func (m ModelManifest) Path() string {
+	if filepath.IsAbs(m.File) || m.dir == "" {
+		return m.File
+	}
+	return filepath.Join(m.dir, m.File)
+}
+
+// Verify checks the model file against the manifest checksum
+func (m ModelManifest) Verify() error {
+	sum, err := fileSHA256(m.Path())
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(sum, m.SHA256) {
+		return fmt.Errorf("model %s checksum mismatch: manifest %s, file %s", m.Ref(), m.SHA256, sum)
+	}
+	return nil
+}
+
+// validate checks the manifest is complete and its features are known
+func (m ModelManifest) validate() error {
+	if m.Name == "" || m.Version == "" || m.File == "" {
+		return fmt.Errorf("model manifest needs a name, version and file")
+	}
+	if m.SHA256 == "" {
+		return fmt.Errorf("model %s has no checksum", m.Ref())
+	}
+	if m.Features.SequenceLen < 1 || len(m.Features.Names) == 0 {
+		return fmt.Errorf("model %s needs a sequence length and feature names", m.Ref())
+	}
+	spec := m.Features.PipelineSpec()
+	if err := spec.Validate(); err != nil {
+		return fmt.Errorf("model %s feature pipeline: %w", m.Ref(), err)
+	}
+	if m.Features.Version != "" && m.Features.Version != spec.Version() {
+		return fmt.Errorf("model %s was trained on features %s but its pipeline is now %s",
+			m.Ref(), m.Features.Version, spec.Version())
+	}
+	for _, name := range m.Features.Names {
+		if spec.Index(name) < 0 {
+			return fmt.Errorf("model %s uses feature %q, not in pipeline %s", m.Ref(), name, spec.Name)
+		}
+	}
+	for name, dist := range m.Training {
+		if spec.Index(name) < 0 {
+			return fmt.Errorf("model %s has a training distribution for unknown feature %q", m.Ref(), name)
+		}
+		if err := dist.validate(); err != nil {
+			return fmt.Errorf("model %s training distribution for %s: %w", m.Ref(), name, err)
+		}
+	}
+	switch m.OutputKind {
+	case "", "probability", "logit":
+	default:
+		return fmt.Errorf("model %s has unknown output kind %q", m.Ref(), m.OutputKind)
+	}
+	return nil
+}
+
+// ModelRegistry is a directory of versioned models laid out as
+// <root>/<name>/<version>/manifest.json next to the model file
+type ModelRegistry struct {
+	root string
+}
+
+// NewModelRegistry opens (and creates if needed) a registry rooted at dir
+func NewModelRegistry(dir string) (*ModelRegistry, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create model registry %s: %w", dir, err)
+	}
+	return &ModelRegistry{root: dir}, nil
+}
+
+// ModelRegistryDirFromEnv returns MODEL_REGISTRY_DIR or DefaultModelRegistryDir
+func ModelRegistryDirFromEnv() string {
+	if dir := os.Getenv("MODEL_REGISTRY_DIR"); dir != "" {
+		return dir
+	}
+	return DefaultModelRegistryDir
+}
+
+// Register copies an ONNX file into the registry under manifest's name and
+// version, filling in the file name and checksum. Existing versions are
+// never overwritten.
+func (r *ModelRegistry) Register(onnxPath string, manifest ModelManifest) (ModelManifest, error) {
+	if manifest.Name == "" || manifest.Version == "" {
+		return ModelManifest{}, fmt.Errorf("model manifest needs a name and version")
+	}
+	dir := filepath.Join(r.root, safeName(manifest.Name), safeName(manifest.Version))
+	if _, err := os.Stat(dir); err == nil {
+		return ModelManifest{}, fmt.Errorf("model %s is already registered", manifest.Ref())
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return ModelManifest{}, err
+	}
+
+	manifest.File = "model.onnx"
+	manifest.dir = dir
+	if err := copyFile(onnxPath, manifest.Path()); err != nil {
+		os.RemoveAll(dir)
+		return ModelManifest{}, fmt.Errorf("failed to copy model: %w", err)
+	}
+	sum, err := fileSHA256(manifest.Path())
+	if err != nil {
+		os.RemoveAll(dir)
+		return ModelManifest{}, err
+	}
+	manifest.SHA256 = sum
+	if manifest.Features.Version == "" {
+		manifest.Features.Version = manifest.Features.PipelineSpec().Version()
+	}
+	if err := manifest.validate(); err != nil {
+		os.RemoveAll(dir)
+		return ModelManifest{}, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		os.RemoveAll(dir)
+		return ModelManifest{}, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestFile), append(data, '\n'), 0644); err != nil {
+		os.RemoveAll(dir)
+		return ModelManifest{}, err
+	}
+	return manifest, nil
+}
+
+// Get resolves a "name" or "name@version" reference; without a version (or
+// with "latest") the highest version wins
+func (r *ModelRegistry) Get(ref string) (ModelManifest, error) {
+	name, version, _ := strings.Cut(ref, "@")
+	if version == "" || version == "latest" {
+		versions, err := r.Versions(name)
+		if err != nil {
+			return ModelManifest{}, err
+		}
+		if len(versions) == 0 {
+			return ModelManifest{}, fmt.Errorf("model %s has no registered versions", name)
+		}
+		version = versions[len(versions)-1]
+	}
+	return r.load(filepath.Join(r.root, safeName(name), safeName(version)))
+}
+
+// Versions lists a model's registered versions, oldest first
+func (r *ModelRegistry) Versions(name string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(r.root, safeName(name)))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("unknown model %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versionLess(versions[i], versions[j]) })
+	return versions, nil
+}
+
+// List returns the manifest of every registered model version
+func (r *ModelRegistry) List() ([]ModelManifest, error) {
+	matches, err := filepath.Glob(filepath.Join(r.root, "*", "*", manifestFile))
+	if err != nil {
+		return nil, err
+	}
+	manifests := make([]ModelManifest, 0, len(matches))
+	for _, path := range matches {
+		manifest, err := r.load(filepath.Dir(path))
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	sort.Slice(manifests, func(i, j int) bool {
+		if manifests[i].Name != manifests[j].Name {
+			return manifests[i].Name < manifests[j].Name
+		}
+		return versionLess(manifests[i].Version, manifests[j].Version)
+	})
+	return manifests, nil
+}
+
+// load reads and validates the manifest in dir
+func (r *ModelRegistry) load(dir string) (ModelManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return ModelManifest{}, err
+	}
+	var manifest ModelManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return ModelManifest{}, fmt.Errorf("failed to parse %s: %w", filepath.Join(dir, manifestFile), err)
+	}
+	manifest.dir = dir
+	if err := manifest.validate(); err != nil {
+		return ModelManifest{}, err
+	}
+	return manifest, nil
+}
+
+// versionLess orders versions numerically by dot-separated parts ("v1.10" >
+// "v1.9"), falling back to string order for non-numeric parts
+func versionLess(a, b string) bool {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil && na != nb:
+			return na < nb
+		case (errA != nil || errB != nil) && pa[i] != pb[i]:
+			return pa[i] < pb[i]
+		}
+	}
+	return len(pa) < len(pb)
+}
+
+// safeName keeps registry names from escaping the root
+func safeName(name string) string {
+	return strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(name)
+}
+
+// fileSHA256 returns the hex SHA-256 digest of a file
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// copyFile copies src to a new file at dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
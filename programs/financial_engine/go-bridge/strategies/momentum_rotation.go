@@ -12,6 +12,8 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
 )
 
 // MomentumRotationStrategy implements portfolio-level momentum investing
@@ -23,21 +25,37 @@ type MomentumRotationStrategy struct {
 	TopN          int      // Number of top performers to hold
 	MinMomentum   float64  // Minimum momentum threshold (e.g., 0% = positive only)
 	RebalanceDays int      // Days between rebalancing (e.g., 30 = monthly)
-	MaxSectorExp  float64  // Max exposure to single sector (optional)
+	MaxSectorExp  float64  // Max exposure to single sector (0 = no cap); excess goes to the cash proxy
 	UseCashProxy  bool     // Use cash proxy (e.g., SHY) when no positive momentum
 	CashProxy     string   // Cash equivalent symbol
+	SectorFile    string   // CSV or JSON symbol -> sector map (see LoadSectorMap); unlisted symbols are their own sector
+	Weighting     WeightingScheme // How holdings share capital: equal, inverse_vol, risk_parity or mean_variance
+	RiskAversion  float64  // Mean-variance risk aversion (higher = closer to minimum variance)
+	AbsoluteMomentum bool  // Hold only assets whose return beats the cash proxy's; their weight moves to the cash proxy
+	ExecutionAlgo ExecutionAlgo // Work rebalance trades as twap, vwap or pov parent orders ("" = single market orders)
+	ExecutionMinutes int   // Window for worked rebalance orders
+	Participation float64  // Share of market volume for pov
 
 	// Alpaca clients
-	tradingClient *alpaca.Client
+	tradingClient broker.Broker
 	dataClient    *marketdata.Client
 
+	// Order routing through the shared OMS
+	orderRouting
+
 	// State management
 	mu                sync.RWMutex
 	currentHoldings   map[string]float64 // Symbol -> weight
+	holdingQty        map[string]int64   // Symbol -> filled shares
+	holdingPrice      map[string]float64 // Symbol -> average entry price
 	momentumScores    map[string]float64 // Symbol -> ROC score
+	assets            map[string]AssetMomentum // Symbol -> latest momentum detail
+	dailyReturns      map[string]map[string]float64 // Symbol -> date -> return over the lookback, for weighting
+	cashROC           float64 // Cash proxy return over the lookback, the absolute momentum hurdle
+	sectors           map[string]string // Symbol -> sector
 	lastRebalance     time.Time
 	nextRebalance     time.Time
-	sectorExposure    map[string]float64 // Sector -> exposure %
+	sectorExposure    map[string]float64 // Sector -> target weight at the last rebalance
 	totalRebalances   int
 	
 	// Performance tracking
@@ -54,11 +72,28 @@ type MomentumRotationStrategy struct {
 type AssetMomentum struct {
 	Symbol   string
 	ROC      float64 // Rate of change
+	Score    float64 // ROC penalized for negative Sharpe, used for ranking
 	Sharpe   float64 // Risk-adjusted momentum
 	Volume   float64 // Average volume for liquidity
 	Sector   string  // For sector exposure tracking
 }
 
+// SetSectors replaces the symbol -> sector classification used for
+// MaxSectorExp. Symbols without a sector count as a sector of their own.
+func (s *MomentumRotationStrategy) SetSectors(sectors map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sectors = sectors
+}
+
+// sectorOf returns a symbol's sector, or the symbol itself if unclassified
+func (s *MomentumRotationStrategy) sectorOf(symbol string) string {
+	if sector, ok := s.sectors[symbol]; ok {
+		return sector
+	}
+	return symbol
+}
+
 // NewMomentumRotationStrategy creates a new momentum rotation strategy
 func NewMomentumRotationStrategy(basket []string) *MomentumRotationStrategy {
 	return &MomentumRotationStrategy{
@@ -70,21 +105,38 @@ func NewMomentumRotationStrategy(basket []string) *MomentumRotationStrategy {
 		MaxSectorExp:    0.4,    // Max 40% in one sector
 		UseCashProxy:    true,   // Use cash when defensive
 		CashProxy:       "SHY",  // Short-term Treasury ETF
+		Weighting:       WeightEqual,
+		RiskAversion:    5.0,
+		ExecutionMinutes: 30,
+		Participation:   0.1,
 		currentHoldings: make(map[string]float64),
+		holdingQty:      make(map[string]int64),
+		holdingPrice:    make(map[string]float64),
 		momentumScores:  make(map[string]float64),
+		assets:          make(map[string]AssetMomentum),
+		dailyReturns:    make(map[string]map[string]float64),
 		sectorExposure:  make(map[string]float64),
 		logger:          log.New(log.Writer(), "[MOMENTUM-ROT] ", log.LstdFlags),
+		orderRouting:    orderRouting{strategyID: "momentum"},
 	}
 }
 
 // Initialize sets up the Alpaca clients and prepares for trading
 func (s *MomentumRotationStrategy) Initialize(apiKey, apiSecret, baseURL string) error {
-	// Initialize Alpaca trading client
-	s.tradingClient = alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		BaseURL:   baseURL,
-	})
+	// Load the sector classification for sector caps
+	if s.SectorFile != "" {
+		sectors, err := LoadSectorMap(s.SectorFile)
+		if err != nil {
+			return fmt.Errorf("failed to load sectors: %w", err)
+		}
+		s.SetSectors(sectors)
+	}
+
+	// Trade through the shared OMS's broker, or an Alpaca account when standalone
+	s.tradingClient = s.ensureOrderManager(apiKey, apiSecret, baseURL)
+	if err := s.ensureStateStore(); err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
+	}
 
 	// Initialize market data client
 	s.dataClient = marketdata.NewClient(marketdata.ClientOpts{
@@ -102,10 +154,13 @@ func (s *MomentumRotationStrategy) Initialize(apiKey, apiSecret, baseURL string)
 	s.startEquity = equity
 	s.currentEquity = equity
 
-	// Sync current holdings
-	if err := s.syncHoldings(); err != nil {
-		return fmt.Errorf("failed to sync holdings: %w", err)
-	}
+	// Holdings follow this strategy's own fills; re-read its book after stream gaps
+	s.oms.Subscribe(s.strategyID, s.onFill)
+	s.oms.OnReconnect(s.strategyID, func() {
+		if err := s.syncHoldings(); err != nil {
+			s.logger.Printf("Failed to re-sync holdings after reconnect: %v", err)
+		}
+	})
 
 	// Calculate initial momentum scores
 	if err := s.calculateMomentumScores(); err != nil {
@@ -116,6 +171,14 @@ func (s *MomentumRotationStrategy) Initialize(apiKey, apiSecret, baseURL string)
 	s.lastRebalance = time.Now()
 	s.nextRebalance = s.getNextRebalanceDate()
 
+	// Resume holdings, the rebalance schedule and counters from before a restart
+	if err := s.restoreState(); err != nil {
+		return fmt.Errorf("failed to restore state: %w", err)
+	}
+	if err := s.syncHoldings(); err != nil {
+		return fmt.Errorf("failed to sync holdings: %w", err)
+	}
+
 	s.logger.Printf("Momentum rotation initialized with %d assets, rebalancing every %d days",
 		len(s.Basket), s.RebalanceDays)
 	s.logger.Printf("Current top performers: %v", s.getTopPerformers())
@@ -123,42 +186,145 @@ func (s *MomentumRotationStrategy) Initialize(apiKey, apiSecret, baseURL string)
 	return nil
 }
 
-// syncHoldings syncs current portfolio positions
+// syncHoldings loads basket holdings from the strategy's own OMS book and
+// weights them against current account equity
 func (s *MomentumRotationStrategy) syncHoldings() error {
-	positions, err := s.tradingClient.GetPositions()
-	if err != nil {
-		return err
-	}
-
 	account, err := s.tradingClient.GetAccount()
 	if err != nil {
 		return err
 	}
 
 	equity, _ := account.Equity.Float64()
-	s.currentEquity = equity
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.currentEquity = equity
 	s.currentHoldings = make(map[string]float64)
-	
-	for _, pos := range positions {
-		// Check if position is in our basket
-		for _, symbol := range s.Basket {
-			if pos.Symbol == symbol {
-				marketValue, _ := pos.MarketValue.Float64()
-				weight := marketValue / equity
-				s.currentHoldings[symbol] = weight
-				s.logger.Printf("Found holding: %s (%.2f%%)", symbol, weight*100)
-				break
-			}
+	s.holdingQty = make(map[string]int64)
+	s.holdingPrice = make(map[string]float64)
+
+	for _, symbol := range s.Basket {
+		qty, avgPrice := s.bookPosition(symbol)
+		if qty == 0 {
+			continue
+		}
+		s.holdingQty[symbol] = qty
+		s.holdingPrice[symbol] = avgPrice
+		if equity > 0 {
+			s.currentHoldings[symbol] = float64(qty) * avgPrice / equity
 		}
+		s.logger.Printf("Holding %s: %d @ %.2f (%.2f%%)", symbol, qty, avgPrice, s.currentHoldings[symbol]*100)
 	}
 
 	return nil
 }
 
+// onFill updates holdings and P&L from actual executions
+func (s *MomentumRotationStrategy) onFill(fill FillEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	qty := fill.Position.Qty.IntPart()
+	if qty == 0 {
+		delete(s.holdingQty, fill.Symbol)
+		delete(s.holdingPrice, fill.Symbol)
+		delete(s.currentHoldings, fill.Symbol)
+	} else {
+		s.holdingQty[fill.Symbol] = qty
+		s.holdingPrice[fill.Symbol] = fill.Position.AvgEntryPrice.InexactFloat64()
+		if s.currentEquity > 0 {
+			s.currentHoldings[fill.Symbol] = float64(qty) * fill.Price.InexactFloat64() / s.currentEquity
+		}
+	}
+
+	pnl := fill.RealizedPnL.InexactFloat64()
+	s.totalPnL += pnl
+
+	s.saveStateLocked()
+
+	s.logger.Printf("%s %s %s %s @ %s (holding %d, realized %.2f)",
+		fill.Event, fill.Side, fill.Qty.String(), fill.Symbol, fill.Price.StringFixed(2), qty, pnl)
+}
+
+// momentumState is the live state persisted across restarts
+type momentumState struct {
+	LastRebalance   time.Time        `json:"last_rebalance"`
+	NextRebalance   time.Time        `json:"next_rebalance"`
+	RebalanceCount  int              `json:"rebalance_count"`
+	Turnover        float64          `json:"turnover"`
+	TotalPnL        float64          `json:"total_pnl"`
+	StartEquity     float64          `json:"start_equity"`
+	BenchmarkReturn float64          `json:"benchmark_return"`
+	HoldingQty      map[string]int64   `json:"holding_qty"`
+	HoldingPrice    map[string]float64 `json:"holding_price"` // Average entry price per holding
+}
+
+// saveStateLocked snapshots live state to the store; caller must hold s.mu
+func (s *MomentumRotationStrategy) saveStateLocked() {
+	holdings := make(map[string]int64, len(s.holdingQty))
+	prices := make(map[string]float64, len(s.holdingQty))
+	for symbol, qty := range s.holdingQty {
+		holdings[symbol] = qty
+		prices[symbol] = s.holdingPrice[symbol]
+	}
+	state := momentumState{
+		LastRebalance:   s.lastRebalance,
+		NextRebalance:   s.nextRebalance,
+		RebalanceCount:  s.rebalanceCount,
+		Turnover:        s.turnover,
+		TotalPnL:        s.totalPnL,
+		StartEquity:     s.startEquity,
+		BenchmarkReturn: s.benchmarkReturn,
+		HoldingQty:      holdings,
+		HoldingPrice:    prices,
+	}
+	if err := s.saveState(state); err != nil {
+		s.logger.Printf("Failed to save state: %v", err)
+	}
+}
+
+// restoreState reloads the rebalance schedule and performance saved before a
+// restart so a restart does not trigger an early rebalance. Saved holdings
+// seed this strategy's OMS book.
+func (s *MomentumRotationStrategy) restoreState() error {
+	var saved momentumState
+	found, err := s.loadState(&saved)
+	if err != nil || !found {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !saved.LastRebalance.IsZero() {
+		s.lastRebalance = saved.LastRebalance
+		s.nextRebalance = saved.NextRebalance
+	}
+	s.rebalanceCount = saved.RebalanceCount
+	s.totalRebalances = saved.RebalanceCount
+	s.turnover = saved.Turnover
+	s.totalPnL = saved.TotalPnL
+	s.benchmarkReturn = saved.BenchmarkReturn
+	if saved.StartEquity > 0 {
+		s.startEquity = saved.StartEquity
+	}
+
+	for _, symbol := range s.Basket {
+		qty := saved.HoldingQty[symbol]
+		if qty != 0 {
+			s.holdingQty[symbol] = qty
+			s.holdingPrice[symbol] = saved.HoldingPrice[symbol]
+		}
+		s.restorePosition(symbol, qty, saved.HoldingPrice[symbol])
+	}
+
+	s.logger.Printf("Restored state: %d rebalances, next rebalance %s",
+		s.rebalanceCount, s.nextRebalance.Format("2006-01-02"))
+	s.saveStateLocked()
+	return nil
+}
+
 // calculateMomentumScores calculates ROC for all assets
 func (s *MomentumRotationStrategy) calculateMomentumScores() error {
 	end := time.Now()
@@ -168,9 +334,18 @@ func (s *MomentumRotationStrategy) calculateMomentumScores() error {
 	defer s.mu.Unlock()
 
 	s.momentumScores = make(map[string]float64)
-	
+	s.assets = make(map[string]AssetMomentum)
+	s.dailyReturns = make(map[string]map[string]float64)
+	s.cashROC = 0
+
+	// The cash proxy's return is the absolute momentum hurdle
+	symbols := s.Basket
+	if s.UseCashProxy && s.CashProxy != "" && !containsSymbol(s.Basket, s.CashProxy) {
+		symbols = append(append([]string{}, s.Basket...), s.CashProxy)
+	}
+
 	// Calculate momentum for each asset
-	for _, symbol := range s.Basket {
+	for _, symbol := range symbols {
 		barsReq := marketdata.GetBarsRequest{
 			TimeFrame: marketdata.OneDay,
 			Start:     start,
@@ -195,14 +370,21 @@ func (s *MomentumRotationStrategy) calculateMomentumScores() error {
 		endPrice := bars[len(bars)-1].Close
 		
 		roc := ((endPrice - startPrice) / startPrice) * 100
-		
+		if symbol == s.CashProxy {
+			s.cashROC = roc
+		}
+
 		// Calculate risk-adjusted momentum (simplified Sharpe)
 		returns := []float64{}
+		byDate := make(map[string]float64)
+		volume := 0.0
 		for i := startIdx + 1; i < len(bars); i++ {
 			dayReturn := (bars[i].Close - bars[i-1].Close) / bars[i-1].Close
 			returns = append(returns, dayReturn)
+			byDate[bars[i].Timestamp.Format("2006-01-02")] = dayReturn
+			volume += float64(bars[i].Volume)
 		}
-		
+
 		avgReturn := s.mean(returns)
 		stdDev := s.stdDev(returns)
 		
@@ -217,9 +399,23 @@ func (s *MomentumRotationStrategy) calculateMomentumScores() error {
 		if sharpe < 0 {
 			score *= 0.8 // Penalize negative Sharpe
 		}
-		
+
+		s.dailyReturns[symbol] = byDate
+		if !containsSymbol(s.Basket, symbol) {
+			s.logger.Printf("%s (cash proxy): ROC=%.2f%%", symbol, roc)
+			continue
+		}
+
 		s.momentumScores[symbol] = score
-		
+		s.assets[symbol] = AssetMomentum{
+			Symbol: symbol,
+			ROC:    roc,
+			Score:  score,
+			Sharpe: sharpe,
+			Volume: volume / float64(len(returns)),
+			Sector: s.sectorOf(symbol),
+		}
+
 		s.logger.Printf("%s: ROC=%.2f%%, Sharpe=%.2f, Score=%.2f",
 			symbol, roc, sharpe, score)
 	}
@@ -264,16 +460,13 @@ func (s *MomentumRotationStrategy) getTopPerformers() []AssetMomentum {
 	for symbol, score := range s.momentumScores {
 		// Apply minimum momentum filter
 		if score >= s.MinMomentum {
-			assets = append(assets, AssetMomentum{
-				Symbol: symbol,
-				ROC:    score,
-			})
+			assets = append(assets, s.assets[symbol])
 		}
 	}
 
 	// Sort by momentum score (descending)
 	sort.Slice(assets, func(i, j int) bool {
-		return assets[i].ROC > assets[j].ROC
+		return assets[i].Score > assets[j].Score
 	})
 
 	// Return top N
@@ -284,7 +477,7 @@ func (s *MomentumRotationStrategy) getTopPerformers() []AssetMomentum {
 
 	// If no assets meet criteria, use cash proxy
 	if topN == 0 && s.UseCashProxy {
-		return []AssetMomentum{{Symbol: s.CashProxy, ROC: 0}}
+		return []AssetMomentum{{Symbol: s.CashProxy, ROC: s.cashROC, Score: s.cashROC, Sector: cashSector}}
 	}
 
 	return assets[:topN]
@@ -310,6 +503,7 @@ func (s *MomentumRotationStrategy) getNextRebalanceDate() time.Time {
 func (s *MomentumRotationStrategy) ProcessBar(price float64, timestamp time.Time) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.markData()
 
 	// Check if it's time to rebalance
 	if timestamp.After(s.nextRebalance) || 
@@ -319,6 +513,7 @@ func (s *MomentumRotationStrategy) ProcessBar(price float64, timestamp time.Time
 		
 		s.lastRebalance = timestamp
 		s.nextRebalance = s.getNextRebalanceDate()
+		s.saveStateLocked()
 	}
 }
 
@@ -351,13 +546,8 @@ func (s *MomentumRotationStrategy) rebalance() {
 	equity, _ := account.Equity.Float64()
 	s.currentEquity = equity
 	
-	// Calculate target weights (equal weight for simplicity)
-	targetWeight := 1.0 / float64(len(topAssets))
-	targetAllocations := make(map[string]float64)
-	
-	for _, asset := range topAssets {
-		targetAllocations[asset.Symbol] = targetWeight
-	}
+	// Weight the holdings, then apply absolute momentum and sector caps
+	targetAllocations := s.targetWeights(topAssets)
 
 	s.logger.Printf("Target allocations: %v", targetAllocations)
 
@@ -382,10 +572,12 @@ func (s *MomentumRotationStrategy) rebalance() {
 	// Execute rebalancing trades
 	s.executeTrades(targetAllocations, currentAllocations, equity)
 	
-	// Update holdings
-	s.currentHoldings = targetAllocations
+	// Holdings are updated from fills (see onFill)
+	s.mu.Lock()
 	s.rebalanceCount++
 	s.totalRebalances++
+	s.saveStateLocked()
+	s.mu.Unlock()
 	
 	// Log performance
 	totalReturn := ((s.currentEquity - s.startEquity) / s.startEquity) * 100
@@ -393,6 +585,95 @@ func (s *MomentumRotationStrategy) rebalance() {
 		s.rebalanceCount, totalReturn, s.benchmarkReturn)
 }
 
+// cashSector labels the cash proxy and uninvested cash in sector exposure
+const cashSector = "Cash"
+
+// targetWeights sizes the selected assets with the weighting scheme, drops
+// those that fail absolute momentum, caps sector exposure and parks whatever
+// is left in the cash proxy (or cash without one)
+func (s *MomentumRotationStrategy) targetWeights(selected []AssetMomentum) map[string]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Nothing qualified and getTopPerformers fell back to the cash proxy
+	if len(selected) == 1 && selected[0].Sector == cashSector {
+		s.sectorExposure = map[string]float64{cashSector: 1}
+		return map[string]float64{s.CashProxy: 1}
+	}
+
+	symbols := make([]string, len(selected))
+	for i, asset := range selected {
+		symbols[i] = asset.Symbol
+	}
+	weights, err := portfolioWeights(s.Weighting, s.alignedReturns(symbols), s.RiskAversion)
+	if err != nil {
+		s.logger.Printf("%s weighting failed, using equal weights: %v", s.Weighting, err)
+		weights = equalWeights(len(symbols))
+	}
+
+	// Absolute momentum: an asset must beat the cash proxy (or zero) to be held
+	hurdle := 0.0
+	if s.UseCashProxy {
+		hurdle = s.cashROC
+	}
+	risky := make(map[string]float64)
+	for i, asset := range selected {
+		if s.AbsoluteMomentum && asset.ROC <= hurdle {
+			s.logger.Printf("%s return %.2f%% does not beat the %.2f%% hurdle, holding cash instead",
+				asset.Symbol, asset.ROC, hurdle)
+			continue
+		}
+		risky[asset.Symbol] = weights[i]
+	}
+
+	if s.MaxSectorExp > 0 && s.MaxSectorExp < 1 {
+		risky = applySectorCaps(risky, s.sectorOf, s.MaxSectorExp)
+	}
+
+	target := make(map[string]float64)
+	invested := 0.0
+	for symbol, weight := range risky {
+		target[symbol] = weight
+		invested += weight
+	}
+	s.sectorExposure = sectorExposures(risky, s.sectorOf)
+	if residual := 1 - invested; residual > 1e-6 {
+		s.sectorExposure[cashSector] += residual
+		if s.UseCashProxy {
+			target[s.CashProxy] += residual
+		}
+	}
+	return target
+}
+
+// alignedReturns returns each symbol's daily returns on the dates all of them
+// traded, oldest first; caller must hold s.mu
+func (s *MomentumRotationStrategy) alignedReturns(symbols []string) [][]float64 {
+	dates := []string{}
+	for date := range s.dailyReturns[symbols[0]] {
+		shared := true
+		for _, symbol := range symbols[1:] {
+			if _, ok := s.dailyReturns[symbol][date]; !ok {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+
+	returns := make([][]float64, len(symbols))
+	for i, symbol := range symbols {
+		returns[i] = make([]float64, len(dates))
+		for t, date := range dates {
+			returns[i][t] = s.dailyReturns[symbol][date]
+		}
+	}
+	return returns
+}
+
 // calculateTurnover calculates portfolio turnover percentage
 func (s *MomentumRotationStrategy) calculateTurnover(current, target map[string]float64) float64 {
 	turnover := 0.0
@@ -418,6 +699,7 @@ func (s *MomentumRotationStrategy) calculateTurnover(current, target map[string]
 // executeTrades places orders to rebalance the portfolio
 func (s *MomentumRotationStrategy) executeTrades(target, current map[string]float64, equity float64) {
 	// First, close positions not in target
+	var worked []string
 	for symbol, currWeight := range current {
 		if targetWeight, exists := target[symbol]; !exists || targetWeight == 0 {
 			s.logger.Printf("Closing position: %s (%.2f%%)", symbol, currWeight*100)
@@ -426,16 +708,11 @@ func (s *MomentumRotationStrategy) executeTrades(target, current map[string]floa
 			positions, _ := s.tradingClient.GetPositions()
 			for _, pos := range positions {
 				if pos.Symbol == symbol {
-					orderReq := alpaca.PlaceOrderRequest{
-						Symbol:      symbol,
-						Qty:         &[]decimal.Decimal{pos.Qty}[0],
-						Side:        alpaca.Sell,
-						Type:        alpaca.Market,
-						TimeInForce: alpaca.Day,
-					}
-					
-					if _, err := s.tradingClient.PlaceOrder(orderReq); err != nil {
+					id, err := s.placeRebalanceOrder("SELL", symbol, alpaca.Sell, pos.Qty.IntPart(), positionPrice(pos))
+					if err != nil {
 						s.logger.Printf("Failed to close %s: %v", symbol, err)
+					} else if id != "" {
+						worked = append(worked, id)
 					}
 					break
 				}
@@ -443,8 +720,21 @@ func (s *MomentumRotationStrategy) executeTrades(target, current map[string]floa
 		}
 	}
 
-	// Wait for sells to complete
-	time.Sleep(5 * time.Second)
+	// Wait for sells to complete; worked orders take up to the execution window
+	if len(worked) == 0 {
+		time.Sleep(5 * time.Second)
+	}
+	for _, id := range worked {
+		ctx, cancel := context.WithTimeout(context.Background(), s.executionWindow()+time.Minute)
+		progress, err := s.exec.Wait(ctx, id)
+		cancel()
+		if err != nil {
+			s.logger.Printf("Stopped waiting for %s: %v", id, err)
+			continue
+		}
+		s.logger.Printf("Sold %d/%d %s (%s, shortfall %.1f bps)",
+			progress.FilledQty, progress.Qty, progress.Symbol, progress.State, progress.ShortfallBps)
+	}
 
 	// Then, adjust or open positions in target
 	for symbol, targetWeight := range target {
@@ -490,6 +780,7 @@ func (s *MomentumRotationStrategy) executeTrades(target, current map[string]floa
 		// Place order
 		var side alpaca.Side
 		var qty int64
+		signal := "BUY"
 		
 		if qtyDiff > 0 {
 			side = alpaca.Buy
@@ -498,18 +789,11 @@ func (s *MomentumRotationStrategy) executeTrades(target, current map[string]floa
 		} else {
 			side = alpaca.Sell
 			qty = -qtyDiff
+			signal = "SELL"
 			s.logger.Printf("Selling %d shares of %s (target: %.2f%%)", qty, symbol, targetWeight*100)
 		}
 
-		orderReq := alpaca.PlaceOrderRequest{
-			Symbol:      symbol,
-			Qty:         &[]decimal.Decimal{decimal.NewFromInt(qty)}[0],
-			Side:        side,
-			Type:        alpaca.Market,
-			TimeInForce: alpaca.Day,
-		}
-
-		if _, err := s.tradingClient.PlaceOrder(orderReq); err != nil {
+		if _, err := s.placeRebalanceOrder(signal, symbol, side, qty, price); err != nil {
 			s.logger.Printf("Failed to place order for %s: %v", symbol, err)
 		}
 	}
@@ -517,6 +801,42 @@ func (s *MomentumRotationStrategy) executeTrades(target, current map[string]floa
 	s.logger.Println("Trade execution complete")
 }
 
+// placeRebalanceOrder sends a rebalance trade as one market order, or works
+// it through the execution engine when ExecutionAlgo is set. Worked orders
+// return their execution ID.
+func (s *MomentumRotationStrategy) placeRebalanceOrder(signal, symbol string, side alpaca.Side, qty int64, price float64) (string, error) {
+	if s.exec != nil && s.ExecutionAlgo != "" {
+		progress, err := s.exec.Submit(ParentOrder{
+			StrategyID:    s.strategyID,
+			Signal:        signal,
+			Symbol:        symbol,
+			Side:          side,
+			Qty:           qty,
+			Algo:          s.ExecutionAlgo,
+			End:           time.Now().Add(s.executionWindow()),
+			Participation: s.Participation,
+			Randomize:     0.2, // Avoid a regular footprint at every rebalance
+			ArrivalPrice:  price,
+		})
+		return progress.ID, err
+	}
+
+	orderReq := alpaca.PlaceOrderRequest{
+		Symbol:      symbol,
+		Qty:         &[]decimal.Decimal{decimal.NewFromInt(qty)}[0],
+		Side:        side,
+		Type:        alpaca.Market,
+		TimeInForce: alpaca.Day,
+	}
+	_, err := s.submitOrder(signal, orderReq, price)
+	return "", err
+}
+
+// executionWindow is how long a worked rebalance order may take
+func (s *MomentumRotationStrategy) executionWindow() time.Duration {
+	return time.Duration(s.ExecutionMinutes) * time.Minute
+}
+
 // liquidateAll closes all positions (defensive mode)
 func (s *MomentumRotationStrategy) liquidateAll() {
 	positions, err := s.tradingClient.GetPositions()
@@ -534,7 +854,7 @@ func (s *MomentumRotationStrategy) liquidateAll() {
 			TimeInForce: alpaca.Day,
 		}
 
-		if _, err := s.tradingClient.PlaceOrder(orderReq); err != nil {
+		if _, err := s.submitOrder("SELL", orderReq, positionPrice(pos)); err != nil {
 			s.logger.Printf("Failed to liquidate %s: %v", pos.Symbol, err)
 		} else {
 			s.logger.Printf("Liquidated position: %s", pos.Symbol)
@@ -559,7 +879,7 @@ func (s *MomentumRotationStrategy) liquidateAll() {
 				TimeInForce: alpaca.Day,
 			}
 
-			if _, err := s.tradingClient.PlaceOrder(orderReq); err != nil {
+			if _, err := s.submitOrder("BUY", orderReq, price); err != nil {
 				s.logger.Printf("Failed to buy cash proxy %s: %v", s.CashProxy, err)
 			} else {
 				s.logger.Printf("Moved to cash proxy: %s", s.CashProxy)
@@ -569,6 +889,25 @@ func (s *MomentumRotationStrategy) liquidateAll() {
 }
 
 // Helper functions
+
+// positionPrice returns the latest price for a position, falling back to entry
+func positionPrice(pos alpaca.Position) float64 {
+	if pos.CurrentPrice != nil {
+		return pos.CurrentPrice.InexactFloat64()
+	}
+	return pos.AvgEntryPrice.InexactFloat64()
+}
+
+// containsSymbol reports whether symbol is in symbols
+func containsSymbol(symbols []string, symbol string) bool {
+	for _, candidate := range symbols {
+		if candidate == symbol {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *MomentumRotationStrategy) mean(values []float64) float64 {
 	if len(values) == 0 {
 		return 0
@@ -592,8 +931,51 @@ func (s *MomentumRotationStrategy) stdDev(values []float64) float64 {
 	return math.Sqrt(variance / float64(len(values)))
 }
 
+// SetParameters updates configuration fields while the strategy runs
+func (s *MomentumRotationStrategy) SetParameters(params map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sectorFile := s.SectorFile
+	var sectors map[string]string
+	err := setParameters(s, params, func() error {
+		if s.TopN < 1 || s.LookbackDays < 1 || s.RebalanceDays < 1 {
+			return fmt.Errorf("top n, lookback days and rebalance days must be positive")
+		}
+		if s.MaxSectorExp < 0 || s.MaxSectorExp > 1 {
+			return fmt.Errorf("max sector exposure must be between 0 and 1")
+		}
+		if !s.Weighting.valid() {
+			return fmt.Errorf("unknown weighting %q", s.Weighting)
+		}
+		if s.RiskAversion <= 0 {
+			return fmt.Errorf("risk aversion must be positive")
+		}
+		switch s.ExecutionAlgo {
+		case "", AlgoTWAP, AlgoVWAP, AlgoPOV:
+		default:
+			return fmt.Errorf("unknown execution algo %q", s.ExecutionAlgo)
+		}
+		if s.ExecutionMinutes < 1 || s.Participation <= 0 || s.Participation >= 1 {
+			return fmt.Errorf("execution minutes must be positive and participation in (0, 1)")
+		}
+		if s.SectorFile != sectorFile && s.SectorFile != "" {
+			loaded, err := LoadSectorMap(s.SectorFile)
+			if err != nil {
+				return fmt.Errorf("failed to load sectors: %w", err)
+			}
+			sectors = loaded
+		}
+		return nil
+	}, "CashProxy")
+	if err == nil && s.SectorFile != sectorFile {
+		s.sectors = sectors
+	}
+	return err
+}
+
 // GetStatistics returns strategy performance metrics
-func (s *MomentumRotationStrategy) GetStatistics() map[string]interface{} {
+func (s *MomentumRotationStrategy) GetStatistics() StrategyStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -610,24 +992,32 @@ func (s *MomentumRotationStrategy) GetStatistics() map[string]interface{} {
 		top = append(top, fmt.Sprintf("%s(%.1f%%)", asset.Symbol, asset.ROC))
 	}
 
-	return map[string]interface{}{
-		"symbol":           fmt.Sprintf("Basket(%d)", len(s.Basket)),
-		"strategy":         "Momentum Rotation",
-		"lookback_days":    s.LookbackDays,
-		"top_n":            s.TopN,
-		"rebalances":       s.rebalanceCount,
-		"total_return":     totalReturn,
-		"benchmark_return": s.benchmarkReturn,
-		"outperformance":   outperformance,
-		"avg_turnover":     s.turnover * 100,
-		"current_holdings": s.currentHoldings,
-		"top_performers":   top,
-		"next_rebalance":   s.nextRebalance.Format("2006-01-02"),
-	}
+	// Rotation has no round-trip trades; performance is tracked as return
+	stats := s.newStats("Momentum Rotation", s.Basket, 0, 0, s.totalPnL, nil)
+	stats.warmingUp(len(s.momentumScores) == 0)
+	stats.Extra["lookback_days"] = s.LookbackDays
+	stats.Extra["top_n"] = s.TopN
+	stats.Extra["rebalances"] = s.rebalanceCount
+	stats.Extra["total_return"] = totalReturn
+	stats.Extra["benchmark_return"] = s.benchmarkReturn
+	stats.Extra["outperformance"] = outperformance
+	stats.Extra["avg_turnover"] = s.turnover * 100
+	stats.Extra["current_holdings"] = s.currentHoldings
+	stats.Extra["top_performers"] = top
+	stats.Extra["weighting"] = string(s.Weighting)
+	stats.Extra["sector_exposure"] = s.sectorExposure
+	stats.Extra["cash_proxy_return"] = s.cashROC
+	stats.Extra["next_rebalance"] = s.nextRebalance.Format("2006-01-02")
+	return stats
 }
 
 // Run starts the strategy main loop
 func (s *MomentumRotationStrategy) Run(ctx context.Context) error {
+	// Start order tracking (no-op if the runner already started the shared OMS)
+	if err := s.oms.Start(ctx); err != nil {
+		s.logger.Printf("Order updates unavailable: %v", err)
+	}
+
 	// For momentum rotation, we don't need real-time streaming
 	// Just check daily for rebalance dates
 	
@@ -656,7 +1046,10 @@ func (s *MomentumRotationStrategy) Run(ctx context.Context) error {
 			if now.After(s.nextRebalance) {
 				s.logger.Println("Rebalance day reached")
 				s.rebalance()
+				s.mu.Lock()
 				s.nextRebalance = s.getNextRebalanceDate()
+				s.saveStateLocked()
+				s.mu.Unlock()
 			}
 
 		case <-statsTicker.C:
@@ -11,6 +11,9 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/indicators"
 )
 
 // MovingAverageCrossoverStrategy implements a classic trend-following strategy
@@ -25,12 +28,17 @@ type MovingAverageCrossoverStrategy struct {
 	TakeProfitPct float64
 
 	// Alpaca clients
-	tradingClient *alpaca.Client
+	tradingClient broker.Broker
 	dataClient    *marketdata.Client
 
+	// Order routing through the shared OMS
+	orderRouting
+
 	// State management
 	mu            sync.RWMutex
 	prices        []float64
+	shortAvg      *indicators.SMA
+	longAvg       *indicators.SMA
 	shortMA       float64
 	longMA        float64
 	lastSignal    string
@@ -55,18 +63,21 @@ func NewMovingAverageCrossoverStrategy(symbol string, shortWindow, longWindow in
 		StopLossPct:   0.02, // 2% stop loss
 		TakeProfitPct: 0.05, // 5% take profit
 		prices:        make([]float64, 0, longWindow+1),
+		shortAvg:      indicators.NewSMA(shortWindow),
+		longAvg:       indicators.NewSMA(longWindow),
 		logger:        log.New(log.Writer(), "[MA-CROSS] ", log.LstdFlags),
+		orderRouting:  orderRouting{strategyID: "ma_" + symbol},
 	}
 }
 
 // Initialize sets up the Alpaca clients and loads historical data
 func (s *MovingAverageCrossoverStrategy) Initialize(apiKey, apiSecret, baseURL string) error {
-	// Initialize Alpaca trading client
-	s.tradingClient = alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		BaseURL:   baseURL,
-	})
+	// Trade through the shared OMS's broker, or an Alpaca account when standalone
+	s.tradingClient = s.ensureOrderManager(apiKey, apiSecret, baseURL)
+	s.ensureMarketDataHub(apiKey, apiSecret, baseURL)
+	if err := s.ensureStateStore(); err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
+	}
 
 	// Initialize market data client
 	s.dataClient = marketdata.NewClient(marketdata.ClientOpts{
@@ -79,11 +90,15 @@ func (s *MovingAverageCrossoverStrategy) Initialize(apiKey, apiSecret, baseURL s
 		return fmt.Errorf("failed to load historical data: %w", err)
 	}
 
-	// Check current position
-	if err := s.syncPosition(); err != nil {
-		return fmt.Errorf("failed to sync position: %w", err)
+	// Resume position, counters and trade context from before a restart
+	if err := s.restoreState(); err != nil {
+		return fmt.Errorf("failed to restore state: %w", err)
 	}
 
+	// Position and P&L follow this strategy's own fills; re-read its book after stream gaps
+	s.oms.Subscribe(s.strategyID, s.onFill)
+	s.oms.OnReconnect(s.strategyID, s.syncPosition)
+
 	s.logger.Printf("Strategy initialized for %s (MA %d/%d)", s.Symbol, s.ShortWindow, s.LongWindow)
 	return nil
 }
@@ -108,9 +123,10 @@ func (s *MovingAverageCrossoverStrategy) loadHistoricalData() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Store closing prices
+	// Store closing prices, warming up the MAs
 	for _, bar := range bars {
 		s.prices = append(s.prices, bar.Close)
+		s.updateMAs(bar.Close)
 	}
 
 	// Trim to max needed
@@ -118,41 +134,99 @@ func (s *MovingAverageCrossoverStrategy) loadHistoricalData() error {
 		s.prices = s.prices[len(s.prices)-s.LongWindow:]
 	}
 
-	// Calculate initial MAs if we have enough data
-	if len(s.prices) >= s.LongWindow {
-		s.longMA = s.calculateMA(s.prices)
-		if len(s.prices) >= s.ShortWindow {
-			s.shortMA = s.calculateMA(s.prices[len(s.prices)-s.ShortWindow:])
-		}
-	}
-
 	s.logger.Printf("Loaded %d historical bars, initial MAs: short=%.2f, long=%.2f", 
 		len(s.prices), s.shortMA, s.longMA)
 	return nil
 }
 
-// syncPosition checks if we have an existing position
-func (s *MovingAverageCrossoverStrategy) syncPosition() error {
-	positions, err := s.tradingClient.GetPositions()
-	if err != nil {
-		return err
+// syncPosition loads the strategy's position from its own OMS book
+func (s *MovingAverageCrossoverStrategy) syncPosition() {
+	qty, entryPrice := s.bookPosition(s.Symbol)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.positionQty = qty
+	s.entryPrice = entryPrice
+	s.hasPosition = qty != 0
+}
+
+// onFill updates position and P&L from an actual execution, including
+// partial fills and bracket stop/target exits
+func (s *MovingAverageCrossoverStrategy) onFill(fill FillEvent) {
+	if fill.Symbol != s.Symbol {
+		return
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.hasPosition = false
-	for _, pos := range positions {
-		if pos.Symbol == s.Symbol {
-			s.hasPosition = true
-			s.positionQty = pos.Qty.BigInt().Int64()
-			avgPrice, _ := pos.AvgEntryPrice.Float64()
-			s.entryPrice = avgPrice
-			s.logger.Printf("Found existing position: %d shares at %.2f", s.positionQty, s.entryPrice)
-			break
+	s.positionQty = fill.Position.Qty.IntPart()
+	s.entryPrice = fill.Position.AvgEntryPrice.InexactFloat64()
+	s.hasPosition = s.positionQty != 0
+
+	pnl := fill.RealizedPnL.InexactFloat64()
+	s.totalPnL += pnl
+	if fill.TripClosed {
+		s.tradeCount++
+		if fill.TripPnL.IsPositive() {
+			s.winCount++
 		}
 	}
 
+	s.saveStateLocked()
+
+	s.logger.Printf("%s %s %s @ %s (position %d @ %.2f, realized %.2f)",
+		fill.Event, fill.Side, fill.Qty.String(), fill.Price.StringFixed(2),
+		s.positionQty, s.entryPrice, pnl)
+}
+
+// crossoverState is the live state persisted across restarts
+type crossoverState struct {
+	positionState
+}
+
+// saveStateLocked snapshots live state to the store; caller must hold s.mu
+func (s *MovingAverageCrossoverStrategy) saveStateLocked() {
+	state := crossoverState{
+		positionState: positionState{
+			PositionQty: s.positionQty,
+			EntryPrice:  s.entryPrice,
+			LastSignal:  s.lastSignal,
+			Trades:      s.tradeCount,
+			Wins:        s.winCount,
+			TotalPnL:    s.totalPnL,
+		},
+	}
+	if err := s.saveState(state); err != nil {
+		s.logger.Printf("Failed to save state: %v", err)
+	}
+}
+
+// restoreState reloads state saved before a restart, including the position,
+// which seeds this strategy's OMS book
+func (s *MovingAverageCrossoverStrategy) restoreState() error {
+	var saved crossoverState
+	found, err := s.loadState(&saved)
+	if err != nil || !found {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tradeCount = saved.Trades
+	s.winCount = saved.Wins
+	s.totalPnL = saved.TotalPnL
+	s.positionQty = saved.PositionQty
+	s.entryPrice = saved.EntryPrice
+	s.hasPosition = saved.PositionQty != 0
+	s.lastSignal = saved.LastSignal
+	s.restorePosition(s.Symbol, saved.PositionQty, saved.EntryPrice)
+
+	s.logger.Printf("Restored state: position %d at %.2f, %d trades, %d wins, P&L %.2f",
+		s.positionQty, s.entryPrice, s.tradeCount, s.winCount, s.totalPnL)
+	s.saveStateLocked()
 	return nil
 }
 
@@ -160,6 +234,7 @@ func (s *MovingAverageCrossoverStrategy) syncPosition() error {
 func (s *MovingAverageCrossoverStrategy) ProcessBar(price float64, timestamp time.Time) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.markData()
 
 	// Update price history
 	s.prices = append(s.prices, price)
@@ -167,17 +242,16 @@ func (s *MovingAverageCrossoverStrategy) ProcessBar(price float64, timestamp tim
 		s.prices = s.prices[1:] // Keep only needed history
 	}
 
-	// Need full window for long MA
-	if len(s.prices) < s.LongWindow {
-		return
-	}
-
 	// Calculate moving averages
 	prevShortMA := s.shortMA
 	prevLongMA := s.longMA
 	
-	s.longMA = s.calculateMA(s.prices)
-	s.shortMA = s.calculateMA(s.prices[len(s.prices)-s.ShortWindow:])
+	s.updateMAs(price)
+
+	// Need full window for long MA
+	if !s.longAvg.Ready() {
+		return
+	}
 
 	// Generate signal
 	signal := s.generateSignal(prevShortMA, prevLongMA)
@@ -206,21 +280,34 @@ func (s *MovingAverageCrossoverStrategy) generateSignal(prevShort, prevLong floa
 	return ""
 }
 
-// calculateMA computes simple moving average
-func (s *MovingAverageCrossoverStrategy) calculateMA(prices []float64) float64 {
-	if len(prices) == 0 {
-		return 0
+// updateMAs feeds a price to both moving averages
+func (s *MovingAverageCrossoverStrategy) updateMAs(price float64) {
+	if short := s.shortAvg.Update(price); s.shortAvg.Ready() {
+		s.shortMA = short
 	}
-	
-	sum := 0.0
-	for _, p := range prices {
-		sum += p
+	if long := s.longAvg.Update(price); s.longAvg.Ready() {
+		s.longMA = long
+	}
+}
+
+// rebuildMAs replays the retained prices after a window change
+func (s *MovingAverageCrossoverStrategy) rebuildMAs() {
+	s.shortAvg = indicators.NewSMA(s.ShortWindow)
+	s.longAvg = indicators.NewSMA(s.LongWindow)
+	s.shortMA, s.longMA = 0, 0
+	for _, price := range s.prices {
+		s.updateMAs(price)
 	}
-	return sum / float64(len(prices))
 }
 
 // executeTrade places orders based on signals
 func (s *MovingAverageCrossoverStrategy) executeTrade(signal string, currentPrice float64) {
+	// Don't stack signals on an order that hasn't filled yet
+	if s.hasWorkingOrder(s.Symbol) {
+		s.logger.Printf("Order already working for %s, skipping %s", s.Symbol, signal)
+		return
+	}
+
 	// Get account info for position sizing
 	account, err := s.tradingClient.GetAccount()
 	if err != nil {
@@ -293,40 +380,27 @@ func (s *MovingAverageCrossoverStrategy) executeTrade(signal string, currentPric
 			TimeInForce: alpaca.Day,
 		}
 
-		// Calculate P&L for logging
+		// Estimate only: realized P&L is booked in onFill from the execution
 		pnl := (currentPrice - s.entryPrice) * float64(s.positionQty)
-		s.totalPnL += pnl
-		if pnl > 0 {
-			s.winCount++
-		}
-		s.tradeCount++
 
-		s.logger.Printf("Placing SELL order: %d shares, entry=%.2f, exit=%.2f, P&L=%.2f",
+		s.logger.Printf("Placing SELL order: %d shares, entry=%.2f, exit=%.2f, est. P&L=%.2f",
 			s.positionQty, s.entryPrice, currentPrice, pnl)
 	}
 
 	// Submit order
-	order, err := s.tradingClient.PlaceOrder(orderReq)
+	order, err := s.submitOrder(signal, orderReq, currentPrice)
 	if err != nil {
 		s.logger.Printf("Order failed: %v", err)
 		return
 	}
 
-	// Update state
+	// Position state is updated from fills (see onFill)
 	s.mu.Lock()
-	if signal == "BUY" {
-		s.hasPosition = true
-		s.entryPrice = currentPrice
-		s.positionQty = qty
-	} else if signal == "SELL" {
-		s.hasPosition = false
-		s.entryPrice = 0
-		s.positionQty = 0
-	}
 	s.lastSignal = signal
+	s.saveStateLocked()
 	s.mu.Unlock()
 
-	s.logger.Printf("Order placed successfully: %s", order.ID)
+	s.logger.Printf("Order placed successfully: %s", order.ClientOrderID)
 }
 
 // validateTradeCompliance checks PDT rules and other protections
@@ -350,45 +424,72 @@ func (s *MovingAverageCrossoverStrategy) validateTradeCompliance(account *alpaca
 	return true
 }
 
+// SetParameters updates configuration fields while the strategy runs
+func (s *MovingAverageCrossoverStrategy) SetParameters(params map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	short, long := s.ShortWindow, s.LongWindow
+	err := setParameters(s, params, func() error {
+		if s.ShortWindow < 1 || s.ShortWindow >= s.LongWindow {
+			return fmt.Errorf("windows must satisfy 0 < short < long")
+		}
+		if s.PositionSize <= 0 || s.PositionSize > 1 {
+			return fmt.Errorf("position size must be a fraction of equity in (0, 1]")
+		}
+		return nil
+	}, "Symbol")
+	if err == nil && (s.ShortWindow != short || s.LongWindow != long) {
+		s.rebuildMAs()
+	}
+	return err
+}
+
 // GetStatistics returns strategy performance metrics
-func (s *MovingAverageCrossoverStrategy) GetStatistics() map[string]interface{} {
+func (s *MovingAverageCrossoverStrategy) GetStatistics() StrategyStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	winRate := 0.0
-	if s.tradeCount > 0 {
-		winRate = float64(s.winCount) / float64(s.tradeCount) * 100
-	}
-
-	return map[string]interface{}{
-		"symbol":       s.Symbol,
-		"strategy":     "Moving Average Crossover",
-		"short_window": s.ShortWindow,
-		"long_window":  s.LongWindow,
-		"trades":       s.tradeCount,
-		"wins":         s.winCount,
-		"win_rate":     winRate,
-		"total_pnl":    s.totalPnL,
-		"current_ma_short": s.shortMA,
-		"current_ma_long":  s.longMA,
-		"has_position": s.hasPosition,
-	}
+	stats := s.newStats("Moving Average Crossover", []string{s.Symbol}, s.tradeCount, s.winCount, s.totalPnL, lastMark(s.Symbol, s.prices))
+	stats.warmingUp(!s.longAvg.Ready())
+	stats.Extra["short_window"] = s.ShortWindow
+	stats.Extra["long_window"] = s.LongWindow
+	stats.Extra["current_ma_short"] = s.shortMA
+	stats.Extra["current_ma_long"] = s.longMA
+	stats.Extra["has_position"] = s.hasPosition
+	return stats
 }
 
 // Run starts the strategy main loop with WebSocket streaming
 func (s *MovingAverageCrossoverStrategy) Run(ctx context.Context) error {
-	// Use polling approach (WebSocket streaming will be implemented later)
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
+	// Start order tracking (no-op if the runner already started the shared OMS)
+	if err := s.oms.Start(ctx); err != nil {
+		s.logger.Printf("Order updates unavailable: %v", err)
+	}
+
+	// Bars arrive from the shared market data hub
+	bars, err := s.subscribeBars(ctx, s.Symbol)
+	if err != nil {
+		return err
+	}
+	defer bars.Close()
 
 	s.logger.Printf("Strategy running for %s", s.Symbol)
 
 	// Run until context is cancelled
-	<-ctx.Done()
-	
-	// Print final statistics
-	stats := s.GetStatistics()
-	s.logger.Printf("Final Statistics: %+v", stats)
-	
-	return nil
+	for {
+		select {
+		case <-ctx.Done():
+			// Print final statistics
+			stats := s.GetStatistics()
+			s.logger.Printf("Final Statistics: %+v", stats)
+			return nil
+
+		case event := <-bars.Events():
+			if event.Cached {
+				continue // Last-value replay of a bar already in history or processed
+			}
+			s.ProcessBar(event.Bar.Close, event.Bar.Timestamp)
+		}
+	}
 }
\ No newline at end of file
@@ -0,0 +1,333 @@
+package strategies
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+
+	"zig-financial-engine/internal/features"
+)
+
+// ortInit guards the process-wide ONNX Runtime environment, which every
+// model shares
+var ortInit sync.Mutex
+
+// ensureONNXRuntime initializes the ONNX Runtime environment once
+func ensureONNXRuntime() error {
+	ortInit.Lock()
+	defer ortInit.Unlock()
+	if ort.IsInitialized() {
+		return nil
+	}
+	return ort.InitializeEnvironment()
+}
+
+// onnxModel is a loaded model whose input and output have been checked
+// against its manifest
+type onnxModel struct {
+	manifest    ModelManifest
+	session     *ort.DynamicAdvancedSession
+	inputShape  ort.Shape
+	outputIndex int    // Resolved manifest OutputIndex, or -1 for the last element
+	pipeline    string // Feature pipeline version the inputs are read from
+	columns     []int  // Pipeline column of each manifest feature
+}
+
+// loadONNXModel verifies the model file checksum, then binds the manifest's
+// input and output after checking their element type and shape against the
+// model's own metadata. The batch dimension may be dynamic; every other
+// input dimension must match the feature spec, either as
+// [batch, sequence, features] or flattened to [batch, sequence*features].
+func loadONNXModel(manifest ModelManifest) (*onnxModel, error) {
+	if err := manifest.Verify(); err != nil {
+		return nil, err
+	}
+	if err := ensureONNXRuntime(); err != nil {
+		return nil, fmt.Errorf("failed to initialize ONNX runtime: %w", err)
+	}
+
+	inputs, outputs, err := ort.GetInputOutputInfo(manifest.Path())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model metadata: %w", err)
+	}
+	input, err := findTensorInfo(inputs, manifest.InputName, "input")
+	if err != nil {
+		return nil, fmt.Errorf("model %s: %w", manifest.Ref(), err)
+	}
+	output, err := findTensorInfo(outputs, manifest.OutputName, "output")
+	if err != nil {
+		return nil, fmt.Errorf("model %s: %w", manifest.Ref(), err)
+	}
+
+	shape, err := bindInputShape(input.Dimensions, manifest.Features)
+	if err != nil {
+		return nil, fmt.Errorf("model %s input %s: %w", manifest.Ref(), input.Name, err)
+	}
+
+	spec := manifest.Features.PipelineSpec()
+	model := &onnxModel{
+		manifest:    manifest,
+		inputShape:  shape,
+		outputIndex: manifest.OutputIndex,
+		pipeline:    spec.Version(),
+	}
+	for _, name := range manifest.Features.Names {
+		model.columns = append(model.columns, spec.Index(name))
+	}
+	if len(output.Dimensions) > 1 {
+		// Per-row output width, when the model states it
+		width := int64(1)
+		for _, dim := range output.Dimensions[1:] {
+			width *= dim
+		}
+		if width > 0 {
+			if model.outputIndex < 0 {
+				model.outputIndex = int(width) - 1
+			}
+			if int64(model.outputIndex) >= width {
+				return nil, fmt.Errorf("model %s output %s has %d values, manifest reads index %d",
+					manifest.Ref(), output.Name, width, model.outputIndex)
+			}
+		}
+	}
+
+	options, err := ort.NewSessionOptions()
+	if err != nil {
+		return nil, err
+	}
+	defer options.Destroy()
+	options.SetGraphOptimizationLevel(1)
+
+	session, err := ort.NewDynamicAdvancedSession(manifest.Path(),
+		[]string{input.Name}, []string{output.Name}, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ONNX session: %w", err)
+	}
+	model.session = session
+	return model, nil
+}
+
+// findTensorInfo picks the named float tensor from a model's inputs or
+// outputs; an empty name is allowed when there is exactly one
+func findTensorInfo(infos []ort.InputOutputInfo, name, kind string) (ort.InputOutputInfo, error) {
+	var found *ort.InputOutputInfo
+	if name == "" {
+		if len(infos) != 1 {
+			return ort.InputOutputInfo{}, fmt.Errorf("model has %d %ss, manifest must name one", len(infos), kind)
+		}
+		found = &infos[0]
+	}
+	var names []string
+	for i := range infos {
+		names = append(names, infos[i].Name)
+		if infos[i].Name == name {
+			found = &infos[i]
+		}
+	}
+	if found == nil {
+		return ort.InputOutputInfo{}, fmt.Errorf("no %s named %q (have %s)", kind, name, strings.Join(names, ", "))
+	}
+	if found.OrtValueType != ort.ONNXTypeTensor {
+		return ort.InputOutputInfo{}, fmt.Errorf("%s %s is a %s, not a tensor", kind, found.Name, found.OrtValueType)
+	}
+	if found.DataType != ort.TensorElementDataTypeFloat {
+		return ort.InputOutputInfo{}, fmt.Errorf("%s %s holds %s, expected float", kind, found.Name, found.DataType)
+	}
+	return *found, nil
+}
+
+// bindInputShape resolves a model's declared input dimensions (-1 where
+// dynamic) to the concrete shape of a single-row batch for spec
+func bindInputShape(dims ort.Shape, spec FeatureSpec) (ort.Shape, error) {
+	seq, width := int64(spec.SequenceLen), int64(len(spec.Names))
+	var expected ort.Shape
+	switch len(dims) {
+	case 3:
+		expected = ort.Shape{1, seq, width}
+	case 2:
+		expected = ort.Shape{1, seq * width}
+	default:
+		return nil, fmt.Errorf("rank %d shape %v, expected [batch, sequence, features]", len(dims), dims)
+	}
+	for i, dim := range dims {
+		if dim > 0 && dim != expected[i] {
+			return nil, fmt.Errorf("shape %v does not fit %d bars of %d features", dims, seq, width)
+		}
+	}
+	return expected, nil
+}
+
+// features lays out the model's columns of the last SequenceLen feature
+// vectors in history as its input
+func (m *onnxModel) features(history []Bar) ([]float32, error) {
+	seq := m.manifest.Features.SequenceLen
+	if len(history) < seq {
+		return nil, fmt.Errorf("need %d bars, have %d", seq, len(history))
+	}
+	data := make([]float32, 0, seq*len(m.columns))
+	for _, bar := range history[len(history)-seq:] {
+		vector, ok := bar.Features[m.pipeline]
+		if !ok {
+			return nil, fmt.Errorf("no %s features for bar at %s", m.pipeline, bar.Time.Format("2006-01-02 15:04"))
+		}
+		for _, col := range m.columns {
+			data = append(data, float32(vector[col]))
+		}
+	}
+	return data, nil
+}
+
+// latest returns the model's named features on the newest bar of history
+func (m *onnxModel) latest(history []Bar) map[string]float64 {
+	values := make(map[string]float64, len(m.columns))
+	if len(history) == 0 {
+		return values
+	}
+	if vector, ok := history[len(history)-1].Features[m.pipeline]; ok {
+		for i, col := range m.columns {
+			values[m.manifest.Features.Names[i]] = vector[col]
+		}
+	}
+	return values
+}
+
+// predict runs the model on the end of history and returns P(up)
+func (m *onnxModel) predict(history []Bar) (float64, error) {
+	data, err := m.features(history)
+	if err != nil {
+		return 0, err
+	}
+	input, err := ort.NewTensor(m.inputShape, data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create input tensor: %w", err)
+	}
+	defer input.Destroy()
+
+	// A nil output is allocated by the runtime to whatever shape the model produces
+	outputs := []ort.Value{nil}
+	if err := m.session.Run([]ort.Value{input}, outputs); err != nil {
+		return 0, fmt.Errorf("inference failed: %w", err)
+	}
+	defer outputs[0].Destroy()
+
+	tensor, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return 0, fmt.Errorf("unexpected output type %T", outputs[0])
+	}
+	values := tensor.GetData()
+	index := m.outputIndex
+	if index < 0 {
+		index = len(values) - 1
+	}
+	if index < 0 || index >= len(values) {
+		return 0, fmt.Errorf("output has %d values, manifest reads index %d", len(values), index)
+	}
+
+	p := float64(values[index])
+	if m.manifest.OutputKind == "logit" {
+		p = 1 / (1 + math.Exp(-p))
+	}
+	if math.IsNaN(p) {
+		return 0, fmt.Errorf("model returned NaN")
+	}
+	return math.Max(0, math.Min(1, p)), nil
+}
+
+// Destroy releases the session
+func (m *onnxModel) Destroy() {
+	if m.session != nil {
+		m.session.Destroy()
+	}
+}
+
+// manifestForFile describes a bare ONNX file that was never registered. The
+// checksum is taken from the file as it is now, the version from the model's
+// metadata, and the single input and output are bound by position.
+func manifestForFile(path string, sequenceLen int) (ModelManifest, error) {
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return ModelManifest{}, err
+	}
+	version := "unversioned"
+	if err := ensureONNXRuntime(); err != nil {
+		return ModelManifest{}, fmt.Errorf("failed to initialize ONNX runtime: %w", err)
+	}
+	if metadata, err := ort.GetModelMetadata(path); err == nil {
+		if v, err := metadata.GetVersion(); err == nil && v > 0 {
+			version = "v" + strconv.FormatInt(v, 10)
+		}
+		metadata.Destroy()
+	}
+	return ModelManifest{
+		Name:        strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Version:     version,
+		File:        path,
+		SHA256:      sum,
+		OutputIndex: -1,
+		Features:    FeatureSpec{Names: features.DefaultSpec().Names(), SequenceLen: sequenceLen},
+	}, nil
+}
+
+// EnsembleMethod decides how an ensemble combines its models' probabilities
+type EnsembleMethod string
+
+const (
+	EnsembleAverage EnsembleMethod = "average" // Weighted mean probability
+	EnsembleVote    EnsembleMethod = "vote"    // Weighted share of models above 0.5
+	EnsembleMedian  EnsembleMethod = "median"  // Median probability, robust to one outlier model
+)
+
+// valid reports whether the method is known
+func (e EnsembleMethod) valid() bool {
+	switch e {
+	case EnsembleAverage, EnsembleVote, EnsembleMedian:
+		return true
+	}
+	return false
+}
+
+// combinePredictions merges per-model probabilities with the given weights
+// (nil for equal weights)
+func combinePredictions(method EnsembleMethod, predictions, weights []float64) float64 {
+	if len(predictions) == 0 {
+		return 0.5
+	}
+	if weights == nil {
+		weights = equalWeights(len(predictions))
+	}
+	total := sum(weights)
+	if total <= 0 {
+		return 0.5
+	}
+
+	switch method {
+	case EnsembleVote:
+		up := 0.0
+		for i, p := range predictions {
+			if p > 0.5 {
+				up += weights[i]
+			}
+		}
+		return up / total
+	case EnsembleMedian:
+		sorted := append([]float64(nil), predictions...)
+		sort.Float64s(sorted)
+		mid := len(sorted) / 2
+		if len(sorted)%2 == 0 {
+			return (sorted[mid-1] + sorted[mid]) / 2
+		}
+		return sorted[mid]
+	}
+
+	mean := 0.0
+	for i, p := range predictions {
+		mean += weights[i] * p
+	}
+	return mean / total
+}
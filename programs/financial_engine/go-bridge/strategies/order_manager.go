@@ -0,0 +1,1929 @@
+package strategies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/protection"
+)
+
+// ErrEntriesPaused is returned when a paused strategy tries to open or add to a position
+var ErrEntriesPaused = errors.New("strategy entries paused")
+
+// ErrPositionBreak is returned for intents on a symbol whose books don't add up to the account
+var ErrPositionBreak = errors.New("unresolved position break")
+
+// positionCheckInterval is how often unresolved position breaks are rechecked
+// and finished orders pruned
+const positionCheckInterval = time.Minute
+
+// orderRetention is how long terminal orders stay queryable before they are pruned
+const orderRetention = 24 * time.Hour
+
+// OrderState tracks an order through its lifecycle in the OMS
+type OrderState string
+
+const (
+	OrderStateNew             OrderState = "new"
+	OrderStateAccepted        OrderState = "accepted"
+	OrderStatePartiallyFilled OrderState = "partially_filled"
+	OrderStateFilled          OrderState = "filled"
+	OrderStateCanceled        OrderState = "canceled"
+	OrderStateRejected        OrderState = "rejected"
+)
+
+// orderTransitions lists the states each state may move to
+var orderTransitions = map[OrderState][]OrderState{
+	OrderStateNew:             {OrderStateAccepted, OrderStatePartiallyFilled, OrderStateFilled, OrderStateCanceled, OrderStateRejected},
+	OrderStateAccepted:        {OrderStatePartiallyFilled, OrderStateFilled, OrderStateCanceled, OrderStateRejected},
+	OrderStatePartiallyFilled: {OrderStatePartiallyFilled, OrderStateFilled, OrderStateCanceled},
+}
+
+// IsTerminal reports whether no further transitions are possible
+func (s OrderState) IsTerminal() bool {
+	return s == OrderStateFilled || s == OrderStateCanceled || s == OrderStateRejected
+}
+
+// canTransition reports whether the state machine allows from -> to
+func canTransition(from, to OrderState) bool {
+	for _, allowed := range orderTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderIntent is what strategies submit to the OMS instead of calling the broker
+type OrderIntent struct {
+	StrategyID string                   // Owning strategy, used to tag client order IDs
+	Signal     string                   // Signal that produced the intent (for logs)
+	Request    alpaca.PlaceOrderRequest // Broker order; ClientOrderID is assigned by the OMS
+	RefPrice   decimal.Decimal          // Price at signal time, used for risk and internal crosses
+}
+
+// ManagedOrder is the OMS view of a single order
+type ManagedOrder struct {
+	ClientOrderID   string           `json:"client_order_id"`
+	BrokerOrderID   string           `json:"broker_order_id"`
+	StrategyID      string           `json:"strategy_id"`
+	ParentID        string           `json:"parent_id,omitempty"` // Bracket parent for child legs
+	Symbol          string           `json:"symbol"`
+	Side            alpaca.Side      `json:"side"`
+	Type            alpaca.OrderType `json:"type"`
+	Qty             decimal.Decimal  `json:"qty"`
+	Notional        *decimal.Decimal `json:"notional,omitempty"` // Dollar amount, for orders sized that way
+	LimitPrice      *decimal.Decimal `json:"limit_price,omitempty"`
+	StopPrice       *decimal.Decimal `json:"stop_price,omitempty"`
+	State           OrderState       `json:"state"`
+	FilledQty       decimal.Decimal  `json:"filled_qty"`
+	AvgFillPrice    decimal.Decimal  `json:"avg_fill_price"`
+	Signal          string           `json:"signal,omitempty"`
+	RejectReason    string           `json:"reject_reason,omitempty"`
+	CancelRequested bool             `json:"cancel_requested"`
+	ReplacedBy      string           `json:"replaced_by,omitempty"`
+	SubmittedAt     time.Time        `json:"submitted_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// BookPosition is a strategy's share of a symbol as tracked by the OMS
+type BookPosition struct {
+	Symbol        string          `json:"symbol"`
+	Qty           decimal.Decimal `json:"qty"` // Signed: negative for short
+	AvgEntryPrice decimal.Decimal `json:"avg_entry_price"`
+	RealizedPnL   decimal.Decimal `json:"realized_pnl"`
+
+	tripPnL decimal.Decimal // Realized P&L since the position was last flat
+}
+
+// FillEvent is delivered to strategies for every execution on their orders
+type FillEvent struct {
+	ClientOrderID string          // Order that filled (a bracket leg for stop/target exits)
+	ParentID      string          // Bracket parent, empty for top-level orders
+	StrategyID    string          // Owning strategy
+	Symbol        string          // Filled symbol
+	Side          alpaca.Side     // Execution side
+	Event         string          // "partial_fill" or "fill"
+	Signal        string          // Signal that produced the order
+	Qty           decimal.Decimal // Quantity of this execution
+	Price         decimal.Decimal // Price of this execution
+	RealizedPnL   decimal.Decimal // P&L realized by this execution
+	Position      BookPosition    // Strategy position after the execution
+	TripClosed    bool            // Execution took the position flat (or flipped it)
+	TripPnL       decimal.Decimal // Realized P&L of the closed round trip
+	Timestamp     time.Time
+}
+
+// PositionBook holds the positions attributed to one strategy
+type PositionBook struct {
+	StrategyID string
+	positions  map[string]*BookPosition
+}
+
+// newPositionBook creates an empty book for a strategy
+func newPositionBook(strategyID string) *PositionBook {
+	return &PositionBook{
+		StrategyID: strategyID,
+		positions:  make(map[string]*BookPosition),
+	}
+}
+
+// applyFill updates the book with an execution and returns the realized P&L.
+// tripClosed is set when the execution takes the position flat or through flat.
+func (b *PositionBook) applyFill(symbol string, side alpaca.Side, qty, price decimal.Decimal) (realized decimal.Decimal, tripClosed bool, tripPnL decimal.Decimal) {
+	pos, exists := b.positions[symbol]
+	if !exists {
+		pos = &BookPosition{Symbol: symbol}
+		b.positions[symbol] = pos
+	}
+
+	signedQty := qty
+	if side == alpaca.Sell {
+		signedQty = qty.Neg()
+	}
+
+	realized = decimal.Zero
+	switch {
+	case pos.Qty.IsZero() || pos.Qty.Sign() == signedQty.Sign():
+		// Opening or adding: recompute average entry
+		totalCost := pos.AvgEntryPrice.Mul(pos.Qty.Abs()).Add(price.Mul(qty))
+		pos.Qty = pos.Qty.Add(signedQty)
+		pos.AvgEntryPrice = totalCost.Div(pos.Qty.Abs())
+
+	default:
+		// Reducing, closing or flipping
+		closingQty := decimal.Min(qty, pos.Qty.Abs())
+		if pos.Qty.IsPositive() {
+			realized = price.Sub(pos.AvgEntryPrice).Mul(closingQty)
+		} else {
+			realized = pos.AvgEntryPrice.Sub(price).Mul(closingQty)
+		}
+		pos.RealizedPnL = pos.RealizedPnL.Add(realized)
+		pos.tripPnL = pos.tripPnL.Add(realized)
+
+		remaining := pos.Qty.Add(signedQty)
+		if remaining.Sign() != pos.Qty.Sign() {
+			tripClosed = true
+			tripPnL = pos.tripPnL
+			pos.tripPnL = decimal.Zero
+		}
+		if remaining.Sign() != 0 && remaining.Sign() != pos.Qty.Sign() {
+			pos.AvgEntryPrice = price // Flipped through flat
+		} else if remaining.IsZero() {
+			pos.AvgEntryPrice = decimal.Zero
+		}
+		pos.Qty = remaining
+	}
+
+	return realized, tripClosed, tripPnL
+}
+
+// OrderManager is the shared order management system for live strategies.
+// Strategies submit intents; order state is driven by the trade_updates stream.
+type OrderManager struct {
+	tradingClient broker.Broker
+
+	admitMu     sync.Mutex // Serializes risk checks with order registration
+	mu          sync.RWMutex
+	orders      map[string]*ManagedOrder // Client order ID -> order
+	brokerIndex map[string]string        // Broker order ID -> client order ID
+	books       map[string]*PositionBook // Strategy ID -> position book
+	seq         uint64
+	crosses     map[string]*pendingCross      // Resting client order ID -> cross awaiting its cancel
+	breaks      []PositionBreak               // Last CheckPositions result
+	pruned      map[string]map[OrderState]int // Strategy ID -> state -> orders pruned, kept for Metrics
+	paused      map[string]bool               // Strategy ID -> entries blocked, exits allowed
+	intents     map[string]map[string]uint64  // Strategy ID -> signal -> intents received
+
+	submitLatency *latencyHistogram // PlaceOrder round trip
+	fillLatency   *latencyHistogram // Submission to complete fill
+
+	startOnce sync.Once
+	startErr  error
+	stream    broker.TradeStream
+
+	risk    *PortfolioRiskManager      // Optional pre-trade risk layer
+	breaker *protection.CircuitBreaker // Optional kill switch; blocks all submissions when tripped
+
+	listenersMu sync.RWMutex
+	listeners   map[string][]func(FillEvent) // Strategy ID -> fill handlers
+	onReconnect map[string][]func()          // Strategy ID -> resync handlers
+
+	logger *log.Logger
+}
+
+// NewOrderManager creates an OMS that routes orders through the given broker
+func NewOrderManager(tradingClient broker.Broker) *OrderManager {
+	return &OrderManager{
+		tradingClient: tradingClient,
+		orders:        make(map[string]*ManagedOrder),
+		brokerIndex:   make(map[string]string),
+		books:         make(map[string]*PositionBook),
+		crosses:       make(map[string]*pendingCross),
+		pruned:        make(map[string]map[OrderState]int),
+		paused:        make(map[string]bool),
+		intents:       make(map[string]map[string]uint64),
+		submitLatency: newLatencyHistogram(),
+		fillLatency:   newLatencyHistogram(),
+		listeners:     make(map[string][]func(FillEvent)),
+		onReconnect:   make(map[string][]func()),
+		logger:        log.New(log.Writer(), "[OMS] ", log.LstdFlags),
+	}
+}
+
+// Start adopts open orders from the broker and subscribes to trade updates.
+// It is safe to call more than once; only the first call connects.
+func (m *OrderManager) Start(ctx context.Context) error {
+	m.startOnce.Do(func() {
+		if err := m.loadOpenOrders(); err != nil {
+			m.logger.Printf("Failed to load open orders: %v", err)
+		}
+		if _, err := m.CheckPositions(); err != nil {
+			m.logger.Printf("Failed to check positions: %v", err)
+		}
+
+		m.stream = m.tradingClient.TradeUpdates()
+		m.stream.SetTradeUpdateHandler(m.HandleTradeUpdate)
+		m.stream.SetReconnectHandler(m.handleReconnect)
+		if err := m.stream.Connect(ctx); err != nil {
+			m.startErr = fmt.Errorf("failed to connect trade updates: %w", err)
+			return
+		}
+
+		go func() {
+			<-ctx.Done()
+			m.stream.Disconnect()
+		}()
+		go m.maintain(ctx)
+
+		m.logger.Printf("Order manager started")
+	})
+
+	return m.startErr
+}
+
+// Broker returns the broker orders are routed to
+func (m *OrderManager) Broker() broker.Broker {
+	return m.tradingClient
+}
+
+// SetRiskManager routes every intent through a portfolio risk layer before submission
+func (m *OrderManager) SetRiskManager(risk *PortfolioRiskManager) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.risk = risk
+	if risk != nil {
+		risk.attach(m)
+	}
+}
+
+// RiskManager returns the attached risk layer, if any
+func (m *OrderManager) RiskManager() *PortfolioRiskManager {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.risk
+}
+
+// SetCircuitBreaker blocks submissions while the breaker is tripped and
+// feeds it broker rejections
+func (m *OrderManager) SetCircuitBreaker(breaker *protection.CircuitBreaker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.breaker = breaker
+}
+
+// CircuitBreaker returns the attached circuit breaker, if any
+func (m *OrderManager) CircuitBreaker() *protection.CircuitBreaker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.breaker
+}
+
+// Subscribe registers a handler for fills on a strategy's orders, including
+// partial fills and bracket child exits. Handlers run outside the OMS lock.
+func (m *OrderManager) Subscribe(strategyID string, handler func(FillEvent)) {
+	m.listenersMu.Lock()
+	defer m.listenersMu.Unlock()
+	m.listeners[strategyID] = append(m.listeners[strategyID], handler)
+}
+
+// OnReconnect registers a handler run after the trade updates stream reconnects
+// and open orders have been reconciled, and after CheckPositions resyncs the
+// strategy's book. Handlers should re-read the strategy's positions.
+func (m *OrderManager) OnReconnect(strategyID string, handler func()) {
+	m.listenersMu.Lock()
+	defer m.listenersMu.Unlock()
+	m.onReconnect[strategyID] = append(m.onReconnect[strategyID], handler)
+}
+
+// Unsubscribe drops a strategy's fill and reconnect handlers, e.g. when it is removed
+func (m *OrderManager) Unsubscribe(strategyID string) {
+	m.listenersMu.Lock()
+	defer m.listenersMu.Unlock()
+	delete(m.listeners, strategyID)
+	delete(m.onReconnect, strategyID)
+}
+
+// SetEntriesPaused blocks (or unblocks) intents that would open or add to a
+// strategy's positions. Exits and reductions are still accepted.
+func (m *OrderManager) SetEntriesPaused(strategyID string, paused bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if paused {
+		m.paused[strategyID] = true
+	} else {
+		delete(m.paused, strategyID)
+	}
+}
+
+// EntriesPaused reports whether a strategy's entries are blocked
+func (m *OrderManager) EntriesPaused(strategyID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.paused[strategyID]
+}
+
+// opensPosition reports whether an intent would open, add to or flip the strategy's position
+func (m *OrderManager) opensPosition(intent OrderIntent) bool {
+	pos := m.Position(intent.StrategyID, intent.Request.Symbol).Qty
+	if intent.Request.Side == alpaca.Buy && !pos.IsNegative() {
+		return true
+	}
+	if intent.Request.Side == alpaca.Sell && !pos.IsPositive() {
+		return true
+	}
+	return intent.Request.Qty != nil && intent.Request.Qty.GreaterThan(pos.Abs())
+}
+
+// SyncPosition overwrites a strategy's book position, e.g. from the strategy's
+// saved state at startup. Realized P&L history is kept.
+func (m *OrderManager) SyncPosition(strategyID, symbol string, qty, avgEntryPrice decimal.Decimal) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book := m.bookLocked(strategyID)
+	pos, exists := book.positions[symbol]
+	if !exists {
+		pos = &BookPosition{Symbol: symbol}
+		book.positions[symbol] = pos
+	}
+
+	pos.Qty = qty
+	pos.AvgEntryPrice = avgEntryPrice
+	if qty.IsZero() {
+		pos.AvgEntryPrice = decimal.Zero
+		pos.tripPnL = decimal.Zero
+	}
+}
+
+// handleReconnect reconciles orders after a stream gap and notifies strategies
+func (m *OrderManager) handleReconnect() {
+	if err := m.reconcileOrders(); err != nil {
+		m.logger.Printf("Failed to reconcile orders after reconnect: %v", err)
+	}
+	if _, err := m.CheckPositions(); err != nil {
+		m.logger.Printf("Failed to check positions after reconnect: %v", err)
+	}
+
+	m.listenersMu.RLock()
+	handlers := make([]func(), 0)
+	for _, strategyHandlers := range m.onReconnect {
+		handlers = append(handlers, strategyHandlers...)
+	}
+	m.listenersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler()
+	}
+}
+
+// reconcileOrders refreshes orders that were working before a stream gap
+func (m *OrderManager) reconcileOrders() error {
+	if err := m.loadOpenOrders(); err != nil {
+		return err
+	}
+
+	for _, open := range m.OpenOrders("") {
+		if open.BrokerOrderID == "" {
+			continue
+		}
+
+		order, err := m.tradingClient.GetOrder(open.BrokerOrderID)
+		if err != nil {
+			m.logger.Printf("Failed to refresh %s: %v", open.ClientOrderID, err)
+			continue
+		}
+
+		m.mu.Lock()
+		var fill *FillEvent
+		if managed, exists := m.orders[open.ClientOrderID]; exists {
+			fill = m.missedFillLocked(managed, order)
+			managed.FilledQty = order.FilledQty
+			if order.FilledAvgPrice != nil {
+				managed.AvgFillPrice = *order.FilledAvgPrice
+			}
+			if order.ReplacedBy != nil {
+				m.markReplacedLocked(managed, *order.ReplacedBy)
+			}
+			if state := stateFromStatus(order.Status); state != managed.State {
+				m.transitionLocked(managed, state)
+			}
+		}
+		m.mu.Unlock()
+		if fill != nil {
+			m.dispatchFill(*fill)
+		}
+		m.settleCross(open.ClientOrderID, false)
+	}
+
+	return nil
+}
+
+// missedFillLocked books executions the stream dropped during a gap, so each
+// strategy's book keeps following its own fills; caller must hold m.mu
+func (m *OrderManager) missedFillLocked(managed *ManagedOrder, order *alpaca.Order) *FillEvent {
+	qty := order.FilledQty.Sub(managed.FilledQty)
+	if !qty.IsPositive() {
+		return nil
+	}
+
+	// Price the missed qty so the order's overall average comes out right
+	price := managed.AvgFillPrice
+	if order.FilledAvgPrice != nil {
+		price = order.FilledAvgPrice.Mul(order.FilledQty).Sub(managed.AvgFillPrice.Mul(managed.FilledQty)).Div(qty)
+	}
+
+	event := "partial_fill"
+	if order.Status == "filled" {
+		event = "fill"
+	}
+
+	book := m.bookLocked(managed.StrategyID)
+	realized, tripClosed, tripPnL := book.applyFill(managed.Symbol, managed.Side, qty, price)
+	m.logger.Printf("[%s] %s %s %s @ %s missed during stream gap (realized %s)",
+		managed.StrategyID, event, qty.String(), managed.Symbol, price.String(), realized.StringFixed(2))
+
+	return &FillEvent{
+		ClientOrderID: managed.ClientOrderID,
+		ParentID:      managed.ParentID,
+		StrategyID:    managed.StrategyID,
+		Symbol:        managed.Symbol,
+		Side:          managed.Side,
+		Event:         event,
+		Signal:        managed.Signal,
+		Qty:           qty,
+		Price:         price,
+		RealizedPnL:   realized,
+		Position:      *book.positions[managed.Symbol],
+		TripClosed:    tripClosed,
+		TripPnL:       tripPnL,
+		Timestamp:     time.Now(),
+	}
+}
+
+// maintain rechecks positions while breaks are unresolved, e.g. until the
+// working orders that kept a break from being resynced are done, and prunes
+// orders that finished more than orderRetention ago
+func (m *OrderManager) maintain(ctx context.Context) {
+	ticker := time.NewTicker(positionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.pruneOrders(time.Now().Add(-orderRetention))
+
+			m.mu.RLock()
+			unresolved := len(m.breaks) > 0
+			m.mu.RUnlock()
+			if !unresolved {
+				continue
+			}
+			if _, err := m.CheckPositions(); err != nil {
+				m.logger.Printf("Failed to recheck positions: %v", err)
+			}
+		}
+	}
+}
+
+// pruneOrders drops terminal orders last updated before cutoff. Orders still
+// referenced by a working bracket leg or a pending cross are kept.
+func (m *OrderManager) pruneOrders(cutoff time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	parents := make(map[string]bool)
+	for _, order := range m.orders {
+		if order.ParentID != "" && !order.State.IsTerminal() {
+			parents[order.ParentID] = true
+		}
+	}
+
+	pruned := 0
+	for clientOrderID, order := range m.orders {
+		if !order.State.IsTerminal() || order.UpdatedAt.After(cutoff) || parents[clientOrderID] {
+			continue
+		}
+		if _, crossing := m.crosses[clientOrderID]; crossing {
+			continue
+		}
+
+		if m.pruned[order.StrategyID] == nil {
+			m.pruned[order.StrategyID] = make(map[OrderState]int)
+		}
+		m.pruned[order.StrategyID][order.State]++
+		delete(m.orders, clientOrderID)
+		if m.brokerIndex[order.BrokerOrderID] == clientOrderID {
+			delete(m.brokerIndex, order.BrokerOrderID)
+		}
+		pruned++
+	}
+
+	if pruned > 0 {
+		m.logger.Printf("Pruned %d finished orders", pruned)
+	}
+}
+
+// positionBreakLocked reports whether symbol has an unresolved break; caller must hold m.mu
+func (m *OrderManager) positionBreakLocked(symbol string) bool {
+	for _, b := range m.breaks {
+		if b.Symbol == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// PositionBreak is a symbol whose strategy books don't add up to the account position
+type PositionBreak struct {
+	Symbol  string          `json:"symbol"`
+	Account decimal.Decimal `json:"account"` // Broker account position
+	Books   decimal.Decimal `json:"books"`   // Sum of every strategy's book
+}
+
+// CheckPositions reconciles the sum of all strategy books with the account's
+// positions. Books follow each strategy's own fills, so a break means fills
+// were missed (e.g. a bracket leg filled while the process was down) or the
+// account was traded outside the OMS. A symbol held by a single strategy with
+// no working orders is resynced to the account position and the strategy is
+// notified through its OnReconnect handlers. Any other break is logged,
+// reported in Metrics and blocks new orders on the symbol until a later check
+// clears it.
+func (m *OrderManager) CheckPositions() ([]PositionBreak, error) {
+	positions, err := m.tradingClient.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	account := make(map[string]alpaca.Position, len(positions))
+	for _, pos := range positions {
+		account[pos.Symbol] = pos
+	}
+
+	m.mu.Lock()
+	books := make(map[string]decimal.Decimal)
+	owners := make(map[string][]string) // Symbol -> strategies holding it
+	for strategyID, book := range m.books {
+		for symbol, pos := range book.positions {
+			books[symbol] = books[symbol].Add(pos.Qty)
+			if !pos.Qty.IsZero() {
+				owners[symbol] = append(owners[symbol], strategyID)
+			}
+		}
+	}
+
+	symbols := make(map[string]bool, len(account)+len(books))
+	for symbol := range account {
+		symbols[symbol] = true
+	}
+	for symbol := range books {
+		symbols[symbol] = true
+	}
+
+	breaks := make([]PositionBreak, 0)
+	resynced := make([]string, 0)
+	for _, symbol := range sortedKeys(symbols) {
+		held := account[symbol]
+		if held.Qty.Equal(books[symbol]) {
+			continue
+		}
+
+		if len(owners[symbol]) == 1 && !m.workingLocked(symbol) {
+			strategyID := owners[symbol][0]
+			pos := m.bookLocked(strategyID).positions[symbol]
+			m.logger.Printf("[%s] Resynced %s from %s to the account's %s",
+				strategyID, symbol, pos.Qty.String(), held.Qty.String())
+			pos.Qty = held.Qty
+			pos.AvgEntryPrice = held.AvgEntryPrice
+			if held.Qty.IsZero() {
+				pos.AvgEntryPrice = decimal.Zero
+				pos.tripPnL = decimal.Zero
+			}
+			resynced = append(resynced, strategyID)
+			continue
+		}
+		breaks = append(breaks, PositionBreak{Symbol: symbol, Account: held.Qty, Books: books[symbol]})
+	}
+	m.breaks = breaks
+	m.mu.Unlock()
+
+	for _, b := range breaks {
+		m.logger.Printf("Position break in %s: account %s, strategy books %s; orders blocked until resolved",
+			b.Symbol, b.Account.String(), b.Books.String())
+	}
+
+	m.listenersMu.RLock()
+	handlers := make([]func(), 0)
+	for _, strategyID := range resynced {
+		handlers = append(handlers, m.onReconnect[strategyID]...)
+	}
+	m.listenersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler()
+	}
+	return breaks, nil
+}
+
+// workingLocked reports whether any order in symbol is still working, so its
+// fills may not have reached the books yet; caller must hold m.mu
+func (m *OrderManager) workingLocked(symbol string) bool {
+	for _, order := range m.orders {
+		if order.Symbol == symbol && !order.State.IsTerminal() {
+			return true
+		}
+	}
+	return false
+}
+
+// loadOpenOrders adopts working orders tagged by one of our strategies
+func (m *OrderManager) loadOpenOrders() error {
+	orders, err := m.tradingClient.GetOrders(alpaca.GetOrdersRequest{
+		Status: "open",
+		Nested: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	adopted := 0
+	for _, order := range orders {
+		strategyID := strategyFromClientOrderID(order.ClientOrderID)
+		if strategyID == "" {
+			continue
+		}
+		if _, known := m.orders[order.ClientOrderID]; known {
+			continue
+		}
+
+		managed := &ManagedOrder{
+			ClientOrderID: order.ClientOrderID,
+			BrokerOrderID: order.ID,
+			StrategyID:    strategyID,
+			Symbol:        order.Symbol,
+			Side:          order.Side,
+			Type:          order.Type,
+			LimitPrice:    order.LimitPrice,
+			StopPrice:     order.StopPrice,
+			State:         stateFromStatus(order.Status),
+			FilledQty:     order.FilledQty,
+			SubmittedAt:   order.SubmittedAt,
+			UpdatedAt:     order.UpdatedAt,
+		}
+		if order.Qty != nil {
+			managed.Qty = *order.Qty
+		}
+		if order.FilledAvgPrice != nil {
+			managed.AvgFillPrice = *order.FilledAvgPrice
+		}
+
+		m.orders[managed.ClientOrderID] = managed
+		m.brokerIndex[managed.BrokerOrderID] = managed.ClientOrderID
+		m.registerLegsLocked(managed, order.Legs)
+		adopted++
+	}
+
+	if adopted > 0 {
+		m.logger.Printf("Adopted %d open orders from broker", adopted)
+	}
+	return nil
+}
+
+// Submit validates an intent, tags it with a client order ID and sends it to the broker
+func (m *OrderManager) Submit(intent OrderIntent) (ManagedOrder, error) {
+	if intent.StrategyID == "" {
+		return ManagedOrder{}, fmt.Errorf("order intent missing strategy ID")
+	}
+	if intent.Request.Symbol == "" {
+		return ManagedOrder{}, fmt.Errorf("order intent missing symbol")
+	}
+	if intent.Request.Qty == nil && intent.Request.Notional == nil {
+		return ManagedOrder{}, fmt.Errorf("order intent for %s has no quantity", intent.Request.Symbol)
+	}
+	m.countIntent(intent)
+
+	if err := m.admit(intent); err != nil {
+		return m.recordRejected(intent, err), err
+	}
+
+	// Pre-trade portfolio risk: limits, then netting against other strategies.
+	// The check and the order's registration are atomic, so a working order
+	// reserves its exposure before the next intent is checked; the reservation
+	// ends when the order is rejected, canceled or filled into the books.
+	m.admitMu.Lock()
+	var crossed []string
+	if risk := m.RiskManager(); risk != nil {
+		if err := risk.Check(intent); err != nil {
+			m.admitMu.Unlock()
+			return m.recordRejected(intent, err), err
+		}
+
+		if risk.NettingFor(intent.StrategyID) == NettingCross && intent.Request.Qty != nil {
+			var remaining decimal.Decimal
+			var crossing ManagedOrder
+			remaining, crossing, crossed = m.crossOpposing(intent)
+			if remaining.IsZero() {
+				m.admitMu.Unlock()
+				m.cancelCrossed(crossed)
+				return crossing, nil // Crossing entirely against other strategies
+			}
+			intent.Request.Qty = &remaining
+		}
+	}
+
+	m.mu.Lock()
+	managed := m.newOrderLocked(intent)
+	m.mu.Unlock()
+	m.admitMu.Unlock()
+
+	m.cancelCrossed(crossed)
+	return m.send(managed, intent)
+}
+
+// admit runs the checks every order must pass before portfolio risk, for new
+// orders and replacements alike
+func (m *OrderManager) admit(intent OrderIntent) error {
+	// Kill switch: nothing reaches the broker while tripped
+	if breaker := m.CircuitBreaker(); breaker != nil {
+		if err := breaker.Allow(); err != nil {
+			return err
+		}
+	}
+
+	// Books that disagree with the account can't size any order on the symbol
+	m.mu.RLock()
+	broken := m.positionBreakLocked(intent.Request.Symbol)
+	m.mu.RUnlock()
+	if broken {
+		return fmt.Errorf("%w in %s", ErrPositionBreak, intent.Request.Symbol)
+	}
+
+	// Paused strategies may still reduce or close positions
+	if m.EntriesPaused(intent.StrategyID) && m.opensPosition(intent) {
+		return fmt.Errorf("%w: %s", ErrEntriesPaused, intent.StrategyID)
+	}
+	return nil
+}
+
+// newOrderLocked registers an intent as a new OMS order; caller must hold m.mu
+func (m *OrderManager) newOrderLocked(intent OrderIntent) *ManagedOrder {
+	clientOrderID := m.nextClientOrderIDLocked(intent.StrategyID)
+	managed := &ManagedOrder{
+		ClientOrderID: clientOrderID,
+		StrategyID:    intent.StrategyID,
+		Symbol:        intent.Request.Symbol,
+		Side:          intent.Request.Side,
+		Type:          intent.Request.Type,
+		LimitPrice:    intent.Request.LimitPrice,
+		StopPrice:     intent.Request.StopPrice,
+		State:         OrderStateNew,
+		Signal:        intent.Signal,
+		SubmittedAt:   time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if intent.Request.Qty != nil {
+		managed.Qty = *intent.Request.Qty
+	} else {
+		managed.Notional = intent.Request.Notional
+	}
+	m.orders[clientOrderID] = managed
+	m.bookLocked(intent.StrategyID)
+	return managed
+}
+
+// send places a registered order with the broker
+func (m *OrderManager) send(managed *ManagedOrder, intent OrderIntent) (ManagedOrder, error) {
+	req := intent.Request
+	req.ClientOrderID = managed.ClientOrderID
+
+	placedAt := time.Now()
+	order, err := m.tradingClient.PlaceOrder(req)
+	m.submitLatency.observe(time.Since(placedAt))
+	if breaker := m.CircuitBreaker(); breaker != nil {
+		defer breaker.RecordOrderResult(err != nil) // Deferred so a trip runs after m.mu is released
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		m.transitionLocked(managed, OrderStateRejected)
+		managed.RejectReason = err.Error()
+		m.logger.Printf("[%s] %s %s %s rejected: %v",
+			intent.StrategyID, managed.Side, managed.Qty.String(), managed.Symbol, err)
+		return *managed, err
+	}
+
+	managed.BrokerOrderID = order.ID
+	m.brokerIndex[order.ID] = managed.ClientOrderID
+	switch state := stateFromStatus(order.Status); state {
+	case OrderStateFilled, OrderStatePartiallyFilled:
+		// Executions are booked from trade updates, or by reconcile after a
+		// stream gap; an ack that already reports fills only marks it working
+		if managed.State == OrderStateNew {
+			m.transitionLocked(managed, OrderStateAccepted)
+		}
+	default:
+		m.transitionLocked(managed, state)
+	}
+	m.registerLegsLocked(managed, order.Legs)
+
+	m.logger.Printf("[%s] %s %s %s submitted as %s (%s)",
+		intent.StrategyID, managed.Side, managed.Qty.String(), managed.Symbol, managed.ClientOrderID, intent.Signal)
+	return *managed, nil
+}
+
+// recordRejected stores an intent that never reached the broker
+func (m *OrderManager) recordRejected(intent OrderIntent, reason error) ManagedOrder {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clientOrderID := m.nextClientOrderIDLocked(intent.StrategyID)
+	managed := &ManagedOrder{
+		ClientOrderID: clientOrderID,
+		StrategyID:    intent.StrategyID,
+		Symbol:        intent.Request.Symbol,
+		Side:          intent.Request.Side,
+		Type:          intent.Request.Type,
+		State:         OrderStateRejected,
+		Signal:        intent.Signal,
+		RejectReason:  reason.Error(),
+		SubmittedAt:   time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if intent.Request.Qty != nil {
+		managed.Qty = *intent.Request.Qty
+	}
+	m.orders[clientOrderID] = managed
+
+	m.logger.Printf("[%s] %s %s %s blocked pre-trade: %v",
+		intent.StrategyID, managed.Side, managed.Qty.String(), managed.Symbol, reason)
+	return *managed
+}
+
+// pendingCross is the intent side of an internal cross, waiting for the broker
+// to confirm the resting order it crosses is canceled
+type pendingCross struct {
+	order  *ManagedOrder // Internal order for the intent, working until settled
+	intent OrderIntent
+	price  decimal.Decimal
+}
+
+// crossOpposing nets an intent against other strategies' resting orders on the
+// opposite side of the same symbol. Each resting order crossed is canceled at
+// the broker (see cancelCrossed), and the cross is only booked once the cancel
+// is confirmed (see settleCross); until then the intent's share waits in an
+// internal order. Only whole resting limit orders the intent's reference price
+// reaches are crossed. Returns the quantity to send to the broker now, the
+// last internal order and the resting orders to cancel.
+func (m *OrderManager) crossOpposing(intent OrderIntent) (decimal.Decimal, ManagedOrder, []string) {
+	remaining := *intent.Request.Qty
+	var crossing ManagedOrder
+	var crossed []string
+	if !intent.RefPrice.IsPositive() {
+		return remaining, crossing, crossed
+	}
+
+	for _, resting := range m.OpenOrders("") {
+		if remaining.IsZero() {
+			break
+		}
+		if resting.StrategyID == intent.StrategyID || resting.Symbol != intent.Request.Symbol ||
+			resting.Side == intent.Request.Side || resting.ParentID != "" || resting.BrokerOrderID == "" {
+			continue
+		}
+		price, ok := crossPrice(resting, intent)
+		if !ok {
+			continue
+		}
+
+		// Shrinking a resting order in place can race its fills, so only
+		// orders the intent absorbs whole are crossed
+		open := resting.Qty.Sub(resting.FilledQty)
+		if !open.IsPositive() || open.GreaterThan(remaining) {
+			continue
+		}
+
+		pending, ok := m.reserveCross(resting, intent, open, price)
+		if !ok {
+			continue // Already being crossed
+		}
+		crossing = pending
+		crossed = append(crossed, resting.ClientOrderID)
+		remaining = remaining.Sub(open)
+	}
+
+	return remaining, crossing, crossed
+}
+
+// reserveCross registers the internal order for a cross against resting
+func (m *OrderManager) reserveCross(resting ManagedOrder, intent OrderIntent, qty, price decimal.Decimal) (ManagedOrder, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.crosses[resting.ClientOrderID]; exists {
+		return ManagedOrder{}, false
+	}
+
+	crossIntent := intent
+	crossIntent.Request.Qty = &qty
+	order := m.newOrderLocked(crossIntent)
+	m.transitionLocked(order, OrderStateAccepted)
+	m.crosses[resting.ClientOrderID] = &pendingCross{order: order, intent: intent, price: price}
+	return *order, true
+}
+
+// cancelCrossed asks the broker to cancel crossed resting orders. A cross
+// whose cancel fails falls through to the broker.
+func (m *OrderManager) cancelCrossed(restingIDs []string) {
+	for _, restingID := range restingIDs {
+		if err := m.Cancel(restingID); err != nil {
+			m.logger.Printf("Cross against %s not confirmed: %v", restingID, err)
+			m.settleCross(restingID, true)
+		}
+	}
+}
+
+// settleCross completes a cross once its resting order is done. If the
+// resting order was canceled, its unfilled quantity is crossed internally;
+// whatever it filled at the broker first (all of it, if the cancel failed
+// or the order was replaced) goes to the broker as a normal order.
+func (m *OrderManager) settleCross(restingID string, cancelFailed bool) {
+	m.mu.Lock()
+	pending, exists := m.crosses[restingID]
+	resting := m.orders[restingID]
+	if !exists || resting == nil || (!resting.State.IsTerminal() && !cancelFailed) {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.crosses, restingID)
+
+	crossing := pending.order
+	qty := decimal.Zero
+	if resting.State == OrderStateCanceled && resting.ReplacedBy == "" {
+		qty = decimal.Min(crossing.Qty, resting.Qty.Sub(resting.FilledQty))
+	}
+	leftover := crossing.Qty.Sub(qty)
+
+	var fills []FillEvent
+	if qty.IsPositive() {
+		crossing.Qty = qty
+		crossing.FilledQty = qty
+		crossing.AvgFillPrice = pending.price
+		m.transitionLocked(crossing, OrderStateFilled)
+		fills = append(fills,
+			m.internalFillLocked(crossing.ClientOrderID, crossing.StrategyID, crossing.Symbol, crossing.Side, crossing.Signal, qty, pending.price),
+			m.internalFillLocked(resting.ClientOrderID, resting.StrategyID, resting.Symbol, resting.Side, resting.Signal, qty, pending.price),
+		)
+	} else {
+		m.transitionLocked(crossing, OrderStateCanceled)
+	}
+
+	var next *ManagedOrder
+	intent := pending.intent
+	if leftover.IsPositive() {
+		intent.Request.Qty = &leftover
+		next = m.newOrderLocked(intent)
+	}
+	m.mu.Unlock()
+
+	if qty.IsPositive() {
+		if risk := m.RiskManager(); risk != nil {
+			risk.recordCross()
+		}
+		m.logger.Printf("Crossed %s %s internally between %s and %s @ %s",
+			qty.String(), crossing.Symbol, crossing.StrategyID, resting.StrategyID, pending.price.StringFixed(2))
+		for _, fill := range fills {
+			m.dispatchFill(fill)
+		}
+	}
+
+	if next == nil {
+		return
+	}
+	m.logger.Printf("Cross against %s fell through; sending %s %s to the broker",
+		restingID, leftover.String(), next.Symbol)
+	if breaker := m.CircuitBreaker(); breaker != nil {
+		if err := breaker.Allow(); err != nil {
+			m.mu.Lock()
+			m.transitionLocked(next, OrderStateRejected)
+			next.RejectReason = err.Error()
+			m.mu.Unlock()
+			return
+		}
+	}
+	m.send(next, intent)
+}
+
+// crossPrice is the price an intent crosses a resting order at: the resting
+// limit, provided the intent's reference price (and its own limit, if any)
+// reaches it. Market and stop orders never rest at a known price.
+func crossPrice(resting ManagedOrder, intent OrderIntent) (decimal.Decimal, bool) {
+	if resting.Type != alpaca.Limit || resting.LimitPrice == nil {
+		return decimal.Zero, false
+	}
+	price := *resting.LimitPrice
+
+	reaches := func(p decimal.Decimal) bool {
+		if resting.Side == alpaca.Buy {
+			return p.LessThanOrEqual(price) // Selling into a bid at or above it
+		}
+		return p.GreaterThanOrEqual(price) // Buying from an offer at or below it
+	}
+	if !reaches(intent.RefPrice) {
+		return decimal.Zero, false
+	}
+	if intent.Request.LimitPrice != nil && !reaches(*intent.Request.LimitPrice) {
+		return decimal.Zero, false
+	}
+	return price, true
+}
+
+// internalFillLocked applies an internal execution to a book and builds its event
+func (m *OrderManager) internalFillLocked(clientOrderID, strategyID, symbol string, side alpaca.Side, signal string, qty, price decimal.Decimal) FillEvent {
+	book := m.bookLocked(strategyID)
+	realized, tripClosed, tripPnL := book.applyFill(symbol, side, qty, price)
+
+	return FillEvent{
+		ClientOrderID: clientOrderID,
+		StrategyID:    strategyID,
+		Symbol:        symbol,
+		Side:          side,
+		Event:         "internal_cross",
+		Signal:        signal,
+		Qty:           qty,
+		Price:         price,
+		RealizedPnL:   realized,
+		Position:      *book.positions[symbol],
+		TripClosed:    tripClosed,
+		TripPnL:       tripPnL,
+		Timestamp:     time.Now(),
+	}
+}
+
+// Cancel requests cancellation of a working order
+func (m *OrderManager) Cancel(clientOrderID string) error {
+	m.mu.Lock()
+	managed, exists := m.orders[clientOrderID]
+	if !exists {
+		m.mu.Unlock()
+		return fmt.Errorf("unknown order %s", clientOrderID)
+	}
+	if managed.State.IsTerminal() {
+		m.mu.Unlock()
+		return fmt.Errorf("order %s already %s", clientOrderID, managed.State)
+	}
+	brokerOrderID := managed.BrokerOrderID
+	managed.CancelRequested = true
+	m.mu.Unlock()
+
+	if brokerOrderID == "" {
+		return fmt.Errorf("order %s not yet acknowledged by broker", clientOrderID)
+	}
+
+	if err := m.tradingClient.CancelOrder(brokerOrderID); err != nil {
+		return fmt.Errorf("failed to cancel %s: %w", clientOrderID, err)
+	}
+
+	m.logger.Printf("Cancel requested for %s", clientOrderID)
+	return nil
+}
+
+// CancelAll cancels every working order for a strategy ("" cancels all strategies)
+func (m *OrderManager) CancelAll(strategyID string) error {
+	var firstErr error
+	for _, order := range m.OpenOrders(strategyID) {
+		if order.ParentID != "" {
+			continue // Legs are canceled with their parent
+		}
+		if err := m.Cancel(order.ClientOrderID); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// FlattenAll closes every position in every strategy's book with market orders
+// tagged to the owning strategy, so the closing fills land in its book, then
+// trades out whatever the account holds beyond the books. It is the kill
+// switch's flatten action, so it skips the kill switch and risk checks.
+func (m *OrderManager) FlattenAll() error {
+	m.mu.RLock()
+	strategyIDs := sortedKeys(m.books)
+	m.mu.RUnlock()
+
+	var firstErr error
+	books := make(map[string]decimal.Decimal)
+	for _, strategyID := range strategyIDs {
+		for _, pos := range m.Positions(strategyID) {
+			books[pos.Symbol] = books[pos.Symbol].Add(pos.Qty)
+
+			intent := closeIntent(strategyID, "KILL_SWITCH", pos.Symbol, pos.Qty, pos.AvgEntryPrice)
+			m.mu.Lock()
+			managed := m.newOrderLocked(intent)
+			m.mu.Unlock()
+			if _, err := m.send(managed, intent); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("failed to flatten %s for %s: %w", pos.Symbol, strategyID, err)
+			}
+		}
+	}
+
+	// Whatever the books don't explain was traded outside the OMS
+	positions, err := m.tradingClient.GetPositions()
+	if err != nil {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("failed to get positions: %w", err)
+		}
+		return firstErr
+	}
+	residual := make(map[string]decimal.Decimal, len(books))
+	for symbol, qty := range books {
+		residual[symbol] = qty.Neg()
+	}
+	for _, pos := range positions {
+		residual[pos.Symbol] = residual[pos.Symbol].Add(pos.Qty)
+	}
+	for _, symbol := range sortedKeys(residual) {
+		if residual[symbol].IsZero() {
+			continue
+		}
+		intent := closeIntent("", "KILL_SWITCH", symbol, residual[symbol], decimal.Zero)
+		if _, err := m.tradingClient.PlaceOrder(intent.Request); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to flatten %s outside the books: %w", symbol, err)
+		}
+	}
+	return firstErr
+}
+
+// closeIntent is a market order that trades qty (signed like a position) back to flat
+func closeIntent(strategyID, signal, symbol string, qty, refPrice decimal.Decimal) OrderIntent {
+	side := alpaca.Sell
+	if qty.IsNegative() {
+		side = alpaca.Buy
+	}
+	timeInForce := alpaca.Day
+	if strings.Contains(symbol, "/") {
+		timeInForce = alpaca.GTC // Crypto does not accept day orders
+	}
+
+	abs := qty.Abs()
+	return OrderIntent{
+		StrategyID: strategyID,
+		Signal:     signal,
+		Request: alpaca.PlaceOrderRequest{
+			Symbol:      symbol,
+			Qty:         &abs,
+			Side:        side,
+			Type:        alpaca.Market,
+			TimeInForce: timeInForce,
+		},
+		RefPrice: refPrice,
+	}
+}
+
+// Replace amends quantity and/or prices of a working order (cancel/replace).
+// The amended order goes through the same kill switch, pause and risk checks
+// as a new submission.
+func (m *OrderManager) Replace(clientOrderID string, qty, limitPrice, stopPrice *decimal.Decimal) (ManagedOrder, error) {
+	m.mu.Lock()
+	old, exists := m.orders[clientOrderID]
+	if !exists {
+		m.mu.Unlock()
+		return ManagedOrder{}, fmt.Errorf("unknown order %s", clientOrderID)
+	}
+	if old.State.IsTerminal() {
+		m.mu.Unlock()
+		return ManagedOrder{}, fmt.Errorf("order %s already %s", clientOrderID, old.State)
+	}
+	if old.BrokerOrderID == "" {
+		m.mu.Unlock()
+		return ManagedOrder{}, fmt.Errorf("order %s not yet acknowledged by broker", clientOrderID)
+	}
+
+	newClientOrderID := m.nextClientOrderIDLocked(old.StrategyID)
+	brokerOrderID := old.BrokerOrderID
+	intent := replaceIntent(old, qty, limitPrice, stopPrice)
+	m.mu.Unlock()
+
+	if err := m.admit(intent); err != nil {
+		return ManagedOrder{}, fmt.Errorf("failed to replace %s: %w", clientOrderID, err)
+	}
+
+	// As in Submit, the check holds until the replacement is registered
+	m.admitMu.Lock()
+	defer m.admitMu.Unlock()
+	if risk := m.RiskManager(); risk != nil {
+		if err := risk.CheckReplace(intent, clientOrderID); err != nil {
+			return ManagedOrder{}, fmt.Errorf("failed to replace %s: %w", clientOrderID, err)
+		}
+	}
+
+	order, err := m.tradingClient.ReplaceOrder(brokerOrderID, alpaca.ReplaceOrderRequest{
+		Qty:           qty,
+		LimitPrice:    limitPrice,
+		StopPrice:     stopPrice,
+		ClientOrderID: newClientOrderID,
+	})
+	if err != nil {
+		return ManagedOrder{}, fmt.Errorf("failed to replace %s: %w", clientOrderID, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	replacement := &ManagedOrder{
+		ClientOrderID: newClientOrderID,
+		BrokerOrderID: order.ID,
+		StrategyID:    old.StrategyID,
+		ParentID:      old.ParentID,
+		Symbol:        old.Symbol,
+		Side:          old.Side,
+		Type:          old.Type,
+		Qty:           old.Qty,
+		LimitPrice:    order.LimitPrice,
+		StopPrice:     order.StopPrice,
+		State:         OrderStateNew,
+		Signal:        old.Signal,
+		SubmittedAt:   time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if order.Qty != nil {
+		replacement.Qty = *order.Qty
+	}
+	m.transitionLocked(replacement, stateFromStatus(order.Status))
+
+	old.ReplacedBy = newClientOrderID
+	m.orders[newClientOrderID] = replacement
+	m.brokerIndex[order.ID] = newClientOrderID
+
+	m.logger.Printf("Replaced %s with %s", clientOrderID, newClientOrderID)
+	return *replacement, nil
+}
+
+// replaceIntent describes the unfilled remainder of an order after a replace
+func replaceIntent(old *ManagedOrder, qty, limitPrice, stopPrice *decimal.Decimal) OrderIntent {
+	remaining := old.Qty
+	if qty != nil {
+		remaining = *qty
+	}
+	remaining = decimal.Max(remaining.Sub(old.FilledQty), decimal.Zero)
+
+	if limitPrice == nil {
+		limitPrice = old.LimitPrice
+	}
+	if stopPrice == nil {
+		stopPrice = old.StopPrice
+	}
+	refPrice := decimal.Zero
+	if limitPrice != nil {
+		refPrice = *limitPrice
+	} else if stopPrice != nil {
+		refPrice = *stopPrice
+	}
+
+	return OrderIntent{
+		StrategyID: old.StrategyID,
+		Signal:     old.Signal,
+		Request: alpaca.PlaceOrderRequest{
+			Symbol:     old.Symbol,
+			Side:       old.Side,
+			Type:       old.Type,
+			Qty:        &remaining,
+			LimitPrice: limitPrice,
+			StopPrice:  stopPrice,
+		},
+		RefPrice: refPrice,
+	}
+}
+
+// OpenOrders returns working orders for a strategy ("" returns all strategies)
+func (m *OrderManager) OpenOrders(strategyID string) []ManagedOrder {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	open := make([]ManagedOrder, 0)
+	for _, order := range m.orders {
+		if order.State.IsTerminal() {
+			continue
+		}
+		if strategyID != "" && order.StrategyID != strategyID {
+			continue
+		}
+		open = append(open, *order)
+	}
+	return open
+}
+
+// Order looks up a single order by client order ID
+func (m *OrderManager) Order(clientOrderID string) (ManagedOrder, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	order, exists := m.orders[clientOrderID]
+	if !exists {
+		return ManagedOrder{}, false
+	}
+	return *order, true
+}
+
+// Position returns a strategy's book position in a symbol
+func (m *OrderManager) Position(strategyID, symbol string) BookPosition {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if book, exists := m.books[strategyID]; exists {
+		if pos, exists := book.positions[symbol]; exists {
+			return *pos
+		}
+	}
+	return BookPosition{Symbol: symbol}
+}
+
+// Positions returns all non-flat positions in a strategy's book
+func (m *OrderManager) Positions(strategyID string) []BookPosition {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	positions := make([]BookPosition, 0)
+	if book, exists := m.books[strategyID]; exists {
+		for _, pos := range book.positions {
+			if !pos.Qty.IsZero() {
+				positions = append(positions, *pos)
+			}
+		}
+	}
+	return positions
+}
+
+// RecentOrders returns up to limit orders, most recently updated first.
+// An empty strategyID includes every strategy; limit <= 0 returns all.
+func (m *OrderManager) RecentOrders(strategyID string, limit int) []ManagedOrder {
+	m.mu.RLock()
+	orders := make([]ManagedOrder, 0, len(m.orders))
+	for _, order := range m.orders {
+		if strategyID == "" || order.StrategyID == strategyID {
+			orders = append(orders, *order)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].UpdatedAt.After(orders[j].UpdatedAt)
+	})
+	if limit > 0 && len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders
+}
+
+// AllPositions returns the non-flat positions of every strategy
+func (m *OrderManager) AllPositions() map[string][]BookPosition {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	all := make(map[string][]BookPosition)
+	for strategyID, book := range m.books {
+		for _, pos := range book.positions {
+			if !pos.Qty.IsZero() {
+				all[strategyID] = append(all[strategyID], *pos)
+			}
+		}
+	}
+	return all
+}
+
+// OrderMetrics is a snapshot of OMS activity for monitoring
+type OrderMetrics struct {
+	Intents       map[string]map[string]uint64  `json:"intents"` // Strategy ID -> signal -> count
+	Orders        map[string]map[OrderState]int `json:"orders"`  // Strategy ID -> state -> tracked orders
+	SubmitLatency LatencySnapshot               `json:"submit_latency"`
+	FillLatency   LatencySnapshot               `json:"fill_latency"`
+	Breaks        []PositionBreak               `json:"position_breaks"` // Symbols where books and account disagree
+}
+
+// Metrics snapshots signal counts, order states and latencies
+func (m *OrderManager) Metrics() OrderMetrics {
+	m.mu.RLock()
+	metrics := OrderMetrics{
+		Intents: make(map[string]map[string]uint64),
+		Orders:  make(map[string]map[OrderState]int),
+	}
+	for strategyID, signals := range m.intents {
+		metrics.Intents[strategyID] = make(map[string]uint64, len(signals))
+		for signal, count := range signals {
+			metrics.Intents[strategyID][signal] = count
+		}
+	}
+	for _, order := range m.orders {
+		if metrics.Orders[order.StrategyID] == nil {
+			metrics.Orders[order.StrategyID] = make(map[OrderState]int)
+		}
+		metrics.Orders[order.StrategyID][order.State]++
+	}
+	for strategyID, states := range m.pruned {
+		if metrics.Orders[strategyID] == nil {
+			metrics.Orders[strategyID] = make(map[OrderState]int)
+		}
+		for state, count := range states {
+			metrics.Orders[strategyID][state] += count
+		}
+	}
+	metrics.Breaks = append([]PositionBreak{}, m.breaks...)
+	m.mu.RUnlock()
+
+	metrics.SubmitLatency = m.submitLatency.snapshot()
+	metrics.FillLatency = m.fillLatency.snapshot()
+	return metrics
+}
+
+// countIntent tallies an intent by strategy and signal
+func (m *OrderManager) countIntent(intent OrderIntent) {
+	signal := intent.Signal
+	if signal == "" {
+		signal = "unknown"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.intents[intent.StrategyID] == nil {
+		m.intents[intent.StrategyID] = make(map[string]uint64)
+	}
+	m.intents[intent.StrategyID][signal]++
+}
+
+// riskSnapshot copies every strategy's book and all working orders for risk checks
+func (m *OrderManager) riskSnapshot() (map[string][]BookPosition, []ManagedOrder) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	positions := make(map[string][]BookPosition, len(m.books))
+	for strategyID, book := range m.books {
+		for _, pos := range book.positions {
+			if !pos.Qty.IsZero() {
+				positions[strategyID] = append(positions[strategyID], *pos)
+			}
+		}
+	}
+
+	open := make([]ManagedOrder, 0)
+	for _, order := range m.orders {
+		if !order.State.IsTerminal() {
+			open = append(open, *order)
+		}
+	}
+
+	return positions, open
+}
+
+// HandleTradeUpdate advances order state and position books from a trade_updates event
+func (m *OrderManager) HandleTradeUpdate(update TradeUpdate) {
+	m.mu.Lock()
+	fill := m.applyTradeUpdateLocked(update)
+	breaker := m.breaker
+	restingID := ""
+	if managed := m.lookupLocked(update.Data.Order); managed != nil {
+		restingID = managed.ClientOrderID
+	}
+	m.mu.Unlock()
+
+	if breaker != nil && update.Data.Event == "rejected" {
+		breaker.RecordOrderResult(true)
+	}
+
+	if fill != nil {
+		m.dispatchFill(*fill)
+	}
+	if restingID != "" {
+		m.settleCross(restingID, update.Data.Event == "order_cancel_rejected")
+	}
+}
+
+// dispatchFill notifies the risk layer and the owning strategy's fill handlers
+func (m *OrderManager) dispatchFill(fill FillEvent) {
+	if risk := m.RiskManager(); risk != nil {
+		risk.UpdatePrice(fill.Symbol, fill.Price)
+	}
+
+	m.listenersMu.RLock()
+	handlers := append([]func(FillEvent){}, m.listeners[fill.StrategyID]...)
+	m.listenersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(fill)
+	}
+}
+
+// applyTradeUpdateLocked updates order and book state; returns a fill event for executions
+func (m *OrderManager) applyTradeUpdateLocked(update TradeUpdate) *FillEvent {
+	event := update.Data.Event
+	order := update.Data.Order
+
+	managed := m.lookupLocked(order)
+	if managed == nil {
+		return nil // Not an OMS order
+	}
+
+	var fill *FillEvent
+
+	if managed.BrokerOrderID == "" {
+		managed.BrokerOrderID = order.ID
+		m.brokerIndex[order.ID] = managed.ClientOrderID
+	}
+	managed.UpdatedAt = time.Now()
+	m.registerLegsLocked(managed, toAlpacaLegs(order.Legs))
+
+	switch event {
+	case "pending_new", "new", "accepted":
+		m.transitionLocked(managed, OrderStateAccepted)
+
+	case "partial_fill", "fill":
+		// Book only what the OMS hasn't seen: reconcile may already have
+		// applied this execution, and the stream can redeliver it
+		execQty, _ := decimal.NewFromString(update.Data.Qty)
+		price, _ := decimal.NewFromString(update.Data.Price)
+		filled, err := decimal.NewFromString(order.FilledQty)
+		if err != nil || filled.IsZero() {
+			filled = managed.FilledQty.Add(execQty)
+		}
+		qty := filled.Sub(managed.FilledQty)
+		avgPrice := decimal.Zero
+		if order.FilledAvgPrice != nil {
+			avgPrice, _ = decimal.NewFromString(*order.FilledAvgPrice)
+		}
+		switch {
+		case !qty.IsPositive():
+		case avgPrice.IsZero():
+			avgPrice = managed.AvgFillPrice.Mul(managed.FilledQty).Add(price.Mul(qty)).Div(filled)
+		case !qty.Equal(execQty):
+			// Part of the execution was booked already; price the rest so the order's average comes out right
+			price = avgPrice.Mul(filled).Sub(managed.AvgFillPrice.Mul(managed.FilledQty)).Div(qty)
+		}
+		if qty.IsPositive() {
+			book := m.bookLocked(managed.StrategyID)
+			realized, tripClosed, tripPnL := book.applyFill(managed.Symbol, managed.Side, qty, price)
+			m.logger.Printf("[%s] %s %s %s @ %s (realized %s)",
+				managed.StrategyID, event, qty.String(), managed.Symbol, price.String(), realized.StringFixed(2))
+
+			timestamp := update.Data.Timestamp
+			if timestamp.IsZero() {
+				timestamp = time.Now()
+			}
+			fill = &FillEvent{
+				ClientOrderID: managed.ClientOrderID,
+				ParentID:      managed.ParentID,
+				StrategyID:    managed.StrategyID,
+				Symbol:        managed.Symbol,
+				Side:          managed.Side,
+				Event:         event,
+				Signal:        managed.Signal,
+				Qty:           qty,
+				Price:         price,
+				RealizedPnL:   realized,
+				Position:      *book.positions[managed.Symbol],
+				TripClosed:    tripClosed,
+				TripPnL:       tripPnL,
+				Timestamp:     timestamp,
+			}
+			managed.FilledQty = filled
+			managed.AvgFillPrice = avgPrice
+		}
+
+		if event == "fill" {
+			if managed.ParentID == "" && !managed.SubmittedAt.IsZero() {
+				m.fillLatency.observe(time.Since(managed.SubmittedAt))
+			}
+			m.transitionLocked(managed, OrderStateFilled)
+		} else {
+			m.transitionLocked(managed, OrderStatePartiallyFilled)
+		}
+
+	case "canceled", "expired", "done_for_day":
+		m.transitionLocked(managed, OrderStateCanceled)
+
+	case "replaced":
+		if order.ReplacedBy != nil {
+			m.markReplacedLocked(managed, *order.ReplacedBy)
+		}
+		m.transitionLocked(managed, OrderStateCanceled)
+
+	case "rejected":
+		m.transitionLocked(managed, OrderStateRejected)
+		managed.RejectReason = "rejected by broker"
+
+	case "order_cancel_rejected":
+		managed.CancelRequested = false
+
+	default:
+		// pending_cancel, pending_replace, calculated, etc. carry no state change
+	}
+
+	return fill
+}
+
+// lookupLocked finds the managed order for a trade update; caller must hold m.mu
+func (m *OrderManager) lookupLocked(order Order) *ManagedOrder {
+	if managed, exists := m.orders[order.ClientOrderID]; exists {
+		return managed
+	}
+	if clientID, exists := m.brokerIndex[order.ID]; exists {
+		return m.orders[clientID]
+	}
+
+	// Adopt orders tagged by our strategies from a previous session
+	strategyID := strategyFromClientOrderID(order.ClientOrderID)
+	if strategyID == "" {
+		return nil
+	}
+
+	qty, _ := decimal.NewFromString(order.Qty)
+	managed := &ManagedOrder{
+		ClientOrderID: order.ClientOrderID,
+		BrokerOrderID: order.ID,
+		StrategyID:    strategyID,
+		Symbol:        order.Symbol,
+		Side:          alpaca.Side(order.Side),
+		Type:          alpaca.OrderType(order.Type),
+		Qty:           qty,
+		State:         OrderStateNew,
+		SubmittedAt:   order.SubmittedAt,
+	}
+	m.orders[managed.ClientOrderID] = managed
+	m.brokerIndex[managed.BrokerOrderID] = managed.ClientOrderID
+	m.bookLocked(strategyID)
+	return managed
+}
+
+// markReplacedLocked links an order to its replacement, by broker order ID
+// until the replacement is known to the OMS
+func (m *OrderManager) markReplacedLocked(managed *ManagedOrder, brokerOrderID string) {
+	if managed.ReplacedBy != "" && managed.ReplacedBy != brokerOrderID {
+		return // Already linked by Replace
+	}
+	managed.ReplacedBy = brokerOrderID
+	if clientID, exists := m.brokerIndex[brokerOrderID]; exists {
+		managed.ReplacedBy = clientID
+	}
+}
+
+// registerLegsLocked tracks bracket child orders under their parent's strategy
+func (m *OrderManager) registerLegsLocked(parent *ManagedOrder, legs []alpaca.Order) {
+	for _, leg := range legs {
+		if _, exists := m.brokerIndex[leg.ID]; exists {
+			continue
+		}
+
+		child := &ManagedOrder{
+			ClientOrderID: leg.ClientOrderID,
+			BrokerOrderID: leg.ID,
+			StrategyID:    parent.StrategyID,
+			ParentID:      parent.ClientOrderID,
+			Symbol:        leg.Symbol,
+			Side:          leg.Side,
+			Type:          leg.Type,
+			LimitPrice:    leg.LimitPrice,
+			StopPrice:     leg.StopPrice,
+			State:         OrderStateNew,
+			Signal:        parent.Signal,
+			SubmittedAt:   leg.SubmittedAt,
+			UpdatedAt:     leg.UpdatedAt,
+		}
+		if leg.Qty != nil {
+			child.Qty = *leg.Qty
+		}
+		m.transitionLocked(child, stateFromStatus(leg.Status))
+
+		m.orders[child.ClientOrderID] = child
+		m.brokerIndex[child.BrokerOrderID] = child.ClientOrderID
+	}
+}
+
+// transitionLocked moves an order to a new state if the state machine allows it
+func (m *OrderManager) transitionLocked(order *ManagedOrder, to OrderState) {
+	if order.State == to && to != OrderStatePartiallyFilled {
+		return
+	}
+	if !canTransition(order.State, to) {
+		m.logger.Printf("Ignoring invalid transition for %s: %s -> %s", order.ClientOrderID, order.State, to)
+		return
+	}
+	order.State = to
+	order.UpdatedAt = time.Now()
+}
+
+// bookLocked returns (creating if needed) a strategy's position book
+func (m *OrderManager) bookLocked(strategyID string) *PositionBook {
+	book, exists := m.books[strategyID]
+	if !exists {
+		book = newPositionBook(strategyID)
+		m.books[strategyID] = book
+	}
+	return book
+}
+
+// nextClientOrderIDLocked builds "<strategyID>-<unix millis>-<seq>"
+func (m *OrderManager) nextClientOrderIDLocked(strategyID string) string {
+	m.seq++
+	return fmt.Sprintf("%s-%d-%d", strategyID, time.Now().UnixMilli(), m.seq)
+}
+
+// strategyFromClientOrderID extracts the strategy tag from an OMS client order ID
+func strategyFromClientOrderID(clientOrderID string) string {
+	seqIdx := strings.LastIndex(clientOrderID, "-")
+	if seqIdx <= 0 {
+		return ""
+	}
+	tsIdx := strings.LastIndex(clientOrderID[:seqIdx], "-")
+	if tsIdx <= 0 {
+		return ""
+	}
+
+	// Both trailing segments must be numeric, otherwise it's a broker-generated ID
+	for _, part := range []string{clientOrderID[tsIdx+1 : seqIdx], clientOrderID[seqIdx+1:]} {
+		if part == "" {
+			return ""
+		}
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return ""
+			}
+		}
+	}
+
+	return clientOrderID[:tsIdx]
+}
+
+// stateFromStatus maps an Alpaca order status onto the OMS state machine
+func stateFromStatus(status string) OrderState {
+	switch status {
+	case "new", "accepted", "pending_new", "accepted_for_bidding", "held",
+		"pending_cancel", "pending_replace", "calculated", "stopped":
+		return OrderStateAccepted
+	case "partially_filled":
+		return OrderStatePartiallyFilled
+	case "filled":
+		return OrderStateFilled
+	case "canceled", "expired", "done_for_day", "replaced":
+		return OrderStateCanceled
+	case "rejected", "suspended":
+		return OrderStateRejected
+	default:
+		return OrderStateNew
+	}
+}
+
+// toAlpacaLegs converts stream order legs into REST order structs
+func toAlpacaLegs(legs []Order) []alpaca.Order {
+	converted := make([]alpaca.Order, 0, len(legs))
+	for _, leg := range legs {
+		order := alpaca.Order{
+			ID:            leg.ID,
+			ClientOrderID: leg.ClientOrderID,
+			Symbol:        leg.Symbol,
+			Side:          alpaca.Side(leg.Side),
+			Type:          alpaca.OrderType(leg.Type),
+			Status:        leg.Status,
+			SubmittedAt:   leg.SubmittedAt,
+			UpdatedAt:     leg.UpdatedAt,
+		}
+		if qty, err := decimal.NewFromString(leg.Qty); err == nil {
+			order.Qty = &qty
+		}
+		if leg.LimitPrice != nil {
+			if price, err := decimal.NewFromString(*leg.LimitPrice); err == nil {
+				order.LimitPrice = &price
+			}
+		}
+		if leg.StopPrice != nil {
+			if price, err := decimal.NewFromString(*leg.StopPrice); err == nil {
+				order.StopPrice = &price
+			}
+		}
+		converted = append(converted, order)
+	}
+	return converted
+}
+
+// orderRouting is embedded by strategies that route orders through an OrderManager
+type orderRouting struct {
+	strategyID string
+	oms        *OrderManager
+	store      StateStore       // Live state persistence; nil disables it
+	hub        *MarketDataHub   // Shared market data fan-out
+	exec       *ExecutionEngine // Parent-order algos; nil sends single orders
+
+	// Reported in StrategyStats; guarded separately because orders may be
+	// submitted outside the strategy's own lock
+	statsMu      sync.Mutex
+	lastSignal   string
+	lastSignalAt time.Time
+	lastDataAt   time.Time
+}
+
+// StrategyID returns the tag used for this strategy's client order IDs
+func (r *orderRouting) StrategyID() string {
+	return r.strategyID
+}
+
+// SetOrderManager routes the strategy's orders through a shared OMS
+func (r *orderRouting) SetOrderManager(oms *OrderManager) {
+	r.oms = oms
+}
+
+// ensureOrderManager creates a private OMS trading on an Alpaca account when
+// the strategy runs standalone, and returns the broker its orders go to
+func (r *orderRouting) ensureOrderManager(apiKey, apiSecret, baseURL string) broker.Broker {
+	if r.oms == nil {
+		r.oms = NewOrderManager(NewAlpacaBroker(apiKey, apiSecret, baseURL))
+	}
+	return r.oms.Broker()
+}
+
+// hasWorkingOrder reports whether an entry or exit order (not a bracket leg) is still working
+func (r *orderRouting) hasWorkingOrder(symbol string) bool {
+	if r.oms == nil {
+		return false
+	}
+	for _, order := range r.oms.OpenOrders(r.strategyID) {
+		if order.Symbol == symbol && order.ParentID == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// submitOrder sends an order request to the OMS tagged with this strategy
+func (r *orderRouting) submitOrder(signal string, req alpaca.PlaceOrderRequest, refPrice float64) (ManagedOrder, error) {
+	if r.oms == nil {
+		return ManagedOrder{}, fmt.Errorf("strategy %s has no order manager", r.strategyID)
+	}
+
+	r.statsMu.Lock()
+	r.lastSignal = signal
+	r.lastSignalAt = time.Now()
+	r.statsMu.Unlock()
+
+	return r.oms.Submit(OrderIntent{
+		StrategyID: r.strategyID,
+		Signal:     signal,
+		Request:    req,
+		RefPrice:   decimal.NewFromFloat(refPrice),
+	})
+}
+
+// riskRejections returns how many of this strategy's intents the risk layer rejected
+func (r *orderRouting) riskRejections() int {
+	if r.oms == nil || r.oms.RiskManager() == nil {
+		return 0
+	}
+	return r.oms.RiskManager().Rejections(r.strategyID)
+}
+
+// markData records that market data is still arriving, for strategy health
+// and the circuit breaker
+func (r *orderRouting) markData() {
+	r.statsMu.Lock()
+	r.lastDataAt = time.Now()
+	r.statsMu.Unlock()
+
+	if r.oms == nil {
+		return
+	}
+	if breaker := r.oms.CircuitBreaker(); breaker != nil {
+		breaker.RecordMarketData()
+	}
+}
@@ -0,0 +1,53 @@
+package strategies
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// PairsConfig is one pair to trade, as written by cmd/pair-scanner or by hand
+type PairsConfig struct {
+	SymbolA    string                 `json:"symbol_a"` // Regressed on SymbolB: A = alpha + hedge * B
+	SymbolB    string                 `json:"symbol_b"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"` // Strategy fields, named as for SetParameters
+	Scan       json.RawMessage        `json:"scan,omitempty"`       // Scanner evidence, carried along for reference
+}
+
+// LoadPairsConfig reads a JSON array of pairs
+func LoadPairsConfig(path string) ([]PairsConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pairs []PairsConfig
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return nil, fmt.Errorf("failed to parse pairs config %s: %w", path, err)
+	}
+	for i, pair := range pairs {
+		if pair.SymbolA == "" || pair.SymbolB == "" || pair.SymbolA == pair.SymbolB {
+			return nil, fmt.Errorf("pairs config %s entry %d needs two different symbols", path, i)
+		}
+	}
+	return pairs, nil
+}
+
+// SavePairsConfig writes pairs as an indented JSON array
+func SavePairsConfig(path string, pairs []PairsConfig) error {
+	data, err := json.MarshalIndent(pairs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// NewStrategy creates a pairs strategy with the configured parameters applied
+func (c PairsConfig) NewStrategy() (*PairsTradingStrategy, error) {
+	strategy := NewPairsTradingStrategy(c.SymbolA, c.SymbolB)
+	if len(c.Parameters) > 0 {
+		if err := strategy.SetParameters(c.Parameters); err != nil {
+			return nil, fmt.Errorf("pair %s/%s: %w", c.SymbolA, c.SymbolB, err)
+		}
+	}
+	return strategy, nil
+}
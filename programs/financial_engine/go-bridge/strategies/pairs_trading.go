@@ -12,6 +12,9 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/shopspring/decimal"
 	"gonum.org/v1/gonum/stat"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/cointegration"
 )
 
 // PairsTradingStrategy implements statistical arbitrage on correlated pairs
@@ -26,11 +29,18 @@ type PairsTradingStrategy struct {
 	StopZScore    float64 // Stop loss z-score (e.g., 3.0)
 	PositionSize  float64 // Percentage of equity per leg
 	MinCorrelation float64 // Minimum correlation required
+	MaxPValue     float64 // Engle-Granger p-value at or below which the pair trades (e.g., 0.05)
+	HalfLifeMultiple float64 // Close trades held this many spread half-lives (0 disables)
+	UseKalman     bool    // Update the hedge ratio every bar with a Kalman filter
+	KalmanDelta   float64 // How fast the Kalman hedge ratio may drift per bar (e.g., 1e-4)
 
 	// Alpaca clients
-	tradingClient *alpaca.Client
+	tradingClient broker.Broker
 	dataClient    *marketdata.Client
 
+	// Order routing through the shared OMS
+	orderRouting
+
 	// State management
 	mu            sync.RWMutex
 	pricesA       []float64
@@ -52,10 +62,19 @@ type PairsTradingStrategy struct {
 	qtyB          int64
 	entryPriceA   float64
 	entryPriceB   float64
+	tripPnL       float64 // Realized P&L of the pair trade in progress
 	
 	// Cointegration tracking
 	isCointegrated bool
 	lastCointegrationCheck time.Time
+	olsHedgeRatio  float64 // Engle-Granger hedge; hedgeRatio follows the Kalman filter when enabled
+	intercept      float64
+	adfStat        float64
+	pValue         float64
+	halfLife       float64 // Spread half-life in days, 0 if the spread does not mean-revert
+	kalman         *cointegration.KalmanHedge
+	entryTime      time.Time // Bar time of the open pair trade's entry
+	lastBarTime    time.Time
 	
 	// Performance tracking
 	logger        *log.Logger
@@ -77,20 +96,24 @@ func NewPairsTradingStrategy(symbolA, symbolB string) *PairsTradingStrategy {
 		StopZScore:     3.0,   // Stop loss at 3 std devs
 		PositionSize:   0.01,  // 1% per leg
 		MinCorrelation: 0.70,  // Minimum 70% correlation
+		MaxPValue:      0.05,  // Cointegrated at 5% significance
+		HalfLifeMultiple: 3.0, // Give the spread three half-lives to revert
+		KalmanDelta:    1e-4,
 		pricesA:        make([]float64, 0, 252),
 		pricesB:        make([]float64, 0, 252),
 		logger:         log.New(log.Writer(), "[PAIRS] ", log.LstdFlags),
+		orderRouting:   orderRouting{strategyID: "pairs_" + symbolA + "_" + symbolB},
 	}
 }
 
 // Initialize sets up the Alpaca clients and calculates initial pair parameters
 func (s *PairsTradingStrategy) Initialize(apiKey, apiSecret, baseURL string) error {
-	// Initialize Alpaca trading client
-	s.tradingClient = alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		BaseURL:   baseURL,
-	})
+	// Trade through the shared OMS's broker, or an Alpaca account when standalone
+	s.tradingClient = s.ensureOrderManager(apiKey, apiSecret, baseURL)
+	s.ensureMarketDataHub(apiKey, apiSecret, baseURL)
+	if err := s.ensureStateStore(); err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
+	}
 
 	// Initialize market data client
 	s.dataClient = marketdata.NewClient(marketdata.ClientOpts{
@@ -103,10 +126,15 @@ func (s *PairsTradingStrategy) Initialize(apiKey, apiSecret, baseURL string) err
 		return fmt.Errorf("failed to calculate pair parameters: %w", err)
 	}
 
-	// Check current positions
-	if err := s.syncPositions(); err != nil {
-		return fmt.Errorf("failed to sync positions: %w", err)
+	// Resume leg positions, counters and the open pair trade from before a restart
+	if err := s.restoreState(); err != nil {
+		return fmt.Errorf("failed to restore state: %w", err)
 	}
+	s.syncPositions()
+
+	// Leg positions and P&L follow this strategy's own fills; re-read its book after stream gaps
+	s.oms.Subscribe(s.strategyID, s.onFill)
+	s.oms.OnReconnect(s.strategyID, s.syncPositions)
 
 	s.logger.Printf("Pairs trading initialized for %s/%s (Correlation: %.3f, Hedge Ratio: %.3f)",
 		s.SymbolA, s.SymbolB, s.correlation, s.hedgeRatio)
@@ -162,105 +190,218 @@ func (s *PairsTradingStrategy) calculatePairParameters() error {
 		return fmt.Errorf("insufficient data: only %d aligned bars", len(s.pricesA))
 	}
 
-	// Calculate correlation
+	// Correlation is a secondary filter; cointegration decides whether the pair trades
 	s.correlation = stat.Correlation(s.pricesA, s.pricesB, nil)
-	
 	if s.correlation < s.MinCorrelation {
 		s.logger.Printf("Warning: Low correlation %.3f < %.3f", s.correlation, s.MinCorrelation)
-		s.isCointegrated = false
-	} else {
-		s.isCointegrated = true
 	}
 
-	// Calculate hedge ratio using linear regression (OLS)
-	// priceA = alpha + beta * priceB
-	// beta is our hedge ratio
-	alpha, beta := stat.LinearRegression(s.pricesB, s.pricesA, nil, false)
-	s.hedgeRatio = beta
-	
+	// Engle-Granger: priceA = alpha + beta * priceB by OLS, then an augmented
+	// Dickey-Fuller test on the spread. beta is our hedge ratio.
+	eg, err := cointegration.EngleGranger(s.pricesA, s.pricesB, -1)
+	if err != nil {
+		return fmt.Errorf("cointegration test failed: %w", err)
+	}
+	s.olsHedgeRatio = eg.HedgeRatio
+	s.intercept = eg.Intercept
+	s.adfStat = eg.Statistic
+	s.pValue = eg.PValue
+	if s.kalman == nil {
+		s.hedgeRatio = eg.HedgeRatio
+	}
+
+	wasCointegrated := s.isCointegrated
+	s.isCointegrated = eg.Cointegrated(s.MaxPValue)
+	if !s.isCointegrated {
+		if wasCointegrated {
+			s.correlationBreaks++
+		}
+		s.logger.Printf("Warning: Pair not cointegrated (ADF %.2f, p=%.3f > %.3f), no new entries",
+			eg.Statistic, eg.PValue, s.MaxPValue)
+	}
+
 	// Calculate spread series: spreadT = priceA - hedgeRatio * priceB
 	spreads := make([]float64, len(s.pricesA))
 	for i := range s.pricesA {
-		spreads[i] = s.pricesA[i] - s.hedgeRatio*s.pricesB[i]
+		spreads[i] = s.pricesA[i] - s.olsHedgeRatio*s.pricesB[i]
 	}
-	
+
 	// Calculate mean and standard deviation of spread
 	s.meanSpread = stat.Mean(spreads, nil)
 	s.stdSpread = stat.StdDev(spreads, nil)
-	
-	// Simple stationarity check (approximation of ADF test)
-	// Check if spread mean-reverts by looking at autocorrelation
-	if len(spreads) > 1 {
-		autocorr := s.calculateAutocorrelation(spreads, 1)
-		if autocorr > 0.95 {
-			s.logger.Printf("Warning: Spread shows high autocorrelation (%.3f), may not be stationary", autocorr)
-			s.isCointegrated = false
-		}
+
+	// Ornstein-Uhlenbeck half-life sets how long a trade may wait for reversion
+	s.halfLife = 0
+	if halfLife, err := cointegration.HalfLife(spreads); err == nil && !math.IsInf(halfLife, 1) {
+		s.halfLife = halfLife
 	}
-	
+
 	s.lastCointegrationCheck = time.Now()
-	
-	s.logger.Printf("Pair parameters: Alpha=%.3f, Beta=%.3f, Mean=%.2f, Std=%.2f, Corr=%.3f",
-		alpha, s.hedgeRatio, s.meanSpread, s.stdSpread, s.correlation)
-	
+
+	s.logger.Printf("Pair parameters: Alpha=%.3f, Beta=%.3f, Mean=%.2f, Std=%.2f, Corr=%.3f, ADF=%.2f (p=%.3f), HalfLife=%.1fd",
+		eg.Intercept, eg.HedgeRatio, s.meanSpread, s.stdSpread, s.correlation, eg.Statistic, eg.PValue, s.halfLife)
+
 	return nil
 }
 
-// calculateAutocorrelation computes autocorrelation at given lag
-func (s *PairsTradingStrategy) calculateAutocorrelation(series []float64, lag int) float64 {
-	if lag >= len(series) {
+// maxHoldingPeriod is HalfLifeMultiple spread half-lives, or 0 for no limit.
+// Half-lives are in daily bars; counting them as calendar days errs toward
+// exiting early.
+func (s *PairsTradingStrategy) maxHoldingPeriod() time.Duration {
+	if s.HalfLifeMultiple <= 0 || s.halfLife <= 0 {
 		return 0
 	}
-	
-	n := len(series) - lag
-	series1 := series[:n]
-	series2 := series[lag:]
-	
-	return stat.Correlation(series1, series2, nil)
+	return time.Duration(s.HalfLifeMultiple * s.halfLife * float64(24*time.Hour))
 }
 
-// syncPositions checks if we have existing positions in the pair
-func (s *PairsTradingStrategy) syncPositions() error {
-	positions, err := s.tradingClient.GetPositions()
-	if err != nil {
-		return err
+// syncPositions loads both legs from the strategy's own OMS book. A single
+// leg left open is still a pair trade to exit, not a flat book.
+func (s *PairsTradingStrategy) syncPositions() {
+	qtyA, entryPriceA := s.bookPosition(s.SymbolA)
+	qtyB, entryPriceB := s.bookPosition(s.SymbolB)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.qtyA, s.entryPriceA = qtyA, entryPriceA
+	s.qtyB, s.entryPriceB = qtyB, entryPriceB
+	s.inPosition = qtyA != 0 || qtyB != 0
+
+	switch {
+	case !s.inPosition:
+		s.positionType = ""
+	case qtyA > 0 || (qtyA == 0 && qtyB < 0):
+		s.positionType = "longA_shortB"
+	default:
+		s.positionType = "shortA_longB"
 	}
 
+	if s.inPosition && (qtyA == 0 || qtyB == 0) {
+		s.logger.Printf("Managing partial pair position (A: %d, B: %d)", qtyA, qtyB)
+	}
+}
+
+// onFill updates leg positions and P&L from actual executions. A pair trade
+// counts as closed once both legs are flat.
+func (s *PairsTradingStrategy) onFill(fill FillEvent) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.inPosition = false
-	hasA, hasB := false, false
-	
-	for _, pos := range positions {
-		if pos.Symbol == s.SymbolA {
-			hasA = true
-			s.qtyA = pos.Qty.BigInt().Int64()
-			avgPrice, _ := pos.AvgEntryPrice.Float64()
-			s.entryPriceA = avgPrice
-			
-			// Determine position type based on side
-			if s.qtyA > 0 {
-				s.positionType = "longA_shortB"
-			} else {
-				s.positionType = "shortA_longB"
-			}
+	qty := fill.Position.Qty.IntPart()
+	avgPrice := fill.Position.AvgEntryPrice.InexactFloat64()
+
+	switch fill.Symbol {
+	case s.SymbolA:
+		s.qtyA = qty
+		s.entryPriceA = avgPrice
+	case s.SymbolB:
+		s.qtyB = qty
+		s.entryPriceB = avgPrice
+	default:
+		return
+	}
+
+	pnl := fill.RealizedPnL.InexactFloat64()
+	s.totalPnL += pnl
+	s.tripPnL += pnl
+
+	wasInPosition := s.inPosition
+	s.inPosition = s.qtyA != 0 || s.qtyB != 0
+
+	if s.inPosition {
+		if s.qtyA > 0 || (s.qtyA == 0 && s.qtyB < 0) {
+			s.positionType = "longA_shortB"
+		} else {
+			s.positionType = "shortA_longB"
 		}
-		if pos.Symbol == s.SymbolB {
-			hasB = true
-			s.qtyB = pos.Qty.BigInt().Int64()
-			avgPrice, _ := pos.AvgEntryPrice.Float64()
-			s.entryPriceB = avgPrice
+	} else {
+		if wasInPosition {
+			s.tradeCount++
+			if s.tripPnL > 0 {
+				s.winCount++
+			}
+			s.logger.Printf("Pair trade closed, P&L: %.2f", s.tripPnL)
 		}
+		s.positionType = ""
+		s.tripPnL = 0
+		s.entryTime = time.Time{}
 	}
-	
-	s.inPosition = hasA && hasB
-	
-	if s.inPosition {
-		s.logger.Printf("Found existing pair position: %s (A: %d @ %.2f, B: %d @ %.2f)",
-			s.positionType, s.qtyA, s.entryPriceA, s.qtyB, s.entryPriceB)
+
+	s.saveStateLocked()
+
+	s.logger.Printf("%s %s %s %s @ %s (A: %d @ %.2f, B: %d @ %.2f)",
+		fill.Event, fill.Side, fill.Qty.String(), fill.Symbol, fill.Price.StringFixed(2),
+		s.qtyA, s.entryPriceA, s.qtyB, s.entryPriceB)
+}
+
+// pairsState is the live state persisted across restarts
+type pairsState struct {
+	QtyA               int64   `json:"qty_a"`
+	QtyB               int64   `json:"qty_b"`
+	EntryPriceA        float64 `json:"entry_price_a"`
+	EntryPriceB        float64 `json:"entry_price_b"`
+	PositionType       string  `json:"position_type,omitempty"`
+	EntrySpread        float64 `json:"entry_spread"`
+	EntryTime          time.Time `json:"entry_time"`
+	TripPnL            float64 `json:"trip_pnl"` // Realized so far on the open pair trade
+	Trades             int     `json:"trades"`
+	Wins               int     `json:"wins"`
+	TotalPnL           float64 `json:"total_pnl"`
+	CorrelationBreaks  int     `json:"correlation_breaks"`
+	MaxSpreadDeviation float64 `json:"max_spread_deviation"`
+}
+
+// saveStateLocked snapshots live state to the store; caller must hold s.mu
+func (s *PairsTradingStrategy) saveStateLocked() {
+	state := pairsState{
+		QtyA:               s.qtyA,
+		QtyB:               s.qtyB,
+		EntryPriceA:        s.entryPriceA,
+		EntryPriceB:        s.entryPriceB,
+		PositionType:       s.positionType,
+		EntrySpread:        s.entrySpread,
+		EntryTime:          s.entryTime,
+		TripPnL:            s.tripPnL,
+		Trades:             s.tradeCount,
+		Wins:               s.winCount,
+		TotalPnL:           s.totalPnL,
+		CorrelationBreaks:  s.correlationBreaks,
+		MaxSpreadDeviation: s.maxSpreadDeviation,
+	}
+	if err := s.saveState(state); err != nil {
+		s.logger.Printf("Failed to save state: %v", err)
 	}
+}
+
+// restoreState reloads state saved before a restart, including both leg
+// positions, which seed this strategy's OMS book
+func (s *PairsTradingStrategy) restoreState() error {
+	var saved pairsState
+	found, err := s.loadState(&saved)
+	if err != nil || !found {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	s.tradeCount = saved.Trades
+	s.winCount = saved.Wins
+	s.totalPnL = saved.TotalPnL
+	s.correlationBreaks = saved.CorrelationBreaks
+	s.maxSpreadDeviation = saved.MaxSpreadDeviation
+
+	s.qtyA, s.entryPriceA = saved.QtyA, saved.EntryPriceA
+	s.qtyB, s.entryPriceB = saved.QtyB, saved.EntryPriceB
+	s.positionType = saved.PositionType
+	s.entrySpread = saved.EntrySpread
+	s.entryTime = saved.EntryTime
+	s.tripPnL = saved.TripPnL
+	s.restorePosition(s.SymbolA, saved.QtyA, saved.EntryPriceA)
+	s.restorePosition(s.SymbolB, saved.QtyB, saved.EntryPriceB)
+
+	s.logger.Printf("Restored state: %d trades, %d wins, P&L %.2f", s.tradeCount, s.winCount, s.totalPnL)
+	s.saveStateLocked()
 	return nil
 }
 
@@ -268,10 +409,12 @@ func (s *PairsTradingStrategy) syncPositions() error {
 func (s *PairsTradingStrategy) ProcessBars(priceA, priceB float64, timestamp time.Time) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.markData()
 
 	// Update prices
 	s.lastPriceA = priceA
 	s.lastPriceB = priceB
+	s.lastBarTime = timestamp
 	
 	// Update rolling price windows
 	s.pricesA = append(s.pricesA, priceA)
@@ -292,10 +435,23 @@ func (s *PairsTradingStrategy) ProcessBars(priceA, priceB float64, timestamp tim
 		}()
 	}
 	
-	// Calculate current spread and z-score
-	s.currentSpread = priceA - s.hedgeRatio*priceB
-	if s.stdSpread > 0 {
-		s.currentZScore = (s.currentSpread - s.meanSpread) / s.stdSpread
+	// Calculate current spread and z-score, from the Kalman filter's one-bar
+	// forecast error when enabled
+	if s.UseKalman && s.stdSpread > 0 {
+		if s.kalman == nil {
+			s.kalman = cointegration.NewKalmanHedge(s.KalmanDelta, s.stdSpread*s.stdSpread, s.olsHedgeRatio, s.intercept)
+		}
+		estimate := s.kalman.Update(priceA, priceB)
+		s.hedgeRatio = estimate.HedgeRatio
+		s.currentSpread = estimate.Spread
+		s.currentZScore = estimate.ZScore
+	} else {
+		s.kalman = nil
+		s.hedgeRatio = s.olsHedgeRatio
+		s.currentSpread = priceA - s.hedgeRatio*priceB
+		if s.stdSpread > 0 {
+			s.currentZScore = (s.currentSpread - s.meanSpread) / s.stdSpread
+		}
 	}
 	
 	// Track maximum spread deviation
@@ -304,17 +460,17 @@ func (s *PairsTradingStrategy) ProcessBars(priceA, priceB float64, timestamp tim
 		s.maxSpreadDeviation = absZ
 	}
 	
-	// Check if pair is still valid
-	if !s.isCointegrated || s.correlation < s.MinCorrelation {
-		if s.inPosition {
-			s.logger.Printf("Cointegration/correlation broken, unwinding position")
-			go s.executePairTrade("UNWIND", priceA, priceB)
-		}
+	// Give up on trades the spread has had several half-lives to close
+	if limit := s.maxHoldingPeriod(); s.inPosition && limit > 0 && !s.entryTime.IsZero() && timestamp.Sub(s.entryTime) > limit {
+		s.logger.Printf("Holding period of %s exceeded (half-life %.1fd), unwinding position",
+			limit.Round(time.Hour), s.halfLife)
+		go s.executePairTrade("UNWIND", priceA, priceB)
 		return
 	}
-	
-	// Generate trading signal
-	signal := s.generateSignal()
+
+	// Generate trading signal; only a cointegrated, correlated pair opens new
+	// trades, while open trades keep their exits if the relationship breaks
+	signal := s.generateSignal(s.isCointegrated && s.correlation >= s.MinCorrelation)
 	
 	if signal != "" {
 		s.logger.Printf("Signal: %s | Spread: %.2f | Z-Score: %.2f | Prices: A=%.2f B=%.2f",
@@ -326,9 +482,9 @@ func (s *PairsTradingStrategy) ProcessBars(priceA, priceB float64, timestamp tim
 }
 
 // generateSignal determines if we should enter/exit positions
-func (s *PairsTradingStrategy) generateSignal() string {
+func (s *PairsTradingStrategy) generateSignal(canEnter bool) string {
 	// Entry signals
-	if !s.inPosition {
+	if !s.inPosition && canEnter {
 		if s.currentZScore > s.EntryZScore {
 			// Spread is too high: A is overvalued relative to B
 			// Short A, Long B
@@ -371,6 +527,12 @@ func (s *PairsTradingStrategy) generateSignal() string {
 
 // executePairTrade places simultaneous orders for both legs
 func (s *PairsTradingStrategy) executePairTrade(signal string, priceA, priceB float64) {
+	// Don't stack signals while either leg is still working
+	if s.hasWorkingOrder(s.SymbolA) || s.hasWorkingOrder(s.SymbolB) {
+		s.logger.Printf("Orders already working for %s/%s, skipping %s", s.SymbolA, s.SymbolB, signal)
+		return
+	}
+
 	// Get account info for position sizing
 	account, err := s.tradingClient.GetAccount()
 	if err != nil {
@@ -394,17 +556,11 @@ func (s *PairsTradingStrategy) executePairTrade(signal string, priceA, priceB fl
 			return
 		}
 		
-		// Calculate P&L before closing
+		// Estimate only: realized P&L is booked in onFill from the executions
 		pnlA := float64(s.qtyA) * (priceA - s.entryPriceA)
-		pnlB := -float64(s.qtyB) * (priceB - s.entryPriceB) // Negative because opposite side
+		pnlB := float64(s.qtyB) * (priceB - s.entryPriceB) // qtyB is negative when short
 		totalPnL := pnlA + pnlB
 		
-		s.totalPnL += totalPnL
-		if totalPnL > 0 {
-			s.winCount++
-		}
-		s.tradeCount++
-		
 		// Close position A
 		orderA := alpaca.PlaceOrderRequest{
 			Symbol:      s.SymbolA,
@@ -430,29 +586,18 @@ func (s *PairsTradingStrategy) executePairTrade(signal string, priceA, priceB fl
 		}
 		
 		// Submit orders
-		if _, err := s.tradingClient.PlaceOrder(orderA); err != nil {
+		if _, err := s.submitOrder(signal, orderA, priceA); err != nil {
 			s.logger.Printf("Failed to close %s: %v", s.SymbolA, err)
 			return
 		}
-		if _, err := s.tradingClient.PlaceOrder(orderB); err != nil {
+		if _, err := s.submitOrder(signal, orderB, priceB); err != nil {
 			s.logger.Printf("Failed to close %s: %v", s.SymbolB, err)
 			return
 		}
 		
-		s.logger.Printf("Unwound pair position, P&L: %.2f (A: %.2f, B: %.2f)",
+		s.logger.Printf("Unwinding pair position, est. P&L: %.2f (A: %.2f, B: %.2f)",
 			totalPnL, pnlA, pnlB)
 		
-		// Update state
-		s.mu.Lock()
-		s.inPosition = false
-		s.positionType = ""
-		s.qtyA = 0
-		s.qtyB = 0
-		s.entryPriceA = 0
-		s.entryPriceB = 0
-		s.entrySpread = 0
-		s.mu.Unlock()
-		
 	} else {
 		// Enter new position
 		if s.inPosition {
@@ -521,37 +666,28 @@ func (s *PairsTradingStrategy) executePairTrade(signal string, priceA, priceB fl
 		}
 		
 		// Submit orders (ideally would batch these)
-		orderAResp, err := s.tradingClient.PlaceOrder(orderA)
+		orderAResp, err := s.submitOrder(signal, orderA, priceA)
 		if err != nil {
 			s.logger.Printf("Failed to place order for %s: %v", s.SymbolA, err)
 			return
 		}
 		
-		_, err = s.tradingClient.PlaceOrder(orderB)
+		_, err = s.submitOrder(signal, orderB, priceB)
 		if err != nil {
 			s.logger.Printf("Failed to place order for %s: %v", s.SymbolB, err)
 			// Cancel first order if second fails
-			s.tradingClient.CancelOrder(orderAResp.ID)
+			s.oms.Cancel(orderAResp.ClientOrderID)
 			return
 		}
 		
-		s.logger.Printf("Entered pair position: %s (A: %d @ %.2f, B: %d @ %.2f)",
+		s.logger.Printf("Submitted pair entry: %s (A: %d @ %.2f, B: %d @ %.2f)",
 			signal, qtyA, priceA, qtyB, priceB)
 		
-		// Update state
+		// Leg positions are updated from fills (see onFill)
 		s.mu.Lock()
-		s.inPosition = true
-		s.positionType = signal
-		s.qtyA = qtyA
-		s.qtyB = qtyB
-		s.entryPriceA = priceA
-		s.entryPriceB = priceB
 		s.entrySpread = s.currentSpread
-		if signal == "SHORT_A_LONG_B" {
-			s.qtyA = -qtyA // Negative for short
-		} else {
-			s.qtyB = -qtyB // Negative for short
-		}
+		s.entryTime = s.lastBarTime
+		s.saveStateLocked()
 		s.mu.Unlock()
 	}
 }
@@ -583,45 +719,79 @@ func (s *PairsTradingStrategy) validateTradeCompliance(account *alpaca.Account,
 	return true
 }
 
+// SetParameters updates configuration fields while the strategy runs
+func (s *PairsTradingStrategy) SetParameters(params map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delta := s.KalmanDelta
+	err := setParameters(s, params, func() error {
+		if s.LookbackDays < 2 {
+			return fmt.Errorf("lookback days must be at least 2")
+		}
+		if s.ExitZScore < 0 || s.ExitZScore >= s.EntryZScore || s.EntryZScore >= s.StopZScore {
+			return fmt.Errorf("z-scores must satisfy 0 <= exit < entry < stop")
+		}
+		if s.PositionSize <= 0 || s.PositionSize > 1 {
+			return fmt.Errorf("position size must be a fraction of equity in (0, 1]")
+		}
+		if s.MaxPValue <= 0 || s.MaxPValue > 1 {
+			return fmt.Errorf("max p-value must be in (0, 1]")
+		}
+		if s.HalfLifeMultiple < 0 {
+			return fmt.Errorf("half-life multiple must not be negative")
+		}
+		if s.KalmanDelta <= 0 || s.KalmanDelta >= 1 {
+			return fmt.Errorf("kalman delta must be in (0, 1)")
+		}
+		return nil
+	}, "SymbolA", "SymbolB")
+	if err == nil && s.KalmanDelta != delta {
+		s.kalman = nil // Restarted from the OLS hedge with the new delta
+	}
+	return err
+}
+
 // GetStatistics returns strategy performance metrics
-func (s *PairsTradingStrategy) GetStatistics() map[string]interface{} {
+func (s *PairsTradingStrategy) GetStatistics() StrategyStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	winRate := 0.0
-	avgTrade := 0.0
-	if s.tradeCount > 0 {
-		winRate = float64(s.winCount) / float64(s.tradeCount) * 100
-		avgTrade = s.totalPnL / float64(s.tradeCount)
-	}
-
-	return map[string]interface{}{
-		"symbol":              fmt.Sprintf("%s/%s", s.SymbolA, s.SymbolB),
-		"strategy":            "Pairs Trading",
-		"correlation":         s.correlation,
-		"hedge_ratio":         s.hedgeRatio,
-		"current_z_score":     s.currentZScore,
-		"max_z_score":         s.maxSpreadDeviation,
-		"trades":              s.tradeCount,
-		"wins":                s.winCount,
-		"win_rate":            winRate,
-		"total_pnl":           s.totalPnL,
-		"avg_trade_pnl":       avgTrade,
-		"correlation_breaks":  s.correlationBreaks,
-		"is_cointegrated":     s.isCointegrated,
-		"in_position":         s.inPosition,
-		"position_type":       s.positionType,
-	}
+	marks := map[string]float64{s.SymbolA: s.lastPriceA, s.SymbolB: s.lastPriceB}
+
+	stats := s.newStats("Pairs Trading", []string{s.SymbolA, s.SymbolB}, s.tradeCount, s.winCount, s.totalPnL, marks)
+	stats.warmingUp(len(s.pricesA) < 20)
+	stats.Extra["correlation"] = s.correlation
+	stats.Extra["hedge_ratio"] = s.hedgeRatio
+	stats.Extra["current_z_score"] = s.currentZScore
+	stats.Extra["max_z_score"] = s.maxSpreadDeviation
+	stats.Extra["correlation_breaks"] = s.correlationBreaks
+	stats.Extra["is_cointegrated"] = s.isCointegrated
+	stats.Extra["adf_statistic"] = s.adfStat
+	stats.Extra["cointegration_p_value"] = s.pValue
+	stats.Extra["half_life_days"] = s.halfLife
+	stats.Extra["kalman_hedge"] = s.kalman != nil
+	stats.Extra["in_position"] = s.inPosition
+	stats.Extra["position_type"] = s.positionType
+	return stats
 }
 
 // Run starts the strategy main loop with polling (WebSocket coming next)
 func (s *PairsTradingStrategy) Run(ctx context.Context) error {
-	// Use polling approach for now
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
+	// Start order tracking (no-op if the runner already started the shared OMS)
+	if err := s.oms.Start(ctx); err != nil {
+		s.logger.Printf("Order updates unavailable: %v", err)
+	}
+
+	// Bars for both legs arrive from the shared market data hub
+	bars, err := s.subscribeBars(ctx, s.SymbolA, s.SymbolB)
+	if err != nil {
+		return err
+	}
+	defer bars.Close()
 
-	// Polling setup (WebSocket implementation will follow)
-	// This ensures compilation success first
+	// The spread needs both legs at the same bar time; hold the newest of each
+	var pendingA, pendingB *MarketBar
 
 	s.logger.Printf("Pairs trading strategy running for %s/%s", s.SymbolA, s.SymbolB)
 
@@ -645,6 +815,20 @@ func (s *PairsTradingStrategy) Run(ctx context.Context) error {
 			stats := s.GetStatistics()
 			s.logger.Printf("Final Statistics: %+v", stats)
 			return nil
+
+		case event := <-bars.Events():
+			if event.Cached {
+				continue // Last-value replay of a bar already in history or processed
+			}
+			if event.Symbol == s.SymbolA {
+				pendingA = event.Bar
+			} else {
+				pendingB = event.Bar
+			}
+			if pendingA != nil && pendingB != nil && pendingA.Timestamp.Equal(pendingB.Timestamp) {
+				s.ProcessBars(pendingA.Close, pendingB.Close, pendingA.Timestamp)
+				pendingA, pendingB = nil, nil
+			}
 			
 		case <-statsTicker.C:
 			// Print periodic statistics
@@ -0,0 +1,467 @@
+package strategies
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+// Risk rules reported in rejections and statistics
+const (
+	RiskRuleSymbolExposure = "symbol_exposure"
+	RiskRuleSectorExposure = "sector_exposure"
+	RiskRuleGrossExposure  = "gross_exposure"
+	RiskRuleNetExposure    = "net_exposure"
+	RiskRuleOpenOrders     = "open_orders"
+	RiskRuleOpposingIntent = "opposing_intent"
+)
+
+// NettingPolicy controls how an intent that opposes another strategy is handled
+type NettingPolicy string
+
+const (
+	NettingAllow  NettingPolicy = "allow"  // Send to the broker regardless
+	NettingReject NettingPolicy = "reject" // Reject intents that fight another strategy
+	NettingCross  NettingPolicy = "cross"  // Cross internally against resting opposite orders
+)
+
+// ErrRiskRejected is wrapped by every risk rejection
+var ErrRiskRejected = errors.New("rejected by portfolio risk")
+
+// RiskLimits are dollar exposure and order count limits. Zero disables a limit.
+type RiskLimits struct {
+	MaxSymbolExposure float64 `json:"max_symbol_exposure"` // Absolute notional in any one symbol
+	MaxSectorExposure float64 `json:"max_sector_exposure"` // Absolute notional in any one sector
+	MaxGrossExposure  float64 `json:"max_gross_exposure"`  // Sum of absolute symbol notionals
+	MaxNetExposure    float64 `json:"max_net_exposure"`    // Absolute long minus short notional
+	MaxOpenOrders     int     `json:"max_open_orders"`     // Working orders (bracket legs excluded)
+}
+
+// StrategyRiskConfig is the per-strategy risk configuration
+type StrategyRiskConfig struct {
+	Limits  RiskLimits    `json:"limits"`
+	Netting NettingPolicy `json:"netting"`
+}
+
+// RiskRejection describes a rejected intent
+type RiskRejection struct {
+	StrategyID string
+	Symbol     string
+	Rule       string
+	Reason     string
+}
+
+func (r *RiskRejection) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", ErrRiskRejected, r.Reason, r.Rule)
+}
+
+func (r *RiskRejection) Unwrap() error {
+	return ErrRiskRejected
+}
+
+// PortfolioRiskManager is the shared pre-trade risk layer for all strategies.
+// Global limits apply to the combined books of every strategy; per-strategy
+// limits apply to that strategy's own book.
+type PortfolioRiskManager struct {
+	mu             sync.RWMutex
+	global         RiskLimits
+	defaultNetting NettingPolicy
+	strategies     map[string]StrategyRiskConfig
+	sectors        map[string]string          // Symbol -> sector
+	prices         map[string]decimal.Decimal // Symbol -> last known price
+	rejections     map[string]map[string]int  // Strategy ID -> rule -> count
+	crossed        int
+
+	oms    *OrderManager
+	logger *log.Logger
+}
+
+// NewPortfolioRiskManager creates a risk layer with global limits
+func NewPortfolioRiskManager(global RiskLimits) *PortfolioRiskManager {
+	return &PortfolioRiskManager{
+		global:         global,
+		defaultNetting: NettingReject,
+		strategies:     make(map[string]StrategyRiskConfig),
+		sectors:        make(map[string]string),
+		prices:         make(map[string]decimal.Decimal),
+		rejections:     make(map[string]map[string]int),
+		logger:         log.New(log.Writer(), "[PORTFOLIO-RISK] ", log.LstdFlags),
+	}
+}
+
+// attach links the risk layer to the OMS whose books it reads
+func (r *PortfolioRiskManager) attach(oms *OrderManager) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.oms = oms
+}
+
+// SetGlobalLimits replaces the portfolio-wide limits
+func (r *PortfolioRiskManager) SetGlobalLimits(limits RiskLimits) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.global = limits
+}
+
+// SetStrategyConfig sets limits and netting policy for one strategy
+func (r *PortfolioRiskManager) SetStrategyConfig(strategyID string, cfg StrategyRiskConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.strategies[strategyID] = cfg
+}
+
+// SetDefaultNetting sets the netting policy for strategies without their own config
+func (r *PortfolioRiskManager) SetDefaultNetting(policy NettingPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaultNetting = policy
+}
+
+// SetSectors sets the symbol -> sector map used for sector limits
+func (r *PortfolioRiskManager) SetSectors(sectors map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sectors = make(map[string]string, len(sectors))
+	for symbol, sector := range sectors {
+		r.sectors[symbol] = sector
+	}
+}
+
+// UpdatePrice records the latest price used to mark exposure
+func (r *PortfolioRiskManager) UpdatePrice(symbol string, price decimal.Decimal) {
+	if !price.IsPositive() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prices[symbol] = price
+}
+
+// NettingFor returns the netting policy for a strategy
+func (r *PortfolioRiskManager) NettingFor(strategyID string) NettingPolicy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if cfg, exists := r.strategies[strategyID]; exists && cfg.Netting != "" {
+		return cfg.Netting
+	}
+	return r.defaultNetting
+}
+
+// exposureSnapshot is signed notional per symbol plus open order counts
+type exposureSnapshot struct {
+	bySymbol   map[string]float64
+	openOrders int
+}
+
+// Check validates an intent against global and strategy limits. Exposure
+// counts book positions plus working orders, and the OMS registers an
+// accepted intent's order before checking the next one, so concurrent
+// intents cannot each pass against the same headroom. Exposure checks only
+// reject intents that increase the exposure being measured, and intents that
+// reduce the strategy's own position skip the order count and netting
+// checks, so closing trades always pass.
+func (r *PortfolioRiskManager) Check(intent OrderIntent) error {
+	return r.check(intent, "")
+}
+
+// CheckReplace validates the order a working order would become after a
+// cancel/replace; the working order's own exposure is left out of the check
+func (r *PortfolioRiskManager) CheckReplace(intent OrderIntent, replacing string) error {
+	return r.check(intent, replacing)
+}
+
+// check validates an intent, ignoring the working order being replaced, if any
+func (r *PortfolioRiskManager) check(intent OrderIntent, replacing string) error {
+	r.UpdatePrice(intent.Request.Symbol, intent.RefPrice)
+
+	r.mu.RLock()
+	oms := r.oms
+	r.mu.RUnlock()
+	if oms == nil {
+		return nil
+	}
+
+	positions, openOrders := oms.riskSnapshot()
+	if replacing != "" {
+		kept := openOrders[:0]
+		for _, order := range openOrders {
+			if order.ClientOrderID != replacing {
+				kept = append(kept, order)
+			}
+		}
+		openOrders = kept
+	}
+
+	r.mu.RLock()
+	cfg, hasCfg := r.strategies[intent.StrategyID]
+	netting := r.defaultNetting
+	if hasCfg && cfg.Netting != "" {
+		netting = cfg.Netting
+	}
+
+	delta := r.intentNotional(intent)
+	portfolio := r.snapshot(positions, openOrders, "")
+	own := r.snapshot(positions, openOrders, intent.StrategyID)
+	reducing := reducesPosition(intent, positions[intent.StrategyID])
+
+	rejection := r.checkLimits(r.global, portfolio, intent.Request.Symbol, delta, reducing, "portfolio")
+	if rejection == nil && hasCfg {
+		rejection = r.checkLimits(cfg.Limits, own, intent.Request.Symbol, delta, reducing, "strategy")
+	}
+	if rejection == nil && netting == NettingReject && !reducing {
+		rejection = r.checkOpposing(intent, positions, openOrders)
+	}
+	r.mu.RUnlock()
+
+	if rejection == nil {
+		return nil
+	}
+
+	rejection.StrategyID = intent.StrategyID
+	rejection.Symbol = intent.Request.Symbol
+	r.recordRejection(rejection)
+	return rejection
+}
+
+// intentNotional is the signed dollar change in exposure the intent would cause
+func (r *PortfolioRiskManager) intentNotional(intent OrderIntent) float64 {
+	var notional float64
+	switch {
+	case intent.Request.Qty != nil:
+		notional = intent.Request.Qty.Mul(r.priceFor(intent.Request.Symbol, intent.RefPrice)).InexactFloat64()
+	case intent.Request.Notional != nil:
+		notional = intent.Request.Notional.InexactFloat64()
+	}
+	if intent.Request.Side == alpaca.Sell {
+		notional = -notional
+	}
+	return notional
+}
+
+// priceFor returns the mark for a symbol, falling back to the given price
+func (r *PortfolioRiskManager) priceFor(symbol string, fallback decimal.Decimal) decimal.Decimal {
+	if price, exists := r.prices[symbol]; exists {
+		return price
+	}
+	return fallback
+}
+
+// snapshot builds signed exposure (positions plus working orders) for one strategy, or all if ""
+func (r *PortfolioRiskManager) snapshot(positions map[string][]BookPosition, openOrders []ManagedOrder, strategyID string) exposureSnapshot {
+	snap := exposureSnapshot{bySymbol: make(map[string]float64)}
+
+	for id, book := range positions {
+		if strategyID != "" && id != strategyID {
+			continue
+		}
+		for _, pos := range book {
+			price := r.priceFor(pos.Symbol, pos.AvgEntryPrice)
+			snap.bySymbol[pos.Symbol] += pos.Qty.Mul(price).InexactFloat64()
+		}
+	}
+
+	for _, order := range openOrders {
+		if order.ParentID != "" || (strategyID != "" && order.StrategyID != strategyID) {
+			continue // Bracket legs only ever reduce the parent's position
+		}
+		snap.openOrders++
+
+		price := decimal.Zero
+		if order.LimitPrice != nil {
+			price = *order.LimitPrice
+		}
+		remaining := order.Qty.Sub(order.FilledQty).Mul(r.priceFor(order.Symbol, price)).InexactFloat64()
+		if order.Qty.IsZero() && order.Notional != nil {
+			remaining = math.Max(order.Notional.Sub(order.FilledQty.Mul(order.AvgFillPrice)).InexactFloat64(), 0)
+		}
+		if order.Side == alpaca.Sell {
+			remaining = -remaining
+		}
+		snap.bySymbol[order.Symbol] += remaining
+	}
+
+	return snap
+}
+
+// reducesPosition reports whether an intent trades against the strategy's own
+// position in its symbol without being able to flip it
+func reducesPosition(intent OrderIntent, book []BookPosition) bool {
+	ownQty := decimal.Zero
+	for _, pos := range book {
+		if pos.Symbol == intent.Request.Symbol {
+			ownQty = pos.Qty
+		}
+	}
+	if ownQty.IsZero() || intent.Request.Qty == nil {
+		return false
+	}
+
+	buying := intent.Request.Side == alpaca.Buy
+	if (buying && ownQty.IsPositive()) || (!buying && ownQty.IsNegative()) {
+		return false
+	}
+	return intent.Request.Qty.LessThanOrEqual(ownQty.Abs())
+}
+
+// checkLimits tests an exposure snapshot against limits after applying delta.
+// Reducing intents are exempt from the open order limit.
+func (r *PortfolioRiskManager) checkLimits(limits RiskLimits, snap exposureSnapshot, symbol string, delta float64, reducing bool, scope string) *RiskRejection {
+	if limits.MaxOpenOrders > 0 && snap.openOrders >= limits.MaxOpenOrders && !reducing {
+		return &RiskRejection{Rule: RiskRuleOpenOrders,
+			Reason: fmt.Sprintf("%s has %d open orders (max %d)", scope, snap.openOrders, limits.MaxOpenOrders)}
+	}
+
+	before := snap.bySymbol[symbol]
+	after := before + delta
+	if limits.MaxSymbolExposure > 0 && math.Abs(after) > limits.MaxSymbolExposure && math.Abs(after) > math.Abs(before) {
+		return &RiskRejection{Rule: RiskRuleSymbolExposure,
+			Reason: fmt.Sprintf("%s %s exposure $%.2f > $%.2f", scope, symbol, math.Abs(after), limits.MaxSymbolExposure)}
+	}
+
+	if limits.MaxSectorExposure > 0 {
+		if sector, exists := r.sectors[symbol]; exists {
+			sectorBefore := 0.0
+			for sym, notional := range snap.bySymbol {
+				if r.sectors[sym] == sector {
+					sectorBefore += notional
+				}
+			}
+			sectorAfter := sectorBefore + delta
+			if math.Abs(sectorAfter) > limits.MaxSectorExposure && math.Abs(sectorAfter) > math.Abs(sectorBefore) {
+				return &RiskRejection{Rule: RiskRuleSectorExposure,
+					Reason: fmt.Sprintf("%s %s sector exposure $%.2f > $%.2f", scope, sector, math.Abs(sectorAfter), limits.MaxSectorExposure)}
+			}
+		}
+	}
+
+	grossBefore, netBefore := 0.0, 0.0
+	for _, notional := range snap.bySymbol {
+		grossBefore += math.Abs(notional)
+		netBefore += notional
+	}
+	grossAfter := grossBefore - math.Abs(before) + math.Abs(after)
+	netAfter := netBefore + delta
+
+	if limits.MaxGrossExposure > 0 && grossAfter > limits.MaxGrossExposure && grossAfter > grossBefore {
+		return &RiskRejection{Rule: RiskRuleGrossExposure,
+			Reason: fmt.Sprintf("%s gross exposure $%.2f > $%.2f", scope, grossAfter, limits.MaxGrossExposure)}
+	}
+	if limits.MaxNetExposure > 0 && math.Abs(netAfter) > limits.MaxNetExposure && math.Abs(netAfter) > math.Abs(netBefore) {
+		return &RiskRejection{Rule: RiskRuleNetExposure,
+			Reason: fmt.Sprintf("%s net exposure $%.2f > $%.2f", scope, math.Abs(netAfter), limits.MaxNetExposure)}
+	}
+
+	return nil
+}
+
+// checkOpposing rejects a position-increasing intent that would trade against
+// another strategy's working order or add exposure opposite to another
+// strategy's position. Check skips it for intents that reduce our own position.
+func (r *PortfolioRiskManager) checkOpposing(intent OrderIntent, positions map[string][]BookPosition, openOrders []ManagedOrder) *RiskRejection {
+	symbol := intent.Request.Symbol
+	buying := intent.Request.Side == alpaca.Buy
+
+	for _, order := range openOrders {
+		if order.StrategyID != intent.StrategyID && order.Symbol == symbol &&
+			order.ParentID == "" && order.Side != intent.Request.Side {
+			return &RiskRejection{Rule: RiskRuleOpposingIntent,
+				Reason: fmt.Sprintf("%s has a working %s order in %s", order.StrategyID, order.Side, symbol)}
+		}
+	}
+
+	for id, book := range positions {
+		if id == intent.StrategyID {
+			continue
+		}
+		for _, pos := range book {
+			if pos.Symbol != symbol || pos.Qty.IsZero() {
+				continue
+			}
+			if (buying && pos.Qty.IsNegative()) || (!buying && pos.Qty.IsPositive()) {
+				return &RiskRejection{Rule: RiskRuleOpposingIntent,
+					Reason: fmt.Sprintf("%s holds %s %s", id, pos.Qty.String(), symbol)}
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordRejection counts and logs a rejection
+func (r *PortfolioRiskManager) recordRejection(rejection *RiskRejection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byRule, exists := r.rejections[rejection.StrategyID]
+	if !exists {
+		byRule = make(map[string]int)
+		r.rejections[rejection.StrategyID] = byRule
+	}
+	byRule[rejection.Rule]++
+
+	r.logger.Printf("[%s] %s rejected: %s", rejection.StrategyID, rejection.Symbol, rejection.Reason)
+}
+
+// recordCross counts an internal cross
+func (r *PortfolioRiskManager) recordCross() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.crossed++
+}
+
+// Rejections returns the number of rejected intents for a strategy ("" for all)
+func (r *PortfolioRiskManager) Rejections(strategyID string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	total := 0
+	for id, byRule := range r.rejections {
+		if strategyID != "" && id != strategyID {
+			continue
+		}
+		for _, count := range byRule {
+			total += count
+		}
+	}
+	return total
+}
+
+// GetStatistics returns rejection counts by strategy and rule
+func (r *PortfolioRiskManager) GetStatistics() map[string]interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	byStrategy := make(map[string]map[string]int, len(r.rejections))
+	byRule := make(map[string]int)
+	total := 0
+	for id, rules := range r.rejections {
+		byStrategy[id] = make(map[string]int, len(rules))
+		for rule, count := range rules {
+			byStrategy[id][rule] = count
+			byRule[rule] += count
+			total += count
+		}
+	}
+
+	strategyIDs := make([]string, 0, len(r.strategies))
+	for id := range r.strategies {
+		strategyIDs = append(strategyIDs, id)
+	}
+	sort.Strings(strategyIDs)
+
+	return map[string]interface{}{
+		"strategy":               "Portfolio Risk",
+		"rejections":             total,
+		"rejections_by_rule":     byRule,
+		"rejections_by_strategy": byStrategy,
+		"internal_crosses":       r.crossed,
+		"configured_strategies":  strategyIDs,
+		"global_limits":          r.global,
+	}
+}
@@ -0,0 +1,238 @@
+package strategies
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// WeightingScheme decides how a rotation splits capital among its holdings
+type WeightingScheme string
+
+const (
+	WeightEqual        WeightingScheme = "equal"         // 1/N
+	WeightInverseVol   WeightingScheme = "inverse_vol"   // Proportional to 1/volatility
+	WeightRiskParity   WeightingScheme = "risk_parity"   // Equal contribution to portfolio variance
+	WeightMeanVariance WeightingScheme = "mean_variance" // Long-only Markowitz utility with a risk aversion
+)
+
+// valid reports whether the scheme is known
+func (w WeightingScheme) valid() bool {
+	switch w {
+	case WeightEqual, WeightInverseVol, WeightRiskParity, WeightMeanVariance:
+		return true
+	}
+	return false
+}
+
+// portfolioWeights returns long-only weights summing to 1 for assets with the
+// given daily returns, one date-aligned series per asset. Means and
+// covariances are annualized, which only matters for mean-variance.
+func portfolioWeights(scheme WeightingScheme, returns [][]float64, riskAversion float64) ([]float64, error) {
+	n := len(returns)
+	if scheme == WeightEqual || n == 1 {
+		return equalWeights(n), nil
+	}
+	rows := len(returns[0])
+	if rows < n+2 {
+		return nil, fmt.Errorf("%s weighting needs at least %d aligned returns, have %d", scheme, n+2, rows)
+	}
+
+	data := mat.NewDense(rows, n, nil)
+	for i, series := range returns {
+		data.SetCol(i, series)
+	}
+	var cov mat.SymDense
+	stat.CovarianceMatrix(&cov, data, nil)
+	cov.ScaleSym(252, &cov)
+	for i := 0; i < n; i++ {
+		if cov.At(i, i) <= 0 {
+			return nil, fmt.Errorf("asset %d has no return variance", i)
+		}
+	}
+
+	switch scheme {
+	case WeightInverseVol:
+		weights := make([]float64, n)
+		for i := range weights {
+			weights[i] = 1 / math.Sqrt(cov.At(i, i))
+		}
+		return normalizeWeights(weights), nil
+	case WeightRiskParity:
+		return riskParityWeights(&cov), nil
+	case WeightMeanVariance:
+		means := make([]float64, n)
+		for i := range means {
+			means[i] = 252 * stat.Mean(returns[i], nil)
+		}
+		return meanVarianceWeights(means, &cov, riskAversion)
+	}
+	return nil, fmt.Errorf("unknown weighting scheme %q", scheme)
+}
+
+// riskParityWeights finds weights whose contributions w_i·(Σw)_i to portfolio
+// variance are equal, by cyclical coordinate descent on
+// ½·xᵀΣx - Σ ln(x_i)/n, whose minimizer is proportional to the answer
+func riskParityWeights(cov *mat.SymDense) []float64 {
+	n, _ := cov.Dims()
+	budget := 1 / float64(n)
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = 1 / math.Sqrt(cov.At(i, i))
+	}
+
+	for iter := 0; iter < 1000; iter++ {
+		maxChange := 0.0
+		for i := range x {
+			c := 0.0
+			for j := range x {
+				if j != i {
+					c += cov.At(i, j) * x[j]
+				}
+			}
+			sii := cov.At(i, i)
+			next := (-c + math.Sqrt(c*c+4*sii*budget)) / (2 * sii)
+			maxChange = math.Max(maxChange, math.Abs(next-x[i])/next)
+			x[i] = next
+		}
+		if maxChange < 1e-10 {
+			break
+		}
+	}
+	return normalizeWeights(x)
+}
+
+// meanVarianceWeights maximizes wᵀμ - (λ/2)·wᵀΣw with weights summing to 1
+// and none negative. The budget-constrained optimum is solved in closed form;
+// while any weight comes out negative the most negative asset is dropped and
+// the rest re-solved.
+func meanVarianceWeights(means []float64, cov *mat.SymDense, riskAversion float64) ([]float64, error) {
+	if riskAversion <= 0 {
+		return nil, fmt.Errorf("risk aversion must be positive")
+	}
+	n := len(means)
+	active := make([]int, n)
+	for i := range active {
+		active[i] = i
+	}
+
+	for len(active) > 0 {
+		k := len(active)
+		sub := mat.NewSymDense(k, nil)
+		mu := mat.NewVecDense(k, nil)
+		ones := mat.NewVecDense(k, nil)
+		for a, i := range active {
+			mu.SetVec(a, means[i])
+			ones.SetVec(a, 1)
+			for b, j := range active[a:] {
+				sub.SetSym(a, a+b, cov.At(i, j))
+			}
+		}
+		var chol mat.Cholesky
+		if ok := chol.Factorize(sub); !ok {
+			return nil, fmt.Errorf("covariance matrix is not positive definite")
+		}
+		var invMu, invOnes mat.VecDense
+		if err := chol.SolveVecTo(&invMu, mu); err != nil {
+			return nil, err
+		}
+		if err := chol.SolveVecTo(&invOnes, ones); err != nil {
+			return nil, err
+		}
+
+		// w = Σ⁻¹(μ - γ1)/λ with γ set so the weights sum to 1
+		gamma := (mat.Sum(&invMu) - riskAversion) / mat.Sum(&invOnes)
+		worst, worstWeight := -1, 0.0
+		weights := make([]float64, n)
+		for a, i := range active {
+			weights[i] = (invMu.AtVec(a) - gamma*invOnes.AtVec(a)) / riskAversion
+			if weights[i] < worstWeight {
+				worst, worstWeight = a, weights[i]
+			}
+		}
+		if worst < 0 {
+			return weights, nil
+		}
+		active = append(active[:worst], active[worst+1:]...)
+	}
+	return nil, fmt.Errorf("no long-only solution")
+}
+
+// applySectorCaps scales down sectors above maxExposure and hands the excess
+// to uncapped sectors in proportion to their weights. Sectors that hit the
+// cap take no more; excess nobody can absorb is left unallocated, so the
+// result may sum to less than the input.
+func applySectorCaps(weights map[string]float64, sectorOf func(string) string, maxExposure float64) map[string]float64 {
+	capped := make(map[string]float64, len(weights))
+	for symbol, w := range weights {
+		capped[symbol] = w
+	}
+	full := make(map[string]bool)
+
+	for {
+		exposure := sectorExposures(capped, sectorOf)
+		excess := 0.0
+		for sector, total := range exposure {
+			if total > maxExposure+1e-12 {
+				scale := maxExposure / total
+				for symbol, w := range capped {
+					if sectorOf(symbol) == sector {
+						capped[symbol] = w * scale
+					}
+				}
+				excess += total - maxExposure
+				full[sector] = true
+			}
+		}
+		if excess == 0 {
+			return capped
+		}
+
+		room := 0.0
+		for symbol, w := range capped {
+			if !full[sectorOf(symbol)] {
+				room += w
+			}
+		}
+		if room == 0 {
+			return capped
+		}
+		for symbol, w := range capped {
+			if !full[sectorOf(symbol)] {
+				capped[symbol] = w + excess*w/room
+			}
+		}
+	}
+}
+
+// sectorExposures sums weights by sector
+func sectorExposures(weights map[string]float64, sectorOf func(string) string) map[string]float64 {
+	exposure := make(map[string]float64)
+	for symbol, w := range weights {
+		exposure[sectorOf(symbol)] += w
+	}
+	return exposure
+}
+
+// equalWeights returns n weights of 1/n
+func equalWeights(n int) []float64 {
+	weights := make([]float64, n)
+	for i := range weights {
+		weights[i] = 1 / float64(n)
+	}
+	return weights
+}
+
+// normalizeWeights scales weights to sum to 1
+func normalizeWeights(weights []float64) []float64 {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+	return weights
+}
@@ -11,6 +11,9 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/indicators"
 )
 
 // RSIMeanReversionStrategy implements a mean reversion strategy using RSI
@@ -27,17 +30,19 @@ type RSIMeanReversionStrategy struct {
 	UseTrendFilter bool   // Optional: only trade in direction of trend
 
 	// Alpaca clients
-	tradingClient *alpaca.Client
+	tradingClient broker.Broker
 	dataClient    *marketdata.Client
 
+	// Order routing through the shared OMS
+	orderRouting
+
 	// State management
 	mu            sync.RWMutex
-	prices        []float64
-	gains         []float64
-	losses        []float64
+	prices        []float64 // Recent closes, enough to re-warm the indicators
+	rsi           *indicators.RSI
+	trend         *indicators.SMA
 	currentRSI    float64
-	avgGain       float64
-	avgLoss       float64
+	prevRSI       float64
 	lastSignal    string
 	hasPosition   bool
 	entryPrice    float64
@@ -52,6 +57,12 @@ type RSIMeanReversionStrategy struct {
 	consecutiveSignals int // Track consecutive oversold/overbought readings
 }
 
+// Price history kept for the trend filter and for rebuilding the RSI when its period changes
+const (
+	rsiTrendPeriod = 200
+	rsiHistoryLen  = 220
+)
+
 // NewRSIMeanReversionStrategy creates a new RSI mean reversion strategy
 func NewRSIMeanReversionStrategy(symbol string, rsiPeriod int) *RSIMeanReversionStrategy {
 	return &RSIMeanReversionStrategy{
@@ -63,21 +74,22 @@ func NewRSIMeanReversionStrategy(symbol string, rsiPeriod int) *RSIMeanReversion
 		StopLossPct:     0.02, // 2% stop loss
 		TakeProfitPct:   0.03, // 3% take profit (smaller for mean reversion)
 		UseTrendFilter:  true, // Only buy in uptrend, sell in downtrend
-		prices:          make([]float64, 0, rsiPeriod+2),
-		gains:           make([]float64, 0, rsiPeriod),
-		losses:          make([]float64, 0, rsiPeriod),
+		prices:          make([]float64, 0, rsiHistoryLen),
+		rsi:             indicators.NewRSI(rsiPeriod),
+		trend:           indicators.NewSMA(rsiTrendPeriod),
 		logger:          log.New(log.Writer(), "[RSI-MEAN-REV] ", log.LstdFlags),
+		orderRouting:    orderRouting{strategyID: "rsi_" + symbol},
 	}
 }
 
 // Initialize sets up the Alpaca clients and loads historical data
 func (s *RSIMeanReversionStrategy) Initialize(apiKey, apiSecret, baseURL string) error {
-	// Initialize Alpaca trading client
-	s.tradingClient = alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		BaseURL:   baseURL,
-	})
+	// Trade through the shared OMS's broker, or an Alpaca account when standalone
+	s.tradingClient = s.ensureOrderManager(apiKey, apiSecret, baseURL)
+	s.ensureMarketDataHub(apiKey, apiSecret, baseURL)
+	if err := s.ensureStateStore(); err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
+	}
 
 	// Initialize market data client
 	s.dataClient = marketdata.NewClient(marketdata.ClientOpts{
@@ -90,11 +102,15 @@ func (s *RSIMeanReversionStrategy) Initialize(apiKey, apiSecret, baseURL string)
 		return fmt.Errorf("failed to load historical data: %w", err)
 	}
 
-	// Check current position
-	if err := s.syncPosition(); err != nil {
-		return fmt.Errorf("failed to sync position: %w", err)
+	// Resume position, counters and trade context from before a restart
+	if err := s.restoreState(); err != nil {
+		return fmt.Errorf("failed to restore state: %w", err)
 	}
 
+	// Position and P&L follow this strategy's own fills; re-read its book after stream gaps
+	s.oms.Subscribe(s.strategyID, s.onFill)
+	s.oms.OnReconnect(s.strategyID, s.syncPosition)
+
 	s.logger.Printf("RSI strategy initialized for %s (Period: %d, Oversold: %.1f, Overbought: %.1f)",
 		s.Symbol, s.RSIPeriod, s.OversoldLevel, s.OverboughtLevel)
 	return nil
@@ -125,19 +141,9 @@ func (s *RSIMeanReversionStrategy) loadHistoricalData() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Store closing prices
+	// Warm up RSI and trend MA on closing prices
 	for _, bar := range bars {
-		s.prices = append(s.prices, bar.Close)
-	}
-
-	// Calculate initial RSI if we have enough data
-	if len(s.prices) > s.RSIPeriod {
-		s.calculateInitialRSI()
-		
-		// Calculate trend MA if using trend filter
-		if s.UseTrendFilter && len(s.prices) >= 200 {
-			s.trendMA = s.calculateMA(s.prices[len(s.prices)-200:])
-		}
+		s.addPrice(bar.Close)
 	}
 
 	s.logger.Printf("Loaded %d historical bars, initial RSI: %.2f, Trend MA: %.2f",
@@ -145,63 +151,94 @@ func (s *RSIMeanReversionStrategy) loadHistoricalData() error {
 	return nil
 }
 
-// calculateInitialRSI computes the first RSI value using all available data
-func (s *RSIMeanReversionStrategy) calculateInitialRSI() {
-	if len(s.prices) < s.RSIPeriod+1 {
+// syncPosition loads the strategy's position from its own OMS book
+func (s *RSIMeanReversionStrategy) syncPosition() {
+	qty, entryPrice := s.bookPosition(s.Symbol)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.positionQty = qty
+	s.entryPrice = entryPrice
+	s.hasPosition = qty != 0
+}
+
+// onFill updates position and P&L from an actual execution, including
+// partial fills and bracket stop/target exits
+func (s *RSIMeanReversionStrategy) onFill(fill FillEvent) {
+	if fill.Symbol != s.Symbol {
 		return
 	}
 
-	// Calculate price changes
-	s.gains = []float64{}
-	s.losses = []float64{}
-	
-	startIdx := len(s.prices) - s.RSIPeriod - 1
-	for i := startIdx + 1; i < len(s.prices); i++ {
-		change := s.prices[i] - s.prices[i-1]
-		if change > 0 {
-			s.gains = append(s.gains, change)
-			s.losses = append(s.losses, 0)
-		} else {
-			s.gains = append(s.gains, 0)
-			s.losses = append(s.losses, math.Abs(change))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.positionQty = fill.Position.Qty.IntPart()
+	s.entryPrice = fill.Position.AvgEntryPrice.InexactFloat64()
+	s.hasPosition = s.positionQty != 0
+
+	pnl := fill.RealizedPnL.InexactFloat64()
+	s.totalPnL += pnl
+	if fill.TripClosed {
+		s.tradeCount++
+		if fill.TripPnL.IsPositive() {
+			s.winCount++
 		}
 	}
 
-	// Calculate average gain and loss
-	s.avgGain = s.average(s.gains)
-	s.avgLoss = s.average(s.losses)
+	s.saveStateLocked()
+
+	s.logger.Printf("%s %s %s @ %s (position %d @ %.2f, realized %.2f)",
+		fill.Event, fill.Side, fill.Qty.String(), fill.Price.StringFixed(2),
+		s.positionQty, s.entryPrice, pnl)
+}
 
-	// Calculate RSI
-	if s.avgLoss == 0 {
-		s.currentRSI = 100
-	} else {
-		rs := s.avgGain / s.avgLoss
-		s.currentRSI = 100 - (100 / (1 + rs))
+// rsiState is the live state persisted across restarts
+type rsiState struct {
+	positionState
+}
+
+// saveStateLocked snapshots live state to the store; caller must hold s.mu
+func (s *RSIMeanReversionStrategy) saveStateLocked() {
+	state := rsiState{
+		positionState: positionState{
+			PositionQty: s.positionQty,
+			EntryPrice:  s.entryPrice,
+			LastSignal:  s.lastSignal,
+			Trades:      s.tradeCount,
+			Wins:        s.winCount,
+			TotalPnL:    s.totalPnL,
+		},
+	}
+	if err := s.saveState(state); err != nil {
+		s.logger.Printf("Failed to save state: %v", err)
 	}
 }
 
-// syncPosition checks if we have an existing position
-func (s *RSIMeanReversionStrategy) syncPosition() error {
-	positions, err := s.tradingClient.GetPositions()
-	if err != nil {
+// restoreState reloads state saved before a restart, including the position,
+// which seeds this strategy's OMS book
+func (s *RSIMeanReversionStrategy) restoreState() error {
+	var saved rsiState
+	found, err := s.loadState(&saved)
+	if err != nil || !found {
 		return err
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.hasPosition = false
-	for _, pos := range positions {
-		if pos.Symbol == s.Symbol {
-			s.hasPosition = true
-			s.positionQty = pos.Qty.BigInt().Int64()
-			avgPrice, _ := pos.AvgEntryPrice.Float64()
-			s.entryPrice = avgPrice
-			s.logger.Printf("Found existing position: %d shares at %.2f", s.positionQty, s.entryPrice)
-			break
-		}
-	}
-
+	s.tradeCount = saved.Trades
+	s.winCount = saved.Wins
+	s.totalPnL = saved.TotalPnL
+	s.positionQty = saved.PositionQty
+	s.entryPrice = saved.EntryPrice
+	s.hasPosition = saved.PositionQty != 0
+	s.lastSignal = saved.LastSignal
+	s.restorePosition(s.Symbol, saved.PositionQty, saved.EntryPrice)
+
+	s.logger.Printf("Restored state: position %d at %.2f, %d trades, %d wins, P&L %.2f",
+		s.positionQty, s.entryPrice, s.tradeCount, s.winCount, s.totalPnL)
+	s.saveStateLocked()
 	return nil
 }
 
@@ -209,32 +246,16 @@ func (s *RSIMeanReversionStrategy) syncPosition() error {
 func (s *RSIMeanReversionStrategy) ProcessBar(price float64, timestamp time.Time) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.markData()
 
-	// Update price history
-	s.prices = append(s.prices, price)
+	// Update RSI and trend MA
+	s.addPrice(price)
 	
 	// Need at least RSI period + 1 prices
-	if len(s.prices) < s.RSIPeriod+1 {
+	if !s.rsi.Ready() {
 		return
 	}
 
-	// Keep only what we need (max 200 for trend filter)
-	maxKeep := s.RSIPeriod + 1
-	if s.UseTrendFilter {
-		maxKeep = 201
-	}
-	if len(s.prices) > maxKeep {
-		s.prices = s.prices[len(s.prices)-maxKeep:]
-	}
-
-	// Update RSI calculation
-	s.updateRSI(price)
-
-	// Update trend filter if enabled
-	if s.UseTrendFilter && len(s.prices) >= 200 {
-		s.trendMA = s.calculateMA(s.prices[len(s.prices)-200:])
-	}
-
 	// Generate signal
 	signal := s.generateSignal(price)
 	
@@ -247,35 +268,30 @@ func (s *RSIMeanReversionStrategy) ProcessBar(price float64, timestamp time.Time
 	}
 }
 
-// updateRSI calculates RSI using the exponential moving average method
-func (s *RSIMeanReversionStrategy) updateRSI(currentPrice float64) {
-	if len(s.prices) < 2 {
-		return
+// addPrice records a close and updates the RSI and trend MA
+func (s *RSIMeanReversionStrategy) addPrice(price float64) {
+	s.prices = append(s.prices, price)
+	if len(s.prices) > rsiHistoryLen {
+		s.prices = s.prices[len(s.prices)-rsiHistoryLen:]
 	}
 
-	// Calculate the latest price change
-	previousPrice := s.prices[len(s.prices)-2]
-	change := currentPrice - previousPrice
-	
-	gain := 0.0
-	loss := 0.0
-	if change > 0 {
-		gain = change
-	} else {
-		loss = math.Abs(change)
-	}
-
-	// Update exponential moving averages (Wilder's smoothing)
-	alpha := 1.0 / float64(s.RSIPeriod)
-	s.avgGain = (s.avgGain * (1 - alpha)) + (gain * alpha)
-	s.avgLoss = (s.avgLoss * (1 - alpha)) + (loss * alpha)
-
-	// Calculate RSI
-	if s.avgLoss == 0 {
-		s.currentRSI = 100
-	} else {
-		rs := s.avgGain / s.avgLoss
-		s.currentRSI = 100 - (100 / (1 + rs))
+	rsi := s.rsi.Update(price)
+	if s.rsi.Ready() {
+		s.prevRSI, s.currentRSI = s.currentRSI, rsi
+	}
+	if trend := s.trend.Update(price); s.trend.Ready() {
+		s.trendMA = trend
+	}
+}
+
+// rebuildRSI replays the price history through a fresh RSI after a period change
+func (s *RSIMeanReversionStrategy) rebuildRSI() {
+	s.rsi = indicators.NewRSI(s.RSIPeriod)
+	s.currentRSI, s.prevRSI = 0, 0
+	for _, price := range s.prices {
+		if rsi := s.rsi.Update(price); s.rsi.Ready() {
+			s.prevRSI, s.currentRSI = s.currentRSI, rsi
+		}
 	}
 }
 
@@ -293,7 +309,7 @@ func (s *RSIMeanReversionStrategy) generateSignal(currentPrice float64) string {
 		}
 		
 		// Additional confirmation: RSI starting to turn up
-		if len(s.gains) > 1 && s.gains[len(s.gains)-1] > s.gains[len(s.gains)-2] {
+		if s.prevRSI > 0 && s.currentRSI > s.prevRSI {
 			s.consecutiveSignals++
 			if s.consecutiveSignals >= 1 { // Can require multiple confirmations
 				s.consecutiveSignals = 0
@@ -326,34 +342,14 @@ func (s *RSIMeanReversionStrategy) generateSignal(currentPrice float64) string {
 	return ""
 }
 
-// calculateMA computes simple moving average
-func (s *RSIMeanReversionStrategy) calculateMA(prices []float64) float64 {
-	if len(prices) == 0 {
-		return 0
-	}
-	
-	sum := 0.0
-	for _, p := range prices {
-		sum += p
-	}
-	return sum / float64(len(prices))
-}
-
-// average calculates the average of a slice
-func (s *RSIMeanReversionStrategy) average(values []float64) float64 {
-	if len(values) == 0 {
-		return 0
-	}
-	
-	sum := 0.0
-	for _, v := range values {
-		sum += v
-	}
-	return sum / float64(len(values))
-}
-
 // executeTrade places orders based on signals
 func (s *RSIMeanReversionStrategy) executeTrade(signal string, currentPrice float64) {
+	// Don't stack signals on an order that hasn't filled yet
+	if s.hasWorkingOrder(s.Symbol) {
+		s.logger.Printf("Order already working for %s, skipping %s", s.Symbol, signal)
+		return
+	}
+
 	// Get account info for position sizing
 	account, err := s.tradingClient.GetAccount()
 	if err != nil {
@@ -426,40 +422,27 @@ func (s *RSIMeanReversionStrategy) executeTrade(signal string, currentPrice floa
 			TimeInForce: alpaca.Day,
 		}
 
-		// Calculate P&L for logging
+		// Estimate only: realized P&L is booked in onFill from the execution
 		pnl := (currentPrice - s.entryPrice) * float64(s.positionQty)
-		s.totalPnL += pnl
-		if pnl > 0 {
-			s.winCount++
-		}
-		s.tradeCount++
 
-		s.logger.Printf("Placing SELL order: %d shares at RSI %.2f, entry=%.2f, exit=%.2f, P&L=%.2f",
+		s.logger.Printf("Placing SELL order: %d shares at RSI %.2f, entry=%.2f, exit=%.2f, est. P&L=%.2f",
 			s.positionQty, s.currentRSI, s.entryPrice, currentPrice, pnl)
 	}
 
 	// Submit order
-	order, err := s.tradingClient.PlaceOrder(orderReq)
+	order, err := s.submitOrder(signal, orderReq, currentPrice)
 	if err != nil {
 		s.logger.Printf("Order failed: %v", err)
 		return
 	}
 
-	// Update state
+	// Position state is updated from fills (see onFill)
 	s.mu.Lock()
-	if signal == "BUY" {
-		s.hasPosition = true
-		s.entryPrice = currentPrice
-		s.positionQty = qty
-	} else if signal == "SELL" {
-		s.hasPosition = false
-		s.entryPrice = 0
-		s.positionQty = 0
-	}
 	s.lastSignal = signal
+	s.saveStateLocked()
 	s.mu.Unlock()
 
-	s.logger.Printf("Order placed successfully: %s", order.ID)
+	s.logger.Printf("Order placed successfully: %s", order.ClientOrderID)
 }
 
 // validateTradeCompliance checks PDT rules and other protections
@@ -489,41 +472,60 @@ func (s *RSIMeanReversionStrategy) validateTradeCompliance(account *alpaca.Accou
 	return true
 }
 
+// SetParameters updates configuration fields while the strategy runs
+func (s *RSIMeanReversionStrategy) SetParameters(params map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	period := s.RSIPeriod
+	err := setParameters(s, params, func() error {
+		if s.RSIPeriod < 2 {
+			return fmt.Errorf("rsi period must be at least 2")
+		}
+		if s.OversoldLevel <= 0 || s.OversoldLevel >= s.OverboughtLevel || s.OverboughtLevel >= 100 {
+			return fmt.Errorf("levels must satisfy 0 < oversold < overbought < 100")
+		}
+		if s.PositionSize <= 0 || s.PositionSize > 1 {
+			return fmt.Errorf("position size must be a fraction of equity in (0, 1]")
+		}
+		return nil
+	}, "Symbol")
+	if err == nil && s.RSIPeriod != period {
+		s.rebuildRSI()
+	}
+	return err
+}
+
 // GetStatistics returns strategy performance metrics
-func (s *RSIMeanReversionStrategy) GetStatistics() map[string]interface{} {
+func (s *RSIMeanReversionStrategy) GetStatistics() StrategyStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	winRate := 0.0
-	avgTrade := 0.0
-	if s.tradeCount > 0 {
-		winRate = float64(s.winCount) / float64(s.tradeCount) * 100
-		avgTrade = s.totalPnL / float64(s.tradeCount)
-	}
-
-	return map[string]interface{}{
-		"symbol":         s.Symbol,
-		"strategy":       "RSI Mean Reversion",
-		"rsi_period":     s.RSIPeriod,
-		"current_rsi":    s.currentRSI,
-		"oversold_level": s.OversoldLevel,
-		"overbought_level": s.OverboughtLevel,
-		"trades":         s.tradeCount,
-		"wins":           s.winCount,
-		"win_rate":       winRate,
-		"total_pnl":      s.totalPnL,
-		"avg_trade_pnl":  avgTrade,
-		"has_position":   s.hasPosition,
-		"trend_filter":   s.UseTrendFilter,
-		"trend_ma":       s.trendMA,
-	}
+	stats := s.newStats("RSI Mean Reversion", []string{s.Symbol}, s.tradeCount, s.winCount, s.totalPnL, lastMark(s.Symbol, s.prices))
+	stats.warmingUp(!s.rsi.Ready())
+	stats.Extra["rsi_period"] = s.RSIPeriod
+	stats.Extra["current_rsi"] = s.currentRSI
+	stats.Extra["oversold_level"] = s.OversoldLevel
+	stats.Extra["overbought_level"] = s.OverboughtLevel
+	stats.Extra["has_position"] = s.hasPosition
+	stats.Extra["trend_filter"] = s.UseTrendFilter
+	stats.Extra["trend_ma"] = s.trendMA
+	return stats
 }
 
 // Run starts the strategy main loop with WebSocket streaming
 func (s *RSIMeanReversionStrategy) Run(ctx context.Context) error {
-	// Use polling approach for now (WebSocket streaming to follow)
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
+	// Start order tracking (no-op if the runner already started the shared OMS)
+	if err := s.oms.Start(ctx); err != nil {
+		s.logger.Printf("Order updates unavailable: %v", err)
+	}
+
+	// Bars arrive from the shared market data hub
+	bars, err := s.subscribeBars(ctx, s.Symbol)
+	if err != nil {
+		return err
+	}
+	defer bars.Close()
 
 	s.logger.Printf("RSI Mean Reversion strategy running for %s", s.Symbol)
 
@@ -538,6 +540,12 @@ func (s *RSIMeanReversionStrategy) Run(ctx context.Context) error {
 			stats := s.GetStatistics()
 			s.logger.Printf("Final Statistics: %+v", stats)
 			return nil
+
+		case event := <-bars.Events():
+			if event.Cached {
+				continue // Last-value replay of a bar already in history or processed
+			}
+			s.ProcessBar(event.Bar.Close, event.Bar.Timestamp)
 			
 		case <-statsTicker.C:
 			// Print periodic statistics
@@ -0,0 +1,70 @@
+package strategies
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadSectorMap reads a symbol -> sector classification from a local file,
+// as used by MomentumRotationStrategy sector caps and
+// PortfolioRiskManager.SetSectors. A .json file holds an object of symbol to
+// sector or an array of {"symbol", "sector"} records; anything else is CSV
+// with symbol and sector in the first two columns and an optional header row.
+func LoadSectorMap(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sectors := make(map[string]string)
+	add := func(symbol, sector string) {
+		symbol, sector = strings.ToUpper(strings.TrimSpace(symbol)), strings.TrimSpace(sector)
+		if symbol != "" && sector != "" {
+			sectors[symbol] = sector
+		}
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var object map[string]string
+		if err := json.Unmarshal(data, &object); err == nil {
+			for symbol, sector := range object {
+				add(symbol, sector)
+			}
+			return sectors, nil
+		}
+		var records []struct {
+			Symbol string `json:"symbol"`
+			Sector string `json:"sector"`
+		}
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, fmt.Errorf("failed to parse sector map %s: %w", path, err)
+		}
+		for _, record := range records {
+			add(record.Symbol, record.Sector)
+		}
+		return sectors, nil
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sector map %s: %w", path, err)
+	}
+	for i, row := range rows {
+		if len(row) < 2 {
+			continue
+		}
+		if i == 0 && strings.EqualFold(strings.TrimSpace(row[0]), "symbol") {
+			continue
+		}
+		add(row[0], row[1])
+	}
+	return sectors, nil
+}
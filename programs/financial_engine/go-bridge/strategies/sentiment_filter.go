@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"log"
 	"math"
-	"net/http"
+	"os"
 	"sync"
 	"time"
 )
@@ -20,6 +20,9 @@ type SentimentFilter struct {
 	// State
 	mu              sync.RWMutex
 	sentimentCache  map[string]*SentimentData
+	fetchedAt       map[string]time.Time // When each cache entry was fetched, by the filter's clock
+	provider        SentimentProvider // nil leaves every symbol neutral with no confidence
+	clock           func() time.Time  // Simulated time in backtests; nil is the wall clock
 	logger          *log.Logger
 	
 	// Metrics
@@ -30,13 +33,13 @@ type SentimentFilter struct {
 
 // SentimentData holds sentiment analysis results
 type SentimentData struct {
-	Symbol      string
-	Score       float64   // -1.0 (bearish) to 1.0 (bullish)
-	Confidence  float64   // 0-1 confidence in the score
-	Volume      int       // Number of mentions/posts analyzed
-	Keywords    []string  // Key terms found
-	Timestamp   time.Time
-	Source      string    // "twitter", "news", "reddit", etc.
+	Symbol      string    `json:"symbol"`
+	Score       float64   `json:"score"`      // -1.0 (bearish) to 1.0 (bullish)
+	Confidence  float64   `json:"confidence"` // 0-1 confidence in the score; 0 means no data
+	Volume      int       `json:"volume"`     // Number of mentions/posts analyzed
+	Keywords    []string  `json:"keywords,omitempty"` // Key terms found
+	Timestamp   time.Time `json:"timestamp"`
+	Source      string    `json:"source"`     // "news", "file", "composite", etc.
 }
 
 // SentimentProvider interface for different sentiment sources
@@ -45,25 +48,122 @@ type SentimentProvider interface {
 	GetBulkSentiment(symbols []string) (map[string]*SentimentData, error)
 }
 
-// NewSentimentFilter creates a new sentiment filter
+// NewSentimentFilter creates a new sentiment filter reading the sources
+// configured in the environment (see SentimentProviderFromEnv)
 func NewSentimentFilter() *SentimentFilter {
-	return &SentimentFilter{
+	f := &SentimentFilter{
 		MinPositiveSentiment: 0.3,  // Moderate positive sentiment required
 		MaxNegativeSentiment: -0.3, // Moderate negative sentiment threshold
 		CacheExpiry:          15 * time.Minute,
 		sentimentCache:       make(map[string]*SentimentData),
+		fetchedAt:            make(map[string]time.Time),
 		logger:               log.New(log.Writer(), "[SENTIMENT] ", log.LstdFlags),
 	}
+	
+	provider, err := SentimentProviderFromEnv()
+	if err != nil {
+		f.logger.Printf("Sentiment sources unavailable, signals will not be filtered: %v", err)
+	} else if provider != nil {
+		f.provider = provider
+	}
+	return f
+}
+
+// SentimentProviderFromEnv blends the local sentiment sources named in the
+// environment: SENTIMENT_NEWS_FILE (news articles as JSON or NDJSON, scored
+// with the lexicon in SENTIMENT_LEXICON or the built-in one) and
+// SENTIMENT_SCORES_FILE (pre-scored NDJSON). It returns nil when neither is
+// set.
+func SentimentProviderFromEnv() (SentimentProvider, error) {
+	var sources []SentimentSource
+	
+	if path := os.Getenv("SENTIMENT_NEWS_FILE"); path != "" {
+		lexicon := DefaultSentimentLexicon()
+		if lexiconPath := os.Getenv("SENTIMENT_LEXICON"); lexiconPath != "" {
+			loaded, err := LoadSentimentLexicon(lexiconPath)
+			if err != nil {
+				return nil, err
+			}
+			lexicon = loaded
+		}
+		articles, err := LoadNewsArticles(path)
+		if err != nil {
+			return nil, err
+		}
+		news := NewNewsSentimentProvider(lexicon)
+		news.AddArticles(articles...)
+		sources = append(sources, SentimentSource{Name: "news", Provider: news, Weight: newsSourceWeight})
+	}
+	
+	if path := os.Getenv("SENTIMENT_SCORES_FILE"); path != "" {
+		scores, err := NewFileSentimentProvider(path)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, SentimentSource{Name: "file", Provider: scores, Weight: scoresSourceWeight, HalfLife: scoresHalfLife})
+	}
+	
+	if len(sources) == 0 {
+		return nil, nil
+	}
+	return NewAggregateSentimentProvider(sources...), nil
+}
+
+// NewBacktestSentimentFilter creates a filter that reads history as of the
+// time clock reports, for replaying a strategy over past bars
+func NewBacktestSentimentFilter(history HistoricalSentimentProvider, clock func() time.Time) *SentimentFilter {
+	return &SentimentFilter{
+		MinPositiveSentiment: 0.3,
+		MaxNegativeSentiment: -0.3,
+		CacheExpiry:          15 * time.Minute,
+		sentimentCache:       make(map[string]*SentimentData),
+		fetchedAt:            make(map[string]time.Time),
+		provider:             history,
+		clock:                clock,
+		logger:               log.New(log.Writer(), "[SENTIMENT] ", log.LstdFlags),
+	}
+}
+
+// SetProvider replaces the sentiment source and clears the cache
+func (f *SentimentFilter) SetProvider(provider SentimentProvider) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.provider = provider
+	f.sentimentCache = make(map[string]*SentimentData)
+	f.fetchedAt = make(map[string]time.Time)
+}
+
+// SetClock makes the filter read sentiment as of clock's time rather than
+// now. The provider must then be a HistoricalSentimentProvider; a nil clock
+// restores the wall clock. The cache is cleared.
+func (f *SentimentFilter) SetClock(clock func() time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clock = clock
+	f.sentimentCache = make(map[string]*SentimentData)
+	f.fetchedAt = make(map[string]time.Time)
+}
+
+// now is the filter's current time
+func (f *SentimentFilter) now() time.Time {
+	if f.clock != nil {
+		return f.clock()
+	}
+	return time.Now()
 }
 
 // GetSentiment retrieves sentiment score for a symbol
 func (f *SentimentFilter) GetSentiment(symbol string) *SentimentData {
+	now := f.now()
+	
 	f.mu.RLock()
 	cached, exists := f.sentimentCache[symbol]
+	fetched := f.fetchedAt[symbol]
 	f.mu.RUnlock()
 	
-	// Check cache
-	if exists && time.Since(cached.Timestamp) < f.CacheExpiry {
+	// Check cache; a simulated clock that moved backwards (a new backtest
+	// run) never reuses a later reading
+	if exists && !now.Before(fetched) && now.Sub(fetched) < f.CacheExpiry {
 		f.sentimentHits++
 		return cached
 	}
@@ -71,52 +171,45 @@ func (f *SentimentFilter) GetSentiment(symbol string) *SentimentData {
 	f.sentimentMisses++
 	
 	// Fetch new sentiment data
-	sentiment := f.fetchSentiment(symbol)
+	sentiment := f.fetchSentiment(symbol, now)
 	
 	// Update cache
 	f.mu.Lock()
 	f.sentimentCache[symbol] = sentiment
+	f.fetchedAt[symbol] = now
 	f.mu.Unlock()
 	
 	return sentiment
 }
 
-// fetchSentiment fetches real-time sentiment data
-// In production, this would call external APIs or services
-func (f *SentimentFilter) fetchSentiment(symbol string) *SentimentData {
-	// For demo purposes, we'll simulate sentiment analysis
-	// In production, integrate with:
-	// - Twitter/X API for social sentiment
-	// - News API for news sentiment
-	// - Reddit API for WSB sentiment
-	// - StockTwits API
-	
-	sentiment := &SentimentData{
-		Symbol:    symbol,
-		Timestamp: time.Now(),
-		Source:    "composite",
-	}
-	
-	// Simulate different data sources
-	twitterScore := f.getTwitterSentiment(symbol)
-	newsScore := f.getNewsSentiment(symbol)
-	redditScore := f.getRedditSentiment(symbol)
+// fetchSentiment asks the provider for a fresh reading as of now. Without a
+// provider, or when it fails, the symbol reads neutral with zero confidence.
+func (f *SentimentFilter) fetchSentiment(symbol string, now time.Time) *SentimentData {
+	f.mu.RLock()
+	provider, simulated := f.provider, f.clock != nil
+	f.mu.RUnlock()
 	
-	// Weighted average (Twitter has more real-time signal)
-	sentiment.Score = (twitterScore*0.5 + newsScore*0.3 + redditScore*0.2)
+	neutral := &SentimentData{Symbol: symbol, Timestamp: now, Source: "none"}
+	if provider == nil {
+		return neutral
+	}
 	
-	// Confidence based on volume of data
-	sentiment.Volume = f.getMentionVolume(symbol)
-	if sentiment.Volume > 1000 {
-		sentiment.Confidence = 0.9
-	} else if sentiment.Volume > 100 {
-		sentiment.Confidence = 0.7
+	var sentiment *SentimentData
+	var err error
+	if simulated {
+		history, ok := provider.(HistoricalSentimentProvider)
+		if !ok {
+			f.logger.Printf("%s sentiment has no history; reading neutral at %s", symbol, now.Format(time.RFC3339))
+			return neutral
+		}
+		sentiment, err = history.SentimentAt(symbol, now)
 	} else {
-		sentiment.Confidence = 0.5
+		sentiment, err = provider.GetSentiment(symbol)
+	}
+	if err != nil || sentiment == nil {
+		f.logger.Printf("%s sentiment unavailable: %v", symbol, err)
+		return neutral
 	}
-	
-	// Extract keywords
-	sentiment.Keywords = f.extractKeywords(symbol)
 	
 	f.logger.Printf("%s sentiment: Score=%.2f, Confidence=%.2f, Volume=%d",
 		symbol, sentiment.Score, sentiment.Confidence, sentiment.Volume)
@@ -124,136 +217,6 @@ func (f *SentimentFilter) fetchSentiment(symbol string) *SentimentData {
 	return sentiment
 }
 
-// getTwitterSentiment simulates Twitter/X sentiment analysis
-func (f *SentimentFilter) getTwitterSentiment(symbol string) float64 {
-	// In production, use Twitter API or X semantic search
-	// Example implementation with X tool:
-	/*
-	query := fmt.Sprintf("$%s stock sentiment", symbol)
-	results := xSemanticSearch(query, 100)
-	
-	positive := 0
-	negative := 0
-	for _, tweet := range results {
-		if containsPositiveWords(tweet) {
-			positive++
-		} else if containsNegativeWords(tweet) {
-			negative++
-		}
-	}
-	
-	if positive + negative == 0 {
-		return 0
-	}
-	return float64(positive - negative) / float64(positive + negative)
-	*/
-	
-	// Simulation based on common patterns
-	bullishKeywords := []string{"moon", "rocket", "bullish", "buy", "long", "calls", "breakthrough", "beat"}
-	bearishKeywords := []string{"crash", "sell", "puts", "bearish", "short", "dump", "overvalued", "bubble"}
-	
-	// Simulate scoring
-	score := 0.0
-	
-	// Production would analyze these keywords in social media posts
-	// For simulation, we use length as a proxy for sentiment analysis
-	score += float64(len(bullishKeywords)-len(bearishKeywords)) * 0.001
-	
-	// Some symbols tend to have more positive sentiment
-	techStocks := []string{"AAPL", "GOOGL", "MSFT", "NVDA", "TSLA"}
-	for _, tech := range techStocks {
-		if symbol == tech {
-			score += 0.2
-			break
-		}
-	}
-	
-	// Add some randomness for realism (would be real data in production)
-	hash := 0
-	for _, c := range symbol {
-		hash += int(c)
-	}
-	noise := float64((hash%20)-10) / 100.0
-	score += noise
-	
-	// Clamp to [-1, 1]
-	if score > 1 {
-		score = 1
-	} else if score < -1 {
-		score = -1
-	}
-	
-	return score
-}
-
-// getNewsSentiment simulates news sentiment analysis
-func (f *SentimentFilter) getNewsSentiment(symbol string) float64 {
-	// In production, use news APIs like:
-	// - Alpha Vantage News Sentiment
-	// - NewsAPI
-	// - Benzinga
-	
-	// Simulate based on market conditions
-	// Would actually parse headlines and articles
-	
-	return 0.1 // Slightly positive baseline
-}
-
-// getRedditSentiment simulates Reddit/WSB sentiment
-func (f *SentimentFilter) getRedditSentiment(symbol string) float64 {
-	// In production, use Reddit API to analyze:
-	// - r/wallstreetbets
-	// - r/stocks
-	// - r/investing
-	
-	// WSB tends to be bullish on meme stocks
-	memeStocks := []string{"GME", "AMC", "BB", "BBBY"}
-	for _, meme := range memeStocks {
-		if symbol == meme {
-			return 0.7 // Very bullish
-		}
-	}
-	
-	return 0.0 // Neutral default
-}
-
-// getMentionVolume simulates mention volume tracking
-func (f *SentimentFilter) getMentionVolume(symbol string) int {
-	// In production, count actual mentions across platforms
-	
-	// Popular stocks have more mentions
-	popularStocks := map[string]int{
-		"AAPL": 5000,
-		"TSLA": 8000,
-		"SPY":  3000,
-		"GME":  10000,
-		"NVDA": 4000,
-	}
-	
-	if volume, exists := popularStocks[symbol]; exists {
-		return volume
-	}
-	
-	return 100 // Default volume
-}
-
-// extractKeywords extracts relevant keywords from sentiment data
-func (f *SentimentFilter) extractKeywords(symbol string) []string {
-	// In production, use NLP to extract actual keywords
-	
-	keywords := []string{}
-	
-	// Add some common keywords based on sentiment
-	sentiment := f.sentimentCache[symbol]
-	if sentiment != nil && sentiment.Score > 0.5 {
-		keywords = append(keywords, "bullish", "upgrade", "beat", "strong")
-	} else if sentiment != nil && sentiment.Score < -0.5 {
-		keywords = append(keywords, "bearish", "downgrade", "miss", "weak")
-	}
-	
-	return keywords
-}
-
 // FilterBuySignal filters buy signals based on sentiment
 func (f *SentimentFilter) FilterBuySignal(symbol string, originalSignal bool) bool {
 	if !originalSignal {
@@ -262,6 +225,11 @@ func (f *SentimentFilter) FilterBuySignal(symbol string, originalSignal bool) bo
 	
 	sentiment := f.GetSentiment(symbol)
 	
+	// No data is no opinion
+	if sentiment.Confidence == 0 {
+		return true
+	}
+	
 	// Require positive sentiment for buys
 	if sentiment.Score < f.MinPositiveSentiment {
 		f.logger.Printf("Buy signal filtered for %s: sentiment %.2f < %.2f",
@@ -309,21 +277,29 @@ func (f *SentimentFilter) GetMarketSentiment() *SentimentData {
 	// Analyze broad market sentiment using indices
 	indices := []string{"SPY", "QQQ", "DIA", "IWM"}
 	
-	totalScore := 0.0
+	weightedScore := 0.0
+	totalConfidence := 0.0
 	totalVolume := 0
 	
 	for _, index := range indices {
 		sentiment := f.GetSentiment(index)
-		totalScore += sentiment.Score
+		weightedScore += sentiment.Score * sentiment.Confidence
+		totalConfidence += sentiment.Confidence
 		totalVolume += sentiment.Volume
 	}
 	
+	score := 0.0
+	if totalConfidence > 0 {
+		score = weightedScore / totalConfidence
+	}
+	
 	return &SentimentData{
-		Symbol:    "MARKET",
-		Score:     totalScore / float64(len(indices)),
-		Volume:    totalVolume,
-		Timestamp: time.Now(),
-		Source:    "composite",
+		Symbol:     "MARKET",
+		Score:      score,
+		Confidence: totalConfidence / float64(len(indices)),
+		Volume:     totalVolume,
+		Timestamp:  f.now(),
+		Source:     "composite",
 	}
 }
 
@@ -358,7 +334,7 @@ func (f *SentimentFilter) DetectSentimentShift(symbol string) *SentimentAlert {
 			OldScore:  previous.Score,
 			NewScore:  current.Score,
 			Change:    change,
-			Timestamp: time.Now(),
+			Timestamp: f.now(),
 		}
 		
 		if change > 0 {
@@ -449,50 +425,3 @@ func ExampleSentimentIntegration() {
 	
 	fmt.Println("Sentiment filter initialized and ready for integration")
 }
-
-// TwitterAPIClient implements real Twitter/X integration (example)
-type TwitterAPIClient struct {
-	APIKey    string
-	APISecret string
-	BaseURL   string
-}
-
-// GetSentiment implements SentimentProvider for Twitter
-func (t *TwitterAPIClient) GetSentiment(symbol string) (*SentimentData, error) {
-	// This would make actual API calls to Twitter/X
-	// Using their sentiment analysis endpoints or processing raw tweets
-	
-	query := fmt.Sprintf("$%s OR %s stock", symbol, symbol)
-	
-	// Make API request (simplified)
-	resp, err := http.Get(fmt.Sprintf("%s/search?q=%s", t.BaseURL, query))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	
-	// Parse response and analyze sentiment
-	// This would involve NLP processing of tweets
-	
-	return &SentimentData{
-		Symbol:    symbol,
-		Score:     0.0, // Calculate from tweet analysis
-		Timestamp: time.Now(),
-		Source:    "twitter",
-	}, nil
-}
-
-// GetBulkSentiment batch fetches sentiment for multiple symbols
-func (t *TwitterAPIClient) GetBulkSentiment(symbols []string) (map[string]*SentimentData, error) {
-	results := make(map[string]*SentimentData)
-	
-	for _, symbol := range symbols {
-		sentiment, err := t.GetSentiment(symbol)
-		if err != nil {
-			continue
-		}
-		results[symbol] = sentiment
-	}
-	
-	return results, nil
-}
\ No newline at end of file
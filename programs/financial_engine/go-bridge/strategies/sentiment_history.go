@@ -0,0 +1,107 @@
+package strategies
+
+import (
+	"time"
+
+	"zig-financial-engine/internal/market"
+)
+
+// HistoricalSentimentProvider answers what a provider would have reported at
+// a past moment, using only articles and records known by then
+type HistoricalSentimentProvider interface {
+	SentimentProvider
+	SentimentAt(symbol string, at time.Time) (*SentimentData, error)
+}
+
+// Source weights shared by the live blend and the history store
+const (
+	newsSourceWeight   = 0.6
+	scoresSourceWeight = 0.4
+	scoresHalfLife     = 6 * time.Hour
+)
+
+// SentimentHistory is an in-memory, time-indexed store of news articles and
+// pre-scored sentiment records for backtests. It blends the two the same
+// way the live filter does, so a strategy sees the sentiment it would have
+// seen at each bar and nothing published later.
+type SentimentHistory struct {
+	News   *NewsSentimentProvider
+	Scores *ScoreHistory
+	blend  *AggregateSentimentProvider
+}
+
+// ScoreHistory is the pre-scored half of a SentimentHistory
+type ScoreHistory struct {
+	MaxAge  time.Duration // Oldest record still used
+	records sentimentRecords
+}
+
+// NewSentimentHistory creates an empty store scoring news with lexicon (the
+// default lexicon when nil)
+func NewSentimentHistory(lexicon *SentimentLexicon) *SentimentHistory {
+	h := &SentimentHistory{
+		News:   NewNewsSentimentProvider(lexicon),
+		Scores: &ScoreHistory{MaxAge: 24 * time.Hour, records: make(sentimentRecords)},
+	}
+	h.blend = NewAggregateSentimentProvider(
+		SentimentSource{Name: "news", Provider: h.News, Weight: newsSourceWeight},
+		SentimentSource{Name: "scores", Provider: h.Scores, Weight: scoresSourceWeight, HalfLife: scoresHalfLife},
+	)
+	return h
+}
+
+// AddArticles ingests news articles, dated by publication time
+func (h *SentimentHistory) AddArticles(articles ...market.NewsArticle) {
+	h.News.AddArticles(articles...)
+}
+
+// AddRecords ingests pre-scored records, dated by their timestamps
+func (h *SentimentHistory) AddRecords(records ...SentimentData) error {
+	return h.Scores.records.add(records...)
+}
+
+// LoadNews ingests a JSON or NDJSON article file (see LoadNewsArticles)
+func (h *SentimentHistory) LoadNews(path string) error {
+	articles, err := LoadNewsArticles(path)
+	if err != nil {
+		return err
+	}
+	h.AddArticles(articles...)
+	return nil
+}
+
+// LoadScores ingests an NDJSON file of pre-scored records (see
+// FileSentimentProvider)
+func (h *SentimentHistory) LoadScores(path string) error {
+	return h.Scores.records.load(path)
+}
+
+// SentimentAt implements HistoricalSentimentProvider
+func (h *SentimentHistory) SentimentAt(symbol string, at time.Time) (*SentimentData, error) {
+	return h.blend.SentimentAt(symbol, at)
+}
+
+// GetSentiment implements SentimentProvider with the latest known sentiment
+func (h *SentimentHistory) GetSentiment(symbol string) (*SentimentData, error) {
+	return h.SentimentAt(symbol, time.Now())
+}
+
+// GetBulkSentiment implements SentimentProvider
+func (h *SentimentHistory) GetBulkSentiment(symbols []string) (map[string]*SentimentData, error) {
+	return bulkSentiment(h, symbols)
+}
+
+// SentimentAt implements HistoricalSentimentProvider
+func (s *ScoreHistory) SentimentAt(symbol string, at time.Time) (*SentimentData, error) {
+	return s.records.at(symbol, at, s.MaxAge, "scores"), nil
+}
+
+// GetSentiment implements SentimentProvider
+func (s *ScoreHistory) GetSentiment(symbol string) (*SentimentData, error) {
+	return s.SentimentAt(symbol, time.Now())
+}
+
+// GetBulkSentiment implements SentimentProvider
+func (s *ScoreHistory) GetBulkSentiment(symbols []string) (map[string]*SentimentData, error) {
+	return bulkSentiment(s, symbols)
+}
@@ -0,0 +1,227 @@
+package strategies
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SentimentLexicon scores text by counting finance-specific positive and
+// negative words, in the style of the Loughran-McDonald dictionaries: general
+// purpose word lists misread financial text ("liability", "tax" and "cost"
+// are not negative news), so only words that carry tone in filings and
+// market news are counted.
+type SentimentLexicon struct {
+	positive map[string]bool
+	negative map[string]bool
+	negators map[string]bool
+}
+
+// negationWindow is how many words back a negator reverses a tone word, as
+// in Loughran and McDonald (2011)
+const negationWindow = 3
+
+// LexiconScore is the tone of one text
+type LexiconScore struct {
+	Score    float64  // (positive - negative) / (positive + negative), 0 without hits
+	Positive int      // Positive hits, including negated negative words
+	Negative int      // Negative hits, including negated positive words
+	Words    int      // Tokens in the text
+	Hits     []string // Tone words found, prefixed "not " when negated
+}
+
+// defaultPositiveWords are core Loughran-McDonald positive words plus common
+// market-news terms
+var defaultPositiveWords = []string{
+	"able", "accomplish", "accomplished", "achieve", "achieved", "achievement", "advance", "advanced",
+	"advantage", "advantageous", "attractive", "beat", "beats", "benefit", "benefited", "better",
+	"bolstered", "boom", "booming", "boost", "boosted", "breakthrough", "bullish", "collaboration",
+	"confident", "creative", "delight", "delighted", "dependable", "desirable", "diligent",
+	"distinction", "efficiencies", "efficiency", "efficient", "empower", "enable", "enhance",
+	"enhanced", "enjoy", "enthusiastic", "exceed", "exceeded", "exceeding", "exceeds", "excellence",
+	"excellent", "exceptional", "exciting", "favorable", "gain", "gained", "gains", "good", "great",
+	"greater", "highest", "honor", "ideal", "impressive", "improve", "improved", "improvement",
+	"improving", "innovative", "leadership", "lucrative", "momentum", "opportunities", "opportunity",
+	"optimistic", "outpace", "outperform", "outperformed", "outperforms", "perfect", "pleased",
+	"popularity", "positive", "premier", "profitability", "profitable", "progress", "prosper",
+	"rally", "rallied", "rebound", "rebounded", "record", "resolve", "resolved", "reward",
+	"robust", "smooth", "solid", "stability", "stable", "strength", "strengthen", "strengthened",
+	"strong", "stronger", "strongest", "succeed", "success", "successful", "superior", "surge",
+	"surged", "surpass", "surpassed", "upgrade", "upgraded", "upturn", "valuable", "win", "winner",
+}
+
+// defaultNegativeWords are core Loughran-McDonald negative words plus common
+// market-news terms
+var defaultNegativeWords = []string{
+	"abandon", "abandoned", "adverse", "adversely", "allegation", "allegations", "bad",
+	"bankrupt", "bankruptcy", "bearish", "breach", "burden", "caution", "cautious", "challenge",
+	"challenging", "closure", "collapse", "collapsed", "concern", "concerns", "crash", "crisis",
+	"critical", "cut", "cuts", "damage", "decline", "declined", "declines", "declining", "default",
+	"deficit", "delay", "delayed", "delays", "delinquent", "deteriorate", "deteriorated",
+	"deterioration", "difficult", "difficulty", "disappoint", "disappointed", "disappointing",
+	"dispute", "downgrade", "downgraded", "downturn", "drop", "dropped", "fail", "failed", "failure",
+	"fall", "fell", "fraud", "halt", "halted", "impairment", "inability", "investigation",
+	"lawsuit", "layoff", "layoffs", "litigation", "lose", "loss", "losses", "lost", "misconduct",
+	"miss", "missed", "misses", "negative", "outage", "penalty", "plunge", "plunged", "poor",
+	"problem", "problems", "recall", "recession", "resign", "resigned", "restate", "restated",
+	"restatement", "risk", "risks", "sank", "selloff", "shortfall", "slowdown", "slump", "slumped",
+	"subpoena", "suspend", "suspended", "tumble", "tumbled", "turmoil", "uncertain", "uncertainty",
+	"underperform", "underperformed", "unfavorable", "volatile", "warn", "warned", "warning",
+	"weak", "weaken", "weakened", "weaker", "weakness", "worse", "worst", "writedown",
+}
+
+// defaultNegators reverse the tone of the words after them
+var defaultNegators = []string{"no", "not", "none", "neither", "never", "nobody", "nor", "without", "cannot"}
+
+// DefaultSentimentLexicon returns the built-in word lists
+func DefaultSentimentLexicon() *SentimentLexicon {
+	return NewSentimentLexicon(defaultPositiveWords, defaultNegativeWords)
+}
+
+// NewSentimentLexicon builds a lexicon from word lists, matched case-insensitively
+func NewSentimentLexicon(positive, negative []string) *SentimentLexicon {
+	l := &SentimentLexicon{
+		positive: make(map[string]bool, len(positive)),
+		negative: make(map[string]bool, len(negative)),
+		negators: make(map[string]bool, len(defaultNegators)),
+	}
+	for _, w := range positive {
+		l.positive[strings.ToLower(w)] = true
+	}
+	for _, w := range negative {
+		l.negative[strings.ToLower(w)] = true
+	}
+	for _, w := range defaultNegators {
+		l.negators[w] = true
+	}
+	return l
+}
+
+// LoadSentimentLexicon reads a dictionary CSV in the layout of the
+// Loughran-McDonald master dictionary: a header naming Word, Positive and
+// Negative columns, where a non-zero Positive or Negative value puts the
+// word in that list
+func LoadSentimentLexicon(path string) (*SentimentLexicon, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lexicon header: %w", err)
+	}
+	columns := map[string]int{"word": -1, "positive": -1, "negative": -1}
+	for i, name := range header {
+		if _, ok := columns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+	}
+	for name, i := range columns {
+		if i < 0 {
+			return nil, fmt.Errorf("lexicon %s has no %s column", path, name)
+		}
+	}
+
+	var positive, negative []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lexicon %s: %w", path, err)
+		}
+		if len(record) <= columns["word"] || len(record) <= columns["positive"] || len(record) <= columns["negative"] {
+			continue
+		}
+		word := record[columns["word"]]
+		if v, _ := strconv.ParseFloat(strings.TrimSpace(record[columns["positive"]]), 64); v != 0 {
+			positive = append(positive, word)
+		}
+		if v, _ := strconv.ParseFloat(strings.TrimSpace(record[columns["negative"]]), 64); v != 0 {
+			negative = append(negative, word)
+		}
+	}
+	if len(positive) == 0 && len(negative) == 0 {
+		return nil, fmt.Errorf("lexicon %s has no positive or negative words", path)
+	}
+	return NewSentimentLexicon(positive, negative), nil
+}
+
+// Score counts tone words in text. A tone word within three words after a
+// negator ("not", "no", "never", or any "-n't" contraction) counts toward
+// the opposite side.
+func (l *SentimentLexicon) Score(text string) LexiconScore {
+	tokens := tokenize(text)
+	result := LexiconScore{Words: len(tokens)}
+	lastNegator := -negationWindow - 1
+	for i, token := range tokens {
+		if l.negators[token] || strings.HasSuffix(token, "n't") {
+			lastNegator = i
+			continue
+		}
+		positive, negative := l.positive[token], l.negative[token]
+		if !positive && !negative {
+			continue
+		}
+		hit := token
+		if i-lastNegator <= negationWindow {
+			positive, negative = negative, positive
+			hit = "not " + token
+		}
+		if positive {
+			result.Positive++
+		} else {
+			result.Negative++
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	if total := result.Positive + result.Negative; total > 0 {
+		result.Score = float64(result.Positive-result.Negative) / float64(total)
+	}
+	return result
+}
+
+// tokenize lowercases text and splits it into words, keeping apostrophes so
+// contractions stay whole
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\'' && r != '’'
+	})
+	tokens := fields[:0]
+	for _, f := range fields {
+		f = strings.ReplaceAll(strings.Trim(f, "'’"), "’", "'")
+		f = strings.TrimSuffix(f, "'s")
+		if f != "" {
+			tokens = append(tokens, f)
+		}
+	}
+	return tokens
+}
+
+// topKeywords returns the most frequent hits, most common first
+func topKeywords(counts map[string]int, limit int) []string {
+	keywords := make([]string, 0, len(counts))
+	for word := range counts {
+		keywords = append(keywords, word)
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if counts[keywords[i]] != counts[keywords[j]] {
+			return counts[keywords[i]] > counts[keywords[j]]
+		}
+		return keywords[i] < keywords[j]
+	})
+	if len(keywords) > limit {
+		keywords = keywords[:limit]
+	}
+	return keywords
+}
@@ -0,0 +1,405 @@
+package strategies
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"zig-financial-engine/internal/market"
+)
+
+// NewsSentimentProvider scores news articles with a lexicon. Each article's
+// headline and summary are scored together, and articles about a symbol are
+// averaged with recency weights so a day-old story counts half as much as
+// one published now (with the default half-life).
+type NewsSentimentProvider struct {
+	Lookback time.Duration // Oldest article considered
+	HalfLife time.Duration // Age at which an article's weight halves
+
+	mu       sync.RWMutex
+	lexicon  *SentimentLexicon
+	articles map[string][]market.NewsArticle // By symbol, in publication order
+}
+
+// newsConfidenceArticles is the weighted article count at which confidence
+// reaches about 63%
+const newsConfidenceArticles = 3.0
+
+// NewNewsSentimentProvider scores articles with lexicon (the default
+// lexicon when nil)
+func NewNewsSentimentProvider(lexicon *SentimentLexicon) *NewsSentimentProvider {
+	if lexicon == nil {
+		lexicon = DefaultSentimentLexicon()
+	}
+	return &NewsSentimentProvider{
+		Lookback: 72 * time.Hour,
+		HalfLife: 24 * time.Hour,
+		lexicon:  lexicon,
+		articles: make(map[string][]market.NewsArticle),
+	}
+}
+
+// AddArticles indexes articles by each symbol they mention
+func (p *NewsSentimentProvider) AddArticles(articles ...market.NewsArticle) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	touched := make(map[string]bool)
+	for _, article := range articles {
+		for _, symbol := range article.Symbols {
+			symbol = strings.ToUpper(symbol)
+			p.articles[symbol] = append(p.articles[symbol], article)
+			touched[symbol] = true
+		}
+	}
+	for symbol := range touched {
+		indexed := p.articles[symbol]
+		sort.SliceStable(indexed, func(i, j int) bool {
+			return publishedAt(indexed[i]).Before(publishedAt(indexed[j]))
+		})
+	}
+}
+
+// publishedAt is when an article became known
+func publishedAt(article market.NewsArticle) time.Time {
+	if article.CreatedAt.IsZero() {
+		return article.UpdatedAt
+	}
+	return article.CreatedAt
+}
+
+// LoadNewsArticles reads articles from a JSON array or NDJSON file, as
+// saved from the Alpaca news API
+func LoadNewsArticles(path string) ([]market.NewsArticle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var articles []market.NewsArticle
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &articles); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return articles, nil
+	}
+	err = readNDJSON(data, func(line []byte) error {
+		var article market.NewsArticle
+		if err := json.Unmarshal(line, &article); err != nil {
+			return err
+		}
+		articles = append(articles, article)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return articles, nil
+}
+
+// GetSentiment implements SentimentProvider
+func (p *NewsSentimentProvider) GetSentiment(symbol string) (*SentimentData, error) {
+	return p.SentimentAt(symbol, time.Now())
+}
+
+// SentimentAt implements HistoricalSentimentProvider, scoring only articles
+// published by at
+func (p *NewsSentimentProvider) SentimentAt(symbol string, at time.Time) (*SentimentData, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	data := &SentimentData{Symbol: symbol, Timestamp: at, Source: "news"}
+	var weighted, totalWeight float64
+	hits := make(map[string]int)
+	articles := p.articles[strings.ToUpper(symbol)]
+	known := sort.Search(len(articles), func(i int) bool { return publishedAt(articles[i]).After(at) })
+	for _, article := range articles[:known] {
+		age := at.Sub(publishedAt(article))
+		if age > p.Lookback {
+			continue
+		}
+		score := p.lexicon.Score(article.Headline + ". " + article.Summary)
+		data.Volume++
+		if score.Positive+score.Negative == 0 {
+			continue // No tone words; the article says nothing either way
+		}
+		weight := decayWeight(age, p.HalfLife)
+		weighted += weight * score.Score
+		totalWeight += weight
+		for _, hit := range score.Hits {
+			hits[hit]++
+		}
+	}
+	if totalWeight > 0 {
+		data.Score = weighted / totalWeight
+		data.Confidence = 1 - math.Exp(-totalWeight/newsConfidenceArticles)
+		data.Keywords = topKeywords(hits, 5)
+	}
+	return data, nil
+}
+
+// GetBulkSentiment implements SentimentProvider
+func (p *NewsSentimentProvider) GetBulkSentiment(symbols []string) (map[string]*SentimentData, error) {
+	return bulkSentiment(p, symbols)
+}
+
+// FileSentimentProvider serves pre-scored sentiment from an NDJSON file, one
+// SentimentData object per line (e.g. {"symbol":"AAPL","score":0.4,
+// "confidence":0.8,"volume":120,"source":"stocktwits",
+// "timestamp":"2024-05-01T14:30:00Z"}). The newest record for a symbol no
+// older than MaxAge is returned. The file is re-read when it changes, so an
+// external scorer can keep appending to it.
+type FileSentimentProvider struct {
+	Path   string
+	MaxAge time.Duration
+
+	mu      sync.Mutex
+	modTime time.Time
+	records sentimentRecords
+}
+
+// NewFileSentimentProvider reads path once to check it parses
+func NewFileSentimentProvider(path string) (*FileSentimentProvider, error) {
+	p := &FileSentimentProvider{Path: path, MaxAge: 24 * time.Hour}
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// reload re-reads the file if it changed since the last read
+func (p *FileSentimentProvider) reload() error {
+	info, err := os.Stat(p.Path)
+	if err != nil {
+		return err
+	}
+	if !info.ModTime().After(p.modTime) && p.records != nil {
+		return nil
+	}
+	records := make(sentimentRecords)
+	if err := records.load(p.Path); err != nil {
+		return err
+	}
+	p.records, p.modTime = records, info.ModTime()
+	return nil
+}
+
+// GetSentiment implements SentimentProvider
+func (p *FileSentimentProvider) GetSentiment(symbol string) (*SentimentData, error) {
+	return p.SentimentAt(symbol, time.Now())
+}
+
+// SentimentAt implements HistoricalSentimentProvider. Records are dated by
+// their timestamp, so the file must not hold revisions of past scores.
+func (p *FileSentimentProvider) SentimentAt(symbol string, at time.Time) (*SentimentData, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+	return p.records.at(symbol, at, p.MaxAge, "file"), nil
+}
+
+// GetBulkSentiment implements SentimentProvider
+func (p *FileSentimentProvider) GetBulkSentiment(symbols []string) (map[string]*SentimentData, error) {
+	return bulkSentiment(p, symbols)
+}
+
+// SentimentSource is one input to an AggregateSentimentProvider
+type SentimentSource struct {
+	Name     string
+	Provider SentimentProvider
+	Weight   float64       // Relative trust in the source
+	HalfLife time.Duration // Age at which a reading's weight halves; 0 never decays
+}
+
+// AggregateSentimentProvider blends several sources. Each reading counts
+// in proportion to its source weight, its own confidence and a decay on its
+// age; the blended confidence is the share of total source weight that
+// delivered confident, fresh readings, so a silent source lowers it.
+type AggregateSentimentProvider struct {
+	sources []SentimentSource
+	logger  *log.Logger
+}
+
+// NewAggregateSentimentProvider blends sources; those without a provider or
+// with a non-positive weight are dropped
+func NewAggregateSentimentProvider(sources ...SentimentSource) *AggregateSentimentProvider {
+	a := &AggregateSentimentProvider{
+		logger: log.New(log.Writer(), "[SENTIMENT] ", log.LstdFlags),
+	}
+	for _, source := range sources {
+		if source.Provider != nil && source.Weight > 0 {
+			a.sources = append(a.sources, source)
+		}
+	}
+	return a
+}
+
+// GetSentiment implements SentimentProvider
+func (a *AggregateSentimentProvider) GetSentiment(symbol string) (*SentimentData, error) {
+	return a.blend(symbol, time.Now(), func(p SentimentProvider) (*SentimentData, error) {
+		return p.GetSentiment(symbol)
+	})
+}
+
+// SentimentAt implements HistoricalSentimentProvider. Every source must
+// keep history; a live-only source would leak the present into the past.
+func (a *AggregateSentimentProvider) SentimentAt(symbol string, at time.Time) (*SentimentData, error) {
+	for _, source := range a.sources {
+		if _, ok := source.Provider.(HistoricalSentimentProvider); !ok {
+			return nil, fmt.Errorf("%s sentiment has no history", source.Name)
+		}
+	}
+	return a.blend(symbol, at, func(p SentimentProvider) (*SentimentData, error) {
+		return p.(HistoricalSentimentProvider).SentimentAt(symbol, at)
+	})
+}
+
+// blend weighs each source's reading as of now
+func (a *AggregateSentimentProvider) blend(symbol string, now time.Time, read func(SentimentProvider) (*SentimentData, error)) (*SentimentData, error) {
+	data := &SentimentData{Symbol: symbol, Timestamp: now, Source: "composite"}
+	var weighted, effective, total float64
+	seen := make(map[string]bool)
+	for _, source := range a.sources {
+		total += source.Weight
+		reading, err := read(source.Provider)
+		if err != nil {
+			a.logger.Printf("%s sentiment for %s unavailable: %v", source.Name, symbol, err)
+			continue
+		}
+		if reading == nil || reading.Confidence <= 0 {
+			continue
+		}
+		w := source.Weight * math.Min(reading.Confidence, 1) * decayWeight(now.Sub(reading.Timestamp), source.HalfLife)
+		weighted += w * reading.Score
+		effective += w
+		data.Volume += reading.Volume
+		for _, keyword := range reading.Keywords {
+			if !seen[keyword] {
+				seen[keyword] = true
+				data.Keywords = append(data.Keywords, keyword)
+			}
+		}
+	}
+	if effective > 0 && total > 0 {
+		data.Score = weighted / effective
+		data.Confidence = effective / total
+	}
+	return data, nil
+}
+
+// GetBulkSentiment implements SentimentProvider
+func (a *AggregateSentimentProvider) GetBulkSentiment(symbols []string) (map[string]*SentimentData, error) {
+	return bulkSentiment(a, symbols)
+}
+
+// Sources lists the blended source names
+func (a *AggregateSentimentProvider) Sources() []string {
+	names := make([]string, len(a.sources))
+	for i, source := range a.sources {
+		names[i] = source.Name
+	}
+	return names
+}
+
+// sentimentRecords holds pre-scored readings by symbol, in time order
+type sentimentRecords map[string][]SentimentData
+
+// add normalizes and inserts records, keeping each symbol in time order
+func (r sentimentRecords) add(records ...SentimentData) error {
+	touched := make(map[string]bool)
+	for _, record := range records {
+		if record.Symbol == "" || record.Timestamp.IsZero() {
+			return fmt.Errorf("record needs a symbol and timestamp")
+		}
+		record.Symbol = strings.ToUpper(record.Symbol)
+		record.Score = math.Max(-1, math.Min(1, record.Score))
+		r[record.Symbol] = append(r[record.Symbol], record)
+		touched[record.Symbol] = true
+	}
+	for symbol := range touched {
+		series := r[symbol]
+		sort.SliceStable(series, func(i, j int) bool { return series[i].Timestamp.Before(series[j].Timestamp) })
+	}
+	return nil
+}
+
+// load adds every record in an NDJSON file
+func (r sentimentRecords) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	err = readNDJSON(data, func(line []byte) error {
+		var record SentimentData
+		if err := json.Unmarshal(line, &record); err != nil {
+			return err
+		}
+		return r.add(record)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// at returns the newest record stamped no later than at and no older than
+// maxAge, or a zero-confidence reading when there is none
+func (r sentimentRecords) at(symbol string, at time.Time, maxAge time.Duration, source string) *SentimentData {
+	series := r[strings.ToUpper(symbol)]
+	i := sort.Search(len(series), func(i int) bool { return series[i].Timestamp.After(at) }) - 1
+	if i < 0 || at.Sub(series[i].Timestamp) > maxAge {
+		return &SentimentData{Symbol: symbol, Timestamp: at, Source: source}
+	}
+	data := series[i]
+	if data.Source == "" {
+		data.Source = source
+	}
+	if data.Confidence == 0 {
+		data.Confidence = 1 // Unstated confidence means the scorer vouches for it
+	}
+	return &data
+}
+
+// decayWeight halves every halfLife of age; a zero half-life never decays
+func decayWeight(age, halfLife time.Duration) float64 {
+	if halfLife <= 0 || age <= 0 {
+		return 1
+	}
+	return math.Exp2(-float64(age) / float64(halfLife))
+}
+
+// bulkSentiment fetches symbols one at a time, skipping failures
+func bulkSentiment(p SentimentProvider, symbols []string) (map[string]*SentimentData, error) {
+	results := make(map[string]*SentimentData, len(symbols))
+	for _, symbol := range symbols {
+		if data, err := p.GetSentiment(symbol); err == nil {
+			results[symbol] = data
+		}
+	}
+	return results, nil
+}
+
+// readNDJSON calls fn for each non-blank line, reporting the line number of
+// the first failure
+func readNDJSON(data []byte, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,210 @@
+package strategies
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultStateDir is where strategies persist live state unless
+// STRATEGY_STATE_DIR overrides it
+const DefaultStateDir = "/tmp/strategy_state"
+
+// stateVersion is bumped when a persisted state layout changes incompatibly;
+// snapshots from other versions are ignored rather than misread
+const stateVersion = 1
+
+// StateStore persists each strategy's live state so a restarted process can
+// resume mid-trade instead of rebuilding it from scratch
+type StateStore interface {
+	// Save replaces the stored state for a strategy
+	Save(strategyID string, state interface{}) error
+	// Load decodes the stored state into state; found is false if there is none
+	Load(strategyID string, state interface{}) (found bool, err error)
+	// Delete removes the stored state, e.g. when a strategy is retired
+	Delete(strategyID string) error
+}
+
+// Persistent is implemented by strategies that snapshot and restore live state
+type Persistent interface {
+	SetStateStore(store StateStore)
+}
+
+// stateEnvelope wraps a strategy's state on disk
+type stateEnvelope struct {
+	Version    int             `json:"version"`
+	StrategyID string          `json:"strategy_id"`
+	SavedAt    time.Time       `json:"saved_at"`
+	State      json.RawMessage `json:"state"`
+}
+
+// FileStateStore keeps one JSON file per strategy in a directory. Writes go
+// to a temporary file that is synced and renamed over the old one, so a crash
+// mid-write leaves the previous snapshot intact.
+type FileStateStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStateStore creates a store in dir, creating the directory if needed
+func NewFileStateStore(dir string) (*FileStateStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory %s: %w", dir, err)
+	}
+	return &FileStateStore{dir: dir}, nil
+}
+
+// StateDirFromEnv returns STRATEGY_STATE_DIR or DefaultStateDir
+func StateDirFromEnv() string {
+	if dir := os.Getenv("STRATEGY_STATE_DIR"); dir != "" {
+		return dir
+	}
+	return DefaultStateDir
+}
+
+// path maps a strategy ID to its file, keeping IDs from escaping the directory
+func (f *FileStateStore) path(strategyID string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(strategyID)
+	return filepath.Join(f.dir, name+".json")
+}
+
+// Save atomically replaces a strategy's snapshot
+func (f *FileStateStore) Save(strategyID string, state interface{}) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	data, err := json.MarshalIndent(stateEnvelope{
+		Version:    stateVersion,
+		StrategyID: strategyID,
+		SavedAt:    time.Now(),
+		State:      raw,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tmp, err := os.CreateTemp(f.dir, ".state-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path(strategyID)); err != nil {
+		return fmt.Errorf("failed to replace state: %w", err)
+	}
+	return nil
+}
+
+// Load decodes a strategy's snapshot into state
+func (f *FileStateStore) Load(strategyID string, state interface{}) (bool, error) {
+	f.mu.Lock()
+	data, err := os.ReadFile(f.path(strategyID))
+	f.mu.Unlock()
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	var envelope stateEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return false, fmt.Errorf("failed to decode state: %w", err)
+	}
+	if envelope.Version != stateVersion {
+		return false, fmt.Errorf("state version %d is not supported (want %d)", envelope.Version, stateVersion)
+	}
+	if err := json.Unmarshal(envelope.State, state); err != nil {
+		return false, fmt.Errorf("failed to decode state: %w", err)
+	}
+	return true, nil
+}
+
+// Delete removes a strategy's snapshot
+func (f *FileStateStore) Delete(strategyID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.Remove(f.path(strategyID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete state: %w", err)
+	}
+	return nil
+}
+
+// SetStateStore sets where the strategy persists its live state
+func (r *orderRouting) SetStateStore(store StateStore) {
+	r.store = store
+}
+
+// ensureStateStore opens the default file store when the strategy runs standalone
+func (r *orderRouting) ensureStateStore() error {
+	if r.store != nil {
+		return nil
+	}
+	store, err := NewFileStateStore(StateDirFromEnv())
+	if err != nil {
+		return err
+	}
+	r.store = store
+	return nil
+}
+
+// saveState snapshots the strategy's state; a nil store disables persistence
+func (r *orderRouting) saveState(state interface{}) error {
+	if r.store == nil {
+		return nil
+	}
+	return r.store.Save(r.strategyID, state)
+}
+
+// loadState restores the strategy's last snapshot into state
+func (r *orderRouting) loadState(state interface{}) (bool, error) {
+	if r.store == nil {
+		return false, nil
+	}
+	return r.store.Load(r.strategyID, state)
+}
+
+// restorePosition seeds the strategy's OMS book with a saved position. From
+// there the book follows the strategy's own fills; when the OMS starts it
+// reconciles the books with the account (see CheckPositions).
+func (r *orderRouting) restorePosition(symbol string, qty int64, entryPrice float64) {
+	r.oms.SyncPosition(r.strategyID, symbol, decimal.NewFromInt(qty), decimal.NewFromFloat(entryPrice))
+}
+
+// bookPosition reads the strategy's own position in symbol from its OMS book
+func (r *orderRouting) bookPosition(symbol string) (int64, float64) {
+	pos := r.oms.Position(r.strategyID, symbol)
+	return pos.Qty.IntPart(), pos.AvgEntryPrice.InexactFloat64()
+}
+
+// positionState is the persisted state shared by single-symbol strategies
+type positionState struct {
+	PositionQty int64   `json:"position_qty"`
+	EntryPrice  float64 `json:"entry_price"`
+	LastSignal  string  `json:"last_signal,omitempty"`
+	Trades      int     `json:"trades"`
+	Wins        int     `json:"wins"`
+	TotalPnL    float64 `json:"total_pnl"`
+}
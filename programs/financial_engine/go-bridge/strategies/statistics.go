@@ -0,0 +1,186 @@
+package strategies
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Strategy health states reported in StrategyStats
+const (
+	HealthOK        = "ok"         // Receiving data and warmed up
+	HealthWarmingUp = "warming_up" // Not enough history to trade yet
+	HealthStale     = "stale"      // No market data within statsStaleAfter
+	HealthNoData    = "no_data"    // No market data since start
+)
+
+// statsStaleAfter is how long without market data before a strategy reports stale
+const statsStaleAfter = 5 * time.Minute
+
+// StrategyStats is the schema-stable statistics every strategy reports.
+// Common fields have fixed names and types so aggregation, dashboards and the
+// metrics exporter can rely on them; anything specific to one strategy goes in
+// Extra. Lifecycle fields are filled by the StrategyRunner.
+type StrategyStats struct {
+	StrategyID     string     `json:"strategy_id"`
+	Strategy       string     `json:"strategy"` // Display name
+	Symbols        []string   `json:"symbols"`
+	Trades         int        `json:"trades"` // Completed round trips
+	Wins           int        `json:"wins"`
+	WinRate        float64    `json:"win_rate"` // Percent of trades
+	TotalPnL       float64    `json:"total_pnl"`
+	AvgTradePnL    float64    `json:"avg_trade_pnl"`
+	RiskRejections int        `json:"risk_rejections"`
+	Exposure       Exposure   `json:"exposure"`
+	LastSignal     string     `json:"last_signal,omitempty"`
+	LastSignalAt   *time.Time `json:"last_signal_at,omitempty"`
+	Health         Health     `json:"health"`
+
+	Lifecycle      string     `json:"lifecycle,omitempty"`
+	LifecycleSince *time.Time `json:"lifecycle_since,omitempty"`
+	LifecycleError string     `json:"lifecycle_error,omitempty"`
+
+	Extra map[string]interface{} `json:"extra,omitempty"` // Strategy-specific fields
+}
+
+// Exposure summarizes the open positions in a strategy's OMS book, in dollars
+type Exposure struct {
+	Positions int     `json:"positions"`
+	Long      float64 `json:"long"`
+	Short     float64 `json:"short"` // Positive dollar value of short positions
+	Gross     float64 `json:"gross"`
+	Net       float64 `json:"net"`
+}
+
+// Health reports whether a strategy is receiving the data it needs to trade
+type Health struct {
+	Status     string     `json:"status"`
+	LastDataAt *time.Time `json:"last_data_at,omitempty"`
+}
+
+// Symbol returns the symbols joined for display, e.g. "SPY" or "KO/PEP"
+func (s StrategyStats) Symbol() string {
+	switch len(s.Symbols) {
+	case 0:
+		return ""
+	case 1:
+		return s.Symbols[0]
+	case 2:
+		return s.Symbols[0] + "/" + s.Symbols[1]
+	}
+	return fmt.Sprintf("Basket(%d)", len(s.Symbols))
+}
+
+// newStats fills the common statistics for a strategy. marks prices positions
+// for exposure; symbols without a mark are valued at their average entry price.
+func (r *orderRouting) newStats(name string, symbols []string, trades, wins int, totalPnL float64, marks map[string]float64) StrategyStats {
+	stats := StrategyStats{
+		StrategyID:     r.strategyID,
+		Strategy:       name,
+		Symbols:        symbols,
+		Trades:         trades,
+		Wins:           wins,
+		TotalPnL:       totalPnL,
+		RiskRejections: r.riskRejections(),
+		Exposure:       r.exposure(marks),
+		Extra:          make(map[string]interface{}),
+	}
+	if trades > 0 {
+		stats.WinRate = float64(wins) / float64(trades) * 100
+		stats.AvgTradePnL = totalPnL / float64(trades)
+	}
+
+	r.statsMu.Lock()
+	stats.LastSignal = r.lastSignal
+	stats.LastSignalAt = optionalTime(r.lastSignalAt)
+	stats.Health.LastDataAt = optionalTime(r.lastDataAt)
+	r.statsMu.Unlock()
+
+	switch {
+	case stats.Health.LastDataAt == nil:
+		stats.Health.Status = HealthNoData
+	case time.Since(*stats.Health.LastDataAt) > statsStaleAfter:
+		stats.Health.Status = HealthStale
+	default:
+		stats.Health.Status = HealthOK
+	}
+	return stats
+}
+
+// optionalTime returns nil for the zero time so it is omitted from JSON
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// exposure values the strategy's open book positions
+func (r *orderRouting) exposure(marks map[string]float64) Exposure {
+	var exposure Exposure
+	if r.oms == nil {
+		return exposure
+	}
+
+	for _, pos := range r.oms.Positions(r.strategyID) {
+		price, ok := marks[pos.Symbol]
+		if !ok || price <= 0 {
+			price = pos.AvgEntryPrice.InexactFloat64()
+		}
+		value := pos.Qty.InexactFloat64() * price
+
+		exposure.Positions++
+		if value >= 0 {
+			exposure.Long += value
+		} else {
+			exposure.Short -= value
+		}
+	}
+	exposure.Gross = exposure.Long + exposure.Short
+	exposure.Net = exposure.Long - exposure.Short
+	return exposure
+}
+
+// warmingUp downgrades a healthy status while the strategy lacks history
+func (s *StrategyStats) warmingUp(warming bool) {
+	if warming && s.Health.Status == HealthOK {
+		s.Health.Status = HealthWarmingUp
+	}
+}
+
+// AggregateStats sums trades, wins, P&L and exposure across strategies
+func AggregateStats(all []StrategyStats) StrategyStats {
+	total := StrategyStats{Strategy: "Aggregate"}
+	seen := make(map[string]bool)
+	for _, stats := range all {
+		total.Trades += stats.Trades
+		total.Wins += stats.Wins
+		total.TotalPnL += stats.TotalPnL
+		total.RiskRejections += stats.RiskRejections
+		total.Exposure.Positions += stats.Exposure.Positions
+		total.Exposure.Long += stats.Exposure.Long
+		total.Exposure.Short += stats.Exposure.Short
+		total.Exposure.Gross += stats.Exposure.Gross
+		total.Exposure.Net += stats.Exposure.Net
+		for _, symbol := range stats.Symbols {
+			if !seen[symbol] {
+				seen[symbol] = true
+				total.Symbols = append(total.Symbols, symbol)
+			}
+		}
+	}
+	sort.Strings(total.Symbols)
+	if total.Trades > 0 {
+		total.WinRate = float64(total.Wins) / float64(total.Trades) * 100
+		total.AvgTradePnL = total.TotalPnL / float64(total.Trades)
+	}
+	return total
+}
+
+// lastMark returns symbol marked at the newest price in a history, if any
+func lastMark(symbol string, prices []float64) map[string]float64 {
+	if len(prices) == 0 {
+		return nil
+	}
+	return map[string]float64{symbol: prices[len(prices)-1]}
+}
@@ -0,0 +1,387 @@
+package strategies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultStatusAddr is where the runner serves status and metrics unless
+// RUNNER_STATUS_ADDR overrides it. Loopback only: this is for local monitoring.
+const DefaultStatusAddr = "127.0.0.1:9464"
+
+// defaultRecentOrders is how many orders /api/orders returns without ?limit=
+const defaultRecentOrders = 100
+
+// StatusServer exposes runner state as JSON and Prometheus metrics over HTTP,
+// plus strategy controls: add, pause, resume, reconfigure and remove
+type StatusServer struct {
+	runner *StrategyRunner
+	server *http.Server
+	logger *log.Logger
+}
+
+// NewStatusServer creates a status server for a runner listening on addr
+func NewStatusServer(runner *StrategyRunner, addr string) *StatusServer {
+	s := &StatusServer{
+		runner: runner,
+		logger: log.New(log.Writer(), "[STATUS] ", log.LstdFlags),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/status", s.handleStatus)
+	mux.HandleFunc("GET /api/strategies", s.handleStrategies)
+	mux.HandleFunc("GET /api/strategies/{id}", s.handleStrategy)
+	mux.HandleFunc("GET /api/positions", s.handlePositions)
+	mux.HandleFunc("GET /api/orders", s.handleOrders)
+	mux.HandleFunc("GET /api/executions", s.handleExecutions)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+
+	// Controls; the listener is loopback-only unless RUNNER_STATUS_ADDR says otherwise
+	mux.HandleFunc("POST /api/strategies", s.handleAddStrategy)
+	mux.HandleFunc("POST /api/strategies/{id}/pause", s.handlePauseStrategy)
+	mux.HandleFunc("POST /api/strategies/{id}/resume", s.handleResumeStrategy)
+	mux.HandleFunc("PATCH /api/strategies/{id}", s.handleReconfigureStrategy)
+	mux.HandleFunc("DELETE /api/strategies/{id}", s.handleRemoveStrategy)
+
+	s.server = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// Start binds the listener and serves in the background until ctx is done
+func (s *StatusServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.server.Addr, err)
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Printf("Status server error: %v", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Printf("Serving status on http://%s (JSON under /api, Prometheus at /metrics)", listener.Addr())
+	return nil
+}
+
+// handleStatus serves runner-level state
+func (s *StatusServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.runner.Status())
+}
+
+// handleStrategies serves statistics for every strategy
+func (s *StatusServer) handleStrategies(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.runner.GetStatistics())
+}
+
+// handleStrategy serves statistics for one strategy by ID
+func (s *StatusServer) handleStrategy(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	for _, stats := range s.runner.GetStatistics() {
+		if stats.StrategyID == id {
+			writeJSON(w, http.StatusOK, stats)
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown strategy " + id})
+}
+
+// handleAddStrategy builds a strategy from a StrategySpec body and adds it
+func (s *StatusServer) handleAddStrategy(w http.ResponseWriter, r *http.Request) {
+	var spec StrategySpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid strategy spec: " + err.Error()})
+		return
+	}
+	strategy, err := NewStrategyFromSpec(spec)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := s.runner.AddStrategy(strategy); err != nil {
+		writeControlError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"id": strategyIDOf(strategy)})
+}
+
+// handlePauseStrategy blocks a strategy's entries; exits keep working
+func (s *StatusServer) handlePauseStrategy(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.runner.PauseStrategy(id); err != nil {
+		writeControlError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": id, "lifecycle": LifecyclePaused})
+}
+
+// handleResumeStrategy lets a paused strategy open positions again
+func (s *StatusServer) handleResumeStrategy(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.runner.ResumeStrategy(id); err != nil {
+		writeControlError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": id, "lifecycle": LifecycleRunning})
+}
+
+// handleReconfigureStrategy applies a JSON object of parameters to a strategy
+func (s *StatusServer) handleReconfigureStrategy(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var params map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid parameters: " + err.Error()})
+		return
+	}
+	if err := s.runner.ReconfigureStrategy(id, params); err != nil {
+		writeControlError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "params": params})
+}
+
+// handleRemoveStrategy removes a strategy; ?policy= is keep, flatten or drain
+// (default flatten) and ?drain_timeout= a duration. Removal waits on the
+// broker, so it runs in the background; the strategy shows as stopping until
+// it is gone.
+func (s *StatusServer) handleRemoveStrategy(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	policy := PositionPolicy(r.URL.Query().Get("policy"))
+	if policy == "" {
+		policy = PositionPolicyFlatten
+	}
+	switch policy {
+	case PositionPolicyKeep, PositionPolicyFlatten, PositionPolicyDrain:
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unknown position policy %q", policy)})
+		return
+	}
+
+	var drainTimeout time.Duration
+	if raw := r.URL.Query().Get("drain_timeout"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid drain_timeout " + raw})
+			return
+		}
+		drainTimeout = parsed
+	}
+
+	found := false
+	for _, stats := range s.runner.GetStatistics() {
+		found = found || stats.StrategyID == id
+	}
+	if !found {
+		writeControlError(w, fmt.Errorf("%w %s", ErrUnknownStrategy, id))
+		return
+	}
+
+	go func() {
+		if err := s.runner.RemoveStrategy(id, policy, drainTimeout); err != nil {
+			s.logger.Printf("Failed to remove strategy %s: %v", id, err)
+		}
+	}()
+	writeJSON(w, http.StatusAccepted, map[string]string{"id": id, "policy": string(policy)})
+}
+
+// writeControlError maps a control method error to a status code
+func writeControlError(w http.ResponseWriter, err error) {
+	code := http.StatusConflict
+	if errors.Is(err, ErrUnknownStrategy) {
+		code = http.StatusNotFound
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// handlePositions serves the OMS book positions of every strategy
+func (s *StatusServer) handlePositions(w http.ResponseWriter, r *http.Request) {
+	oms := s.runner.OrderManager()
+	if oms == nil {
+		writeJSON(w, http.StatusOK, map[string][]BookPosition{})
+		return
+	}
+	writeJSON(w, http.StatusOK, oms.AllPositions())
+}
+
+// handleOrders serves recent orders; ?strategy= filters, ?limit= caps the count
+func (s *StatusServer) handleOrders(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRecentOrders
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit " + raw})
+			return
+		}
+		limit = parsed
+	}
+
+	oms := s.runner.OrderManager()
+	if oms == nil {
+		writeJSON(w, http.StatusOK, []ManagedOrder{})
+		return
+	}
+	writeJSON(w, http.StatusOK, oms.RecentOrders(r.URL.Query().Get("strategy"), limit))
+}
+
+// handleExecutions serves parent order progress; ?strategy= filters
+func (s *StatusServer) handleExecutions(w http.ResponseWriter, r *http.Request) {
+	engine := s.runner.ExecutionEngine()
+	if engine == nil {
+		writeJSON(w, http.StatusOK, []ExecutionProgress{})
+		return
+	}
+	writeJSON(w, http.StatusOK, engine.Executions(r.URL.Query().Get("strategy")))
+}
+
+// handleMetrics serves the Prometheus text format
+func (s *StatusServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p := newPromWriter(w)
+
+	status := s.runner.Status()
+	p.family("runner_uptime_seconds", "gauge", "Seconds since the runner started")
+	p.sample("runner_uptime_seconds", status["uptime_seconds"].(float64))
+
+	// Per-strategy performance
+	p.family("strategy_lifecycle_state", "gauge", "1 for the strategy's current lifecycle state")
+	p.family("strategy_trades_total", "counter", "Completed round-trip trades")
+	p.family("strategy_wins_total", "counter", "Winning round-trip trades")
+	p.family("strategy_win_rate_percent", "gauge", "Winning trades as a percentage of trades")
+	p.family("strategy_pnl_dollars", "gauge", "Realized P&L in dollars")
+	p.family("strategy_risk_rejections_total", "counter", "Intents rejected by portfolio risk")
+	p.family("strategy_exposure_dollars", "gauge", "Open position value in the strategy's book, by side")
+	p.family("strategy_health", "gauge", "1 for the strategy's current data health status")
+	p.family("strategy_last_data_timestamp_seconds", "gauge", "Unix time of the last market data the strategy processed")
+	for _, stats := range s.runner.GetStatistics() {
+		id := stats.StrategyID
+		p.sample("strategy_lifecycle_state", 1, "strategy_id", id, "state", stats.Lifecycle)
+		p.sample("strategy_trades_total", float64(stats.Trades), "strategy_id", id)
+		p.sample("strategy_wins_total", float64(stats.Wins), "strategy_id", id)
+		p.sample("strategy_win_rate_percent", stats.WinRate, "strategy_id", id)
+		p.sample("strategy_pnl_dollars", stats.TotalPnL, "strategy_id", id)
+		p.sample("strategy_risk_rejections_total", float64(stats.RiskRejections), "strategy_id", id)
+		p.sample("strategy_exposure_dollars", stats.Exposure.Long, "strategy_id", id, "side", "long")
+		p.sample("strategy_exposure_dollars", stats.Exposure.Short, "strategy_id", id, "side", "short")
+		p.sample("strategy_health", 1, "strategy_id", id, "status", stats.Health.Status)
+		if stats.Health.LastDataAt != nil {
+			p.sample("strategy_last_data_timestamp_seconds", float64(stats.Health.LastDataAt.Unix()), "strategy_id", id)
+		}
+	}
+
+	// Order management: signals, order states, positions and latencies
+	if oms := s.runner.OrderManager(); oms != nil {
+		metrics := oms.Metrics()
+
+		p.family("strategy_signals_total", "counter", "Order intents submitted, by signal")
+		for _, id := range sortedKeys(metrics.Intents) {
+			for _, signal := range sortedKeys(metrics.Intents[id]) {
+				p.sample("strategy_signals_total", float64(metrics.Intents[id][signal]), "strategy_id", id, "signal", signal)
+			}
+		}
+
+		p.family("oms_orders", "gauge", "Orders tracked by the OMS, by state")
+		for _, id := range sortedKeys(metrics.Orders) {
+			for _, state := range sortedKeys(metrics.Orders[id]) {
+				p.sample("oms_orders", float64(metrics.Orders[id][state]), "strategy_id", id, "state", string(state))
+			}
+		}
+
+		p.family("strategy_position_qty", "gauge", "Signed position quantity in the strategy's book")
+		positions := oms.AllPositions()
+		for _, id := range sortedKeys(positions) {
+			sort.Slice(positions[id], func(i, j int) bool { return positions[id][i].Symbol < positions[id][j].Symbol })
+			for _, pos := range positions[id] {
+				p.sample("strategy_position_qty", pos.Qty.InexactFloat64(), "strategy_id", id, "symbol", pos.Symbol)
+			}
+		}
+
+		p.family("oms_position_break_qty", "gauge", "Account position minus the sum of strategy books, for symbols that disagree")
+		for _, b := range metrics.Breaks {
+			p.sample("oms_position_break_qty", b.Account.Sub(b.Books).InexactFloat64(), "symbol", b.Symbol)
+		}
+
+		p.histogram("oms_order_submit_latency_seconds", "Broker round trip for order submission", metrics.SubmitLatency)
+		p.histogram("oms_order_fill_latency_seconds", "Time from submission to complete fill", metrics.FillLatency)
+	}
+
+	// Websocket activity across all streams in the process
+	p.family("websocket_messages_total", "counter", "Websocket messages received, by stream and type")
+	p.family("websocket_read_errors_total", "counter", "Failed websocket reads")
+	p.family("websocket_reconnects_total", "counter", "Websocket reconnections")
+	for _, stream := range []struct {
+		name     string
+		counters *streamCounters
+	}{
+		{"market_data", marketDataCounters},
+		{"trade_updates", tradeUpdatesCounters},
+	} {
+		messages := stream.counters.messages()
+		for _, msgType := range sortedKeys(messages) {
+			p.sample("websocket_messages_total", float64(messages[msgType]), "stream", stream.name, "type", msgType)
+		}
+		p.sample("websocket_read_errors_total", float64(stream.counters.readErrorCount()), "stream", stream.name)
+		p.sample("websocket_reconnects_total", float64(stream.counters.reconnectCount()), "stream", stream.name)
+	}
+
+	// Market data fan-out per subscriber
+	if hub := s.runner.MarketDataHub(); hub != nil {
+		p.family("market_data_events_delivered_total", "counter", "Market data events queued to a subscriber")
+		p.family("market_data_events_dropped_total", "counter", "Market data events dropped because the subscriber fell behind")
+		p.family("market_data_events_buffered", "gauge", "Market data events waiting to be consumed")
+		for _, sub := range hub.Stats() {
+			p.sample("market_data_events_delivered_total", float64(sub.Delivered), "subscriber", sub.ID)
+			p.sample("market_data_events_dropped_total", float64(sub.Dropped), "subscriber", sub.ID)
+			p.sample("market_data_events_buffered", float64(sub.Buffered), "subscriber", sub.ID)
+		}
+	}
+
+	// Kill switch
+	tripped := 0.0
+	if s.runner.CircuitBreaker().IsTripped() {
+		tripped = 1
+	}
+	p.family("circuit_breaker_tripped", "gauge", "1 while trading is halted by the circuit breaker")
+	p.sample("circuit_breaker_tripped", tripped)
+
+	if err := p.flush(); err != nil {
+		s.logger.Printf("Failed to write metrics: %v", err)
+	}
+}
+
+// writeJSON encodes v with the given status code
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// sortedKeys returns map keys in order so metric output is stable between scrapes
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,487 @@
+package strategies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+// Strategy lifecycle states reported in runner statistics
+const (
+	LifecyclePending  = "pending"  // Added, not yet running
+	LifecycleRunning  = "running"  // Trading normally
+	LifecyclePaused   = "paused"   // No new entries; exits still managed
+	LifecycleStopping = "stopping" // Being removed
+	LifecycleStopped  = "stopped"  // Run returned
+	LifecycleFailed   = "failed"   // Initialize or Run returned an error
+)
+
+// PositionPolicy decides what happens to a strategy's positions when it is removed
+type PositionPolicy string
+
+const (
+	PositionPolicyKeep    PositionPolicy = "keep"    // Cancel orders, leave positions in the account
+	PositionPolicyFlatten PositionPolicy = "flatten" // Cancel orders and close positions at market
+	PositionPolicyDrain   PositionPolicy = "drain"   // Block entries, let the strategy exit, flatten on timeout
+)
+
+// ErrUnknownStrategy is returned by the control methods for an ID the runner doesn't have
+var ErrUnknownStrategy = errors.New("unknown strategy")
+
+// defaultDrainTimeout bounds PositionPolicyDrain when no timeout is given
+const defaultDrainTimeout = 30 * time.Minute
+
+// settleTimeout bounds the wait for a removed strategy's cancels and flatten fills
+const settleTimeout = 2 * time.Minute
+
+// strategyEntry tracks one strategy instance inside the runner
+type strategyEntry struct {
+	id       string
+	strategy Strategy
+	state    string
+	err      error
+	since    time.Time
+	cancel   context.CancelFunc // Stops this strategy only
+	done     chan struct{}      // Closed when Run returns
+}
+
+// strategyIDOf returns the ID a strategy is controlled by
+func strategyIDOf(strategy Strategy) string {
+	if routed, ok := strategy.(OrderRouted); ok && routed.StrategyID() != "" {
+		return routed.StrategyID()
+	}
+	return fmt.Sprintf("%T", strategy)
+}
+
+// AddStrategy adds a strategy to the runner. Strategies added before Initialize
+// are set up with the rest; once the runner is initialized the strategy is
+// initialized here, and started immediately if the runner is already running.
+func (r *StrategyRunner) AddStrategy(strategy Strategy) error {
+	if r.ctx.Err() != nil {
+		return fmt.Errorf("runner is shut down")
+	}
+
+	id := strategyIDOf(strategy)
+	entry := &strategyEntry{id: id, strategy: strategy, state: LifecyclePending, since: time.Now()}
+
+	r.mu.Lock()
+	if r.findLocked(id) != nil {
+		r.mu.Unlock()
+		return fmt.Errorf("strategy %s already added", id)
+	}
+	r.entries = append(r.entries, entry)
+	initialized := r.apiKey != ""
+	r.mu.Unlock()
+
+	r.logger.Printf("Added strategy: %s (%T)", id, strategy)
+	if !initialized {
+		return nil
+	}
+
+	if err := r.initializeEntry(entry); err != nil {
+		return err
+	}
+
+	// Reconcile the restored book with the account like a startup would
+	r.mu.RLock()
+	running := r.running
+	r.mu.RUnlock()
+	if running {
+		if _, err := r.oms.CheckPositions(); err != nil {
+			r.logger.Printf("Failed to check positions: %v", err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		r.launchLocked(entry)
+	}
+	return nil
+}
+
+// StrategySpec describes a strategy to build at runtime, e.g. from the status API
+type StrategySpec struct {
+	Type      string                 `json:"type"`                 // ma, rsi, bb, macd, vwap, ml, pairs or momentum
+	Symbols   []string               `json:"symbols"`              // One symbol; two for pairs; the basket for momentum
+	ModelPath string                 `json:"model_path,omitempty"` // ml only
+	Params    map[string]interface{} `json:"params,omitempty"`     // Applied with SetParameters
+}
+
+// NewStrategyFromSpec builds a strategy with its default settings, then applies spec.Params
+func NewStrategyFromSpec(spec StrategySpec) (Strategy, error) {
+	single := func() (string, error) {
+		if len(spec.Symbols) != 1 {
+			return "", fmt.Errorf("%s strategy takes one symbol, got %d", spec.Type, len(spec.Symbols))
+		}
+		return spec.Symbols[0], nil
+	}
+
+	var strategy Strategy
+	switch spec.Type {
+	case "ma", "rsi", "bb", "macd", "vwap", "ml":
+		symbol, err := single()
+		if err != nil {
+			return nil, err
+		}
+		switch spec.Type {
+		case "ma":
+			strategy = NewMovingAverageCrossoverStrategy(symbol, 50, 200)
+		case "rsi":
+			strategy = NewRSIMeanReversionStrategy(symbol, 14)
+		case "bb":
+			strategy = NewBollingerBandsStrategy(symbol, 20)
+		case "macd":
+			strategy = NewMACDDivergenceStrategy(symbol)
+		case "vwap":
+			strategy = NewVWAPIntradayStrategy(symbol, marketdata.OneMin)
+		case "ml":
+			if spec.ModelPath == "" {
+				return nil, fmt.Errorf("ml strategy needs a model_path")
+			}
+			strategy = NewMLPredictiveONNXStrategy(symbol, spec.ModelPath)
+		}
+	case "pairs":
+		if len(spec.Symbols) != 2 {
+			return nil, fmt.Errorf("pairs strategy takes two symbols, got %d", len(spec.Symbols))
+		}
+		strategy = NewPairsTradingStrategy(spec.Symbols[0], spec.Symbols[1])
+	case "momentum":
+		if len(spec.Symbols) == 0 {
+			return nil, fmt.Errorf("momentum strategy needs a basket")
+		}
+		strategy = NewMomentumRotationStrategy(spec.Symbols)
+	default:
+		return nil, fmt.Errorf("unknown strategy type %q", spec.Type)
+	}
+
+	if len(spec.Params) > 0 {
+		reconfigurable, ok := strategy.(Reconfigurable)
+		if !ok {
+			return nil, fmt.Errorf("%s strategy does not take parameters", spec.Type)
+		}
+		if err := reconfigurable.SetParameters(spec.Params); err != nil {
+			return nil, fmt.Errorf("invalid %s parameters: %w", spec.Type, err)
+		}
+	}
+	return strategy, nil
+}
+
+// PauseStrategy stops a strategy from opening or adding to positions. It keeps
+// running, so stops, targets and signal exits are still managed.
+func (r *StrategyRunner) PauseStrategy(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, err := r.routedEntryLocked(id)
+	if err != nil {
+		return err
+	}
+	if entry.state != LifecycleRunning {
+		return fmt.Errorf("strategy %s is %s, not running", id, entry.state)
+	}
+
+	r.oms.SetEntriesPaused(id, true)
+	r.setStateLocked(entry, LifecyclePaused, nil)
+	r.logger.Printf("Paused strategy %s (exits only)", id)
+	return nil
+}
+
+// ResumeStrategy lets a paused strategy open positions again
+func (r *StrategyRunner) ResumeStrategy(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, err := r.routedEntryLocked(id)
+	if err != nil {
+		return err
+	}
+	if entry.state != LifecyclePaused {
+		return fmt.Errorf("strategy %s is %s, not paused", id, entry.state)
+	}
+
+	r.oms.SetEntriesPaused(id, false)
+	r.setStateLocked(entry, LifecycleRunning, nil)
+	r.logger.Printf("Resumed strategy %s", id)
+	return nil
+}
+
+// RemoveStrategy stops a strategy, cancels its working orders and handles its
+// positions according to policy. drainTimeout applies to PositionPolicyDrain
+// (zero uses a default). Positions are only flattened once the strategy's
+// cancels are confirmed, and its saved state is deleted only once its book is
+// flat. Other strategies keep running.
+func (r *StrategyRunner) RemoveStrategy(id string, policy PositionPolicy, drainTimeout time.Duration) error {
+	switch policy {
+	case PositionPolicyKeep, PositionPolicyFlatten, PositionPolicyDrain:
+	default:
+		return fmt.Errorf("unknown position policy %q", policy)
+	}
+
+	r.mu.Lock()
+	entry := r.findLocked(id)
+	if entry == nil {
+		r.mu.Unlock()
+		return fmt.Errorf("%w %s", ErrUnknownStrategy, id)
+	}
+	if entry.state == LifecycleStopping {
+		r.mu.Unlock()
+		return fmt.Errorf("strategy %s is already being removed", id)
+	}
+	r.setStateLocked(entry, LifecycleStopping, nil)
+	cancel, done := entry.cancel, entry.done
+	r.mu.Unlock()
+
+	r.logger.Printf("Removing strategy %s (positions: %s)", id, policy)
+
+	var firstErr error
+	if r.oms != nil {
+		r.oms.SetEntriesPaused(id, true)
+
+		// Let the strategy work its own exits before it is stopped
+		if policy == PositionPolicyDrain && done != nil {
+			if drainTimeout <= 0 {
+				drainTimeout = defaultDrainTimeout
+			}
+			if !r.waitFlat(id, drainTimeout) {
+				r.logger.Printf("Strategy %s not flat after %v, flattening", id, drainTimeout)
+			}
+		}
+	}
+
+	// Stop only this strategy's loop
+	if cancel != nil {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(30 * time.Second):
+			r.logger.Printf("Timeout waiting for strategy %s to stop", id)
+		}
+	}
+
+	if r.oms != nil {
+		firstErr = r.unwindStrategy(id, policy)
+		r.oms.Unsubscribe(id)
+		r.oms.SetEntriesPaused(id, false)
+	}
+
+	// A strategy is retired once its book is flat; kept positions, or a flatten
+	// that didn't complete, keep their state for a re-add
+	if policy != PositionPolicyKeep && firstErr == nil && r.store != nil {
+		if err := r.store.Delete(id); err != nil {
+			firstErr = fmt.Errorf("failed to delete state for %s: %w", id, err)
+		}
+	}
+
+	r.mu.Lock()
+	for i, e := range r.entries {
+		if e == entry {
+			r.entries = append(r.entries[:i], r.entries[i+1:]...)
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	r.logger.Printf("Removed strategy %s", id)
+	return firstErr
+}
+
+// ReconfigureStrategy updates a running strategy's parameters in place
+func (r *StrategyRunner) ReconfigureStrategy(id string, params map[string]interface{}) error {
+	r.mu.RLock()
+	entry := r.findLocked(id)
+	r.mu.RUnlock()
+	if entry == nil {
+		return fmt.Errorf("%w %s", ErrUnknownStrategy, id)
+	}
+
+	reconfigurable, ok := entry.strategy.(Reconfigurable)
+	if !ok {
+		return fmt.Errorf("strategy %s does not support reconfiguration", id)
+	}
+	if err := reconfigurable.SetParameters(params); err != nil {
+		return fmt.Errorf("failed to reconfigure strategy %s: %w", id, err)
+	}
+
+	r.logger.Printf("Reconfigured strategy %s: %v", id, params)
+	return nil
+}
+
+// GetStatistics returns each strategy's statistics with its ID and lifecycle state
+func (r *StrategyRunner) GetStatistics() []StrategyStats {
+	r.mu.RLock()
+	entries := make([]strategyEntry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, *entry)
+	}
+	r.mu.RUnlock()
+
+	all := make([]StrategyStats, 0, len(entries))
+	for _, entry := range entries {
+		stats := entry.strategy.GetStatistics()
+		stats.StrategyID = entry.id
+		stats.Lifecycle = entry.state
+		stats.LifecycleSince = optionalTime(entry.since)
+		if entry.err != nil {
+			stats.LifecycleError = entry.err.Error()
+		}
+		all = append(all, stats)
+	}
+	return all
+}
+
+// initializeEntry wires a strategy to the shared OMS and initializes it
+func (r *StrategyRunner) initializeEntry(entry *strategyEntry) error {
+	if routed, ok := entry.strategy.(OrderRouted); ok {
+		routed.SetOrderManager(r.oms)
+	}
+	if persistent, ok := entry.strategy.(Persistent); ok && r.store != nil {
+		persistent.SetStateStore(r.store)
+	}
+	if consumer, ok := entry.strategy.(MarketDataConsumer); ok && r.hub != nil {
+		consumer.SetMarketDataHub(r.hub)
+	}
+	if user, ok := entry.strategy.(ExecutionUser); ok && r.exec != nil {
+		user.SetExecutionEngine(r.exec)
+	}
+
+	r.mu.RLock()
+	apiKey, apiSecret, baseURL := r.apiKey, r.apiSecret, r.baseURL
+	r.mu.RUnlock()
+
+	if err := entry.strategy.Initialize(apiKey, apiSecret, baseURL); err != nil {
+		r.mu.Lock()
+		r.setStateLocked(entry, LifecycleFailed, err)
+		r.mu.Unlock()
+		return fmt.Errorf("failed to initialize strategy %s: %w", entry.id, err)
+	}
+	return nil
+}
+
+// launchLocked runs a pending strategy under its own context; caller must hold r.mu
+func (r *StrategyRunner) launchLocked(entry *strategyEntry) {
+	if entry.state != LifecyclePending {
+		return // Failed to initialize
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	entry.cancel = cancel
+	entry.done = make(chan struct{})
+	r.setStateLocked(entry, LifecycleRunning, nil)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer close(entry.done)
+		defer cancel()
+
+		r.logger.Printf("Starting strategy: %s", entry.id)
+		err := entry.strategy.Run(ctx)
+
+		r.mu.Lock()
+		if err != nil {
+			r.logger.Printf("Strategy %s error: %v", entry.id, err)
+			r.setStateLocked(entry, LifecycleFailed, err)
+		} else {
+			r.setStateLocked(entry, LifecycleStopped, nil)
+		}
+		r.mu.Unlock()
+		r.logger.Printf("Strategy %s stopped", entry.id)
+	}()
+}
+
+// unwindStrategy cancels a stopped strategy's orders and, unless policy keeps
+// positions, flattens its book. The flatten is only sent once every cancel is
+// confirmed, so a resting order can't fill against it, and the book must be
+// flat before the call succeeds.
+func (r *StrategyRunner) unwindStrategy(id string, policy PositionPolicy) error {
+	if err := r.oms.CancelAll(id); err != nil {
+		return fmt.Errorf("failed to cancel orders for %s: %w", id, err)
+	}
+	if policy == PositionPolicyKeep {
+		return nil
+	}
+
+	if !r.waitUntil(settleTimeout, func() bool { return len(r.oms.OpenOrders(id)) == 0 }) {
+		return fmt.Errorf("orders for %s still working after %v, not flattening", id, settleTimeout)
+	}
+	if err := r.flattenStrategy(id); err != nil {
+		return err
+	}
+	if !r.waitFlat(id, settleTimeout) {
+		return fmt.Errorf("strategy %s not flat %v after flattening", id, settleTimeout)
+	}
+	return nil
+}
+
+// waitFlat polls the strategy's book until it holds no positions or timeout elapses
+func (r *StrategyRunner) waitFlat(id string, timeout time.Duration) bool {
+	return r.waitUntil(timeout, func() bool { return len(r.oms.Positions(id)) == 0 })
+}
+
+// waitUntil polls done every second until it holds or timeout elapses
+func (r *StrategyRunner) waitUntil(timeout time.Duration, done func() bool) bool {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		if done() {
+			return true
+		}
+		select {
+		case <-deadline:
+			return false
+		case <-r.ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// flattenStrategy closes every position in a strategy's book with market orders
+func (r *StrategyRunner) flattenStrategy(id string) error {
+	var firstErr error
+	for _, pos := range r.oms.Positions(id) {
+		_, err := r.oms.Submit(closeIntent(id, "UNWIND", pos.Symbol, pos.Qty, pos.AvgEntryPrice))
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to flatten %s for %s: %w", pos.Symbol, id, err)
+		}
+	}
+	return firstErr
+}
+
+// findLocked returns the entry for a strategy ID; caller must hold r.mu
+func (r *StrategyRunner) findLocked(id string) *strategyEntry {
+	for _, entry := range r.entries {
+		if entry.id == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// routedEntryLocked returns an entry whose orders go through the shared OMS
+func (r *StrategyRunner) routedEntryLocked(id string) (*strategyEntry, error) {
+	entry := r.findLocked(id)
+	if entry == nil {
+		return nil, fmt.Errorf("%w %s", ErrUnknownStrategy, id)
+	}
+	if _, ok := entry.strategy.(OrderRouted); !ok {
+		return nil, fmt.Errorf("strategy %s does not route orders through the order manager", id)
+	}
+	if r.oms == nil {
+		return nil, fmt.Errorf("runner not initialized")
+	}
+	return entry, nil
+}
+
+// setStateLocked records a lifecycle transition; caller must hold r.mu
+func (r *StrategyRunner) setStateLocked(entry *strategyEntry, state string, err error) {
+	entry.state = state
+	entry.err = err
+	entry.since = time.Now()
+}
@@ -0,0 +1,114 @@
+package strategies
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Reconfigurable is implemented by strategies that accept parameter updates while running
+type Reconfigurable interface {
+	SetParameters(params map[string]interface{}) error
+}
+
+// setParameters assigns params to the exported configuration fields of target,
+// a pointer to a strategy struct. Keys match field names case-insensitively and
+// ignoring underscores, so "rsi_period" and "RSIPeriod" are equivalent. Only
+// int, float, bool and string fields can be set; fields named in immutable
+// (identity such as the symbol) are refused. If validate is non-nil it runs
+// after the update and a failure restores the previous values, so a bad update
+// leaves the strategy unchanged. The caller must hold the strategy's lock.
+func setParameters(target interface{}, params map[string]interface{}, validate func() error, immutable ...string) error {
+	value := reflect.ValueOf(target).Elem()
+	structType := value.Type()
+
+	fields := make(map[string]int)
+	for i := 0; i < structType.NumField(); i++ {
+		if structType.Field(i).IsExported() {
+			fields[normalizeParamName(structType.Field(i).Name)] = i
+		}
+	}
+	locked := make(map[string]bool)
+	for _, name := range immutable {
+		locked[normalizeParamName(name)] = true
+	}
+
+	updates := make(map[int]reflect.Value)
+	for key, raw := range params {
+		name := normalizeParamName(key)
+		index, exists := fields[name]
+		if !exists {
+			return fmt.Errorf("unknown parameter %q", key)
+		}
+		if locked[name] {
+			return fmt.Errorf("parameter %q cannot be changed while running", key)
+		}
+
+		converted, err := convertParam(raw, structType.Field(index).Type)
+		if err != nil {
+			return fmt.Errorf("parameter %q: %w", key, err)
+		}
+		updates[index] = converted
+	}
+
+	previous := make(map[int]reflect.Value)
+	for index, converted := range updates {
+		previous[index] = reflect.ValueOf(value.Field(index).Interface())
+		value.Field(index).Set(converted)
+	}
+
+	if validate != nil {
+		if err := validate(); err != nil {
+			for index, old := range previous {
+				value.Field(index).Set(old)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeParamName lowercases a name and strips underscores
+func normalizeParamName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// convertParam converts a JSON-style value (numbers arrive as float64) to the field type
+func convertParam(raw interface{}, fieldType reflect.Type) (reflect.Value, error) {
+	rawValue := reflect.ValueOf(raw)
+	if !rawValue.IsValid() {
+		return reflect.Value{}, fmt.Errorf("value is nil")
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		switch rawValue.Kind() {
+		case reflect.Int, reflect.Int64, reflect.Int32:
+			return reflect.ValueOf(rawValue.Int()).Convert(fieldType), nil
+		case reflect.Float64, reflect.Float32:
+			f := rawValue.Float()
+			if f != float64(int64(f)) {
+				return reflect.Value{}, fmt.Errorf("expected integer, got %v", f)
+			}
+			return reflect.ValueOf(int64(f)).Convert(fieldType), nil
+		}
+
+	case reflect.Float64, reflect.Float32:
+		switch rawValue.Kind() {
+		case reflect.Int, reflect.Int64, reflect.Int32:
+			return reflect.ValueOf(float64(rawValue.Int())).Convert(fieldType), nil
+		case reflect.Float64, reflect.Float32:
+			return reflect.ValueOf(rawValue.Float()).Convert(fieldType), nil
+		}
+
+	case reflect.Bool, reflect.String:
+		if rawValue.Kind() == fieldType.Kind() {
+			return rawValue.Convert(fieldType), nil
+		}
+
+	default:
+		return reflect.Value{}, fmt.Errorf("type %s is not reconfigurable", fieldType)
+	}
+
+	return reflect.Value{}, fmt.Errorf("expected %s, got %T", fieldType.Kind(), raw)
+}
@@ -6,42 +6,97 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/protection"
 )
 
 // StrategyRunner manages multiple trading strategies concurrently
 type StrategyRunner struct {
-	strategies []Strategy
+	mu         sync.RWMutex
+	entries    []*strategyEntry // In the order added
+	running    bool
+	apiKey     string
+	apiSecret  string
+	baseURL    string
 	wg         sync.WaitGroup
 	logger     *log.Logger
 	ctx        context.Context
 	cancel     context.CancelFunc
+	oms        *OrderManager              // Shared order manager for all strategies
+	risk       *PortfolioRiskManager      // Pre-trade risk layer every order passes through
+	breaker    *protection.CircuitBreaker // Kill switch that halts all strategies
+	client     broker.Broker              // Account, clock and flatten calls; shared with the OMS
+	store      StateStore                 // Strategy state snapshots for restarts
+	hub        *MarketDataHub             // One market data connection for all strategies
+	exec       *ExecutionEngine           // TWAP/VWAP/POV parent orders for strategies that use them
+	statusAddr string                     // HTTP status/metrics address, empty to disable
+	startedAt  time.Time
 }
 
 // Strategy interface that all strategies must implement
 type Strategy interface {
 	Initialize(apiKey, apiSecret, baseURL string) error
 	Run(ctx context.Context) error
-	GetStatistics() map[string]interface{}
+	GetStatistics() StrategyStats
+}
+
+// OrderRouted is implemented by strategies that submit orders through an OrderManager
+type OrderRouted interface {
+	StrategyID() string
+	SetOrderManager(oms *OrderManager)
 }
 
 // NewStrategyRunner creates a new strategy runner
 func NewStrategyRunner() *StrategyRunner {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &StrategyRunner{
-		strategies: make([]Strategy, 0),
+		entries:    make([]*strategyEntry, 0),
 		logger:     log.New(log.Writer(), "[RUNNER] ", log.LstdFlags),
 		ctx:        ctx,
 		cancel:     cancel,
+		risk:       NewPortfolioRiskManager(DefaultRiskLimits()),
+		breaker:    protection.NewCircuitBreaker(runnerBreakerConfig(protection.DefaultCircuitBreakerConfig())),
+		statusAddr: statusAddrFromEnv(),
+	}
+}
+
+// breakerStateFile holds a latched trip next to the strategies' saved state
+const breakerStateFile = "circuit_breaker.trip.json"
+
+// runnerBreakerConfig persists trips in the state directory unless cfg names a file
+func runnerBreakerConfig(cfg protection.CircuitBreakerConfig) protection.CircuitBreakerConfig {
+	if cfg.StateFile == "" {
+		cfg.StateFile = filepath.Join(StateDirFromEnv(), breakerStateFile)
+	}
+	return cfg
+}
+
+// statusAddrFromEnv reads RUNNER_STATUS_ADDR ("off" disables the server)
+func statusAddrFromEnv() string {
+	addr := os.Getenv("RUNNER_STATUS_ADDR")
+	switch addr {
+	case "":
+		return DefaultStatusAddr
+	case "off":
+		return ""
 	}
+	return addr
 }
 
-// AddStrategy adds a strategy to the runner
-func (r *StrategyRunner) AddStrategy(strategy Strategy) {
-	r.strategies = append(r.strategies, strategy)
-	r.logger.Printf("Added strategy: %T", strategy)
+// DefaultRiskLimits are the portfolio-wide limits a runner starts with.
+// Exposure limits are account-size dependent and left disabled.
+func DefaultRiskLimits() RiskLimits {
+	return RiskLimits{
+		MaxOpenOrders: 20,
+	}
 }
 
 // Initialize all strategies
@@ -49,51 +104,126 @@ func (r *StrategyRunner) Initialize() error {
 	// Get API credentials from environment
 	apiKey := os.Getenv("APCA_API_KEY_ID")
 	apiSecret := os.Getenv("APCA_API_SECRET_KEY")
-	baseURL := os.Getenv("APCA_API_BASE_URL")
+	baseURL := BaseURLFromEnv() // Defaults to paper trading
 
 	if apiKey == "" || apiSecret == "" {
 		return fmt.Errorf("missing API credentials in environment")
 	}
+	r.logger.Printf("Using trading API: %s", baseURL)
+
+	// Shared OMS so every strategy's orders and positions are tracked in one place
+	if r.client == nil {
+		client, err := BrokerFromEnv(apiKey, apiSecret, baseURL)
+		if err != nil {
+			return err
+		}
+		r.client = client
+	}
+	r.oms = NewOrderManager(r.client)
+	r.oms.SetRiskManager(r.risk)
+	r.oms.SetCircuitBreaker(r.breaker)
+	r.hub = NewMarketDataHub(apiKey, apiSecret, baseURL)
+
+	// A simulated broker fills against the same market data the strategies see
+	if feed, ok := broker.FeedOf(r.client); ok {
+		r.hub.Observe(func(event MarketEvent) {
+			if simulated, ok := simEvent(event); ok {
+				feed.Apply(simulated)
+			}
+		})
+		r.logger.Printf("Trading on the in-process simulator")
+	}
+	r.exec = NewExecutionEngine(r.oms, r.hub)
+	r.exec.SetProfileSource(NewVolumeProfileLoader(marketdata.NewClient(marketdata.ClientOpts{
+		APIKey:    apiKey,
+		APISecret: apiSecret,
+	}), 20, 5*time.Minute).Profile)
 
-	if baseURL == "" {
-		baseURL = "https://paper-api.alpaca.markets" // Default to paper trading
-		r.logger.Printf("Using default paper trading URL: %s", baseURL)
+	// Strategies snapshot live state here so a restart resumes mid-trade
+	if r.store == nil {
+		store, err := NewFileStateStore(StateDirFromEnv())
+		if err != nil {
+			return fmt.Errorf("failed to open state store: %w", err)
+		}
+		r.store = store
 	}
 
+	// A trip cancels every working order; flattening is opt-in via config and
+	// goes through the OMS so each strategy's book follows its closing fills
+	r.breaker.SetActions(
+		r.client.CancelAllOrders, // Also catches orders placed outside the OMS
+		r.oms.FlattenAll,
+	)
+	r.breaker.SetMarketOpenFunc(func() bool {
+		clock, err := r.client.GetClock()
+		return err != nil || clock.IsOpen // Assume open if the clock is unreachable
+	})
+
+	// Strategies added after this point are initialized by AddStrategy itself
+	r.mu.Lock()
+	r.apiKey, r.apiSecret, r.baseURL = apiKey, apiSecret, baseURL
+	entries := append([]*strategyEntry{}, r.entries...)
+	r.mu.Unlock()
+
 	// Initialize each strategy
-	for _, strategy := range r.strategies {
-		if err := strategy.Initialize(apiKey, apiSecret, baseURL); err != nil {
-			return fmt.Errorf("failed to initialize strategy %T: %w", strategy, err)
+	for _, entry := range entries {
+		if err := r.initializeEntry(entry); err != nil {
+			return err
 		}
 	}
 
-	r.logger.Printf("Initialized %d strategies", len(r.strategies))
+	r.logger.Printf("Initialized %d strategies", len(entries))
+
+	// Books restored from each strategy's state are reconciled against the
+	// account when the OMS starts, once open orders are adopted
 	return nil
 }
 
 // Run starts all strategies concurrently
 func (r *StrategyRunner) Run() error {
-	if len(r.strategies) == 0 {
+	r.mu.RLock()
+	count := len(r.entries)
+	r.mu.RUnlock()
+	if count == 0 {
 		return fmt.Errorf("no strategies to run")
 	}
 
+	// Start order tracking before any strategy can submit
+	if r.oms != nil {
+		if err := r.oms.Start(r.ctx); err != nil {
+			r.logger.Printf("Order manager stream unavailable: %v", err)
+		}
+
+		go r.breaker.Run(r.ctx, r.accountEquity)
+		go r.breaker.WatchSignals(r.ctx)
+	}
+
+	// One market data connection; strategies subscribe their symbols as they start
+	if r.hub != nil {
+		if err := r.hub.Start(r.ctx); err != nil {
+			r.logger.Printf("Market data hub unavailable: %v", err)
+		}
+	}
+
+	// Status API and Prometheus metrics for local monitoring
+	r.startedAt = time.Now()
+	if r.statusAddr != "" {
+		if err := NewStatusServer(r, r.statusAddr).Start(r.ctx); err != nil {
+			r.logger.Printf("Status server unavailable: %v", err)
+		}
+	}
+
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Start each strategy in its own goroutine
-	for _, strategy := range r.strategies {
-		r.wg.Add(1)
-		go func(s Strategy) {
-			defer r.wg.Done()
-			
-			r.logger.Printf("Starting strategy: %T", s)
-			if err := s.Run(r.ctx); err != nil {
-				r.logger.Printf("Strategy %T error: %v", s, err)
-			}
-			r.logger.Printf("Strategy %T stopped", s)
-		}(strategy)
+	// Start each strategy in its own goroutine; strategies added from now on start immediately
+	r.mu.Lock()
+	r.running = true
+	for _, entry := range r.entries {
+		r.launchLocked(entry)
 	}
+	r.mu.Unlock()
 
 	// Start statistics reporter
 	go r.reportStatistics()
@@ -102,8 +232,11 @@ func (r *StrategyRunner) Run() error {
 	<-sigChan
 	r.logger.Println("Shutdown signal received, stopping strategies...")
 
-	// Cancel context to stop all strategies
+	// Cancel context to stop all strategies and any parent orders still working
 	r.cancel()
+	if r.exec != nil {
+		r.exec.Stop()
+	}
 
 	// Wait for all strategies to finish
 	done := make(chan struct{})
@@ -126,6 +259,101 @@ func (r *StrategyRunner) Run() error {
 	return nil
 }
 
+// RiskManager returns the portfolio risk layer, for configuring limits before Run
+func (r *StrategyRunner) RiskManager() *PortfolioRiskManager {
+	return r.risk
+}
+
+// SetStrategyRisk configures limits and netting for a strategy by its ID
+func (r *StrategyRunner) SetStrategyRisk(strategyID string, cfg StrategyRiskConfig) {
+	r.risk.SetStrategyConfig(strategyID, cfg)
+}
+
+// CircuitBreaker returns the kill switch, for configuring triggers or resetting after a trip
+func (r *StrategyRunner) CircuitBreaker() *protection.CircuitBreaker {
+	return r.breaker
+}
+
+// SetCircuitBreakerConfig replaces the kill switch configuration; call before
+// Initialize. Without a StateFile, trips persist in the state directory.
+func (r *StrategyRunner) SetCircuitBreakerConfig(cfg protection.CircuitBreakerConfig) {
+	r.breaker = protection.NewCircuitBreaker(runnerBreakerConfig(cfg))
+}
+
+// SetBroker replaces the broker every strategy trades through; call before
+// Initialize. Without one the runner uses BrokerFromEnv.
+func (r *StrategyRunner) SetBroker(b broker.Broker) {
+	r.client = b
+}
+
+// SetStateStore replaces where strategies persist live state; call before Initialize.
+// Without one the runner opens a FileStateStore in STRATEGY_STATE_DIR.
+func (r *StrategyRunner) SetStateStore(store StateStore) {
+	r.store = store
+}
+
+// accountEquity reads realized + unrealized equity for the loss triggers
+func (r *StrategyRunner) accountEquity() (decimal.Decimal, error) {
+	account, err := r.client.GetAccount()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return account.Equity, nil
+}
+
+// SetStatusAddr changes the status server address before Run; empty disables it
+func (r *StrategyRunner) SetStatusAddr(addr string) {
+	r.statusAddr = addr
+}
+
+// Status summarizes the runner for the status API
+func (r *StrategyRunner) Status() map[string]interface{} {
+	r.mu.RLock()
+	running := r.running
+	lifecycles := make(map[string]int)
+	for _, entry := range r.entries {
+		lifecycles[entry.state]++
+	}
+	count := len(r.entries)
+	r.mu.RUnlock()
+
+	var marketData []SubscriptionStats
+	if r.hub != nil {
+		marketData = r.hub.Stats()
+	}
+
+	uptime := 0.0
+	if !r.startedAt.IsZero() {
+		uptime = time.Since(r.startedAt).Seconds()
+	}
+
+	return map[string]interface{}{
+		"running":         running,
+		"started_at":      r.startedAt,
+		"uptime_seconds":  uptime,
+		"strategies":      count,
+		"lifecycles":      lifecycles,
+		"portfolio_risk":  r.risk.GetStatistics(),
+		"circuit_breaker": r.breaker.GetStatus(),
+		"market_data":     marketData,
+	}
+}
+
+// OrderManager returns the shared OMS (nil until Initialize)
+func (r *StrategyRunner) OrderManager() *OrderManager {
+	return r.oms
+}
+
+// ExecutionEngine returns the shared execution algo engine (nil until Initialize)
+func (r *StrategyRunner) ExecutionEngine() *ExecutionEngine {
+	return r.exec
+}
+
+// MarketDataHub returns the shared market data hub (nil until Initialize)
+func (r *StrategyRunner) MarketDataHub() *MarketDataHub {
+	return r.hub
+}
+
 // reportStatistics periodically logs strategy performance
 func (r *StrategyRunner) reportStatistics() {
 	ticker := time.NewTicker(5 * time.Minute)
@@ -137,10 +365,11 @@ func (r *StrategyRunner) reportStatistics() {
 			return
 		case <-ticker.C:
 			r.logger.Println("=== Strategy Performance Report ===")
-			for _, strategy := range r.strategies {
-				stats := strategy.GetStatistics()
-				r.logger.Printf("%s: %+v", stats["strategy"], stats)
+			for _, stats := range r.GetStatistics() {
+				r.logger.Printf("%s: %+v", stats.Strategy, stats)
 			}
+			r.logger.Printf("Portfolio risk: %+v", r.risk.GetStatistics())
+			r.logger.Printf("Circuit breaker: %+v", r.breaker.GetStatus())
 			r.logger.Println("===================================")
 		}
 	}
@@ -150,36 +379,29 @@ func (r *StrategyRunner) reportStatistics() {
 func (r *StrategyRunner) printFinalStatistics() {
 	r.logger.Println("\n=== FINAL STRATEGY PERFORMANCE ===")
 	
-	totalTrades := 0
-	totalWins := 0
-	totalPnL := 0.0
-
-	for _, strategy := range r.strategies {
-		stats := strategy.GetStatistics()
-		
-		r.logger.Printf("\n%s (%s):", stats["strategy"], stats["symbol"])
-		r.logger.Printf("  Trades: %v", stats["trades"])
-		r.logger.Printf("  Win Rate: %.2f%%", stats["win_rate"])
-		r.logger.Printf("  Total P&L: $%.2f", stats["total_pnl"])
-		
-		// Aggregate statistics
-		if trades, ok := stats["trades"].(int); ok {
-			totalTrades += trades
-		}
-		if wins, ok := stats["wins"].(int); ok {
-			totalWins += wins
-		}
-		if pnl, ok := stats["total_pnl"].(float64); ok {
-			totalPnL += pnl
-		}
+	allStats := r.GetStatistics()
+	for _, stats := range allStats {
+		r.logger.Printf("\n%s (%s):", stats.Strategy, stats.Symbol())
+		r.logger.Printf("  State: %s", stats.Lifecycle)
+		r.logger.Printf("  Trades: %d", stats.Trades)
+		r.logger.Printf("  Win Rate: %.2f%%", stats.WinRate)
+		r.logger.Printf("  Total P&L: $%.2f", stats.TotalPnL)
+		r.logger.Printf("  Exposure: $%.2f gross, $%.2f net", stats.Exposure.Gross, stats.Exposure.Net)
 	}
 
+	total := AggregateStats(allStats)
+
 	r.logger.Println("\n=== AGGREGATE PERFORMANCE ===")
-	r.logger.Printf("Total Strategies: %d", len(r.strategies))
-	r.logger.Printf("Total Trades: %d", totalTrades)
-	if totalTrades > 0 {
-		r.logger.Printf("Overall Win Rate: %.2f%%", float64(totalWins)/float64(totalTrades)*100)
+	r.logger.Printf("Total Strategies: %d", len(allStats))
+	r.logger.Printf("Total Trades: %d", total.Trades)
+	if total.Trades > 0 {
+		r.logger.Printf("Overall Win Rate: %.2f%%", total.WinRate)
+	}
+	r.logger.Printf("Total P&L: $%.2f", total.TotalPnL)
+	r.logger.Printf("Gross Exposure: $%.2f", total.Exposure.Gross)
+	r.logger.Printf("Risk Rejections: %d", r.risk.Rejections(""))
+	if status := r.breaker.GetStatus(); status["tripped"] == true {
+		r.logger.Printf("Circuit Breaker: TRIPPED (%s: %s)", status["trip_reason"], status["trip_detail"])
 	}
-	r.logger.Printf("Total P&L: $%.2f", totalPnL)
 	r.logger.Println("===============================")
 }
\ No newline at end of file
@@ -11,6 +11,9 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/shopspring/decimal"
+
+	"zig-financial-engine/internal/broker"
+	"zig-financial-engine/internal/indicators"
 )
 
 // VWAPIntradayStrategy implements mean reversion around Volume-Weighted Average Price
@@ -27,14 +30,17 @@ type VWAPIntradayStrategy struct {
 	MaxPositions  int     // Max intraday positions (risk management)
 
 	// Alpaca clients
-	tradingClient *alpaca.Client
+	tradingClient broker.Broker
 	dataClient    *marketdata.Client
 
+	// Order routing through the shared OMS
+	orderRouting
+
 	// State management
 	mu            sync.RWMutex
 	vwap          float64
-	cumulativePV  float64 // Cumulative Price*Volume
-	cumulativeVol float64 // Cumulative Volume
+	sessionVWAP   *indicators.VWAP // Cumulative over the session
+	volumeMA      *indicators.SMA  // Recent average volume
 	dayHighPrice  float64
 	dayLowPrice   float64
 	avgVolume     float64
@@ -78,18 +84,21 @@ func NewVWAPIntradayStrategy(symbol string, timeFrame marketdata.TimeFrame) *VWA
 		MinVolume:     10000,  // Minimum volume threshold
 		MaxPositions:  3,      // Max 3 positions per day
 		intradayBars:  make([]IntradayBar, 0, 390), // ~390 minutes in regular session
+		sessionVWAP:   indicators.NewVWAP(0),
+		volumeMA:      indicators.NewSMA(20),
 		logger:        log.New(log.Writer(), "[VWAP-INTRADAY] ", log.LstdFlags),
+		orderRouting:  orderRouting{strategyID: "vwap_" + symbol},
 	}
 }
 
 // Initialize sets up the Alpaca clients and prepares for trading
 func (s *VWAPIntradayStrategy) Initialize(apiKey, apiSecret, baseURL string) error {
-	// Initialize Alpaca trading client
-	s.tradingClient = alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		BaseURL:   baseURL,
-	})
+	// Trade through the shared OMS's broker, or an Alpaca account when standalone
+	s.tradingClient = s.ensureOrderManager(apiKey, apiSecret, baseURL)
+	s.ensureMarketDataHub(apiKey, apiSecret, baseURL)
+	if err := s.ensureStateStore(); err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
+	}
 
 	// Initialize market data client
 	s.dataClient = marketdata.NewClient(marketdata.ClientOpts{
@@ -105,11 +114,15 @@ func (s *VWAPIntradayStrategy) Initialize(apiKey, apiSecret, baseURL string) err
 		return fmt.Errorf("failed to initialize VWAP: %w", err)
 	}
 
-	// Check current position
-	if err := s.syncPosition(); err != nil {
-		return fmt.Errorf("failed to sync position: %w", err)
+	// Resume position, counters and trade context from before a restart
+	if err := s.restoreState(); err != nil {
+		return fmt.Errorf("failed to restore state: %w", err)
 	}
 
+	// Position and P&L follow this strategy's own fills; re-read its book after stream gaps
+	s.oms.Subscribe(s.strategyID, s.onFill)
+	s.oms.OnReconnect(s.strategyID, s.syncPosition)
+
 	s.logger.Printf("VWAP Intraday strategy initialized for %s (TimeFrame: %v, Deviation: %.2f%%)",
 		s.Symbol, s.TimeFrame, s.VWAPDeviation*100)
 	return nil
@@ -163,8 +176,8 @@ func (s *VWAPIntradayStrategy) initializeVWAP() error {
 	defer s.mu.Unlock()
 
 	// Reset VWAP calculation
-	s.cumulativePV = 0
-	s.cumulativeVol = 0
+	s.sessionVWAP.Reset()
+	s.volumeMA.Reset()
 	s.intradayBars = []IntradayBar{}
 	s.dayHighPrice = 0
 	s.dayLowPrice = math.MaxFloat64
@@ -172,11 +185,9 @@ func (s *VWAPIntradayStrategy) initializeVWAP() error {
 	// Process bars to calculate VWAP
 	totalVolume := 0.0
 	for _, bar := range bars {
-		typicalPrice := (bar.High + bar.Low + bar.Close) / 3
 		volume := float64(bar.Volume)
-		
-		s.cumulativePV += typicalPrice * volume
-		s.cumulativeVol += volume
+		s.sessionVWAP.Update(indicators.TypicalPrice(bar.High, bar.Low, bar.Close), volume)
+		s.volumeMA.Update(volume)
 		totalVolume += volume
 		
 		// Track day high/low
@@ -198,8 +209,8 @@ func (s *VWAPIntradayStrategy) initializeVWAP() error {
 	}
 
 	// Calculate VWAP
-	if s.cumulativeVol > 0 {
-		s.vwap = s.cumulativePV / s.cumulativeVol
+	if s.sessionVWAP.Ready() {
+		s.vwap = s.sessionVWAP.Value()
 		s.avgVolume = totalVolume / float64(len(bars))
 	}
 
@@ -208,28 +219,117 @@ func (s *VWAPIntradayStrategy) initializeVWAP() error {
 	return nil
 }
 
-// syncPosition checks if we have an existing position
-func (s *VWAPIntradayStrategy) syncPosition() error {
-	positions, err := s.tradingClient.GetPositions()
-	if err != nil {
-		return err
+// syncPosition loads the strategy's position from its own OMS book
+func (s *VWAPIntradayStrategy) syncPosition() {
+	qty, entryPrice := s.bookPosition(s.Symbol)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.positionQty = qty
+	s.entryPrice = entryPrice
+	s.hasPosition = qty != 0
+}
+
+// onFill updates position and P&L from an actual execution, including
+// partial fills and bracket stop/target exits
+func (s *VWAPIntradayStrategy) onFill(fill FillEvent) {
+	if fill.Symbol != s.Symbol {
+		return
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.hasPosition = false
-	for _, pos := range positions {
-		if pos.Symbol == s.Symbol {
-			s.hasPosition = true
-			s.positionQty = pos.Qty.BigInt().Int64()
-			avgPrice, _ := pos.AvgEntryPrice.Float64()
-			s.entryPrice = avgPrice
-			s.logger.Printf("Found existing position: %d shares at %.2f", s.positionQty, s.entryPrice)
-			break
+	wasFlat := !s.hasPosition
+	s.positionQty = fill.Position.Qty.IntPart()
+	s.entryPrice = fill.Position.AvgEntryPrice.InexactFloat64()
+	s.hasPosition = s.positionQty != 0
+
+	pnl := fill.RealizedPnL.InexactFloat64()
+	s.totalPnL += pnl
+	if wasFlat && s.hasPosition {
+		s.currentDrawdown = 0 // Reset on new position
+	}
+	if fill.TripClosed {
+		s.tradeCount++
+		if fill.TripPnL.IsPositive() {
+			s.winCount++
+		} else {
+			// Track drawdown
+			s.currentDrawdown += math.Abs(fill.TripPnL.InexactFloat64())
+			if s.currentDrawdown > s.maxDrawdown {
+				s.maxDrawdown = s.currentDrawdown
+			}
 		}
 	}
 
+	s.saveStateLocked()
+
+	s.logger.Printf("%s %s %s @ %s (position %d @ %.2f, realized %.2f)",
+		fill.Event, fill.Side, fill.Qty.String(), fill.Price.StringFixed(2),
+		s.positionQty, s.entryPrice, pnl)
+}
+
+// vwapState is the live state persisted across restarts
+type vwapState struct {
+	positionState
+	TradingDay      string  `json:"trading_day"` // Day trades only carry over within a day
+	DayTrades       int     `json:"day_trades"`
+	MaxDrawdown     float64 `json:"max_drawdown"`
+	CurrentDrawdown float64 `json:"current_drawdown"`
+}
+
+// saveStateLocked snapshots live state to the store; caller must hold s.mu
+func (s *VWAPIntradayStrategy) saveStateLocked() {
+	state := vwapState{
+		positionState: positionState{
+			PositionQty: s.positionQty,
+			EntryPrice:  s.entryPrice,
+			LastSignal:  s.lastSignal,
+			Trades:      s.tradeCount,
+			Wins:        s.winCount,
+			TotalPnL:    s.totalPnL,
+		},
+		TradingDay:      time.Now().Format("2006-01-02"),
+		DayTrades:       s.dayTrades,
+		MaxDrawdown:     s.maxDrawdown,
+		CurrentDrawdown: s.currentDrawdown,
+	}
+	if err := s.saveState(state); err != nil {
+		s.logger.Printf("Failed to save state: %v", err)
+	}
+}
+
+// restoreState reloads state saved before a restart, including the position,
+// which seeds this strategy's OMS book
+func (s *VWAPIntradayStrategy) restoreState() error {
+	var saved vwapState
+	found, err := s.loadState(&saved)
+	if err != nil || !found {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tradeCount = saved.Trades
+	s.winCount = saved.Wins
+	s.totalPnL = saved.TotalPnL
+	s.maxDrawdown = saved.MaxDrawdown
+	s.currentDrawdown = saved.CurrentDrawdown
+	if saved.TradingDay == time.Now().Format("2006-01-02") {
+		s.dayTrades = saved.DayTrades
+	}
+	s.positionQty = saved.PositionQty
+	s.entryPrice = saved.EntryPrice
+	s.hasPosition = saved.PositionQty != 0
+	s.lastSignal = saved.LastSignal
+	s.restorePosition(s.Symbol, saved.PositionQty, saved.EntryPrice)
+
+	s.logger.Printf("Restored state: position %d at %.2f, %d trades, %d wins, P&L %.2f",
+		s.positionQty, s.entryPrice, s.tradeCount, s.winCount, s.totalPnL)
+	s.saveStateLocked()
 	return nil
 }
 
@@ -237,17 +337,14 @@ func (s *VWAPIntradayStrategy) syncPosition() error {
 func (s *VWAPIntradayStrategy) ProcessBar(price, volume float64, high, low float64, timestamp time.Time) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.markData()
 
 	// Check if we need to reset for new trading day
 	s.checkDayReset(timestamp)
 
 	// Update VWAP calculation
-	typicalPrice := (high + low + price) / 3
-	s.cumulativePV += typicalPrice * volume
-	s.cumulativeVol += volume
-	
-	if s.cumulativeVol > 0 {
-		s.vwap = s.cumulativePV / s.cumulativeVol
+	if vwap := s.sessionVWAP.Update(indicators.TypicalPrice(high, low, price), volume); s.sessionVWAP.Ready() {
+		s.vwap = vwap
 	}
 
 	// Update day high/low
@@ -268,12 +365,8 @@ func (s *VWAPIntradayStrategy) ProcessBar(price, volume float64, high, low float
 	})
 
 	// Update average volume
-	if len(s.intradayBars) > 20 {
-		recentVol := 0.0
-		for i := len(s.intradayBars) - 20; i < len(s.intradayBars); i++ {
-			recentVol += s.intradayBars[i].Volume
-		}
-		s.avgVolume = recentVol / 20
+	if avg := s.volumeMA.Update(volume); s.volumeMA.Ready() {
+		s.avgVolume = avg
 	}
 
 	// Generate signal
@@ -304,8 +397,8 @@ func (s *VWAPIntradayStrategy) checkDayReset(timestamp time.Time) {
 	
 	if shouldReset {
 		s.logger.Printf("New trading day detected, resetting VWAP")
-		s.cumulativePV = 0
-		s.cumulativeVol = 0
+		s.sessionVWAP.Reset()
+		s.volumeMA.Reset()
 		s.vwap = 0
 		s.dayHighPrice = 0
 		s.dayLowPrice = math.MaxFloat64
@@ -318,7 +411,7 @@ func (s *VWAPIntradayStrategy) checkDayReset(timestamp time.Time) {
 // generateSignal determines if we should buy/sell based on VWAP levels
 func (s *VWAPIntradayStrategy) generateSignal(price, volume float64) string {
 	// Need valid VWAP
-	if s.vwap == 0 || s.cumulativeVol < s.MinVolume {
+	if s.vwap == 0 || s.sessionVWAP.Volume() < s.MinVolume {
 		return ""
 	}
 
@@ -388,6 +481,12 @@ func (s *VWAPIntradayStrategy) generateSignal(price, volume float64) string {
 
 // executeTrade places orders based on signals
 func (s *VWAPIntradayStrategy) executeTrade(signal string, currentPrice float64) {
+	// Don't stack signals on an order that hasn't filled yet
+	if s.hasWorkingOrder(s.Symbol) {
+		s.logger.Printf("Order already working for %s, skipping %s", s.Symbol, signal)
+		return
+	}
+
 	// Get account info for position sizing
 	account, err := s.tradingClient.GetAccount()
 	if err != nil {
@@ -460,48 +559,30 @@ func (s *VWAPIntradayStrategy) executeTrade(signal string, currentPrice float64)
 			TimeInForce: alpaca.IOC, // Immediate or cancel for quick exit
 		}
 
-		// Calculate P&L
+		// Estimate only: realized P&L is booked in onFill from the execution
 		pnl := (currentPrice - s.entryPrice) * float64(s.positionQty)
-		s.totalPnL += pnl
-		if pnl > 0 {
-			s.winCount++
-		} else {
-			// Track drawdown
-			s.currentDrawdown += math.Abs(pnl)
-			if s.currentDrawdown > s.maxDrawdown {
-				s.maxDrawdown = s.currentDrawdown
-			}
-		}
-		s.tradeCount++
 
-		s.logger.Printf("Placing SELL order: %d shares, entry=%.2f, exit=%.2f, P&L=%.2f",
+		s.logger.Printf("Placing SELL order: %d shares, entry=%.2f, exit=%.2f, est. P&L=%.2f",
 			s.positionQty, s.entryPrice, currentPrice, pnl)
 	}
 
 	// Submit order
-	order, err := s.tradingClient.PlaceOrder(orderReq)
+	order, err := s.submitOrder(signal, orderReq, currentPrice)
 	if err != nil {
 		s.logger.Printf("Order failed: %v", err)
 		return
 	}
 
-	// Update state
+	// Position state is updated from fills (see onFill)
 	s.mu.Lock()
 	if signal == "BUY" {
-		s.hasPosition = true
-		s.entryPrice = currentPrice
-		s.positionQty = qty
 		s.dayTrades++
-		s.currentDrawdown = 0 // Reset on new position
-	} else if signal == "SELL" {
-		s.hasPosition = false
-		s.entryPrice = 0
-		s.positionQty = 0
 	}
 	s.lastSignal = signal
+	s.saveStateLocked()
 	s.mu.Unlock()
 
-	s.logger.Printf("Order placed successfully: %s", order.ID)
+	s.logger.Printf("Order placed successfully: %s", order.ClientOrderID)
 }
 
 // validateTradeCompliance checks PDT rules for stock trading
@@ -531,49 +612,61 @@ func (s *VWAPIntradayStrategy) validateTradeCompliance(account *alpaca.Account,
 	return true
 }
 
+// SetParameters updates configuration fields while the strategy runs
+func (s *VWAPIntradayStrategy) SetParameters(params map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return setParameters(s, params, func() error {
+		if s.VWAPDeviation <= 0 || s.MaxPositions < 1 {
+			return fmt.Errorf("vwap deviation and max positions must be positive")
+		}
+		if s.PositionSize <= 0 || s.PositionSize > 1 {
+			return fmt.Errorf("position size must be a fraction of equity in (0, 1]")
+		}
+		return nil
+	}, "Symbol")
+}
+
 // GetStatistics returns strategy performance metrics
-func (s *VWAPIntradayStrategy) GetStatistics() map[string]interface{} {
+func (s *VWAPIntradayStrategy) GetStatistics() StrategyStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	winRate := 0.0
-	avgTrade := 0.0
-	if s.tradeCount > 0 {
-		winRate = float64(s.winCount) / float64(s.tradeCount) * 100
-		avgTrade = s.totalPnL / float64(s.tradeCount)
-	}
-
 	profitFactor := 0.0
 	if s.maxDrawdown > 0 {
 		profitFactor = math.Max(0, s.totalPnL) / s.maxDrawdown
 	}
 
-	return map[string]interface{}{
-		"symbol":         s.Symbol,
-		"strategy":       "VWAP Intraday",
-		"timeframe":      s.TimeFrame,
-		"trades":         s.tradeCount,
-		"wins":           s.winCount,
-		"win_rate":       winRate,
-		"total_pnl":      s.totalPnL,
-		"avg_trade_pnl":  avgTrade,
-		"max_drawdown":   s.maxDrawdown,
-		"profit_factor":  profitFactor,
-		"current_vwap":   s.vwap,
-		"day_trades":     s.dayTrades,
-		"has_position":   s.hasPosition,
+	var marks map[string]float64
+	if len(s.intradayBars) > 0 {
+		marks = map[string]float64{s.Symbol: s.intradayBars[len(s.intradayBars)-1].Price}
 	}
+
+	stats := s.newStats("VWAP Intraday", []string{s.Symbol}, s.tradeCount, s.winCount, s.totalPnL, marks)
+	stats.warmingUp(!s.sessionVWAP.Ready())
+	stats.Extra["timeframe"] = s.TimeFrame.String()
+	stats.Extra["max_drawdown"] = s.maxDrawdown
+	stats.Extra["profit_factor"] = profitFactor
+	stats.Extra["current_vwap"] = s.vwap
+	stats.Extra["day_trades"] = s.dayTrades
+	stats.Extra["has_position"] = s.hasPosition
+	return stats
 }
 
 // Run starts the strategy main loop with WebSocket streaming
 func (s *VWAPIntradayStrategy) Run(ctx context.Context) error {
-	// Create WebSocket connection for real-time bars
-	// Temporary polling approach - WebSocket implementation follows
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
-	
-	// WebSocket streaming temporarily disabled for compilation
-	// Will implement proper Alpaca WebSocket streaming next
+	// Start order tracking (no-op if the runner already started the shared OMS)
+	if err := s.oms.Start(ctx); err != nil {
+		s.logger.Printf("Order updates unavailable: %v", err)
+	}
+
+	// Bars arrive from the shared market data hub
+	bars, err := s.subscribeBars(ctx, s.Symbol)
+	if err != nil {
+		return err
+	}
+	defer bars.Close()
 
 	s.logger.Printf("VWAP Intraday strategy running for %s", s.Symbol)
 
@@ -592,6 +685,13 @@ func (s *VWAPIntradayStrategy) Run(ctx context.Context) error {
 			stats := s.GetStatistics()
 			s.logger.Printf("Final Statistics: %+v", stats)
 			return nil
+
+		case event := <-bars.Events():
+			if event.Cached {
+				continue // Last-value replay of a bar already in history or processed
+			}
+			bar := event.Bar
+			s.ProcessBar(bar.Close, float64(bar.Volume), bar.High, bar.Low, bar.Timestamp)
 			
 		case <-statsTicker.C:
 			// Print periodic statistics
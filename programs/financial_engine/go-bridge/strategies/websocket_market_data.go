@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -18,12 +19,24 @@ type MarketDataStream struct {
 	conn      *websocket.Conn
 	logger    *log.Logger
 	
+	// Connection state
+	mu      sync.Mutex
+	writeMu sync.Mutex // Websocket writes must not be concurrent
+	closed  bool       // Set by Disconnect to stop reconnect attempts
+	
 	// Callbacks
-	onBar   func(MarketBar)
-	onTrade func(Trade)
-	onQuote func(Quote)
+	onBar       func(MarketBar)
+	onTrade     func(Trade)
+	onQuote     func(Quote)
+	onReconnect func()
 }
 
+// Reconnect backoff bounds
+const (
+	marketStreamMinBackoff = time.Second
+	marketStreamMaxBackoff = 30 * time.Second
+)
+
 // MarketBar represents a market data bar from WebSocket
 type MarketBar struct {
 	Type      string    `json:"T"`
@@ -113,29 +126,53 @@ func (m *MarketDataStream) SetQuoteHandler(handler func(Quote)) {
 	m.onQuote = handler
 }
 
+// SetReconnectHandler sets a callback invoked after the stream reconnects.
+// Subscriptions do not survive a reconnect, so handlers should resubscribe.
+func (m *MarketDataStream) SetReconnectHandler(handler func()) {
+	m.onReconnect = handler
+}
+
 // Connect establishes WebSocket connection to Alpaca market data stream
 func (m *MarketDataStream) Connect(ctx context.Context) error {
-	// Use SIP feed for production, IEX for testing
-	streamURL := "wss://stream.data.alpaca.markets/v2/sip"
-	if m.BaseURL == "https://paper-api.alpaca.markets" {
-		streamURL = "wss://stream.data.alpaca.markets/v2/iex"
+	m.mu.Lock()
+	m.closed = false
+	m.mu.Unlock()
+
+	if err := m.dial(); err != nil {
+		return err
 	}
 
+	// Start message handler
+	go m.handleMessages(ctx)
+
+	return nil
+}
+
+// dial opens and authenticates a new connection
+func (m *MarketDataStream) dial() error {
+	// Use SIP feed for production, IEX for testing
+	streamURL := DataStreamURL(m.BaseURL)
+
 	m.logger.Printf("Connecting to market data stream: %s", streamURL)
 	
-	var err error
-	m.conn, _, err = websocket.DefaultDialer.Dial(streamURL, nil)
+	conn, _, err := websocket.DefaultDialer.Dial(streamURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
 
+	m.mu.Lock()
+	m.conn = conn
+	m.mu.Unlock()
+
 	// Wait for welcome message
 	var welcome []ResponseMessage
-	if err := m.conn.ReadJSON(&welcome); err != nil {
+	if err := conn.ReadJSON(&welcome); err != nil {
+		conn.Close()
 		return fmt.Errorf("failed to read welcome message: %w", err)
 	}
 
 	if len(welcome) == 0 || welcome[0].Type != "success" {
+		conn.Close()
 		return fmt.Errorf("unexpected welcome message: %+v", welcome)
 	}
 
@@ -143,15 +180,72 @@ func (m *MarketDataStream) Connect(ctx context.Context) error {
 
 	// Authenticate
 	if err := m.authenticate(); err != nil {
+		conn.Close()
 		return fmt.Errorf("authentication failed: %w", err)
 	}
 
-	// Start message handler
-	go m.handleMessages(ctx)
-
 	return nil
 }
 
+// reconnect re-dials with exponential backoff until it succeeds, the context
+// is canceled or Disconnect is called. Returns false if the stream should stop.
+func (m *MarketDataStream) reconnect(ctx context.Context) bool {
+	backoff := marketStreamMinBackoff
+
+	for {
+		if m.isClosed() {
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+
+		if m.isClosed() {
+			return false
+		}
+
+		if err := m.dial(); err != nil {
+			m.logger.Printf("Reconnect failed: %v (retrying in %v)", err, backoff)
+			backoff *= 2
+			if backoff > marketStreamMaxBackoff {
+				backoff = marketStreamMaxBackoff
+			}
+			continue
+		}
+
+		marketDataCounters.reconnect()
+		m.logger.Printf("Reconnected to market data stream")
+		if m.onReconnect != nil {
+			go m.onReconnect()
+		}
+		return true
+	}
+}
+
+// isClosed reports whether Disconnect has been called
+func (m *MarketDataStream) isClosed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.closed
+}
+
+// writeJSON sends a control message on the current connection
+func (m *MarketDataStream) writeJSON(v interface{}) error {
+	m.mu.Lock()
+	conn := m.conn
+	m.mu.Unlock()
+	if conn == nil {
+		return fmt.Errorf("market data stream is not connected")
+	}
+
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	return conn.WriteJSON(v)
+}
+
 // authenticate sends authentication message
 func (m *MarketDataStream) authenticate() error {
 	authMsg := AuthMessage{
@@ -160,7 +254,7 @@ func (m *MarketDataStream) authenticate() error {
 		Secret: m.APISecret,
 	}
 
-	if err := m.conn.WriteJSON(authMsg); err != nil {
+	if err := m.writeJSON(authMsg); err != nil {
 		return err
 	}
 
@@ -187,7 +281,7 @@ func (m *MarketDataStream) Subscribe(symbols []string) error {
 		Quotes: symbols,
 	}
 
-	if err := m.conn.WriteJSON(subMsg); err != nil {
+	if err := m.writeJSON(subMsg); err != nil {
 		return err
 	}
 
@@ -202,7 +296,7 @@ func (m *MarketDataStream) SubscribeBars(symbols []string) error {
 		Bars:   symbols,
 	}
 
-	if err := m.conn.WriteJSON(subMsg); err != nil {
+	if err := m.writeJSON(subMsg); err != nil {
 		return err
 	}
 
@@ -210,6 +304,27 @@ func (m *MarketDataStream) SubscribeBars(symbols []string) error {
 	return nil
 }
 
+// UpdateSubscription subscribes or unsubscribes (action "subscribe" or
+// "unsubscribe") bars, trades and quotes per symbol list
+func (m *MarketDataStream) UpdateSubscription(action string, bars, trades, quotes []string) error {
+	if len(bars) == 0 && len(trades) == 0 && len(quotes) == 0 {
+		return nil
+	}
+
+	msg := SubscribeMessage{
+		Action: action,
+		Bars:   bars,
+		Trades: trades,
+		Quotes: quotes,
+	}
+	if err := m.writeJSON(msg); err != nil {
+		return err
+	}
+
+	m.logger.Printf("%s bars: %v, trades: %v, quotes: %v", action, bars, trades, quotes)
+	return nil
+}
+
 // handleMessages handles incoming WebSocket messages
 func (m *MarketDataStream) handleMessages(ctx context.Context) {
 	for {
@@ -219,7 +334,17 @@ func (m *MarketDataStream) handleMessages(ctx context.Context) {
 		default:
 			var messages []json.RawMessage
 			if err := m.conn.ReadJSON(&messages); err != nil {
+				if ctx.Err() != nil || m.isClosed() {
+					return
+				}
+				marketDataCounters.readError()
 				m.logger.Printf("Error reading message: %v", err)
+
+				// A failed read leaves the connection unusable; re-dial
+				m.conn.Close()
+				if !m.reconnect(ctx) {
+					return
+				}
 				continue
 			}
 
@@ -241,6 +366,7 @@ func (m *MarketDataStream) processMessage(rawMsg json.RawMessage) {
 		m.logger.Printf("Error parsing message type: %v", err)
 		return
 	}
+	marketDataCounters.message(typeMsg.Type)
 
 	switch typeMsg.Type {
 	case "b": // Minute bar
@@ -292,6 +418,10 @@ func (m *MarketDataStream) processMessage(rawMsg json.RawMessage) {
 
 // Disconnect closes the WebSocket connection
 func (m *MarketDataStream) Disconnect() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
 	if m.conn != nil {
 		return m.conn.Close()
 	}
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+
+	"zig-financial-engine/internal/broker"
 )
 
 // TradeUpdatesStream handles WebSocket connections for Alpaca trade updates
@@ -18,57 +21,26 @@ type TradeUpdatesStream struct {
 	conn      *websocket.Conn
 	logger    *log.Logger
 	
-	// Callback
+	// Connection state
+	mu     sync.Mutex
+	closed bool // Set by Disconnect to stop reconnect attempts
+	
+	// Callbacks
 	onTradeUpdate func(TradeUpdate)
+	onReconnect   func()
 }
 
+// Reconnect backoff bounds
+const (
+	tradeStreamMinBackoff = time.Second
+	tradeStreamMaxBackoff = 30 * time.Second
+)
+
 // TradeUpdate represents a trade update message
-type TradeUpdate struct {
-	Stream string `json:"stream"`
-	Data   struct {
-		Event       string    `json:"event"`
-		ExecutionID string    `json:"execution_id,omitempty"`
-		Order       Order     `json:"order"`
-		Timestamp   time.Time `json:"timestamp,omitempty"`
-		Price       string    `json:"price,omitempty"`
-		Qty         string    `json:"qty,omitempty"`
-		PositionQty string    `json:"position_qty,omitempty"`
-	} `json:"data"`
-}
+type TradeUpdate = broker.TradeUpdate
 
 // Order represents an order in trade updates
-type Order struct {
-	ID              string    `json:"id"`
-	ClientOrderID   string    `json:"client_order_id"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
-	SubmittedAt     time.Time `json:"submitted_at"`
-	FilledAt        *time.Time `json:"filled_at"`
-	ExpiredAt       *time.Time `json:"expired_at"`
-	CanceledAt      *time.Time `json:"canceled_at"`
-	FailedAt        *time.Time `json:"failed_at"`
-	ReplacedAt      *time.Time `json:"replaced_at"`
-	AssetID         string    `json:"asset_id"`
-	Symbol          string    `json:"symbol"`
-	AssetClass      string    `json:"asset_class"`
-	Notional        *string   `json:"notional"`
-	Qty             string    `json:"qty"`
-	FilledQty       string    `json:"filled_qty"`
-	FilledAvgPrice  *string   `json:"filled_avg_price"`
-	OrderClass      string    `json:"order_class"`
-	OrderType       string    `json:"order_type"`
-	Type            string    `json:"type"`
-	Side            string    `json:"side"`
-	TimeInForce     string    `json:"time_in_force"`
-	LimitPrice      *string   `json:"limit_price"`
-	StopPrice       *string   `json:"stop_price"`
-	Status          string    `json:"status"`
-	ExtendedHours   bool      `json:"extended_hours"`
-	Legs            []Order   `json:"legs"`
-	TrailPercent    *string   `json:"trail_percent"`
-	TrailPrice      *string   `json:"trail_price"`
-	Hwm             *string   `json:"hwm"`
-}
+type Order = broker.Order
 
 // TradeAuthMessage represents the authentication message for trade updates
 type TradeAuthMessage struct {
@@ -110,38 +82,104 @@ func (t *TradeUpdatesStream) SetTradeUpdateHandler(handler func(TradeUpdate)) {
 	t.onTradeUpdate = handler
 }
 
+// SetReconnectHandler sets a callback invoked after the stream reconnects.
+// Updates may have been missed while disconnected, so handlers should re-sync state.
+func (t *TradeUpdatesStream) SetReconnectHandler(handler func()) {
+	t.onReconnect = handler
+}
+
 // Connect establishes WebSocket connection to Alpaca trade updates stream
 func (t *TradeUpdatesStream) Connect(ctx context.Context) error {
-	// Use paper or live trading API based on baseURL
-	streamURL := "wss://api.alpaca.markets/stream"
-	if t.BaseURL == "https://paper-api.alpaca.markets" {
-		streamURL = "wss://paper-api.alpaca.markets/stream"
+	t.mu.Lock()
+	t.closed = false
+	t.mu.Unlock()
+
+	if err := t.dial(); err != nil {
+		return err
 	}
 
+	// Start message handler
+	go t.handleMessages(ctx)
+
+	return nil
+}
+
+// dial opens, authenticates and subscribes a new connection
+func (t *TradeUpdatesStream) dial() error {
+	// The stream is served by the same host as the trading API
+	streamURL := TradeStreamURL(t.BaseURL)
+
 	t.logger.Printf("Connecting to trade updates stream: %s", streamURL)
 	
-	var err error
-	t.conn, _, err = websocket.DefaultDialer.Dial(streamURL, nil)
+	conn, _, err := websocket.DefaultDialer.Dial(streamURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
 
+	t.mu.Lock()
+	t.conn = conn
+	t.mu.Unlock()
+
 	// Authenticate immediately
 	if err := t.authenticate(); err != nil {
+		conn.Close()
 		return fmt.Errorf("authentication failed: %w", err)
 	}
 
 	// Subscribe to trade updates
 	if err := t.subscribe(); err != nil {
+		conn.Close()
 		return fmt.Errorf("subscription failed: %w", err)
 	}
 
-	// Start message handler
-	go t.handleMessages(ctx)
-
 	return nil
 }
 
+// reconnect re-dials with exponential backoff until it succeeds, the context
+// is canceled or Disconnect is called. Returns false if the stream should stop.
+func (t *TradeUpdatesStream) reconnect(ctx context.Context) bool {
+	backoff := tradeStreamMinBackoff
+
+	for {
+		if t.isClosed() {
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+
+		if t.isClosed() {
+			return false
+		}
+
+		if err := t.dial(); err != nil {
+			t.logger.Printf("Reconnect failed: %v (retrying in %v)", err, backoff)
+			backoff *= 2
+			if backoff > tradeStreamMaxBackoff {
+				backoff = tradeStreamMaxBackoff
+			}
+			continue
+		}
+
+		tradeUpdatesCounters.reconnect()
+		t.logger.Printf("Reconnected to trade updates stream")
+		if t.onReconnect != nil {
+			go t.onReconnect()
+		}
+		return true
+	}
+}
+
+// isClosed reports whether Disconnect has been called
+func (t *TradeUpdatesStream) isClosed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed
+}
+
 // authenticate sends authentication message
 func (t *TradeUpdatesStream) authenticate() error {
 	authMsg := TradeAuthMessage{
@@ -206,7 +244,17 @@ func (t *TradeUpdatesStream) handleMessages(ctx context.Context) {
 		default:
 			var message json.RawMessage
 			if err := t.conn.ReadJSON(&message); err != nil {
+				if ctx.Err() != nil || t.isClosed() {
+					return
+				}
+				tradeUpdatesCounters.readError()
 				t.logger.Printf("Error reading message: %v", err)
+
+				// A failed read leaves the connection unusable; re-dial
+				t.conn.Close()
+				if !t.reconnect(ctx) {
+					return
+				}
 				continue
 			}
 
@@ -224,6 +272,12 @@ func (t *TradeUpdatesStream) processMessage(rawMsg json.RawMessage) {
 		return
 	}
 
+	if tradeUpdate.Stream == "trade_updates" {
+		tradeUpdatesCounters.message(tradeUpdate.Data.Event)
+	} else {
+		tradeUpdatesCounters.message(tradeUpdate.Stream)
+	}
+
 	if tradeUpdate.Stream == "trade_updates" && t.onTradeUpdate != nil {
 		t.onTradeUpdate(tradeUpdate)
 	} else {
@@ -237,6 +291,10 @@ func (t *TradeUpdatesStream) processMessage(rawMsg json.RawMessage) {
 
 // Disconnect closes the WebSocket connection
 func (t *TradeUpdatesStream) Disconnect() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
 	if t.conn != nil {
 		return t.conn.Close()
 	}